@@ -7,15 +7,22 @@ import (
 	"os/signal"
 	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/executor"
 	"sl.framework.com/game_server/game/controller"
 	"sl.framework.com/game_server/game/dao"
+	"sl.framework.com/game_server/game/dao/gamedb"
 	"sl.framework.com/game_server/game/dao/redisdb"
 	"sl.framework.com/game_server/game/dao/uiddb"
 	"sl.framework.com/game_server/game/filter"
 	"sl.framework.com/game_server/game/filter/common"
 	"sl.framework.com/game_server/game/service"
 	"sl.framework.com/game_server/game/service/base"
+	"sl.framework.com/game_server/healthcheck"
+	"sl.framework.com/game_server/lifecycle"
+	"sl.framework.com/game_server/metrics"
 	"sl.framework.com/game_server/mq"
+	"sl.framework.com/game_server/outbox"
+	"sl.framework.com/tool"
 	"sl.framework.com/trace"
 	"syscall"
 	"time"
@@ -27,18 +34,21 @@ const timeLayout = "2006-01-02 15:04:05"
 func init() {
 	//初始化log 内部使用sync.Once保证只初始化一次
 	trace.LoggerInit()
+	//Watcher阶段耗时导出到prometheus
+	tool.SetWatcherObserver(metrics.ObserveWatcher)
 }
 
 // beegoWebInit初始化并异步启动web
 func beegoWebInit() {
 	async.AsyncRunCoroutine(func() {
 		//通用配置开启
-		beego.BConfig.CopyRequestBody = true                                    //必须设置为true 否则http拿不到数据
-		beego.BConfig.Listen.EnableAdmin = conf.ServerConf.BeegoCFG.AdminEnable //默认不启动8088端口 8088端口手动独立启动
-		beego.BConfig.WebConfig.AutoRender = false                              //没有前端 设置为false
-		beego.BConfig.RunMode = conf.ServerConf.BeegoCFG.RunMode                //显式设置开发模式为dev 打印路由耗时
-		beego.BConfig.Listen.Graceful = conf.ServerConf.BeegoCFG.GraceEnable    //优雅关闭
-		beego.BConfig.EnableErrorsRender = false                                //关闭默认错误页面
+		beegoCFG := conf.GetBeeGoConfig()
+		beego.BConfig.CopyRequestBody = true                    //必须设置为true 否则http拿不到数据
+		beego.BConfig.Listen.EnableAdmin = beegoCFG.AdminEnable //默认不启动8088端口 8088端口手动独立启动
+		beego.BConfig.WebConfig.AutoRender = false              //没有前端 设置为false
+		beego.BConfig.RunMode = beegoCFG.RunMode                //显式设置开发模式为dev 打印路由耗时
+		beego.BConfig.Listen.Graceful = beegoCFG.GraceEnable    //优雅关闭
+		beego.BConfig.EnableErrorsRender = false                //关闭默认错误页面
 		beego.BConfig.RecoverPanic = true
 
 		beego.BConfig.RecoverFunc = filter.RecoverPanic //重写 panic错误处理函数
@@ -62,23 +72,25 @@ func beegoWebInit() {
 
 /**
  * beegoStop
- * beego关闭时调用beego优雅关闭功能
+ * beego关闭时调用beego优雅关闭功能 只关闭8080业务端口 健康检查端口保持到进程退出
  *
- * @param
- * @return
+ * @param ctx context.Context - 等待处理中的请求的超时控制
+ * @return error - 超时返回错误
  */
 
-func beegoStop() {
-	// 设置超时时间为5秒
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	//调用beego自带优雅关闭功能
+func beegoStop(ctx context.Context) error {
+	//调用beego自带优雅关闭功能 等待处理中的请求完成
 	if err := beego.BeeApp.Server.Shutdown(ctx); err != nil {
 		trace.Error("beegoStop Server forced to shutdown failed, err=%v", err.Error())
-		return
+		return err
 	}
 	trace.Info("beegoStop Server forced to shutdown success")
+	return nil
+}
+
+// beegoGraceTimeout 关闭http的超时时间 使用beego.graceTimeOut配置 未配置时使用默认超时时间
+func beegoGraceTimeout() time.Duration {
+	return time.Duration(conf.GetBeeGoConfig().GraceTimeOUt) * time.Second
 }
 
 /*
@@ -105,24 +117,61 @@ func appInit() bool {
 	}
 	//读取到nacos配置以后设置日志打印级别
 	trace.SetLevel(conf.GetLogLevel())
+	//配置已经在内存中 nacos不可用时只影响配置更新 不是关键依赖
+	healthcheck.RegisterReadiness(healthcheck.Check{Name: "nacos", Probe: conf.ProbeNacos})
 
 	// 初始化Redis
 	if ok := redisdb.RedisClientInitOnce(); !ok {
 		trace.Error("appInit RedisClientInitOnce failed")
 		return false
 	}
+	lifecycle.Register(lifecycle.Hook{Name: "redis", Order: lifecycle.OrderRedis, Stop: func(context.Context) error {
+		redisdb.RedisClientClose()
+		return nil
+	}})
+	healthcheck.RegisterReadiness(healthcheck.Check{Name: "redis", Critical: true, Probe: redisdb.Ping})
+
+	//抢占雪花算法节点id并设置server id 需要在生成任何订单id之前 没有空闲id时拒绝启动
+	if err := base.GetWorkerLeaseInstance().Acquire(); err != nil {
+		trace.Error("appInit acquire worker lease failed, error=%v", err.Error())
+		return false
+	}
+	//释放节点id租约 供其他节点使用
+	lifecycle.Register(lifecycle.Hook{Name: "workerLease", Order: lifecycle.OrderLease, Stop: func(context.Context) error {
+		base.GetWorkerLeaseInstance().Release()
+		return nil
+	}})
 
 	//Database Orm初始化
 	if err := dao.OrmInit(); nil != err {
 		trace.Error("appInit OrmInit failed, error=%v", err.Error())
 		return false
 	}
-
-	//初始化rocketmq
-	if ok := mq.InitRocketManager(); !ok {
+	//注单入库依赖游戏数据库 uid数据库只在生成节点id时使用
+	healthcheck.RegisterReadiness(healthcheck.Check{Name: "gameDb", Critical: true, Probe: gamedb.Ping})
+	healthcheck.RegisterReadiness(healthcheck.Check{Name: "uidDb", Probe: uiddb.Ping})
+	//派彩 小票 局消息先写入发件箱 能力平台暂时不可用时由发件箱重试 不是关键依赖
+	healthcheck.RegisterReadiness(healthcheck.Check{Name: "platform", Probe: healthcheck.ProbeHttp(conf.GetPlatformInfoUrl)})
+
+	//启动发件箱后台任务 重试上次未发送成功的派彩 小票 局消息 未发送成功的消息下次启动后继续发送
+	outbox.Start()
+	lifecycle.Register(lifecycle.Hook{Name: "outbox", Order: lifecycle.OrderOutbox, Stop: func(context.Context) error {
+		outbox.Stop()
+		return nil
+	}})
+
+	//初始化消息队列
+	if ok := mq.InitMQManager(); !ok {
 		trace.Error("appInit RocketMQInit failed")
 		return false
 	}
+	//停止拉取结算消息 等待处理中的开奖分片派彩和更新注单完成
+	lifecycle.Register(lifecycle.Hook{Name: "mq", Order: lifecycle.OrderMQ, Stop: mq.DrainMQManager})
+	healthcheck.RegisterReadiness(healthcheck.Check{Name: "mq", Critical: true, Probe: func(context.Context) error {
+		return mq.Check()
+	}})
+	//协程池在第一次使用时创建 关闭时等待所有协程池中的入库 小票 局消息任务完成
+	lifecycle.Register(lifecycle.Hook{Name: "executor", Order: lifecycle.OrderExecutor, Stop: executor.DrainAll})
 
 	//数据初始化
 	FrameworkDataInit()
@@ -132,19 +181,52 @@ func appInit() bool {
 
 	//启动内存管理任务
 	base.GetCacheManager().RunLoopTask()
-
-	//初始化uid db并设置server id
-	uiddb.GetUniqueIdGeneratorInstance().SetUniqueId()
+	lifecycle.Register(lifecycle.Hook{Name: "cacheManager", Order: lifecycle.OrderLoopTask, Stop: base.GetCacheManager().StopLoopTask})
+	healthcheck.RegisterLiveness(healthcheck.Check{Name: "cacheManager", Probe: healthcheck.ProbeAlive(base.GetCacheManager().Alive)})
+
+	//启动房间在线玩家清理任务
+	base.GetPresenceSweeper().RunLoopTask()
+	lifecycle.Register(lifecycle.Hook{Name: "presenceSweeper", Order: lifecycle.OrderLoopTask, Stop: base.GetPresenceSweeper().StopLoopTask})
+	healthcheck.RegisterLiveness(healthcheck.Check{Name: "presenceSweeper", Probe: healthcheck.ProbeAlive(base.GetPresenceSweeper().Alive)})
+
+	//启动心跳任务 加入集群 需要在设置server id之后 心跳时续期节点id租约
+	base.GetHeartbeatInstance().OnHeartbeat(base.GetWorkerLeaseInstance().Renew)
+	base.GetHeartbeatInstance().RunLoopTask()
+	//最先退出集群 其他节点下次心跳时感知
+	lifecycle.Register(lifecycle.Hook{Name: "heartbeatLeave", Order: lifecycle.OrderHeartbeat, Stop: func(context.Context) error {
+		base.GetHeartbeatInstance().Leave()
+		return nil
+	}})
+	//退出集群后心跳继续续期节点id租约 排空其他子系统的时间可能超过租约时间 释放租约之后才停止
+	lifecycle.Register(lifecycle.Hook{Name: "heartbeat", Order: lifecycle.OrderLease, Stop: base.GetHeartbeatInstance().StopLoopTask})
+	healthcheck.RegisterLiveness(healthcheck.Check{Name: "heartbeat", Probe: healthcheck.ProbeAlive(base.GetHeartbeatInstance().Alive)})
 
 	//初始化api并启动监听端口
 	beegoWebInit()
+	//停止接收下注请求 等待处理中的下注 取消 提交注单请求完成
+	lifecycle.Register(lifecycle.Hook{Name: "http", Order: lifecycle.OrderHTTP, Timeout: beegoGraceTimeout(), Stop: beegoStop})
 
+	//启动完成 readiness置为就绪
+	lifecycle.SetReady()
 	return true
 }
 
-// Start 启动游戏服 供外部调用
+// Start 启动游戏服 供外部调用 命令行带--check-config时只做配置自检 见CheckConfig
 func Start() {
 	defer async.TryException() //保证运行时panic有日志输出
+
+	//自检模式 校验配置并检测依赖连通性后退出
+	if checkMode, path := parseCheckConfigArg(os.Args[1:]); checkMode {
+		location := conf.OnServerConf
+		if path != "" {
+			location = conf.OnLocalConf
+		}
+		if !CheckConfig(location, path, os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	service.ValidateService()
 
 	//初始化log 内部使用sync.Once保证只初始化一次
@@ -191,23 +273,13 @@ func waitOnSignal() {
 func graceStop() {
 	trace.Info("graceStop start, current time=%v", time.Now().Format(timeLayout))
 
-	//等待下注和结算任务处理完毕再关闭数据库等相关服务
-	time.Sleep(time.Second * 5)
-
-	//关闭beego 停止接收下注请求
-	beegoStop()
-
-	//关闭mq 停止接收结算消息
-	mq.StopRocketManager()
-
-	//关闭数据库
-	/*
-		beego orm 并不提供显式的关闭数据库连接的方法，通常依赖于数据库驱动的连接池管理
-	*/
-
-	//关闭缓存管理类
-
-	//断开redis
-	redisdb.RedisClientClose()
-	trace.Info("graceStop done, current time=%v", time.Now().Format(timeLayout))
+	//readiness置为未就绪后按顺序执行appInit中注册的关闭钩子 每个钩子的结果在lifecycle中记录日志
+	//关闭数据库 beego orm 并不提供显式的关闭数据库连接的方法，通常依赖于数据库驱动的连接池管理
+	failed := 0
+	for _, result := range lifecycle.Shutdown() {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	trace.Info("graceStop done, failed hooks=%v, current time=%v", failed, time.Now().Format(timeLayout))
 }
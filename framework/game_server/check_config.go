@@ -0,0 +1,139 @@
+package gameserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	_ "github.com/go-sql-driver/mysql"
+	"io"
+	"net"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/mq"
+	"strings"
+	"time"
+)
+
+/*
+	启动自检
+	gameserver --check-config			从nacos读取配置(与正常启动相同的环境变量)
+	gameserver --check-config=server.yaml	从本地文件读取配置
+	校验配置并检测redis mysql rocketmq name server的连通性 输出报告后退出 全部通过时退出码为0
+*/
+
+const (
+	checkConfigArg = "--check-config"
+	probeTimeout   = 3 * time.Second //每项连通性检测的超时时间
+)
+
+// parseCheckConfigArg 解析命令行中的--check-config参数 返回是否为自检模式和本地配置文件路径
+func parseCheckConfigArg(args []string) (bool, string) {
+	for _, arg := range args {
+		if arg == checkConfigArg {
+			return true, ""
+		}
+		if path, ok := strings.CutPrefix(arg, checkConfigArg+"="); ok {
+			return true, path
+		}
+	}
+
+	return false, ""
+}
+
+/**
+ * CheckConfig
+ * 校验配置并检测各项依赖的连通性 结果输出到w
+ *
+ * @param location conf.ConfigLocation - 配置位置 OnLocalConf时从path读取 OnServerConf时从nacos读取
+ * @param path string - 本地配置文件路径
+ * @param w io.Writer - 报告输出
+ * @return bool - 是否全部通过
+ */
+
+func CheckConfig(location conf.ConfigLocation, path string, w io.Writer) bool {
+	cfg, err := conf.LoadForCheck(location, path)
+	if err != nil {
+		fmt.Fprintf(w, "[FAIL] load config from %v %v: %v\n", location, path, err.Error())
+		return false
+	}
+
+	passed := true
+	if err = cfg.Validate(); err != nil {
+		passed = false
+		problems := strings.Split(err.Error(), "\n")
+		fmt.Fprintf(w, "[FAIL] config: %v problem(s)\n", len(problems))
+		for _, problem := range problems {
+			fmt.Fprintf(w, "       - %v\n", problem)
+		}
+	} else {
+		fmt.Fprintf(w, "[ OK ] config\n")
+	}
+
+	report := func(name string, fn func() error) {
+		start := time.Now()
+		if err := fn(); err != nil {
+			passed = false
+			fmt.Fprintf(w, "[FAIL] %v: %v\n", name, err.Error())
+			return
+		}
+		fmt.Fprintf(w, "[ OK ] %v (%v)\n", name, time.Since(start).Round(time.Millisecond))
+	}
+
+	report(fmt.Sprintf("redis %v:%v", cfg.RedisInfo.Host, cfg.RedisInfo.Port), func() error {
+		return redisdb.Probe(probeTimeout)
+	})
+	report(fmt.Sprintf("mysql uid %v/%v", cfg.Database.UidDb.Host, cfg.Database.UidDb.Database), func() error {
+		return probeMySql(conf.GetMySqlDsnUidDb())
+	})
+	report(fmt.Sprintf("mysql gameDb %v/%v", cfg.Database.GameDb.Host, cfg.Database.GameDb.Database), func() error {
+		return probeMySql(conf.GetMySqlGameDb())
+	})
+	if transport := conf.GetMQTransport(); transport != mq.TransportRocketMQ {
+		fmt.Fprintf(w, "[SKIP] rocketmq name server: transport=%v\n", transport)
+	} else {
+		report(fmt.Sprintf("rocketmq name server %v", cfg.Rocketmq.NameServer), func() error {
+			return probeNameServer(cfg.Rocketmq.NameServer)
+		})
+	}
+
+	if passed {
+		fmt.Fprintf(w, "check config passed\n")
+	} else {
+		fmt.Fprintf(w, "check config failed\n")
+	}
+	return passed
+}
+
+// probeMySql 连接mysql并ping
+func probeMySql(dsn string) error {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
+
+// probeNameServer 逐个tcp连接name server 地址可以带http://前缀 多个地址用;分隔
+func probeNameServer(nameServer string) error {
+	addrs := strings.FieldsFunc(nameServer, func(r rune) bool { return r == ';' || r == ',' })
+	if len(addrs) == 0 {
+		return errors.New("name server is empty")
+	}
+
+	for _, addr := range addrs {
+		addr = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(addr), "http://"), "https://")
+		conn, err := net.DialTimeout("tcp", addr, probeTimeout)
+		if err != nil {
+			return err
+		}
+		_ = conn.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package gameserver
+
+import (
+	"os"
+	"path/filepath"
+	"sl.framework.com/game_server/conf"
+	"strings"
+	"testing"
+)
+
+func TestParseCheckConfigArg(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantCheck bool
+		wantPath  string
+	}{
+		{name: "no args", args: nil},
+		{name: "other args", args: []string{"gameserver", "-v", "--check"}},
+		{name: "nacos", args: []string{"gameserver", "--check-config"}, wantCheck: true},
+		{name: "local file", args: []string{"gameserver", "--check-config=conf/server.yaml"}, wantCheck: true,
+			wantPath: "conf/server.yaml"},
+		{name: "empty path", args: []string{"gameserver", "--check-config="}, wantCheck: true},
+		{name: "first wins", args: []string{"--check-config=a.yaml", "--check-config=b.yaml"}, wantCheck: true,
+			wantPath: "a.yaml"},
+		{name: "prefix only", args: []string{"--check-configs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, path := parseCheckConfigArg(tt.args)
+			if check != tt.wantCheck || path != tt.wantPath {
+				t.Fatalf("parseCheckConfigArg(%v) = %v, %q, want %v, %q", tt.args, check, path, tt.wantCheck, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestCheckConfigUnreachableRedis(t *testing.T) {
+	//端口1没有监听 连接立即被拒绝
+	t.Setenv("REDIS_HOST", "127.0.0.1")
+	t.Setenv("REDIS_PORT", "1")
+	t.Setenv("UID_HOST", "127.0.0.1:1")
+	t.Setenv("TIDB_HOST", "127.0.0.1:1")
+	t.Setenv("MQ_NAMESERVER", "127.0.0.1:1")
+
+	data, err := os.ReadFile(filepath.Join("conf", "server.yaml"))
+	if err != nil {
+		t.Fatalf("read server.yaml error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	if err = os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write %v error = %v", path, err)
+	}
+
+	var report strings.Builder
+	if CheckConfig(conf.OnLocalConf, path, &report) {
+		t.Fatalf("CheckConfig() = true, want false\n%v", report.String())
+	}
+	for _, want := range []string{"[ OK ] config\n", "[FAIL] redis 127.0.0.1:1:", "check config failed\n"} {
+		if !strings.Contains(report.String(), want) {
+			t.Fatalf("CheckConfig() report missing %q\n%v", want, report.String())
+		}
+	}
+}
@@ -3,7 +3,6 @@ package conf
 import (
 	"errors"
 	"fmt"
-	"gopkg.in/yaml.v2"
 	"os"
 	"reflect"
 	"regexp"
@@ -15,8 +14,7 @@ import (
 )
 
 var (
-	configMutex sync.Mutex
-	ServerConf  *Configuration
+	configMutex sync.Mutex //修改配置时加锁 读取配置使用Current()不需要加锁
 
 	/* 该文件是nacos中配置文件名字 对应nacos中的DataId字段 有框架使用者传入*/
 	configurationFileName string
@@ -25,22 +23,22 @@ var (
 // RedisInfo redis集群信息
 type (
 	RedisInfo struct {
-		Host      string `yaml:"host"`
-		Port      string `yaml:"port"`
+		Host      string `yaml:"host" validate:"required"`
+		Port      string `yaml:"port" validate:"required"`
 		Db        string `yaml:"defaultDb"`
 		UserName  string `yaml:"userName"`
-		Password  string `yaml:"password"`
+		Password  string `yaml:"password" mask:"true"`
 		KeyPrefix string `yaml:"keyPrefix"`
-		Mode      string `yaml:"mode"` // redis工作模式 single:单节点模式 cluster:集群模式
+		Mode      string `yaml:"mode" validate:"oneof=single cluster"` // redis工作模式 single:单节点模式 cluster:集群模式
 	}
 
 	// DbItem mysql数据库信息
 	DbItem struct {
-		Host      string `yaml:"host"`
-		Database  string `yaml:"database"`
-		AliasName string `yaml:"aliasName"`
-		UserName  string `yaml:"username"`
-		Password  string `yaml:"password"`
+		Host      string `yaml:"host" validate:"required,hostport"`
+		Database  string `yaml:"database" validate:"required"`
+		AliasName string `yaml:"aliasName" validate:"required"`
+		UserName  string `yaml:"username" validate:"required"`
+		Password  string `yaml:"password" mask:"true"`
 	}
 
 	// Database mysql数据库信息
@@ -51,39 +49,43 @@ type (
 
 	// Platform 能力平台信息
 	Platform struct {
-		Host          string `yaml:"host"`
-		Port          int    `yaml:"port"`
-		RetryTime     int    `yaml:"retryTime"`
-		RetryInterval int    `yaml:"retryInterval"`
+		Host          string `yaml:"host" validate:"required,url"`
+		Port          int    `yaml:"port" validate:"min=0,max=65535"`
+		RetryTime     int    `yaml:"retryTime" validate:"min=0"`
+		RetryInterval int    `yaml:"retryInterval" validate:"min=0"`
 	}
 
 	// Common 部分配置
 	Common struct {
-		HeartbeatInterval int    `yaml:"heartbeatInterval"` //心跳间隔(s)
-		HeartbeatExpired  int    `yaml:"heartbeatExpired"`  //心跳redis超时时间(s)
-		LoopTaskExpired   int    `yaml:"loopTaskExpired"`   //任务超时时间
-		UserLimitSwitch   string `yaml:"userLimitSwitch"`   //个人限红开关 on:开启个人限红校验 off:关闭个人限红校验
-		LogLevel          string `yaml:"logLevel"`          //日志登记
-		DrawSize          int    `yaml:"drawSize"`          //开奖分片中，每一片的大小
-		BetConfirmSIze    int    `yaml:"betConfirmSize"`    //提交注单分片中，每一片的大小
-		GameId            int    `yaml:"gameId"`            //游戏Id
+		HeartbeatInterval  int    `yaml:"heartbeatInterval" validate:"min=0"`                                                          //心跳间隔(s)
+		HeartbeatExpired   int    `yaml:"heartbeatExpired" validate:"min=0"`                                                           //心跳redis超时时间(s)
+		LoopTaskExpired    int    `yaml:"loopTaskExpired"`                                                                             //任务超时时间
+		UserLimitSwitch    string `yaml:"userLimitSwitch" validate:"oneof=on off"`                                                     //个人限红开关 on:开启个人限红校验 off:关闭个人限红校验
+		LogLevel           string `yaml:"logLevel" validate:"oneof=Emergency Alert Critical Error Warning Notice Informational Debug"` //日志登记
+		DrawSize           int    `yaml:"drawSize" validate:"min=1"`                                                                   //开奖分片中，每一片的大小
+		BetConfirmSIze     int    `yaml:"betConfirmSize" validate:"min=1"`                                                             //提交注单分片中，每一片的大小
+		GameId             int    `yaml:"gameId" validate:"min=1"`                                                                     //游戏Id
+		PresenceExpired    int    `yaml:"presenceExpired"`                                                                             //玩家在房间内没有活动超过该时间视为离开(s)
+		WorkerLeaseExpired int    `yaml:"workerLeaseExpired"`                                                                          //雪花算法节点id租约超时时间(s) 由心跳续期
+		RoundStateRecovery string `yaml:"roundStateRecovery" validate:"oneof=strict forward off"`                                      //局事件乱序时的处理方式 strict:拒绝 forward:允许向前跳过 off:不校验
 	}
 
 	// Rocket 相关配置
 	Rocket struct {
+		Transport             string            `yaml:"transport" validate:"oneof=rocketmq memory"` //消息队列传输层 rocketmq或者memory 默认rocketmq
 		NameServer            string            `yaml:"nameServer"`
-		ProducerQueueMaxLen   int               `yaml:"producerQueueMaxLen"`
+		ProducerQueueMaxLen   int               `yaml:"producerQueueMaxLen" validate:"min=0"`
 		JoinMessageRoomTopic  string            `yaml:"joinMessageRoomTopic"`
 		LeaveMessageRoomTopic string            `yaml:"leaveMessageRoomTopic"`
-		Retries               int               `yaml:"retries"`
-		GameDrawTopicsIn      []TopicConfigItem `yaml:"gameDrawTopicsIn"`
-		GameDrawTopicsOut     []TopicConfigItem `yaml:"gameDrawTopicsOut"`
-		BetConfirmTopicsIn    []TopicConfigItem `yaml:"betConfirmTopicsIn"`
-		BetConfirmTopicsOut   []TopicConfigItem `yaml:"betConfirmTopicsOut"`
+		Retries               int               `yaml:"retries" validate:"min=0"`
+		GameDrawTopicsIn      []TopicConfigItem `yaml:"gameDrawTopicsIn" validate:"required"`
+		GameDrawTopicsOut     []TopicConfigItem `yaml:"gameDrawTopicsOut" validate:"required"`
+		BetConfirmTopicsIn    []TopicConfigItem `yaml:"betConfirmTopicsIn" validate:"required"`
+		BetConfirmTopicsOut   []TopicConfigItem `yaml:"betConfirmTopicsOut" validate:"required"`
 	}
 
 	TopicConfigItem struct {
-		TopicName  string `yaml:"topicName"`
+		TopicName  string `yaml:"topicName" validate:"required"`
 		TopicGroup string `yaml:"topicGroup"`
 	}
 	BeeGoConfig struct {
@@ -93,25 +95,83 @@ type (
 		AdminEnable  bool   `yaml:"adminEnable"`
 		RunMode      string `yaml:"runMode"`
 	}
+	// DataSource 数据源鉴权配置 数据源调用/game/event等接口时需要使用各自的密钥签名
+	DataSource struct {
+		SignEnable   bool             `yaml:"signEnable"`                    //是否开启签名校验
+		ReplayWindow int              `yaml:"replayWindow" validate:"min=0"` //时间戳允许的偏差 同时也是nonce防重放的保存时长 单位秒
+		Sources      []DataSourceItem `yaml:"sources"`                       //数据源密钥列表
+	}
+
+	// DataSourceItem 数据源密钥
+	DataSourceItem struct {
+		SourceId string `yaml:"sourceId" validate:"required"` //数据源Id 对应请求头Source-Id
+		Secret   string `yaml:"secret" mask:"true"`           //签名密钥 建议使用${ENV}形式从环境变量读取
+	}
+
+	// PlayerAuth 玩家身份校验配置 开启后/bet相关接口需要携带Authorization: Bearer <token>
+	PlayerAuth struct {
+		Enable       bool   `yaml:"enable"`                  //是否开启玩家token校验
+		Algorithm    string `yaml:"algorithm"`               //token签名算法 HS256或者RS256
+		Secret       string `yaml:"secret" mask:"true"`      //HS256密钥
+		PublicKey    string `yaml:"publicKey"`               //RS256公钥 PEM格式
+		Leeway       int    `yaml:"leeway" validate:"min=0"` //过期时间允许的时钟偏差 单位秒
+		SessionCheck bool   `yaml:"sessionCheck"`            //是否校验token中的sid与用户会话缓存一致
+	}
+
+	// Outbox 外发请求发件箱配置 派彩 小票 局消息先落库再由后台任务重试发送
+	Outbox struct {
+		Store         string `yaml:"store" validate:"oneof=db memory"` //发件箱存储 db或者memory 默认db
+		PollInterval  int    `yaml:"pollInterval" validate:"min=0"`    //后台任务扫描间隔 单位ms
+		BatchSize     int    `yaml:"batchSize" validate:"min=0"`       //每次扫描最多处理的消息数
+		MaxRetries    int    `yaml:"maxRetries" validate:"min=0"`      //最大重试次数 超过后标记为failed等待人工处理
+		RetryInterval int    `yaml:"retryInterval"`                    //首次重试间隔 之后按2倍递增 单位ms
+		RetryMax      int    `yaml:"retryMax" validate:"min=0"`        //重试间隔上限 单位秒
+	}
+
+	// ExecutorItem 协程池配置 修改后重启生效
+	ExecutorItem struct {
+		Workers   int    `yaml:"workers" validate:"min=0"`                  //worker数量
+		QueueSize int    `yaml:"queueSize" validate:"min=0"`                //等待队列长度
+		Policy    string `yaml:"policy" validate:"oneof=reject callerRuns"` //队列满时的处理策略 reject:丢弃 callerRuns:在调用方执行
+	}
+
+	// Shutdown 优雅关闭配置
+	Shutdown struct {
+		ReadinessDelay int `yaml:"readinessDelay" validate:"min=0"` //readiness置为未就绪后等待负载均衡摘除流量的时间 单位秒
+		Timeout        int `yaml:"timeout" validate:"min=0"`        //每个关闭步骤默认的超时时间 单位秒
+	}
+
+	// Health 健康检查配置
+	Health struct {
+		CacheTtl int `yaml:"cacheTtl" validate:"min=0"` //依赖检查结果的缓存时间 避免每次探针都访问依赖 单位ms
+		Timeout  int `yaml:"timeout" validate:"min=0"`  //每个依赖检查的超时时间 单位ms
+	}
+
 	// Http 相关配置
 	Http struct {
-		HttpConnectTimeout   int `yaml:"httpConnectTimeout"`   //http连接超时时间 单位秒
-		HttpReadWriteTimeout int `yaml:"httpReadWriteTimeout"` //http读写超时时间单位秒
+		HttpConnectTimeout   int `yaml:"httpConnectTimeout" validate:"min=0"`   //http连接超时时间 单位秒
+		HttpReadWriteTimeout int `yaml:"httpReadWriteTimeout" validate:"min=0"` //http读写超时时间单位秒
 	}
 
 	// Configuration 服务配置信息
 	Configuration struct {
-		RedisInfo      RedisInfo   `yaml:"redis"`
-		Database       Database    `yaml:"database"`
-		Platform       Platform    `yaml:"platform"`
-		Common         Common      `yaml:"common"`
-		Rocketmq       Rocket      `yaml:"rocketmq"`
-		BeegoCFG       BeeGoConfig `yaml:"beego"`
-		Http           Http        `yaml:"http"`
-		ServerId       int64       `yaml:"serverId"`
-		GameConfig     GameConfig  `yaml:"gameConfig"`
-		ConfigFileName string      //配置文件名字 有具体游戏传入并设置
-		AgentToken     string      //跟能力中台交互使用的Token
+		RedisInfo      RedisInfo               `yaml:"redis"`
+		Database       Database                `yaml:"database"`
+		Platform       Platform                `yaml:"platform"`
+		Common         Common                  `yaml:"common"`
+		Rocketmq       Rocket                  `yaml:"rocketmq"`
+		BeegoCFG       BeeGoConfig             `yaml:"beego"`
+		Http           Http                    `yaml:"http"`
+		DataSource     DataSource              `yaml:"dataSource"`
+		PlayerAuth     PlayerAuth              `yaml:"playerAuth"`
+		Outbox         Outbox                  `yaml:"outbox"`
+		Executor       map[string]ExecutorItem `yaml:"executor"` //协程池配置 key为协程池名称
+		Shutdown       Shutdown                `yaml:"shutdown"`
+		Health         Health                  `yaml:"health"`
+		ServerId       int64                   `yaml:"serverId"`
+		GameConfig     GameConfig              `yaml:"gameConfig"`
+		ConfigFileName string                  //配置文件名字 有具体游戏传入并设置
+		AgentToken     string                  `mask:"true"` //跟能力中台交互使用的Token
 
 	}
 	// GameConfig 游戏独立配置
@@ -144,27 +204,6 @@ func GetConfigurationFileName() string {
 	return configurationFileName
 }
 
-// parseContent 解析xml文件内容到Configuration结构体中
-func parseContent(content string) error {
-	var err error
-	if ServerConf == nil {
-		ServerConf = &Configuration{}
-	}
-
-	configMutex.Lock()
-	defer configMutex.Unlock()
-	if err = yaml.Unmarshal([]byte(content), ServerConf); nil != err {
-		trace.Error("parseContent Unmarshal failed, error=%v", err.Error())
-		return err
-	}
-
-	trace.Info("NacosClientInit Unmarshal conf=%+v", ServerConf)
-	walkConfMember(ServerConf)
-	trace.Info("NacosClientInit Unmarshal after env var replace conf=%+v", ServerConf)
-
-	return err
-}
-
 // 遍历配置结构体成员并解析有变量默认值形式的成员
 func walkConfMember(conf interface{}) {
 	val := reflect.ValueOf(conf)
@@ -194,6 +233,11 @@ func walkConfMember(conf interface{}) {
 		} else if field.Kind() == reflect.Struct {
 			// 处理嵌套结构体
 			walkConfMember(field.Addr().Interface())
+		} else if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct {
+			// 处理结构体切片
+			for j := 0; j < field.Len(); j++ {
+				walkConfMember(field.Index(j).Addr().Interface())
+			}
 		}
 	}
 }
@@ -240,13 +284,14 @@ func getEnv(envKey string) (bIsExit bool, val string) {
 
 // GetRedisAddr 获取redis集群信息
 func GetRedisAddr() (addr []string, err error) {
-	if ServerConf == nil {
-		err = errors.New("ServerConf is nul")
-		trace.Error("GetRedisAddr ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		err = errors.New("configuration is nil")
+		trace.Error("GetRedisAddr Current() == nil")
 		return
 	}
 
-	addr = []string{ServerConf.RedisInfo.Host + ":" + ServerConf.RedisInfo.Port}
+	addr = []string{cfg.RedisInfo.Host + ":" + cfg.RedisInfo.Port}
 	trace.Info("GetRedisAddr addr=%v", addr)
 	return
 }
@@ -268,94 +313,102 @@ const (
  */
 
 func GetRedisMode() RedisMode {
-	if ServerConf == nil {
-		trace.Error("GetRedisMode ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetRedisMode Current() == nil")
 		return ""
 	}
 
 	mode := RedisModeSingle
-	if RedisMode(ServerConf.RedisInfo.Mode) == RedisModeCluster {
+	if RedisMode(cfg.RedisInfo.Mode) == RedisModeCluster {
 		mode = RedisModeCluster
 	}
-	trace.Info("GetRedisMode ClusterNodes=%v, mode=%v", ServerConf.RedisInfo.Mode, mode)
+	trace.Info("GetRedisMode ClusterNodes=%v, mode=%v", cfg.RedisInfo.Mode, mode)
 	return mode
 }
 
 // GetRedisDb 获取redis集群信息
 func GetRedisDb() int {
-	if ServerConf == nil {
-		trace.Error("GetRedisDb ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetRedisDb Current() == nil")
 		return 0
 	}
 
-	db, _ := strconv.Atoi(ServerConf.RedisInfo.Db)
+	db, _ := strconv.Atoi(cfg.RedisInfo.Db)
 	return db
 }
 
 // GetKeyPrefix redis key的公用前缀
 func GetKeyPrefix() string {
-	if ServerConf == nil {
-		trace.Error("GetKeyPrefix ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetKeyPrefix Current() == nil")
 		return ""
 	}
 
-	return ServerConf.RedisInfo.KeyPrefix
+	return cfg.RedisInfo.KeyPrefix
 }
 
 // GetRedisUserInfo 获取redis用户
 func GetRedisUserInfo() (string, string) {
-	if ServerConf == nil {
-		trace.Error("GetRedisUserInfo ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetRedisUserInfo Current() == nil")
 		return "", ""
 	}
 
-	return ServerConf.RedisInfo.UserName, ServerConf.RedisInfo.Password
+	return cfg.RedisInfo.UserName, cfg.RedisInfo.Password
 }
 
 // GetUidDbAliasName 以数据库名作为别名
 func GetUidDbAliasName() string {
-	if ServerConf == nil {
-		trace.Error("GetMySqlDsnUidDb ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetMySqlDsnUidDb Current() == nil")
 		return ""
 	}
 
-	return ServerConf.Database.UidDb.AliasName
+	return cfg.Database.UidDb.AliasName
 }
 
 // GetMySqlDsnGameDb 获取MySql信息
 func GetMySqlDsnGameDb() string {
-	if ServerConf == nil {
-		trace.Error("GetMySqlDsnUidDb ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetMySqlDsnUidDb Current() == nil")
 		return ""
 	}
 	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True",
-		ServerConf.Database.UidDb.UserName, ServerConf.Database.UidDb.Password,
-		ServerConf.Database.UidDb.Host, ServerConf.Database.UidDb.Database)
+		cfg.Database.UidDb.UserName, cfg.Database.UidDb.Password,
+		cfg.Database.UidDb.Host, cfg.Database.UidDb.Database)
 
 	return dsn
 }
 
 // GetMySqlGameDb 获取MySql信息
 func GetMySqlGameDb() string {
-	if ServerConf == nil {
-		trace.Error("GetMySqlGameDb ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetMySqlGameDb Current() == nil")
 		return ""
 	}
 	gameDb := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True",
-		ServerConf.Database.GameDb.UserName, ServerConf.Database.GameDb.Password,
-		ServerConf.Database.GameDb.Host, ServerConf.Database.GameDb.Database)
+		cfg.Database.GameDb.UserName, cfg.Database.GameDb.Password,
+		cfg.Database.GameDb.Host, cfg.Database.GameDb.Database)
 
 	return gameDb
 }
 
 // GetGameDbAliasName 以数据库名作为别名
 func GetGameDbAliasName() string {
-	if ServerConf == nil {
-		trace.Error("GetMySqlDsnUidDb ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetMySqlDsnUidDb Current() == nil")
 		return ""
 	}
 
-	return ServerConf.Database.GameDb.AliasName
+	return cfg.Database.GameDb.AliasName
 }
 
 /**
@@ -367,60 +420,65 @@ func GetGameDbAliasName() string {
  */
 
 func GetGameId() int64 {
-	if ServerConf == nil {
-		trace.Error("GetGameId ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetGameId Current() == nil")
 		return 0
 	}
 
-	return int64(ServerConf.Common.GameId)
+	return int64(cfg.Common.GameId)
 }
 
 // GetServerId 获取MySql信息
 func GetServerId() int64 {
-	if ServerConf == nil {
-		trace.Error("GetServerId ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetServerId Current() == nil")
 		return 0
 	}
 
-	configMutex.Lock()
-	defer configMutex.Unlock()
-	return ServerConf.ServerId
+	return cfg.ServerId
 }
 
 // SetServerId 获取MySql信息
 func SetServerId(serverId int64) {
-	if ServerConf == nil {
-		trace.Error("SetServerId ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("SetServerId Current() == nil")
 		return
 	}
 	trace.Info("SetServerId server id=%v", serverId)
 
+	//复制一份快照再替换 不修改正在被读取的配置
 	configMutex.Lock()
-	ServerConf.ServerId = serverId
-	configMutex.Unlock()
+	defer configMutex.Unlock()
+	updated := *currentConf.Load()
+	updated.ServerId = serverId
+	currentConf.Store(&updated)
 }
 
 const suffix = "v1/"
 
 // GetPlatformInfoUrl 获取平台host port信息
 func GetPlatformInfoUrl() string {
-	if ServerConf == nil {
-		trace.Error("GetPlatformInfo ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetPlatformInfo Current() == nil")
 		return ""
 	}
 
 	//host需要以 http:// 或者 https:// 开头
-	host := ServerConf.Platform.Host
+	host := cfg.Platform.Host
 	if !strings.HasPrefix(host, "http://") &&
 		!strings.HasPrefix(host, "https://") {
 		host = "http://" + host
 	}
 
 	strUrl := ""
-	if ServerConf.Platform.Port == 0 {
+	if cfg.Platform.Port == 0 {
 		strUrl = host
 	} else {
-		strUrl = fmt.Sprintf("%v:%v", host, ServerConf.Platform.Port)
+		strUrl = fmt.Sprintf("%v:%v", host, cfg.Platform.Port)
 	}
 	//if !strings.HasSuffix(strUrl, "/") {
 	//	strUrl = strUrl + "/"
@@ -431,12 +489,13 @@ func GetPlatformInfoUrl() string {
 
 // GetHeartbeatInterval 获取心跳配置信息
 func GetHeartbeatInterval() (interval int) {
-	if ServerConf == nil {
-		trace.Error("GetPlatformInfo ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetPlatformInfo Current() == nil")
 		return -1
 	}
 
-	interval = ServerConf.Common.HeartbeatInterval
+	interval = cfg.Common.HeartbeatInterval
 	if interval <= 0 {
 		interval = 1
 	}
@@ -446,24 +505,26 @@ func GetHeartbeatInterval() (interval int) {
 
 // GetMySqlDsnUidDb 获取MySql信息
 func GetMySqlDsnUidDb() string {
-	if ServerConf == nil {
-		trace.Error("GetMySqlDsnUidDb ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetMySqlDsnUidDb Current() == nil")
 		return ""
 	}
 	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True",
-		ServerConf.Database.UidDb.UserName, ServerConf.Database.UidDb.Password,
-		ServerConf.Database.UidDb.Host, ServerConf.Database.UidDb.Database)
+		cfg.Database.UidDb.UserName, cfg.Database.UidDb.Password,
+		cfg.Database.UidDb.Host, cfg.Database.UidDb.Database)
 
 	return dsn
 }
 
 // GetLoopTaskExpired 获取心跳配置信息
 func GetLoopTaskExpired() (expired int) {
-	if ServerConf == nil {
-		trace.Error("GetPlatformInfo ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetPlatformInfo Current() == nil")
 		return -1
 	}
-	expired = ServerConf.Common.LoopTaskExpired
+	expired = cfg.Common.LoopTaskExpired
 	if expired <= 0 {
 		expired = 10
 	}
@@ -472,12 +533,13 @@ func GetLoopTaskExpired() (expired int) {
 
 // GetHeartbeatExpired 获取心跳配置信息
 func GetHeartbeatExpired() (expired int) {
-	if ServerConf == nil {
-		trace.Error("GetPlatformInfo ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetPlatformInfo Current() == nil")
 		return -1
 	}
 
-	expired = ServerConf.Common.HeartbeatExpired
+	expired = cfg.Common.HeartbeatExpired
 	if expired <= 0 {
 		expired = 6 //单位s秒
 	}
@@ -485,15 +547,61 @@ func GetHeartbeatExpired() (expired int) {
 	return
 }
 
+// GetPresenceExpired 获取玩家在房间内的在线超时时间 单位s
+func GetPresenceExpired() (expired int) {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetPresenceExpired Current() == nil")
+		return 1800
+	}
+
+	expired = cfg.Common.PresenceExpired
+	if expired <= 0 {
+		expired = 1800 //单位s秒 默认30分钟
+	}
+
+	return
+}
+
+// GetWorkerLeaseExpired 获取雪花算法节点id租约超时时间 单位s 不能小于两个心跳间隔
+func GetWorkerLeaseExpired() (expired int) {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetWorkerLeaseExpired Current() == nil")
+		return 60
+	}
+
+	expired = cfg.Common.WorkerLeaseExpired
+	if expired <= 0 {
+		expired = 60 //单位s秒
+	}
+	if minExpired := 2 * GetHeartbeatInterval(); expired < minExpired {
+		expired = minExpired
+	}
+
+	return
+}
+
+// GetRoundStateRecovery 获取局事件乱序时的处理方式 默认forward
+func GetRoundStateRecovery() string {
+	cfg := Current()
+	if cfg == nil || cfg.Common.RoundStateRecovery == "" {
+		return "forward"
+	}
+
+	return strings.ToLower(cfg.Common.RoundStateRecovery)
+}
+
 // GetHttpRetryInfo 获取http重试次数和重试间隔
 func GetHttpRetryInfo() (retryTimes int, retryInterval int) {
-	if ServerConf == nil {
-		trace.Error("GetPlatformInfo ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetPlatformInfo Current() == nil")
 		return -1, -1
 	}
 
-	retryTimes = ServerConf.Platform.RetryTime
-	retryInterval = ServerConf.Platform.RetryInterval
+	retryTimes = cfg.Platform.RetryTime
+	retryInterval = cfg.Platform.RetryInterval
 	if retryTimes <= 0 {
 		retryTimes = 5
 	}
@@ -506,11 +614,12 @@ func GetHttpRetryInfo() (retryTimes int, retryInterval int) {
 
 // GetRocketMQNameServer 获取mq地址
 func GetRocketMQNameServer() []string {
-	if ServerConf == nil {
-		trace.Error("GetRocketMQNameServer ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetRocketMQNameServer Current() == nil")
 		return nil
 	}
-	nameSvr := ServerConf.Rocketmq.NameServer
+	nameSvr := cfg.Rocketmq.NameServer
 
 	return []string{nameSvr}
 }
@@ -523,12 +632,13 @@ func GetRocketMQNameServer() []string {
  */
 
 func GetJoinMessageRoomGroup() string {
-	if ServerConf == nil {
-		trace.Error("GetJoinMessageRoomGroup ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetJoinMessageRoomGroup Current() == nil")
 		return ""
 	}
 
-	return ServerConf.Rocketmq.JoinMessageRoomTopic
+	return cfg.Rocketmq.JoinMessageRoomTopic
 }
 
 /**
@@ -539,30 +649,33 @@ func GetJoinMessageRoomGroup() string {
  */
 
 func GetLeaveMessageRoomGroup() string {
-	if ServerConf == nil {
-		trace.Error("GetLeaveMessageRoomGroup ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetLeaveMessageRoomGroup Current() == nil")
 		return ""
 	}
 
-	return ServerConf.Rocketmq.LeaveMessageRoomTopic
+	return cfg.Rocketmq.LeaveMessageRoomTopic
 }
 
 // GetRocketMQRetries 获取mq地址
 func GetRocketMQRetries() int {
-	if ServerConf == nil {
-		trace.Error("GetRocketMQRetries ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetRocketMQRetries Current() == nil")
 		return 2
 	}
-	return ServerConf.Rocketmq.Retries
+	return cfg.Rocketmq.Retries
 }
 
 func GetRocketMQQueueMaxLen() int {
-	if ServerConf == nil {
-		trace.Error("GetRocketMQQueueMaxLen ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetRocketMQQueueMaxLen Current() == nil")
 		return 500
 	}
 
-	maxLen := ServerConf.Rocketmq.ProducerQueueMaxLen
+	maxLen := cfg.Rocketmq.ProducerQueueMaxLen
 	if maxLen <= 0 {
 		maxLen = 500
 	}
@@ -570,14 +683,84 @@ func GetRocketMQQueueMaxLen() int {
 	return maxLen
 }
 
+/**
+ * GetMQTransport
+ * 获取消息队列传输层名称 未配置时使用rocketmq
+ *
+ * @return string - 传输层名称 小写
+ */
+
+func GetMQTransport() string {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetMQTransport Current() == nil")
+		return "rocketmq"
+	}
+
+	transport := strings.ToLower(strings.TrimSpace(cfg.Rocketmq.Transport))
+	if len(transport) == 0 {
+		transport = "rocketmq"
+	}
+
+	return transport
+}
+
+/**
+ * GetGameDrawTopicsOut
+ * 获取开奖分片主题列表 列表长度即开奖分片数 顺序决定房间到分片的映射
+ *
+ * @return []string - 开奖分片主题名列表
+ */
+
+func GetGameDrawTopicsOut() []string {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetGameDrawTopicsOut Current() == nil")
+		return nil
+	}
+
+	return topicNames(cfg.Rocketmq.GameDrawTopicsOut)
+}
+
+/**
+ * GetBetConfirmTopicsOut
+ * 获取提交注单分片主题列表 列表长度即提交注单分片数
+ *
+ * @return []string - 提交注单分片主题名列表
+ */
+
+func GetBetConfirmTopicsOut() []string {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetBetConfirmTopicsOut Current() == nil")
+		return nil
+	}
+
+	return topicNames(cfg.Rocketmq.BetConfirmTopicsOut)
+}
+
+// topicNames 提取主题名 忽略空主题名
+func topicNames(items []TopicConfigItem) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		if len(item.TopicName) == 0 {
+			continue
+		}
+		names = append(names, item.TopicName)
+	}
+
+	return names
+}
+
 // GetHttpConnectTimeout 获取http连接超时时间单位秒
 func GetHttpConnectTimeout() time.Duration {
-	if ServerConf == nil {
-		trace.Error("GetHttpConnectTimeout ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetHttpConnectTimeout Current() == nil")
 		return 5
 	}
 
-	connectTimeout := ServerConf.Http.HttpConnectTimeout
+	connectTimeout := cfg.Http.HttpConnectTimeout
 	if connectTimeout <= 0 {
 		connectTimeout = 5
 	}
@@ -587,12 +770,13 @@ func GetHttpConnectTimeout() time.Duration {
 
 // GetHttpReadWriteTimeout 获取http连接超时时间单位秒
 func GetHttpReadWriteTimeout() time.Duration {
-	if ServerConf == nil {
-		trace.Error("GetHttpReadWriteTimeout ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetHttpReadWriteTimeout Current() == nil")
 		return 5
 	}
 
-	readWriteTimeout := ServerConf.Http.HttpReadWriteTimeout
+	readWriteTimeout := cfg.Http.HttpReadWriteTimeout
 	if readWriteTimeout <= 0 {
 		readWriteTimeout = 5
 	}
@@ -609,13 +793,14 @@ const (
 
 // GetUserLimitSwitch 获取个人限红校验开关 返回是否校验个人限红
 func GetUserLimitSwitch() bool {
-	if ServerConf == nil {
-		trace.Error("GetUserLimitSwitch ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetUserLimitSwitch Current() == nil")
 		return false
 	}
 
 	bIsOn := false
-	if switchOn == Switch(ServerConf.Common.UserLimitSwitch) {
+	if switchOn == Switch(cfg.Common.UserLimitSwitch) {
 		bIsOn = true
 	}
 
@@ -646,13 +831,14 @@ const (
 
 func GetLogLevel() int {
 	level := trace.LevelInformational
-	if ServerConf == nil {
-		trace.Error("GetLogLevel ServerConf == nil")
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetLogLevel Current() == nil")
 		return level
 	}
-	trace.Info("GetLogLevel log level=%v", ServerConf.Common.LogLevel)
+	trace.Info("GetLogLevel log level=%v", cfg.Common.LogLevel)
 
-	switch LogLevel(ServerConf.Common.LogLevel) {
+	switch LogLevel(cfg.Common.LogLevel) {
 	case LogLevelEmergency:
 		level = trace.LevelEmergency
 	case LogLevelAlert:
@@ -670,8 +856,227 @@ func GetLogLevel() int {
 	case LogLevelDebug:
 		level = trace.LevelDebug
 	default:
-		trace.Error("GetLogLevel no log level=%v", ServerConf.Common.LogLevel)
+		trace.Error("GetLogLevel no log level=%v", cfg.Common.LogLevel)
 	}
 
 	return level
 }
+
+const defaultDataSourceReplayWindow = 300 //数据源时间戳默认允许偏差300秒
+
+// GetDataSourceSignEnable 获取数据源签名校验开关
+func GetDataSourceSignEnable() bool {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetDataSourceSignEnable Current() == nil")
+		return false
+	}
+	return cfg.DataSource.SignEnable
+}
+
+// GetDataSourceReplayWindow 获取数据源时间戳允许的偏差 未配置时默认300秒
+func GetDataSourceReplayWindow() time.Duration {
+	window := defaultDataSourceReplayWindow
+	cfg := Current()
+	if cfg != nil && cfg.DataSource.ReplayWindow > 0 {
+		window = cfg.DataSource.ReplayWindow
+	}
+	return time.Duration(window) * time.Second
+}
+
+/**
+ * GetDataSourceSecret
+ * 获取数据源签名密钥 nacos配置变更时会重新解析配置 这里加锁读取
+ *
+ * @param sourceId string - 数据源Id
+ * @return string - 密钥
+ * @return bool - 数据源是否存在
+ */
+
+func GetDataSourceSecret(sourceId string) (string, bool) {
+	cfg := Current()
+	if cfg == nil || len(sourceId) == 0 {
+		return "", false
+	}
+
+	for _, source := range cfg.DataSource.Sources {
+		if source.SourceId == sourceId && len(source.Secret) > 0 {
+			return source.Secret, true
+		}
+	}
+	return "", false
+}
+
+// GetPlayerAuth 获取玩家身份校验配置 返回配置副本
+func GetPlayerAuth() PlayerAuth {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetPlayerAuth Current() == nil")
+		return PlayerAuth{}
+	}
+
+	return cfg.PlayerAuth
+}
+
+/**
+ * GetOutbox
+ * 获取发件箱配置 未配置的字段使用默认值
+ *
+ * @return Outbox - 发件箱配置副本
+ */
+
+func GetOutbox() Outbox {
+	outbox := Outbox{}
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetOutbox Current() == nil")
+	} else {
+		outbox = cfg.Outbox
+	}
+
+	outbox.Store = strings.ToLower(strings.TrimSpace(outbox.Store))
+	if len(outbox.Store) == 0 {
+		outbox.Store = "db"
+	}
+	if outbox.PollInterval <= 0 {
+		outbox.PollInterval = 1000
+	}
+	if outbox.BatchSize <= 0 {
+		outbox.BatchSize = 100
+	}
+	if outbox.MaxRetries <= 0 {
+		outbox.MaxRetries = 20
+	}
+	if outbox.RetryInterval <= 0 {
+		outbox.RetryInterval = 1000
+	}
+	if outbox.RetryMax <= 0 {
+		outbox.RetryMax = 300
+	}
+
+	return outbox
+}
+
+// GetExecutor 获取协程池配置 没有配置时返回false
+func GetExecutor(name string) (ExecutorItem, bool) {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetExecutor Current() == nil")
+		return ExecutorItem{}, false
+	}
+
+	item, ok := cfg.Executor[name]
+	return item, ok
+}
+
+/**
+ * GetShutdown
+ * 获取优雅关闭配置 未配置的字段使用默认值
+ *
+ * @return Shutdown - 优雅关闭配置副本
+ */
+
+func GetShutdown() Shutdown {
+	shutdown := Shutdown{}
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetShutdown Current() == nil")
+	} else {
+		shutdown = cfg.Shutdown
+	}
+
+	if shutdown.ReadinessDelay < 0 {
+		shutdown.ReadinessDelay = 0
+	}
+	if shutdown.Timeout <= 0 {
+		shutdown.Timeout = 10
+	}
+
+	return shutdown
+}
+
+// GetHealth 获取健康检查配置 未配置时缓存1秒 超时2秒
+func GetHealth() Health {
+	health := Health{}
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetHealth Current() == nil")
+	} else {
+		health = cfg.Health
+	}
+
+	if health.CacheTtl <= 0 {
+		health.CacheTtl = 1000
+	}
+	if health.Timeout <= 0 {
+		health.Timeout = 2000
+	}
+
+	return health
+}
+
+// GetDrawSize 获取开奖分片大小
+func GetDrawSize() int {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetDrawSize Current() == nil")
+		return 0
+	}
+
+	return cfg.Common.DrawSize
+}
+
+// GetBetConfirmSize 获取提交注单分片大小
+func GetBetConfirmSize() int {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetBetConfirmSize Current() == nil")
+		return 0
+	}
+
+	return cfg.Common.BetConfirmSIze
+}
+
+// GetDynamicOddsEnable 获取动态赔率开关
+func GetDynamicOddsEnable() bool {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetDynamicOddsEnable Current() == nil")
+		return false
+	}
+
+	return cfg.GameConfig.DynamicOddsEnable
+}
+
+// GetAgentToken 获取跟能力中台交互使用的Token
+func GetAgentToken() string {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetAgentToken Current() == nil")
+		return ""
+	}
+
+	return cfg.AgentToken
+}
+
+// GetBeeGoConfig 获取beego配置 返回配置副本
+func GetBeeGoConfig() BeeGoConfig {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetBeeGoConfig Current() == nil")
+		return BeeGoConfig{}
+	}
+
+	return cfg.BeegoCFG
+}
+
+// GetRocketMQ 获取消息队列配置 返回配置副本 主题列表不能修改
+func GetRocketMQ() Rocket {
+	cfg := Current()
+	if cfg == nil {
+		trace.Error("GetRocketMQ Current() == nil")
+		return Rocket{}
+	}
+
+	return cfg.Rocketmq
+}
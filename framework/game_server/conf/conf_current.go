@@ -0,0 +1,275 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	"gopkg.in/yaml.v2"
+	"os"
+	"reflect"
+	"sl.framework.com/trace"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	配置快照
+	nacos推送的配置先解析到新的Configuration中 校验通过后整体原子替换 校验失败则保留之前的配置
+	Current返回的快照是只读的 读取时不需要加锁 修改配置只能通过nacos推送或者SetServerId
+	替换成功后按一级配置分区(yaml字段名)比较 有变化的分区通知订阅者
+*/
+
+// Section 配置分区 对应Configuration中一级字段的yaml名字
+type Section string
+
+const (
+	SectionRedis      Section = "redis"
+	SectionDatabase   Section = "database"
+	SectionPlatform   Section = "platform"
+	SectionCommon     Section = "common"
+	SectionRocketmq   Section = "rocketmq"
+	SectionBeego      Section = "beego"
+	SectionHttp       Section = "http"
+	SectionDataSource Section = "dataSource"
+	SectionPlayerAuth Section = "playerAuth"
+	SectionOutbox     Section = "outbox"
+	SectionGameConfig Section = "gameConfig"
+)
+
+/**
+ * ChangeListener
+ * 配置分区变化回调 在nacos推送协程中同步调用 不能阻塞
+ *
+ * @param old *Configuration - 变化前的配置快照
+ * @param new *Configuration - 变化后的配置快照
+ */
+
+type ChangeListener func(old, new *Configuration)
+
+var (
+	currentConf atomic.Pointer[Configuration]
+
+	listenersMutex sync.Mutex
+	listeners      = make(map[Section][]ChangeListener)
+)
+
+func init() {
+	//日志级别变化时重新设置日志打印级别
+	OnChange(SectionCommon, func(old, new *Configuration) {
+		if old.Common.LogLevel != new.Common.LogLevel {
+			trace.SetLevel(GetLogLevel())
+		}
+	})
+}
+
+// Current 当前生效的配置快照 未初始化时返回nil 返回值只读
+func Current() *Configuration {
+	return currentConf.Load()
+}
+
+// SetCurrent 直接替换当前配置 不校验也不通知订阅者 返回之前的配置 供测试使用
+func SetCurrent(cfg *Configuration) *Configuration {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	return currentConf.Swap(cfg)
+}
+
+// OnChange 订阅配置分区变化
+func OnChange(section Section, listener ChangeListener) {
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+	listeners[section] = append(listeners[section], listener)
+}
+
+/**
+ * parseContent
+ * 解析yaml内容 校验通过后替换当前配置并通知有变化的分区的订阅者
+ * 运行时设置的字段(ServerId ConfigFileName AgentToken)从之前的配置继承
+ *
+ * @param content string - yaml内容
+ * @return error - 解析或者校验失败时返回错误 当前配置保持不变
+ */
+
+func parseContent(content string) error {
+	configMutex.Lock()
+	cfg, err := decodeContent(content)
+	if nil != err {
+		configMutex.Unlock()
+		trace.Error("parseContent Unmarshal failed, error=%v", err.Error())
+		return err
+	}
+
+	old := currentConf.Load()
+	if old != nil {
+		cfg.ServerId = old.ServerId
+		cfg.ConfigFileName = old.ConfigFileName
+		cfg.AgentToken = old.AgentToken
+	}
+	if err := cfg.Validate(); err != nil {
+		configMutex.Unlock()
+		trace.Error("parseContent validate failed, keep previous configuration, error=%v", err.Error())
+		return err
+	}
+	currentConf.Store(cfg)
+	configMutex.Unlock()
+	trace.Info("parseContent success conf=%+v", cfg.Masked())
+
+	if old != nil {
+		notifyChange(old, cfg)
+	}
+
+	return nil
+}
+
+// decodeContent 解析yaml内容并替换其中的环境变量 不校验
+func decodeContent(content string) (*Configuration, error) {
+	cfg := &Configuration{}
+	if err := yaml.Unmarshal([]byte(content), cfg); nil != err {
+		return nil, err
+	}
+	walkConfMember(cfg)
+
+	return cfg, nil
+}
+
+/**
+ * LoadForCheck
+ * 读取配置 供启动自检使用 不校验也不监听nacos配置变化 校验由调用者通过Validate完成
+ * 解析成功后设置为当前配置 校验不通过时也可以继续检测各项依赖的连通性
+ *
+ * @param location ConfigLocation - 配置位置 OnLocalConf时从path读取 OnServerConf时从nacos读取
+ * @param path string - 本地配置文件路径 为空时使用conf/server.yaml
+ * @return *Configuration - 解析后的配置
+ * @return error - 读取或者解析失败
+ */
+
+func LoadForCheck(location ConfigLocation, path string) (*Configuration, error) {
+	var content string
+	switch location {
+	case OnLocalConf:
+		if path == "" {
+			path = localConfPath()
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content = string(data)
+	case OnServerConf:
+		client, dataId, ok := newNacosConfigClient()
+		if !ok {
+			return nil, errors.New("create nacos config client failed")
+		}
+		data, err := client.GetConfig(vo.ConfigParam{DataId: dataId, Group: nacosGroup})
+		if err != nil {
+			return nil, err
+		}
+		content = data
+	default:
+		return nil, fmt.Errorf("unknown config location=%v", location)
+	}
+
+	cfg, err := decodeContent(content)
+	if err != nil {
+		return nil, err
+	}
+	SetCurrent(cfg)
+
+	return cfg, nil
+}
+
+/**
+ * changedSections
+ * 比较两个配置 返回有变化的一级分区 按Configuration中字段顺序
+ *
+ * @param old *Configuration - 变化前的配置
+ * @param new *Configuration - 变化后的配置
+ * @return []Section - 有变化的分区
+ */
+
+func changedSections(old, new *Configuration) []Section {
+	oldVal, newVal := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	sections := make([]Section, 0)
+	for i := 0; i < oldVal.NumField(); i++ {
+		field := oldVal.Type().Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || field.Type.Kind() != reflect.Struct {
+			continue
+		}
+		if !reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			sections = append(sections, Section(name))
+		}
+	}
+
+	return sections
+}
+
+// notifyChange 通知有变化的分区的订阅者
+func notifyChange(old, new *Configuration) {
+	sections := changedSections(old, new)
+	if len(sections) == 0 {
+		return
+	}
+	trace.Notice("configuration changed, sections=%v", sections)
+
+	for _, section := range sections {
+		listenersMutex.Lock()
+		sectionListeners := listeners[section]
+		listenersMutex.Unlock()
+		for _, listener := range sectionListeners {
+			notifyListener(section, listener, old, new)
+		}
+	}
+}
+
+// notifyListener 调用订阅者 订阅者panic不影响其他订阅者
+func notifyListener(section Section, listener ChangeListener, old, new *Configuration) {
+	defer func() {
+		if err := recover(); err != nil {
+			trace.Error("configuration change listener panic, section=%v, err=%v", section, err)
+		}
+	}()
+	listener(old, new)
+}
+
+const maskedValue = "******"
+
+/**
+ * Masked
+ * 复制一份配置 带mask:"true"标签的字段替换为****** 用于日志和运维接口输出
+ *
+ * @return *Configuration - 屏蔽敏感信息后的配置
+ */
+
+func (c *Configuration) Masked() *Configuration {
+	if c == nil {
+		return nil
+	}
+	masked := *c
+	maskMember(reflect.ValueOf(&masked).Elem())
+
+	return &masked
+}
+
+// maskMember 遍历结构体成员屏蔽敏感字段 切片先复制再修改 不影响原配置
+func maskMember(val reflect.Value) {
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		switch {
+		case field.Kind() == reflect.String && val.Type().Field(i).Tag.Get("mask") == "true":
+			if field.String() != "" {
+				field.SetString(maskedValue)
+			}
+		case field.Kind() == reflect.Struct:
+			maskMember(field)
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct && !field.IsNil():
+			copied := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(copied, field)
+			for j := 0; j < copied.Len(); j++ {
+				maskMember(copied.Index(j))
+			}
+			field.Set(copied)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseContentHotReload(t *testing.T) {
+	old := SetCurrent(nil)
+	defer SetCurrent(old)
+
+	base := `
+common:
+  logLevel: Debug
+  userLimitSwitch: on
+  drawSize: 100
+http:
+  httpConnectTimeout: 3
+redis:
+  password: redis-pass
+dataSource:
+  sources:
+    - sourceId: "dealer"
+      secret: "dealer-secret"
+`
+	if err := parseContent(withLocalConf(t, base)); err != nil {
+		t.Fatalf("parseContent() error = %v", err)
+	}
+	SetServerId(12)
+	first := Current()
+
+	var (
+		httpChanges   []int
+		commonChanges int
+	)
+	OnChange(SectionHttp, func(old, new *Configuration) {
+		httpChanges = append(httpChanges, new.Http.HttpConnectTimeout)
+	})
+	OnChange(SectionCommon, func(old, new *Configuration) { commonChanges++ })
+	OnChange(SectionHttp, func(old, new *Configuration) { panic("listener panic") })
+
+	//只通知有变化的分区 运行时设置的server id保留
+	changed := `
+common:
+  logLevel: Debug
+  userLimitSwitch: on
+  drawSize: 100
+http:
+  httpConnectTimeout: 5
+redis:
+  password: redis-pass
+dataSource:
+  sources:
+    - sourceId: "dealer"
+      secret: "dealer-secret"
+`
+	if err := parseContent(withLocalConf(t, changed)); err != nil {
+		t.Fatalf("parseContent() changed error = %v", err)
+	}
+	if !reflect.DeepEqual(httpChanges, []int{5}) || commonChanges != 0 {
+		t.Fatalf("listeners httpChanges=%v, commonChanges=%v, want [5], 0", httpChanges, commonChanges)
+	}
+	if GetServerId() != 12 || first.Http.HttpConnectTimeout != 3 {
+		t.Fatalf("serverId=%v, old snapshot timeout=%v, want 12, 3", GetServerId(), first.Http.HttpConnectTimeout)
+	}
+
+	//校验失败时保留之前的配置 不通知订阅者
+	invalid := `
+common:
+  logLevel: Verbose
+  userLimitSwitch: maybe
+http:
+  httpConnectTimeout: 9
+`
+	if err := parseContent(withLocalConf(t, invalid)); err == nil {
+		t.Fatalf("parseContent() invalid error = nil")
+	}
+	if GetHttpConnectTimeout().Seconds() != 5 || !GetUserLimitSwitch() || len(httpChanges) != 1 {
+		t.Fatalf("invalid update applied, timeout=%v, httpChanges=%v", GetHttpConnectTimeout(), httpChanges)
+	}
+	if err := parseContent("common: [\n"); err == nil || GetDrawSize() != 100 {
+		t.Fatalf("parseContent() broken yaml error = %v, drawSize=%v", err, GetDrawSize())
+	}
+
+	//屏蔽敏感字段 不影响当前配置
+	masked := Current().Masked()
+	if masked.RedisInfo.Password != maskedValue || masked.DataSource.Sources[0].Secret != maskedValue {
+		t.Fatalf("Masked() = %+v, want secrets masked", masked)
+	}
+	if Current().RedisInfo.Password != "redis-pass" || Current().DataSource.Sources[0].Secret != "dealer-secret" {
+		t.Fatalf("Masked() modified current configuration")
+	}
+	if masked.Common.DrawSize != 100 || masked.ServerId != 12 {
+		t.Fatalf("Masked() lost fields, drawSize=%v, serverId=%v", masked.Common.DrawSize, masked.ServerId)
+	}
+}
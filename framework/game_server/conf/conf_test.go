@@ -3,6 +3,7 @@ package conf
 import (
 	"log"
 	"testing"
+	"time"
 )
 
 func Test_parseConfLine(t *testing.T) {
@@ -25,3 +26,37 @@ func Test_parseConfLine(t *testing.T) {
 		})
 	}
 }
+
+func TestParseContentDataSource(t *testing.T) {
+	t.Setenv("TEST_DATA_SOURCE_SECRET", "s3cret")
+	SetCurrent(nil)
+	defer SetCurrent(nil)
+
+	content := `
+dataSource:
+  signEnable: true
+  sources:
+    - sourceId: "dealer"
+      secret: ${TEST_DATA_SOURCE_SECRET}
+    - sourceId: "empty"
+      secret: ""
+`
+	if err := parseContent(withLocalConf(t, content)); err != nil {
+		t.Fatalf("parseContent() error = %v", err)
+	}
+	if !GetDataSourceSignEnable() {
+		t.Errorf("GetDataSourceSignEnable() = false, want true")
+	}
+	if secret, ok := GetDataSourceSecret("dealer"); !ok || secret != "s3cret" {
+		t.Errorf("GetDataSourceSecret(dealer) = %v, %v, want s3cret, true", secret, ok)
+	}
+	if _, ok := GetDataSourceSecret("empty"); ok {
+		t.Errorf("GetDataSourceSecret(empty) ok = true, want false")
+	}
+	if _, ok := GetDataSourceSecret("unknown"); ok {
+		t.Errorf("GetDataSourceSecret(unknown) ok = true, want false")
+	}
+	if window := GetDataSourceReplayWindow(); window != 300*time.Second {
+		t.Errorf("GetDataSourceReplayWindow() = %v, want 5m", window)
+	}
+}
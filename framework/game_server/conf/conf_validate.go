@@ -0,0 +1,225 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	配置校验
+	校验规则写在配置结构体字段的validate标签中 多个规则用逗号分隔
+		required	字符串去掉空格后不能为空 数字不能为0 切片不能为空
+		min=N		数字不能小于N
+		max=N		数字不能大于N
+		oneof=a b	字符串只能是其中之一 为空时不校验 需要同时配合required
+		hostport	字符串为host:port格式 端口1到65535 为空时不校验
+		url			字符串为http(s)地址或者域名 可以省略http:// 为空时不校验
+	字段之间有关联的规则在validateRelations中校验
+*/
+
+const validateTag = "validate"
+
+/**
+ * Validate
+ * 校验配置 有多个错误时全部返回 每个错误以yaml路径开头 如common.drawSize
+ *
+ * @return error - 校验失败的原因 多个错误用errors.Join合并
+ */
+
+func (c *Configuration) Validate() error {
+	errs := validateMember("", reflect.ValueOf(c).Elem())
+	errs = append(errs, validateRelations(c)...)
+
+	return errors.Join(errs...)
+}
+
+// validateMember 遍历结构体成员按validate标签校验 嵌套结构体和结构体切片 结构体map递归校验
+func validateMember(path string, val reflect.Value) []error {
+	errs := make([]error, 0)
+	for i := 0; i < val.NumField(); i++ {
+		field, fieldType := val.Field(i), val.Type().Field(i)
+		name := strings.Split(fieldType.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			continue
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+
+		if rules := fieldType.Tag.Get(validateTag); rules != "" {
+			for _, rule := range strings.Split(rules, ",") {
+				if err := validateRule(name, field, rule); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+
+		switch {
+		case field.Kind() == reflect.Struct:
+			errs = append(errs, validateMember(name, field)...)
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < field.Len(); j++ {
+				errs = append(errs, validateMember(fmt.Sprintf("%v[%v]", name, j), field.Index(j))...)
+			}
+		case field.Kind() == reflect.Map && field.Type().Elem().Kind() == reflect.Struct:
+			keys := field.MapKeys()
+			sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+			for _, key := range keys {
+				errs = append(errs, validateMember(fmt.Sprintf("%v.%v", name, key), field.MapIndex(key))...)
+			}
+		}
+	}
+
+	return errs
+}
+
+/**
+ * validateRule
+ * 按一条规则校验字段
+ *
+ * @param name string - 字段yaml路径
+ * @param field reflect.Value - 字段值
+ * @param rule string - 规则 如min=1
+ * @return error - 不满足规则时返回错误
+ */
+
+func validateRule(name string, field reflect.Value, rule string) error {
+	ruleName, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+	switch ruleName {
+	case "required":
+		if isZeroValue(field) {
+			return fmt.Errorf("%v is required", name)
+		}
+	case "min", "max":
+		limit, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || !field.CanInt() {
+			return fmt.Errorf("%v has invalid rule %v", name, rule)
+		}
+		if ruleName == "min" && field.Int() < limit {
+			return fmt.Errorf("%v=%v must be >= %v", name, field.Int(), limit)
+		}
+		if ruleName == "max" && field.Int() > limit {
+			return fmt.Errorf("%v=%v must be <= %v", name, field.Int(), limit)
+		}
+	case "oneof":
+		value := strings.TrimSpace(field.String())
+		if value == "" {
+			return nil
+		}
+		for _, option := range strings.Fields(arg) {
+			if strings.EqualFold(value, option) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%v=%v must be one of [%v]", name, value, arg)
+	case "hostport":
+		if value := strings.TrimSpace(field.String()); value != "" && !validHostPort(value) {
+			return fmt.Errorf("%v=%v must be host:port", name, value)
+		}
+	case "url":
+		if value := strings.TrimSpace(field.String()); value != "" && !validHttpUrl(value) {
+			return fmt.Errorf("%v=%v must be a http(s) address", name, value)
+		}
+	default:
+		return fmt.Errorf("%v has unknown rule %v", name, rule)
+	}
+
+	return nil
+}
+
+// isZeroValue 字符串去掉空格后为空 数字为0 切片为空
+func isZeroValue(field reflect.Value) bool {
+	switch field.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(field.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return field.Len() == 0
+	default:
+		return field.IsZero()
+	}
+}
+
+// validHostPort 是否为host:port格式
+func validHostPort(value string) bool {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || host == "" || strings.ContainsAny(host, " /") {
+		return false
+	}
+	iPort, err := strconv.Atoi(port)
+
+	return err == nil && iPort > 0 && iPort <= 65535
+}
+
+// validHttpUrl 是否为http(s)地址 没有协议头时按http://处理
+func validHttpUrl(value string) bool {
+	if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+		value = "http://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Hostname() == "" || strings.ContainsAny(u.Host, " ") {
+		return false
+	}
+	if port := u.Port(); port != "" {
+		if iPort, err := strconv.Atoi(port); err != nil || iPort <= 0 || iPort > 65535 {
+			return false
+		}
+	}
+
+	return true
+}
+
+/**
+ * validateRelations
+ * 校验字段之间有关联的规则
+ *
+ * @param cfg *Configuration - 待校验的配置
+ * @return []error - 校验失败的原因
+ */
+
+func validateRelations(cfg *Configuration) []error {
+	errs := make([]error, 0)
+	if strings.EqualFold(strings.TrimSpace(cfg.Rocketmq.Transport), "rocketmq") ||
+		strings.TrimSpace(cfg.Rocketmq.Transport) == "" {
+		if strings.TrimSpace(cfg.Rocketmq.NameServer) == "" {
+			errs = append(errs, errors.New("rocketmq.nameServer is required for rocketmq transport"))
+		}
+	}
+	for _, topics := range []struct {
+		name  string
+		items []TopicConfigItem
+	}{
+		{"rocketmq.gameDrawTopicsIn", cfg.Rocketmq.GameDrawTopicsIn},
+		{"rocketmq.betConfirmTopicsIn", cfg.Rocketmq.BetConfirmTopicsIn},
+	} {
+		for i, item := range topics.items {
+			if strings.TrimSpace(item.TopicGroup) == "" {
+				errs = append(errs, fmt.Errorf("%v[%v].topicGroup is required for consumer", topics.name, i))
+			}
+		}
+	}
+	if interval, expired := cfg.Common.HeartbeatInterval, cfg.Common.HeartbeatExpired; interval > 0 && expired > 0 && expired <= interval {
+		errs = append(errs, fmt.Errorf("common.heartbeatExpired=%v must be greater than heartbeatInterval=%v", expired, interval))
+	}
+	if cfg.PlayerAuth.Enable {
+		switch cfg.PlayerAuth.Algorithm {
+		case "HS256":
+			if cfg.PlayerAuth.Secret == "" {
+				errs = append(errs, errors.New("playerAuth.secret is required for HS256"))
+			}
+		case "RS256":
+			if cfg.PlayerAuth.PublicKey == "" {
+				errs = append(errs, errors.New("playerAuth.publicKey is required for RS256"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("playerAuth.algorithm=%v must be HS256 or RS256", cfg.PlayerAuth.Algorithm))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,97 @@
+package conf
+
+import (
+	"gopkg.in/yaml.v2"
+	"os"
+	"strings"
+	"testing"
+)
+
+// withLocalConf 以本地server.yaml为基础 覆盖测试需要修改的配置 返回完整的yaml内容
+func withLocalConf(t *testing.T, overlay string) string {
+	t.Helper()
+	t.Setenv("MQ_NAMESERVER", "127.0.0.1:9876")
+
+	data, err := os.ReadFile("server.yaml")
+	if err != nil {
+		t.Fatalf("read server.yaml error = %v", err)
+	}
+	//直接解析到配置结构体 避免on off等值在map中被解析成bool
+	var cfg Configuration
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("unmarshal server.yaml error = %v", err)
+	}
+	if err = yaml.Unmarshal([]byte(overlay), &cfg); err != nil {
+		t.Fatalf("unmarshal overlay error = %v", err)
+	}
+
+	content, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("marshal merged conf error = %v", err)
+	}
+	return string(content)
+}
+
+func TestValidateLocalConf(t *testing.T) {
+	cfg, err := decodeContent(withLocalConf(t, ""))
+	if err != nil {
+		t.Fatalf("decodeContent() error = %v", err)
+	}
+	if err = cfg.Validate(); err != nil {
+		t.Fatalf("server.yaml Validate() error = %v", err)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlay string
+		want    []string //期望包含的错误 为空表示校验通过
+	}{
+		{name: "valid", overlay: "common: {drawSize: 1}"},
+		{name: "draw size zero", overlay: "common: {drawSize: 0, betConfirmSize: -1}",
+			want: []string{"common.drawSize=0 must be >= 1", "common.betConfirmSize=-1 must be >= 1"}},
+		{name: "empty topics", overlay: "rocketmq: {gameDrawTopicsOut: [], betConfirmTopicsIn: [{topicName: bet-confirm-0}]}",
+			want: []string{"rocketmq.gameDrawTopicsOut is required", "rocketmq.betConfirmTopicsIn[0].topicGroup is required for consumer"}},
+		{name: "topic name", overlay: "rocketmq: {gameDrawTopicsIn: [{topicName: ' ', topicGroup: g}]}",
+			want: []string{"rocketmq.gameDrawTopicsIn[0].topicName is required"}},
+		{name: "platform host", overlay: "platform: {host: 'http://bad host', port: 70000}",
+			want: []string{"platform.host=http://bad host must be a http(s) address", "platform.port=70000 must be <= 65535"}},
+		{name: "platform host without scheme", overlay: "platform: {host: 'game-server-nginx:8080'}"},
+		{name: "db host", overlay: "database: {uid: {host: 'mysql'}, gameDb: {host: 'mysql:0'}}",
+			want: []string{"database.uid.host=mysql must be host:port", "database.gameDb.host=mysql:0 must be host:port"}},
+		{name: "switches", overlay: "common: {userLimitSwitch: maybe, logLevel: Verbose}\nredis: {mode: sentinel}",
+			want: []string{"common.userLimitSwitch=maybe", "common.logLevel=Verbose", "redis.mode=sentinel"}},
+		{name: "memory transport", overlay: "rocketmq: {transport: memory, nameServer: ''}"},
+		{name: "heartbeat", overlay: "common: {heartbeatInterval: 5, heartbeatExpired: 5}",
+			want: []string{"common.heartbeatExpired=5 must be greater than heartbeatInterval=5"}},
+		{name: "player auth", overlay: "playerAuth: {enable: true, algorithm: HS256, secret: ''}",
+			want: []string{"playerAuth.secret is required for HS256"}},
+		{name: "executor", overlay: "executor: {receipt: {workers: -1, policy: discard}}",
+			want: []string{"executor.receipt.workers=-1 must be >= 0", "executor.receipt.policy=discard must be one of [reject callerRuns]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := decodeContent(withLocalConf(t, tt.overlay))
+			if err != nil {
+				t.Fatalf("decodeContent() error = %v", err)
+			}
+			err = cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %v", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want contains %v", err, want)
+				}
+			}
+		})
+	}
+}
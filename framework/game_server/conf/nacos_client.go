@@ -1,10 +1,12 @@
 package conf
 
 import (
+	"context"
 	"github.com/nacos-group/nacos-sdk-go/clients"
 	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
 	"github.com/nacos-group/nacos-sdk-go/common/constant"
 	"github.com/nacos-group/nacos-sdk-go/vo"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -28,6 +30,8 @@ const (
 
 	defaultNacosUserName = "nacos" //默认登陆nacos账号
 	defaultNacosPassword = "nacos" //默认登陆nacos密码
+
+	nacosGroup = "DEFAULT_GROUP" //配置所在的nacos分组
 )
 
 /*
@@ -88,6 +92,19 @@ func getDetailInHost(host string) (string, uint64) {
 	return strIp, uint64(iPort)
 }
 
+// localConfFile 本地配置文件相对于工作目录的路径
+var localConfFile = filepath.Join("framework", "game_server", "conf", "server.yaml")
+
+// localConfPath 本地配置文件路径 获取工作目录失败时返回相对路径
+func localConfPath() string {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return localConfFile
+	}
+
+	return filepath.Join(workDir, localConfFile)
+}
+
 // 本地文件初始化
 func localConfInit() (isSuccess bool) {
 	var workDir string
@@ -99,8 +116,7 @@ func localConfInit() (isSuccess bool) {
 		return
 	}
 
-	fileName := "server.yaml"
-	configPath := filepath.Join(workDir, "framework", "game_server", "conf", fileName)
+	configPath := filepath.Join(workDir, localConfFile)
 	if content, err = os.ReadFile(configPath); err != nil {
 		trace.Error("localConfInit configPath=%v, read file error=%v", configPath, err.Error())
 		return
@@ -116,19 +132,81 @@ func localConfInit() (isSuccess bool) {
 	return
 }
 
-// nacosClientInit 初始化Nacos
+// nacosClientInit 初始化Nacos 读取配置并监听配置变化
 func nacosClientInit() (isSuccess bool) {
-	var strHost, strNamespace, strDataId string
+	var ok bool
+	var strDataId string
+	if configClient, strDataId, ok = newNacosConfigClient(); !ok {
+		return
+	}
+
+	content, err := configClient.GetConfig(vo.ConfigParam{
+		DataId: strDataId,
+		Group:  nacosGroup,
+	})
+	if err != nil {
+		trace.Info("NacosClientInit GetConfig error=%v", err.Error())
+		return
+	}
+	trace.Info("NacosClientInit GetConfig content=\n%v", content)
+	if err = parseContent(content); nil != err {
+		trace.Error("NacosClientInit GetConfig, parse error=%v", err.Error())
+		return
+	}
+
+	err = configClient.ListenConfig(vo.ConfigParam{
+		DataId:   strDataId,
+		Group:    nacosGroup,
+		OnChange: onChangeCallback,
+	})
+
+	isSuccess = true
+	return
+}
+
+/**
+ * ProbeNacos
+ * tcp连接nacos服务器 检测配置中心是否可达 使用本地配置时不检测
+ *
+ * @param ctx context.Context - 连接超时控制
+ * @return error - 连接失败的原因
+ */
+
+func ProbeNacos(ctx context.Context) error {
+	if configClient == nil {
+		return nil
+	}
+	_, strHost := getEnv(nacosRegisterHost)
+	strIp, uiPort := getDetailInHost(strHost)
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(strIp, strconv.FormatUint(uiPort, 10)))
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+/**
+ * newNacosConfigClient
+ * 根据Pod中的环境变量创建nacos配置客户端
+ *
+ * @return config_client.IConfigClient - nacos配置客户端
+ * @return string - 配置文件名字 对应nacos中的DataId
+ * @return bool - 是否创建成功
+ */
+
+func newNacosConfigClient() (client config_client.IConfigClient, strDataId string, isSuccess bool) {
+	var strHost, strNamespace string
 	var isExist bool
 
 	//REGISTER_HOST为Pod中环境变量
 	if isExist, strHost = getEnv(nacosRegisterHost); !isExist {
-		trace.Error("nacosClientInit REGISTER_HOST env not exist")
+		trace.Error("newNacosConfigClient REGISTER_HOST env not exist")
 		return
 	}
 	//NAMESPACE为Pod中环境变量
 	if isExist, strNamespace = getEnv(nacosNameSpace); !isExist {
-		trace.Error("nacosClientInit NAMESPACE env not exist")
+		trace.Error("newNacosConfigClient NAMESPACE env not exist")
 		return
 	}
 	userName := getEnvWithDefault(nacosUserName, "")
@@ -136,7 +214,7 @@ func nacosClientInit() (isSuccess bool) {
 
 	strDataId = GetConfigurationFileName()
 	strIp, uiPort := getDetailInHost(strHost)
-	trace.Info("nacosClientInit nacos host=%v, ip=%v, port=%v, namespace=%v, dataId=%v",
+	trace.Info("newNacosConfigClient nacos host=%v, ip=%v, port=%v, namespace=%v, dataId=%v",
 		strHost, strIp, uiPort, strNamespace, strDataId)
 
 	var err error
@@ -161,7 +239,7 @@ func nacosClientInit() (isSuccess bool) {
 	if len(password) != 0 {
 		cc.Password = password
 	}
-	configClient, err = clients.CreateConfigClient(map[string]interface{}{
+	client, err = clients.CreateConfigClient(map[string]interface{}{
 		"serverConfigs": sc,
 		"clientConfig":  cc,
 	})
@@ -171,32 +249,13 @@ func nacosClientInit() (isSuccess bool) {
 	}
 	trace.Info("NacosClientInit success cc=%+v, sc=%+v", cc, sc)
 
-	content, err := configClient.GetConfig(vo.ConfigParam{
-		DataId: strDataId,
-		Group:  "DEFAULT_GROUP",
-	})
-	if err != nil {
-		trace.Info("NacosClientInit GetConfig error=%v", err.Error())
-		return
-	}
-	trace.Info("NacosClientInit GetConfig content=\n%v", content)
-	if err = parseContent(content); nil != err {
-		trace.Error("NacosClientInit GetConfig, parse error=%v", err.Error())
-	}
-
-	err = configClient.ListenConfig(vo.ConfigParam{
-		DataId:   strDataId,
-		Group:    "DEFAULT_GROUP",
-		OnChange: onChangeCallback,
-	})
-
 	isSuccess = true
 	return
 }
 
 /**
  * onChangeCallback
- * 配置变化时的回调
+ * 配置变化时的回调 校验通过后替换当前配置并通知订阅者
  *
  * @param namespace string - 名字空间
  * @param group string - 组名
@@ -206,17 +265,11 @@ func nacosClientInit() (isSuccess bool) {
  */
 
 func onChangeCallback(namespace, group, dataId, data string) {
-	preLogLevel := ServerConf.Common.LogLevel
-
 	trace.Info("NacosClientInit nacos configuration changed, namespace=%v, group=%v, dataId=%v, data=\n%v",
 		namespace, group, dataId, data)
+	//解析或者校验失败时保留之前的配置 日志级别等变化由订阅者处理 见OnChange
 	if err := parseContent(data); nil != err {
-		trace.Error("NacosClientInit nacos configuration changed, parse error=%v", err.Error())
+		trace.Error("NacosClientInit nacos configuration changed, rejected, error=%v", err.Error())
 		return
 	}
-
-	//读取到nacos配置以后 如果日志级别变化则重新设置日志打印级别
-	if preLogLevel != ServerConf.Common.LogLevel {
-		trace.SetLevel(GetLogLevel())
-	}
 }
@@ -1,14 +1,46 @@
 package snowflaker
 
 import (
-	"github.com/bwmarrin/snowflake"
+	"errors"
+	"fmt"
 	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
 	"sl.framework.com/trace"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+/*
+	雪花算法 41位毫秒时间戳 + 10位节点id + 12位序号 与github.com/bwmarrin/snowflake的默认布局和起始时间一致
+	节点id由base.WorkerLease分配 保证集群内同一时间不会有两个节点使用同一个id
+	时钟回拨时不再退化为时间戳 回拨较小则等待时钟追上 回拨过大则停止生成
+	租约丢失时节点id设置为NoNode 停止生成直到重新持有租约
+	任何情况下都不会用时间戳代替id 停止生成后GetUniqueId返回ErrStopped
+	每次SetNode递增代数 预生成的id带有代数 GetUniqueId丢弃旧代数的id
+*/
+
+const (
+	epoch     = int64(1288834974657) //起始时间(ms)
+	nodeBits  = 10
+	stepBits  = 12
+	MaxNodeId = int64(1)<<nodeBits - 1 //节点id最大值
+	NoNode    = int64(-1)              //没有持有节点id 停止生成id
+	stepMask  = int64(1)<<stepBits - 1
+
+	maxClockRollback = time.Second            //可以等待的最大时钟回拨
+	rollbackRetry    = 100 * time.Millisecond //时钟回拨过大时重试间隔
+)
+
+// ErrClockRollback 时钟回拨超过maxClockRollback
+var ErrClockRollback = errors.New("snowflake clock moved backwards")
+
+// ErrNoNode 没有持有节点id
+var ErrNoNode = errors.New("snowflake no node id")
+
+// ErrStopped 已经调用StopLoop 不再生成id
+var ErrStopped = errors.New("snowflake stopped")
+
 var (
 	snowFlakeOnce     sync.Once
 	snowFlakeInstance *SnowFlake
@@ -16,21 +48,15 @@ var (
 
 // Note:实测结果使用channel方式并不能更快,这种场景下反而慢了点
 
-// GetSnowFlakeInstance 雪花算法实例单例
+// GetSnowFlakeInstance 雪花算法实例单例 节点id取server id 租约分配后通过SetNode更新
 func GetSnowFlakeInstance() *SnowFlake {
 	snowFlakeOnce.Do(func() {
-		// 设置节点ID,可以从0到1023之间选择一个唯一的ID
-		nodeID := conf.GetServerId() % 1024
-		node, err := snowflake.NewNode(nodeID)
-		if err != nil {
-			trace.Error("GetSnowFlakeInstance failed, nodeId=%v, error=%v", nodeID, err.Error())
-			return
-		}
-		snowFlakeInstance = &SnowFlake{
-			node:   node,
-			chId:   make(chan int64, 64), //用有缓冲channel 提前生产Id放入其中 供消息者消费
-			chQuit: make(chan struct{}),
+		nodeID := conf.GetServerId()
+		if nodeID < 0 || nodeID > MaxNodeId {
+			trace.Error("GetSnowFlakeInstance invalid nodeId=%v, use 0 until worker lease is acquired", nodeID)
+			nodeID = 0
 		}
+		snowFlakeInstance = newSnowFlake(nodeID, 0, func() int64 { return time.Now().UnixMilli() })
 		snowFlakeInstance.runningLoop()
 		trace.Info("GetSnowFlakeInstance success, nodeId=%v", nodeID)
 	})
@@ -38,31 +64,109 @@ func GetSnowFlakeInstance() *SnowFlake {
 	return snowFlakeInstance
 }
 
+func newSnowFlake(nodeId, lastMs int64, now func() int64) *SnowFlake {
+	return &SnowFlake{
+		nodeId: nodeId,
+		lastMs: lastMs,
+		now:    now,
+		chId:   make(chan snowId, 64), //用有缓冲channel 提前生产Id放入其中 供消息者消费
+		chQuit: make(chan struct{}),
+	}
+}
+
 // SnowFlake 雪花算法对象
 type SnowFlake struct {
-	node     *snowflake.Node
-	chId     chan int64
+	mutex  sync.Mutex
+	nodeId int64
+	lastMs int64        //上次生成id的时间(ms)
+	step   int64        //同一毫秒内的序号
+	now    func() int64 //当前时间(ms) 测试时替换
+	gen    int64        //节点id代数 每次SetNode递增 持有mutex时修改 原子读取
+
+	chId     chan snowId
 	chQuit   chan struct{}
 	quitOnce sync.Once
 }
 
+// snowId 预生成的id和生成时的节点id代数
+type snowId struct {
+	id  int64
+	gen int64
+}
+
+/**
+ * SetNode
+ * 设置节点id 获得工作节点租约后调用 已经预生成的id会被丢弃
+ * 租约丢失时传NoNode 停止生成id GetUniqueId阻塞到重新设置节点id
+ *
+ * @param nodeId int64 - 节点id 取值0到MaxNodeId或者NoNode
+ * @param lastMs int64 - 该节点id上次生成id的时间(ms) 用于检测重启前后的时钟回拨 没有记录时传0
+ * @return error - 节点id无效或者时钟回拨过大时返回错误
+ */
+
+func (s *SnowFlake) SetNode(nodeId, lastMs int64) error {
+	if nodeId != NoNode && (nodeId < 0 || nodeId > MaxNodeId) {
+		return fmt.Errorf("snowflake invalid nodeId=%v", nodeId)
+	}
+
+	s.mutex.Lock()
+	if now := s.now(); lastMs-now > maxClockRollback.Milliseconds() {
+		s.mutex.Unlock()
+		return fmt.Errorf("%w: nodeId=%v last=%v now=%v", ErrClockRollback, nodeId, lastMs, now)
+	}
+	if nodeId != s.nodeId {
+		s.step = 0
+	}
+	s.nodeId = nodeId
+	if lastMs > s.lastMs {
+		s.lastMs = lastMs
+	}
+	//生成协程可能已经拿着旧节点id的id阻塞在发送上 递增代数后由GetUniqueId丢弃
+	atomic.AddInt64(&s.gen, 1)
+	s.mutex.Unlock()
+
+	//丢弃旧节点id预生成的id
+	for {
+		select {
+		case <-s.chId:
+		default:
+			trace.Info("SnowFlake SetNode success, nodeId=%v, lastMs=%v", nodeId, lastMs)
+			return nil
+		}
+	}
+}
+
+// LastTimestamp 上次生成id的时间(ms) 随租约续期保存
+func (s *SnowFlake) LastTimestamp() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastMs
+}
+
 // runningLoop 启动循环生产Id
 func (s *SnowFlake) runningLoop() {
 	async.AsyncRunCoroutine(func() {
 		trace.Info("SnowFlake runningLoop start")
-	loop:
 		for {
-			select {
-			case s.chId <- s.getSnowId():
-			case _, ok := <-s.chQuit:
-				if !ok {
-					if s.chId != nil {
-						close(s.chId)
-					}
-					s.node = nil
-					trace.Notice("SnowFlake runningLoop stop")
-					break loop
+			id, err := s.nextId()
+			if err != nil {
+				if !errors.Is(err, ErrNoNode) {
+					trace.Error("SnowFlake runningLoop nextId failed, error=%v", err.Error())
 				}
+				select {
+				case <-time.After(rollbackRetry):
+					continue
+				case <-s.chQuit:
+					s.stopped()
+					return
+				}
+			}
+
+			select {
+			case s.chId <- id:
+			case <-s.chQuit:
+				s.stopped()
+				return
 			}
 		}
 	})
@@ -70,33 +174,85 @@ func (s *SnowFlake) runningLoop() {
 	return
 }
 
+func (s *SnowFlake) stopped() {
+	close(s.chId)
+	trace.Notice("SnowFlake runningLoop stop")
+}
+
 // getSnowId 生成唯一id
-func (s *SnowFlake) getSnowId() int64 {
-	if s.node == nil {
-		trace.Info("getSnowId node is nil, return timestamp")
-		return time.Now().Unix()
+func (s *SnowFlake) getSnowId() (int64, error) {
+	sid, err := s.nextId()
+	return sid.id, err
+}
+
+/**
+ * nextId
+ * 生成唯一id 时钟回拨不超过maxClockRollback时等待时钟追上 超过则返回ErrClockRollback
+ *
+ * @return snowId - 唯一id和当前节点id代数
+ * @return error - 没有节点id或者时钟回拨过大时返回错误
+ */
+
+func (s *SnowFlake) nextId() (snowId, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.nodeId == NoNode {
+		return snowId{}, ErrNoNode
+	}
+	now := s.now()
+	if now < s.lastMs {
+		rollback := s.lastMs - now
+		if rollback > maxClockRollback.Milliseconds() {
+			return snowId{}, fmt.Errorf("%w: rollback=%vms", ErrClockRollback, rollback)
+		}
+		trace.Notice("SnowFlake getSnowId clock moved backwards %vms, wait", rollback)
+		for now < s.lastMs {
+			time.Sleep(time.Duration(s.lastMs-now) * time.Millisecond)
+			now = s.now()
+		}
+	}
+
+	if now == s.lastMs {
+		s.step = (s.step + 1) & stepMask
+		if s.step == 0 {
+			//同一毫秒内序号用完 等待下一毫秒
+			for now <= s.lastMs {
+				now = s.now()
+			}
+		}
+	} else {
+		s.step = 0
 	}
+	s.lastMs = now
 
-	return int64(s.node.Generate())
+	return snowId{id: (now-epoch)<<(nodeBits+stepBits) | s.nodeId<<stepBits | s.step, gen: s.gen}, nil
 }
 
-// GetUniqueId 生成唯一id
-func (s *SnowFlake) GetUniqueId() int64 {
-	id, ok := <-s.chId
-	if !ok {
-		trace.Notice("SnowFlake GetUniqueId get from chan failed.")
-		return time.Now().Unix() //失败则返回当前时间戳
+/**
+ * GetUniqueId
+ * 生成唯一id 丢弃旧节点id生成的id 没有节点id时阻塞到重新持有租约
+ *
+ * @return int64 - 唯一id
+ * @return error - 已经停止生成时返回ErrStopped 调用方不能再使用其他值代替id
+ */
+
+func (s *SnowFlake) GetUniqueId() (int64, error) {
+	for {
+		sid, ok := <-s.chId
+		if !ok {
+			return 0, ErrStopped
+		}
+		if sid.gen == atomic.LoadInt64(&s.gen) {
+			return sid.id, nil
+		}
 	}
-	return id
 }
 
 // StopLoop 停止产生Id
 func (s *SnowFlake) StopLoop() {
 	s.quitOnce.Do(func() {
 		trace.Info("SnowFlake StopLoop")
-		if s.chQuit != nil {
-			close(s.chQuit)
-			s.chQuit = nil
-		}
+		close(s.chQuit)
 	})
 }
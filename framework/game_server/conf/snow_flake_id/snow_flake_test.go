@@ -1,9 +1,12 @@
 package snowflaker
 
 import (
+	"errors"
 	"sl.framework.com/tool"
 	"sl.framework.com/trace"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestSnowFlakeGetSnowFlakeId(t *testing.T) {
@@ -23,3 +26,143 @@ func BenchmarkGetSnowFlakeId(b *testing.B) {
 		GetSnowFlakeInstance().getSnowId()
 	}
 }
+
+func TestSnowFlakeUniqueId(t *testing.T) {
+	_ = trace.LoggerInit()
+	var nowMs int64 = 1_700_000_000_000
+	s := newSnowFlake(12, 0, func() int64 { return nowMs })
+
+	//同一毫秒内序号递增 不同节点id不冲突
+	other := newSnowFlake(13, 0, func() int64 { return nowMs })
+	ids := make(map[int64]bool, 2000)
+	for i := 0; i < 1000; i++ {
+		for _, flake := range []*SnowFlake{s, other} {
+			id, err := flake.getSnowId()
+			if err != nil {
+				t.Fatalf("getSnowId() error=%v", err)
+			}
+			if ids[id] {
+				t.Fatalf("getSnowId() duplicate id=%v", id)
+			}
+			ids[id] = true
+		}
+	}
+	id, _ := s.getSnowId()
+	if nodeId := id >> stepBits & MaxNodeId; nodeId != 12 {
+		t.Fatalf("nodeId=%v, want 12", nodeId)
+	}
+	if ms := id>>(nodeBits+stepBits) + epoch; ms != nowMs {
+		t.Fatalf("timestamp=%v, want %v", ms, nowMs)
+	}
+}
+
+func TestSnowFlakeClockRollback(t *testing.T) {
+	_ = trace.LoggerInit()
+	var nowMs int64 = 1_700_000_000_000
+	s := newSnowFlake(1, 0, func() int64 { return nowMs })
+	last, err := s.getSnowId()
+	if err != nil {
+		t.Fatalf("getSnowId() error=%v", err)
+	}
+
+	//回拨超过maxClockRollback时返回错误 不再退化为时间戳
+	nowMs -= 10_000
+	if id, err := s.getSnowId(); !errors.Is(err, ErrClockRollback) {
+		t.Fatalf("getSnowId() after rollback = %v, %v, want ErrClockRollback", id, err)
+	}
+
+	//时钟追上后恢复 生成的id大于回拨前
+	nowMs += 10_001
+	id, err := s.getSnowId()
+	if err != nil || id <= last {
+		t.Fatalf("getSnowId() after recover = %v, %v, want > %v", id, err, last)
+	}
+
+	//重启后节点id上次生成id的时间晚于当前时间 拒绝使用
+	restarted := newSnowFlake(0, 0, func() int64 { return nowMs - 10_000 })
+	if err := restarted.SetNode(1, s.LastTimestamp()); !errors.Is(err, ErrClockRollback) {
+		t.Fatalf("SetNode() = %v, want ErrClockRollback", err)
+	}
+	if err := restarted.SetNode(MaxNodeId+1, 0); err == nil {
+		t.Fatalf("SetNode(%v) want error", MaxNodeId+1)
+	}
+}
+
+func TestSnowFlakeSetNodeBufferFull(t *testing.T) {
+	_ = trace.LoggerInit()
+	var nowMs int64 = 1_700_000_000_000
+	s := newSnowFlake(3, 0, func() int64 { return atomic.AddInt64(&nowMs, 1) })
+	s.runningLoop()
+	defer s.StopLoop()
+
+	//缓冲区写满后生成协程拿着节点3的id阻塞在发送上
+	deadline := time.Now().Add(time.Second)
+	for len(s.chId) < cap(s.chId) {
+		if time.Now().After(deadline) {
+			t.Fatalf("buffer not full, len=%v", len(s.chId))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.SetNode(5, 0); err != nil {
+		t.Fatalf("SetNode() error=%v", err)
+	}
+	for i := 0; i < 2*cap(s.chId); i++ {
+		id, err := s.GetUniqueId()
+		if nodeId := id >> stepBits & MaxNodeId; err != nil || nodeId != 5 {
+			t.Fatalf("GetUniqueId() %v nodeId=%v err=%v, want 5", i, nodeId, err)
+		}
+	}
+
+	//租约丢失后停止生成 重新设置节点id后恢复
+	if err := s.SetNode(NoNode, 0); err != nil {
+		t.Fatalf("SetNode(NoNode) error=%v", err)
+	}
+	chId := make(chan int64, 1)
+	go func() {
+		id, _ := s.GetUniqueId()
+		chId <- id
+	}()
+	select {
+	case id := <-chId:
+		t.Fatalf("GetUniqueId() without node = %v, want blocked", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := s.SetNode(7, 0); err != nil {
+		t.Fatalf("SetNode() error=%v", err)
+	}
+	select {
+	case id := <-chId:
+		if nodeId := id >> stepBits & MaxNodeId; nodeId != 7 {
+			t.Fatalf("GetUniqueId() nodeId=%v, want 7", nodeId)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("GetUniqueId() still blocked after SetNode")
+	}
+}
+
+func TestSnowFlakeStopped(t *testing.T) {
+	s := newSnowFlake(1, 0, func() int64 { return time.Now().UnixMilli() })
+	s.runningLoop()
+	s.StopLoop()
+
+	//停止后缓冲区中剩余的id仍然可用 取完之后返回ErrStopped 不能退化为时间戳
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case <-deadline:
+			t.Fatalf("GetUniqueId() never returned ErrStopped")
+		default:
+		}
+		id, err := s.GetUniqueId()
+		if err == nil {
+			if nodeId := id >> stepBits & MaxNodeId; nodeId != 1 {
+				t.Fatalf("GetUniqueId() nodeId=%v, want 1", nodeId)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrStopped) || id != 0 {
+			t.Fatalf("GetUniqueId() = %v, %v, want 0, ErrStopped", id, err)
+		}
+		return
+	}
+}
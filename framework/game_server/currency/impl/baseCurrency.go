@@ -12,11 +12,23 @@ type BaseCurrency struct {
 }
 
 /**
- * FunctionName
- * 函数功能描述
+ * MinorUnits
+ * 获取币种最小单位对应的小数位数
  *
- * @param PARAM - 参数说明
- * @return RETURN - 返回值说明
+ * @return int - 小数位数
+ */
+
+func (c *BaseCurrency) MinorUnits() int {
+	return c.Length
+}
+
+/**
+ * NewCurrency
+ * 根据币种编码创建货币对象
+ *
+ * @param currency string - 币种编码
+ * @param src float64 - 原始数值
+ * @return intefaces.ICurrency - 货币对象 未知币种返回nil
  */
 
 func NewCurrency(currency string, src float64) intefaces.ICurrency {
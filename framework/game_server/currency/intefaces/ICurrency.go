@@ -2,4 +2,5 @@ package intefaces
 
 type ICurrency interface {
 	CurrencyValue() float64
+	MinorUnits() int //币种最小单位对应的小数位数
 }
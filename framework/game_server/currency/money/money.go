@@ -0,0 +1,320 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"github.com/beego/beego/v2/client/orm"
+	"math"
+	"math/big"
+	"sl.framework.com/game_server/currency/impl"
+	"sl.framework.com/game_server/currency/intefaces"
+	"strconv"
+	"strings"
+)
+
+/*
+	Money 定点数金额
+	以10^-Scale为最小单位的整数存储金额 所有币种共用同一精度 Scale取支持币种中最大的小数位数(USDT 6位)
+	加减与比较均为整数运算 不会出现float64累加产生的分差
+	需要按币种最小单位截取时调用Truncate 截取规则与tool.Trunc一致 向零截取
+	json序列化为不带引号的十进制数字 与原float64字段的线上格式兼容
+*/
+
+type Money struct {
+	value int64 //以10^-Scale为单位的金额
+}
+
+const (
+	Scale = 6 //金额精度 小数点后位数
+
+	unit = int64(1000000) //1.0对应的内部数值 10^Scale
+)
+
+var (
+	// ErrMoneyInvalid 金额格式错误
+	ErrMoneyInvalid = errors.New("money: invalid amount")
+	// ErrMoneyOverflow 金额超出范围
+	ErrMoneyOverflow = errors.New("money: amount overflow")
+
+	bigUnit = big.NewInt(unit)
+)
+
+/**
+ * Parse
+ * 从十进制字符串解析金额 支持科学计数法 超出精度部分向零截取
+ *
+ * @param s string - 十进制字符串 例如"12.34" "-0.5" "1.5E3"
+ * @return Money - 金额
+ * @return error - 格式错误或者超出范围返回错误
+ */
+
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return Money{}, ErrMoneyInvalid
+	}
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrMoneyInvalid, s)
+	}
+	scaled := new(big.Int).Mul(rat.Num(), bigUnit)
+	scaled.Quo(scaled, rat.Denom()) //Quo向零截取
+	if !scaled.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %q", ErrMoneyOverflow, s)
+	}
+	return Money{value: scaled.Int64()}, nil
+}
+
+/**
+ * FromFloat
+ * 从float64构造金额 先取float64的最短十进制表示再解析 避免二进制误差带入金额
+ *
+ * @param f float64 - 浮点金额
+ * @return Money - 金额 NaN和超出范围返回0
+ */
+
+func FromFloat(f float64) Money {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Money{}
+	}
+	m, err := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return Money{}
+	}
+	return m
+}
+
+/**
+ * Add
+ * 金额相加 结果超出int64范围时返回ErrMoneyOverflow 不会回绕
+ *
+ * @param other Money - 加数
+ * @return Money - 和 溢出时返回原金额
+ * @return error - 溢出错误
+ */
+
+func (m Money) Add(other Money) (Money, error) {
+	sum := m.value + other.value
+	//同号相加结果变号即溢出
+	if (other.value > 0 && sum < m.value) || (other.value < 0 && sum > m.value) {
+		return m, fmt.Errorf("%w: %v + %v", ErrMoneyOverflow, m, other)
+	}
+	return Money{value: sum}, nil
+}
+
+/**
+ * Sub
+ * 金额相减 结果超出int64范围时返回ErrMoneyOverflow 不会回绕
+ *
+ * @param other Money - 减数
+ * @return Money - 差 溢出时返回原金额
+ * @return error - 溢出错误
+ */
+
+func (m Money) Sub(other Money) (Money, error) {
+	diff := m.value - other.value
+	if (other.value > 0 && diff > m.value) || (other.value < 0 && diff < m.value) {
+		return m, fmt.Errorf("%w: %v - %v", ErrMoneyOverflow, m, other)
+	}
+	return Money{value: diff}, nil
+}
+
+/**
+ * MulOdds
+ * 金额乘以赔率 供游戏drawer按赔率计算派彩
+ * 赔率以float32的最短十进制表示参与运算 结果向零截取到Scale位
+ *
+ * @param odds float32 - 赔率
+ * @return Money - 乘积
+ * @return error - 赔率为NaN/Inf返回ErrMoneyInvalid 乘积超出范围返回ErrMoneyOverflow
+ */
+
+func (m Money) MulOdds(odds float32) (Money, error) {
+	rat, ok := new(big.Rat).SetString(strconv.FormatFloat(float64(odds), 'f', -1, 32))
+	if !ok {
+		return Money{}, fmt.Errorf("%w: odds %v", ErrMoneyInvalid, odds)
+	}
+	product := new(big.Int).Mul(big.NewInt(m.value), rat.Num())
+	product.Quo(product, rat.Denom())
+	if !product.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %v * %v", ErrMoneyOverflow, m, odds)
+	}
+	return Money{value: product.Int64()}, nil
+}
+
+/**
+ * Cmp
+ * 比较两个金额
+ *
+ * @param other Money - 比较对象
+ * @return int - 小于返回-1 等于返回0 大于返回1
+ */
+
+func (m Money) Cmp(other Money) int {
+	switch {
+	case m.value < other.value:
+		return -1
+	case m.value > other.value:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Sign 金额符号 负数返回-1 零返回0 正数返回1
+func (m Money) Sign() int {
+	return m.Cmp(Money{})
+}
+
+// IsZero 金额是否为零
+func (m Money) IsZero() bool {
+	return m.value == 0
+}
+
+/**
+ * Truncate
+ * 按币种最小单位向零截取 未知币种不截取
+ *
+ * @param currency string - 币种编码 例如USD VND
+ * @return Money - 截取后的金额
+ */
+
+func (m Money) Truncate(currency string) Money {
+	c := impl.NewCurrency(currency, 0)
+	if c == nil {
+		return m
+	}
+	return m.TruncateTo(c)
+}
+
+/**
+ * TruncateTo
+ * 按币种最小单位向零截取
+ *
+ * @param c intefaces.ICurrency - 币种
+ * @return Money - 截取后的金额
+ */
+
+func (m Money) TruncateTo(c intefaces.ICurrency) Money {
+	step := pow10(Scale - minorUnits(c))
+	return Money{value: m.value / step * step}
+}
+
+// Float64 转为float64 仅用于展示或者对接仍使用float64的外部接口
+func (m Money) Float64() float64 {
+	f, _ := strconv.ParseFloat(m.String(), 64)
+	return f
+}
+
+// String 十进制字符串 去掉小数末尾的0
+func (m Money) String() string {
+	var (
+		sign  string
+		value = m.value
+	)
+	if value < 0 {
+		sign = "-"
+	}
+	//取绝对值时使用uint64 避免math.MinInt64溢出
+	abs := uint64(value)
+	if value < 0 {
+		abs = uint64(-(value + 1)) + 1
+	}
+	intPart := abs / uint64(unit)
+	fracPart := abs % uint64(unit)
+	if fracPart == 0 {
+		return sign + strconv.FormatUint(intPart, 10)
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%0*d", Scale, fracPart), "0")
+	return sign + strconv.FormatUint(intPart, 10) + "." + frac
+}
+
+// MarshalJSON 序列化为json数字
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 从json数字或者字符串解析 null保持原值
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	val, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = val
+	return nil
+}
+
+/*
+	以下方法实现beego orm的Fielder接口 Money可以直接作为数据库模型字段
+	写库时以十进制字符串传给驱动 decimal列不会丢失精度
+*/
+
+// FieldType orm字段类型 与原float64字段一致
+func (m Money) FieldType() int {
+	return orm.TypeFloatField
+}
+
+// RawValue 写入数据库的值
+func (m Money) RawValue() interface{} {
+	return m.String()
+}
+
+// SetRaw 从数据库读取的值 驱动可能返回浮点数 整数或者十进制字符串
+func (m *Money) SetRaw(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+	case float64:
+		*m = FromFloat(v)
+	case float32:
+		*m = FromFloat(float64(v))
+	case int64:
+		return m.setString(strconv.FormatInt(v, 10))
+	case int:
+		return m.setString(strconv.Itoa(v))
+	case string:
+		return m.setString(v)
+	case []byte:
+		return m.setString(string(v))
+	default:
+		return fmt.Errorf("%w: unsupported raw type %T", ErrMoneyInvalid, value)
+	}
+	return nil
+}
+
+func (m *Money) setString(s string) error {
+	val, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = val
+	return nil
+}
+
+// minorUnits 币种小数位数 超出范围时按Scale处理
+func minorUnits(c intefaces.ICurrency) int {
+	if c == nil {
+		return Scale
+	}
+	digits := c.MinorUnits()
+	if digits < 0 || digits > Scale {
+		return Scale
+	}
+	return digits
+}
+
+// pow10 10的n次方 n取值[0, Scale]
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
@@ -0,0 +1,153 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		wantErr bool
+	}{
+		{name: "integer", src: "100", want: "100"},
+		{name: "decimal", src: "12.34", want: "12.34"},
+		{name: "negative", src: "-0.5", want: "-0.5"},
+		{name: "exponent", src: "1.5E3", want: "1500"},
+		{name: "truncate", src: "0.1234567", want: "0.123456"},
+		{name: "negativeTruncate", src: "-0.1234567", want: "-0.123456"},
+		{name: "empty", src: "", wantErr: true},
+		{name: "invalid", src: "abc", wantErr: true},
+		{name: "overflow", src: "1e20", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("Parse(%q) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumHasNoFloatDrift(t *testing.T) {
+	//0.1累加10次 float64结果为0.9999999999999999
+	var sumMoney Money
+	for i := 0; i < 10; i++ {
+		var err error
+		if sumMoney, err = sumMoney.Add(FromFloat(0.1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sumMoney.Cmp(FromFloat(1)) != 0 {
+		t.Errorf("sum = %v, want 1", sumMoney)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	amount, _ := Parse("1234.56789")
+	tests := []struct {
+		currency string
+		want     string
+	}{
+		{currency: "USD", want: "1234.56"},
+		{currency: "VND", want: "1234"},
+		{currency: "PHP", want: "1234.5678"},
+		{currency: "USDT", want: "1234.56789"},
+		{currency: "UNKNOWN", want: "1234.56789"},
+	}
+	for _, tt := range tests {
+		if got := amount.Truncate(tt.currency); got.String() != tt.want {
+			t.Errorf("Truncate(%v) = %v, want %v", tt.currency, got, tt.want)
+		}
+	}
+
+	negative, _ := Parse("-1.239")
+	if got := negative.Truncate("USD"); got.String() != "-1.23" {
+		t.Errorf("Truncate(USD) = %v, want -1.23", got)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	maxMoney := Money{value: math.MaxInt64}
+	minMoney := Money{value: math.MinInt64}
+	one := Money{value: 1}
+	if _, err := maxMoney.Add(one); !errors.Is(err, ErrMoneyOverflow) {
+		t.Errorf("max + 1 error = %v, want ErrMoneyOverflow", err)
+	}
+	if _, err := minMoney.Sub(one); !errors.Is(err, ErrMoneyOverflow) {
+		t.Errorf("min - 1 error = %v, want ErrMoneyOverflow", err)
+	}
+	if _, err := one.Sub(minMoney); !errors.Is(err, ErrMoneyOverflow) {
+		t.Errorf("1 - min error = %v, want ErrMoneyOverflow", err)
+	}
+	if _, err := maxMoney.MulOdds(2); !errors.Is(err, ErrMoneyOverflow) {
+		t.Errorf("max * 2 error = %v, want ErrMoneyOverflow", err)
+	}
+	if got, err := maxMoney.Sub(maxMoney); err != nil || !got.IsZero() {
+		t.Errorf("max - max = %v, %v, want 0", got, err)
+	}
+}
+
+func TestMulOdds(t *testing.T) {
+	amount, _ := Parse("100")
+	if got, err := amount.MulOdds(0.95); err != nil || got.String() != "95" {
+		t.Errorf("MulOdds(0.95) = %v, %v, want 95", got, err)
+	}
+	amount, _ = Parse("33.33")
+	if got, err := amount.MulOdds(11); err != nil || got.String() != "366.63" {
+		t.Errorf("MulOdds(11) = %v, %v, want 366.63", got, err)
+	}
+	if _, err := amount.MulOdds(float32(math.NaN())); !errors.Is(err, ErrMoneyInvalid) {
+		t.Errorf("MulOdds(NaN) error = %v, want ErrMoneyInvalid", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type order struct {
+		BetAmount Money `json:"betAmount"`
+		WinAmount Money `json:"winAmount"`
+	}
+
+	src := `{"betAmount":10.25,"winAmount":"20.5"}`
+	dst := new(order)
+	if err := json.Unmarshal([]byte(src), dst); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if dst.BetAmount.String() != "10.25" || dst.WinAmount.String() != "20.5" {
+		t.Errorf("json.Unmarshal() = %+v", dst)
+	}
+
+	data, err := json.Marshal(dst)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `{"betAmount":10.25,"winAmount":20.5}` {
+		t.Errorf("json.Marshal() = %s", data)
+	}
+}
+
+func TestOrmFielder(t *testing.T) {
+	var m Money
+	for _, raw := range []interface{}{12.34, "12.34", []byte("12.34")} {
+		if err := m.SetRaw(raw); err != nil || m.String() != "12.34" {
+			t.Errorf("SetRaw(%#v) = %v, %v, want 12.34", raw, m, err)
+		}
+	}
+	if err := m.SetRaw(int64(7)); err != nil || m.String() != "7" {
+		t.Errorf("SetRaw(int64) = %v, %v, want 7", m, err)
+	}
+	if err := m.SetRaw(true); !errors.Is(err, ErrMoneyInvalid) {
+		t.Errorf("SetRaw(bool) error = %v, want ErrMoneyInvalid", err)
+	}
+	if m.RawValue() != "7" {
+		t.Errorf("RawValue = %#v, want \"7\"", m.RawValue())
+	}
+}
@@ -22,10 +22,11 @@ func GetErrMsg(errCode int) string {
 const (
 	MaxLoopCount = 1000 //查询UniqueId最大尝试次数
 
-	ErrorInvalid     = -1   //无效小数值
-	ErrorOk          = 0    //统一为没有错误
-	ErrorSettleEmpty = 1    //结算注单为零
-	ErrorUnknown     = 7000 //统一为内部错误
+	ErrorInvalid        = -1   //无效小数值
+	ErrorOk             = 0    //统一为没有错误
+	ErrorSettleEmpty    = 1    //结算注单为零
+	ErrorResettleAdjust = 2    //重新结算有注单补差没有成功 需要重新投递
+	ErrorUnknown        = 7000 //统一为内部错误
 )
 
 /*
@@ -75,7 +76,7 @@ const (
 	HttpErrorOrderParse                = iota + 8020 //订单解析错误
 	HttpErrorDataFailed                              //数据异常
 	HttpErrorInvalidParam                            //无效的参数
-	HttpErrorPlatformReply                           //平台中心返回错误码
+	HttpErrorPlatformReply                           //平台中心返回业务错误码 重试不会成功
 	HttpErrorServerReply                             //服务器返回错误
 	HttpErrorPlatformPost                            //向平台中心发送信息错误
 	HttpErrorPlatFormBuildWorkerFailed               //向平台中心发送创建员工信息返回失败
@@ -114,9 +115,27 @@ const (
 	GameErrorWrongGameId                           //错误的游戏Id
 	GameErrorBettorNotExist                        //下注对象为注册
 	GameErrorGameEventExist                        //游戏事件已存在
+	GameErrorSettleShardBusy                       //开奖分片正在结算
+	GameErrorBetConfirmPending                     //注单扣款结果未知
+	GameErrorServerStopping                        //服务正在关闭
 
 )
 
+/* 鉴权相关错误 [8090, 8109] 包括数据源签名以及玩家token */
+const (
+	AuthErrorSignatureMissing  = iota + 8090 //缺少签名相关的请求头
+	AuthErrorSourceUnknown                   //未知的数据源
+	AuthErrorTimestampExpired                //时间戳超出允许范围
+	AuthErrorSignatureInvalid                //签名错误
+	AuthErrorNonceReplayed                   //nonce重复 重放请求
+	AuthErrorNonceCheckFailed                //nonce校验失败 redis异常
+	AuthErrorTokenMissing                    //缺少玩家token
+	AuthErrorTokenInvalid                    //玩家token无效
+	AuthErrorTokenExpired                    //玩家token已过期
+	AuthErrorPrincipalMismatch               //token中的玩家信息与请求不一致
+	AuthErrorSessionInvalid                  //玩家会话不存在或者已失效
+)
+
 func init() {
 	bacErrorMap = make(map[int]string, 32)
 	bacErrorMap[ErrorOk] = "success"
@@ -171,6 +190,22 @@ func init() {
 	bacErrorMap[GameErrorWrongGameId] = "wrong game id"                            //错误的游戏Id
 	bacErrorMap[GameErrorBettorNotExist] = "bettor not exist"                      //下注对象不存在
 	bacErrorMap[GameErrorGameEventExist] = "The game event exist"                  //游戏事件已存在
+	bacErrorMap[GameErrorSettleShardBusy] = "settle shard is processing"           //开奖分片正在结算
+	bacErrorMap[GameErrorBetConfirmPending] = "bet confirm result is pending"      //注单扣款结果未知
+	bacErrorMap[GameErrorServerStopping] = "server is stopping"                    //服务正在关闭
+
+	//数据源鉴权相关错误
+	bacErrorMap[AuthErrorSignatureMissing] = "signature headers missing" //缺少签名相关的请求头
+	bacErrorMap[AuthErrorSourceUnknown] = "unknown data source"          //未知的数据源
+	bacErrorMap[AuthErrorTimestampExpired] = "timestamp expired"         //时间戳超出允许范围
+	bacErrorMap[AuthErrorSignatureInvalid] = "signature invalid"         //签名错误
+	bacErrorMap[AuthErrorNonceReplayed] = "nonce replayed"               //nonce重复
+	bacErrorMap[AuthErrorNonceCheckFailed] = "nonce check failed"        //nonce校验失败
+	bacErrorMap[AuthErrorTokenMissing] = "token missing"                 //缺少玩家token
+	bacErrorMap[AuthErrorTokenInvalid] = "token invalid"                 //玩家token无效
+	bacErrorMap[AuthErrorTokenExpired] = "token expired"                 //玩家token已过期
+	bacErrorMap[AuthErrorPrincipalMismatch] = "principal mismatch"       //token中的玩家信息与请求不一致
+	bacErrorMap[AuthErrorSessionInvalid] = "session invalid"             //玩家会话不存在或者已失效
 
 	//结算相关错误
 	bacErrorMap[ValidateErrorResultParseFailed] = "result parse failed" //result 解析错误
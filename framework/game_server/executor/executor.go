@@ -0,0 +1,191 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sl.framework.com/async"
+	"sl.framework.com/game_server/metrics"
+	"sl.framework.com/trace"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	有界协程池
+	参考base.GoPool 固定数量的worker从有界队列中取任务执行 队列满时按拒绝策略处理
+		reject		丢弃任务 返回ErrRejected 由调用方记录或者补偿
+		callerRuns	在调用方协程中直接执行 调用方变慢从而对上游形成背压 任务不会丢失
+	Drain之后不再接收新任务 callerRuns的任务在调用方执行 reject的任务返回ErrStopped
+*/
+
+const (
+	PolicyReject     = "reject"
+	PolicyCallerRuns = "callerRuns"
+)
+
+var (
+	ErrRejected = errors.New("executor queue is full")
+	ErrStopped  = errors.New("executor is stopped")
+)
+
+// 任务执行结果 用于metrics
+const (
+	resultDone       = "done"
+	resultPanic      = "panic"
+	resultRejected   = "rejected"
+	resultCallerRuns = "caller_runs"
+)
+
+// Options 协程池配置
+type Options struct {
+	Workers   int    //worker数量
+	QueueSize int    //等待队列长度
+	Policy    string //队列满时的处理策略
+}
+
+// Executor 有名字的有界协程池
+type Executor struct {
+	name    string
+	policy  string
+	tasks   chan func()
+	pending sync.WaitGroup //已经提交还没有执行完的任务 包括队列中的任务
+
+	mu      sync.RWMutex //保护stopped 关闭队列时不能有任务正在入队
+	stopped bool
+}
+
+/**
+ * New
+ * 创建协程池并启动worker
+ *
+ * @param name string - 协程池名称 作为metrics的label
+ * @param opts Options - 协程池配置 非法值使用默认值
+ * @return *Executor - 协程池
+ */
+
+func New(name string, opts Options) *Executor {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultOptions.Workers
+	}
+	if opts.QueueSize < 0 {
+		opts.QueueSize = defaultOptions.QueueSize
+	}
+	if strings.EqualFold(opts.Policy, PolicyReject) {
+		opts.Policy = PolicyReject
+	} else {
+		opts.Policy = PolicyCallerRuns
+	}
+
+	e := &Executor{name: name, policy: opts.Policy, tasks: make(chan func(), opts.QueueSize)}
+	for i := 0; i < opts.Workers; i++ {
+		go e.worker()
+	}
+	trace.Info("executor %v start, workers=%v, queueSize=%v, policy=%v", name, opts.Workers, opts.QueueSize, opts.Policy)
+	return e
+}
+
+// Name 协程池名称
+func (e *Executor) Name() string {
+	return e.name
+}
+
+func (e *Executor) worker() {
+	for fn := range e.tasks {
+		metrics.SetExecutorQueueLength(e.name, len(e.tasks))
+		e.run(fn)
+		e.pending.Done()
+	}
+}
+
+// run 执行任务 任务panic不会导致worker退出
+func (e *Executor) run(fn func()) {
+	metrics.AddExecutorActive(e.name, 1)
+	defer metrics.AddExecutorActive(e.name, -1)
+
+	result := resultPanic
+	func() {
+		defer async.TryException()
+		fn()
+		result = resultDone
+	}()
+	metrics.IncExecutorTask(e.name, result)
+}
+
+/**
+ * Submit
+ * 提交任务 队列满或者已经停止时按拒绝策略处理
+ *
+ * @param fn func() - 任务
+ * @return error - reject策略下队列满返回ErrRejected 已经停止返回ErrStopped
+ */
+
+func (e *Executor) Submit(fn func()) error {
+	//pending.Add在读锁内调用 保证Drain开始等待之后不会再增加计数
+	e.mu.RLock()
+	stopped := e.stopped
+	if !stopped {
+		e.pending.Add(1)
+		select {
+		case e.tasks <- fn:
+			e.mu.RUnlock()
+			metrics.SetExecutorQueueLength(e.name, len(e.tasks))
+			return nil
+		default:
+			if e.policy == PolicyReject {
+				e.pending.Done()
+			}
+		}
+	}
+	e.mu.RUnlock()
+
+	if e.policy == PolicyReject {
+		metrics.IncExecutorTask(e.name, resultRejected)
+		if stopped {
+			return ErrStopped
+		}
+		trace.Notice("executor %v queue is full, task rejected", e.name)
+		return ErrRejected
+	}
+
+	//队列满时在调用方执行 已经停止时也在调用方执行 停止后的任务由调用方自己等待
+	metrics.IncExecutorTask(e.name, resultCallerRuns)
+	e.run(fn)
+	if !stopped {
+		e.pending.Done()
+	}
+	return nil
+}
+
+/**
+ * Drain
+ * 停止接收新任务 等待已提交的任务执行完毕
+ *
+ * @param ctx context.Context - 等待超时控制
+ * @return error - 超时前没有执行完返回ctx.Err()
+ */
+
+func (e *Executor) Drain(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.stopped {
+		e.stopped = true
+		close(e.tasks)
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.pending.Wait()
+		close(done)
+	}()
+
+	start := time.Now()
+	select {
+	case <-done:
+		trace.Info("executor %v drained, elapse=%v", e.name, time.Since(start))
+		return nil
+	case <-ctx.Done():
+		trace.Error("executor %v drain timeout, queued=%v, error=%v", e.name, len(e.tasks), ctx.Err().Error())
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecutorBounded(t *testing.T) {
+	e := New("test-bounded", Options{Workers: 2, QueueSize: 100, Policy: PolicyReject})
+
+	var running, peak int32
+	for i := 0; i < 20; i++ {
+		if err := e.Submit(func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	if err := e.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if peak > 2 {
+		t.Fatalf("peak concurrency = %v, want <= 2", peak)
+	}
+}
+
+func TestExecutorPolicy(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{})
+	blocker := func() {
+		started <- struct{}{}
+		<-block
+	}
+
+	reject := New("test-reject", Options{Workers: 1, QueueSize: 1, Policy: PolicyReject})
+	_ = reject.Submit(blocker)
+	<-started
+	if err := reject.Submit(func() {}); err != nil {
+		t.Fatalf("Submit() queued error = %v", err)
+	}
+	if err := reject.Submit(func() {}); !errors.Is(err, ErrRejected) {
+		t.Fatalf("Submit() full error = %v, want ErrRejected", err)
+	}
+
+	callerRuns := New("test-caller-runs", Options{Workers: 1, QueueSize: 1, Policy: PolicyCallerRuns})
+	_ = callerRuns.Submit(blocker)
+	<-started
+	_ = callerRuns.Submit(func() {})
+	ran := false
+	if err := callerRuns.Submit(func() { ran = true }); err != nil || !ran {
+		t.Fatalf("Submit() full error = %v, ran = %v, want run in caller", err, ran)
+	}
+
+	close(block)
+	_ = reject.Drain(context.Background())
+	_ = callerRuns.Drain(context.Background())
+	if err := reject.Submit(func() {}); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Submit() after drain error = %v, want ErrStopped", err)
+	}
+}
+
+func TestExecutorDrain(t *testing.T) {
+	e := New("test-drain", Options{Workers: 1, QueueSize: 10})
+	var done int32
+	for i := 0; i < 5; i++ {
+		_ = e.Submit(func() {
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&done, 1)
+		})
+	}
+	//panic的任务不影响worker继续执行
+	_ = e.Submit(func() { panic("task panic") })
+	_ = e.Submit(func() { atomic.AddInt32(&done, 1) })
+
+	if err := e.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+	if done != 6 {
+		t.Fatalf("done = %v, want 6", done)
+	}
+}
+
+func TestExecutorDrainTimeout(t *testing.T) {
+	e := New("test-drain-timeout", Options{Workers: 1, QueueSize: 1})
+	block := make(chan struct{})
+	defer close(block)
+	_ = e.Submit(func() { <-block })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := e.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestSubmitWait(t *testing.T) {
+	var done int32
+	if err := SubmitWait(PoolDBSave, func() {
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&done, 1)
+	}); err != nil || atomic.LoadInt32(&done) != 1 {
+		t.Fatalf("SubmitWait() error = %v, done = %v", err, done)
+	}
+	//任务panic时同样返回
+	if err := SubmitWait(PoolDBSave, func() { panic("save failed") }); err != nil {
+		t.Fatalf("SubmitWait(panic) error = %v", err)
+	}
+}
+
+func TestDrainAll(t *testing.T) {
+	var wg sync.WaitGroup
+	var done int32
+	for _, name := range []string{PoolReceipt, PoolMessage} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			_ = Submit(name, func() {
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&done, 1)
+			})
+		}(name)
+	}
+	wg.Wait()
+
+	if err := DrainAll(context.Background()); err != nil {
+		t.Fatalf("DrainAll() error = %v", err)
+	}
+	if done != 2 {
+		t.Fatalf("done = %v, want 2", done)
+	}
+}
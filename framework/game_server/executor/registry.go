@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sl.framework.com/game_server/conf"
+	"sync"
+)
+
+// 协程池名称 对应配置中executor下的key
+const (
+	PoolUserLimit  = "userLimit"  //停止下注后预先缓存在线玩家的个人限红
+	PoolReceipt    = "receipt"    //给玩家发送小票
+	PoolDBSave     = "dbSave"     //注单批量入库
+	PoolMessage    = "message"    //给ws推送局消息
+	PoolBetConfirm = "betConfirm" //提交注单消息中每个用户的注单提交
+)
+
+var (
+	// defaultOptions 没有配置时使用的默认值
+	defaultOptions = Options{Workers: 16, QueueSize: 1024, Policy: PolicyCallerRuns}
+
+	executors     = make(map[string]*Executor)
+	executorsLock sync.Mutex
+)
+
+/**
+ * Get
+ * 获取指定名称的协程池 第一次使用时按配置创建 配置修改后重启生效
+ *
+ * @param name string - 协程池名称
+ * @return *Executor - 协程池
+ */
+
+func Get(name string) *Executor {
+	executorsLock.Lock()
+	defer executorsLock.Unlock()
+
+	if e, ok := executors[name]; ok {
+		return e
+	}
+	opts := defaultOptions
+	if item, ok := conf.GetExecutor(name); ok {
+		opts = Options{Workers: item.Workers, QueueSize: item.QueueSize, Policy: item.Policy}
+	}
+	e := New(name, opts)
+	executors[name] = e
+	return e
+}
+
+/**
+ * Submit
+ * 向指定名称的协程池提交任务
+ *
+ * @param name string - 协程池名称
+ * @param fn func() - 任务
+ * @return error - 任务被拒绝时返回错误
+ */
+
+func Submit(name string, fn func()) error {
+	return Get(name).Submit(fn)
+}
+
+/**
+ * SubmitWait
+ * 向指定名称的协程池提交任务并等待执行完毕 任务panic时同样返回
+ *
+ * @param name string - 协程池名称
+ * @param fn func() - 任务
+ * @return error - 任务被拒绝时返回错误 此时任务没有执行
+ */
+
+func SubmitWait(name string, fn func()) error {
+	done := make(chan struct{})
+	if err := Submit(name, func() {
+		defer close(done)
+		fn()
+	}); err != nil {
+		return err
+	}
+	<-done
+	return nil
+}
+
+/**
+ * DrainAll
+ * 停止所有协程池并等待已提交的任务执行完毕 各协程池并行等待
+ *
+ * @param ctx context.Context - 等待超时控制
+ * @return error - 有协程池超时时返回错误
+ */
+
+func DrainAll(ctx context.Context) error {
+	executorsLock.Lock()
+	list := make([]*Executor, 0, len(executors))
+	for _, e := range executors {
+		list = append(list, e)
+	}
+	executorsLock.Unlock()
+
+	errs := make([]error, len(list))
+	wg := sync.WaitGroup{}
+	for i, e := range list {
+		wg.Add(1)
+		go func(i int, e *Executor) {
+			defer wg.Done()
+			errs[i] = e.Drain(ctx)
+		}(i, e)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
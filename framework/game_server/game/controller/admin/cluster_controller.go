@@ -0,0 +1,48 @@
+package admin
+
+import (
+	beego "github.com/beego/beego/v2/server/web"
+	"net/http"
+	"sl.framework.com/game_server/conf"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/cache"
+	"time"
+)
+
+// ClusterMembers 集群在线节点
+type ClusterMembers struct {
+	Online  int                  `json:"online"`          //在线节点数量
+	Members []*types.ClusterNode `json:"members"`         //在线节点信息 按serverId排序
+	Error   string               `json:"error,omitempty"` //查询失败时的错误信息
+}
+
+/**
+ * ClusterController
+ * 集群成员运维控制器 直接从redis查询在线节点
+ */
+
+type ClusterController struct {
+	beego.Controller
+}
+
+/**
+ * Members
+ * 查询集群在线节点 直接读取redis 不依赖本节点的心跳结果
+ *
+ * @return
+ */
+
+func (c *ClusterController) Members() {
+	var members ClusterMembers
+	nodes, ok := cache.GetClusterNodes("admin", time.Now(), time.Duration(conf.GetHeartbeatExpired())*time.Second)
+	if !ok {
+		members.Error = "get cluster nodes from redis failed"
+		c.Ctx.Output.SetStatus(http.StatusServiceUnavailable)
+	} else {
+		members.Online = len(nodes)
+		members.Members = nodes
+	}
+
+	c.Data["json"] = members
+	c.ServeJSON()
+}
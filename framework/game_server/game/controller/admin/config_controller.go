@@ -0,0 +1,42 @@
+package admin
+
+import (
+	beego "github.com/beego/beego/v2/server/web"
+	"net/http"
+	"sl.framework.com/game_server/conf"
+)
+
+// EffectiveConfig 当前生效的配置
+type EffectiveConfig struct {
+	Config *conf.Configuration `json:"config,omitempty"` //屏蔽敏感信息后的配置
+	Error  string              `json:"error,omitempty"`  //配置未初始化时的错误信息
+}
+
+/**
+ * ConfigController
+ * 配置运维控制器 返回屏蔽敏感字段后的生效配置
+ */
+
+type ConfigController struct {
+	beego.Controller
+}
+
+/**
+ * Effective
+ * 查询当前生效的配置 密码 密钥 token等敏感字段显示为******
+ *
+ * @return
+ */
+
+func (c *ConfigController) Effective() {
+	var effective EffectiveConfig
+	if cfg := conf.Current(); cfg == nil {
+		effective.Error = "configuration not loaded"
+		c.Ctx.Output.SetStatus(http.StatusServiceUnavailable)
+	} else {
+		effective.Config = cfg.Masked()
+	}
+
+	c.Data["json"] = effective
+	c.ServeJSON()
+}
@@ -0,0 +1,35 @@
+package admin
+
+import (
+	beego "github.com/beego/beego/v2/server/web"
+	"net/http"
+	"sl.framework.com/game_server/healthcheck"
+)
+
+/**
+ * HealthDetailController
+ * 健康检查详情运维控制器 任意检查项不健康时返回503
+ */
+
+type HealthDetailController struct {
+	beego.Controller
+}
+
+/**
+ * Detail
+ * 查询readiness和liveness每个检查项的状态 耗时和错误信息
+ *
+ * @return
+ */
+
+func (c *HealthDetailController) Detail() {
+	detail := healthcheck.Detail()
+	for _, report := range detail {
+		if report.Status != healthcheck.StatusUp {
+			c.Ctx.Output.SetStatus(http.StatusServiceUnavailable)
+		}
+	}
+
+	c.Data["json"] = detail
+	c.ServeJSON()
+}
@@ -0,0 +1,50 @@
+package admin
+
+import (
+	beego "github.com/beego/beego/v2/server/web"
+	"net/http"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/outbox"
+	"sl.framework.com/trace"
+)
+
+// OutboxStats 发件箱各状态消息数量
+type OutboxStats struct {
+	Pending int64  `json:"pending"`         //等待发送或者等待重试
+	Failed  int64  `json:"failed"`          //超过最大重试次数 需要人工处理
+	Success int64  `json:"success"`         //发送成功
+	Error   string `json:"error,omitempty"` //统计失败时的错误信息
+}
+
+/**
+ * OutboxController
+ * 发件箱运维控制器 统计各状态的消息数量
+ */
+
+type OutboxController struct {
+	beego.Controller
+}
+
+/**
+ * Stats
+ * 查询发件箱中等待发送和发送失败的消息数量
+ *
+ * @return
+ */
+
+func (c *OutboxController) Stats() {
+	var stats OutboxStats
+	counts, err := outbox.Stats()
+	if err != nil {
+		trace.Error("OutboxController Stats failed, error=%v", err.Error())
+		stats.Error = err.Error()
+		c.Ctx.Output.SetStatus(http.StatusServiceUnavailable)
+	} else {
+		stats.Pending = counts[string(types.OutboxStatusPending)]
+		stats.Failed = counts[string(types.OutboxStatusFailed)]
+		stats.Success = counts[string(types.OutboxStatusSuccess)]
+	}
+
+	c.Data["json"] = stats
+	c.ServeJSON()
+}
@@ -0,0 +1,46 @@
+package admin
+
+import (
+	beego "github.com/beego/beego/v2/server/web"
+	"net/http"
+	"sl.framework.com/game_server/conf"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/cache"
+)
+
+// RoundStates 各房间当前的局状态
+type RoundStates struct {
+	Recovery string                 `json:"recovery"`        //局事件乱序时的处理方式
+	Rooms    []*types.RoundStateDTO `json:"rooms"`           //房间局状态 按房间id排序
+	Error    string                 `json:"error,omitempty"` //查询失败时的错误信息
+}
+
+/**
+ * RoundStateController
+ * 局状态运维控制器 返回各房间的局状态和乱序处理方式
+ */
+
+type RoundStateController struct {
+	beego.Controller
+}
+
+/**
+ * States
+ * 查询每个房间当前局的状态
+ *
+ * @return
+ */
+
+func (c *RoundStateController) States() {
+	states := RoundStates{Recovery: conf.GetRoundStateRecovery()}
+	rooms, ok := cache.GetRoundStates("admin")
+	if !ok {
+		states.Error = "get round states from redis failed"
+		c.Ctx.Output.SetStatus(http.StatusServiceUnavailable)
+	} else {
+		states.Rooms = rooms
+	}
+
+	c.Data["json"] = states
+	c.ServeJSON()
+}
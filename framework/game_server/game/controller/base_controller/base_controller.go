@@ -1,6 +1,7 @@
 package base_controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	beego "github.com/beego/beego/v2/server/web"
@@ -102,6 +103,7 @@ func (c *BaseController) ClientResponse(code int, traceId string, data interface
 /**
  * ParserFromDataSource
  * 解析来自数据源的请求header头以及参数
+ * 开启签名校验时先校验数据源签名 校验失败直接回包
  *
  * @param receiver interfaces{} - http body中data的解析对象 必须为指针类型 如果为nil则不解析数据
  * @return controllerParserDTO dto.ControllerParserDTO - 解析得到的数据
@@ -125,6 +127,13 @@ func (c *BaseController) ParserFromDataSource(receiver interface{}) (controllerP
 	trace.Debug("BaseController parser controllerParserDTO=%+v,"+
 		"body=%v,head=%v", controllerParserDTO, string(c.Ctx.Input.RequestBody), c.Ctx.Request.Header)
 
+	//数据源签名校验
+	if code := c.verifyDataSource(controllerParserDTO.TraceId); errcode.ErrorOk != code {
+		controllerParserDTO.Code = code
+		c.DataSourceResponse(code, controllerParserDTO.TraceId, nil)
+		return
+	}
+
 	//没有接受者则直接返回不解析
 	if receiver == nil {
 		return
@@ -133,8 +142,8 @@ func (c *BaseController) ParserFromDataSource(receiver interface{}) (controllerP
 	//解析body中的数据到receiver中
 	if err := json.Unmarshal(data, receiver); nil != err {
 		controllerParserDTO.Code = errcode.JsonErrorUnMarshal
-		trace.Error("BaseController json unmarshal failed,errMsg=%+v controllerParserDTO=%+v", err.Error(),
-			controllerParserDTO)
+		c.Log().Error("BaseController json unmarshal failed", "error", err.Error(), "path", c.Ctx.Request.URL.Path,
+			"requestId", controllerParserDTO.RequestId)
 		c.DataSourceResponse(errcode.JsonErrorUnMarshal, controllerParserDTO.TraceId, nil)
 		return
 	}
@@ -179,8 +188,8 @@ func (c *BaseController) ParserFromClient(receiver interface{}) (controllerParse
 	//解析body中的数据到receiver中
 	if err := json.Unmarshal(data, receiver); nil != err {
 		controllerParserDTO.Code = errcode.JsonErrorUnMarshal
-		trace.Error("BaseController json unmarshal failed,errMsg=%+v controllerParserDTO=%+v", err.Error(),
-			controllerParserDTO)
+		c.Log().Error("BaseController json unmarshal failed", "error", err.Error(), "path", c.Ctx.Request.URL.Path,
+			"requestId", controllerParserDTO.RequestId)
 		c.ClientResponse(errcode.JsonErrorUnMarshal, controllerParserDTO.TraceId, nil)
 		return
 	}
@@ -192,3 +201,13 @@ func (c *BaseController) ParserFromClient(receiver interface{}) (controllerParse
 type BaseController struct {
 	beego.Controller
 }
+
+// LogContext 请求的日志上下文 由filter.TraceContextFilter设置traceId和用户Id
+func (c *BaseController) LogContext() context.Context {
+	return c.Ctx.Request.Context()
+}
+
+// Log 带有请求traceId和用户Id字段的日志对象
+func (c *BaseController) Log() *trace.Entry {
+	return trace.FromContext(c.LogContext())
+}
@@ -0,0 +1,72 @@
+package base_controller
+
+import (
+	"fmt"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/game_server/sign"
+	"sl.framework.com/trace"
+	"time"
+)
+
+const maxNonceLen = 64 //nonce最大长度 防止超长key写入redis
+
+/**
+ * verifyDataSource
+ * 校验数据源请求签名 依次校验请求头 数据源 时间戳 签名 nonce
+ * 签名通过后才写入nonce 避免伪造请求占用合法的nonce
+ * 配置中关闭签名校验时直接通过
+ *
+ * @param traceId string - 用于日志跟踪
+ * @return int - 校验结果 通过返回errcode.ErrorOk
+ */
+
+func (c *BaseController) verifyDataSource(traceId string) int {
+	if !conf.GetDataSourceSignEnable() {
+		return errcode.ErrorOk
+	}
+
+	input := c.Ctx.Input
+	sourceId := input.Header(sign.HeaderSourceId)
+	timestamp := input.Header(sign.HeaderTimestamp)
+	nonce := input.Header(sign.HeaderNonce)
+	signature := input.Header(sign.HeaderSignature)
+	msgHeader := fmt.Sprintf("verifyDataSource traceId=%v, sourceId=%v, timestamp=%v, nonce=%v, path=%v",
+		traceId, sourceId, timestamp, nonce, c.Ctx.Request.URL.Path)
+
+	if len(sourceId) == 0 || len(timestamp) == 0 || len(nonce) == 0 || len(nonce) > maxNonceLen || len(signature) == 0 {
+		trace.Error("%v, 签名请求头缺失", msgHeader)
+		return errcode.AuthErrorSignatureMissing
+	}
+	secret, ok := conf.GetDataSourceSecret(sourceId)
+	if !ok {
+		trace.Error("%v, 未知的数据源", msgHeader)
+		return errcode.AuthErrorSourceUnknown
+	}
+	window := conf.GetDataSourceReplayWindow()
+	if !sign.CheckTimestamp(timestamp, time.Now(), window) {
+		trace.Error("%v, 时间戳超出允许范围 window=%v", msgHeader, window)
+		return errcode.AuthErrorTimestampExpired
+	}
+	if !sign.Verify(secret, c.Ctx.Request.Method, c.Ctx.Request.URL.Path, sourceId, timestamp, nonce,
+		input.RequestBody, signature) {
+		trace.Error("%v, 签名错误", msgHeader)
+		return errcode.AuthErrorSignatureInvalid
+	}
+
+	//时间戳前后各允许window的偏差 nonce需要保存两倍window才能覆盖整个有效期
+	redisInfo := rediskey.GetDataSourceNonceRedisInfo(sourceId, nonce, 2*window)
+	isNew, err := redisdb.SetNX(redisInfo.Key, timestamp, redisInfo.Expire)
+	if err != nil {
+		trace.Error("%v, nonce写入redis失败 error=%v", msgHeader, err.Error())
+		return errcode.AuthErrorNonceCheckFailed
+	}
+	if !isNew {
+		trace.Error("%v, nonce重复 疑似重放请求", msgHeader)
+		return errcode.AuthErrorNonceReplayed
+	}
+
+	return errcode.ErrorOk
+}
@@ -3,7 +3,6 @@ package client
 import (
 	"fmt"
 	errcode "sl.framework.com/game_server/error_code"
-	"sl.framework.com/game_server/game/controller/base_controller"
 	"sl.framework.com/game_server/game/dao/redisdb"
 	"sl.framework.com/game_server/game/service/bet"
 	types "sl.framework.com/game_server/game/service/type"
@@ -27,7 +26,7 @@ func (p *BetController) BetCancel() {
 		trace.Error("BetController BetCancel parser error, code=%v", controllerParserDTO.Code)
 		return
 	}
-	userId := p.Ctx.Input.Header(string(base_controller.TagUserId))
+	userId, _, _, _ := p.player()
 	//requestId := p.Ctx.Input.Header(string(tagRequestId))
 
 	//参数判断
@@ -25,11 +25,8 @@ func (p *BetController) BetConfirm() {
 		trace.Error("BetController BetConfirm parser error, code=%v", controllerParserDTO.Code)
 		return
 	}
-	userId := p.Ctx.Input.Header(string(base_controller.TagUserId))
-	userName := p.Ctx.Input.Header(string(base_controller.TagUserName))
-	userType := p.Ctx.Input.Header(string(base_controller.TagUserType))
+	userId, userName, userType, currency := p.player()
 	language := p.Ctx.Input.Header(string(base_controller.TagLanguage))
-	currency := p.Ctx.Input.Header(string(base_controller.TagCurrency))
 	clientType := p.Ctx.Input.Header(string(base_controller.TagClientType))
 
 	msgHeader := fmt.Sprintf("游戏投注确认 BetController BetConfirm traceId=%v, gameRoomId=%v, gameRoundId=%v",
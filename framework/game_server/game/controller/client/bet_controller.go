@@ -5,12 +5,14 @@ import (
 	"sl.framework.com/game_server/error_code"
 	"sl.framework.com/game_server/game/controller/base_controller"
 	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/game/filter"
 	"sl.framework.com/game_server/game/service/bet"
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/redis/cache"
 	rediskey "sl.framework.com/game_server/redis/rediskey"
 	"sl.framework.com/tool"
 	"sl.framework.com/trace"
+	"strconv"
 )
 
 // BetController 玩家下注订单处理
@@ -18,6 +20,39 @@ type BetController struct {
 	base_controller.BaseController
 }
 
+/**
+ * player
+ * 下注相关接口使用的玩家身份
+ * 开启玩家身份校验时使用token中校验过的身份 token中没有的字段以及未开启校验时使用请求头
+ *
+ * @return userId string - 用户Id
+ * @return userName string - 用户名
+ * @return userType string - 用户类型
+ * @return currency string - 币种
+ */
+
+func (p *BetController) player() (userId, userName, userType, currency string) {
+	userId = p.Ctx.Input.Header(string(base_controller.TagUserId))
+	userName = p.Ctx.Input.Header(string(base_controller.TagUserName))
+	userType = p.Ctx.Input.Header(string(base_controller.TagUserType))
+	currency = p.Ctx.Input.Header(string(base_controller.TagCurrency))
+	principal, ok := filter.PrincipalFromContext(p.Ctx)
+	if !ok {
+		return
+	}
+	userId = strconv.FormatInt(principal.UserId, 10)
+	if len(principal.UserName) > 0 {
+		userName = principal.UserName
+	}
+	if len(principal.UserType) > 0 {
+		userType = principal.UserType
+	}
+	if len(principal.Currency) > 0 {
+		currency = principal.Currency
+	}
+	return
+}
+
 /**
  * Bet
  * 处理玩家下注信息
@@ -30,11 +65,8 @@ func (p *BetController) Bet() {
 		trace.Error("BetController Bet parser error, code=%v", controllerParserDTO.Code)
 		return
 	}
-	userId := p.Ctx.Input.Header(string(base_controller.TagUserId))
-	userName := p.Ctx.Input.Header(string(base_controller.TagUserName))
-	userType := p.Ctx.Input.Header(string(base_controller.TagUserType))
+	userId, userName, userType, currency := p.player()
 	language := p.Ctx.Input.Header(string(base_controller.TagLanguage))
-	currency := p.Ctx.Input.Header(string(base_controller.TagCurrency))
 	clientType := p.Ctx.Input.Header(string(base_controller.TagClientType))
 
 	msgHeader := fmt.Sprintf("游戏投注 BetController Bet traceId=%v, gameRoomId=%v, gameRoundId=%v",
@@ -45,7 +77,7 @@ func (p *BetController) Bet() {
 	trace.Info("%v, param Bets=%+v", msgHeader, param.Bets)
 	trace.Info("%v, param LimitRule=%+v", msgHeader, param.LimitRule)
 	trace.Info("%v, param Device=%+v", msgHeader, param.Device)
-	if len(param.GameRoundId) == 0 || len(param.GameRoomId) == 0 || len(param.Bets) == 0 || param.BetAmount <= 0 ||
+	if len(param.GameRoundId) == 0 || len(param.GameRoomId) == 0 || len(param.Bets) == 0 || param.BetAmount.Sign() <= 0 ||
 		len(userId) <= 0 || len(param.Currency) <= 0 {
 		trace.Error("%v, invalid param", msgHeader)
 		p.ClientResponse(errcode.HttpErrorInvalidParam, controllerParserDTO.TraceId, nil)
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"fmt"
+	errcode "sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/controller/base_controller"
+	fairodds "sl.framework.com/game_server/game/service/fair_odds"
+	"sl.framework.com/trace"
+	"strconv"
+)
+
+// DynamicOddsController 动态赔率验证接口
+type DynamicOddsController struct {
+	base_controller.BaseController
+}
+
+/**
+ * Verify
+ * 验证一局的动态赔率 Game_End之前只返回Bet_Start公布的种子hash
+ * Game_End之后返回公开的种子 审计记录以及复算结果
+ *
+ * @param
+ * @return
+ */
+
+func (p *DynamicOddsController) Verify() {
+	controllerParserDTO := p.ParserFromClient(nil)
+	if controllerParserDTO.Code != errcode.ErrorOk {
+		trace.Error("DynamicOddsController Verify parser error, code=%v", controllerParserDTO.Code)
+		return
+	}
+
+	gameRoomId, _ := strconv.ParseInt(p.Ctx.Input.Param(":gameRoomId"), 10, 64)
+	gameRoundId, _ := strconv.ParseInt(p.Ctx.Input.Param(":gameRoundId"), 10, 64)
+	msgHeader := fmt.Sprintf("动态赔率验证 DynamicOddsController traceId=%v, gameRoomId=%v, gameRoundId=%v",
+		controllerParserDTO.TraceId, gameRoomId, gameRoundId)
+	trace.Info("%v", msgHeader)
+	if gameRoomId == 0 || gameRoundId == 0 {
+		trace.Error("%v, invalid param", msgHeader)
+		p.ClientResponse(errcode.HttpErrorInvalidParam, controllerParserDTO.TraceId, nil)
+		return
+	}
+
+	result, ok := fairodds.VerifyRound(controllerParserDTO.TraceId, gameRoomId, gameRoundId)
+	if !ok {
+		trace.Notice("%v, no dynamic odds seed", msgHeader)
+		p.ClientResponse(errcode.HttpErrorDataFailed, controllerParserDTO.TraceId, nil)
+		return
+	}
+
+	trace.Info("%v, revealed=%v, verified=%v, records=%v", msgHeader, result.Revealed, result.Verified, len(result.Records))
+	p.ClientResponse(controllerParserDTO.Code, controllerParserDTO.TraceId, result)
+}
@@ -2,6 +2,8 @@ package health
 
 import (
 	beego "github.com/beego/beego/v2/server/web"
+	"net/http"
+	"sl.framework.com/game_server/healthcheck"
 )
 
 // HealthStatus 健康状态
@@ -24,6 +26,38 @@ func (c *HealthController) HealthCheck() {
 	c.ServeJSON()
 }
 
+/**
+ * Readiness
+ * 就绪检查回包 启动完成之前 开始关闭之后或者关键依赖不可用时返回503 负载均衡不再转发流量
+ *
+ * @return
+ */
+
+func (c *HealthController) Readiness() {
+	c.serveReport(healthcheck.Readiness())
+}
+
+/**
+ * Liveness
+ * 存活检查回包 定时任务卡死时返回503 k8s重启服务
+ *
+ * @return
+ */
+
+func (c *HealthController) Liveness() {
+	c.serveReport(healthcheck.Liveness())
+}
+
+// serveReport 只返回总体状态 各检查项的详情通过/admin/health查询
+func (c *HealthController) serveReport(report healthcheck.Report) {
+	if report.Status != healthcheck.StatusUp {
+		c.Ctx.Output.SetStatus(http.StatusServiceUnavailable)
+	}
+	c.Data["json"] = HealthStatus{Status: report.Status}
+
+	c.ServeJSON()
+}
+
 /**
  * HealthController
  * 健康检查控制器
@@ -4,10 +4,13 @@ import (
 	beego "github.com/beego/beego/v2/server/web"
 	"github.com/beego/beego/v2/server/web/context"
 	"net/http"
+	"sl.framework.com/game_server/game/controller/admin"
 	"sl.framework.com/game_server/game/controller/client"
 	"sl.framework.com/game_server/game/controller/health"
 	"sl.framework.com/game_server/game/controller/middle_platform"
 	"sl.framework.com/game_server/game/controller/resource"
+	"sl.framework.com/game_server/game/filter"
+	"sl.framework.com/game_server/metrics"
 	"sl.framework.com/trace"
 	"strings"
 )
@@ -39,16 +42,30 @@ func browserFilter(ctx *context.Context) {
  * RegisterHealthRouter
  * 注册健康检查端口路由
  * 以下四个路由必须实现 否则k8s健康检查不过会重启服务
+ * /actuator/health/readiness启动完成之前 开始关闭之后或者关键依赖不可用时返回503
+ * /actuator/health/liveness定时任务卡死时返回503
+ * /actuator/prometheus导出prometheus监控指标
+ * /admin/outbox查询发件箱等待发送和发送失败的消息数量
+ * /admin/cluster查询集群在线节点
+ * /admin/config查询当前生效的配置 敏感字段已屏蔽
+ * /admin/rounds查询每个房间当前的局状态
+ * /admin/health查询每个健康检查项的状态和错误信息
+ * /admin下的运维接口只注册在健康检查端口上 不对外暴露
  *
  * @param server *beego.HttpServer - http服务
  * @return
  */
 
 func RegisterHealthRouter(server *beego.HttpServer) {
-	server.Router("/actuator/health/readiness", &health.HealthController{}, "get:HealthCheck")
-	server.Router("/actuator/health/liveness", &health.HealthController{}, "get:HealthCheck")
-	server.Router("/actuator/prometheus", &health.HealthController{}, "get:HealthCheck")
+	server.Router("/actuator/health/readiness", &health.HealthController{}, "get:Readiness")
+	server.Router("/actuator/health/liveness", &health.HealthController{}, "get:Liveness")
+	server.Handler("/actuator/prometheus", metrics.Handler())
 	server.Router("/healthcheck", &health.HealthController{}, "get:HealthCheck")
+	server.Router("/admin/outbox", &admin.OutboxController{}, "get:Stats")
+	server.Router("/admin/cluster", &admin.ClusterController{}, "get:Members")
+	server.Router("/admin/config", &admin.ConfigController{}, "get:Effective")
+	server.Router("/admin/rounds", &admin.RoundStateController{}, "get:States")
+	server.Router("/admin/health", &admin.HealthDetailController{}, "get:Detail")
 }
 
 /*
@@ -61,12 +78,21 @@ func RegisterRouter() {
 	/* 获取唯一节点Id */
 	beego.Router("/uniqueId", &middle_platform.UniqueIDController{}, "get:GetUniqueId")
 
+	/* 日志上下文 请求头中的traceId和用户Id自动带入控制器日志 */
+	beego.InsertFilter("/*", beego.BeforeRouter, filter.TraceContextFilter)
+
+	/* 玩家身份校验 配置中开启后校验投注相关接口的玩家token */
+	beego.InsertFilter("/bet", beego.BeforeRouter, filter.PlayerAuthFilter)
+	beego.InsertFilter("/bet/*", beego.BeforeRouter, filter.PlayerAuthFilter)
+
 	/* 与客户端交互路由 包括 投注 投注取消 投注确认 投注记录查询 */
 	beego.Router("/bet", &client.BetController{}, "post:Bet")
 	beego.Router("/bet/cancel", &client.BetController{}, "put:BetCancel")
 	beego.Router("/bet/confirmed", &client.BetController{}, "post:BetConfirm")
 	beego.Router("/bet/records/:gameRoomId/:gameRoundId", &client.BetRecordController{}, "get:BetRecord")
 	beego.Router("/settle/draw/list/:gameRoomId/", &client.DrawResultController{}, "get:GetList")
+	/* 动态赔率验证 Game_End后公开种子和审计记录 */
+	beego.Router("/dynamicOdds/verify/:gameRoomId/:gameRoundId", &client.DynamicOddsController{}, "get:Verify")
 	/* 处理事件 包括游戏事件 玩家进入房间或者离开房间事件 */
 	//beego.Router("/v1/gameEvent", &GameEventController{}, "post:GameEvent")
 	beego.Router("/v1/joinOrLeave", &client.JoinOrLeaveController{}, "post:JoinOrLeaveRoom")
@@ -0,0 +1,196 @@
+package gamedb
+
+import (
+	"encoding/json"
+	"fmt"
+	types "sl.framework.com/game_server/game/service/type"
+	"time"
+)
+
+// dynamicOddsSeedRow dynamic_odds_seed表的行 时间为毫秒时间戳 未公开时reveal_time为0
+type dynamicOddsSeedRow struct {
+	GameRoomId  int64
+	GameRoundId int64
+	SeedHash    string
+	ServerSeed  string
+	Revealed    bool
+	CommitTime  int64
+	RevealTime  int64
+}
+
+// dynamicOddsAuditRow dynamic_odds_audit表的行 权重区间保存为json
+type dynamicOddsAuditRow struct {
+	TraceId       string
+	GameId        int64
+	GameRoomId    int64
+	GameRoundId   int64
+	WagerId       int64
+	SeedHash      string
+	ClientSeed    string
+	OriginalOdds  float32
+	TriggerWeight int
+	TriggerRoll   int
+	Triggered     bool
+	Weights       string
+	OddsRange     int
+	OddsRoll      int
+	Odds          float32
+	CreateTime    time.Time
+}
+
+/**
+ * InsertOddsSeed
+ * 写入局的动态赔率种子 同一局已经有种子时忽略 种子一旦写入不能再修改
+ *
+ * @param seed *types.DynamicOddsSeedDTO - 种子
+ * @return bool - 是否新写入 false表示本局已经有种子
+ * @return error - 数据库错误
+ */
+
+func InsertOddsSeed(seed *types.DynamicOddsSeedDTO) (bool, error) {
+	o := GetGameGDBOrm()
+	res, err := o.Raw("INSERT IGNORE INTO dynamic_odds_seed (game_room_id, game_round_id, seed_hash, server_seed, revealed, "+
+		"commit_time, reveal_time) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		seed.GameRoomId, seed.GameRoundId, seed.SeedHash, seed.ServerSeed, seed.Revealed, seed.CommitTime.UnixMilli(), 0).Exec()
+	if err != nil {
+		return false, fmt.Errorf("insert dynamic odds seed failed: %v", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("insert dynamic odds seed rows affected failed: %v", err)
+	}
+	return affected > 0, nil
+}
+
+/**
+ * QueryOddsSeed
+ * 查询局的动态赔率种子
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return *types.DynamicOddsSeedDTO - 种子 本局没有种子时为nil
+ * @return error - 数据库错误
+ */
+
+func QueryOddsSeed(gameRoomId, gameRoundId int64) (*types.DynamicOddsSeedDTO, error) {
+	var rows []dynamicOddsSeedRow
+	o := GetGameGDBOrm()
+	if _, err := o.Raw("SELECT * FROM dynamic_odds_seed WHERE game_room_id = ? AND game_round_id = ?",
+		gameRoomId, gameRoundId).QueryRows(&rows); err != nil {
+		return nil, fmt.Errorf("query dynamic odds seed failed: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	row := rows[0]
+	seed := &types.DynamicOddsSeedDTO{
+		GameRoomId:  row.GameRoomId,
+		GameRoundId: row.GameRoundId,
+		SeedHash:    row.SeedHash,
+		ServerSeed:  row.ServerSeed,
+		Revealed:    row.Revealed,
+		CommitTime:  time.UnixMilli(row.CommitTime),
+	}
+	if row.Revealed {
+		seed.RevealTime = time.UnixMilli(row.RevealTime)
+	}
+	return seed, nil
+}
+
+/**
+ * RevealOddsSeed
+ * 把局的动态赔率种子标记为已公开 已经公开过的种子保持第一次公开的时间
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @param now time.Time - 公开时间
+ * @return error - 数据库错误
+ */
+
+func RevealOddsSeed(gameRoomId, gameRoundId int64, now time.Time) error {
+	o := GetGameGDBOrm()
+	_, err := o.Raw("UPDATE dynamic_odds_seed SET revealed = 1, reveal_time = ? WHERE game_room_id = ? AND game_round_id = ? AND revealed = 0",
+		now.UnixMilli(), gameRoomId, gameRoundId).Exec()
+	if err != nil {
+		return fmt.Errorf("reveal dynamic odds seed failed: %v", err)
+	}
+	return nil
+}
+
+/**
+ * InsertDynamicOddsAudit
+ * 写入玩法的动态赔率审计记录 同一局同一玩法已经有记录时忽略 审计记录写入后不能再修改
+ *
+ * @param audit *types.DynamicOddsAuditDTO - 审计记录
+ * @return bool - 是否新写入 false表示记录已经存在
+ * @return error - 数据库错误
+ */
+
+func InsertDynamicOddsAudit(audit *types.DynamicOddsAuditDTO) (bool, error) {
+	weights, err := json.Marshal(audit.Weights)
+	if err != nil {
+		return false, fmt.Errorf("marshal dynamic odds weights failed: %v", err)
+	}
+
+	o := GetGameGDBOrm()
+	res, err := o.Raw("INSERT IGNORE INTO dynamic_odds_audit (trace_id, game_id, game_room_id, game_round_id, wager_id, seed_hash, "+
+		"client_seed, original_odds, trigger_weight, trigger_roll, triggered, weights, odds_range, odds_roll, odds, create_time) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		audit.TraceId, audit.GameId, audit.GameRoomId, audit.GameRoundId, audit.WagerId, audit.SeedHash, audit.ClientSeed,
+		audit.OriginalOdds, audit.TriggerWeight, audit.TriggerRoll, audit.Triggered, string(weights), audit.OddsRange,
+		audit.OddsRoll, audit.Odds, audit.CreateTime).Exec()
+	if err != nil {
+		return false, fmt.Errorf("insert dynamic odds audit failed: %v", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("insert dynamic odds audit rows affected failed: %v", err)
+	}
+	return affected > 0, nil
+}
+
+/**
+ * QueryDynamicOddsAudits
+ * 查询局的全部动态赔率审计记录
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return []*types.DynamicOddsAuditDTO - 审计记录 按玩法id升序排列
+ * @return error - 数据库错误
+ */
+
+func QueryDynamicOddsAudits(gameRoomId, gameRoundId int64) ([]*types.DynamicOddsAuditDTO, error) {
+	var rows []dynamicOddsAuditRow
+	o := GetGameGDBOrm()
+	if _, err := o.Raw("SELECT * FROM dynamic_odds_audit WHERE game_room_id = ? AND game_round_id = ? ORDER BY wager_id",
+		gameRoomId, gameRoundId).QueryRows(&rows); err != nil {
+		return nil, fmt.Errorf("query dynamic odds audit failed: %v", err)
+	}
+
+	audits := make([]*types.DynamicOddsAuditDTO, 0, len(rows))
+	for _, row := range rows {
+		audit := &types.DynamicOddsAuditDTO{
+			TraceId:       row.TraceId,
+			GameId:        row.GameId,
+			GameRoomId:    row.GameRoomId,
+			GameRoundId:   row.GameRoundId,
+			WagerId:       row.WagerId,
+			SeedHash:      row.SeedHash,
+			ClientSeed:    row.ClientSeed,
+			OriginalOdds:  row.OriginalOdds,
+			TriggerWeight: row.TriggerWeight,
+			TriggerRoll:   row.TriggerRoll,
+			Triggered:     row.Triggered,
+			OddsRange:     row.OddsRange,
+			OddsRoll:      row.OddsRoll,
+			Odds:          row.Odds,
+			CreateTime:    row.CreateTime,
+		}
+		if err := json.Unmarshal([]byte(row.Weights), &audit.Weights); err != nil {
+			return nil, fmt.Errorf("unmarshal dynamic odds weights failed, wagerId=%v: %v", row.WagerId, err)
+		}
+		audits = append(audits, audit)
+	}
+	return audits, nil
+}
@@ -0,0 +1,30 @@
+DROP TABLE IF EXISTS  dynamic_odds_seed;
+CREATE TABLE dynamic_odds_seed (
+  game_room_id    bigint(20) NOT NULL comment '游戏房间id',
+  game_round_id   bigint(20) NOT NULL comment '游戏局id',
+  seed_hash       char(64) NOT NULL comment '种子的sha256 hex 开局之前公布',
+  server_seed     char(64) NOT NULL comment '服务端种子 hex 公开之前不能对外返回',
+  revealed        tinyint(1) NOT NULL DEFAULT 0 comment '种子是否已经公开',
+  commit_time     bigint(20) NOT NULL comment '种子生成时间 毫秒时间戳',
+  reveal_time     bigint(20) NOT NULL DEFAULT 0 comment '种子公开时间 毫秒时间戳 未公开时为0',
+  PRIMARY KEY (game_room_id, game_round_id)) comment='动态赔率服务端种子';
+
+DROP TABLE IF EXISTS  dynamic_odds_audit;
+CREATE TABLE dynamic_odds_audit (
+  trace_id        varchar(64) NOT NULL DEFAULT '' comment '跟踪id',
+  game_id         bigint(20) NOT NULL comment '游戏id',
+  game_room_id    bigint(20) NOT NULL comment '游戏房间id',
+  game_round_id   bigint(20) NOT NULL comment '游戏局id',
+  wager_id        bigint(20) NOT NULL comment '玩法id',
+  seed_hash       char(64) NOT NULL comment '本局种子的sha256',
+  client_seed     varchar(64) NOT NULL DEFAULT '' comment '参与随机的外部种子 本局Bet_Start事件的请求id',
+  original_odds   double NOT NULL comment '玩法默认赔率',
+  trigger_weight  int NOT NULL comment '触发动态赔率的权重 万分比',
+  trigger_roll    int NOT NULL comment '触发随机值 [0,10000)',
+  triggered       tinyint(1) NOT NULL comment '是否触发动态赔率',
+  weights         text NOT NULL comment '各个动态赔率的权重区间 json',
+  odds_range      int NOT NULL DEFAULT 0 comment '抽取赔率的随机范围 未触发时为0',
+  odds_roll       int NOT NULL DEFAULT 0 comment '抽取赔率的随机值',
+  odds            double NOT NULL comment '最终赔率',
+  create_time     datetime(3) NOT NULL comment '记录时间',
+  PRIMARY KEY (game_room_id, game_round_id, wager_id)) comment='动态赔率审计记录';
@@ -0,0 +1,28 @@
+package gamedb
+
+import (
+	"fmt"
+	"sl.framework.com/game_server/game/service/type/const_type"
+)
+
+/**
+ * QueryResettleOrderNos
+ * 从game_record查询局内需要重新结算的订单号 已派彩或者已重新结算过的注单
+ * 注单缓存过期之后重新结算以数据库为准
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return []int64 - 订单号 按订单号升序排列
+ * @return error - 数据库错误
+ */
+
+func QueryResettleOrderNos(gameRoomId, gameRoundId int64) ([]int64, error) {
+	var orderNoList []int64
+	o := GetGameGDBOrm()
+	_, err := o.Raw("SELECT order_no FROM game_record WHERE game_room_id = ? AND game_round_id = ? AND post_status IN (?, ?) ORDER BY order_no",
+		gameRoomId, gameRoundId, string(const_type.PostStatusPaid), string(const_type.PostStatusResettle)).QueryRows(&orderNoList)
+	if err != nil {
+		return nil, fmt.Errorf("query resettle orders failed: %v", err)
+	}
+	return orderNoList, nil
+}
@@ -1,6 +1,7 @@
 package gamedb
 
 import (
+	"context"
 	"github.com/beego/beego/v2/client/orm"
 	_ "github.com/go-sql-driver/mysql"
 	"sl.framework.com/game_server/conf"
@@ -21,6 +22,17 @@ func GetGameGDBOrm() orm.Ormer {
 	trace.Info("GetGameGDBOrm alias=%v", alias)
 	return orm.NewOrmUsingDB(alias)
 }
+
+// Ping 检测游戏数据库连接池是否可用 供健康检查使用
+func Ping(ctx context.Context) error {
+	db, err := orm.GetDB(conf.GetGameDbAliasName())
+	if err != nil {
+		return err
+	}
+
+	return db.PingContext(ctx)
+}
+
 func OrmGameDbInit() (err error) {
 	fn := func() {
 		err = nil
@@ -43,6 +55,8 @@ func OrmGameDbInit() (err error) {
 		//orm.RegisterModel(new(types.FastBacOrder))
 		// 注册定义的 游戏注单记录表
 		orm.RegisterModel(new(types.BetOrderV2))
+		// 注册定义的 外发请求发件箱表
+		orm.RegisterModel(new(types.OutboxMessage))
 
 		//设置最大连接数
 		orm.SetMaxOpenConns(aliasGdb, 5)
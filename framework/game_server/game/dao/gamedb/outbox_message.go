@@ -0,0 +1,123 @@
+package gamedb
+
+import (
+	"fmt"
+	types "sl.framework.com/game_server/game/service/type"
+	"time"
+)
+
+/**
+ * InsertOutboxMessage
+ * 写入发件箱消息 业务唯一键已经存在时忽略
+ *
+ * @param message *types.OutboxMessage - 发件箱消息
+ * @return bool - 是否新写入 false表示相同业务键的消息已经存在
+ * @return error - 数据库错误
+ */
+
+func InsertOutboxMessage(message *types.OutboxMessage) (bool, error) {
+	o := GetGameGDBOrm()
+	res, err := o.Raw("INSERT IGNORE INTO outbox_message (id, trace_id, kind, biz_key, payload, status, retry_count, last_code, "+
+		"next_retry_time, create_time, update_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		message.Id, message.TraceId, message.Kind, message.BizKey, message.Payload, message.Status, message.RetryCount,
+		message.LastCode, message.NextRetryTime, message.CreateTime, message.UpdateTime).Exec()
+	if err != nil {
+		return false, fmt.Errorf("insert outbox message failed: %v", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("insert outbox message rows affected failed: %v", err)
+	}
+	return affected > 0, nil
+}
+
+/**
+ * QueryDueOutboxMessages
+ * 查询到期需要发送的发件箱消息 按下次发送时间排序
+ *
+ * @param now time.Time - 当前时间
+ * @param limit int - 最多返回的条数
+ * @return []*types.OutboxMessage - 到期的消息
+ * @return error - 数据库错误
+ */
+
+func QueryDueOutboxMessages(now time.Time, limit int) ([]*types.OutboxMessage, error) {
+	var messages []*types.OutboxMessage
+	o := GetGameGDBOrm()
+	_, err := o.Raw("SELECT * FROM outbox_message WHERE status = ? AND next_retry_time <= ? ORDER BY next_retry_time LIMIT ?",
+		string(types.OutboxStatusPending), now, limit).QueryRows(&messages)
+	if err != nil {
+		return nil, fmt.Errorf("query due outbox message failed: %v", err)
+	}
+	return messages, nil
+}
+
+/**
+ * ClaimOutboxMessage
+ * 抢占到期的发件箱消息 把下次发送时间推迟到租约结束 多个实例同时扫描时只有一个能抢占成功
+ *
+ * @param id int64 - 消息id
+ * @param now time.Time - 当前时间
+ * @param until time.Time - 租约结束时间 发送进程异常退出时到期后由其他实例重新发送
+ * @return bool - 是否抢占成功
+ * @return error - 数据库错误
+ */
+
+func ClaimOutboxMessage(id int64, now, until time.Time) (bool, error) {
+	o := GetGameGDBOrm()
+	res, err := o.Raw("UPDATE outbox_message SET next_retry_time = ?, update_time = ? WHERE id = ? AND status = ? AND next_retry_time <= ?",
+		until, now, id, string(types.OutboxStatusPending), now).Exec()
+	if err != nil {
+		return false, fmt.Errorf("claim outbox message failed: %v", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim outbox message rows affected failed: %v", err)
+	}
+	return affected > 0, nil
+}
+
+/**
+ * UpdateOutboxMessage
+ * 更新发件箱消息的发送结果
+ *
+ * @param message *types.OutboxMessage - 发件箱消息
+ * @return error - 数据库错误
+ */
+
+func UpdateOutboxMessage(message *types.OutboxMessage) error {
+	o := GetGameGDBOrm()
+	_, err := o.Raw("UPDATE outbox_message SET status = ?, retry_count = ?, last_code = ?, next_retry_time = ?, update_time = ? WHERE id = ?",
+		message.Status, message.RetryCount, message.LastCode, message.NextRetryTime, message.UpdateTime, message.Id).Exec()
+	if err != nil {
+		return fmt.Errorf("update outbox message failed: %v", err)
+	}
+	return nil
+}
+
+// outboxStatusCount 按状态统计的结果行
+type outboxStatusCount struct {
+	Status string
+	Total  int64
+}
+
+/**
+ * CountOutboxMessages
+ * 按状态统计发件箱消息数量
+ *
+ * @return map[string]int64 - key为状态 value为数量
+ * @return error - 数据库错误
+ */
+
+func CountOutboxMessages() (map[string]int64, error) {
+	var rows []outboxStatusCount
+	o := GetGameGDBOrm()
+	if _, err := o.Raw("SELECT status, COUNT(*) AS total FROM outbox_message GROUP BY status").QueryRows(&rows); err != nil {
+		return nil, fmt.Errorf("count outbox message failed: %v", err)
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Total
+	}
+	return counts, nil
+}
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS  outbox_message;
+CREATE TABLE outbox_message (
+  id              bigint(20) NOT NULL comment 'id',
+  trace_id        varchar(64) NOT NULL DEFAULT '' comment '跟踪id',
+  kind            varchar(32) NOT NULL comment '消息类型 settle receipt gameMessage',
+  biz_key         varchar(128) NOT NULL comment '业务唯一键 相同的键只发送一次',
+  payload         text NOT NULL comment '请求内容',
+  status          varchar(16) NOT NULL comment '状态 pending success failed',
+  retry_count     int NOT NULL DEFAULT 0 comment '已重试次数',
+  last_code       int NOT NULL DEFAULT 0 comment '最后一次发送的返回码',
+  next_retry_time datetime(3) NOT NULL comment '下次发送时间',
+  create_time     datetime(3) NOT NULL comment '创建时间',
+  update_time     datetime(3) NOT NULL comment '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_biz_key (biz_key),
+  KEY idx_status_next_retry_time (status, next_retry_time)) comment='外发请求发件箱';
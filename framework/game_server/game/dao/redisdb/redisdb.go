@@ -8,6 +8,7 @@ import (
 	"sl.framework.com/game_server/conf"
 	snowflaker "sl.framework.com/game_server/conf/snow_flake_id"
 	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/metrics"
 	redistool "sl.framework.com/game_server/redis/redis_tool"
 
 	"sl.framework.com/game_server/redis/types"
@@ -43,6 +44,18 @@ func RedisClientClose() {
 	trace.Info("RedisClientClose redisUniversal close success")
 }
 
+/**
+ * RedisClientInitWith
+ * 使用外部构造好的redis客户端初始化 用于单元测试或者嵌入式场景
+ *
+ * @param client redis.UniversalClient - redis客户端
+ * @return
+ */
+
+func RedisClientInitWith(client redis.UniversalClient) {
+	redisUniversal = client
+}
+
 func RedisClientInitOnce() (ok bool) {
 	redisInitOnce.Do(func() {
 		if redisUniversal == nil {
@@ -60,28 +73,11 @@ func RedisClientInitOnce() (ok bool) {
 // 集群模式:[]string{"127.0.0.1:7000", "127.0.0.1:7001", "127.0.0.1:7002"}
 // 分片模式:[]string{"127.0.0.1:7000", "127.0.0.1:7001"}
 func redisClientUniversalInit() (isSuccess bool) {
-	addrs, _ := conf.GetRedisAddr()
-	if len(addrs) == 0 {
-		return
-	}
-
-	_, password := conf.GetRedisUserInfo()
-	switch conf.GetRedisMode() {
-	case conf.RedisModeSingle:
-		redisUniversal = redis.NewClient(&redis.Options{
-			Addr:     addrs[0],
-			DB:       conf.GetRedisDb(),
-			Password: password,
-		})
-	case conf.RedisModeCluster:
-		redisUniversal = redis.NewClusterClient(&redis.ClusterOptions{
-			Addrs:    addrs,
-			Password: password,
-		})
-	default:
-		trace.Error("redisClientUniversalInit wrong redis mode =%v", conf.GetRedisMode())
+	client, ok := newUniversalClient()
+	if !ok {
 		return
 	}
+	redisUniversal = client
 
 	// 检测是否建立连接(需要传递上下文)
 	timeoutCtx, cancelFunc := context.WithTimeout(context.Background(), time.Second*5)
@@ -96,7 +92,11 @@ func redisClientUniversalInit() (isSuccess bool) {
 	// 设置redis key的公用前缀
 	prefix := conf.GetKeyPrefix()
 	if len(prefix) <= 0 {
-		uniqueId := snowflaker.GetSnowFlakeInstance().GetUniqueId()
+		uniqueId, err := snowflaker.GetSnowFlakeInstance().GetUniqueId()
+		if err != nil {
+			trace.Error("redisClientUniversalInit generate key prefix failed, error=%v", err.Error())
+			return
+		}
 		prefix = strconv.FormatInt(uniqueId, 10)
 		trace.Notice("GetKeyPrefix prefix is empty, generate one prefix=%v", prefix)
 	}
@@ -106,6 +106,62 @@ func redisClientUniversalInit() (isSuccess bool) {
 	return true
 }
 
+// newUniversalClient 根据配置创建单节点或者集群模式的redis客户端
+func newUniversalClient() (redis.UniversalClient, bool) {
+	addrs, _ := conf.GetRedisAddr()
+	if len(addrs) == 0 {
+		return nil, false
+	}
+
+	_, password := conf.GetRedisUserInfo()
+	switch conf.GetRedisMode() {
+	case conf.RedisModeSingle:
+		return redis.NewClient(&redis.Options{
+			Addr:     addrs[0],
+			DB:       conf.GetRedisDb(),
+			Password: password,
+		}), true
+	case conf.RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    addrs,
+			Password: password,
+		}), true
+	default:
+		trace.Error("newUniversalClient wrong redis mode =%v", conf.GetRedisMode())
+		return nil, false
+	}
+}
+
+// Ping 使用正在使用的连接ping redis 供健康检查使用
+func Ping(ctx context.Context) error {
+	if redisUniversal == nil {
+		return errors.New("redis client is not initialized")
+	}
+
+	return redisUniversal.Ping(ctx).Err()
+}
+
+/**
+ * Probe
+ * 按当前配置新建redis连接并ping 不影响正在使用的连接 供启动自检使用
+ *
+ * @param timeout time.Duration - ping超时时间
+ * @return error - 连接失败的原因
+ */
+
+func Probe(timeout time.Duration) error {
+	client, ok := newUniversalClient()
+	if !ok {
+		return errors.New("invalid redis configuration")
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return client.Ping(ctx).Err()
+}
+
 /**
  * Lock
  * 为给定的信息加锁 加锁失败则尝试5次每次间隔20ms
@@ -123,16 +179,19 @@ func Lock(lock *types.RedisLockInfo) bool {
 		bIsExist, err := redisUniversal.SetNX(ctx, lock.Key, strconv.FormatInt(lock.Owner, 10), lock.Expire).Result()
 		if nil != err {
 			trace.Error("redisdb setnx lock failed,key=%v, server id=%v, error=%v", lock.Key, lock.Owner, err.Error())
+			metrics.IncRedisLock(lock.Name, metrics.LockError)
 			time.Sleep(time.Millisecond * time.Duration(tryInterval))
 			continue
 		}
 		//锁被占用
 		if !bIsExist {
 			trace.Error("redisdb setnx lock already locked, key=%v, server id=%v", lock.Key, lock.Owner)
+			metrics.IncRedisLock(lock.Name, metrics.LockContended)
 			time.Sleep(time.Millisecond * time.Duration(tryInterval))
 			continue
 		}
 		bIsLocked = true
+		metrics.IncRedisLock(lock.Name, metrics.LockAcquired)
 		break
 	}
 
@@ -153,14 +212,17 @@ func TryLock(lock *types.RedisLockInfo) (bIsLocked bool) {
 	bIsExist, err := redisUniversal.SetNX(ctx, lock.Key, strconv.FormatInt(lock.Owner, 10), lock.Expire).Result()
 	if nil != err {
 		trace.Error("redisdb setnx try lock failed, key=%v, server id=%v, error=%v", lock.Key, lock.Owner, err.Error())
+		metrics.IncRedisLock(lock.Name, metrics.LockError)
 		return
 	}
 	//锁被占用
 	if !bIsExist {
 		trace.Warning("redisdb setnx try lock already locked, key=%v, server id=%v", lock.Key, lock.Owner)
+		metrics.IncRedisLock(lock.Name, metrics.LockContended)
 		return
 	}
 	bIsLocked = true
+	metrics.IncRedisLock(lock.Name, metrics.LockAcquired)
 
 	return bIsLocked
 }
@@ -188,6 +250,32 @@ func Unlock(lock *types.RedisLockInfo) {
 	return
 }
 
+/**
+ * EvalScript
+ * 执行lua脚本 脚本在redis中原子执行 用于需要多条命令原子完成的场景
+ * 集群模式下keys需要落在同一个slot上 调用方需要使用hash tag
+ *
+ * @param script *redis.Script - lua脚本
+ * @param keys []string - 脚本中使用的KEYS
+ * @param args ...interface{} - 脚本中使用的ARGV
+ * @return interface{} - 脚本返回值
+ * @return error - 错误信息 如果执行没报错则返回nil
+ */
+
+func EvalScript(script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	ctx := context.Background()
+	val, err := script.Run(ctx, redisUniversal, keys, args...).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil //脚本返回nil则认为是非错误
+		}
+		trace.Error("EvalScript keys=%v failed, err=%v", keys, err.Error())
+		return nil, err
+	}
+
+	return val, nil
+}
+
 // Set 增
 func Set(key, value string, expired time.Duration) (string, error) {
 	ctx := context.Background()
@@ -200,6 +288,18 @@ func Set(key, value string, expired time.Duration) (string, error) {
 	return val, nil
 }
 
+// SetNX key不存在时设置 返回是否设置成功
+func SetNX(key, value string, expired time.Duration) (bool, error) {
+	ctx := context.Background()
+	ok, err := redisUniversal.SetNX(ctx, key, value, expired).Result()
+	if err != nil {
+		trace.Error("SetNX key=%v, value=%v, err=%v", key, value, err.Error())
+		return false, err
+	}
+
+	return ok, nil
+}
+
 func Delete(key string) (int64, error) {
 	ctx := context.Background()
 	return redisUniversal.Del(ctx, key).Result()
@@ -372,6 +472,138 @@ func HGetAll(hashTable string) (map[string]string, error) {
 	return dstMap, nil
 }
 
+// SMembers 读取集合的所有成员
+func SMembers(key string) ([]string, error) {
+	ctx := context.Background()
+	val, err := redisUniversal.SMembers(ctx, key).Result()
+	if err != nil {
+		trace.Error("SMembers key=%v, failed, err=%v", key, err.Error())
+		return nil, err
+	}
+	return val, nil
+}
+
+/**
+ * ZAdd
+ * 设置有序集合成员的分数 成员不存在时新增
+ *
+ * @param key string - 有序集合的key
+ * @param member string - 成员
+ * @param score float64 - 分数
+ * @param expiration time.Duration - 有序集合的过期时间
+ * @return error - 错误信息 如果设置没报错则返回nil
+ */
+
+func ZAdd(key, member string, score float64, expiration time.Duration) error {
+	ctx := context.Background()
+	if err := redisUniversal.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err(); err != nil {
+		trace.Error("ZAdd key=%v, member=%v, score=%v, err=%v", key, member, score, err.Error())
+		return err
+	}
+
+	// 设置有序集合的过期时间
+	if err := redisUniversal.Expire(ctx, key, expiration).Err(); err != nil {
+		trace.Error("ZAdd key=%v, member=%v, expire failed, err=%v", key, member, err.Error())
+		return err
+	}
+	return nil
+}
+
+// ZRangeWithScores 读取有序集合的所有成员和分数 按分数从小到大排列
+func ZRangeWithScores(key string) ([]redis.Z, error) {
+	ctx := context.Background()
+	val, err := redisUniversal.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		trace.Error("ZRangeWithScores key=%v, failed, err=%v", key, err.Error())
+		return nil, err
+	}
+	return val, nil
+}
+
+// ZRemRangeByScore 删除有序集合中分数在[min, max]之间的成员 返回删除的数量
+func ZRemRangeByScore(key string, min, max float64) (int64, error) {
+	ctx := context.Background()
+	val, err := redisUniversal.ZRemRangeByScore(ctx, key,
+		strconv.FormatFloat(min, 'f', -1, 64), strconv.FormatFloat(max, 'f', -1, 64)).Result()
+	if err != nil {
+		trace.Error("ZRemRangeByScore key=%v, min=%v, max=%v failed, err=%v", key, min, max, err.Error())
+		return 0, err
+	}
+	return val, nil
+}
+
+// ZRem 删除有序集合的成员 返回删除的数量
+func ZRem(key string, member string) (int64, error) {
+	ctx := context.Background()
+	val, err := redisUniversal.ZRem(ctx, key, member).Result()
+	if err != nil {
+		trace.Error("ZRem key=%v, member=%v failed, err=%v", key, member, err.Error())
+		return 0, err
+	}
+	return val, nil
+}
+
+// ZScore 读取有序集合成员的分数 成员不存在时返回false
+func ZScore(key, member string) (float64, bool, error) {
+	ctx := context.Background()
+	val, err := redisUniversal.ZScore(ctx, key, member).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, false, nil //成员不存在则认为是非错误
+		}
+		trace.Error("ZScore key=%v, member=%v failed, err=%v", key, member, err.Error())
+		return 0, false, err
+	}
+	return val, true, nil
+}
+
+// ZCount 统计有序集合中分数在[min, max]之间的成员数量 min max可以使用"-inf" "+inf" "("等redis语法
+func ZCount(key, min, max string) (int64, error) {
+	ctx := context.Background()
+	val, err := redisUniversal.ZCount(ctx, key, min, max).Result()
+	if err != nil {
+		trace.Error("ZCount key=%v, min=%v, max=%v failed, err=%v", key, min, max, err.Error())
+		return 0, err
+	}
+	return val, nil
+}
+
+/**
+ * HScan
+ * 使用hscan命令分批读取hash表 不会像HGetAll一样在大表上阻塞redis
+ *
+ * @param hashTable string - hash表名
+ * @param cursor uint64 - 游标 第一次调用传0
+ * @param count int64 - 每批读取数量的建议值
+ * @return []string - field value交替排列
+ * @return uint64 - 下一次调用的游标 为0表示遍历结束
+ * @return error - 错误信息 如果读取没报错则返回nil
+ */
+
+func HScan(hashTable string, cursor uint64, count int64) ([]string, uint64, error) {
+	ctx := context.Background()
+	val, next, err := redisUniversal.HScan(ctx, hashTable, cursor, "", count).Result()
+	if err != nil {
+		trace.Error("HScan hashTable=%v, cursor=%v failed, err=%v", hashTable, cursor, err.Error())
+		return nil, 0, err
+	}
+	return val, next, nil
+}
+
+// SAdd 向集合添加成员并刷新集合的过期时间
+func SAdd(key, member string, expiration time.Duration) error {
+	ctx := context.Background()
+	if err := redisUniversal.SAdd(ctx, key, member).Err(); err != nil {
+		trace.Error("SAdd key=%v, member=%v failed, err=%v", key, member, err.Error())
+		return err
+	}
+	if err := redisUniversal.Expire(ctx, key, expiration).Err(); err != nil {
+		trace.Error("SAdd key=%v, member=%v, expire failed, err=%v", key, member, err.Error())
+		return err
+	}
+	return nil
+}
+
 // LAppend 向list追加数据
 func LAppend(list, val string, expiration time.Duration) error {
 	ctx := context.Background()
@@ -1,10 +1,10 @@
 package uiddb
 
 import (
+	"context"
 	"errors"
 	"github.com/beego/beego/v2/client/orm"
 	_ "github.com/go-sql-driver/mysql"
-	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
 	"sl.framework.com/game_server/error_code"
 	"sl.framework.com/tool"
@@ -25,6 +25,16 @@ func GetUidDBOrm() orm.Ormer {
 	return orm.NewOrmUsingDB(alias)
 }
 
+// Ping 检测uid数据库连接池是否可用 供健康检查使用
+func Ping(ctx context.Context) error {
+	db, err := orm.GetDB(conf.GetUidDbAliasName())
+	if err != nil {
+		return err
+	}
+
+	return db.PingContext(ctx)
+}
+
 func OrmUidDbInit() (err error) {
 	fn := func() {
 		dsn := conf.GetMySqlDsnUidDb()
@@ -58,6 +68,7 @@ func OrmUidDbInit() (err error) {
 
 /*
 	UniqueIdGenerator连接g32_uid数据库，读表go_worker_node
+	只供UniqueIdController使用 本服务的server id和雪花算法节点id由base.WorkerLease分配
 */
 
 var (
@@ -86,33 +97,6 @@ func (u *UniqueIdGenerator) GetUniqueId() (err error, serverId int64) {
 	return u.mustGetUniqueId(countLoop)
 }
 
-// AsyncSetUniqueId 程序启动时候异步设置本服务Server ID
-func (u *UniqueIdGenerator) AsyncSetUniqueId() {
-	async.AsyncRunCoroutine(func() {
-		var countLoop int64 = 0
-		err, id := u.mustGetUniqueId(countLoop)
-		if nil != err {
-			trace.Error("AsyncSetUniqueId failed, count loop=%v", countLoop)
-			return
-		}
-
-		async.AsyncRunCoroutine(func() {
-			conf.SetServerId(id)
-		})
-	})
-}
-
-// SetUniqueId 程序启动时候异步设置本服务Server ID
-func (u *UniqueIdGenerator) SetUniqueId() {
-	var countLoop int64 = 0
-	err, id := u.mustGetUniqueId(countLoop)
-	if nil != err {
-		trace.Error("AsyncSetUniqueId failed, count loop=%v", countLoop)
-		return
-	}
-	conf.SetServerId(id)
-}
-
 // MustGetServerId 获取ServerId并将Id+1写入数据库
 func (u *UniqueIdGenerator) mustGetUniqueId(countLoop int64) (err error, serverId int64) {
 	retQuery, retInsert := -1, -1
@@ -0,0 +1,218 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/beego/beego/v2/server/web/context"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/controller/base_controller"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/redis/cache"
+	"sl.framework.com/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerAuthorization = "Authorization"
+	bearerPrefix        = "Bearer "
+
+	principalDataKey = "PlayerPrincipal" //玩家身份在context.Input中的key
+)
+
+/**
+ * PlayerAuthFilter
+ * 玩家身份校验 在路由前执行 配置中未开启时直接放行
+ * 1.校验Authorization头中的token
+ * 2.token中的用户 币种 房间必须与请求头以及body中的一致 请求头中没有的用户信息使用token中的值补齐
+ * 3.开启会话校验时token中的sid必须与用户会话缓存中的一致
+ * 校验通过后将玩家身份放入请求上下文 通过PrincipalFromContext获取
+ *
+ * @param ctx *context.Context - 请求上下文
+ * @return
+ */
+
+func PlayerAuthFilter(ctx *context.Context) {
+	auth := conf.GetPlayerAuth()
+	if !auth.Enable {
+		return
+	}
+
+	traceId := ctx.Input.Header(string(base_controller.TagTraceId))
+	principal, code := verifyPlayer(ctx, auth, time.Now())
+	if errcode.ErrorOk != code {
+		trace.FromContext(ctx.Request.Context()).Error("PlayerAuthFilter 玩家身份校验失败", "path", ctx.Request.URL.Path, "code", code)
+		abortWithCode(ctx, traceId, code)
+		return
+	}
+
+	ctx.Input.SetData(principalDataKey, principal)
+	//之后的日志使用token中校验过的用户Id
+	ctx.Request = ctx.Request.WithContext(trace.WithUserId(ctx.Request.Context(), principal.UserId))
+	trace.FromContext(ctx.Request.Context()).Debug("PlayerAuthFilter 玩家身份校验通过", "path", ctx.Request.URL.Path,
+		"principal", fmt.Sprintf("%+v", *principal))
+}
+
+/**
+ * PrincipalFromContext
+ * 获取经过校验的玩家身份
+ *
+ * @param ctx *context.Context - 请求上下文
+ * @return *dto.PrincipalDTO - 玩家身份
+ * @return bool - 是否存在 未开启校验时为false
+ */
+
+func PrincipalFromContext(ctx *context.Context) (*dto.PrincipalDTO, bool) {
+	principal, ok := ctx.Input.GetData(principalDataKey).(*dto.PrincipalDTO)
+	return principal, ok
+}
+
+// verifyPlayer 校验token并与请求比对 返回玩家身份
+func verifyPlayer(ctx *context.Context, auth conf.PlayerAuth, now time.Time) (*dto.PrincipalDTO, int) {
+	token := ctx.Input.Header(headerAuthorization)
+	if !strings.HasPrefix(token, bearerPrefix) || len(token) == len(bearerPrefix) {
+		return nil, errcode.AuthErrorTokenMissing
+	}
+	claims, code := parsePlayerToken(strings.TrimPrefix(token, bearerPrefix), auth, now)
+	if errcode.ErrorOk != code {
+		return nil, code
+	}
+
+	principal := &dto.PrincipalDTO{
+		UserName:  claims.UserName,
+		UserType:  claims.UserType,
+		Currency:  claims.Currency,
+		SessionId: claims.SessionId,
+	}
+	userId, err := strconv.ParseInt(string(claims.Subject), 10, 64)
+	if err != nil || userId <= 0 {
+		//sub缺失或者不是数字 不能使用0作为用户Id放行
+		return nil, errcode.AuthErrorTokenInvalid
+	}
+	principal.UserId = userId
+	if len(claims.GameRoomId) > 0 {
+		if principal.GameRoomId, err = strconv.ParseInt(string(claims.GameRoomId), 10, 64); err != nil {
+			return nil, errcode.AuthErrorTokenInvalid
+		}
+	}
+
+	if code = matchHeaders(ctx, principal); errcode.ErrorOk != code {
+		return nil, code
+	}
+	gameRoomId, code := matchBody(ctx.Input.RequestBody, principal)
+	if errcode.ErrorOk != code {
+		return nil, code
+	}
+	if auth.SessionCheck {
+		if code = matchSession(ctx, principal, gameRoomId); errcode.ErrorOk != code {
+			return nil, code
+		}
+	}
+
+	return principal, errcode.ErrorOk
+}
+
+// matchHeaders 请求头中的用户信息必须与token一致 请求头中没有时使用token中的值补齐 保证控制器读到的都是校验过的值
+func matchHeaders(ctx *context.Context, principal *dto.PrincipalDTO) int {
+	headers := []struct {
+		tag   base_controller.Tag
+		value string
+	}{
+		{tag: base_controller.TagUserId, value: strconv.FormatInt(principal.UserId, 10)},
+		{tag: base_controller.TagUserName, value: principal.UserName},
+		{tag: base_controller.TagUserType, value: principal.UserType},
+		{tag: base_controller.TagCurrency, value: principal.Currency},
+	}
+	for _, h := range headers {
+		if len(h.value) == 0 {
+			continue
+		}
+		header := ctx.Input.Header(string(h.tag))
+		if len(header) == 0 {
+			ctx.Request.Header.Set(string(h.tag), h.value)
+			continue
+		}
+		if header != h.value {
+			trace.Error("matchHeaders header=%v, value=%v, token value=%v mismatch", h.tag, header, h.value)
+			return errcode.AuthErrorPrincipalMismatch
+		}
+	}
+	return errcode.ErrorOk
+}
+
+/**
+ * matchBody
+ * body中的gameRoomId以及currency必须与token一致 token中未携带的字段不校验
+ *
+ * @param body []byte - 请求body 可以为空
+ * @param principal *dto.PrincipalDTO - 玩家身份
+ * @return int64 - body中的房间Id 没有时为token中的房间Id
+ * @return int - 校验结果
+ */
+
+func matchBody(body []byte, principal *dto.PrincipalDTO) (int64, int) {
+	gameRoomId := principal.GameRoomId
+	if len(body) == 0 {
+		return gameRoomId, errcode.ErrorOk
+	}
+
+	var param struct {
+		GameRoomId json.RawMessage `json:"gameRoomId"`
+		Currency   string          `json:"currency"`
+	}
+	if err := json.Unmarshal(body, &param); err != nil {
+		//body格式错误由控制器处理
+		return gameRoomId, errcode.ErrorOk
+	}
+
+	if len(param.GameRoomId) > 0 {
+		var roomId claimString
+		if err := json.Unmarshal(param.GameRoomId, &roomId); err != nil {
+			return 0, errcode.AuthErrorPrincipalMismatch
+		}
+		bodyRoomId, err := strconv.ParseInt(string(roomId), 10, 64)
+		if err != nil || (principal.GameRoomId != 0 && bodyRoomId != principal.GameRoomId) {
+			trace.Error("matchBody gameRoomId=%v, token gameRoomId=%v mismatch", string(roomId), principal.GameRoomId)
+			return 0, errcode.AuthErrorPrincipalMismatch
+		}
+		gameRoomId = bodyRoomId
+	}
+	if len(param.Currency) > 0 && len(principal.Currency) > 0 && param.Currency != principal.Currency {
+		trace.Error("matchBody currency=%v, token currency=%v mismatch", param.Currency, principal.Currency)
+		return 0, errcode.AuthErrorPrincipalMismatch
+	}
+	return gameRoomId, errcode.ErrorOk
+}
+
+// matchSession token中的sid必须与用户在房间内的会话一致
+func matchSession(ctx *context.Context, principal *dto.PrincipalDTO, gameRoomId int64) int {
+	if len(principal.SessionId) == 0 || gameRoomId == 0 {
+		return errcode.AuthErrorSessionInvalid
+	}
+	sessionCache := cache.UserSessionCache{
+		TraceId:    ctx.Input.Header(string(base_controller.TagTraceId)),
+		GameRoomId: gameRoomId,
+		UserId:     principal.UserId,
+	}
+	if !sessionCache.Get() || sessionCache.Data.SessionId != principal.SessionId {
+		return errcode.AuthErrorSessionInvalid
+	}
+	return errcode.ErrorOk
+}
+
+// abortWithCode 以与控制器一致的格式回包并终止请求
+func abortWithCode(ctx *context.Context, traceId string, code int) {
+	res := base_controller.HttpResponse{
+		Code: fmt.Sprintf("%04d", code), Msg: errcode.GetErrMsg(code), Data: "",
+	}
+	data, _ := json.Marshal(res)
+
+	ctx.Output.Header(string(base_controller.TagTraceId), traceId)
+	ctx.Output.Header("Content-Type", "application/json;charset=utf-8")
+	ctx.Output.SetStatus(base_controller.HttpStatusError)
+	if err := ctx.Output.Body(data); err != nil {
+		trace.Error("abortWithCode traceId=%v, write body error=%v", traceId, err.Error())
+	}
+}
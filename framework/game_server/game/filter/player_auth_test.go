@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"net/http/httptest"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/redis/cache"
+	"testing"
+	"time"
+)
+
+var testNow = time.Unix(1700000000, 0)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hs256Token(t *testing.T, secret string, claims map[string]interface{}) string {
+	input := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func testClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":      "10001",
+		"name":     "player",
+		"currency": "CNY",
+		"roomId":   2001,
+		"sid":      "s1",
+		"exp":      testNow.Add(time.Hour).Unix(),
+	}
+}
+
+func newTestContext(token, userId, body string) *context.Context {
+	req := httptest.NewRequest(http.MethodPost, "/bet", bytes.NewBufferString(body))
+	if len(token) > 0 {
+		req.Header.Set(headerAuthorization, bearerPrefix+token)
+	}
+	if len(userId) > 0 {
+		req.Header.Set("User-Id", userId)
+	}
+	ctx := context.NewContext()
+	ctx.Reset(httptest.NewRecorder(), req)
+	ctx.Input.RequestBody = []byte(body)
+	return ctx
+}
+
+func TestVerifyPlayer(t *testing.T) {
+	auth := conf.PlayerAuth{Enable: true, Algorithm: algorithmHS256, Secret: "secret"}
+	token := hs256Token(t, "secret", testClaims())
+
+	expired := testClaims()
+	expired["exp"] = testNow.Add(-time.Minute).Unix()
+	noExp := testClaims()
+	delete(noExp, "exp")
+	noSub := testClaims()
+	delete(noSub, "sub")
+	badSub := testClaims()
+	badSub["sub"] = "player"
+
+	tests := []struct {
+		name   string
+		token  string
+		userId string
+		body   string
+		want   int
+	}{
+		{name: "ok", token: token, userId: "10001", body: `{"gameRoomId":"2001","currency":"CNY"}`, want: errcode.ErrorOk},
+		{name: "ok without headers", token: token, body: `{"gameRoomId":2001}`, want: errcode.ErrorOk},
+		{name: "missing", want: errcode.AuthErrorTokenMissing},
+		{name: "malformed", token: "abc", want: errcode.AuthErrorTokenInvalid},
+		{name: "wrong secret", token: hs256Token(t, "other", testClaims()), want: errcode.AuthErrorTokenInvalid},
+		{name: "expired", token: hs256Token(t, "secret", expired), want: errcode.AuthErrorTokenExpired},
+		{name: "no exp", token: hs256Token(t, "secret", noExp), want: errcode.AuthErrorTokenInvalid},
+		{name: "no sub", token: hs256Token(t, "secret", noSub), want: errcode.AuthErrorTokenInvalid},
+		{name: "non numeric sub", token: hs256Token(t, "secret", badSub), want: errcode.AuthErrorTokenInvalid},
+		{name: "user mismatch", token: token, userId: "10002", want: errcode.AuthErrorPrincipalMismatch},
+		{name: "room mismatch", token: token, body: `{"gameRoomId":"2002"}`, want: errcode.AuthErrorPrincipalMismatch},
+		{name: "currency mismatch", token: token, body: `{"currency":"USD"}`, want: errcode.AuthErrorPrincipalMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(tt.token, tt.userId, tt.body)
+			principal, code := verifyPlayer(ctx, auth, testNow)
+			if code != tt.want {
+				t.Fatalf("verifyPlayer() code = %v, want %v", code, tt.want)
+			}
+			if code != errcode.ErrorOk {
+				return
+			}
+			want := dto.PrincipalDTO{UserId: 10001, UserName: "player", Currency: "CNY", GameRoomId: 2001, SessionId: "s1"}
+			if *principal != want {
+				t.Errorf("verifyPlayer() principal = %+v, want %+v", *principal, want)
+			}
+			if ctx.Input.Header("User-Id") != "10001" || ctx.Input.Header("currency") != "CNY" {
+				t.Errorf("verifyPlayer() headers not filled from token, header=%v", ctx.Request.Header)
+			}
+		})
+	}
+}
+
+func TestVerifyPlayerRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	auth := conf.PlayerAuth{
+		Enable:    true,
+		Algorithm: algorithmRS256,
+		Secret:    "secret",
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}
+
+	input := encodeSegment(t, map[string]string{"alg": "RS256"}) + "." + encodeSegment(t, testClaims())
+	hash := sha256.Sum256([]byte(input))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	token := input + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	if _, code := verifyPlayer(newTestContext(token, "", ""), auth, testNow); code != errcode.ErrorOk {
+		t.Errorf("verifyPlayer() RS256 code = %v, want ok", code)
+	}
+	//配置为RS256时HS256签名的token必须被拒绝
+	if _, code := verifyPlayer(newTestContext(hs256Token(t, "secret", testClaims()), "", ""), auth, testNow); code != errcode.AuthErrorTokenInvalid {
+		t.Errorf("verifyPlayer() alg confusion code = %v, want %v", code, errcode.AuthErrorTokenInvalid)
+	}
+}
+
+func TestVerifyPlayerSession(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	sessionCache := cache.UserSessionCache{GameRoomId: 2001, UserId: 10001}
+	if !sessionCache.Set(&dto.UserSessionDTO{UserId: 10001, GameRoomId: 2001, SessionId: "s1"}) {
+		t.Fatal("set session failed")
+	}
+
+	auth := conf.PlayerAuth{Enable: true, Algorithm: algorithmHS256, Secret: "secret", SessionCheck: true}
+	if _, code := verifyPlayer(newTestContext(hs256Token(t, "secret", testClaims()), "", ""), auth, testNow); code != errcode.ErrorOk {
+		t.Errorf("verifyPlayer() session code = %v, want ok", code)
+	}
+
+	claims := testClaims()
+	claims["sid"] = "s2"
+	if _, code := verifyPlayer(newTestContext(hs256Token(t, "secret", claims), "", ""), auth, testNow); code != errcode.AuthErrorSessionInvalid {
+		t.Errorf("verifyPlayer() stale session code = %v, want %v", code, errcode.AuthErrorSessionInvalid)
+	}
+}
+
+func TestPlayerAuthFilterRejects(t *testing.T) {
+	conf.SetCurrent(&conf.Configuration{PlayerAuth: conf.PlayerAuth{Enable: true, Algorithm: algorithmHS256, Secret: "secret"}})
+	defer conf.SetCurrent(nil)
+
+	ctx := newTestContext("", "10001", "")
+	PlayerAuthFilter(ctx)
+	if !ctx.ResponseWriter.Started || ctx.ResponseWriter.Status != http.StatusBadRequest {
+		t.Errorf("PlayerAuthFilter() started=%v status=%v, want rejected", ctx.ResponseWriter.Started, ctx.ResponseWriter.Status)
+	}
+	if _, ok := PrincipalFromContext(ctx); ok {
+		t.Errorf("PrincipalFromContext() ok = true, want false")
+	}
+}
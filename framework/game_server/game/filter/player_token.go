@@ -0,0 +1,187 @@
+package filter
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/error_code"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 支持的token签名算法
+const (
+	algorithmHS256 = "HS256"
+	algorithmRS256 = "RS256"
+)
+
+/*
+	playerClaims 玩家token中的声明
+	token为标准JWT格式 sub为用户Id 其余字段由能力中心签发时写入
+*/
+
+type playerClaims struct {
+	Subject    claimString `json:"sub"`      //用户Id
+	UserName   string      `json:"name"`     //用户名
+	UserType   string      `json:"userType"` //用户类型
+	Currency   string      `json:"currency"` //币种
+	GameRoomId claimString `json:"roomId"`   //限定的游戏房间 可选
+	SessionId  string      `json:"sid"`      //会话Id
+	ExpiresAt  int64       `json:"exp"`      //过期时间 秒 必须携带
+	NotBefore  int64       `json:"nbf"`      //生效时间 秒 可选
+}
+
+// claimString 兼容JSON中的字符串和数字 用户Id以及房间Id在不同签发方中可能是任一种形式
+type claimString string
+
+func (c *claimString) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*c = claimString(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*c = claimString(n.String())
+	return nil
+}
+
+// publicKeyCache 解析后的RSA公钥 配置不变时不重复解析
+var publicKeyCache struct {
+	sync.Mutex
+	pem string
+	key *rsa.PublicKey
+}
+
+/**
+ * parsePlayerToken
+ * 校验玩家token的签名以及有效期并解析声明
+ * token头中的alg必须与配置一致 防止算法混淆攻击
+ *
+ * @param token string - JWT token
+ * @param auth conf.PlayerAuth - 校验配置
+ * @param now time.Time - 当前时间
+ * @return *playerClaims - 解析得到的声明
+ * @return int - 校验结果 成功返回errcode.ErrorOk
+ */
+
+func parsePlayerToken(token string, auth conf.PlayerAuth, now time.Time) (*playerClaims, int) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errcode.AuthErrorTokenInvalid
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != auth.Algorithm {
+		return nil, errcode.AuthErrorTokenInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errcode.AuthErrorTokenInvalid
+	}
+	if err = verifyTokenSignature(parts[0]+"."+parts[1], signature, auth); err != nil {
+		return nil, errcode.AuthErrorTokenInvalid
+	}
+
+	claims := new(playerClaims)
+	if err = decodeSegment(parts[1], claims); err != nil || len(claims.Subject) == 0 || claims.ExpiresAt <= 0 {
+		return nil, errcode.AuthErrorTokenInvalid
+	}
+	leeway := time.Duration(auth.Leeway) * time.Second
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(leeway)) {
+		return nil, errcode.AuthErrorTokenExpired
+	}
+	if claims.NotBefore > 0 && now.Add(leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, errcode.AuthErrorTokenInvalid
+	}
+	if _, err = strconv.ParseInt(string(claims.Subject), 10, 64); err != nil {
+		return nil, errcode.AuthErrorTokenInvalid
+	}
+	if len(claims.GameRoomId) > 0 {
+		if _, err = strconv.ParseInt(string(claims.GameRoomId), 10, 64); err != nil {
+			return nil, errcode.AuthErrorTokenInvalid
+		}
+	}
+
+	return claims, errcode.ErrorOk
+}
+
+// decodeSegment base64url解码JWT的一段并反序列化
+func decodeSegment(segment string, receiver interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, receiver)
+}
+
+// verifyTokenSignature 按配置的算法校验签名
+func verifyTokenSignature(signingInput string, signature []byte, auth conf.PlayerAuth) error {
+	switch auth.Algorithm {
+	case algorithmHS256:
+		if len(auth.Secret) == 0 {
+			return errors.New("empty secret")
+		}
+		mac := hmac.New(sha256.New, []byte(auth.Secret))
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	case algorithmRS256:
+		key, err := parsePublicKey(auth.PublicKey)
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature)
+	default:
+		return errors.New("unsupported algorithm " + auth.Algorithm)
+	}
+}
+
+// parsePublicKey 解析PEM格式的RSA公钥 支持PKIX以及PKCS1格式
+func parsePublicKey(pemData string) (*rsa.PublicKey, error) {
+	publicKeyCache.Lock()
+	defer publicKeyCache.Unlock()
+	if publicKeyCache.key != nil && publicKeyCache.pem == pemData {
+		return publicKeyCache.key, nil
+	}
+
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, errors.New("invalid public key pem")
+	}
+	var key *rsa.PublicKey
+	if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not rsa")
+		}
+		key = rsaKey
+	} else if key, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+		return nil, err
+	}
+
+	publicKeyCache.pem = pemData
+	publicKeyCache.key = key
+	return key, nil
+}
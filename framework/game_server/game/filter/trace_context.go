@@ -0,0 +1,27 @@
+package filter
+
+import (
+	"github.com/beego/beego/v2/server/web/context"
+	"sl.framework.com/game_server/game/controller/base_controller"
+	"sl.framework.com/trace"
+)
+
+/**
+ * TraceContextFilter
+ * 把请求头中的traceId和用户Id放入请求的context 在所有过滤器之前执行
+ * 控制器通过BaseController.Log打印的日志自动带上这些字段
+ *
+ * @param ctx *context.Context - 请求上下文
+ */
+
+func TraceContextFilter(ctx *context.Context) {
+	traceId := ctx.Input.Header(string(base_controller.TagTraceId))
+	if len(traceId) == 0 {
+		traceId = ctx.Input.Header(string(base_controller.TagRequestId))
+	}
+	logCtx := trace.WithTraceId(ctx.Request.Context(), traceId)
+	if userId := ctx.Input.Header(string(base_controller.TagUserId)); len(userId) > 0 {
+		logCtx = trace.WithUserId(logCtx, userId)
+	}
+	ctx.Request = ctx.Request.WithContext(logCtx)
+}
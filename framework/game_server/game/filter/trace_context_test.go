@@ -0,0 +1,21 @@
+package filter
+
+import (
+	"sl.framework.com/game_server/game/controller/base_controller"
+	"sl.framework.com/trace"
+	"testing"
+)
+
+func TestTraceContextFilter(t *testing.T) {
+	ctx := newTestContext("", "1001", "")
+	ctx.Request.Header.Set(string(base_controller.TagTraceId), "trace-1")
+	TraceContextFilter(ctx)
+
+	fields := trace.FieldsFromContext(ctx.Request.Context())
+	if trace.TraceIdFromContext(ctx.Request.Context()) != "trace-1" {
+		t.Errorf("traceId fields = %+v, want trace-1", fields)
+	}
+	if len(fields) != 2 || fields[1].Key != trace.FieldUserId || fields[1].Value != "1001" {
+		t.Errorf("fields = %+v, want userId 1001", fields)
+	}
+}
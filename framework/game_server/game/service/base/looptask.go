@@ -1,13 +1,24 @@
 package base
 
 import (
+	"context"
+	"fmt"
+	"sl.framework.com/async"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	loopTaskStuckIntervals = 3                //超过该数量的间隔没有完成一次执行视为卡死
+	loopTaskStuckMin       = 30 * time.Second //卡死判断的最小时间 避免间隔很短的任务偶尔变慢就被判断为卡死
+)
+
 // ILoopTask 定时接口
 type ILoopTask interface {
 	RunLoopTask()
+	StopLoopTask(ctx context.Context) error
+	Alive(now time.Time) error
 }
 
 // CLoopTask 定时任务基类
@@ -16,4 +27,101 @@ type CLoopTask struct {
 	interval   time.Duration //定时任务时间间隔,单位ms
 	createTime time.Time     //task创建时间
 	once       sync.Once
+
+	chanOnce sync.Once
+	stopOnce sync.Once
+	stop     chan struct{} //关闭后循环退出
+	done     chan struct{} //循环退出后关闭
+	started  atomic.Bool   //循环协程已经启动
+	lastRun  atomic.Int64  //最近一次执行完成的时间 unix ms 用于检测任务卡死
+}
+
+// channels 延迟创建停止信号 定时任务基类由各任务直接构造
+func (c *CLoopTask) channels() (stop, done chan struct{}) {
+	c.chanOnce.Do(func() {
+		c.stop, c.done = make(chan struct{}), make(chan struct{})
+	})
+
+	return c.stop, c.done
+}
+
+// stopping 停止信号 循环中需要同时监听
+func (c *CLoopTask) stopping() <-chan struct{} {
+	stop, _ := c.channels()
+	return stop
+}
+
+// start 只启动一次循环协程 循环退出时通知StopLoopTask
+func (c *CLoopTask) start(loop func()) {
+	_, done := c.channels()
+	c.once.Do(func() {
+		c.touch(time.Now())
+		c.started.Store(true)
+		async.AsyncRunCoroutine(func() {
+			defer close(done)
+			loop()
+		})
+	})
+}
+
+// touch 记录一次执行完成 循环中每次执行完调用
+func (c *CLoopTask) touch(now time.Time) {
+	c.lastRun.Store(now.UnixMilli())
+}
+
+/**
+ * Alive
+ * 检测循环任务是否存活 供liveness检查使用
+ * 没有启动或者已经停止的任务视为存活 循环协程异常退出或者长时间没有完成一次执行视为卡死
+ *
+ * @param now time.Time - 当前时间
+ * @return error - 卡死的原因
+ */
+
+func (c *CLoopTask) Alive(now time.Time) error {
+	if !c.started.Load() {
+		return nil
+	}
+	stop, done := c.channels()
+	select {
+	case <-stop:
+		return nil
+	default:
+	}
+	select {
+	case <-done:
+		return fmt.Errorf("loop task exited unexpectedly")
+	default:
+	}
+
+	limit := max(loopTaskStuckIntervals*c.interval, loopTaskStuckMin)
+	if elapse := now.Sub(time.UnixMilli(c.lastRun.Load())); elapse > limit {
+		return fmt.Errorf("loop task not run for %v, interval=%v", elapse.Round(time.Second), c.interval)
+	}
+	return nil
+}
+
+/**
+ * StopLoopTask
+ * 通知循环退出并等待正在执行的一轮任务完成 没有启动的任务直接返回
+ *
+ * @param ctx context.Context - 等待超时控制
+ * @return error - 超时返回ctx.Err()
+ */
+
+func (c *CLoopTask) StopLoopTask(ctx context.Context) error {
+	stop, done := c.channels()
+	started := true
+	c.once.Do(func() { started = false }) //还没有启动时不再允许启动
+	c.stopOnce.Do(func() { close(stop) })
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
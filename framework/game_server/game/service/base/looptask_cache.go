@@ -1,7 +1,6 @@
 package base
 
 import (
-	"sl.framework.com/async"
 	"sl.framework.com/trace"
 	"sync"
 	"time"
@@ -45,16 +44,17 @@ func (c *CacheManagerLoopTask) RunLoopTask() {
 		defer c.ticker.Stop()
 		for {
 			select {
+			case <-c.stopping():
+				return
 			case <-c.ticker.C:
 				c.cacheCleanUp()
+				c.touch(time.Now())
 			}
 		}
 	}
 
 	//启动循环任务
-	c.once.Do(func() {
-		async.AsyncRunCoroutine(fn)
-	})
+	c.start(fn)
 }
 
 // 清理缓存任务
@@ -1,152 +1,284 @@
 package base
 
 import (
-	"encoding/json"
-	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
-	"sl.framework.com/game_server/game/dao/redisdb"
 	types "sl.framework.com/game_server/game/service/type"
-	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/game_server/redis/cache"
+	"sl.framework.com/tool"
 	"sl.framework.com/trace"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Version 服务版本号 随心跳上报 编译时通过-ldflags "-X sl.framework.com/game_server/game/service/base.Version=xxx"注入
+var Version = "dev"
+
+const (
+	heartbeatTraceId = "heartbeat"      //心跳日志的traceId
+	roomActiveWindow = 10 * time.Minute //房间最近一次事件在此时间内才上报为本节点处理的房间
+)
+
 var (
 	heartbeatInstanceOnce       sync.Once
 	heartbeatTickerTaskInstance *HeartbeatLoopTask
 )
 
-// GetHeartbeatInstance 获取心跳单例
+// GetHeartbeatInstance 获取心跳单例 需要在设置server id之后调用
 func GetHeartbeatInstance() *HeartbeatLoopTask {
 	heartbeatInstanceOnce.Do(func() {
-		heartbeatTickerTaskInstance = &HeartbeatLoopTask{
-			CLoopTask: &CLoopTask{
-				createTime: time.Now(),
-				interval:   time.Duration(conf.GetHeartbeatInterval()) * time.Second,
-			},
-			clusterNum: 3, //默认值设置为三个
-			serverId:   strconv.FormatInt(conf.GetServerId(), 10),
-		}
+		heartbeatTickerTaskInstance = newHeartbeatLoopTask(strconv.FormatInt(conf.GetServerId(), 10), time.Now())
 	})
 
 	return heartbeatTickerTaskInstance
 }
 
+func newHeartbeatLoopTask(serverId string, startTime time.Time) *HeartbeatLoopTask {
+	return &HeartbeatLoopTask{
+		CLoopTask: &CLoopTask{
+			createTime: startTime,
+			interval:   time.Duration(conf.GetHeartbeatInterval()) * time.Second,
+		},
+		serverId: serverId,
+		ip:       tool.GetLocalIp(),
+		rooms:    make(map[int64]time.Time, 16),
+		members:  make(map[string]*types.ClusterNode, 8),
+	}
+}
+
+/**
+ * MembershipListener
+ * 集群成员变化回调 在心跳协程中同步调用 不能阻塞
+ *
+ * @param joined []*types.ClusterNode - 新加入的节点
+ * @param left []*types.ClusterNode - 主动退出或者心跳超时的节点
+ * @param members []*types.ClusterNode - 当前全部在线节点 按serverId排序
+ */
+
+type MembershipListener func(joined, left, members []*types.ClusterNode)
+
 var _ ILoopTask = (*HeartbeatLoopTask)(nil)
 
-// HeartbeatLoopTask 心跳包循环任务
+// HeartbeatLoopTask 心跳包循环任务 集群成员保存在redis有序集合中 各节点只更新自己的心跳 不需要全局锁
 type HeartbeatLoopTask struct {
 	*CLoopTask
 
-	clusterNumMutex sync.Mutex
-	clusterNum      int
-
 	serverId string
+	ip       string
+	stopped  atomic.Bool //已经退出集群 不再上报心跳 心跳回调继续执行
+
+	roomsMutex sync.Mutex
+	rooms      map[int64]time.Time //本节点处理过的房间 value为最近一次事件时间
+
+	membersMutex sync.Mutex
+	members      map[string]*types.ClusterNode //上次心跳看到的在线节点 key为serverId
+	listeners    []MembershipListener
+	beatHooks    []func(now time.Time) //每次心跳时调用 如续期节点id租约 退出集群后直到心跳任务停止都继续调用
 }
 
 // RunLoopTask 启动loop循环执行逻辑
 func (h *HeartbeatLoopTask) RunLoopTask() {
 	fn := func() {
 		trace.Info("HeartbeatLoopTask RunLoopTask start running, heartbeat interval=%v(s)", h.interval)
+		h.heartbeatSend(time.Now())
 		h.ticker = time.NewTicker(h.interval)
 		defer h.ticker.Stop()
 		for {
 			select {
+			case <-h.stopping():
+				return
 			case <-h.ticker.C:
-				h.heartbeatSend()
+				h.heartbeatSend(time.Now())
+				h.touch(time.Now())
 			}
 		}
 	}
 
-	h.once.Do(func() {
-		async.AsyncRunCoroutine(fn)
-	})
+	h.start(fn)
 }
 
-// 向redis发送心跳包
-func (h *HeartbeatLoopTask) heartbeatSend() {
-	var (
-		err            error
-		val            string
-		jsonData       []byte
-		redisInfo      = rediskey.GetHeartbeatStatusRedisInfo()
-		redisLockInfo  = rediskey.GetHeartbeatStatusLockRedisInfo()
-		serverStatus   = make(map[string]*types.HeartbeatStatus, 8)
-		serverToDelete = make(map[string]*types.HeartbeatStatus, 8)
-	)
-
-	if !redisdb.Lock(redisLockInfo) {
-		trace.Error("HeartbeatLoopTask heartbeatSend lock failed, server id=%v", h.serverId)
+/**
+ * heartbeatSend
+ * 执行心跳回调 上报本节点心跳并刷新集群成员 成员有变化时通知监听者
+ * 退出集群后只执行心跳回调 关闭过程中节点id租约仍然需要续期
+ *
+ * @param now time.Time - 心跳时间
+ * @return
+ */
+
+func (h *HeartbeatLoopTask) heartbeatSend(now time.Time) {
+	h.membersMutex.Lock()
+	beatHooks := h.beatHooks
+	h.membersMutex.Unlock()
+	for _, hook := range beatHooks {
+		runBeatHook(hook, now)
+	}
+	if h.stopped.Load() {
 		return
 	}
-	defer redisdb.Unlock(redisLockInfo)
 
-	//获取redis信息并根据时间做过滤
-	if val, err = redisdb.Get(redisInfo.Key); nil != err {
-		trace.Error("HeartbeatLoopTask heartbeatSend redis get failed, server id=%v, key=%v, err=%v",
-			h.serverId, redisInfo.Key)
+	node := &types.ClusterNode{
+		ServerId:  h.serverId,
+		Ip:        h.ip,
+		Version:   Version,
+		StartTime: h.createTime.UnixMilli(),
+		Rooms:     h.activeRooms(now),
+	}
+	if !cache.HeartbeatClusterNode(heartbeatTraceId, node, now) {
+		trace.Error("HeartbeatLoopTask heartbeatSend failed, server id=%v", h.serverId)
 		return
 	}
-	if len(val) > 0 {
-		if err = json.Unmarshal([]byte(val), &serverStatus); nil != err {
-			trace.Error("HeartbeatLoopTask heartbeatSend json unmarshal failed, server id=%v, key=%v, val=%v, err=%v",
-				h.serverId, redisInfo.Key, val, err.Error())
-			return
-		}
-		for _, v := range serverStatus {
-			if h.serverId == v.ServerId {
-				v.UpdateTime = time.Now()
-			}
-			if time.Since(v.UpdateTime) > time.Duration(conf.GetHeartbeatExpired()) {
-				serverToDelete[v.ServerId] = v
-			}
-		}
-		for k, _ := range serverToDelete {
-			delete(serverStatus, k)
+
+	nodes, ok := cache.GetClusterNodes(heartbeatTraceId, now, time.Duration(conf.GetHeartbeatExpired())*time.Second)
+	if !ok {
+		trace.Error("HeartbeatLoopTask heartbeatSend get cluster nodes failed, server id=%v", h.serverId)
+		return
+	}
+	h.updateMembers(nodes)
+}
+
+/**
+ * updateMembers
+ * 更新在线节点 和上次的成员比较后通知监听者
+ *
+ * @param nodes []*types.ClusterNode - 当前在线节点
+ * @return
+ */
+
+func (h *HeartbeatLoopTask) updateMembers(nodes []*types.ClusterNode) {
+	members := make(map[string]*types.ClusterNode, len(nodes))
+	joined := make([]*types.ClusterNode, 0)
+	left := make([]*types.ClusterNode, 0)
+
+	h.membersMutex.Lock()
+	for _, node := range nodes {
+		members[node.ServerId] = node
+		if _, ok := h.members[node.ServerId]; !ok {
+			joined = append(joined, node)
 		}
-	} else {
-		heartbeatStatus := &types.HeartbeatStatus{
-			ServerId:   h.serverId,
-			UpdateTime: time.Now(),
+	}
+	for serverId, node := range h.members {
+		if _, ok := members[serverId]; !ok {
+			left = append(left, node)
 		}
-		serverStatus[h.serverId] = heartbeatStatus
 	}
+	h.members = members
+	listeners := h.listeners
+	h.membersMutex.Unlock()
 
-	h.clusterNumMutex.Lock()
-	h.clusterNum = len(serverStatus)
-	h.clusterNumMutex.Unlock()
-
-	//更新redis缓存
-	if jsonData, err = json.Marshal(serverStatus); nil != err {
-		trace.Error("HeartbeatLoopTask heartbeatSend json marshal failed, server id=%v, status=%+v, err=%v",
-			h.serverId, serverStatus, err.Error())
+	if len(joined) == 0 && len(left) == 0 {
 		return
 	}
-	if _, err = redisdb.Set(redisInfo.Key, string(jsonData), redisInfo.Expire); nil != err {
-		trace.Error("HeartbeatLoopTask heartbeatSend failed, key=%v, "+
-			"server id=%v, error=%v", redisInfo.Key, h.serverId, err.Error())
-		return
+	sort.Slice(left, func(i, j int) bool { return left[i].ServerId < left[j].ServerId })
+	trace.Notice("HeartbeatLoopTask cluster membership changed, server id=%v, joined=%v, left=%v, online=%v",
+		h.serverId, serverIds(joined), serverIds(left), len(nodes))
+	for _, listener := range listeners {
+		notifyMembership(listener, joined, left, nodes)
 	}
+}
+
+// notifyMembership 调用监听者 监听者panic不影响心跳
+func notifyMembership(listener MembershipListener, joined, left, members []*types.ClusterNode) {
+	defer func() {
+		if err := recover(); err != nil {
+			trace.Error("HeartbeatLoopTask membership listener panic, err=%v", err)
+		}
+	}()
+	listener(joined, left, members)
+}
 
-	trace.Info("HeartbeatLoopTask heartbeatSend success key=%v, server id=%v, val=%v",
-		redisInfo.Key, h.serverId, string(jsonData))
+// runBeatHook 调用心跳回调 回调panic不影响心跳
+func runBeatHook(hook func(now time.Time), now time.Time) {
+	defer func() {
+		if err := recover(); err != nil {
+			trace.Error("HeartbeatLoopTask beat hook panic, err=%v", err)
+		}
+	}()
+	hook(now)
 }
 
-// getClusterOnline 外部接口,获取当前集群个数
-func (h *HeartbeatLoopTask) getClusterOnline() int {
-	h.clusterNumMutex.Lock()
-	defer h.clusterNumMutex.Unlock()
-	return h.clusterNum
+func serverIds(nodes []*types.ClusterNode) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ServerId)
+	}
+	return ids
 }
 
-// GetClusterOnlineWithDefault 外部接口,获取当前集群个数,默认值为3
+// OnMembershipChange 注册集群成员变化回调
+func (h *HeartbeatLoopTask) OnMembershipChange(listener MembershipListener) {
+	h.membersMutex.Lock()
+	defer h.membersMutex.Unlock()
+	h.listeners = append(h.listeners, listener)
+}
+
+// OnHeartbeat 注册心跳回调 在心跳协程中同步调用 退出集群后继续调用 StopLoopTask之后不再调用
+func (h *HeartbeatLoopTask) OnHeartbeat(hook func(now time.Time)) {
+	h.membersMutex.Lock()
+	defer h.membersMutex.Unlock()
+	h.beatHooks = append(h.beatHooks, hook)
+}
+
+// AddRoom 记录本节点处理了房间的事件 随心跳上报
+func (h *HeartbeatLoopTask) AddRoom(gameRoomId int64) {
+	h.roomsMutex.Lock()
+	defer h.roomsMutex.Unlock()
+	h.rooms[gameRoomId] = time.Now()
+}
+
+// activeRooms 获取最近处理过的房间 同时清理超过roomActiveWindow没有事件的房间
+func (h *HeartbeatLoopTask) activeRooms(now time.Time) []int64 {
+	h.roomsMutex.Lock()
+	defer h.roomsMutex.Unlock()
+
+	rooms := make([]int64, 0, len(h.rooms))
+	for gameRoomId, lastTime := range h.rooms {
+		if now.Sub(lastTime) > roomActiveWindow {
+			delete(h.rooms, gameRoomId)
+			continue
+		}
+		rooms = append(rooms, gameRoomId)
+	}
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i] < rooms[j] })
+	return rooms
+}
+
+// Members 外部接口,获取上次心跳看到的在线节点 按serverId排序
+func (h *HeartbeatLoopTask) Members() []*types.ClusterNode {
+	h.membersMutex.Lock()
+	defer h.membersMutex.Unlock()
+
+	nodes := make([]*types.ClusterNode, 0, len(h.members))
+	for _, node := range h.members {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ServerId < nodes[j].ServerId })
+	return nodes
+}
+
+// GetClusterOnlineWithDefault 外部接口,获取当前集群个数 还没有心跳结果时至少包含本节点 返回1
 func (h *HeartbeatLoopTask) GetClusterOnlineWithDefault() (clusterOnline int) {
-	clusterOnline = h.getClusterOnline()
+	h.membersMutex.Lock()
+	defer h.membersMutex.Unlock()
+
+	clusterOnline = len(h.members)
 	if clusterOnline <= 0 {
-		clusterOnline = 3
+		clusterOnline = 1
 	}
 
 	return
 }
+
+// Leave 退出集群 停止上报心跳并删除本节点 其他节点下次心跳时收到成员变化 心跳回调继续执行
+func (h *HeartbeatLoopTask) Leave() {
+	if h.stopped.Swap(true) {
+		return
+	}
+	if !cache.RemoveClusterNode(heartbeatTraceId, h.serverId) {
+		trace.Error("HeartbeatLoopTask Leave failed, server id=%v", h.serverId)
+		return
+	}
+	trace.Info("HeartbeatLoopTask Leave success, server id=%v", h.serverId)
+}
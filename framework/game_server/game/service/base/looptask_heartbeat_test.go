@@ -0,0 +1,123 @@
+package base
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/cache"
+	"sl.framework.com/game_server/redis/rediskey"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatMembershipChange(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	oldConf := conf.SetCurrent(&conf.Configuration{Common: conf.Common{HeartbeatInterval: 1, HeartbeatExpired: 6}})
+	defer conf.SetCurrent(oldConf)
+
+	now := time.Now()
+	node1 := newHeartbeatLoopTask("1", now)
+	node2 := newHeartbeatLoopTask("2", now)
+	if online := node1.GetClusterOnlineWithDefault(); online != 1 {
+		t.Fatalf("GetClusterOnlineWithDefault() before heartbeat = %v, want 1", online)
+	}
+
+	var joined, left, members []*types.ClusterNode
+	node1.OnMembershipChange(func(j, l, m []*types.ClusterNode) { joined, left, members = j, l, m })
+	node1.OnMembershipChange(func(j, l, m []*types.ClusterNode) { panic("listener panic") })
+
+	node1.AddRoom(3001)
+	node1.heartbeatSend(now)
+	if len(joined) != 1 || joined[0].ServerId != "1" || len(joined[0].Rooms) != 1 || joined[0].Rooms[0] != 3001 {
+		t.Fatalf("joined = %+v", joined)
+	}
+
+	//其他节点加入
+	node2.heartbeatSend(now)
+	joined = nil
+	node1.heartbeatSend(now.Add(time.Second))
+	if len(joined) != 1 || joined[0].ServerId != "2" || len(left) != 0 || len(members) != 2 {
+		t.Fatalf("joined=%v, left=%v, members=%v", joined, left, members)
+	}
+	if online := node1.GetClusterOnlineWithDefault(); online != 2 {
+		t.Errorf("GetClusterOnlineWithDefault() = %v, want 2", online)
+	}
+
+	//成员没有变化时不通知
+	joined, left = nil, nil
+	node1.heartbeatSend(now.Add(2 * time.Second))
+	if joined != nil || left != nil {
+		t.Fatalf("notified without membership change, joined=%v, left=%v", joined, left)
+	}
+
+	//节点2心跳超时
+	node1.heartbeatSend(now.Add(10 * time.Second))
+	if len(left) != 1 || left[0].ServerId != "2" || len(members) != 1 {
+		t.Fatalf("left=%v, members=%v", left, members)
+	}
+
+	//节点2重新加入后主动退出 不再发送心跳
+	node2.heartbeatSend(now.Add(10 * time.Second))
+	node1.heartbeatSend(now.Add(10 * time.Second))
+	node2.Leave()
+	node2.heartbeatSend(now.Add(11 * time.Second))
+	node1.heartbeatSend(now.Add(11 * time.Second))
+	if len(left) != 1 || left[0].ServerId != "2" || len(node1.Members()) != 1 {
+		t.Fatalf("left=%v, members=%v after Leave", left, node1.Members())
+	}
+}
+
+// TestHeartbeatRenewsLeaseAfterLeave 退出集群后排空其他子系统期间 心跳继续续期节点id租约
+func TestHeartbeatRenewsLeaseAfterLeave(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	oldConf := conf.SetCurrent(&conf.Configuration{Common: conf.Common{HeartbeatInterval: 1, HeartbeatExpired: 6, WorkerLeaseExpired: 60}})
+	defer conf.SetCurrent(oldConf)
+
+	lease := &WorkerLease{owner: "test", workerId: -1, lostId: -1}
+	if err := lease.Acquire(); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer lease.Release()
+	workerId := lease.WorkerId()
+	leaseKey := rediskey.GetWorkerIdLeaseRedisInfo(workerId).Key
+
+	now := time.Now()
+	h := newHeartbeatLoopTask("1", now)
+	h.OnHeartbeat(lease.Renew)
+	h.heartbeatSend(now)
+	h.Leave()
+
+	//退出集群后两次心跳之间不超过租约时间 累计时间超过租约时间
+	for i := 1; i <= 3; i++ {
+		server.FastForward(40 * time.Second)
+		h.heartbeatSend(now.Add(time.Duration(i*40) * time.Second))
+		if owner, err := server.Get(leaseKey); err != nil || owner != "test" {
+			t.Fatalf("lease after Leave beat %v = %q, %v, want still owned", i, owner, err)
+		}
+	}
+	if id := lease.WorkerId(); id != workerId {
+		t.Errorf("WorkerId() = %v, want %v", id, workerId)
+	}
+	if members := h.Members(); len(members) != 1 {
+		t.Errorf("Members() = %v, left node must not report heartbeat", members)
+	}
+	if nodes, _ := cache.GetClusterNodes("test", now, time.Hour); len(nodes) != 0 {
+		t.Errorf("GetClusterNodes() = %v, left node must not report heartbeat", nodes)
+	}
+}
+
+func TestHeartbeatActiveRooms(t *testing.T) {
+	h := newHeartbeatLoopTask("1", time.Now())
+	h.AddRoom(2)
+	h.AddRoom(1)
+	if rooms := h.activeRooms(time.Now()); len(rooms) != 2 || rooms[0] != 1 || rooms[1] != 2 {
+		t.Fatalf("activeRooms() = %v", rooms)
+	}
+	if rooms := h.activeRooms(time.Now().Add(roomActiveWindow + time.Second)); len(rooms) != 0 {
+		t.Fatalf("activeRooms() after window = %v", rooms)
+	}
+}
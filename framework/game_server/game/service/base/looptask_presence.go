@@ -0,0 +1,81 @@
+package base
+
+import (
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/redis/cache"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+	"sync"
+	"time"
+)
+
+const (
+	presenceTraceId       = "presenceSweeper" //清理任务日志的traceId
+	presenceSweepInterval = time.Minute       //清理间隔
+	presenceSweepLockTime = 50 * time.Second  //清理锁的过期时间 小于清理间隔 保证每轮只有一个节点清理
+)
+
+var (
+	presenceSweeperOnce     sync.Once
+	presenceSweeperInstance *PresenceSweeperLoopTask
+)
+
+// GetPresenceSweeper 获取房间在线玩家清理任务单例
+func GetPresenceSweeper() *PresenceSweeperLoopTask {
+	presenceSweeperOnce.Do(func() {
+		presenceSweeperInstance = &PresenceSweeperLoopTask{
+			CLoopTask: &CLoopTask{
+				createTime: time.Now(),
+				interval:   presenceSweepInterval,
+			},
+		}
+	})
+
+	return presenceSweeperInstance
+}
+
+var _ ILoopTask = (*PresenceSweeperLoopTask)(nil)
+
+// PresenceSweeperLoopTask 房间在线玩家清理任务 删除离开房间消息丢失后超时没有活动的玩家
+type PresenceSweeperLoopTask struct {
+	*CLoopTask
+}
+
+// RunLoopTask 启动清理任务
+func (p *PresenceSweeperLoopTask) RunLoopTask() {
+	fn := func() {
+		trace.Info("PresenceSweeperLoopTask RunLoopTask start running, sweep interval=%v", p.interval)
+		p.ticker = time.NewTicker(p.interval)
+		defer p.ticker.Stop()
+		for {
+			select {
+			case <-p.stopping():
+				return
+			case <-p.ticker.C:
+				p.sweep(time.Now())
+				p.touch(time.Now())
+			}
+		}
+	}
+
+	p.start(fn)
+}
+
+// sweep 清理所有房间中超时的玩家 集群中每轮只有拿到锁的节点执行
+func (p *PresenceSweeperLoopTask) sweep(now time.Time) {
+	if !redisdb.TryLock(rediskey.GetRoomPresenceSweepLockRedisInfo(presenceSweepLockTime)) {
+		return
+	}
+
+	rooms, ok := cache.GetPresenceRooms(presenceTraceId)
+	if !ok {
+		trace.Error("PresenceSweeperLoopTask sweep get rooms failed")
+		return
+	}
+	total := int64(0)
+	for _, roomId := range rooms {
+		removed, _ := cache.SweepRoomPresence(presenceTraceId, roomId, now)
+		total += removed
+	}
+	trace.Info("PresenceSweeperLoopTask sweep done, rooms=%v, removed players=%v", len(rooms), total)
+}
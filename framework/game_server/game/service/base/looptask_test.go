@@ -0,0 +1,87 @@
+package base
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStopLoopTask(t *testing.T) {
+	task := &CLoopTask{interval: time.Millisecond}
+	ticks := make(chan struct{}, 16)
+	task.start(func() {
+		task.ticker = time.NewTicker(task.interval)
+		defer task.ticker.Stop()
+		for {
+			select {
+			case <-task.stopping():
+				return
+			case <-task.ticker.C:
+				select {
+				case ticks <- struct{}{}:
+				default:
+				}
+			}
+		}
+	})
+	<-ticks
+
+	if err := task.StopLoopTask(context.Background()); err != nil {
+		t.Fatalf("StopLoopTask() error = %v", err)
+	}
+	//重复关闭直接返回
+	if err := task.StopLoopTask(context.Background()); err != nil {
+		t.Fatalf("second StopLoopTask() error = %v", err)
+	}
+
+	//没有启动的任务关闭后不再启动
+	idle := &CLoopTask{}
+	if err := idle.StopLoopTask(context.Background()); err != nil {
+		t.Fatalf("StopLoopTask() without start error = %v", err)
+	}
+	started := false
+	idle.start(func() { started = true })
+	if started {
+		t.Fatal("task started after StopLoopTask")
+	}
+}
+
+func TestLoopTaskAlive(t *testing.T) {
+	now := time.Now()
+	task := &CLoopTask{interval: 5 * time.Second}
+	if err := task.Alive(now); err != nil {
+		t.Fatalf("Alive() before start error = %v", err)
+	}
+
+	block := make(chan struct{})
+	task.start(func() {
+		select {
+		case <-task.stopping():
+		case <-block:
+		}
+	})
+	if err := task.Alive(time.Now()); err != nil {
+		t.Fatalf("Alive() after start error = %v", err)
+	}
+	//超过3个间隔和30秒没有执行视为卡死
+	if err := task.Alive(time.Now().Add(31 * time.Second)); err == nil {
+		t.Fatal("Alive() stuck error = nil")
+	}
+	task.touch(time.Now().Add(31 * time.Second))
+	if err := task.Alive(time.Now().Add(31 * time.Second)); err != nil {
+		t.Fatalf("Alive() after touch error = %v", err)
+	}
+
+	//循环异常退出
+	close(block)
+	_, done := task.channels()
+	<-done
+	if err := task.Alive(time.Now()); err == nil {
+		t.Fatal("Alive() after exit error = nil")
+	}
+	//正常停止不是卡死
+	_ = task.StopLoopTask(context.Background())
+	if err := task.Alive(time.Now()); err != nil {
+		t.Fatalf("Alive() after stop error = %v", err)
+	}
+}
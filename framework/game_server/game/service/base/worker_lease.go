@@ -0,0 +1,161 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sl.framework.com/game_server/conf"
+	snowflaker "sl.framework.com/game_server/conf/snow_flake_id"
+	"sl.framework.com/game_server/redis/cache"
+	"sl.framework.com/tool"
+	"sl.framework.com/trace"
+	"sync"
+	"time"
+)
+
+/*
+	雪花算法节点id租约
+	go_worker_node表每次启动插入max(id)+1 取模1024后重启次数多了会和在线节点冲突
+	改为从redis中抢占[0, 1023]中空闲的节点id 心跳时续期 节点下线或者租约过期后id可以重新使用
+	没有空闲id时拒绝启动
+*/
+
+const workerLeaseTraceId = "workerLease" //节点id租约日志的traceId
+
+// ErrNoFreeWorkerId 节点id全部被占用
+var ErrNoFreeWorkerId = errors.New("no free snowflake worker id")
+
+var (
+	workerLeaseOnce     sync.Once
+	workerLeaseInstance *WorkerLease
+)
+
+// GetWorkerLeaseInstance 节点id租约单例
+func GetWorkerLeaseInstance() *WorkerLease {
+	workerLeaseOnce.Do(func() {
+		workerLeaseInstance = &WorkerLease{
+			owner:    fmt.Sprintf("%v:%v:%v", tool.GetLocalIp(), os.Getpid(), time.Now().UnixNano()),
+			workerId: -1,
+			lostId:   -1,
+		}
+	})
+
+	return workerLeaseInstance
+}
+
+// WorkerLease 本节点持有的雪花算法节点id租约
+type WorkerLease struct {
+	mutex    sync.Mutex
+	owner    string //租约持有者 每次启动唯一
+	workerId int64  //持有的节点id 没有持有时为-1
+	lostId   int64  //租约丢失后重新抢占失败时记录丢失的节点id 心跳时继续抢占 没有时为-1
+}
+
+/**
+ * Acquire
+ * 抢占空闲节点id 设置server id和雪花算法节点id 程序启动时在使用雪花算法之前调用
+ *
+ * @return error - 没有空闲id 访问redis失败或者时钟回拨过大时返回错误
+ */
+
+func (w *WorkerLease) Acquire() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	workerId, err := w.acquire(rand.Int63n(snowflaker.MaxNodeId + 1))
+	if err != nil {
+		return err
+	}
+	conf.SetServerId(workerId)
+
+	return nil
+}
+
+// acquire 从start开始抢占节点id 成功后设置雪花算法节点id 调用者持有锁
+func (w *WorkerLease) acquire(start int64) (int64, error) {
+	workerId, lastMs, ok := cache.AcquireWorkerLease(workerLeaseTraceId, w.owner, start, snowflaker.MaxNodeId)
+	if !ok {
+		return -1, errors.New("acquire worker lease failed")
+	}
+	if workerId < 0 {
+		return -1, ErrNoFreeWorkerId
+	}
+	if err := snowflaker.GetSnowFlakeInstance().SetNode(workerId, lastMs); err != nil {
+		cache.ReleaseWorkerLease(workerLeaseTraceId, workerId, w.owner)
+		return -1, err
+	}
+	w.workerId = workerId
+	w.lostId = -1
+	trace.Notice("WorkerLease acquire success, owner=%v, workerId=%v", w.owner, workerId)
+
+	return workerId, nil
+}
+
+/**
+ * Renew
+ * 续期租约并保存最后生成id的时间 由心跳调用
+ * 租约已经过期被其他节点抢占时停止生成id并重新抢占 只更新雪花算法节点id server id保持不变
+ * 重新抢占失败时雪花算法保持停止 下次心跳继续抢占
+ *
+ * @param now time.Time - 心跳时间
+ * @return
+ */
+
+func (w *WorkerLease) Renew(now time.Time) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.workerId < 0 {
+		if w.lostId >= 0 {
+			w.reacquire(now)
+		}
+		return
+	}
+	renewed, ok := cache.RenewWorkerLease(workerLeaseTraceId, w.workerId, w.owner)
+	if !ok {
+		return
+	}
+	if renewed {
+		cache.SetWorkerLastTime(workerLeaseTraceId, w.workerId, snowflaker.GetSnowFlakeInstance().LastTimestamp())
+		return
+	}
+
+	trace.Error("WorkerLease Renew lease lost, owner=%v, workerId=%v, time=%v", w.owner, w.workerId, now)
+	w.lostId = w.workerId
+	w.workerId = -1
+	//节点id可能已经被其他节点使用 重新持有租约之前不能再生成id
+	_ = snowflaker.GetSnowFlakeInstance().SetNode(snowflaker.NoNode, 0)
+	w.reacquire(now)
+}
+
+// reacquire 租约丢失后重新抢占 优先抢占丢失的节点id 调用者持有锁
+func (w *WorkerLease) reacquire(now time.Time) {
+	if _, err := w.acquire(w.lostId); err != nil {
+		trace.Error("WorkerLease Renew reacquire failed, owner=%v, lostId=%v, time=%v, err=%v",
+			w.owner, w.lostId, now, err.Error())
+	}
+}
+
+// Release 保存最后生成id的时间并释放租约 程序退出时调用
+func (w *WorkerLease) Release() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.lostId = -1
+	if w.workerId < 0 {
+		return
+	}
+	cache.SetWorkerLastTime(workerLeaseTraceId, w.workerId, snowflaker.GetSnowFlakeInstance().LastTimestamp())
+	if cache.ReleaseWorkerLease(workerLeaseTraceId, w.workerId, w.owner) {
+		trace.Info("WorkerLease Release success, owner=%v, workerId=%v", w.owner, w.workerId)
+	}
+	w.workerId = -1
+}
+
+// WorkerId 当前持有的节点id 没有持有时返回-1
+func (w *WorkerLease) WorkerId() int64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.workerId
+}
@@ -12,24 +12,6 @@ import (
 	"strconv"
 )
 
-/**
- * isIncluding
- * 判断list是否有对应的数据
- *
- * @param member int64 - 参数说明
- * @param list *[]int64 - 参数说明
- * @return bool - true:存在 false:不存在
- */
-
-func isIncluding[T comparable](member T, list []T) (bExist bool) {
-	for _, val := range list {
-		if member == val {
-			return true
-		}
-	}
-	return
-}
-
 /**
  * ServiceBetCancel
  * 投注取消业务处理函数
@@ -46,34 +28,21 @@ func ServiceBetCancel(traceId, userId string, betCancel *types.BetCancelParam) i
 	msgHeader := fmt.Sprintf("ServiceBetCancel traceId=%v, userId=%v, gameRoomId=%v, gameRound=%v",
 		traceId, userId, betCancel.GameRoomId, betCancel.GameRoundId)
 
-	//从缓存hash中获取该用户的订单数据
-	orderList := cache.GetUserOrder(traceId, betCancel.GameRoomId, betCancel.GameRoundId, userId)
-	if orderList == nil {
-		trace.Info("%v, redis order get user order failed", msgHeader)
+	//从注单簿中原子移除取消的订单
+	orderRemoved := cache.RemoveUserOrders(traceId, betCancel.GameRoomId, betCancel.GameRoundId, userId, betCancel.OrderNoList)
+	if orderRemoved == nil {
+		trace.Info("%v, redis order remove user order failed", msgHeader)
 		return errcode.RedisErrorGet
 	}
-	trace.Info("%v, GetUserOrder orderList size:%v", msgHeader, len(orderList))
-	//剔除掉取消的订单
-	orderRemoved := make([]*dto.BetDTO, 0)
-	orderKeep := make([]*dto.BetDTO, 0)
-	var gameId int64
-	for _, order := range orderList {
-		if isIncluding[string](strconv.FormatInt(order.OrderNo, 10), betCancel.OrderNoList) {
-			gameId = order.GameId
-			orderRemoved = append(orderRemoved, order)
-			trace.Info("%v, append to remove List no:%v,size:%v", msgHeader, order.OrderNo, len(orderRemoved))
-			continue
-		}
-
-		orderKeep = append(orderKeep, order)
-	}
 	if len(orderRemoved) == 0 {
 		trace.Info("%v, bet cancel failed", msgHeader)
 		return errcode.GameErrorBetCancelFailed
 	}
-	//重新设置缓存
-	trace.Info("%v,剩余注单列表%+v", msgHeader, orderKeep)
-	cache.SetUserOrder(traceId, betCancel.GameRoomId, betCancel.GameRoundId, userId, orderKeep)
+	var gameId int64
+	for _, order := range orderRemoved {
+		gameId = order.GameId
+		trace.Info("%v, removed order no:%v", msgHeader, order.OrderNo)
+	}
 
 	//发送下注取消事件
 	var betSimpleDTOList []*dto.BetSimpleDTO
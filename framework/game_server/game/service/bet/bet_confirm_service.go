@@ -2,9 +2,9 @@ package bet
 
 import (
 	"fmt"
-	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
 	errcode "sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/executor"
 	"sl.framework.com/game_server/game/service"
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/game/service/type/const_type"
@@ -19,7 +19,9 @@ import (
 /**
  * BetConfirm
  * 投注确认业务处理函数
- * todo:事务性函数 需要做事务性处理
+ * 每个注单记录提交状态 已经扣款成功的注单不再提交 提交结果未知的注单先按扣款记录对账再决定是否重新提交
+ * 扣款成功的注单入库并更新缓存之后才记为已确认 中途退出时重试只重新入库
+ * 重复调用时只提交未扣款的注单 不会重复扣款
  *
  * @param traceId string - traceId用于日志跟踪
  * @param gameRoomId int64 - 房间Id
@@ -27,7 +29,7 @@ import (
  * @param gameId int64 - 游戏Id
  * @param userId int64 - 用户Id
  * @param currency string - 货币类型
- * @return int - 投注确认操作返回值
+ * @return int - 投注确认操作返回值 中台拒绝扣款时返回errcode.HttpErrorPlatformReply 重试不会成功
  */
 
 func ServiceBetConfirm(traceId, gameRoomId, gameRoundId, userId string, currency string) int {
@@ -47,13 +49,17 @@ func ServiceBetConfirm(traceId, gameRoomId, gameRoundId, userId string, currency
 	//signor := impl.SignContextImpl{TraceId: traceId, GameId: types.GameId(conf.GetGameId())}
 	//////获取验证通过的注单列表
 	//signOrderList := signor.Sign(orderList)
-	//通知中台投注注单，用于扣款
-	if ret := rpcreq.BetRequest(traceId, currency, gameRoomId, gameRoundId, userId, &orderList); ret != errcode.ErrorOk {
-		trace.Error("%v, bet http request failed. return code=%v", ret)
-		return ret
+
+	//通知中台投注注单，用于扣款 只处理扣款成功还没有入库的注单
+	ret := confirmOrders(traceId, currency, gameRoomId, gameRoundId, userId, orderList)
+	orderList = ret.deducted
+	if len(orderList) == 0 {
+		trace.Info("[注单提交业务处理] %v, 没有新扣款成功的注单 ret=%v", msgHeader, ret.code)
+		return ret.code
 	}
 
-	//异步调用具体游戏服接口批量入库 避免具体游戏服数据库写入操作耗时太久而阻塞游戏框架流程
+	//在dbSave协程池中调用具体游戏服接口批量入库 避免具体游戏服数据库写入操作耗时太久而阻塞游戏框架流程
+	//等待入库完成后才能记为已确认
 	dbSaver := service.NewGameDBSaver(traceId, types.GameId(conf.GetGameId()))
 	if dbSaver == nil {
 		trace.Error("[注单提交业务处理] %v, new game order saver interfaces failed", msgHeader)
@@ -62,7 +68,7 @@ func ServiceBetConfirm(traceId, gameRoomId, gameRoundId, userId string, currency
 	trace.Info("[注单提交业务处理] %v, SaveDBBatch orderlist:%+v", msgHeader, orderList)
 	dstOrderList := make([]dto.BetDTO, 0)
 	for _, v2 := range orderList {
-		if conf.ServerConf.GameConfig.DynamicOddsEnable {
+		if conf.GetDynamicOddsEnable() {
 			//从缓存中获取动态赔率，设置结算赔率
 			dynamicOddsCache := cache.DynamicOddsCache{TraceId: traceId, GameId: v2.GameId, WagerId: v2.GameWagerId, RoomId: v2.GameRoomId, GameRoundId: v2.GameRoundId}
 			dynamicOddsCache.Get()
@@ -84,10 +90,18 @@ func ServiceBetConfirm(traceId, gameRoomId, gameRoundId, userId string, currency
 		dstOrderList = append(dstOrderList, *v2)
 	}
 	fn := func() { dbSaver.SaveDBBatch(traceId, llGameRoomId, llGameRoundId, &dstOrderList) }
-	async.AsyncRunCoroutine(fn)
+	if err := executor.SubmitWait(executor.PoolDBSave, fn); err != nil {
+		//注单保持扣款成功未入库 重试时重新入库
+		trace.Error("[注单提交业务处理] %v, 注单入库任务被拒绝 error=%v", msgHeader, err.Error())
+		return errcode.ErrorUnknown
+	}
 
-	//更新缓存
-	cache.SetUserOrder(traceId, gameRoomId, gameRoundId, userId, orderList)
+	//更新缓存 只更新注单簿中仍然存在的注单
+	cache.UpdateUserOrders(traceId, gameRoomId, gameRoundId, userId, orderList)
+	if !cache.PutBetConfirmStates(traceId, llGameRoomId, llGameRoundId,
+		markBetConfirmStates(traceId, orderList, const_type.ConfirmStatusConfirmed, ret.states, time.Now())) {
+		trace.Error("[注单提交业务处理] %v, 记录注单已确认失败 重试时会重新入库", msgHeader)
+	}
 
 	////更新注单入库
 	//dbGet := service.NewGameDBSaver(traceId, types.GameId(conf.GetGameId()))
@@ -114,5 +128,115 @@ func ServiceBetConfirm(traceId, gameRoomId, gameRoundId, userId string, currency
 	defer service.PutBettor(types.GameId(conf.GetGameId()), bettor)
 	bettor.AfterConfirmedComplete(gameRoomIdInt, gameRoundIdInt, usrId, orderList)
 
-	return errcode.ErrorOk
+	return ret.code
+}
+
+// confirmResult 注单提交结果
+type confirmResult struct {
+	deducted []*dto.BetDTO                    //扣款成功还没有入库的注单 需要入库 更新缓存并回调
+	states   map[int64]*types.BetConfirmState //注单提交状态 入库完成后记为已确认
+	code     int                              //提交结果 有注单未扣款成功或者结果未知时返回错误由调用方重试
+}
+
+/**
+ * confirmOrders
+ * 按注单提交状态向中台提交扣款 扣款成功的注单记为扣款成功未入库
+ * 1.已经入库和被拒绝的注单跳过 扣款成功未入库的注单不再提交
+ * 2.上次提交结果未知的注单先查询扣款记录对账 对账仍然未知则本次不提交 等待下次重试
+ * 3.其余注单先记为提交中再提交 提交失败时立即对账 确认没有扣款的注单记为失败 下次重试时重新提交
+ * 4.中台返回业务错误(余额不足 超过限额等)时对账后没有扣款的注单记为被拒绝 不再重试
+ *
+ * @param traceId string - traceId用于日志跟踪
+ * @param currency string - 货币类型
+ * @param gameRoomId string - 房间Id
+ * @param gameRoundId string - 局Id
+ * @param userId string - 用户Id
+ * @param orderList []*dto.BetDTO - 玩家注单
+ * @return confirmResult - 提交结果
+ */
+
+func confirmOrders(traceId, currency, gameRoomId, gameRoundId, userId string, orderList []*dto.BetDTO) (ret confirmResult) {
+	msgHeader := fmt.Sprintf("confirmOrders traceId=%v, gameRoomId=%v, gameRoundId=%v, userId=%v",
+		traceId, gameRoomId, gameRoundId, userId)
+	llGameRoomId, _ := strconv.ParseInt(gameRoomId, 10, 64)
+	llGameRoundId, _ := strconv.ParseInt(gameRoundId, 10, 64)
+	ret.code = errcode.ErrorOk
+
+	states, ok := cache.GetBetConfirmStates(traceId, llGameRoomId, llGameRoundId)
+	if !ok {
+		ret.code = errcode.RedisErrorGet
+		return
+	}
+	ret.states = states
+	deducted, posting, toPost := planBetConfirm(orderList, states)
+	if len(deducted) > 0 {
+		trace.Notice("[注单提交业务处理] %v, 已经扣款成功还没有入库的注单 重新入库 count=%v", msgHeader, len(deducted))
+	}
+	ret.deducted = deducted
+	saveStates := func(orders []*dto.BetDTO, status const_type.ConfirmStatus) bool {
+		return cache.PutBetConfirmStates(traceId, llGameRoomId, llGameRoundId,
+			markBetConfirmStates(traceId, orders, status, states, time.Now()))
+	}
+
+	//上次提交结果未知的注单先对账
+	if len(posting) > 0 {
+		transactionList, code := queryBetTransactions(traceId, posting)
+		if code != errcode.ErrorOk {
+			trace.Error("[注单提交业务处理] %v, 对账查询扣款记录失败 code=%v", msgHeader, code)
+			ret.code = code
+			return
+		}
+		deducted, failed, unknown := reconcileBetConfirm(posting, transactionList)
+		saveStates(deducted, const_type.ConfirmStatusDeducted)
+		saveStates(failed, const_type.ConfirmStatusFailed)
+		ret.deducted = append(ret.deducted, deducted...)
+		toPost = append(toPost, failed...)
+		if len(unknown) > 0 {
+			trace.Notice("[注单提交业务处理] %v, 注单扣款结果未知 等待下次重试 count=%v", msgHeader, len(unknown))
+			ret.code = errcode.GameErrorBetConfirmPending
+		}
+	}
+	if len(toPost) == 0 {
+		return
+	}
+
+	//先记为提交中再提交 提交过程中进程退出时下次重试会先对账
+	if !saveStates(toPost, const_type.ConfirmStatusPosting) {
+		ret.code = errcode.RedisErrorSet
+		return
+	}
+	code := rpcreq.BetRequest(traceId, currency, gameRoomId, gameRoundId, userId, &toPost)
+	if code == errcode.ErrorOk {
+		saveStates(toPost, const_type.ConfirmStatusDeducted)
+		ret.deducted = append(ret.deducted, toPost...)
+		return
+	}
+	trace.Error("[注单提交业务处理] %v, bet http request failed. return code=%v", msgHeader, code)
+	rejected := code == errcode.HttpErrorPlatformReply
+	if !rejected || ret.code == errcode.ErrorOk {
+		//有注单需要重试时优先返回重试的错误码
+		ret.code = code
+	}
+
+	//提交失败时中台可能已经部分扣款 立即对账 对账失败的注单保持提交中 下次重试时再对账
+	transactionList, queryCode := queryBetTransactions(traceId, toPost)
+	if queryCode != errcode.ErrorOk {
+		trace.Error("[注单提交业务处理] %v, 提交失败后对账查询扣款记录失败 code=%v", msgHeader, queryCode)
+		ret.code = queryCode
+		return
+	}
+	deducted, failed, unknown := reconcileBetConfirm(toPost, transactionList)
+	saveStates(deducted, const_type.ConfirmStatusDeducted)
+	ret.deducted = append(ret.deducted, deducted...)
+	if rejected && len(unknown) > 0 {
+		//扣款结果未知的注单保持提交中 需要重试对账
+		ret.code = errcode.GameErrorBetConfirmPending
+	}
+	if rejected {
+		trace.Notice("[注单提交业务处理] %v, 中台拒绝扣款 不再重试 count=%v", msgHeader, len(failed))
+		saveStates(failed, const_type.ConfirmStatusRejected)
+		return
+	}
+	saveStates(failed, const_type.ConfirmStatusFailed)
+	return
 }
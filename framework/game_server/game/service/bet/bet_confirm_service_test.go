@@ -0,0 +1,290 @@
+package bet
+
+import (
+	"encoding/json"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/currency/money"
+	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/game/service"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/VO"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/redis/cache"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	confirmGameId  = 9902
+	confirmRoomId  = int64(3002)
+	confirmRoundId = int64(4002)
+	confirmUserId  = int64(20001)
+)
+
+var (
+	confirmedMutex sync.Mutex
+	confirmedCalls [][]int64 //AfterConfirmedComplete收到的注单号
+)
+
+// confirmBettor 测试用下注接口 记录下注确认回调
+type confirmBettor struct{ service.BaseService }
+
+func (b *confirmBettor) ValidateUserLimit(*dto.BetDTO) int { return errcode.ErrorOk }
+func (b *confirmBettor) ValidateRoomLimit(*dto.BetDTO) int { return errcode.ErrorOk }
+func (b *confirmBettor) ValidatePlayType(*dto.BetDTO) int  { return errcode.ErrorOk }
+func (b *confirmBettor) ValidateExtraRule(*dto.BetDTO) int { return errcode.ErrorOk }
+func (b *confirmBettor) AfterBetComplete(int64, int64, int64, []*dto.BetDTO) int {
+	return errcode.ErrorOk
+}
+func (b *confirmBettor) AfterCancelComplete(int64, int64, int64, []*dto.BetDTO) int {
+	return errcode.ErrorOk
+}
+func (b *confirmBettor) AfterRoundCancel(int64, int64, []*dto.BetDTO) int { return errcode.ErrorOk }
+func (b *confirmBettor) AfterConfirmedComplete(_, _, _ int64, orders []*dto.BetDTO) int {
+	confirmedMutex.Lock()
+	confirmedCalls = append(confirmedCalls, orderNos(orders))
+	confirmedMutex.Unlock()
+	return errcode.ErrorOk
+}
+
+// confirmDB 测试用数据库接口
+type confirmDB struct{}
+
+func (db *confirmDB) SaveDBBatch(string, int64, int64, *[]dto.BetDTO)     {}
+func (db *confirmDB) GetOrderNoList(string, int64, int64, string) []int64 { return nil }
+func (db *confirmDB) UpdateOrders(string, int64, int64, *[]*dto.BetDTO)   {}
+
+// confirmPlatform 模拟平台中心的扣款和扣款记录接口
+type confirmPlatform struct {
+	mutex        sync.Mutex
+	betFail      bool                                   //扣款接口返回错误
+	betReject    bool                                   //扣款接口返回业务错误
+	betRequests  [][]int64                              //扣款请求中的注单号
+	transactions map[int64]const_type.TransactionStatus //扣款记录
+}
+
+func (p *confirmPlatform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	switch {
+	case strings.Contains(r.URL.Path, "/feign/order/bet/"):
+		var orders []*VO.BetOrderVO
+		_ = json.Unmarshal(body, &orders)
+		request := make([]int64, 0, len(orders))
+		for _, order := range orders {
+			orderNo, _ := strconv.ParseInt(order.OrderNo, 10, 64)
+			request = append(request, orderNo)
+		}
+		p.betRequests = append(p.betRequests, request)
+		if p.betFail {
+			_, _ = w.Write([]byte(`{"code":"500","msg":"bet failed","data":""}`))
+			return
+		}
+		if p.betReject {
+			_, _ = w.Write([]byte(`{"code":2001,"msg":"balance not enough","data":null}`))
+			return
+		}
+		_, _ = w.Write(body)
+	case strings.HasSuffix(r.URL.Path, "/feign/wallet/list/transaction"):
+		var query dto.QueryTransactionDTO
+		_ = json.Unmarshal(body, &query)
+		transactions := make([]*dto.UserTransactionDTO, 0)
+		for _, orderNo := range query.OrderNoList {
+			if status, ok := p.transactions[orderNo]; ok {
+				transactions = append(transactions, &dto.UserTransactionDTO{
+					OrderNo: strconv.FormatInt(orderNo, 10),
+					Status:  string(status),
+				})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(transactions)
+	}
+}
+
+func (p *confirmPlatform) set(betFail bool, transactions map[int64]const_type.TransactionStatus) {
+	p.mutex.Lock()
+	p.betFail, p.transactions = betFail, transactions
+	p.mutex.Unlock()
+}
+
+func (p *confirmPlatform) reject(betReject bool) {
+	p.mutex.Lock()
+	p.betReject = betReject
+	p.mutex.Unlock()
+}
+
+func (p *confirmPlatform) lastBetRequest() (int, []int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.betRequests) == 0 {
+		return 0, nil
+	}
+	return len(p.betRequests), p.betRequests[len(p.betRequests)-1]
+}
+
+func lastConfirmed() []int64 {
+	confirmedMutex.Lock()
+	defer confirmedMutex.Unlock()
+	if len(confirmedCalls) == 0 {
+		return nil
+	}
+	return confirmedCalls[len(confirmedCalls)-1]
+}
+
+func equalInt64s(list []int64, expect ...int64) bool {
+	if len(list) != len(expect) {
+		return false
+	}
+	for i := range list {
+		if list[i] != expect[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestServiceBetConfirmPartialFailure 部分扣款失败后重试只提交未扣款的注单 扣款结果未知时先对账
+func TestServiceBetConfirmPartialFailure(t *testing.T) {
+	service.RegisterService(confirmGameId, service.InterfaceTypeBettor, &confirmBettor{})
+	service.RegisterService(confirmGameId, service.InterfaceTypeDBSaver, &confirmDB{})
+
+	redisServer := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+
+	platform := &confirmPlatform{}
+	server := httptest.NewServer(platform)
+	defer server.Close()
+
+	oldConf := conf.SetCurrent(&conf.Configuration{
+		Platform: conf.Platform{Host: server.URL, RetryTime: 1, RetryInterval: 1},
+		Common:   conf.Common{GameId: confirmGameId},
+	})
+	defer conf.SetCurrent(oldConf)
+
+	strRoomId, strRoundId := strconv.FormatInt(confirmRoomId, 10), strconv.FormatInt(confirmRoundId, 10)
+	strUserId := strconv.FormatInt(confirmUserId, 10)
+	appendOrders := func(orderNoList ...int64) {
+		orders := make([]*dto.BetDTO, 0, len(orderNoList))
+		for _, orderNo := range orderNoList {
+			orders = append(orders, &dto.BetDTO{
+				Id:          orderNo,
+				OrderNo:     orderNo,
+				UserId:      confirmUserId,
+				GameId:      confirmGameId,
+				GameRoomId:  confirmRoomId,
+				GameRoundId: confirmRoundId,
+				Currency:    "CNY",
+				BetAmount:   money.FromFloat(10),
+				PostStatus:  string(const_type.PostStatusCreate),
+			})
+		}
+		if !cache.AppendUserOrders("confirm", strRoomId, strRoundId, strUserId, orders) {
+			t.Fatal("AppendUserOrders failed")
+		}
+	}
+	confirm := func() int {
+		return ServiceBetConfirm("confirm", strRoomId, strRoundId, strUserId, "CNY")
+	}
+	appendOrders(11, 12)
+
+	//扣款接口失败但是中台已经扣了注单11 注单11确认 注单12记为失败
+	platform.set(true, map[int64]const_type.TransactionStatus{11: const_type.TransactionStatusSuccess})
+	if code := confirm(); code == errcode.ErrorOk {
+		t.Fatal("confirm with failed bet request should be retried")
+	}
+	if count, request := platform.lastBetRequest(); count != 1 || !equalInt64s(request, 11, 12) {
+		t.Fatalf("bet requests = %v, last = %v", count, request)
+	}
+	if confirmed := lastConfirmed(); !equalInt64s(confirmed, 11) {
+		t.Fatalf("confirmed = %v, want [11]", confirmed)
+	}
+
+	//重试时只提交注单12
+	platform.set(false, nil)
+	if code := confirm(); code != errcode.ErrorOk {
+		t.Fatalf("retry confirm code = %v", code)
+	}
+	if count, request := platform.lastBetRequest(); count != 2 || !equalInt64s(request, 12) {
+		t.Fatalf("bet requests = %v, last = %v, want only 12", count, request)
+	}
+	if confirmed := lastConfirmed(); !equalInt64s(confirmed, 12) {
+		t.Fatalf("confirmed = %v, want [12]", confirmed)
+	}
+
+	//全部扣款成功后重复确认不再提交
+	if code := confirm(); code != errcode.ErrorOk {
+		t.Fatalf("repeated confirm code = %v", code)
+	}
+	if count, _ := platform.lastBetRequest(); count != 2 {
+		t.Fatalf("repeated confirm posted again, bet requests = %v", count)
+	}
+
+	//上次提交结果未知的注单先对账 扣款处理中时不提交
+	appendOrders(13)
+	cache.PutBetConfirmStates("confirm", confirmRoomId, confirmRoundId, []*types.BetConfirmState{
+		{OrderNo: 13, UserId: confirmUserId, Status: string(const_type.ConfirmStatusPosting), Attempts: 1},
+	})
+	platform.set(false, map[int64]const_type.TransactionStatus{13: const_type.TransactionStatusDoing})
+	if code := confirm(); code != errcode.GameErrorBetConfirmPending {
+		t.Fatalf("pending confirm code = %v, want %v", code, errcode.GameErrorBetConfirmPending)
+	}
+	platform.set(false, map[int64]const_type.TransactionStatus{13: const_type.TransactionStatusSuccess})
+	if code := confirm(); code != errcode.ErrorOk {
+		t.Fatalf("reconciled confirm code = %v", code)
+	}
+	if count, _ := platform.lastBetRequest(); count != 2 {
+		t.Fatalf("reconciled order posted again, bet requests = %v", count)
+	}
+	if confirmed := lastConfirmed(); !equalInt64s(confirmed, 13) {
+		t.Fatalf("confirmed = %v, want [13]", confirmed)
+	}
+
+	//中台拒绝扣款 注单记为被拒绝 重复确认不再提交
+	appendOrders(14)
+	platform.set(false, nil)
+	platform.reject(true)
+	if code := confirm(); code != errcode.HttpErrorPlatformReply {
+		t.Fatalf("rejected confirm code = %v, want %v", code, errcode.HttpErrorPlatformReply)
+	}
+	platform.reject(false)
+	if code := confirm(); code != errcode.ErrorOk {
+		t.Fatalf("confirm after reject code = %v", code)
+	}
+	if count, request := platform.lastBetRequest(); count != 3 || !equalInt64s(request, 14) {
+		t.Fatalf("bet requests = %v, last = %v, rejected order posted again", count, request)
+	}
+
+	//扣款成功后入库之前退出 重试时不再提交 只重新入库并更新缓存
+	appendOrders(15)
+	cache.PutBetConfirmStates("confirm", confirmRoomId, confirmRoundId, []*types.BetConfirmState{
+		{OrderNo: 15, UserId: confirmUserId, Status: string(const_type.ConfirmStatusDeducted), Attempts: 1},
+	})
+	if code := confirm(); code != errcode.ErrorOk {
+		t.Fatalf("deducted confirm code = %v", code)
+	}
+	if count, _ := platform.lastBetRequest(); count != 3 {
+		t.Fatalf("deducted order posted again, bet requests = %v", count)
+	}
+	if confirmed := lastConfirmed(); !equalInt64s(confirmed, 15) {
+		t.Fatalf("confirmed = %v, want [15]", confirmed)
+	}
+	states, _ := cache.GetBetConfirmStates("confirm", confirmRoomId, confirmRoundId)
+	if states[15].Status != string(const_type.ConfirmStatusConfirmed) || states[14].Status != string(const_type.ConfirmStatusRejected) {
+		t.Fatalf("states 14=%+v 15=%+v", states[14], states[15])
+	}
+	for _, order := range cache.GetUserOrder("confirm", strRoomId, strRoundId, strUserId) {
+		if order.OrderNo == 15 && order.PostStatus != string(const_type.PostStatusReady) {
+			t.Fatalf("order 15 cached post status = %v, want Ready", order.PostStatus)
+		}
+	}
+}
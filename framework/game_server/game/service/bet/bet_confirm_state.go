@@ -0,0 +1,137 @@
+package bet
+
+import (
+	"sl.framework.com/game_server/error_code"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	rpcreq "sl.framework.com/game_server/rpc_client"
+	"strconv"
+	"time"
+)
+
+/**
+ * planBetConfirm
+ * 按注单提交状态对玩家注单分类 保持原有顺序
+ *
+ * 已经入库以及被中台拒绝的注单不再处理
+ *
+ * @param orderList []*dto.BetDTO - 玩家注单
+ * @param states map[int64]*types.BetConfirmState - 注单提交状态
+ * @return []*dto.BetDTO - 已经扣款成功但是还没有入库的注单 不再提交 只重新入库
+ * @return []*dto.BetDTO - 提交中的注单 扣款结果未知 需要先对账
+ * @return []*dto.BetDTO - 未提交或者提交失败的注单 可以直接提交
+ */
+
+func planBetConfirm(orderList []*dto.BetDTO, states map[int64]*types.BetConfirmState) (deducted, posting, toPost []*dto.BetDTO) {
+	for _, order := range orderList {
+		state, ok := states[order.OrderNo]
+		if !ok {
+			toPost = append(toPost, order)
+			continue
+		}
+		switch const_type.ConfirmStatus(state.Status) {
+		case const_type.ConfirmStatusConfirmed, const_type.ConfirmStatusRejected:
+		case const_type.ConfirmStatusDeducted:
+			deducted = append(deducted, order)
+		case const_type.ConfirmStatusPosting:
+			posting = append(posting, order)
+		default:
+			toPost = append(toPost, order)
+		}
+	}
+
+	return
+}
+
+/**
+ * reconcileBetConfirm
+ * 按中台扣款记录对提交结果未知的注单对账
+ * 扣款成功的注单为已确认 处理中的注单结果仍然未知 失败 已回滚或者没有扣款记录的注单可以重新提交
+ *
+ * @param orders []*dto.BetDTO - 提交结果未知的注单
+ * @param transactionList []*dto.UserTransactionDTO - 中台扣款记录
+ * @return []*dto.BetDTO - 扣款成功的注单
+ * @return []*dto.BetDTO - 未扣款的注单
+ * @return []*dto.BetDTO - 扣款处理中的注单
+ */
+
+func reconcileBetConfirm(orders []*dto.BetDTO, transactionList []*dto.UserTransactionDTO) (confirmed, failed, unknown []*dto.BetDTO) {
+	transactions := make(map[int64]const_type.TransactionStatus, len(transactionList))
+	for _, transaction := range transactionList {
+		orderNo, err := strconv.ParseInt(transaction.OrderNo, 10, 64)
+		if err != nil {
+			continue
+		}
+		transactions[orderNo] = const_type.TransactionStatus(transaction.Status)
+	}
+
+	for _, order := range orders {
+		switch transactions[order.OrderNo] {
+		case const_type.TransactionStatusSuccess:
+			confirmed = append(confirmed, order)
+		case const_type.TransactionStatusDoing, const_type.TransactionStatusRetry:
+			unknown = append(unknown, order)
+		default:
+			failed = append(failed, order)
+		}
+	}
+
+	return
+}
+
+/**
+ * markBetConfirmStates
+ * 更新注单提交状态 状态为提交中时提交次数加一
+ *
+ * @param traceId string - 跟踪id
+ * @param orders []*dto.BetDTO - 注单
+ * @param status const_type.ConfirmStatus - 新状态
+ * @param states map[int64]*types.BetConfirmState - 注单提交状态 会被同步更新
+ * @param now time.Time - 当前时间
+ * @return []*types.BetConfirmState - 需要写入缓存的状态
+ */
+
+func markBetConfirmStates(traceId string, orders []*dto.BetDTO, status const_type.ConfirmStatus,
+	states map[int64]*types.BetConfirmState, now time.Time) []*types.BetConfirmState {
+	changed := make([]*types.BetConfirmState, 0, len(orders))
+	for _, order := range orders {
+		state, ok := states[order.OrderNo]
+		if !ok {
+			state = &types.BetConfirmState{OrderNo: order.OrderNo, UserId: order.UserId}
+			states[order.OrderNo] = state
+		}
+		if status == const_type.ConfirmStatusPosting {
+			state.Attempts++
+		}
+		state.Status = string(status)
+		state.TraceId = traceId
+		state.UpdateTime = now.UnixMilli()
+		changed = append(changed, state)
+	}
+
+	return changed
+}
+
+/**
+ * queryBetTransactions
+ * 查询注单在中台的扣款记录
+ *
+ * @param traceId string - 跟踪id
+ * @param orders []*dto.BetDTO - 注单
+ * @return []*dto.UserTransactionDTO - 扣款记录
+ * @return int - 请求返回码
+ */
+
+func queryBetTransactions(traceId string, orders []*dto.BetDTO) ([]*dto.UserTransactionDTO, int) {
+	if len(orders) == 0 {
+		return nil, errcode.ErrorOk
+	}
+	orderNoList := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		orderNoList = append(orderNoList, order.OrderNo)
+	}
+	now := time.Now().UnixMilli()
+	query := &dto.QueryTransactionDTO{BeginTime: now - 3600*1000, EndTime: now, OrderNoList: orderNoList}
+	return rpcreq.GetTransactionList(traceId, query)
+}
@@ -0,0 +1,81 @@
+package bet
+
+import (
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"testing"
+	"time"
+)
+
+func orderNos(orders []*dto.BetDTO) []int64 {
+	list := make([]int64, 0, len(orders))
+	for _, order := range orders {
+		list = append(list, order.OrderNo)
+	}
+	return list
+}
+
+func equalOrderNos(orders []*dto.BetDTO, expect ...int64) bool {
+	list := orderNos(orders)
+	if len(list) != len(expect) {
+		return false
+	}
+	for i := range list {
+		if list[i] != expect[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPlanBetConfirm(t *testing.T) {
+	orders := []*dto.BetDTO{{OrderNo: 1}, {OrderNo: 2}, {OrderNo: 3}, {OrderNo: 4}, {OrderNo: 5}, {OrderNo: 6}}
+	states := map[int64]*types.BetConfirmState{
+		2: {OrderNo: 2, Status: string(const_type.ConfirmStatusConfirmed)},
+		3: {OrderNo: 3, Status: string(const_type.ConfirmStatusPosting)},
+		4: {OrderNo: 4, Status: string(const_type.ConfirmStatusFailed)},
+		5: {OrderNo: 5, Status: string(const_type.ConfirmStatusDeducted)},
+		6: {OrderNo: 6, Status: string(const_type.ConfirmStatusRejected)},
+	}
+
+	deducted, posting, toPost := planBetConfirm(orders, states)
+	if !equalOrderNos(deducted, 5) || !equalOrderNos(posting, 3) || !equalOrderNos(toPost, 1, 4) {
+		t.Errorf("planBetConfirm() = %v, %v, %v", orderNos(deducted), orderNos(posting), orderNos(toPost))
+	}
+}
+
+func TestReconcileBetConfirm(t *testing.T) {
+	orders := []*dto.BetDTO{{OrderNo: 1}, {OrderNo: 2}, {OrderNo: 3}, {OrderNo: 4}, {OrderNo: 5}}
+	transactions := []*dto.UserTransactionDTO{
+		{OrderNo: "1", Status: string(const_type.TransactionStatusSuccess)},
+		{OrderNo: "2", Status: string(const_type.TransactionStatusDoing)},
+		{OrderNo: "3", Status: string(const_type.TransactionStatusRetry)},
+		{OrderNo: "4", Status: string(const_type.TransactionStatusRollback)},
+		{OrderNo: "bad", Status: string(const_type.TransactionStatusSuccess)},
+	}
+
+	confirmed, failed, unknown := reconcileBetConfirm(orders, transactions)
+	if !equalOrderNos(confirmed, 1) || !equalOrderNos(failed, 4, 5) || !equalOrderNos(unknown, 2, 3) {
+		t.Errorf("reconcileBetConfirm() = %v, %v, %v", orderNos(confirmed), orderNos(failed), orderNos(unknown))
+	}
+}
+
+func TestMarkBetConfirmStates(t *testing.T) {
+	states := map[int64]*types.BetConfirmState{}
+	orders := []*dto.BetDTO{{OrderNo: 1, UserId: 7}}
+	now := time.UnixMilli(1700000000000)
+
+	markBetConfirmStates("t1", orders, const_type.ConfirmStatusPosting, states, now)
+	markBetConfirmStates("t1", orders, const_type.ConfirmStatusFailed, states, now)
+	changed := markBetConfirmStates("t2", orders, const_type.ConfirmStatusPosting, states, now)
+
+	state := states[1]
+	if len(changed) != 1 || changed[0] != state {
+		t.Fatalf("markBetConfirmStates() changed = %v", changed)
+	}
+	if state.UserId != 7 || state.Status != string(const_type.ConfirmStatusPosting) || state.Attempts != 2 ||
+		state.TraceId != "t2" || state.UpdateTime != 1700000000000 {
+		t.Errorf("state = %+v", state)
+	}
+}
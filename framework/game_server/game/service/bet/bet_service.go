@@ -5,6 +5,7 @@ import (
 	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
 	snowflaker "sl.framework.com/game_server/conf/snow_flake_id"
+	"sl.framework.com/game_server/currency/money"
 	"sl.framework.com/game_server/error_code"
 	"sl.framework.com/game_server/game/service"
 	gamelogic "sl.framework.com/game_server/game/service/game"
@@ -12,6 +13,7 @@ import (
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/game/service/type/const_type"
 	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/metrics"
 	"sl.framework.com/game_server/redis/cache"
 	rpcreq "sl.framework.com/game_server/rpc_client"
 	"sl.framework.com/trace"
@@ -30,11 +32,11 @@ import (
  * @param gameRoundId int64 - 局号
  * @param userId int64 - 用户Id
  * @param currency string - 货币类型
- * @param betAmount float64 - 下注金额
+ * @param betAmount money.Money - 下注金额
  * @return int - 校验返回码
  */
 
-func validUserBalance(traceId, gameRoomId, gameRoundId, userId, currency string, betAmount float64) int {
+func validUserBalance(traceId, gameRoomId, gameRoundId, userId, currency string, betAmount money.Money) int {
 	msgHeader := fmt.Sprintf("validUserBalance gameRoomId=%v, gameRoundId=%v, userId=%v, currency=%v, "+
 		"betAmount=%v", gameRoomId, gameRoundId, userId, currency, betAmount)
 
@@ -48,12 +50,21 @@ func validUserBalance(traceId, gameRoomId, gameRoundId, userId, currency string,
 	//从缓存hash中获取该用户的订单数据 如果没有数据或者读数据出错那么返回nil
 	orderList := cache.GetUserOrder(traceId, gameRoomId, gameRoundId, userId)
 	//额度校验
-	sumBetAmount := float64(0)
+	var err error
+	sumBetAmount := betAmount
 	for _, order := range orderList {
 		trace.Debug("%v, order=%+v", msgHeader, order)
-		sumBetAmount += order.BetAmount
+		if sumBetAmount, err = sumBetAmount.Add(order.BetAmount); err != nil {
+			trace.Error("%v, sum bet amount failed, error=%v", msgHeader, err.Error())
+			return errcode.HttpErrorInvalidParam
+		}
+	}
+	remain, err := balance.Balance.Sub(sumBetAmount)
+	if err != nil {
+		trace.Error("%v, balance sub failed, error=%v", msgHeader, err.Error())
+		return errcode.HttpErrorInvalidParam
 	}
-	if balance.Balance-sumBetAmount < 0 {
+	if remain.Sign() < 0 {
 		trace.Error("%v, balance not enough. sumBetAmount=%v, balance=%+v", msgHeader, sumBetAmount, balance)
 		return errcode.GameErrorBalanceNotEnough
 	}
@@ -71,23 +82,32 @@ func validUserBalance(traceId, gameRoomId, gameRoundId, userId, currency string,
  * @param gameRoundDetail *types.GameRoundDTO - 局详情信息
  * @param bets *types.BetVO - 玩家下注信息
  * @return []*types.BetOrderV2 - 构造好的注单列表
+ * @return error - id生成器已经停止时返回错误
  */
 
 func initOrder(traceId string, userInfo *dto.UserDto, gameRoundDetail *types.GameRoundDTO,
-	bets *types.BetVO) []*dto.BetDTO {
+	bets *types.BetVO) ([]*dto.BetDTO, error) {
 	orderList := make([]*dto.BetDTO, 0)
 	if userInfo == nil || gameRoundDetail == nil || bets == nil {
-		return orderList
+		return orderList, nil
 	}
 	for i, betInfo := range bets.Bets {
+		id, err := snowflaker.GetSnowFlakeInstance().GetUniqueId()
+		if err != nil {
+			return nil, err
+		}
+		orderNo, err := snowflaker.GetSnowFlakeInstance().GetUniqueId()
+		if err != nil {
+			return nil, err
+		}
 		gameRoomId, _ := strconv.ParseInt(bets.GameRoomId, 10, 64)
 		gameRoundId, _ := strconv.ParseInt(bets.GameRoundId, 10, 64)
 		gameCategroyId, _ := strconv.ParseInt(gameRoundDetail.GameCategoryId, 10, 64)
 		gameId, _ := strconv.ParseInt(gameRoundDetail.GameId, 10, 64)
 		userId, _ := strconv.ParseInt(userInfo.Id, 10, 64)
 		order := &dto.BetDTO{
-			Id:              snowflaker.GetSnowFlakeInstance().GetUniqueId(),
-			OrderNo:         snowflaker.GetSnowFlakeInstance().GetUniqueId(),
+			Id:              id,
+			OrderNo:         orderNo,
 			UserId:          userId,
 			SiteId:          conf.GetServerId(),
 			Username:        userInfo.UserName,
@@ -119,7 +139,7 @@ func initOrder(traceId string, userInfo *dto.UserDto, gameRoundDetail *types.Gam
 		trace.Debug("initOrder traceId=%v, order=%+v", traceId, order)
 	}
 
-	return orderList
+	return orderList, nil
 }
 
 /**
@@ -213,16 +233,12 @@ func validate(traceId string, bettor bet.IGameBettor, order *dto.BetDTO) int {
 		retCode = retExtraRule
 	}
 	if retCode == errcode.ErrorOk {
-		//缓存注单
-		curOrderList := cache.GetUserOrder(traceId, strconv.FormatInt(order.GameRoomId, 10),
-			strconv.FormatInt(order.GameRoundId, 10), strconv.FormatInt(order.UserId, 10))
-		if len(curOrderList) == 0 {
-
-			curOrderList = make([]*dto.BetDTO, 0)
+		//缓存注单 原子追加到注单簿 避免并发下注时互相覆盖
+		if !cache.AppendUserOrders(traceId, strconv.FormatInt(order.GameRoomId, 10), strconv.FormatInt(order.GameRoundId, 10),
+			strconv.FormatInt(order.UserId, 10), []*dto.BetDTO{order}) {
+			trace.Error("%v, append order to cache failed, orderNo=%v", msgHeader, order.OrderNo)
+			return errcode.RedisErrorSet
 		}
-		curOrderList = append(curOrderList, order)
-		cache.SetUserOrder(traceId, strconv.FormatInt(order.GameRoomId, 10), strconv.FormatInt(order.GameRoundId, 10),
-			strconv.FormatInt(order.UserId, 10), curOrderList)
 
 		trace.Info("%v, retUserLimit=%v, retRoomLimit=%v, retOdd=%v, retPlayType=%v, retExtraRule=%v, odds=%v, reCode=%v",
 			msgHeader, retUserLimit, retRoomLimit, retOdd, retPlayType, retExtraRule, odds, retCode)
@@ -240,7 +256,8 @@ func validate(traceId string, bettor bet.IGameBettor, order *dto.BetDTO) int {
  * @return []types.BetResult - 投注结果信息
  */
 
-func ServiceBet(traceId, userId string, betParam *types.BetVO) (int, []types.BetResult) {
+func ServiceBet(traceId, userId string, betParam *types.BetVO) (code int, betResults []types.BetResult) {
+	defer func(begin time.Time) { metrics.ObserveBet(code, time.Since(begin)) }(time.Now())
 	msgHeader := fmt.Sprintf("ServiceBet traceId=%v, gameRoomId=%v, gameRoundId=%v",
 		traceId, betParam.GameRoomId, betParam.GameRoundId)
 	trace.Debug("%v, betParam=%+v", msgHeader, *betParam)
@@ -275,6 +292,8 @@ func ServiceBet(traceId, userId string, betParam *types.BetVO) (int, []types.Bet
 	}
 	userInfo := userCache.Data
 	trace.Debug("%v, userInfo=%+v", msgHeader, userInfo)
+	//下注视为玩家在房间内的活动 刷新在线时间
+	async.AsyncRunCoroutine(func() { cache.TouchRoomPresence(traceId, roomId, lUserId, time.Now()) })
 
 	//用户余额校验
 	if code := validUserBalance(traceId, betParam.GameRoomId, betParam.GameRoundId, userId, betParam.Currency,
@@ -284,7 +303,11 @@ func ServiceBet(traceId, userId string, betParam *types.BetVO) (int, []types.Bet
 	}
 
 	//组合下注订单后并行校验
-	orderList := initOrder(traceId, userInfo, gameRoundDetail, betParam)
+	orderList, err := initOrder(traceId, userInfo, gameRoundDetail, betParam)
+	if err != nil {
+		trace.Error("%v, generate order id failed, error=%v", msgHeader, err.Error())
+		return errcode.GameErrorServerStopping, make([]types.BetResult, 0)
+	}
 	if orderList == nil || len(orderList) == 0 {
 		trace.Error("%v, betParam param illegal", msgHeader)
 		return errcode.GameErrorBetParamIllegal, make([]types.BetResult, 0)
@@ -0,0 +1,187 @@
+package fairodds
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sort"
+	"strconv"
+)
+
+/*
+	可验证公平的动态赔率
+	1.上一局Bet_Start时用crypto/rand为下一局生成32字节服务端种子 公布种子的sha256(NextSeedHash) 种子本身保密
+	  服务刚启动等没有预先生成种子的局在本局Bet_Start时生成
+	2.本局Bet_Start时混入外部种子(ClientSeed) 取数据源Bet_Start事件的请求id 种子承诺之后才产生 服务端无法再挑选种子
+	  每个玩法的随机值 roll = HMAC-SHA256(key=种子hex, msg="房间id:局id:玩法id:外部种子:阶段") 取前8字节大端无符号整数 对范围取模
+	  阶段trigger决定是否触发动态赔率 范围[0,10000) 小于触发权重(rate*100)时触发
+	  阶段odds在触发后抽取赔率 范围[0,max(权重总和,10000)) 落在某个赔率的[MinWeight,MaxWeight)区间时使用该赔率 否则使用默认赔率
+	3.Game_End或者Cancel_Round时公开种子 任何人都可以用种子和审计记录中的外部种子复算随机值和结果
+	2^64对10000取模的偏差小于1e-15 可以忽略
+*/
+
+const (
+	WeightScale  = 10000 //权重为万分比 rate为百分比 权重=rate*100
+	stageTrigger = "trigger"
+	stageOdds    = "odds"
+	seedBytes    = 32
+)
+
+/**
+ * NewServerSeed
+ * 用crypto/rand生成服务端种子
+ *
+ * @return string - 种子hex
+ * @return error - 系统随机数不可用时返回错误
+ */
+
+func NewServerSeed() (string, error) {
+	seed := make([]byte, seedBytes)
+	if _, err := rand.Read(seed); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(seed), nil
+}
+
+// SeedHash 种子的sha256 hex 用于在开局时承诺种子
+func SeedHash(serverSeed string) string {
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+/**
+ * Roll
+ * 根据种子和玩法信息计算随机值 相同输入总是得到相同结果
+ *
+ * @param serverSeed string - 服务端种子hex
+ * @param audit *types.DynamicOddsAuditDTO - 审计记录 使用其中的房间id 局id 玩法id 外部种子
+ * @param stage string - 随机阶段
+ * @param n int - 随机范围 结果在[0,n)
+ * @return int - 随机值
+ */
+
+func Roll(serverSeed string, audit *types.DynamicOddsAuditDTO, stage string, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	mac := hmac.New(sha256.New, []byte(serverSeed))
+	mac.Write([]byte(fmt.Sprintf("%d:%d:%d:%s:%s", audit.GameRoomId, audit.GameRoundId, audit.WagerId, audit.ClientSeed, stage)))
+	sum := mac.Sum(nil)
+
+	return int(binary.BigEndian.Uint64(sum[:8]) % uint64(n))
+}
+
+// RateWeight 百分比概率转换为万分比权重 四舍五入避免浮点误差
+func RateWeight(rate float64) int {
+	return int(math.Round(rate * 100))
+}
+
+/**
+ * BuildWeights
+ * 根据玩法的动态赔率配置构建权重区间 只使用启用的赔率 按id升序依次累加
+ *
+ * @param oddsList []dto.GameWagerOddsDTO - 动态赔率配置
+ * @return []types.DynamicOddsWeightDTO - 权重区间
+ */
+
+func BuildWeights(oddsList []dto.GameWagerOddsDTO) []types.DynamicOddsWeightDTO {
+	enabled := make([]dto.GameWagerOddsDTO, 0, len(oddsList))
+	for _, odds := range oddsList {
+		if odds.Status == "Enable" {
+			enabled = append(enabled, odds)
+		}
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		id1, _ := strconv.ParseInt(enabled[i].Id, 10, 64)
+		id2, _ := strconv.ParseInt(enabled[j].Id, 10, 64)
+		return id1 < id2
+	})
+
+	weights := make([]types.DynamicOddsWeightDTO, 0, len(enabled))
+	curMin := 0
+	for _, odds := range enabled {
+		id, _ := strconv.ParseInt(odds.Id, 10, 64)
+		weight := RateWeight(odds.Rate)
+		if weight <= 0 {
+			continue
+		}
+		weights = append(weights, types.DynamicOddsWeightDTO{OddsId: id, Odds: odds.Odds, MinWeight: curMin, MaxWeight: curMin + weight})
+		curMin += weight
+	}
+	return weights
+}
+
+/**
+ * Draw
+ * 根据审计记录中的输入(房间 局 玩法 外部种子 默认赔率 触发权重 权重区间)计算随机值和最终赔率 结果写回审计记录
+ *
+ * @param serverSeed string - 服务端种子hex
+ * @param audit *types.DynamicOddsAuditDTO - 审计记录
+ * @return
+ */
+
+func Draw(serverSeed string, audit *types.DynamicOddsAuditDTO) {
+	audit.TriggerRoll = Roll(serverSeed, audit, stageTrigger, WeightScale)
+	audit.Triggered = audit.TriggerRoll < audit.TriggerWeight
+	audit.OddsRange, audit.OddsRoll, audit.Odds = 0, 0, audit.OriginalOdds
+	if !audit.Triggered || len(audit.Weights) == 0 {
+		return
+	}
+
+	audit.OddsRange = max(audit.Weights[len(audit.Weights)-1].MaxWeight, WeightScale)
+	audit.OddsRoll = Roll(serverSeed, audit, stageOdds, audit.OddsRange)
+	for _, weight := range audit.Weights {
+		if audit.OddsRoll >= weight.MinWeight && audit.OddsRoll < weight.MaxWeight {
+			audit.Odds = weight.Odds
+			break
+		}
+	}
+}
+
+// OddsInfo 审计记录对应的动态赔率缓存信息
+func OddsInfo(audit *types.DynamicOddsAuditDTO) *types.DynamicOddsInfo {
+	return &types.DynamicOddsInfo{WagerId: audit.WagerId, Enable: audit.Triggered, Odds: audit.Odds}
+}
+
+/**
+ * Verify
+ * 用公开的种子复算审计记录 种子与承诺不符 没有外部种子 权重区间不连续或者随机结果不一致时返回错误
+ *
+ * @param serverSeed string - 公开的服务端种子hex
+ * @param audit *types.DynamicOddsAuditDTO - 审计记录
+ * @return error - 校验失败的原因
+ */
+
+func Verify(serverSeed string, audit *types.DynamicOddsAuditDTO) error {
+	if SeedHash(serverSeed) != audit.SeedHash {
+		return fmt.Errorf("wagerId=%v seed does not match seedHash=%v", audit.WagerId, audit.SeedHash)
+	}
+	if audit.ClientSeed == "" {
+		return fmt.Errorf("wagerId=%v client seed is empty", audit.WagerId)
+	}
+	curMin := 0
+	for _, weight := range audit.Weights {
+		if weight.MinWeight != curMin || weight.MaxWeight <= weight.MinWeight {
+			return fmt.Errorf("wagerId=%v oddsId=%v weight [%v,%v) is not continuous", audit.WagerId, weight.OddsId,
+				weight.MinWeight, weight.MaxWeight)
+		}
+		curMin = weight.MaxWeight
+	}
+
+	expected := *audit
+	Draw(serverSeed, &expected)
+	if expected.TriggerRoll != audit.TriggerRoll || expected.Triggered != audit.Triggered ||
+		expected.OddsRange != audit.OddsRange || expected.OddsRoll != audit.OddsRoll || expected.Odds != audit.Odds {
+		return fmt.Errorf("wagerId=%v recorded triggerRoll=%v triggered=%v oddsRoll=%v/%v odds=%v, "+
+			"recomputed triggerRoll=%v triggered=%v oddsRoll=%v/%v odds=%v", audit.WagerId,
+			audit.TriggerRoll, audit.Triggered, audit.OddsRoll, audit.OddsRange, audit.Odds,
+			expected.TriggerRoll, expected.Triggered, expected.OddsRoll, expected.OddsRange, expected.Odds)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package fairodds
+
+import (
+	"fmt"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/cache"
+	"sl.framework.com/trace"
+	"time"
+)
+
+// VerifyResult 局动态赔率的验证结果 种子公开之前只返回种子的hash
+type VerifyResult struct {
+	GameRoomId  int64                        `json:"gameRoomId"`           //游戏房间id
+	GameRoundId int64                        `json:"gameRoundId"`          //游戏局id
+	SeedHash    string                       `json:"seedHash"`             //Bet_Start时公布的种子hash
+	ServerSeed  string                       `json:"serverSeed,omitempty"` //Game_End后公开的种子
+	Revealed    bool                         `json:"revealed"`             //种子是否已经公开
+	Verified    bool                         `json:"verified"`             //全部审计记录复算一致
+	Records     []*types.DynamicOddsAuditDTO `json:"records,omitempty"`    //审计记录 种子公开后返回
+	Errors      []string                     `json:"errors,omitempty"`     //复算不一致的原因
+}
+
+/**
+ * CommitRound
+ * 为局生成并保存动态赔率种子 重复调用返回第一次生成的种子 保证公布的hash不会变化
+ * 种子写入数据库后再缓存到redis
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return *types.DynamicOddsSeedDTO - 本局种子
+ * @return bool - 是否执行成功 失败时本局不能启用动态赔率
+ */
+
+func CommitRound(traceId string, gameRoomId, gameRoundId int64) (*types.DynamicOddsSeedDTO, bool) {
+	msgHeader := fmt.Sprintf("CommitRound traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+	serverSeed, err := NewServerSeed()
+	if err != nil {
+		trace.Error("%v, generate seed failed, error=%v", msgHeader, err.Error())
+		return nil, false
+	}
+
+	seed := &types.DynamicOddsSeedDTO{
+		GameRoomId:  gameRoomId,
+		GameRoundId: gameRoundId,
+		SeedHash:    SeedHash(serverSeed),
+		ServerSeed:  serverSeed,
+		CommitTime:  time.Now(),
+	}
+	inserted, err := currentStore().InsertSeed(seed)
+	if err != nil {
+		trace.Error("%v, save seed failed, error=%v", msgHeader, err.Error())
+		return nil, false
+	}
+	if !inserted {
+		if seed, err = currentStore().Seed(gameRoomId, gameRoundId); err != nil || seed == nil {
+			trace.Error("%v, load committed seed failed, error=%v", msgHeader, err)
+			return nil, false
+		}
+		trace.Info("%v, seed already committed, use the previous one, seedHash=%v", msgHeader, seed.SeedHash)
+	}
+	cache.SetOddsSeed(traceId, seed)
+
+	return seed, true
+}
+
+/**
+ * RevealRound
+ * 公开局的动态赔率种子 已经公开过的种子直接返回
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return *types.DynamicOddsSeedDTO - 已公开的种子
+ * @return bool - 本局是否有种子并且公开成功
+ */
+
+func RevealRound(traceId string, gameRoomId, gameRoundId int64) (*types.DynamicOddsSeedDTO, bool) {
+	msgHeader := fmt.Sprintf("RevealRound traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+	seed, ok := getSeed(traceId, gameRoomId, gameRoundId)
+	if !ok || seed.Revealed {
+		return seed, ok
+	}
+
+	if err := currentStore().RevealSeed(gameRoomId, gameRoundId, time.Now()); err != nil {
+		trace.Error("%v, reveal seed failed, error=%v", msgHeader, err.Error())
+		return nil, false
+	}
+	//其他节点可能已经公开 以数据库中的公开时间为准
+	seed, err := currentStore().Seed(gameRoomId, gameRoundId)
+	if err != nil || seed == nil || !seed.Revealed {
+		trace.Error("%v, load revealed seed failed, error=%v", msgHeader, err)
+		return nil, false
+	}
+	cache.SetOddsSeed(traceId, seed)
+
+	trace.Info("%v, seedHash=%v revealed", msgHeader, seed.SeedHash)
+	return seed, true
+}
+
+/**
+ * SaveAudit
+ * 保存玩法的动态赔率审计记录 Bet_Start重复处理时返回第一次保存的记录 保证结果不会变化
+ *
+ * @param audit *types.DynamicOddsAuditDTO - 审计记录
+ * @return *types.DynamicOddsAuditDTO - 生效的审计记录 可能是之前已经保存的记录
+ * @return bool - 是否执行成功 失败时该玩法不能启用动态赔率
+ */
+
+func SaveAudit(audit *types.DynamicOddsAuditDTO) (*types.DynamicOddsAuditDTO, bool) {
+	msgHeader := fmt.Sprintf("SaveAudit traceId=%v, gameRoomId=%v, gameRoundId=%v, wagerId=%v",
+		audit.TraceId, audit.GameRoomId, audit.GameRoundId, audit.WagerId)
+	inserted, err := currentStore().InsertAudit(audit)
+	if err != nil {
+		trace.Error("%v, save audit failed, error=%v", msgHeader, err.Error())
+		return nil, false
+	}
+	if inserted {
+		return audit, true
+	}
+
+	audits, err := currentStore().Audits(audit.GameRoomId, audit.GameRoundId)
+	if err != nil {
+		trace.Error("%v, load saved audit failed, error=%v", msgHeader, err.Error())
+		return nil, false
+	}
+	for _, saved := range audits {
+		if saved.WagerId == audit.WagerId {
+			trace.Notice("%v, audit already saved, use the previous one", msgHeader)
+			return saved, true
+		}
+	}
+	trace.Error("%v, saved audit not found", msgHeader)
+	return nil, false
+}
+
+// getSeed 获取局的动态赔率种子 缓存没有时从数据库加载
+func getSeed(traceId string, gameRoomId, gameRoundId int64) (*types.DynamicOddsSeedDTO, bool) {
+	if seed, ok := cache.GetOddsSeed(traceId, gameRoomId, gameRoundId); ok {
+		return seed, true
+	}
+
+	seed, err := currentStore().Seed(gameRoomId, gameRoundId)
+	if err != nil {
+		trace.Error("getSeed traceId=%v, gameRoomId=%v, gameRoundId=%v, load seed failed, error=%v",
+			traceId, gameRoomId, gameRoundId, err.Error())
+		return nil, false
+	}
+	if seed == nil {
+		return nil, false
+	}
+	cache.SetOddsSeed(traceId, seed)
+	return seed, true
+}
+
+// getAudits 获取局的全部审计记录 缓存没有时从数据库加载 只在种子公开之后调用 此时审计记录已经完整
+func getAudits(traceId string, gameRoomId, gameRoundId int64) ([]*types.DynamicOddsAuditDTO, bool) {
+	if audits, ok := cache.GetDynamicOddsAudits(traceId, gameRoomId, gameRoundId); ok && len(audits) > 0 {
+		return audits, true
+	}
+
+	audits, err := currentStore().Audits(gameRoomId, gameRoundId)
+	if err != nil {
+		trace.Error("getAudits traceId=%v, gameRoomId=%v, gameRoundId=%v, load audits failed, error=%v",
+			traceId, gameRoomId, gameRoundId, err.Error())
+		return nil, false
+	}
+	for _, audit := range audits {
+		cache.PutDynamicOddsAudit(audit)
+	}
+	return audits, true
+}
+
+/**
+ * VerifyRound
+ * 验证局的动态赔率 种子公开后用种子复算每条审计记录
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return *VerifyResult - 验证结果
+ * @return bool - 本局是否有动态赔率种子
+ */
+
+func VerifyRound(traceId string, gameRoomId, gameRoundId int64) (*VerifyResult, bool) {
+	seed, ok := getSeed(traceId, gameRoomId, gameRoundId)
+	if !ok {
+		return nil, false
+	}
+
+	result := &VerifyResult{GameRoomId: gameRoomId, GameRoundId: gameRoundId, SeedHash: seed.SeedHash, Revealed: seed.Revealed}
+	if !seed.Revealed {
+		return result, true
+	}
+
+	result.ServerSeed = seed.ServerSeed
+	records, ok := getAudits(traceId, gameRoomId, gameRoundId)
+	if !ok {
+		result.Errors = append(result.Errors, "load audit records failed")
+		return result, true
+	}
+	result.Records = records
+	if SeedHash(seed.ServerSeed) != seed.SeedHash {
+		result.Errors = append(result.Errors, fmt.Sprintf("seed does not match seedHash=%v", seed.SeedHash))
+	}
+	for _, record := range records {
+		if err := Verify(seed.ServerSeed, record); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+	result.Verified = len(result.Errors) == 0
+
+	return result, true
+}
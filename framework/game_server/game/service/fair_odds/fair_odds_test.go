@@ -0,0 +1,191 @@
+package fairodds
+
+import (
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/redis/cache"
+	"testing"
+	"time"
+)
+
+func newTestAudit(seedHash string, wagerId int64) *types.DynamicOddsAuditDTO {
+	return &types.DynamicOddsAuditDTO{
+		GameRoomId:    1,
+		GameRoundId:   2,
+		WagerId:       wagerId,
+		SeedHash:      seedHash,
+		ClientSeed:    "req-1",
+		OriginalOdds:  1.95,
+		TriggerWeight: 5000,
+		Weights: BuildWeights([]dto.GameWagerOddsDTO{
+			{Id: "10", Rate: 30, Odds: 3, Status: "Enable"},
+			{Id: "9", Rate: 20, Odds: 2.5, Status: "Enable"},
+			{Id: "11", Rate: 50, Odds: 8, Status: "Disable"},
+			{Id: "12", Rate: 0.29, Odds: 20, Status: "Enable"},
+		}),
+	}
+}
+
+func TestBuildWeights(t *testing.T) {
+	weights := newTestAudit("", 1).Weights
+	want := []types.DynamicOddsWeightDTO{
+		{OddsId: 9, Odds: 2.5, MinWeight: 0, MaxWeight: 2000},
+		{OddsId: 10, Odds: 3, MinWeight: 2000, MaxWeight: 5000},
+		{OddsId: 12, Odds: 20, MinWeight: 5000, MaxWeight: 5029},
+	}
+	if len(weights) != len(want) {
+		t.Fatalf("BuildWeights() = %+v, want %+v", weights, want)
+	}
+	for i := range want {
+		if weights[i] != want[i] {
+			t.Fatalf("BuildWeights()[%v] = %+v, want %+v", i, weights[i], want[i])
+		}
+	}
+}
+
+func TestDrawVerify(t *testing.T) {
+	seed, err := NewServerSeed()
+	if err != nil || len(seed) != 2*seedBytes {
+		t.Fatalf("NewServerSeed() = %v, %v", seed, err)
+	}
+	hash := SeedHash(seed)
+
+	triggered := 0
+	for wagerId := int64(1); wagerId <= 2000; wagerId++ {
+		audit := newTestAudit(hash, wagerId)
+		Draw(seed, audit)
+		if audit.TriggerRoll < 0 || audit.TriggerRoll >= WeightScale || audit.OddsRoll < 0 || audit.OddsRoll >= max(audit.OddsRange, 1) {
+			t.Fatalf("Draw() roll out of range, audit=%+v", audit)
+		}
+		if audit.Triggered {
+			triggered++
+		} else if audit.Odds != audit.OriginalOdds {
+			t.Fatalf("Draw() not triggered but odds changed, audit=%+v", audit)
+		}
+
+		//相同种子复算结果一致
+		again := newTestAudit(hash, wagerId)
+		Draw(seed, again)
+		if again.TriggerRoll != audit.TriggerRoll || again.OddsRoll != audit.OddsRoll || again.Odds != audit.Odds {
+			t.Fatalf("Draw() is not deterministic, %+v != %+v", again, audit)
+		}
+		if err = Verify(seed, audit); err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+	}
+	//触发权重50% 2000次抽样偏差不会超过10%
+	if triggered < 800 || triggered > 1200 {
+		t.Fatalf("triggered %v of 2000, want about 1000", triggered)
+	}
+
+	//篡改结果 种子或者权重都会被发现
+	audit := newTestAudit(hash, 1)
+	audit.TriggerWeight = WeightScale
+	Draw(seed, audit)
+	tampered := *audit
+	tampered.Odds = 100
+	if err = Verify(seed, &tampered); err == nil {
+		t.Fatalf("Verify() tampered odds error = nil")
+	}
+	if other, _ := NewServerSeed(); Verify(other, audit) == nil {
+		t.Fatalf("Verify() wrong seed error = nil")
+	}
+	tampered = *audit
+	tampered.Weights = append([]types.DynamicOddsWeightDTO{}, audit.Weights...)
+	tampered.Weights[1].MinWeight = 2500
+	if err = Verify(seed, &tampered); err == nil {
+		t.Fatalf("Verify() broken weights error = nil")
+	}
+
+	//外部种子参与随机 修改或者缺少外部种子都会被发现
+	rolls := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		other := newTestAudit(hash, 1)
+		other.ClientSeed = fmt.Sprintf("req-%v", i)
+		rolls[Roll(seed, other, stageTrigger, WeightScale)] = true
+	}
+	if len(rolls) < 15 {
+		t.Fatalf("Roll() ignores client seed, %v distinct rolls of 20", len(rolls))
+	}
+	tampered = *audit
+	tampered.ClientSeed = ""
+	if err = Verify(seed, &tampered); err == nil {
+		t.Fatalf("Verify() empty client seed error = nil")
+	}
+}
+
+func TestCommitRevealVerifyRound(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	oldStore := SetStore(NewMemoryStore())
+	defer SetStore(oldStore)
+
+	if _, ok := VerifyRound("test", 1, 2); ok {
+		t.Fatalf("VerifyRound() without seed ok = true")
+	}
+
+	//重复开局不会改变已经公布的种子
+	seed, ok := CommitRound("test", 1, 2)
+	if !ok {
+		t.Fatalf("CommitRound() failed")
+	}
+	again, ok := CommitRound("test", 1, 2)
+	if !ok || again.SeedHash != seed.SeedHash || again.ServerSeed != seed.ServerSeed {
+		t.Fatalf("CommitRound() again = %+v, want %+v", again, seed)
+	}
+
+	for wagerId := int64(1); wagerId <= 3; wagerId++ {
+		audit := newTestAudit(seed.SeedHash, wagerId)
+		audit.CreateTime = time.Now()
+		Draw(seed.ServerSeed, audit)
+		if saved, ok := SaveAudit(audit); !ok || saved != audit {
+			t.Fatalf("SaveAudit(%v) = %+v, %v", wagerId, saved, ok)
+		}
+	}
+
+	//Bet_Start重发时外部种子不同 使用第一次保存的审计记录
+	resend := newTestAudit(seed.SeedHash, 1)
+	resend.ClientSeed = "req-2"
+	Draw(seed.ServerSeed, resend)
+	if saved, ok := SaveAudit(resend); !ok || saved.ClientSeed != "req-1" {
+		t.Fatalf("SaveAudit() resend = %+v, %v, want the first audit", saved, ok)
+	}
+
+	//redis只是缓存 缓存丢失后从存储加载
+	server.FlushAll()
+	if again, ok := CommitRound("test", 1, 2); !ok || again.ServerSeed != seed.ServerSeed {
+		t.Fatalf("CommitRound() after cache lost = %+v, want %+v", again, seed)
+	}
+	server.FlushAll()
+
+	//公开之前只返回种子hash
+	result, ok := VerifyRound("test", 1, 2)
+	if !ok || result.Revealed || result.ServerSeed != "" || len(result.Records) != 0 || result.SeedHash != seed.SeedHash {
+		t.Fatalf("VerifyRound() before reveal = %+v, %v", result, ok)
+	}
+
+	if revealed, ok := RevealRound("test", 1, 2); !ok || !revealed.Revealed || revealed.ServerSeed != seed.ServerSeed {
+		t.Fatalf("RevealRound() = %+v, %v", revealed, ok)
+	}
+	result, ok = VerifyRound("test", 1, 2)
+	if !ok || !result.Verified || result.ServerSeed != seed.ServerSeed || len(result.Records) != 3 || result.Records[0].WagerId != 1 {
+		t.Fatalf("VerifyRound() after reveal = %+v, %v", result, ok)
+	}
+	server.FlushAll()
+	if result, ok = VerifyRound("test", 1, 2); !ok || !result.Verified || !result.Revealed || len(result.Records) != 3 {
+		t.Fatalf("VerifyRound() after cache lost = %+v, %v", result, ok)
+	}
+
+	//篡改审计记录后验证失败
+	result.Records[2].Triggered = !result.Records[2].Triggered
+	if !cache.PutDynamicOddsAudit(result.Records[2]) {
+		t.Fatalf("PutDynamicOddsAudit() failed")
+	}
+	if result, ok = VerifyRound("test", 1, 2); !ok || result.Verified || len(result.Errors) != 1 {
+		t.Fatalf("VerifyRound() tampered = %+v, %v", result, ok)
+	}
+}
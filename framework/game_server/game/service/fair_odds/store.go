@@ -0,0 +1,166 @@
+package fairodds
+
+import (
+	"sl.framework.com/game_server/game/dao/gamedb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+	Store 动态赔率种子和审计记录的存储 redis只做缓存
+	InsertSeed 写入种子 本局已经有种子时返回false
+	Seed 查询种子 本局没有种子时返回nil
+	RevealSeed 标记种子已公开
+	InsertAudit 写入审计记录 同一局同一玩法已经有记录时返回false
+	Audits 查询局的全部审计记录 按玩法id升序
+*/
+
+type Store interface {
+	InsertSeed(seed *types.DynamicOddsSeedDTO) (bool, error)
+	Seed(gameRoomId, gameRoundId int64) (*types.DynamicOddsSeedDTO, error)
+	RevealSeed(gameRoomId, gameRoundId int64, now time.Time) error
+	InsertAudit(audit *types.DynamicOddsAuditDTO) (bool, error)
+	Audits(gameRoomId, gameRoundId int64) ([]*types.DynamicOddsAuditDTO, error)
+}
+
+var (
+	storeMutex sync.Mutex
+	store      Store = dbStore{} //当前使用的存储 默认game db
+)
+
+/**
+ * SetStore
+ * 设置当前使用的存储 返回之前的存储 供测试替换存储使用
+ *
+ * @param s Store - 新的存储
+ * @return Store - 之前的存储
+ */
+
+func SetStore(s Store) Store {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	old := store
+	store = s
+	return old
+}
+
+// currentStore 获取当前使用的存储
+func currentStore() Store {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	return store
+}
+
+// dbStore 基于game db的存储 表结构见gamedb/dynamic_odds.sql
+type dbStore struct{}
+
+// NewDBStore 创建基于game db的存储
+func NewDBStore() Store {
+	return dbStore{}
+}
+
+func (dbStore) InsertSeed(seed *types.DynamicOddsSeedDTO) (bool, error) {
+	return gamedb.InsertOddsSeed(seed)
+}
+
+func (dbStore) Seed(gameRoomId, gameRoundId int64) (*types.DynamicOddsSeedDTO, error) {
+	return gamedb.QueryOddsSeed(gameRoomId, gameRoundId)
+}
+
+func (dbStore) RevealSeed(gameRoomId, gameRoundId int64, now time.Time) error {
+	return gamedb.RevealOddsSeed(gameRoomId, gameRoundId, now)
+}
+
+func (dbStore) InsertAudit(audit *types.DynamicOddsAuditDTO) (bool, error) {
+	return gamedb.InsertDynamicOddsAudit(audit)
+}
+
+func (dbStore) Audits(gameRoomId, gameRoundId int64) ([]*types.DynamicOddsAuditDTO, error) {
+	return gamedb.QueryDynamicOddsAudits(gameRoomId, gameRoundId)
+}
+
+/*
+	memoryStore 进程内存储
+	语义与dbStore一致 返回的都是副本 仅用于单元测试和本地开发
+*/
+
+type memoryStore struct {
+	mutex  sync.Mutex
+	seeds  map[[2]int64]types.DynamicOddsSeedDTO  //map[房间id 局id]种子
+	audits map[[3]int64]types.DynamicOddsAuditDTO //map[房间id 局id 玩法id]审计记录
+}
+
+// NewMemoryStore 创建进程内存储
+func NewMemoryStore() Store {
+	return &memoryStore{
+		seeds:  make(map[[2]int64]types.DynamicOddsSeedDTO),
+		audits: make(map[[3]int64]types.DynamicOddsAuditDTO),
+	}
+}
+
+func (s *memoryStore) InsertSeed(seed *types.DynamicOddsSeedDTO) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := [2]int64{seed.GameRoomId, seed.GameRoundId}
+	if _, ok := s.seeds[key]; ok {
+		return false, nil
+	}
+	s.seeds[key] = *seed
+	return true, nil
+}
+
+func (s *memoryStore) Seed(gameRoomId, gameRoundId int64) (*types.DynamicOddsSeedDTO, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	seed, ok := s.seeds[[2]int64{gameRoomId, gameRoundId}]
+	if !ok {
+		return nil, nil
+	}
+	return &seed, nil
+}
+
+func (s *memoryStore) RevealSeed(gameRoomId, gameRoundId int64, now time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := [2]int64{gameRoomId, gameRoundId}
+	if seed, ok := s.seeds[key]; ok && !seed.Revealed {
+		seed.Revealed, seed.RevealTime = true, now
+		s.seeds[key] = seed
+	}
+	return nil
+}
+
+func (s *memoryStore) InsertAudit(audit *types.DynamicOddsAuditDTO) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := [3]int64{audit.GameRoomId, audit.GameRoundId, audit.WagerId}
+	if _, ok := s.audits[key]; ok {
+		return false, nil
+	}
+	stored := *audit
+	stored.Weights = append([]types.DynamicOddsWeightDTO(nil), audit.Weights...)
+	s.audits[key] = stored
+	return true, nil
+}
+
+func (s *memoryStore) Audits(gameRoomId, gameRoundId int64) ([]*types.DynamicOddsAuditDTO, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	audits := make([]*types.DynamicOddsAuditDTO, 0)
+	for key, audit := range s.audits {
+		if key[0] == gameRoomId && key[1] == gameRoundId {
+			audit.Weights = append([]types.DynamicOddsWeightDTO(nil), audit.Weights...)
+			audits = append(audits, &audit)
+		}
+	}
+	sort.Slice(audits, func(i, j int) bool { return audits[i].WagerId < audits[j].WagerId })
+	return audits, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/currency/money"
 	"sl.framework.com/game_server/error_code"
 	"sl.framework.com/game_server/game/dao/redisdb"
 	"sl.framework.com/game_server/game/service/base"
@@ -20,59 +21,6 @@ import (
 	"time"
 )
 
-// getUserIdsInRoom 从redis获取房间内用户
-func GetUserIdsInRoom(roomId int64) *types.PlayerInRoom {
-	var (
-		val           string
-		err           error
-		playersInRoom = new(types.PlayerInRoom)
-	)
-
-	redisInfo := rediskey.GetRoomActionRedisInfo(roomId)
-	if val, err = redisdb.Get(redisInfo.Key); nil != err {
-		trace.Error("getUserIdsInRoom redis get failed, roomId=%v, key=%v, error=%v", roomId, redisInfo.Key, err.Error())
-		return nil
-	}
-
-	if len(val) <= 0 {
-		trace.Notice("getUserIdsInRoom redis get no data roomId=%v, key=%v", roomId, redisInfo.Key)
-		playersInRoom.PlayerInfoSet = make([]types.PlayerInfo, 0, 64)
-		return playersInRoom
-	}
-
-	if err = json.Unmarshal([]byte(val), playersInRoom); nil != err {
-		trace.Error("EventJoinRoom json unmarshal failed, roomId=%v, key=%v, val=%v", roomId, redisInfo.Key, val)
-		return nil
-	}
-
-	return playersInRoom
-}
-
-// SetUserIdsInRoom 设置房间内用户数据
-func SetUserIdsInRoom(roomId int64, playersInRoom *types.PlayerInRoom) int {
-	var (
-		err      error
-		val      string
-		jsonData []byte
-	)
-
-	redisInfo := rediskey.GetRoomActionRedisInfo(roomId)
-	if jsonData, err = json.Marshal(playersInRoom); nil != err {
-		trace.Error("setUserIdsInRoom json marshal failed, roomId=%v, players=%v, error=%v",
-			roomId, playersInRoom, err.Error())
-		return errcode.JsonErrorMarshal
-	}
-
-	if val, err = redisdb.Set(redisInfo.Key, string(jsonData), redisInfo.Expire); nil != err {
-		trace.Error("setUserIdsInRoom redis dao set failed, roomId=%v, key=%v, data=%v",
-			roomId, redisInfo.Key, jsonData)
-		return errcode.RedisErrorSet
-	}
-
-	trace.Info("setUserIdsInRoom redis dao set roomId=%v, key=%v, val=%v", roomId, redisInfo.Key, val)
-	return errcode.ErrorOk
-}
-
 /**
  * OnJoinRoom
  * 玩家进入房间消息 接收到此消息将玩家信息放入缓存 供对玩家个人限红进行预缓存的时候使用
@@ -141,34 +89,30 @@ type EventJoinRoom struct {
 
 // HandleEvent 处理事件
 func (e *EventJoinRoom) HandleEvent() {
-	var (
-		ret           int
-		playersInRoom *types.PlayerInRoom
-	)
 	trace.Info("%v start", e.msgHeader)
 
-	//从redis获取房间内用户
+	//玩家已经在房间内则只刷新活动时间 不再查询用户信息
 	*e.retHandleEvent = errcode.ErrorOk
-	if playersInRoom = GetUserIdsInRoom(e.roomId); playersInRoom == nil {
-		trace.Error("%v, getUserIdsInRoom the return is nil", e.msgHeader)
+	online, ok := cache.IsRoomPlayerOnline(e.traceId, e.roomId, e.userId, time.Now())
+	if !ok {
+		trace.Error("%v, IsRoomPlayerOnline failed", e.msgHeader)
 		*e.retHandleEvent = errcode.RedisErrorGet
 		return
 	}
-	//判断玩家是否在redis中 如果在则直接跳过不更新redis
-	for _, player := range playersInRoom.PlayerInfoSet {
-		if player.UserId == e.userId {
-			trace.Info("%v, user already in redis, skip it.", e.msgHeader)
-			return
+	if online {
+		trace.Info("%v, user already in room, refresh it.", e.msgHeader)
+		if !cache.JoinRoomPresence(e.traceId, e.roomId, e.userId, e.currency, time.Now()) {
+			*e.retHandleEvent = errcode.RedisErrorSet
 		}
+		return
 	}
-	playerInfo := types.PlayerInfo{UserId: e.userId, Currency: e.currency}
+
 	userInfo, ret := rpcreq.GetUserClientInfo(e.traceId, e.userId)
 	trace.Info("%v, GetUserClientInfo from middle agent userinfo=%+v.", e.msgHeader, userInfo)
 	if ret != errcode.ErrorOk {
 		trace.Info("%v, can't get user info from middle agent.", e.msgHeader)
 		return
 	}
-	playersInRoom.PlayerInfoSet = append(playersInRoom.PlayerInfoSet, playerInfo)
 	//设置redis房间内用户
 	userCache := cache.UserInfoCache{
 		TraceId: e.traceId,
@@ -177,9 +121,9 @@ func (e *EventJoinRoom) HandleEvent() {
 		Data:    userInfo,
 	}
 	userCache.Set()
-	if ret = SetUserIdsInRoom(e.roomId, playersInRoom); errcode.ErrorOk != ret {
-		trace.Error("%v, setUserIdsInRoom ret=%v", e.msgHeader, ret)
-		*e.retHandleEvent = ret
+	if !cache.JoinRoomPresence(e.traceId, e.roomId, e.userId, e.currency, time.Now()) {
+		trace.Error("%v, JoinRoomPresence failed", e.msgHeader)
+		*e.retHandleEvent = errcode.RedisErrorSet
 		return
 	}
 
@@ -245,45 +189,38 @@ type EventLeaveRoom struct {
 
 // HandleEvent 处理事件
 func (e *EventLeaveRoom) HandleEvent() {
-	var (
-		ret           int
-		index         = errcode.ErrorInvalid
-		playersInRoom *types.PlayerInRoom
-	)
 	trace.Info("%v start", e.msgHeader)
 
-	//从redis获取房间内用户
-	if playersInRoom = GetUserIdsInRoom(e.roomId); playersInRoom == nil {
-		trace.Error("%v, getUserIdsInRoom the return is nil", e.msgHeader)
-		*e.retHandleEvent = errcode.RedisErrorGet
-		return
-	}
-
 	//删除掉离开房间的玩家
-	for idx, v := range playersInRoom.PlayerInfoSet {
-		if v.UserId == e.userId {
-			index = idx
-			break
-		}
+	removed, ok := cache.LeaveRoomPresence(e.traceId, e.roomId, e.userId)
+	if !ok {
+		trace.Error("%v, LeaveRoomPresence failed", e.msgHeader)
+		*e.retHandleEvent = errcode.RedisErrorSet
+		return
 	}
-	if errcode.ErrorInvalid == index {
+	if !removed {
 		trace.Notice("%v, user not exist", e.msgHeader)
 		//*e.retHandleEvent = errcode.OtherUserNotExist //不存在不返回错误只打印信息
-		return
-	}
-	playersInRoom.PlayerInfoSet = append(playersInRoom.PlayerInfoSet[:index], playersInRoom.PlayerInfoSet[index+1:]...)
-
-	//设置redis房间内用户
-	if ret = SetUserIdsInRoom(e.roomId, playersInRoom); errcode.ErrorOk != ret {
-		trace.Error("%v, ret=%v", e.msgHeader, ret)
-		*e.retHandleEvent = ret
-		return
 	}
 
 	*e.retHandleEvent = errcode.ErrorOk
 	return
 }
 
+/**
+ * GetRoomOnlineCount
+ * 获取房间在线玩家数量
+ *
+ * @param traceId string - 用于日志跟踪
+ * @param roomId int64 - 房间Id
+ * @return int64 - 在线玩家数量 读取失败时返回0
+ */
+
+func GetRoomOnlineCount(traceId string, roomId int64) int64 {
+	count, _ := cache.GetRoomOnlineCount(traceId, roomId, time.Now())
+	return count
+}
+
 /*
 赔率倍率
 	表名:	HRoomOddsInfo{GM054247311DA}
@@ -322,7 +259,7 @@ func getRedisRoomLimitInfo(traceId, currency string, gameRoundId, roomId, gameId
 			redisInfo.Filed, err.Error())
 		return nil
 	}
-	trace.Info("%v, max=%0.4f, min=%0.4f", msgHeader, limitInfo.MaxAmount, limitInfo.MinAmount)
+	trace.Info("%v, max=%v, min=%v", msgHeader, limitInfo.MaxAmount, limitInfo.MinAmount)
 
 	return limitInfo
 
@@ -443,26 +380,29 @@ func GetRoomOddInfo(traceId string, gameRoundId, roomId, gameId, gameWagerId int
 
 // BetDataItem 下注信息 序列化后存入redis
 type BetDataItem struct {
-	OrderNo     int64   //订单号
-	GameRoundId int64   //game round id
-	UserId      int64   //用户Id
-	WagerId     int64   //玩法
-	Currency    string  //币种
-	BetAmount   float64 //下注金额
-}
-
-// GetBetTotalAmount 获取玩家userId的currency币种下gameWagerId玩法的总下注数据
-func GetBetTotalAmount(traceId, currency string, gameRoundId, userId, gameWagerId int64, betAmount float64) float64 {
-	var totalBetAmount float64
+	OrderNo     int64       //订单号
+	GameRoundId int64       //game round id
+	UserId      int64       //用户Id
+	WagerId     int64       //玩法
+	Currency    string      //币种
+	BetAmount   money.Money //下注金额
+}
+
+// GetBetTotalAmount 获取玩家userId的currency币种下gameWagerId玩法的总下注数据 总额溢出时返回错误
+func GetBetTotalAmount(traceId, currency string, gameRoundId, userId, gameWagerId int64, betAmount money.Money) (money.Money, error) {
+	var err error
+	totalBetAmount := betAmount
 	betDataList := GetBetData(traceId, currency, gameRoundId)
 	for _, bet := range betDataList {
 		if userId == bet.UserId && gameWagerId == bet.WagerId &&
 			currency == bet.Currency {
-			totalBetAmount += bet.BetAmount
+			if totalBetAmount, err = totalBetAmount.Add(bet.BetAmount); err != nil {
+				return totalBetAmount, err
+			}
 		}
 	}
 
-	return totalBetAmount + betAmount
+	return totalBetAmount, nil
 }
 
 // GetBetData 获取gameRoundId下所有currency币种玩家的下注数据
@@ -511,7 +451,7 @@ func AsyncAppendBetDataList(traceId, currency string, gameRoundId, userId int64,
 }
 
 // AsyncAppendBetData 异步将下注数据追加到redis list中
-func AsyncAppendBetData(traceId, currency string, gameRoundId, userId, gameWagerId, orderNo int64, betAmount float64) {
+func AsyncAppendBetData(traceId, currency string, gameRoundId, userId, gameWagerId, orderNo int64, betAmount money.Money) {
 	fn := func() {
 		AppendBetData(traceId, currency, gameRoundId, userId, gameWagerId, orderNo, betAmount)
 	}
@@ -519,7 +459,7 @@ func AsyncAppendBetData(traceId, currency string, gameRoundId, userId, gameWager
 }
 
 // AppendBetData 将玩家下注数据追加到redis list中 用于限红判断
-func AppendBetData(traceId, currency string, gameRoundId, userId, gameWagerId, orderNo int64, betAmount float64) {
+func AppendBetData(traceId, currency string, gameRoundId, userId, gameWagerId, orderNo int64, betAmount money.Money) {
 	bet := &BetDataItem{
 		OrderNo:     orderNo,
 		GameRoundId: gameRoundId,
@@ -19,15 +19,17 @@ import (
 )
 
 /**
- * FunctionName
- * 函数功能描述
+ * CreateInstance
+ * 创建游戏事件实例 调用前必须已经通过CheckRoundEvent校验局状态
  *
- * @param PARAM - 参数说明
- * @return RETURN - 返回值说明
+ * @param event types.GameEventVO - 来自数据源的事件信息
+ * @param roundDto *types.GameRoundDTO - 游戏局信息
+ * @param gameEventInitVo *VO.GameEventInitVO - 事件信息
+ * @return events.IEventHandler - 游戏事件实例
  */
 
 func CreateInstance(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEventInitVo *VO.GameEventInitVO) events.IEventHandler {
-	trace.Info("InitEvent traceId:%s,gametype:%d,roomid:%v,gameRoundId:%v,nextGameRoundId:%v",
+	trace.Info("InitEvent traceId:%s,gametype:%v,roomid:%v,gameRoundId:%v,nextGameRoundId:%v",
 		gameEventInitVo.TraceId, event.Command, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId)
 	switch event.Command {
 	case types.GameEventCommandBetStart:
@@ -46,24 +48,33 @@ func CreateInstance(event types.GameEventVO, roundDto *types.GameRoundDTO, gameE
 		return NewCancelRound(event, roundDto, gameEventInitVo)
 	case types.GameEventCommandChangeDeck:
 		return NewChangeCard(event, roundDto, gameEventInitVo)
+	case types.GameEventCommandGameResettle:
+		return NewGameResettle(event, roundDto, gameEventInitVo)
 	default:
-		return &types.EventBase{
-			Dto: &types.EventDTO{
-				GameRoomId:      gameEventInitVo.RoomId,
-				GameRoundId:     gameEventInitVo.RoundId,
-				GameId:          conf.GetGameId(),
-				NextGameRoundId: gameEventInitVo.NextRoundId,
-				GameRoundNo:     event.GameRoundNo,
-				Command:         string(event.Command),
-				Payload:         event.Payload,
-			},
-			RoundDTO:       roundDto,
-			TraceId:        gameEventInitVo.TraceId,
-			RequestId:      gameEventInitVo.RequestId,
-			RetHandleEvent: gameEventInitVo.Code,
-			MsgHeader: fmt.Sprintf("%s HandleEvent traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
-				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
-		}
+		eventBase := newEventBase(event, roundDto, gameEventInitVo)
+		return &eventBase
+	}
+}
+
+// newEventBase 创建没有具体处理逻辑的事件基础信息
+func newEventBase(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEventInitVo *VO.GameEventInitVO) types.EventBase {
+	return types.EventBase{
+		Dto: &types.EventDTO{
+			GameRoomId:      gameEventInitVo.RoomId,
+			GameRoundId:     gameEventInitVo.RoundId,
+			GameId:          conf.GetGameId(),
+			NextGameRoundId: gameEventInitVo.NextRoundId,
+			GameRoundNo:     event.GameRoundNo,
+			Command:         string(event.Command),
+			Payload:         event.Payload,
+		},
+		RoundDTO:       roundDto,
+		TraceId:        gameEventInitVo.TraceId,
+		RequestId:      gameEventInitVo.RequestId,
+		Ctx:            gameEventInitVo.Ctx,
+		RetHandleEvent: gameEventInitVo.Code,
+		MsgHeader: fmt.Sprintf("%s HandleEvent traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
+			"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
 	}
 }
 
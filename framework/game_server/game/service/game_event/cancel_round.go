@@ -3,9 +3,21 @@ package gameevent
 import (
 	"fmt"
 	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/currency/money"
+	errcode "sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/service"
+	fairodds "sl.framework.com/game_server/game/service/fair_odds"
+	"sl.framework.com/game_server/game/service/interface/bet"
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/game/service/type/VO"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/redis/cache"
+	rpcreq "sl.framework.com/game_server/rpc_client"
+	"sl.framework.com/tool"
 	"sl.framework.com/trace"
+	"strconv"
+	"time"
 )
 
 type GameCancelRoundEvent struct {
@@ -41,6 +53,7 @@ func NewCancelRound(event types.GameEventVO, roundDto *types.GameRoundDTO, gameE
 			RoundDTO:       roundDto,
 			TraceId:        gameEventInitVo.TraceId,
 			RequestId:      gameEventInitVo.RequestId,
+			Ctx:            gameEventInitVo.Ctx,
 			RetHandleEvent: gameEventInitVo.Code,
 			MsgHeader: fmt.Sprintf("command=%s  traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
 				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
@@ -50,16 +63,240 @@ func NewCancelRound(event types.GameEventVO, roundDto *types.GameRoundDTO, gameE
 
 /**
  * HandleEvent
- * 游戏换靴 游戏服务器接收到该协议后，房间内牌的数量重置为0
+ * 取消局 设置局状态并通知客户端 然后作废当局所有注单 已经扣款的注单调用钱包退款
  *
  * @param traceId string - 跟踪id
  * @return RETURN
  */
 
 func (e *GameCancelRoundEvent) HandleRondEvent() {
-	//直接设置为成功
 	trace.Info("[取消局] CancelRound %v 局信息%+v", e.MsgHeader, e.Dto.Payload)
 	EventCommonSet(&e.EventBase, string(types.GameEventCommandCancelRound), string(types.GameEventCommandCancelRound))
 
+	e.voidRoundOrders()
+	e.revealOddsSeed()
 	return
 }
+
+/**
+ * revealOddsSeed
+ * 局取消后同样公开动态赔率种子 并推送到ws 玩家可以用种子复算本局的动态赔率
+ *
+ * @return
+ */
+
+func (e *GameCancelRoundEvent) revealOddsSeed() {
+	if !conf.GetDynamicOddsEnable() {
+		return
+	}
+	seed, ok := fairodds.RevealRound(e.TraceId, e.Dto.GameRoomId, e.Dto.GameRoundId)
+	if !ok {
+		return
+	}
+	trace.Info("[取消局] 公开动态赔率种子 %v seedHash=%v", e.MsgHeader, seed.SeedHash)
+	rpcreq.AsyncSendRoundMessage[*types.DynamicOddsSeedDTO](e.TraceId, strconv.FormatInt(e.Dto.GameRoomId, 10), e.RoundDTO.Id,
+		string(types.GameEventCommandOddsReveal), seed)
+}
+
+/**
+ * voidRoundOrders
+ * 作废当局注单
+ * 1.从缓存获取当局全量注单
+ * 2.查询扣款记录 扣款成功的注单调用钱包退款
+ * 3.更新注单状态到缓存和数据库
+ * 4.推送注单作废消息 回调游戏的取消局接口
+ */
+
+func (e *GameCancelRoundEvent) voidRoundOrders() {
+	strRoomId := strconv.FormatInt(e.Dto.GameRoomId, 10)
+	strRoundId := strconv.FormatInt(e.Dto.GameRoundId, 10)
+
+	pWatcher := tool.NewWatcher("取消局获取注单")
+	orderAllList := cache.GetOrders(e.TraceId, strRoomId, strRoundId)
+	pWatcher.Stop()
+	if len(orderAllList) == 0 {
+		trace.Notice("[取消局] %v, 当局没有注单 无需作废", e.MsgHeader)
+		return
+	}
+
+	//查询扣款记录 扣款记录查询失败则不作废注单 避免已扣款注单被当成未扣款作废而不退款
+	pWatcher.Start("取消局查询扣款")
+	orderNoList := make([]int64, 0, len(orderAllList))
+	for _, order := range orderAllList {
+		orderNoList = append(orderNoList, order.OrderNo)
+	}
+	queryTransaction := &dto.QueryTransactionDTO{BeginTime: time.Now().UnixMilli() - 3600*1000, EndTime: time.Now().UnixMilli(), OrderNoList: orderNoList}
+	transactionList, retCode := rpcreq.GetTransactionList(e.TraceId, queryTransaction)
+	pWatcher.Stop()
+	if retCode != errcode.ErrorOk {
+		trace.Error("[取消局] %v, 查询注单扣款记录失败 错误码:%v", e.MsgHeader, retCode)
+		*e.RetHandleEvent = retCode
+		return
+	}
+	deducted, pending := classifyTransactions(transactionList)
+
+	//已扣款注单退款
+	pWatcher.Start("取消局退款")
+	refunded := e.refund(deducted)
+	pWatcher.Stop()
+
+	for _, order := range orderAllList {
+		postStatus := voidPostStatus(order.OrderNo, deducted, pending, refunded)
+		voidOrder(order, postStatus)
+		if postStatus == const_type.PostStatusFailed {
+			trace.Error("[取消局] %v, 注单退款失败 需要人工处理 userId=%v, orderNo=%v, betAmount=%v, currency=%v",
+				e.MsgHeader, order.UserId, order.OrderNo, order.BetAmount, order.Currency)
+		}
+	}
+
+	//更新注单缓存和数据库
+	pWatcher.Start("取消局更新注单")
+	cache.UpdateOrders(e.TraceId, strRoomId, strRoundId, orderAllList)
+	dbSaver := service.NewGameDBSaver(e.TraceId, types.GameId(e.Dto.GameId))
+	if dbSaver == nil {
+		trace.Error("[取消局] %v, 获取游戏数据库失败 NewGameDBSaver failed", e.MsgHeader)
+	} else {
+		dbSaver.UpdateOrders(e.TraceId, e.Dto.GameRoomId, e.Dto.GameRoundId, &orderAllList)
+	}
+	pWatcher.Stop()
+
+	//推送注单作废消息
+	betSimpleDTOList := make([]*dto.BetSimpleDTO, 0, len(orderAllList))
+	for _, order := range orderAllList {
+		betSimpleDTOList = append(betSimpleDTOList, &dto.BetSimpleDTO{
+			UserId:      strconv.FormatInt(order.UserId, 10),
+			GameRoundId: strRoundId,
+			GameId:      strconv.FormatInt(order.GameId, 10),
+			GameWagerId: strconv.FormatInt(order.GameWagerId, 10),
+			Currency:    order.Currency,
+			BetAmount:   order.BetAmount,
+		})
+	}
+	rpcreq.AsyncSendRoundMessage[[]*dto.BetSimpleDTO](e.TraceId, strRoomId, strRoundId, string(types.GameEventCommandBetVoid), betSimpleDTOList)
+
+	//游戏实现了取消局接口则回调
+	bettor := service.GetBettor(e.TraceId, types.GameId(e.Dto.GameId))
+	if bettor == nil {
+		trace.Error("[取消局] %v, no game bet.handler, invalid gameId:%v", e.MsgHeader, e.Dto.GameId)
+		return
+	}
+	defer service.PutBettor(types.GameId(e.Dto.GameId), bettor)
+	if listener, ok := bettor.(bet.IRoundCancelListener); ok {
+		ret := listener.AfterRoundCancel(e.Dto.GameRoomId, e.Dto.GameRoundId, orderAllList)
+		trace.Info("[取消局] %v, AfterRoundCancel ret=%v", e.MsgHeader, ret)
+	}
+	trace.Info("[取消局] %v, 注单作废完成 注单数量:%v 退款数量:%v", e.MsgHeader, len(orderAllList), len(refunded))
+}
+
+/**
+ * refund
+ * 调用钱包对已扣款注单退款
+ *
+ * @param deducted map[int64]struct{} - 已扣款的订单号
+ * @return map[int64]struct{} - 退款成功的订单号
+ */
+
+func (e *GameCancelRoundEvent) refund(deducted map[int64]struct{}) map[int64]struct{} {
+	refunded := make(map[int64]struct{})
+	if len(deducted) == 0 {
+		return refunded
+	}
+
+	refundDTO := &dto.RefundDTO{
+		GameRoomId:  e.Dto.GameRoomId,
+		GameRoundId: e.Dto.GameRoundId,
+		GameRoundNo: e.Dto.GameRoundNo,
+		Reason:      string(types.GameEventCommandCancelRound),
+		OrderNoList: make([]int64, 0, len(deducted)),
+	}
+	for orderNo := range deducted {
+		refundDTO.OrderNoList = append(refundDTO.OrderNoList, orderNo)
+	}
+	resultList, retCode := rpcreq.Refund(e.TraceId, refundDTO)
+	if retCode != errcode.ErrorOk {
+		trace.Error("[取消局] %v, 调用钱包退款失败 错误码:%v orderNoList:%v", e.MsgHeader, retCode, refundDTO.OrderNoList)
+		return refunded
+	}
+	for _, result := range resultList {
+		if result.Status != string(const_type.TransactionStatusSuccess) {
+			trace.Notice("[取消局] %v, 注单退款状态异常 result=%+v", e.MsgHeader, result)
+			continue
+		}
+		orderNo, _ := strconv.ParseInt(result.OrderNo, 10, 64)
+		refunded[orderNo] = struct{}{}
+	}
+	return refunded
+}
+
+/**
+ * classifyTransactions
+ * 按扣款记录对注单分类
+ *
+ * @param transactionList []*dto.UserTransactionDTO - 扣款记录
+ * @return map[int64]struct{} - 扣款成功的订单号
+ * @return map[int64]struct{} - 扣款处理中的订单号 结果未知 不能当作未扣款处理
+ */
+
+func classifyTransactions(transactionList []*dto.UserTransactionDTO) (deducted, pending map[int64]struct{}) {
+	deducted = make(map[int64]struct{})
+	pending = make(map[int64]struct{})
+	for _, transaction := range transactionList {
+		orderNo, err := strconv.ParseInt(transaction.OrderNo, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch const_type.TransactionStatus(transaction.Status) {
+		case const_type.TransactionStatusSuccess:
+			deducted[orderNo] = struct{}{}
+		case const_type.TransactionStatusDoing, const_type.TransactionStatusRetry:
+			pending[orderNo] = struct{}{}
+		}
+	}
+	return
+}
+
+/**
+ * voidPostStatus
+ * 计算作废注单的派奖状态
+ * 未扣款的注单作废 已扣款且退款成功的注单为已退款 退款失败或者扣款结果未知的注单为失败 需要人工处理
+ *
+ * @param orderNo int64 - 订单号
+ * @param deducted map[int64]struct{} - 扣款成功的订单号
+ * @param pending map[int64]struct{} - 扣款处理中的订单号
+ * @param refunded map[int64]struct{} - 退款成功的订单号
+ * @return const_type.PostStatus - 派奖状态
+ */
+
+func voidPostStatus(orderNo int64, deducted, pending, refunded map[int64]struct{}) const_type.PostStatus {
+	if _, ok := pending[orderNo]; ok {
+		return const_type.PostStatusFailed
+	}
+	if _, ok := deducted[orderNo]; !ok {
+		return const_type.PostStatusInvalid
+	}
+	if _, ok := refunded[orderNo]; ok {
+		return const_type.PostStatusRefund
+	}
+	return const_type.PostStatusFailed
+}
+
+/**
+ * voidOrder
+ * 将注单设置为取消状态
+ *
+ * @param order *dto.BetDTO - 注单
+ * @param postStatus const_type.PostStatus - 派奖状态
+ */
+
+func voidOrder(order *dto.BetDTO, postStatus const_type.PostStatus) {
+	order.AvailableStatus = string(const_type.AvailableStatusCancel)
+	order.ClientStatus = string(const_type.ClientStatusCancel)
+	order.PostStatus = string(postStatus)
+	if postStatus == const_type.PostStatusInvalid {
+		order.BetStatus = string(const_type.BetStatusInvalid)
+	}
+	order.AvailableBetAmount = money.Money{}
+	order.WinAmount = money.Money{}
+	order.Summary = string(types.GameEventCommandCancelRound)
+	order.UpdateTime = time.Now()
+}
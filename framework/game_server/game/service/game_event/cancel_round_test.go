@@ -0,0 +1,57 @@
+package gameevent
+
+import (
+	"sl.framework.com/game_server/currency/money"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"testing"
+)
+
+func TestVoidPostStatus(t *testing.T) {
+	transactionList := []*dto.UserTransactionDTO{
+		{OrderNo: "1", Status: string(const_type.TransactionStatusSuccess)},
+		{OrderNo: "2", Status: string(const_type.TransactionStatusSuccess)},
+		{OrderNo: "3", Status: string(const_type.TransactionStatusDoing)},
+		{OrderNo: "4", Status: string(const_type.TransactionStatusFailed)},
+	}
+	deducted, pending := classifyTransactions(transactionList)
+	refunded := map[int64]struct{}{1: {}}
+
+	tests := []struct {
+		orderNo int64
+		want    const_type.PostStatus
+	}{
+		{orderNo: 1, want: const_type.PostStatusRefund},  //扣款成功 退款成功
+		{orderNo: 2, want: const_type.PostStatusFailed},  //扣款成功 退款失败
+		{orderNo: 3, want: const_type.PostStatusFailed},  //扣款处理中
+		{orderNo: 4, want: const_type.PostStatusInvalid}, //扣款失败
+		{orderNo: 5, want: const_type.PostStatusInvalid}, //没有扣款记录
+	}
+	for _, tt := range tests {
+		if got := voidPostStatus(tt.orderNo, deducted, pending, refunded); got != tt.want {
+			t.Errorf("voidPostStatus(%v) = %v, want %v", tt.orderNo, got, tt.want)
+		}
+	}
+}
+
+func TestVoidOrder(t *testing.T) {
+	order := &dto.BetDTO{
+		OrderNo:            1,
+		BetAmount:          money.FromFloat(10),
+		AvailableBetAmount: money.FromFloat(10),
+		BetStatus:          string(const_type.BetStatusUnpaid),
+	}
+	voidOrder(order, const_type.PostStatusInvalid)
+	if order.AvailableStatus != string(const_type.AvailableStatusCancel) ||
+		order.ClientStatus != string(const_type.ClientStatusCancel) ||
+		order.BetStatus != string(const_type.BetStatusInvalid) ||
+		!order.AvailableBetAmount.IsZero() || order.BetAmount.IsZero() {
+		t.Errorf("voidOrder(Invalid) = %+v", order)
+	}
+
+	order = &dto.BetDTO{OrderNo: 2, BetStatus: string(const_type.BetStatusPaid)}
+	voidOrder(order, const_type.PostStatusRefund)
+	if order.BetStatus != string(const_type.BetStatusPaid) || order.PostStatus != string(const_type.PostStatusRefund) {
+		t.Errorf("voidOrder(Refund) = %+v", order)
+	}
+}
@@ -41,6 +41,7 @@ func NewChangeCard(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEv
 			RoundDTO:       roundDto,
 			TraceId:        gameEventInitVo.TraceId,
 			RequestId:      gameEventInitVo.RequestId,
+			Ctx:            gameEventInitVo.Ctx,
 			RetHandleEvent: gameEventInitVo.Code,
 			MsgHeader: fmt.Sprintf("command=%s  traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
 				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
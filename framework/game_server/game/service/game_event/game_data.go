@@ -3,7 +3,6 @@ package gameevent
 import (
 	"encoding/json"
 	"fmt"
-	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
 	errcode "sl.framework.com/game_server/error_code"
 	"sl.framework.com/game_server/game/dao/redisdb"
@@ -53,6 +52,7 @@ func NewGameData(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEven
 			RoundDTO:       roundDto,
 			TraceId:        gameEventInitVo.TraceId,
 			RequestId:      gameEventInitVo.RequestId,
+			Ctx:            gameEventInitVo.Ctx,
 			RetHandleEvent: gameEventInitVo.Code,
 			MsgHeader: fmt.Sprintf("command=%s  traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
 				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
@@ -107,8 +107,16 @@ func (e *GameDataEvent) HandleRondEvent() {
 			return
 		}
 		pWatcher := tool.NewWatcher("提交注单分片")
+		//同一房间固定发往同一个分片主题 同一局的分片使用相同的分区键保证顺序提交
+		topic, ok := mq.BetConfirmTopic(e.Dto.GameRoomId)
+		if !ok {
+			trace.Error("[游戏发牌] [提交注单分片] traceId:%v 没有配置提交注单分片主题 gameRoomId:%v", e.TraceId, e.Dto.GameRoomId)
+			pWatcher.Stop()
+			return
+		}
+		shardingKey := mq.RoundShardingKey(e.Dto.GameRoomId, e.Dto.GameRoundId)
 		//3.获取分片大小
-		patchSize := conf.ServerConf.Common.BetConfirmSIze
+		patchSize := conf.GetBetConfirmSize()
 		trace.Info("[游戏发牌] [提交注单分片] traceId:%v patchSize:%v settleOrderList:%+v", e.TraceId, patchSize, userInfoList)
 		patches := tool.SplitList[types.UserCurrencyInfo](userInfoList, patchSize)
 		//遍历
@@ -129,13 +137,9 @@ func (e *GameDataEvent) HandleRondEvent() {
 			if err != nil {
 				trace.Error("[游戏发牌] [提交注单分片] traceId:%v  序列化messageDto=%+v 失败.", e.TraceId, betConfirmPayload)
 			} else {
-				topic := generateBetConfirmTopic()
 				createTime := strconv.FormatInt(time.Now().Unix(), 10)
-				fn := func() {
-					trace.Info("[游戏发牌] [提交注单分片] traceId:%v 异步发送到Mq topic:%v messageStr:%v", e.TraceId, topic, messageStr)
-					mq.SendMessage(topic, strconv.FormatInt(e.Dto.GameId, 10), e.TraceId, createTime, string(messageStr))
-				}
-				async.AsyncRunCoroutine(fn)
+				trace.Info("[游戏发牌] [提交注单分片] traceId:%v 发送到Mq topic:%v shardingKey:%v messageStr:%v", e.TraceId, topic, shardingKey, messageStr)
+				mq.SendMessage(topic, strconv.FormatInt(e.Dto.GameId, 10), e.TraceId, createTime, shardingKey, string(messageStr))
 			}
 
 		}
@@ -145,17 +149,3 @@ func (e *GameDataEvent) HandleRondEvent() {
 
 	return
 }
-
-func generateBetConfirmTopic() string {
-	var result string
-	rndInt := tool.GenerateRandomRange(1, 9999) % 2
-	switch rndInt {
-	case 0:
-		result = string(mq.TopicBetConfirmOut0)
-	case 1:
-		result = string(mq.TopicBetConfirmOut1)
-	default:
-		result = string(mq.TopicBetConfirmOut0)
-	}
-	return result
-}
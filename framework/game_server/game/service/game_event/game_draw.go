@@ -3,7 +3,6 @@ package gameevent
 import (
 	"encoding/json"
 	"fmt"
-	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
 	errcode "sl.framework.com/game_server/error_code"
 	"sl.framework.com/game_server/game/service"
@@ -53,6 +52,7 @@ func NewGameDraw(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEven
 			RoundDTO:       roundDto,
 			TraceId:        gameEventInitVo.TraceId,
 			RequestId:      gameEventInitVo.RequestId,
+			Ctx:            gameEventInitVo.Ctx,
 			RetHandleEvent: gameEventInitVo.Code,
 			MsgHeader: fmt.Sprintf("command=%s  traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
 				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
@@ -94,7 +94,7 @@ func (e *GameDrawEvent) HandleRondEvent() {
 	gameResult := drawer.ParseGameResult(e.Dto)
 	if gameResult == nil {
 		//*e.RetHandleEvent = errcode.HttpErrorInvalidParam
-		trace.Error("[游戏开奖] 解析牌局结果错误 traceid=%v.", e.TraceId)
+		e.Log().Error("[游戏开奖] 解析牌局结果错误")
 		return
 	}
 	gameResult.GameRoundId = strconv.FormatInt(e.Dto.GameRoundId, 10)
@@ -106,7 +106,7 @@ func (e *GameDrawEvent) HandleRondEvent() {
 	pWatcher.Start("推送开奖结果")
 	trace.Info("[游戏开奖] 推送游戏开奖结果到中台 traceid=%v gameResult=%+v", e.TraceId, gameResult)
 	if errcode.ErrorOk != rpcreq.DrawResultPost(e.TraceId, gameResult) {
-		trace.Error("[游戏开奖] DrawResultPost traceid=%v gameResult=%v failed.", e.TraceId, gameResult)
+		e.Log().Error("[游戏开奖] 推送开奖结果到中台失败", "gameResult", fmt.Sprintf("%+v", gameResult))
 		return
 	}
 	//答应时间差
@@ -179,19 +179,27 @@ func (e *GameDrawEvent) HandleRondEvent() {
 	pWatcher.Stop()
 	//更新完注单状态后重新更新缓存
 	pWatcher.Start("开奖更新注单缓存")
-	cache.SetOrders(e.TraceId, strconv.FormatInt(e.Dto.GameRoomId, 10), strconv.FormatInt(e.Dto.GameRoundId, 10), orderAllList)
+	cache.UpdateOrders(e.TraceId, strconv.FormatInt(e.Dto.GameRoomId, 10), strconv.FormatInt(e.Dto.GameRoundId, 10), orderAllList)
 	pWatcher.Stop()
 
 	trace.Debug("[游戏开奖] 查询待开奖集合，并分组发送mq traceId=%v gameRoomId=%v,gameRoundId=%v transactionList=%v settleOrderList=%v",
 		e.TraceId, e.Dto.GameRoomId, e.Dto.GameRoundId, transactionList, settleOrderList)
 
 	pWatcher.Start("分片发送MQ")
+	//同一房间固定发往同一个分片主题 同一局的分片使用相同的分区键保证顺序结算
+	topic, ok := mq.GameDrawTopic(e.Dto.GameRoomId)
+	if !ok {
+		trace.Error("[游戏开奖] 没有配置开奖分片主题 traceId:%v gameRoomId:%v", e.TraceId, e.Dto.GameRoomId)
+		pWatcher.Stop()
+		return
+	}
+	shardingKey := mq.RoundShardingKey(e.Dto.GameRoomId, e.Dto.GameRoundId)
 	//3.获取分片大小
-	patchSize := conf.ServerConf.Common.DrawSize
+	patchSize := conf.GetDrawSize()
 	trace.Info("[游戏开奖] 分片并发送MQ traceId:%v patchSize:%v settleOrderList:%+v", e.TraceId, patchSize, settleOrderList)
 	patches := tool.SplitList[int64](settleOrderList, patchSize)
 	//遍历
-	for _, row := range patches {
+	for shardNo, row := range patches {
 		if len(row) == 0 {
 			continue
 		}
@@ -202,6 +210,7 @@ func (e *GameDrawEvent) HandleRondEvent() {
 			GameRoundNo:        e.Dto.GameRoundNo,
 			GameRoundResultDTO: *gameResult,
 			OrderList:          row,
+			ShardNo:            shardNo,
 		}
 		messageStr, err := generateGameDrawMessage(e.TraceId, gameDrawDataDTOItem)
 		//id := tool.GenerateRandomString(32)
@@ -209,13 +218,10 @@ func (e *GameDrawEvent) HandleRondEvent() {
 		if err != nil {
 			trace.Error("[游戏开奖]  生成开奖MQ消息失败 generateGameDrawMessage traceId:%v failed.", e.TraceId)
 		} else {
-			topic := generateTopic()
 			createTime := strconv.FormatInt(time.Now().Unix(), 10)
-			fn := func() {
-				trace.Info("[游戏开奖] 分片并发送MQ  异步发送HandleRondEvent traceId:%v dispatch orders slice to gameSvr topic:%v messageStr:%v", e.TraceId, topic, messageStr)
-				mq.SendMessage(topic, strconv.FormatInt(e.Dto.GameId, 10), e.TraceId, createTime, messageStr)
-			}
-			async.AsyncRunCoroutine(fn)
+			trace.Info("[游戏开奖] 分片并发送MQ traceId:%v dispatch orders slice to gameSvr topic:%v shardingKey:%v messageStr:%v", e.TraceId, topic, shardingKey, messageStr)
+			//按分片顺序同步入队 生产者队列是先进先出的 保证同一局分片的发送顺序
+			mq.SendMessage(topic, strconv.FormatInt(e.Dto.GameId, 10), e.TraceId, createTime, shardingKey, messageStr)
 		}
 
 	}
@@ -244,6 +250,10 @@ func generateGameDrawMessage(traceId string, dto types.GameDrawDataDTO) (string,
 		GameId:             dto.GameId,
 		GameRoundResultDTO: dto.GameRoundResultDTO,
 		OrderList:          dto.OrderList,
+		ShardNo:            dto.ShardNo,
+		Resettle:           dto.Resettle,
+		ResettleId:         dto.ResettleId,
+		PreviousResultDTO:  dto.PreviousResultDTO,
 	}
 
 	trace.Info("[生成结算消息] generateGameDrawMessage traceId=%v gameDrawMessage=%+v.", traceId, gameDrawMessage)
@@ -255,44 +265,6 @@ func generateGameDrawMessage(traceId string, dto types.GameDrawDataDTO) (string,
 	return string(messageBuf), err
 }
 
-/**
- * FunctionName
- * 函数功能描述
- *
- * @param PARAM - 参数说明
- * @return RETURN - 返回值说明
- */
-
-func generateTopic() string {
-	var result string
-	rndInt := tool.GenerateRandomRange(1, 9999) % 10
-	switch rndInt {
-	case 0:
-		result = string(mq.TopicGameDrawOut0)
-	case 1:
-		result = string(mq.TopicGameDrawOut1)
-	case 2:
-		result = string(mq.TopicGameDrawOut2)
-	case 3:
-		result = string(mq.TopicGameDrawOut3)
-	case 4:
-		result = string(mq.TopicGameDrawOut4)
-	case 5:
-		result = string(mq.TopicGameDrawOut5)
-	case 6:
-		result = string(mq.TopicGameDrawOut6)
-	case 7:
-		result = string(mq.TopicGameDrawOut7)
-	case 8:
-		result = string(mq.TopicGameDrawOut8)
-	case 9:
-		result = string(mq.TopicGameDrawOut9)
-	default:
-		result = string(mq.TopicGameDrawOut0)
-	}
-	return result
-}
-
 /**
  * PrintTimeOffset
  * 打印事件时间差
@@ -0,0 +1,290 @@
+package gameevent
+
+import (
+	"encoding/json"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/currency/money"
+	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/game/service"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/VO"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/mq"
+	"sl.framework.com/game_server/mq/handler"
+	"sl.framework.com/game_server/outbox"
+	"sl.framework.com/game_server/redis/cache"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	e2eGameId  = 9901
+	e2eRoomId  = int64(3001)
+	e2eRoundId = int64(4001)
+	e2eUserId  = int64(10001)
+)
+
+var (
+	e2eOrderNoList = []int64{1, 2, 3}
+	e2eCompleted   = make(chan []*types.SettleDTO, 8) //AfterCompletion通知测试结算完成
+)
+
+// e2eDrawer 测试用结算接口 每个注单固定赢2倍
+type e2eDrawer struct{ service.BaseService }
+
+func (d *e2eDrawer) ParseGameResult(e *types.EventDTO) *types.GameRoundResultDTO {
+	return &types.GameRoundResultDTO{
+		Headers: &types.Heads{
+			GameRoomId:  strconv.FormatInt(e.GameRoomId, 10),
+			GameRoundId: strconv.FormatInt(e.GameRoundId, 10),
+			GameRoundNo: e.GameRoundNo,
+		},
+	}
+}
+
+func (d *e2eDrawer) SettleOrder(traceId string, result *types.GameRoundResultDTO,
+	gameDrawDTO *types.GameDrawDataDTO, orders *[]int64) []*types.SettleDTO {
+	settleList := make([]*types.SettleDTO, 0, len(*orders))
+	for _, orderNo := range *orders {
+		settleList = append(settleList, &types.SettleDTO{
+			OrderNo:         orderNo,
+			WinAmount:       money.FromFloat(20),
+			WinLostStatus:   "Win",
+			GameRoundResult: *result,
+			SettleTime:      time.Now(),
+		})
+	}
+	return settleList
+}
+
+func (d *e2eDrawer) AfterCompletion(traceId string, gameRoomId, gameRoundId int64, drawOrder []*types.SettleDTO) {
+	e2eCompleted <- drawOrder
+}
+
+// e2eDB 测试用数据库接口
+type e2eDB struct{}
+
+func (db *e2eDB) SaveDBBatch(string, int64, int64, *[]dto.BetDTO) {}
+
+func (db *e2eDB) GetOrderNoList(string, int64, int64, string) []int64 {
+	return e2eOrderNoList
+}
+
+func (db *e2eDB) UpdateOrders(string, int64, int64, *[]*dto.BetDTO) {}
+
+// e2ePlatform 模拟平台中心 记录结算请求
+type e2ePlatform struct {
+	mutex      sync.Mutex
+	settle     [][]*types.SettleDTO
+	settleFail bool //派彩接口返回错误
+}
+
+func (p *e2ePlatform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/feign/wallet/list/transaction"):
+		var query dto.QueryTransactionDTO
+		_ = json.Unmarshal(body, &query)
+		transactions := make([]*dto.UserTransactionDTO, 0, len(query.OrderNoList))
+		for _, orderNo := range query.OrderNoList {
+			transactions = append(transactions, &dto.UserTransactionDTO{
+				OrderNo: strconv.FormatInt(orderNo, 10),
+				Status:  string(const_type.TransactionStatusSuccess),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(transactions)
+	case strings.Contains(r.URL.Path, "/feign/settle/settle/"):
+		var settleList []*types.SettleDTO
+		_ = json.Unmarshal(body, &settleList)
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if p.settleFail {
+			_, _ = w.Write([]byte(`{"code":"500","msg":"settle failed","data":""}`))
+			return
+		}
+		p.settle = append(p.settle, settleList)
+	}
+}
+
+func (p *e2ePlatform) setSettleFail(fail bool) {
+	p.mutex.Lock()
+	p.settleFail = fail
+	p.mutex.Unlock()
+}
+
+func (p *e2ePlatform) settleCount() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.settle)
+}
+
+func (p *e2ePlatform) settledOrders() map[int64]bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	orders := make(map[int64]bool)
+	for _, settleList := range p.settle {
+		for _, settle := range settleList {
+			orders[settle.OrderNo] = true
+		}
+	}
+	return orders
+}
+
+// TestGameDrawThroughMemoryTransport 开奖事件经进程内传输层分片投递到开奖消息处理函数完成结算
+func TestGameDrawThroughMemoryTransport(t *testing.T) {
+	service.RegisterService(e2eGameId, service.InterfaceTypeDrawer, &e2eDrawer{})
+	service.RegisterService(e2eGameId, service.InterfaceTypeDBSaver, &e2eDB{})
+
+	redisServer := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+
+	platform := &e2ePlatform{}
+	server := httptest.NewServer(platform)
+	defer server.Close()
+
+	topics := []conf.TopicConfigItem{
+		{TopicName: "game-draw-0", TopicGroup: "game-draw-0-group"},
+		{TopicName: "game-draw-1", TopicGroup: "game-draw-1-group"},
+	}
+	testConf := &conf.Configuration{
+		Platform: conf.Platform{Host: server.URL, RetryTime: 1},
+		Common:   conf.Common{DrawSize: 2, GameId: e2eGameId},
+		Rocketmq: conf.Rocket{
+			Transport:         mq.TransportMemory,
+			Retries:           1,
+			GameDrawTopicsIn:  topics,
+			GameDrawTopicsOut: topics,
+		},
+		Outbox: conf.Outbox{Store: outbox.StoreMemory, PollInterval: 10, RetryInterval: 10},
+	}
+	oldConf := conf.SetCurrent(testConf)
+	defer conf.SetCurrent(oldConf)
+	oldStore := outbox.SetStore(outbox.NewMemoryStore())
+	defer outbox.SetStore(oldStore)
+
+	if !mq.InitMQManager() {
+		t.Fatal("InitMQManager with memory transport failed")
+	}
+	defer mq.StopMQManager()
+
+	//下注阶段写入的注单缓存
+	strRoomId, strRoundId := strconv.FormatInt(e2eRoomId, 10), strconv.FormatInt(e2eRoundId, 10)
+	orders := make([]*dto.BetDTO, 0, len(e2eOrderNoList))
+	for _, orderNo := range e2eOrderNoList {
+		orders = append(orders, &dto.BetDTO{
+			Id:          orderNo,
+			OrderNo:     orderNo,
+			UserId:      e2eUserId,
+			GameRoomId:  e2eRoomId,
+			GameRoundId: e2eRoundId,
+			Currency:    "CNY",
+			BetAmount:   money.FromFloat(10),
+			PostStatus:  string(const_type.PostStatusCreate),
+		})
+	}
+	if !cache.AppendUserOrders("e2e", strRoomId, strRoundId, strconv.FormatInt(e2eUserId, 10), orders) {
+		t.Fatal("AppendUserOrders failed")
+	}
+
+	code := errcode.ErrorOk
+	event := NewGameDraw(
+		types.GameEventVO{GameRoomId: e2eRoomId, GameRoundNo: "R1", Command: types.GameEventCommandGameDraw, Time: time.Now().UnixMilli()},
+		&types.GameRoundDTO{Id: strRoundId},
+		&VO.GameEventInitVO{TraceId: "e2e", RoomId: e2eRoomId, RoundId: e2eRoundId, RequestId: "e2e", Code: &code},
+	)
+	event.HandleRondEvent()
+	if code != errcode.ErrorOk {
+		t.Fatalf("HandleRondEvent code = %v", code)
+	}
+
+	//DrawSize=2 三个注单分为两片 两片都路由到同一个分片主题
+	settled := 0
+	for settled < len(e2eOrderNoList) {
+		select {
+		case drawOrder := <-e2eCompleted:
+			settled += len(drawOrder)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for settlement, settled=%v", settled)
+		}
+	}
+
+	settledOrders := platform.settledOrders()
+	for _, orderNo := range e2eOrderNoList {
+		if !settledOrders[orderNo] {
+			t.Errorf("order %v not posted to platform settle", orderNo)
+		}
+	}
+	for _, order := range cache.GetOrders("e2e", strRoomId, strRoundId) {
+		if order.PostStatus != string(const_type.PostStatusPaid) || order.WinAmount.Cmp(money.FromFloat(20)) != 0 {
+			t.Errorf("order %v PostStatus=%v WinAmount=%v, want Paid 20", order.OrderNo, order.PostStatus, order.WinAmount)
+		}
+	}
+
+	//mq重新投递已经派彩的分片 结算台账跳过全部注单 不再派彩
+	redelivered := func(shardNo int, orderList []int64) []byte {
+		body, _ := json.Marshal(types.GameDrawDataDTO{
+			GameRoomId:  e2eRoomId,
+			GameRoundId: e2eRoundId,
+			GameId:      e2eGameId,
+			GameRoundNo: "R1",
+			OrderList:   orderList,
+			ShardNo:     shardNo,
+		})
+		return body
+	}
+	settleCount := platform.settleCount()
+	if code := handler.OnGameDrawHandler("e2e-redelivery", redelivered(0, []int64{1, 2})); code != errcode.ErrorOk {
+		t.Fatalf("redelivered shard code = %v", code)
+	}
+	if platform.settleCount() != settleCount {
+		t.Fatalf("redelivered shard posted to platform again")
+	}
+
+	//平台中心派彩失败时派彩请求留在发件箱 分片正常确认 平台恢复后由发件箱后台任务重试 只结算未派彩的注单
+	retryConf := *testConf
+	retryConf.Platform.RetryInterval = 1
+	conf.SetCurrent(&retryConf)
+	platform.setSettleFail(true)
+	if code := handler.OnGameDrawHandler("e2e-retry", redelivered(2, []int64{3, 5})); code != errcode.ErrorOk {
+		t.Fatalf("shard with failed platform settle code = %v", code)
+	}
+	<-e2eCompleted
+	if platform.settleCount() != settleCount {
+		t.Fatalf("failed platform settle recorded")
+	}
+	//重投时派彩请求已经在发件箱中 不再重复派彩
+	if code := handler.OnGameDrawHandler("e2e-retry", redelivered(2, []int64{3, 5})); code != errcode.ErrorOk {
+		t.Fatalf("redelivered shard code = %v", code)
+	}
+
+	platform.setSettleFail(false)
+	outbox.Start()
+	defer outbox.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for platform.settleCount() == settleCount && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if platform.settleCount() != settleCount+1 {
+		t.Fatalf("settle count = %v, want %v", platform.settleCount(), settleCount+1)
+	}
+	platform.mutex.Lock()
+	lastSettle := platform.settle[len(platform.settle)-1]
+	platform.mutex.Unlock()
+	if len(lastSettle) != 1 || lastSettle[0].OrderNo != 5 {
+		t.Errorf("retried shard settled %+v, want only order 5", lastSettle)
+	}
+	if stats, _ := outbox.Stats(); stats[string(types.OutboxStatusFailed)] != 0 {
+		t.Errorf("outbox stats = %v, want no failed message", stats)
+	}
+}
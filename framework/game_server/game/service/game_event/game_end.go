@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sl.framework.com/game_server/conf"
 	errcode "sl.framework.com/game_server/error_code"
+	fairodds "sl.framework.com/game_server/game/service/fair_odds"
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/game/service/type/VO"
 	"sl.framework.com/game_server/game/service/type/dto"
@@ -46,6 +47,7 @@ func NewGameEnd(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEvent
 			RoundDTO:       roundDto,
 			TraceId:        gameEventInitVo.TraceId,
 			RequestId:      gameEventInitVo.RequestId,
+			Ctx:            gameEventInitVo.Ctx,
 			RetHandleEvent: gameEventInitVo.Code,
 			MsgHeader: fmt.Sprintf("command=%s  traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
 				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
@@ -65,6 +67,8 @@ func (e *GameEndEvent) HandleRondEvent() {
 	*e.RetHandleEvent = errcode.ErrorOk
 	trace.Info("[游戏结束] GameEnd %v 局信息%+v", e.MsgHeader, e.Dto.Payload)
 	EventCommonSet(&e.EventBase, string(types.GameEventCommandGameEnd), string(types.GameEventCommandGameEnd))
+	//公开本局动态赔率种子
+	e.revealOddsSeed()
 
 	//房间缓存
 	GameRoomCache(e.TraceId, e.Dto.GameRoomId, e.Dto.GameRoundId, e.Dto.GameId)
@@ -73,6 +77,26 @@ func (e *GameEndEvent) HandleRondEvent() {
 	return
 }
 
+/**
+ * revealOddsSeed
+ * 本局结束后公开动态赔率种子 并推送到ws 玩家可以用种子复算本局的动态赔率
+ *
+ * @return
+ */
+
+func (e *GameEndEvent) revealOddsSeed() {
+	if !conf.GetDynamicOddsEnable() {
+		return
+	}
+	seed, ok := fairodds.RevealRound(e.TraceId, e.Dto.GameRoomId, e.Dto.GameRoundId)
+	if !ok {
+		return
+	}
+	trace.Info("[游戏结束] 公开动态赔率种子 %v seedHash=%v", e.MsgHeader, seed.SeedHash)
+	rpcreq.AsyncSendRoundMessage[*types.DynamicOddsSeedDTO](e.TraceId, strconv.FormatInt(e.Dto.GameRoomId, 10), e.RoundDTO.Id,
+		string(types.GameEventCommandOddsReveal), seed)
+}
+
 /**
  * userLimitCache
  * 用户限红缓存
@@ -0,0 +1,233 @@
+package gameevent
+
+import (
+	"fmt"
+	"sl.framework.com/game_server/conf"
+	errcode "sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/dao/gamedb"
+	"sl.framework.com/game_server/game/service"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/VO"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/mq"
+	"sl.framework.com/game_server/redis/cache"
+	rpcreq "sl.framework.com/game_server/rpc_client"
+	"sl.framework.com/tool"
+	"sl.framework.com/trace"
+	"strconv"
+	"time"
+)
+
+type GameResettleEvent struct {
+	types.EventBase
+}
+
+/**
+ * NewGameResettle
+ * 创建重新结算实例
+ *
+ * @param event types.GameEventVO - 来自数据源的事件信息
+ * @param roundDto *types.GameRoundDTO - 的游戏局信息
+ * @param gameEventInitVo *VO.GameEventInitVO - 事件初始化信息
+ * @return RETURN - 返回游戏事件实例
+ */
+
+func NewGameResettle(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEventInitVo *VO.GameEventInitVO) *GameResettleEvent {
+	return &GameResettleEvent{
+		EventBase: types.EventBase{
+			Dto: &types.EventDTO{
+				GameRoomId:      gameEventInitVo.RoomId,
+				GameRoundId:     gameEventInitVo.RoundId,
+				GameId:          conf.GetGameId(),
+				NextGameRoundId: gameEventInitVo.NextRoundId,
+				GameRoundNo:     event.GameRoundNo,
+				Command:         string(event.Command),
+				Time:            event.Time,
+				ReceiveTime:     event.ReceiveTime,
+				Payload:         event.Payload,
+			},
+			RoundDTO:       roundDto,
+			TraceId:        gameEventInitVo.TraceId,
+			RequestId:      gameEventInitVo.RequestId,
+			Ctx:            gameEventInitVo.Ctx,
+			RetHandleEvent: gameEventInitVo.Code,
+			MsgHeader: fmt.Sprintf("command=%s  traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
+				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
+		},
+	}
+}
+
+/**
+ * HandleRondEvent
+ * 开奖结果修正 使用修正后的结果对已经派彩的注单重新结算
+ * 1.解析修正后的局结果 推送到中台和客户端 并写入结果缓存
+ * 2.从结果缓存中取出修正前的局结果 用于审计
+ * 3.取出已经派彩的注单 分片发送重新结算MQ消息 由结算节点计算差额并补差
+ * 注单缓存过期时从数据库加载 加载失败返回错误码由数据源重发 不能当作没有注单
+ */
+
+func (e *GameResettleEvent) HandleRondEvent() {
+	trace.Info("[重新结算] GameResettle %v 局信息%+v", e.MsgHeader, e.Dto.Payload)
+	*e.RetHandleEvent = errcode.ErrorOk
+	strRoomId := strconv.FormatInt(e.Dto.GameRoomId, 10)
+	strRoundId := strconv.FormatInt(e.Dto.GameRoundId, 10)
+
+	//1.计算修正后的游戏结果
+	pWatcher := tool.NewWatcher("重新结算解析结果")
+	drawer := service.GetDrawer(e.TraceId, types.GameId(conf.GetGameId()))
+	if drawer == nil {
+		trace.Error("[重新结算] %v, no game drawer", e.MsgHeader)
+		*e.RetHandleEvent = errcode.GameErrorWrongGameId
+		return
+	}
+	defer service.PutDrawer(types.GameId(conf.GetGameId()), drawer)
+	gameResult := drawer.ParseGameResult(e.Dto)
+	if gameResult == nil {
+		trace.Error("[重新结算] %v, 解析牌局结果错误", e.MsgHeader)
+		*e.RetHandleEvent = errcode.ValidateErrorResultParseFailed
+		return
+	}
+	gameResult.GameRoundId = strRoundId
+	gameResult.Timestamp = tool.Current()
+	pWatcher.Stop()
+
+	//只有已经派彩的注单需要重新结算 未派彩的注单由正常结算流程处理 在推送结果之前加载 加载失败时不产生任何副作用
+	resettleOrderList, err := loadResettleOrderNos(e.MsgHeader, cache.GetOrders(e.TraceId, strRoomId, strRoundId),
+		e.Dto.GameRoomId, e.Dto.GameRoundId)
+	if err != nil {
+		trace.Error("[重新结算] %v, 加载已派彩注单失败 error=%v", e.MsgHeader, err)
+		*e.RetHandleEvent = errcode.DBErrorNotOk
+		return
+	}
+
+	//2.修正前的局结果 需要在写入新结果之前获取
+	previousResult := e.previousResult()
+	if previousResult == nil {
+		trace.Notice("[重新结算] %v, 结果缓存中没有修正前的局结果", e.MsgHeader)
+	}
+
+	pWatcher.Start("重新结算推送结果")
+	if errcode.ErrorOk != rpcreq.DrawResultPost(e.TraceId, gameResult) {
+		trace.Error("[重新结算] %v, DrawResultPost gameResult=%+v failed.", e.MsgHeader, gameResult)
+		*e.RetHandleEvent = errcode.HttpErrorPlatformPost
+		return
+	}
+	e.RoundDTO.Status = string(types.GameEventCommandGameResettle)
+	roundCache := cache.GameRoundCache{TraceId: e.TraceId, RoomId: e.Dto.GameRoomId, GameRoundId: e.RoundDTO.Id}
+	roundCache.Set(e.RoundDTO)
+	rpcreq.AsyncSendRoundMessage(e.TraceId, strRoomId, e.RoundDTO.Id, string(types.GameEventCommandGameResettle), gameResult)
+	gameResultCache := &cache.SettleCache{TraceId: e.TraceId, RoomId: e.Dto.GameRoomId}
+	gameResultCache.Set(gameResult)
+	pWatcher.Stop()
+
+	//3.分片发送重新结算MQ消息
+	pWatcher.Start("重新结算分片发送MQ")
+	if len(resettleOrderList) == 0 {
+		trace.Notice("[重新结算] %v, 当局没有已派彩注单 无需重新结算", e.MsgHeader)
+		return
+	}
+
+	//与正常结算使用相同的分片主题和分区键 保证重新结算排在该局原结算分片之后
+	topic, ok := mq.GameDrawTopic(e.Dto.GameRoomId)
+	if !ok {
+		trace.Error("[重新结算] %v, 没有配置开奖分片主题", e.MsgHeader)
+		return
+	}
+	shardingKey := mq.RoundShardingKey(e.Dto.GameRoomId, e.Dto.GameRoundId)
+	patches := tool.SplitList[int64](resettleOrderList, conf.GetDrawSize())
+	for shardNo, row := range patches {
+		if len(row) == 0 {
+			continue
+		}
+		gameDrawDataDTOItem := types.GameDrawDataDTO{
+			GameRoomId:         e.Dto.GameRoomId,
+			GameRoundId:        e.Dto.GameRoundId,
+			GameId:             e.Dto.GameId,
+			GameRoundNo:        e.Dto.GameRoundNo,
+			GameRoundResultDTO: *gameResult,
+			OrderList:          row,
+			ShardNo:            shardNo,
+			Resettle:           true,
+			ResettleId:         e.RequestId,
+			PreviousResultDTO:  previousResult,
+		}
+		messageStr, err := generateGameDrawMessage(e.TraceId, gameDrawDataDTOItem)
+		if err != nil {
+			trace.Error("[重新结算] %v, 生成重新结算MQ消息失败", e.MsgHeader)
+			continue
+		}
+		createTime := strconv.FormatInt(time.Now().Unix(), 10)
+		trace.Info("[重新结算] %v, 分片发送MQ topic:%v 分片数量:%v messageStr:%v", e.MsgHeader, topic, len(patches), messageStr)
+		mq.SendMessage(topic, strconv.FormatInt(e.Dto.GameId, 10), e.TraceId, createTime, shardingKey, messageStr)
+	}
+	pWatcher.Stop()
+}
+
+/**
+ * previousResult
+ * 从结果缓存中获取本局最近一次的开奖结果
+ *
+ * @return *types.GameRoundResultDTO - 修正前的局结果 没有则返回nil
+ */
+
+func (e *GameResettleEvent) previousResult() *types.GameRoundResultDTO {
+	gameResultCache := &cache.SettleCache{TraceId: e.TraceId, RoomId: e.Dto.GameRoomId}
+	if !gameResultCache.Get() {
+		return nil
+	}
+	strRoundId := strconv.FormatInt(e.Dto.GameRoundId, 10)
+	for i := len(gameResultCache.Data) - 1; i >= 0; i-- {
+		if gameResultCache.Data[i].GameRoundId == strRoundId {
+			return gameResultCache.Data[i]
+		}
+	}
+	return nil
+}
+
+// queryResettleOrderNos 从数据库查询需要重新结算的订单号 测试时替换
+var queryResettleOrderNos = gamedb.QueryResettleOrderNos
+
+/**
+ * loadResettleOrderNos
+ * 获取需要重新结算的订单号 优先使用注单缓存 缓存中没有已派彩注单时从数据库加载
+ * 缓存有过期时间 缓存为空不代表没有注单
+ *
+ * @param msgHeader string - 日志头
+ * @param cached []*dto.BetDTO - 缓存中的当局全量注单
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return []int64 - 需要重新结算的订单号
+ * @return error - 数据库错误
+ */
+
+func loadResettleOrderNos(msgHeader string, cached []*dto.BetDTO, gameRoomId, gameRoundId int64) ([]int64, error) {
+	if orderNoList := resettleOrderNoList(cached); len(orderNoList) > 0 {
+		return orderNoList, nil
+	}
+	orderNoList, err := queryResettleOrderNos(gameRoomId, gameRoundId)
+	if err != nil {
+		return nil, err
+	}
+	trace.Info("[重新结算] %v, 注单缓存中没有已派彩注单 从数据库加载 size=%v", msgHeader, len(orderNoList))
+	return orderNoList, nil
+}
+
+/**
+ * resettleOrderNoList
+ * 筛选需要重新结算的注单 已派彩或者已重新结算过的注单
+ *
+ * @param orderList []*dto.BetDTO - 当局全量注单
+ * @return []int64 - 需要重新结算的订单号
+ */
+
+func resettleOrderNoList(orderList []*dto.BetDTO) []int64 {
+	orderNoList := make([]int64, 0, len(orderList))
+	for _, order := range orderList {
+		switch const_type.PostStatus(order.PostStatus) {
+		case const_type.PostStatusPaid, const_type.PostStatusResettle:
+			orderNoList = append(orderNoList, order.OrderNo)
+		}
+	}
+	return orderNoList
+}
@@ -0,0 +1,52 @@
+package gameevent
+
+import (
+	"errors"
+	"reflect"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"testing"
+)
+
+func TestResettleOrderNoList(t *testing.T) {
+	orderList := []*dto.BetDTO{
+		{OrderNo: 1, PostStatus: string(const_type.PostStatusPaid)},
+		{OrderNo: 2, PostStatus: string(const_type.PostStatusCreate)},
+		{OrderNo: 3, PostStatus: string(const_type.PostStatusResettle)},
+		{OrderNo: 4, PostStatus: string(const_type.PostStatusRefund)},
+	}
+	if got := resettleOrderNoList(orderList); !reflect.DeepEqual(got, []int64{1, 3}) {
+		t.Errorf("resettleOrderNoList() = %v, want [1 3]", got)
+	}
+}
+
+func TestLoadResettleOrderNos(t *testing.T) {
+	var dbErr error
+	calls := 0
+	old := queryResettleOrderNos
+	queryResettleOrderNos = func(gameRoomId, gameRoundId int64) ([]int64, error) {
+		calls++
+		if dbErr != nil {
+			return nil, dbErr
+		}
+		return []int64{7, 8}, nil
+	}
+	defer func() { queryResettleOrderNos = old }()
+
+	//缓存命中不查库
+	cached := []*dto.BetDTO{{OrderNo: 1, PostStatus: string(const_type.PostStatusPaid)}}
+	if got, err := loadResettleOrderNos("test", cached, 1, 2); err != nil || !reflect.DeepEqual(got, []int64{1}) || calls != 0 {
+		t.Errorf("cache hit = %v, %v, db calls %d", got, err, calls)
+	}
+
+	//缓存过期从数据库加载
+	if got, err := loadResettleOrderNos("test", nil, 1, 2); err != nil || !reflect.DeepEqual(got, []int64{7, 8}) || calls != 1 {
+		t.Errorf("cache miss = %v, %v, db calls %d", got, err, calls)
+	}
+
+	//数据库失败不能当作没有注单
+	dbErr = errors.New("db down")
+	if got, err := loadResettleOrderNos("test", nil, 1, 2); err == nil || got != nil {
+		t.Errorf("db error = %v, %v, want error", got, err)
+	}
+}
@@ -5,15 +5,15 @@ import (
 	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
 	errcode "sl.framework.com/game_server/error_code"
+	fairodds "sl.framework.com/game_server/game/service/fair_odds"
 	gamelogic "sl.framework.com/game_server/game/service/game"
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/game/service/type/VO"
 	"sl.framework.com/game_server/game/service/type/dto"
 	"sl.framework.com/game_server/redis/cache"
-	"sl.framework.com/tool"
 	"sl.framework.com/trace"
-	"sort"
 	"strconv"
+	"time"
 )
 
 type GameStartEvent struct {
@@ -49,6 +49,7 @@ func NewGameStart(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEve
 			RoundDTO:       roundDto,
 			TraceId:        gameEventInitVo.TraceId,
 			RequestId:      gameEventInitVo.RequestId,
+			Ctx:            gameEventInitVo.Ctx,
 			RetHandleEvent: gameEventInitVo.Code,
 			MsgHeader: fmt.Sprintf("command=%s  traceId=%v,RequestId=%v, roomId=%v, gameRoundId=%v, "+
 				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
@@ -69,11 +70,16 @@ func (e *GameStartEvent) HandleRondEvent() {
 	*e.RetHandleEvent = errcode.ErrorOk
 	//基础事务
 	gameRound := types.GameRound{RoundId: strconv.FormatInt(e.Dto.GameRoundId, 10), RoundNo: e.Dto.GameRoundNo}
+	//动态赔率种子必须在Bet_Start公布之前生成 下一局的种子在本局公布 早于下一局的外部种子
+	oddsSeed := e.commitOddsSeed()
+	if oddsSeed != nil {
+		gameRound.SeedHash = oddsSeed.SeedHash
+		gameRound.NextSeedHash = e.commitNextOddsSeed()
+	}
 	trace.Info("[游戏开始] GameStart %v 局信息%+v", e.MsgHeader, gameRound)
 	e.Dto.Payload = gameRound
 	EventCommonSet(&e.EventBase, string(types.GameEventCommandBetStart), string(types.GameEventCommandBetStart))
 	//延伸事务
-	var players *types.PlayerInRoom
 	if e.Dto.NextGameRoundId == 0 {
 		//局号为0则说明局号不正确直接返回 且不当做错误处理
 		trace.Notice("%v", e.MsgHeader)
@@ -90,19 +96,12 @@ func (e *GameStartEvent) HandleRondEvent() {
 
 	//获取redis中在线玩家信息
 	trace.Info("[游戏开始] 获取redis中在线玩家信息 %v 局信息%+v", e.MsgHeader, gameRound)
-	if players = gamelogic.GetUserIdsInRoom(e.Dto.GameRoomId); players == nil {
-		*e.RetHandleEvent = errcode.RedisErrorDataIsEmpty
-		trace.Notice("%v, no player in redis", e.MsgHeader)
-		return
-	}
-
-	//并发设置个人限红
+	//分批遍历在线玩家 并发设置个人限红
 	trace.Info("[游戏开始] 设置个人限红 %v 局信息%+v", e.MsgHeader, gameRound)
-	for _, player := range players.PlayerInfoSet {
-		fn := func(usrId int64, currency string) {
-			gamelogic.NewEventUserLimit(e.TraceId, currency, e.Dto.NextGameRoundId, usrId).HandleEvent()
-		}
-		async.AsyncRunWithAnyMulti[int64, string](fn, player.UserId, player.Currency)
+	if !cache.ScanRoomPlayers(e.TraceId, e.Dto.GameRoomId, time.Now(), precacheUserLimit(e.TraceId, e.Dto.NextGameRoundId)) {
+		*e.RetHandleEvent = errcode.RedisErrorGet
+		trace.Notice("%v, ScanRoomPlayers failed", e.MsgHeader)
+		return
 	}
 
 	//设置redis 设置下一局信息已经缓存
@@ -112,20 +111,54 @@ func (e *GameStartEvent) HandleRondEvent() {
 
 	//初始化动态赔率
 	trace.Info("[游戏开始] 根据动态赔率配置初始化动态赔率 %v", e.MsgHeader)
-	e.InitDynamicOdds()
+	e.InitDynamicOdds(oddsSeed)
 	return
 }
 
+/**
+ * commitOddsSeed
+ * 开启动态赔率时获取本局种子 上一局已经预先生成时使用预先生成的种子 否则现在生成 种子的hash随Bet_Start公布
+ *
+ * @return *types.DynamicOddsSeedDTO - 本局种子 未开启动态赔率或者生成失败时为nil
+ */
+
+func (e *GameStartEvent) commitOddsSeed() *types.DynamicOddsSeedDTO {
+	if !conf.GetDynamicOddsEnable() {
+		return nil
+	}
+	seed, ok := fairodds.CommitRound(e.TraceId, e.Dto.GameRoomId, e.Dto.GameRoundId)
+	if !ok {
+		trace.Error("[初始化动态赔率] 生成种子失败 本局不启用动态赔率 %v", e.MsgHeader)
+		return nil
+	}
+	return seed
+}
+
+// commitNextOddsSeed 预先生成下一局的种子 返回种子的hash 没有下一局或者生成失败时返回空 下一局开局时再生成
+func (e *GameStartEvent) commitNextOddsSeed() string {
+	if e.Dto.NextGameRoundId == 0 {
+		return ""
+	}
+	seed, ok := fairodds.CommitRound(e.TraceId, e.Dto.GameRoomId, e.Dto.NextGameRoundId)
+	if !ok {
+		trace.Error("[初始化动态赔率] 预先生成下一局种子失败 %v", e.MsgHeader)
+		return ""
+	}
+	return seed.SeedHash
+}
+
 /**
  * InitDynamicOdds
- * 初始化动态赔率
+ * 初始化动态赔率 使用已承诺的种子计算每个动态赔率玩法的结果并保存审计记录
+ *
+ * @param seed *types.DynamicOddsSeedDTO - 本局种子 为nil时不启用动态赔率
  * @return RETURN -
  */
 
-func (e *GameStartEvent) InitDynamicOdds() {
+func (e *GameStartEvent) InitDynamicOdds(seed *types.DynamicOddsSeedDTO) {
 	//获取是否开启动态赔率
-	if !conf.ServerConf.GameConfig.DynamicOddsEnable {
-		trace.Notice("[初始化动态赔率] 未开启！traceId=%v DynamicOddsEnable=%v", e.TraceId, conf.ServerConf.GameConfig.DynamicOddsEnable)
+	if seed == nil {
+		trace.Notice("[初始化动态赔率] 未开启！traceId=%v DynamicOddsEnable=%v", e.TraceId, conf.GetDynamicOddsEnable())
 		return
 	}
 	//从redis中读取房间赔率
@@ -137,7 +170,7 @@ func (e *GameStartEvent) InitDynamicOdds() {
 	for _, oddsInfo := range roomOddInfo {
 		wagerId, _ := strconv.ParseInt(oddsInfo.Id, 10, 64)
 		if oddsInfo.Type == "Random" {
-			infoItem := e.GetDynamicInfo(wagerId, oddsInfo.Odds, oddsInfo.Rate, oddsInfo.OddsList)
+			infoItem := e.GetDynamicInfo(seed, wagerId, oddsInfo.Odds, oddsInfo.Rate, oddsInfo.OddsList)
 			//缓存动态赔率
 			dynamicOddsCache := cache.DynamicOddsCache{TraceId: e.TraceId, GameId: e.Dto.GameId, WagerId: wagerId, RoomId: e.Dto.GameRoomId, GameRoundId: e.Dto.GameRoundId}
 			dynamicOddsCache.Set(infoItem)
@@ -148,70 +181,43 @@ func (e *GameStartEvent) InitDynamicOdds() {
 }
 
 /**
- * FunctionName
- * 函数功能描述
+ * GetDynamicInfo
+ * 用本局种子和Bet_Start事件的请求id计算玩法是否触发动态赔率以及使用的赔率 并保存审计记录
  *
- * @param PARAM - 参数说明
- * @return RETURN - 返回值说明
+ * @param seed *types.DynamicOddsSeedDTO - 本局种子
+ * @param wagerId int64 - 玩法id
+ * @param oriOdds float32 - 玩法默认赔率
+ * @param rate float32 - 触发动态赔率的概率 百分比
+ * @param oddsList []dto.GameWagerOddsDTO - 动态赔率配置
+ * @return *types.DynamicOddsInfo - 动态赔率信息
  */
 
-func (e *GameStartEvent) GetDynamicInfo(wagerId int64, oriOdds, rate float32, oddsList []dto.GameWagerOddsDTO) *types.DynamicOddsInfo {
+func (e *GameStartEvent) GetDynamicInfo(seed *types.DynamicOddsSeedDTO, wagerId int64, oriOdds, rate float32,
+	oddsList []dto.GameWagerOddsDTO) *types.DynamicOddsInfo {
 	trace.Info("[获取动态赔率信息] wagerId=%v,oriOdds=%v,rate=%v,oddsList=%v", wagerId, oriOdds, rate, oddsList)
-	//先随机看是否落在启用动态赔率权重内
-	weight := int(rate * 100) //权重0~weight (weight 最大值10000=100*100)
-	randomWeight := tool.GenerateRandomRange(0, 10000)
-	dynamicOddsInfo := &types.DynamicOddsInfo{}
-	dynamicOddsInfo.WagerId = wagerId
-	dynamicOddsInfo.Odds = oriOdds
-	if randomWeight <= weight {
-		dynamicOddsInfo.Enable = true
-	} else {
-		trace.Notice("[获取动态赔率信息] 没有随机触发动态赔率 wagerId=%v,weight=%v,curRandom=%v", wagerId, weight, randomWeight)
-		return dynamicOddsInfo
+	audit := &types.DynamicOddsAuditDTO{
+		TraceId:       e.TraceId,
+		GameId:        e.Dto.GameId,
+		GameRoomId:    e.Dto.GameRoomId,
+		GameRoundId:   e.Dto.GameRoundId,
+		WagerId:       wagerId,
+		SeedHash:      seed.SeedHash,
+		ClientSeed:    e.RequestId,
+		OriginalOdds:  oriOdds,
+		TriggerWeight: fairodds.RateWeight(float64(rate)),
+		Weights:       fairodds.BuildWeights(oddsList),
+		CreateTime:    time.Now(),
 	}
-	if len(oddsList) == 0 {
-		trace.Notice("[获取动态赔率信息] 获取动态赔率列表为空 GetDynamicInfo oddsList is empty")
-		return dynamicOddsInfo
+	fairodds.Draw(seed.ServerSeed, audit)
+	audit, ok := fairodds.SaveAudit(audit)
+	if !ok {
+		//没有审计记录的结果无法复核 不启用动态赔率
+		trace.Error("[获取动态赔率信息] 保存审计记录失败 不启用动态赔率 wagerId=%v", wagerId)
+		return &types.DynamicOddsInfo{WagerId: wagerId, Odds: oriOdds}
 	}
-	//启用动态赔率，接下来根据各个赔率的权重，随机一个赔率
-	//获取动态赔率权重
-	dynamicOddsRecordList := make([]*VO.DynamicOddsRecord, 0)
-	//先按照id进行升序排序
-	sort.Slice(oddsList, func(i, j int) bool { return oddsList[i].Id < oddsList[j].Id })
-	var (
-		curMin              int = 0
-		maxRandom           int = 0
-		randomDynamicWeight int
-	)
-
-	for _, oddsDTO := range oddsList {
-		nId, _ := strconv.ParseInt(oddsDTO.Id, 10, 64)
-		nWagerId, _ := strconv.ParseInt(oddsDTO.GameWagerId, 10, 64)
-		if oddsDTO.Status == "Enable" {
-			item := &VO.DynamicOddsRecord{}
-			item.Id = nId
-			item.WagerId = nWagerId
-			item.Status = true
-			item.MinWeight = curMin
-			item.MaxWeight = int(oddsDTO.Rate * 100)
-			item.Odds = oddsDTO.Odds
-			curMin = item.MaxWeight
-			dynamicOddsRecordList = append(dynamicOddsRecordList, item)
-			trace.Debug("[获取动态赔率信息] 玩法：%v,权重：%v~%v 赔率：%v", nWagerId, item.MinWeight, item.MaxWeight, item.Odds)
-		}
-	}
-	maxRandom = curMin
-	if maxRandom < 10000 {
-		maxRandom = 10000
-	}
-	randomDynamicWeight = tool.GenerateRandomRange(0, maxRandom)
-	//根据(record.MinWeight,record.MaxWeight] 前开后闭区间取赔率
-	for _, record := range dynamicOddsRecordList {
-		if randomDynamicWeight > record.MinWeight && randomWeight <= record.MaxWeight {
-			dynamicOddsInfo.Odds = record.Odds
-			break
-		}
-	}
-	trace.Info("[获取动态赔率信息] wagerId=%v,oriOdds=%v,rate=%v,oddsList=%v,结果:%v", wagerId, oriOdds, rate, oddsList, dynamicOddsInfo)
+
+	dynamicOddsInfo := fairodds.OddsInfo(audit)
+	trace.Info("[获取动态赔率信息] wagerId=%v,权重=%v,触发随机值=%v,赔率区间=%+v,赔率随机值=%v/%v,结果:%+v", wagerId,
+		audit.TriggerWeight, audit.TriggerRoll, audit.Weights, audit.OddsRoll, audit.OddsRange, dynamicOddsInfo)
 	return dynamicOddsInfo
 }
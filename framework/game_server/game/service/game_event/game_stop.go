@@ -5,6 +5,7 @@ import (
 	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
 	errcode "sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/executor"
 	"sl.framework.com/game_server/game/service/game"
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/game/service/type/VO"
@@ -13,6 +14,7 @@ import (
 	rpcreq "sl.framework.com/game_server/rpc_client"
 	"sl.framework.com/trace"
 	"strconv"
+	"time"
 )
 
 type GameStopEvent struct {
@@ -48,6 +50,7 @@ func NewGameStop(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEven
 			RoundDTO:       roundDto,
 			TraceId:        gameEventInitVo.TraceId,
 			RequestId:      gameEventInitVo.RequestId,
+			Ctx:            gameEventInitVo.Ctx,
 			RetHandleEvent: gameEventInitVo.Code,
 			MsgHeader: fmt.Sprintf("command=%s  traceId=%v,requestId=%v, roomId=%v, gameRoundId=%v, "+
 				"nextGameRoundId=%v", event.Command, gameEventInitVo.TraceId, gameEventInitVo.RequestId, gameEventInitVo.RoomId, gameEventInitVo.RoundId, gameEventInitVo.NextRoundId),
@@ -70,7 +73,6 @@ func (e *GameStopEvent) HandleRondEvent() {
 	trace.Info("[游戏停止] GameStop %v 局信息%+v", e.MsgHeader, gameRound)
 	e.Dto.Payload = gameRound
 	EventCommonSet(&e.EventBase, string(types.GameEventCommandBetStop), string(types.GameEventCommandBetStop))
-	var players *types.PlayerInRoom
 	if e.Dto.NextGameRoundId == 0 {
 		//局号为0则说明局号不正确直接返回 且不当做错误处理
 		trace.Notice("[游戏停止] %v, invalid nextGameRoundId", e.MsgHeader)
@@ -78,7 +80,7 @@ func (e *GameStopEvent) HandleRondEvent() {
 	}
 	//停止投注之后 向中台ws推送动态赔率
 
-	if conf.ServerConf.GameConfig.DynamicOddsEnable {
+	if conf.GetDynamicOddsEnable() {
 		trace.Info("[游戏停止] 动态赔率开启，推送动态赔率信息。 traceId=%v,gameRoomId=%v,gameRoundId=%v", e.TraceId, e.Dto.GameRoomId, e.Dto.GameRoundId)
 		//从缓存中获取动态赔率信息
 		//从缓存中获取动态赔率，设置结算赔率
@@ -123,20 +125,36 @@ func (e *GameStopEvent) HandleRondEvent() {
 	}
 	async.AsyncRunCoroutine(fnRoom)
 
-	//获取redis中在线玩家信息
-	if players = gamelogic.GetUserIdsInRoom(e.Dto.GameRoomId); players == nil {
-		*e.RetHandleEvent = errcode.RedisErrorDataIsEmpty
-		trace.Notice("[游戏停止] %v, getUserIdsInRoom no player", e.MsgHeader)
+	//分批遍历redis中在线玩家 并发设置个人限红
+	if !cache.ScanRoomPlayers(e.TraceId, e.Dto.GameRoomId, time.Now(), precacheUserLimit(e.TraceId, e.Dto.NextGameRoundId)) {
+		*e.RetHandleEvent = errcode.RedisErrorGet
+		trace.Notice("[游戏停止] %v, ScanRoomPlayers failed", e.MsgHeader)
 		return
 	}
 
-	//并发设置个人限红
-	for _, player := range players.PlayerInfoSet {
-		fn := func(usrId int64, currency string) {
-			gamelogic.NewEventUserLimit(e.TraceId, currency, e.Dto.NextGameRoundId, usrId).HandleEvent()
+	return
+}
+
+/**
+ * precacheUserLimit
+ * 生成遍历在线玩家时的处理函数 在userLimit协程池中并发设置每批玩家下一局的个人限红
+ *
+ * @param traceId string - 跟踪id
+ * @param nextGameRoundId int64 - 下一局局号
+ * @return func([]types.PlayerInfo) - 每批玩家的处理函数
+ */
+
+func precacheUserLimit(traceId string, nextGameRoundId int64) func([]types.PlayerInfo) {
+	return func(players []types.PlayerInfo) {
+		for _, player := range players {
+			usrId, currency := player.UserId, player.Currency
+			fn := func() {
+				gamelogic.NewEventUserLimit(traceId, currency, nextGameRoundId, usrId).HandleEvent()
+			}
+			//预先缓存失败时下注会重新查询个人限红 拒绝只记录日志
+			if err := executor.Submit(executor.PoolUserLimit, fn); err != nil {
+				trace.Notice("precacheUserLimit traceId=%v, userId=%v rejected, error=%v", traceId, usrId, err.Error())
+			}
 		}
-		async.AsyncRunWithAnyMulti[int64, string](fn, player.UserId, player.Currency)
 	}
-
-	return
 }
@@ -0,0 +1,269 @@
+package gameevent
+
+import (
+	"errors"
+	"fmt"
+	"sl.framework.com/game_server/conf"
+	errcode "sl.framework.com/game_server/error_code"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/VO"
+	"sl.framework.com/game_server/redis/cache"
+	"sl.framework.com/trace"
+	"slices"
+	"time"
+)
+
+/*
+	房间局状态机
+	Idle -Bet_Start-> Betting -Bet_Stop-> BetStopped -Game_Data-> Dealing -Game_Draw-> Drawn -Game_End-> Ended
+	BetStopped也可以直接Game_Draw Betting BetStopped Dealing Drawn都可以Cancel_Round到Cancelled
+	Ended Cancelled Idle状态下收到新局的Bet_Start开始新的一局
+	Game_Pause Change_Deck Game_Resettle等事件不属于局流程 不校验也不改变状态
+	不合法的迁移按照common.roundStateRecovery处理
+		strict	拒绝
+		forward	同一局向前跳过漏掉的事件 或者上一局没有结束直接开始新的一局 记录跳过的事件后接受 向后的迁移和重复的事件拒绝
+		off		只记录状态 不拒绝任何事件
+	上一局迟到的事件除off之外都拒绝
+	迁移后状态为Pending 事件处理成功后才清除 处理失败时同一局同一事件重发可以重新进入
+*/
+
+const (
+	RecoveryStrict  = "strict"
+	RecoveryForward = "forward"
+	RecoveryOff     = "off"
+)
+
+// errIllegalTransition 不合法的局状态迁移
+var errIllegalTransition = errors.New("illegal round state transition")
+
+// errRoundEventMoved 局状态已经被后续事件改变
+var errRoundEventMoved = errors.New("round state moved")
+
+var (
+	// roundEventStates 局事件迁移到的状态
+	roundEventStates = map[types.GameEventCommand]types.RoundState{
+		types.GameEventCommandBetStart:    types.RoundStateBetting,
+		types.GameEventCommandBetStop:     types.RoundStateBetStopped,
+		types.GameEventCommandGameData:    types.RoundStateDealing,
+		types.GameEventCommandGameDraw:    types.RoundStateDrawn,
+		types.GameEventCommandGameEnd:     types.RoundStateEnded,
+		types.GameEventCommandCancelRound: types.RoundStateCancelled,
+	}
+
+	// roundStateSources 同一局内每个状态合法的前置状态
+	roundStateSources = map[types.RoundState][]types.RoundState{
+		types.RoundStateBetStopped: {types.RoundStateBetting},
+		types.RoundStateDealing:    {types.RoundStateBetStopped, types.RoundStateDealing},
+		types.RoundStateDrawn:      {types.RoundStateBetStopped, types.RoundStateDealing},
+		types.RoundStateEnded:      {types.RoundStateDrawn},
+		types.RoundStateCancelled:  {types.RoundStateBetting, types.RoundStateBetStopped, types.RoundStateDealing, types.RoundStateDrawn},
+	}
+
+	// roundStateOrder 状态在局流程中的先后顺序 forward只允许向前迁移
+	roundStateOrder = map[types.RoundState]int{
+		types.RoundStateIdle:       0,
+		types.RoundStateBetting:    1,
+		types.RoundStateBetStopped: 2,
+		types.RoundStateDealing:    3,
+		types.RoundStateDrawn:      4,
+		types.RoundStateEnded:      5,
+		types.RoundStateCancelled:  5,
+	}
+
+	// roundMainPath 局的主流程 用于计算forward跳过的事件 Game_Data可以没有 不算漏掉
+	roundMainPath = []types.GameEventCommand{
+		types.GameEventCommandBetStart,
+		types.GameEventCommandBetStop,
+		types.GameEventCommandGameDraw,
+		types.GameEventCommandGameEnd,
+	}
+)
+
+// isRoundFinished 当前局已经结束 可以开始新的一局
+func isRoundFinished(state types.RoundState) bool {
+	return state == types.RoundStateIdle || state == types.RoundStateEnded || state == types.RoundStateCancelled
+}
+
+// missedCommands 顺序在(fromOrder,toOrder)之间被跳过的主流程事件 格式为 局号:事件
+func missedCommands(roundNo string, fromOrder, toOrder int) []string {
+	missed := make([]string, 0)
+	for _, command := range roundMainPath {
+		if order := roundStateOrder[roundEventStates[command]]; order > fromOrder && order < toOrder {
+			missed = append(missed, fmt.Sprintf("%v:%v", roundNo, command))
+		}
+	}
+	return missed
+}
+
+/**
+ * nextRoundState
+ * 计算房间收到局事件后的新状态 新状态为Pending
+ *
+ * @param cur *types.RoundStateDTO - 房间当前状态 没有状态时为nil
+ * @param event types.GameEventVO - 局事件
+ * @param requestId string - 请求id
+ * @param recovery string - 不合法迁移的处理方式
+ * @param now time.Time - 当前时间
+ * @return *types.RoundStateDTO - 新状态
+ * @return error - 迁移不合法时返回errIllegalTransition
+ */
+
+func nextRoundState(cur *types.RoundStateDTO, event types.GameEventVO, requestId, recovery string,
+	now time.Time) (*types.RoundStateDTO, error) {
+	if cur == nil {
+		cur = &types.RoundStateDTO{GameRoomId: event.GameRoomId, State: types.RoundStateIdle}
+	}
+	target := roundEventStates[event.Command]
+	next := &types.RoundStateDTO{
+		GameRoomId:  event.GameRoomId,
+		GameRoundNo: event.GameRoundNo,
+		State:       target,
+		Command:     string(event.Command),
+		RequestId:   requestId,
+		LastRoundNo: cur.LastRoundNo,
+		Pending:     true,
+		UpdateTime:  now,
+	}
+	if event.GameRoundNo != cur.GameRoundNo && cur.GameRoundNo != "" {
+		next.LastRoundNo = cur.GameRoundNo
+	}
+	if recovery == RecoveryOff {
+		return next, nil
+	}
+
+	//同一局
+	if event.GameRoundNo == cur.GameRoundNo {
+		//上次处理失败的事件重发
+		if cur.Pending && cur.Command == string(event.Command) && cur.State == target {
+			return next, nil
+		}
+		if slices.Contains(roundStateSources[target], cur.State) {
+			return next, nil
+		}
+		if recovery == RecoveryForward && !isRoundFinished(cur.State) && roundStateOrder[target] > roundStateOrder[cur.State] {
+			if target != types.RoundStateCancelled {
+				next.Missed = missedCommands(event.GameRoundNo, roundStateOrder[cur.State], roundStateOrder[target])
+			}
+			return next, nil
+		}
+		return nil, fmt.Errorf("%w: round %v %v -> %v by %v", errIllegalTransition, event.GameRoundNo, cur.State, target, event.Command)
+	}
+
+	//新的一局
+	if event.GameRoundNo == cur.LastRoundNo {
+		return nil, fmt.Errorf("%w: round %v is over, current round %v %v, late %v", errIllegalTransition,
+			event.GameRoundNo, cur.GameRoundNo, cur.State, event.Command)
+	}
+	if target == types.RoundStateBetting && isRoundFinished(cur.State) {
+		return next, nil
+	}
+	if recovery == RecoveryForward {
+		//上一局没有结束就被放弃 Game_End之前的事件都算漏掉
+		if !isRoundFinished(cur.State) {
+			next.Missed = missedCommands(cur.GameRoundNo, roundStateOrder[cur.State], roundStateOrder[types.RoundStateEnded]+1)
+		}
+		if target != types.RoundStateCancelled {
+			next.Missed = append(next.Missed, missedCommands(event.GameRoundNo, roundStateOrder[types.RoundStateIdle], roundStateOrder[target])...)
+		}
+		return next, nil
+	}
+	return nil, fmt.Errorf("%w: round %v %v -> round %v %v by %v", errIllegalTransition, cur.GameRoundNo, cur.State,
+		event.GameRoundNo, target, event.Command)
+}
+
+/**
+ * checkRoundEvent
+ * 校验局事件并迁移房间状态 不属于局流程的事件直接通过
+ *
+ * @param event types.GameEventVO - 局事件
+ * @param gameEventInitVo *VO.GameEventInitVO - 事件信息
+ * @return int - 错误码 通过时为errcode.ErrorOk
+ * @return error - 拒绝的原因
+ */
+
+func checkRoundEvent(event types.GameEventVO, gameEventInitVo *VO.GameEventInitVO) (int, error) {
+	if _, ok := roundEventStates[event.Command]; !ok {
+		return errcode.ErrorOk, nil
+	}
+
+	recovery := conf.GetRoundStateRecovery()
+	state, err := cache.UpdateRoundState(gameEventInitVo.TraceId, event.GameRoomId, func(cur *types.RoundStateDTO) (*types.RoundStateDTO, error) {
+		return nextRoundState(cur, event, gameEventInitVo.RequestId, recovery, time.Now())
+	})
+	switch {
+	case errors.Is(err, errIllegalTransition):
+		return errcode.GameErrorWrongGameRoundStatus, err
+	case err != nil && recovery == RecoveryOff:
+		trace.Error("[局状态] 保存局状态失败 不校验 traceId=%v, roomId=%v, roundNo=%v, command=%v, error=%v",
+			gameEventInitVo.TraceId, event.GameRoomId, event.GameRoundNo, event.Command, err.Error())
+		return errcode.ErrorOk, nil
+	case err != nil:
+		return errcode.RedisErrorGet, err
+	}
+
+	if len(state.Missed) > 0 {
+		trace.FromContext(gameEventInitVo.Ctx).Notice("[局状态] 补偿漏掉的事件", "missed", state.Missed)
+	}
+	trace.FromContext(gameEventInitVo.Ctx).Info("[局状态] 迁移完成", "state", state.State)
+	return errcode.ErrorOk, nil
+}
+
+/**
+ * CheckRoundEvent
+ * 校验局事件并迁移房间状态 在查询局信息和创建下一局之前调用 被拒绝的事件不能产生任何副作用
+ *
+ * @param event types.GameEventVO - 局事件
+ * @param gameEventInitVo *VO.GameEventInitVO - 事件信息 此时还没有局id
+ * @return *RejectedEvent - 校验不通过时返回拒绝事件 通过时为nil
+ */
+
+func CheckRoundEvent(event types.GameEventVO, gameEventInitVo *VO.GameEventInitVO) *RejectedEvent {
+	code, err := checkRoundEvent(event, gameEventInitVo)
+	if err == nil {
+		return nil
+	}
+	return &RejectedEvent{EventBase: newEventBase(event, nil, gameEventInitVo), Code: code, Reason: err}
+}
+
+/**
+ * FinishRoundEvent
+ * 局事件处理成功后清除Pending 之后同一事件的重发按重复事件处理
+ *
+ * @param event types.GameEventVO - 局事件
+ * @param gameEventInitVo *VO.GameEventInitVO - 事件信息
+ * @return
+ */
+
+func FinishRoundEvent(event types.GameEventVO, gameEventInitVo *VO.GameEventInitVO) {
+	if _, ok := roundEventStates[event.Command]; !ok {
+		return
+	}
+
+	_, err := cache.UpdateRoundState(gameEventInitVo.TraceId, event.GameRoomId, func(cur *types.RoundStateDTO) (*types.RoundStateDTO, error) {
+		if cur == nil || !cur.Pending || cur.GameRoundNo != event.GameRoundNo || cur.Command != string(event.Command) {
+			return nil, errRoundEventMoved
+		}
+		next := *cur
+		next.Pending = false
+		return &next, nil
+	})
+	if err != nil && !errors.Is(err, errRoundEventMoved) {
+		trace.FromContext(gameEventInitVo.Ctx).Error("[局状态] 清除Pending失败", "error", err)
+	}
+}
+
+/**
+ * RejectedEvent
+ * 局状态校验不通过的事件 只返回错误码 不做任何处理
+ */
+
+type RejectedEvent struct {
+	types.EventBase
+	Code   int   //错误码
+	Reason error //拒绝的原因
+}
+
+func (e *RejectedEvent) HandleRondEvent() {
+	*e.RetHandleEvent = e.Code
+	e.Log().Notice("[局状态] 拒绝事件", "code", e.Code, "reason", e.Reason)
+}
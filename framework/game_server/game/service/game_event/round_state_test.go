@@ -0,0 +1,123 @@
+package gameevent
+
+import (
+	"errors"
+	"reflect"
+	types "sl.framework.com/game_server/game/service/type"
+	"testing"
+	"time"
+)
+
+func TestNextRoundState(t *testing.T) {
+	type step struct {
+		roundNo string
+		command types.GameEventCommand
+		want    types.RoundState //为空表示拒绝
+		missed  []string
+	}
+	tests := []struct {
+		name     string
+		recovery string
+		steps    []step
+	}{
+		{name: "normal", recovery: RecoveryStrict, steps: []step{
+			{"1", types.GameEventCommandBetStart, types.RoundStateBetting, nil},
+			{"1", types.GameEventCommandBetStop, types.RoundStateBetStopped, nil},
+			{"1", types.GameEventCommandGameData, types.RoundStateDealing, nil},
+			{"1", types.GameEventCommandGameData, types.RoundStateDealing, nil},
+			{"1", types.GameEventCommandGameDraw, types.RoundStateDrawn, nil},
+			{"1", types.GameEventCommandGameEnd, types.RoundStateEnded, nil},
+			{"2", types.GameEventCommandBetStart, types.RoundStateBetting, nil},
+			{"2", types.GameEventCommandCancelRound, types.RoundStateCancelled, nil},
+			{"3", types.GameEventCommandBetStart, types.RoundStateBetting, nil},
+		}},
+		{name: "strict out of order", recovery: RecoveryStrict, steps: []step{
+			{"1", types.GameEventCommandGameDraw, "", nil},
+			{"1", types.GameEventCommandBetStart, types.RoundStateBetting, nil},
+			{"1", types.GameEventCommandGameDraw, "", nil},
+			{"1", types.GameEventCommandBetStart, "", nil},
+			{"2", types.GameEventCommandBetStart, "", nil},
+			{"1", types.GameEventCommandBetStop, types.RoundStateBetStopped, nil},
+			{"1", types.GameEventCommandGameDraw, types.RoundStateDrawn, nil},
+			{"1", types.GameEventCommandBetStart, "", nil},
+			{"1", types.GameEventCommandBetStop, "", nil},
+		}},
+		{name: "forward", recovery: RecoveryForward, steps: []step{
+			{"1", types.GameEventCommandGameDraw, types.RoundStateDrawn, []string{"1:Bet_Start", "1:Bet_Stop"}},
+			{"1", types.GameEventCommandBetStop, "", nil},
+			{"1", types.GameEventCommandGameDraw, "", nil},
+			{"2", types.GameEventCommandBetStart, types.RoundStateBetting, []string{"1:Game_End"}},
+			{"1", types.GameEventCommandGameEnd, "", nil},
+			{"2", types.GameEventCommandGameEnd, types.RoundStateEnded, []string{"2:Bet_Stop", "2:Game_Draw"}},
+			{"2", types.GameEventCommandCancelRound, "", nil},
+			{"3", types.GameEventCommandBetStop, types.RoundStateBetStopped, []string{"3:Bet_Start"}},
+		}},
+		{name: "off", recovery: RecoveryOff, steps: []step{
+			{"1", types.GameEventCommandGameEnd, types.RoundStateEnded, nil},
+			{"1", types.GameEventCommandBetStart, types.RoundStateBetting, nil},
+			{"2", types.GameEventCommandGameDraw, types.RoundStateDrawn, nil},
+			{"1", types.GameEventCommandGameData, types.RoundStateDealing, nil},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cur *types.RoundStateDTO
+			for i, s := range tt.steps {
+				event := types.GameEventVO{GameRoomId: 7, GameRoundNo: s.roundNo, Command: s.command}
+				next, err := nextRoundState(cur, event, "req", tt.recovery, time.Now())
+				if s.want == "" {
+					if !errors.Is(err, errIllegalTransition) {
+						t.Fatalf("step %v %v %v error = %v, want illegal transition", i, s.roundNo, s.command, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %v %v %v error = %v", i, s.roundNo, s.command, err)
+				}
+				if next.State != s.want || next.GameRoundNo != s.roundNo || next.GameRoomId != 7 {
+					t.Fatalf("step %v %v %v state = %+v, want %v", i, s.roundNo, s.command, next, s.want)
+				}
+				if len(next.Missed) != 0 || len(s.missed) != 0 {
+					if !reflect.DeepEqual(next.Missed, s.missed) {
+						t.Fatalf("step %v %v %v missed = %v, want %v", i, s.roundNo, s.command, next.Missed, s.missed)
+					}
+				}
+				if !next.Pending {
+					t.Fatalf("step %v %v %v pending = false, want true", i, s.roundNo, s.command)
+				}
+				//事件处理成功
+				cur = next
+				cur.Pending = false
+			}
+		})
+	}
+}
+
+func TestNextRoundStatePending(t *testing.T) {
+	for _, recovery := range []string{RecoveryStrict, RecoveryForward} {
+		bet := types.GameEventVO{GameRoomId: 7, GameRoundNo: "1", Command: types.GameEventCommandBetStart}
+		cur, err := nextRoundState(nil, bet, "req1", recovery, time.Now())
+		if err != nil {
+			t.Fatalf("%v Bet_Start error = %v", recovery, err)
+		}
+
+		//处理失败后重发同一事件可以重新进入
+		retry, err := nextRoundState(cur, bet, "req2", recovery, time.Now())
+		if err != nil || retry.State != types.RoundStateBetting || !retry.Pending || retry.RequestId != "req2" {
+			t.Fatalf("%v Bet_Start resend = %+v, %v, want re-entry", recovery, retry, err)
+		}
+
+		//其他局或者其他事件不受影响
+		if _, err = nextRoundState(retry, types.GameEventVO{GameRoomId: 7, GameRoundNo: "1",
+			Command: types.GameEventCommandGameEnd}, "req3", recovery, time.Now()); recovery == RecoveryStrict && !errors.Is(err, errIllegalTransition) {
+			t.Fatalf("%v Game_End after pending Bet_Start error = %v, want illegal transition", recovery, err)
+		}
+
+		//处理成功后重发按重复事件拒绝
+		retry.Pending = false
+		if _, err = nextRoundState(retry, bet, "req4", recovery, time.Now()); !errors.Is(err, errIllegalTransition) {
+			t.Fatalf("%v Bet_Start after finished error = %v, want illegal transition", recovery, err)
+		}
+	}
+}
@@ -96,3 +96,20 @@ type IGameBettor interface {
 	*/
 	AfterConfirmedComplete(gameRoomId, gameRoundId, userId int64, drawOrder []*dto.BetDTO) int
 }
+
+/*
+	IRoundCancelListener 取消局回调接口 可选实现
+	下注对象(IGameBettor)同时实现该接口时 框架在取消局并完成注单作废和退款之后回调
+	未实现该接口的游戏不受影响
+*/
+
+type IRoundCancelListener interface {
+	/*
+		AfterRoundCancel 取消局完成回调函数
+		gameRoomId int64 房间ID
+		gameRoundId int64 局id
+		voidOrders []*dto.BetDTO 已经作废的注单 PostStatus为Refund表示已退款 Failed表示退款失败
+		返回值:调用结果 成功返回errcode.ErrorOk 失败返回相应错误码
+	*/
+	AfterRoundCancel(gameRoomId, gameRoundId int64, voidOrders []*dto.BetDTO) int
+}
@@ -1,14 +1,18 @@
 package listenner
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sl.framework.com/game_server/error_code"
 	"sl.framework.com/game_server/game/dao/redisdb"
 	"sl.framework.com/game_server/game/service"
+	"sl.framework.com/game_server/game/service/base"
 	gameevent "sl.framework.com/game_server/game/service/game_event"
+	"sl.framework.com/game_server/game/service/interface/events"
 	"sl.framework.com/game_server/game/service/type/VO"
 	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/metrics"
 	"sl.framework.com/game_server/redis/cache"
 
 	types "sl.framework.com/game_server/game/service/type"
@@ -31,6 +35,7 @@ import (
  */
 
 func DispatchGameEventV2(parserDto *dto.ControllerParserDTO, event types.GameEventVO, result *int) {
+	defer func() { metrics.IncGameEvent(string(event.Command), *result) }()
 
 	//参数校验
 	if len(parserDto.TraceId) == 0 || event.GameRoomId == 0 || len(event.GameRoundNo) == 0 || len(event.Command) == 0 {
@@ -38,43 +43,70 @@ func DispatchGameEventV2(parserDto *dto.ControllerParserDTO, event types.GameEve
 			parserDto.TraceId, event.GameRoomId, event.GameRoundNo, event.Command)
 		return
 	}
+	logCtx := trace.WithRoomId(trace.WithTraceId(context.Background(), parserDto.TraceId), event.GameRoomId)
+	logCtx = trace.WithFields(logCtx, "command", event.Command, "roundNo", event.GameRoundNo)
 	if tool.IsEmpty(event.Payload) {
 		trace.Info("分派游戏事件 DispatchGameEvent skip traceId=%v, game event=%v payload is empty", parserDto.TraceId, event.Command)
 	}
 	//分布式锁
 	gameEventRedisLockInfo := rediskey.GetGameEventLockRedisInfo(parserDto.RequestId, event.GameRoundNo, string(event.Command))
 	if !redisdb.TryLock(gameEventRedisLockInfo) {
-		trace.Error("分派游戏事件 DispatchGameEvent traceId=%v, redis lock key=%v, lock failed", parserDto.TraceId, gameEventRedisLockInfo.Key)
+		trace.FromContext(logCtx).Error("分派游戏事件 重复的事件 加锁失败", "key", gameEventRedisLockInfo.Key)
 		*result = int(errcode.GameErrorGameEventExist)
 		return
 	}
 	//defer redisdb.Unlock(gameEventRedisLockInfo) //同一个局号，同一个消息需要锁住不能释放等过期 数据源是多节点发送同一个消息 这里做幂等
+
+	//校验局状态 乱序和重复的事件不查询局信息 不创建下一局 不通知客户端也不处理
+	gameEventInitVO := &VO.GameEventInitVO{
+		TraceId:     parserDto.TraceId,
+		RoomId:      event.GameRoomId,
+		RequestId:   parserDto.RequestId,
+		Code:        result,
+		Time:        event.Time,
+		ReceiveTime: event.ReceiveTime,
+		Ctx:         logCtx,
+	}
+	if rejected := gameevent.CheckRoundEvent(event, gameEventInitVO); rejected != nil {
+		rejected.HandleRondEvent()
+		return
+	}
+
+	base.GetHeartbeatInstance().AddRoom(event.GameRoomId)
 	//查询局信息
 	pWatcher := tool.NewWatcher("获取局信息")
 
 	trace.Info("分派游戏事件 调用中台接口查询房间信息 traceId=%v, event:%+v command=%v", parserDto.TraceId, event, event.Command)
 	roundDTO := GetRoundInfo(parserDto.TraceId, event.GameRoundNo, event.GameRoomId)
 	pWatcher.Stop()
-	//尝试关闭异常局
-
-	pWatcher.Start("尝试关闭局")
-	gameRoundId, _ := strconv.ParseInt(roundDTO.Id, 10, 64)
-	if types.GameEventCommandGameDraw == event.Command {
-		trace.Info("分派游戏事件 尝试关闭局 traceId=%v, event:%+v command=%v", parserDto.TraceId, event, event.Command)
-		rpcreq.CloseExceptionRoundRequest(parserDto.TraceId, event.GameRoomId, gameRoundId)
-	}
-	pWatcher.Stop()
 	//创建新的局
 	pWatcher.Start(fmt.Sprintf("getNextRoundInfo handle traceId=%v", parserDto.TraceId))
 	trace.Info("分派游戏事件 创建新的局 traceId=%v, event:%+v command=%v", parserDto.TraceId, event, event.Command)
 	nextRoundInfo := getNextRoundInfo(parserDto.TraceId, event.GameRoundNo, parserDto.RequestId, event.GameRoomId)
 	if nextRoundInfo == nil {
-		trace.Error("分派游戏事件 生成下一局信息失败 traceId=%v, event:%+v command=%v", parserDto.TraceId, event, event.Command)
+		trace.FromContext(logCtx).Error("分派游戏事件 生成下一局信息失败", "event", fmt.Sprintf("%+v", event))
 		*result = int(errcode.GameErrorGameEventExist)
+		//局状态已经迁移为Pending 释放事件锁后数据源重发可以重新进入
+		redisdb.Unlock(gameEventRedisLockInfo)
 		return
 	}
 	pWatcher.Stop()
 
+	//创建事件实例
+	roundId, _ := strconv.ParseInt(roundDTO.Id, 10, 64)
+	nextRoundId, _ := strconv.ParseInt(nextRoundInfo.Id, 10, 64)
+	gameEventInitVO.RoundId, gameEventInitVO.NextRoundId = roundId, nextRoundId
+	gameEventInitVO.Ctx = trace.WithRoundId(logCtx, roundId)
+	instance := gameevent.CreateInstance(event, roundDTO, gameEventInitVO)
+
+	//尝试关闭异常局
+	pWatcher.Start("尝试关闭局")
+	if types.GameEventCommandGameDraw == event.Command {
+		trace.Info("分派游戏事件 尝试关闭局 traceId=%v, event:%+v command=%v", parserDto.TraceId, event, event.Command)
+		rpcreq.CloseExceptionRoundRequest(parserDto.TraceId, event.GameRoomId, roundId)
+	}
+	pWatcher.Stop()
+
 	//获取局信息缓存
 	pWatcher.Start("局信息缓存")
 	trace.Info("分派游戏事件 获取局信息缓存 traceId=%v, event:%+v command=%v", parserDto.TraceId, event, event.Command)
@@ -87,20 +119,15 @@ func DispatchGameEventV2(parserDto *dto.ControllerParserDTO, event types.GameEve
 
 	//游戏事件处理
 	pWatcher.Start("游戏事件处理")
-	roundId, _ := strconv.ParseInt(roundDTO.Id, 10, 64)
-	nextRoundId, _ := strconv.ParseInt(nextRoundInfo.Id, 10, 64)
-	gameEventInitVO := &VO.GameEventInitVO{
-		TraceId:     parserDto.TraceId,
-		RoomId:      event.GameRoomId,
-		RoundId:     roundId,
-		NextRoundId: nextRoundId,
-		RequestId:   parserDto.RequestId,
-		Code:        result,
-		Time:        event.Time,
-		ReceiveTime: event.ReceiveTime,
-	}
-	GameEvent(event, roundDTO, gameEventInitVO)
+	GameEvent(parserDto.TraceId, event, instance)
 	pWatcher.Stop()
+
+	//处理失败时释放事件锁 数据源重发时可以重新处理
+	if *result != errcode.ErrorOk {
+		redisdb.Unlock(gameEventRedisLockInfo)
+		return
+	}
+	gameevent.FinishRoundEvent(event, gameEventInitVO)
 }
 
 /**
@@ -181,18 +208,14 @@ func initFirstRound(traceId, gameRoundNo string, gameRoomId int64) (*types.GameR
 
 /**
  * GameEvent
- * 游戏事件处理函数
+ * 游戏事件处理函数 处理完成后通知注册的游戏事件监听
  *
  * @param traceId string - traceId用于日志跟踪
- * @param roundNo int64 - 局Id
- * @param nextRoundNo int64 - 下一局Id
- * @param event types.GameEventVO - 下一局Id
- * @param result  *int - 结果
+ * @param event types.GameEventVO - 游戏事件
+ * @param instance events.IEventHandler - 已通过局状态校验的事件实例
  */
 
-func GameEvent(event types.GameEventVO, roundDto *types.GameRoundDTO, gameEventInitVo *VO.GameEventInitVO) {
-
-	instance := gameevent.CreateInstance(event, roundDto, gameEventInitVo)
+func GameEvent(traceId string, event types.GameEventVO, instance events.IEventHandler) {
 	instance.HandleRondEvent()
-	service.AsyncNotifyGameEventListenerV2(gameEventInitVo.TraceId, event)
+	service.AsyncNotifyGameEventListenerV2(traceId, event)
 }
@@ -0,0 +1,66 @@
+package listenner
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"net/http/httptest"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/redis/cache"
+	rediskey "sl.framework.com/game_server/redis/rediskey"
+	"sort"
+	"sync/atomic"
+	"testing"
+)
+
+// TestDispatchRejectedEventHasNoSideEffect 被局状态拒绝的事件不查询局信息 不创建下一局 不写任何缓存
+func TestDispatchRejectedEventHasNoSideEffect(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+
+	var platformCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		platformCalls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	oldConf := conf.SetCurrent(&conf.Configuration{
+		Platform: conf.Platform{Host: server.URL, RetryTime: 1},
+		Common:   conf.Common{RoundStateRecovery: "strict"},
+	})
+	defer conf.SetCurrent(oldConf)
+
+	const roomId = int64(5001)
+	if _, err := cache.UpdateRoundState("test", roomId, func(cur *types.RoundStateDTO) (*types.RoundStateDTO, error) {
+		return &types.RoundStateDTO{GameRoomId: roomId, GameRoundNo: "R1", State: types.RoundStateEnded}, nil
+	}); err != nil {
+		t.Fatalf("UpdateRoundState() error = %v", err)
+	}
+
+	//已经结束的局收到迟到的Bet_Stop
+	parserDto := &dto.ControllerParserDTO{TraceId: "trace-1", RequestId: "req-1"}
+	event := types.GameEventVO{GameRoomId: roomId, GameRoundNo: "R1", Command: types.GameEventCommandBetStop}
+	result := errcode.ErrorOk
+	DispatchGameEventV2(parserDto, event, &result)
+
+	if result != errcode.GameErrorWrongGameRoundStatus {
+		t.Errorf("result = %v, want GameErrorWrongGameRoundStatus", result)
+	}
+	if n := platformCalls.Load(); n != 0 {
+		t.Errorf("platform calls = %v, want 0", n)
+	}
+	//只有事件锁和局状态 没有下一局的锁 局信息和事件缓存
+	want := []string{
+		rediskey.GetGameEventLockRedisInfo(parserDto.RequestId, event.GameRoundNo, string(event.Command)).Key,
+		rediskey.GetRoundStateRedisInfo().Key,
+	}
+	sort.Strings(want)
+	keys := redisServer.Keys()
+	if len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] {
+		t.Errorf("redis keys = %v, want %v", keys, want)
+	}
+}
@@ -0,0 +1,17 @@
+package types
+
+type (
+	/*
+		BetConfirmState 注单提交状态
+		投注确认时每个注单记录一条 Create->Posting->Confirmed/Failed
+		提交消息重新投递时已经扣款成功的注单不再提交 提交结果未知的注单先对账 避免重复扣款
+	*/
+	BetConfirmState struct {
+		OrderNo    int64  `json:"orderNo"`    //注单号
+		UserId     int64  `json:"userId"`     //用户id
+		Status     string `json:"status"`     //提交状态 见const_type.ConfirmStatus
+		Attempts   int    `json:"attempts"`   //已经向中台提交的次数
+		TraceId    string `json:"traceId"`    //最后一次更新状态的跟踪id
+		UpdateTime int64  `json:"updateTime"` //最后一次更新状态的时间 毫秒时间戳
+	}
+)
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBetAmountsJSONRoundTrip(t *testing.T) {
+	// 0.1+0.2这类金额用float64往返会出现尾差 Money必须原样保留
+	src := `{"id":1,"price":0.1,"betOdds":1.95,"betAmount":0.3,"availableBetAmountid":"0.3","winAmount":0.585}`
+	order := new(BetOrder)
+	if err := json.Unmarshal([]byte(src), order); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if order.Price.String() != "0.1" || order.BetAmount.String() != "0.3" || order.AvailableBetAmount.String() != "0.3" ||
+		order.WinAmount.String() != "0.585" || order.BetOdds != 1.95 {
+		t.Fatalf("json.Unmarshal() = %+v", order)
+	}
+
+	resp := BetOrderResp{
+		BetOrder:          &BetOrder{BetAmount: order.BetAmount},
+		BetOrderExtraResp: &BetOrderExtraResp{BetOdds: order.BetOdds, AvailableBetAmount: order.AvailableBetAmount, WinAmount: order.WinAmount},
+	}
+	data, err := json.Marshal(resp.BetOrderExtraResp)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `{"betOdds":1.95,"availableBetAmount":0.3,"winAmount":0.585}` {
+		t.Errorf("json.Marshal() = %s", data)
+	}
+
+	room := RoomDetailedInfo{UserLostAmount: order.BetAmount, UserWinAmount: order.WinAmount}
+	record := BetRecord{Price: order.Price, BetOdds: order.BetOdds, BetAmount: order.BetAmount}
+	for _, v := range []any{&room, &record} {
+		data, err = json.Marshal(v)
+		if err != nil {
+			t.Fatalf("json.Marshal(%T) error = %v", v, err)
+		}
+		if err = json.Unmarshal(data, v); err != nil {
+			t.Fatalf("json.Unmarshal(%T) error = %v", v, err)
+		}
+	}
+	if room.UserLostAmount.String() != "0.3" || room.UserWinAmount.String() != "0.585" {
+		t.Errorf("room round trip = %v %v", room.UserLostAmount, room.UserWinAmount)
+	}
+	if record.Price.String() != "0.1" || record.BetAmount.String() != "0.3" || record.BetOdds != 1.95 {
+		t.Errorf("record round trip = %+v", record)
+	}
+}
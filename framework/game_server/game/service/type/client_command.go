@@ -45,6 +45,8 @@ type GameEvent struct {
 }
 
 type GameRound struct {
-	RoundId string `json:"id"`      //游戏局id
-	RoundNo string `json:"roundNo"` //游戏局号，不可以超过32长度字符串
+	RoundId      string `json:"id"`                     //游戏局id
+	RoundNo      string `json:"roundNo"`                //游戏局号，不可以超过32长度字符串
+	SeedHash     string `json:"seedHash,omitempty"`     //动态赔率种子的sha256 开启动态赔率时在Bet_Start公布
+	NextSeedHash string `json:"nextSeedHash,omitempty"` //下一局动态赔率种子的sha256 在下一局的外部种子产生之前公布
 }
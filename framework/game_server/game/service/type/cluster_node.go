@@ -0,0 +1,11 @@
+package types
+
+// ClusterNode 集群节点信息 由节点心跳写入redis
+type ClusterNode struct {
+	ServerId  string  `json:"serverId"`  //服务id
+	Ip        string  `json:"ip"`        //节点ip
+	Version   string  `json:"version"`   //服务版本号
+	StartTime int64   `json:"startTime"` //启动时间(ms)
+	Rooms     []int64 `json:"rooms"`     //最近处理过的房间
+	LastSeen  int64   `json:"lastSeen"`  //最后心跳时间(ms) 取自集群成员有序集合的score
+}
@@ -0,0 +1,14 @@
+package const_type
+
+// 注单提交状态 记录投注确认时每个注单向中台提交扣款的进度
+type ConfirmStatus string
+
+const ConfirmStatusPosting ConfirmStatus = "Posting" //提交中 扣款结果未知 需要对账后才能重新提交
+
+const ConfirmStatusDeducted ConfirmStatus = "Deducted" //扣款成功 注单还未入库和更新缓存 重试时不再提交只重新入库
+
+const ConfirmStatusConfirmed ConfirmStatus = "Confirmed" //扣款成功并且已经入库
+
+const ConfirmStatusRejected ConfirmStatus = "Rejected" //中台拒绝扣款 余额不足 超过限额等业务错误 不再提交
+
+const ConfirmStatusFailed ConfirmStatus = "Failed" //扣款失败 可以重新提交
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"sl.framework.com/game_server/currency/money"
 	"time"
 )
 
@@ -46,60 +47,60 @@ type (
 		该注单数据从能力中心获取 int64类型以string类型传过来
 	*/
 	DrawOrder struct {
-		Id                 string  //数据库字段:id id
-		MerchantId         string  //数据库字段:merchant_id 商户id
-		SiteId             string  //数据库字段:site_id 站点id
-		UserId             string  //数据库字段:user_id 用户id
-		Username           string  //数据库字段:username 用户名
-		SiteUsername       string  //数据库字段:site_username 站点用户名
-		Nickname           string  //数据库字段:nickname 用户昵称
-		GroupId            string  //数据库字段:group_id 组id
-		OrderNo            string  //数据库字段:order_no 订单号
-		GameRoomId         string  //数据库字段:game_room_id 游戏房间id
-		GameRoomNo         string  //数据库字段:game_room_no 房号
-		WorkerId           string  //数据库字段:worker_id 现场员工id
-		WorkerUsername     string  //数据库字段:worker_username 主播名称
-		GameRoundId        string  //数据库字段:game_round_id 游戏局id
-		GameRoundNo        string  //数据库字段:game_round_no 局号
-		GameCategoryId     string  //数据库字段:game_category_id 游戏分类id
-		GameCategory       string  //数据库字段:game_category 游戏分类名称
-		GameId             string  //数据库字段:game_id 游戏id
-		GameCode           string  //数据库字段:game_code 游戏编码
-		Game               string  //数据库字段:game 游戏名称
-		GameWagerId        string  //数据库字段:game_wager_id 玩法id
-		GameWager          string  //数据库字段:game_wager 玩法名
-		GameWagerCode      string  //数据库字段:game_wager_code 玩法编码
-		Currency           string  //数据库字段:currency 币种
-		Price              float64 //数据库字段:price 单价
-		Num                int     //数据库字段:num 数量
-		BetOdds            float32 //数据库字段:bet_odds 投注时赔率
-		BetMultiple        int     //数据库字段:bet_multiple 投注倍数
-		BetAmount          float64 //数据库字段:bet_amount 投注金额
-		AvailableBetAmount float64 //数据库字段:available_bet_amount 有效投注金额
-		GameResult         string  //数据库字段:game_result 游戏结果
-		WinAmount          float64 //数据库字段:win_amount 如果赢金额,投注完成时计算好,派奖使用
-		DrawOdds           float32 //数据库字段:draw_odds 开奖时赔率
-		AvailableStatus    string  //数据库字段:available_status 有效状态: 有效 Available，取消 Cancel，重新结算 Resettle
-		ClientStatus       string  //数据库字段:client_status 显示状态：已支付 Paid，已结算 Settled，取消 Cancel，结算失败 Settled_Failed
-		BetStatus          string  //数据库字段:bet_status 投注状态:未支付 Unpaid，已支付 Paid，作废 Invalid,超时未支付 Timeout,支付失败 Failed，支付中 Paying，异常 Exception
-		WinLostStatus      string  //数据库字段:win_lost_status 输赢状态：创建 Create,输 Lose，赢 Win,和 Tie
-		PostStatus         string  //数据库字段:post_status 派奖状态：创建 Create，待派奖 Ready，派彩中 Doing，作废 Invalid，已派奖 Paid ，已退款 Refund , 失败 Failed , 重新结算 Resettle
-		AdvanceRate        float64 //数据库字段:advance_rate 大奖池垫资比例
-		ManualOn           string  //数据库字段:manual_on 手动投注投注:是 Y,否 N
-		TrialOn            string  //数据库字段:trial_on 是否试玩: 是 Y,否 N
-		ClientType         string  //数据库字段:client_type 客户端类型:安卓 Android，IOS IOS,电脑端H5 PC_H5，手机端H5 Mobile_H5
-		BetDoneTime        string  //数据库字段:bet_done_time 投注完成时间 string(date-time)
-		SettleTime         string  //数据库字段:settle_time 输赢结算时间 string(date-time)
-		PostTime           string  //数据库字段:post_time 派彩完成时间 string(date-time)
-		Sort               int     //数据库字段:sort 排序，同一组注单内排序
-		OrderPlanId        string  //数据库字段:order_plan_id 订单计划id
-		OperatorId         string  //数据库字段:operator_id 操作人id
-		Operator           string  //数据库字段:operator 操作人
-		CreateTime         string  //数据库字段:create_time 创建时间string(date-time)
-		UpdateTime         string  //数据库字段:update_time 更新时间string(date-time)
-		Summary            string  //数据库字段:summary 说明
-		Md5                string  //数据库字段:md5 数据指纹
-		SettleStatus       string  `json:"-"` //结算状态,取值为"Success" "Failed" 内部使用不发送出去
+		Id                 string      //数据库字段:id id
+		MerchantId         string      //数据库字段:merchant_id 商户id
+		SiteId             string      //数据库字段:site_id 站点id
+		UserId             string      //数据库字段:user_id 用户id
+		Username           string      //数据库字段:username 用户名
+		SiteUsername       string      //数据库字段:site_username 站点用户名
+		Nickname           string      //数据库字段:nickname 用户昵称
+		GroupId            string      //数据库字段:group_id 组id
+		OrderNo            string      //数据库字段:order_no 订单号
+		GameRoomId         string      //数据库字段:game_room_id 游戏房间id
+		GameRoomNo         string      //数据库字段:game_room_no 房号
+		WorkerId           string      //数据库字段:worker_id 现场员工id
+		WorkerUsername     string      //数据库字段:worker_username 主播名称
+		GameRoundId        string      //数据库字段:game_round_id 游戏局id
+		GameRoundNo        string      //数据库字段:game_round_no 局号
+		GameCategoryId     string      //数据库字段:game_category_id 游戏分类id
+		GameCategory       string      //数据库字段:game_category 游戏分类名称
+		GameId             string      //数据库字段:game_id 游戏id
+		GameCode           string      //数据库字段:game_code 游戏编码
+		Game               string      //数据库字段:game 游戏名称
+		GameWagerId        string      //数据库字段:game_wager_id 玩法id
+		GameWager          string      //数据库字段:game_wager 玩法名
+		GameWagerCode      string      //数据库字段:game_wager_code 玩法编码
+		Currency           string      //数据库字段:currency 币种
+		Price              money.Money //数据库字段:price 单价
+		Num                int         //数据库字段:num 数量
+		BetOdds            float32     //数据库字段:bet_odds 投注时赔率
+		BetMultiple        int         //数据库字段:bet_multiple 投注倍数
+		BetAmount          money.Money //数据库字段:bet_amount 投注金额
+		AvailableBetAmount money.Money //数据库字段:available_bet_amount 有效投注金额
+		GameResult         string      //数据库字段:game_result 游戏结果
+		WinAmount          money.Money //数据库字段:win_amount 如果赢金额,投注完成时计算好,派奖使用
+		DrawOdds           float32     //数据库字段:draw_odds 开奖时赔率
+		AvailableStatus    string      //数据库字段:available_status 有效状态: 有效 Available，取消 Cancel，重新结算 Resettle
+		ClientStatus       string      //数据库字段:client_status 显示状态：已支付 Paid，已结算 Settled，取消 Cancel，结算失败 Settled_Failed
+		BetStatus          string      //数据库字段:bet_status 投注状态:未支付 Unpaid，已支付 Paid，作废 Invalid,超时未支付 Timeout,支付失败 Failed，支付中 Paying，异常 Exception
+		WinLostStatus      string      //数据库字段:win_lost_status 输赢状态：创建 Create,输 Lose，赢 Win,和 Tie
+		PostStatus         string      //数据库字段:post_status 派奖状态：创建 Create，待派奖 Ready，派彩中 Doing，作废 Invalid，已派奖 Paid ，已退款 Refund , 失败 Failed , 重新结算 Resettle
+		AdvanceRate        float64     //数据库字段:advance_rate 大奖池垫资比例
+		ManualOn           string      //数据库字段:manual_on 手动投注投注:是 Y,否 N
+		TrialOn            string      //数据库字段:trial_on 是否试玩: 是 Y,否 N
+		ClientType         string      //数据库字段:client_type 客户端类型:安卓 Android，IOS IOS,电脑端H5 PC_H5，手机端H5 Mobile_H5
+		BetDoneTime        string      //数据库字段:bet_done_time 投注完成时间 string(date-time)
+		SettleTime         string      //数据库字段:settle_time 输赢结算时间 string(date-time)
+		PostTime           string      //数据库字段:post_time 派彩完成时间 string(date-time)
+		Sort               int         //数据库字段:sort 排序，同一组注单内排序
+		OrderPlanId        string      //数据库字段:order_plan_id 订单计划id
+		OperatorId         string      //数据库字段:operator_id 操作人id
+		Operator           string      //数据库字段:operator 操作人
+		CreateTime         string      //数据库字段:create_time 创建时间string(date-time)
+		UpdateTime         string      //数据库字段:update_time 更新时间string(date-time)
+		Summary            string      //数据库字段:summary 说明
+		Md5                string      //数据库字段:md5 数据指纹
+		SettleStatus       string      `json:"-"` //结算状态,取值为"Success" "Failed" 内部使用不发送出去
 	}
 
 	// OrderPlanIdItem 根据OrderPlanId从平台中心获取的数据对应的结构
@@ -109,12 +110,12 @@ type (
 	}
 
 	OrderPlanIdDrawResItem struct {
-		OrderPlanId        string  `json:"orderPlanId"`        //数据库字段:order_plan_id 订单计划id
-		OrderNo            string  `json:"orderNo"`            //数据库字段:order_no 订单号
-		WinAmount          float64 `json:"winAmount"`          //数据库字段:win_amount 如果赢金额,投注完成时计算好,派奖使用
-		AvailableBetAmount float64 `json:"availableBetAmount"` //数据库字段:available_bet_amount 有效投注金额
-		DrawOdds           float64 `json:"drawOdds"`           //数据库字段:draw_odds 开奖时赔率
-		WinLostStatus      string  `json:"winLostStatus"`      //数据库字段:win_lost_status 输赢状态：创建 Create,输 Lose，赢 Win,和 Tie
+		OrderPlanId        string      `json:"orderPlanId"`        //数据库字段:order_plan_id 订单计划id
+		OrderNo            string      `json:"orderNo"`            //数据库字段:order_no 订单号
+		WinAmount          money.Money `json:"winAmount"`          //数据库字段:win_amount 如果赢金额,投注完成时计算好,派奖使用
+		AvailableBetAmount money.Money `json:"availableBetAmount"` //数据库字段:available_bet_amount 有效投注金额
+		DrawOdds           float64     `json:"drawOdds"`           //数据库字段:draw_odds 开奖时赔率
+		WinLostStatus      string      `json:"winLostStatus"`      //数据库字段:win_lost_status 输赢状态：创建 Create,输 Lose，赢 Win,和 Tie
 	}
 
 	// OrderPlanIdSentStatus 更新平台中心注单状态信息
@@ -161,20 +162,20 @@ type (
 		   	Resettle //异常
 	*/
 	GameRoundDTO struct {
-		Id             string    `json:"id"`             //数据库字段:id id
-		GameCategoryId string    `json:"gameCategoryId"` //数据库字段:game_category_id 游戏分类id int64<->string
-		GameId         string    `json:"gameId"`         //数据库字段:game_id 游戏id
-		GameRoomId     string    `json:"gameRoomId"`     //数据库字段:game_room_id 游戏房间id
-		RoundNo        string    `json:"roundNo"`        //数据库字段:round_no 局号
-		Currency       string    `json:"currency"`       //数据库字段:currency 币种
-		UserLostAmount float64   `json:"userLostAmount"` //数据库字段:user_lost_amount 用户输金额
-		UserWinAmount  float64   `json:"userWinAmount"`  //数据库字段:user_win_amount 用户赢金额
-		BetTotal       float64   `json:"betTotal"`       //数据库字段:bet_total 总投注金额
-		Status         string    `json:"status"`         //数据库字段:status 状态:创建 Create,开始投注 Start，停止投注 Stop，开奖中 Doing， 已开奖 Done，取消 Cancel，重算 Resettle，异常 Exception
-		StartTime      time.Time `json:"startTime"`      //数据库字段:start_time 开始时间，默认当下时间
-		EndTime        time.Time `json:"endTime"`        //数据库字段:end_time 结束时间，无限期则设置99年后时间
-		CreateTime     time.Time `json:"createTime"`     //数据库字段:create_time 创建时间
-		Summary        string    `json:"summary"`        //数据库字段:summary 说明
+		Id             string      `json:"id"`             //数据库字段:id id
+		GameCategoryId string      `json:"gameCategoryId"` //数据库字段:game_category_id 游戏分类id int64<->string
+		GameId         string      `json:"gameId"`         //数据库字段:game_id 游戏id
+		GameRoomId     string      `json:"gameRoomId"`     //数据库字段:game_room_id 游戏房间id
+		RoundNo        string      `json:"roundNo"`        //数据库字段:round_no 局号
+		Currency       string      `json:"currency"`       //数据库字段:currency 币种
+		UserLostAmount money.Money `json:"userLostAmount"` //数据库字段:user_lost_amount 用户输金额
+		UserWinAmount  money.Money `json:"userWinAmount"`  //数据库字段:user_win_amount 用户赢金额
+		BetTotal       money.Money `json:"betTotal"`       //数据库字段:bet_total 总投注金额
+		Status         string      `json:"status"`         //数据库字段:status 状态:创建 Create,开始投注 Start，停止投注 Stop，开奖中 Doing， 已开奖 Done，取消 Cancel，重算 Resettle，异常 Exception
+		StartTime      time.Time   `json:"startTime"`      //数据库字段:start_time 开始时间，默认当下时间
+		EndTime        time.Time   `json:"endTime"`        //数据库字段:end_time 结束时间，无限期则设置99年后时间
+		CreateTime     time.Time   `json:"createTime"`     //数据库字段:create_time 创建时间
+		Summary        string      `json:"summary"`        //数据库字段:summary 说明
 	}
 	//游戏局结果数据对象
 	GameRoundResultDTO struct {
@@ -191,12 +192,18 @@ type (
 		GameRoundNo        string             `json:"gameRoundNo"`
 		GameRoundResultDTO GameRoundResultDTO `json:"gameRoundResultDTOs"`
 		OrderList          []int64            `json:"orderList"`
+		ShardNo            int                `json:"shardNo"` //开奖分片序号 同一局内唯一 用于结算幂等
+
+		/*以下字段只在重新结算时设置*/
+		Resettle          bool                `json:"resettle"`                    //是否为重新结算
+		ResettleId        string              `json:"resettleId,omitempty"`        //重新结算批次id 钱包按批次id和订单号对补差做幂等
+		PreviousResultDTO *GameRoundResultDTO `json:"previousResultDTO,omitempty"` //修正前的局结果 用于审计
 	}
 	//游戏结算数据对象
 	SettleDTO struct {
 		OrderNo            int64              `json:"orderNo"`            //order_no 订单号
-		WinAmount          float64            `json:"winAmount"`          //win_amount 如果赢金额,投注完成时计算好,派奖使用
-		AvailableBetAmount float64            `json:"availableBetAmount"` //available_bet_amount 有效投注金额
+		WinAmount          money.Money        `json:"winAmount"`          //win_amount 如果赢金额,投注完成时计算好,派奖使用
+		AvailableBetAmount money.Money        `json:"availableBetAmount"` //available_bet_amount 有效投注金额
 		DrawOdds           float32            `json:"drawOdds"`           //draw_odds 开奖时赔率
 		WinLostStatus      string             `json:"winLostStatus"`      //win_lost_status 输赢状态：创建 Create,输 Lose，赢 Win,和 Tie
 		GameRoundResult    GameRoundResultDTO `json:"gameRoundResult"`    //游戏局开奖通用结果
@@ -205,11 +212,11 @@ type (
 	}
 	//游戏结算小票
 	BetReceipt struct {
-		OrderNo       int64   `json:"orderNo"`
-		GameWagerId   int64   `json:"gameWagerId"`
-		WinAmount     float64 `json:"winAmount"`
-		DrawOdds      float32 `json:"drawOdds"`
-		WinLostStatus string  `json:"winLostStatus"`
+		OrderNo       int64       `json:"orderNo"`
+		GameWagerId   int64       `json:"gameWagerId"`
+		WinAmount     money.Money `json:"winAmount"`
+		DrawOdds      float32     `json:"drawOdds"`
+		WinLostStatus string      `json:"winLostStatus"`
 	}
 	//结算结果对象
 	GameDrawResultVO struct {
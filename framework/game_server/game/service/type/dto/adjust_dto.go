@@ -0,0 +1,28 @@
+package dto
+
+import "sl.framework.com/game_server/currency/money"
+
+// AdjustDTO 重新结算补差请求
+type AdjustDTO struct {
+	ResettleId  string            `json:"resettleId"`  //重新结算批次id 钱包按批次id和订单号做幂等
+	GameRoomId  int64             `json:"gameRoomId"`  //游戏房间id
+	GameRoundId int64             `json:"gameRoundId"` //游戏局id
+	GameRoundNo string            `json:"gameRoundNo"` //局号
+	Reason      string            `json:"reason"`      //补差原因
+	OrderList   []*AdjustOrderDTO `json:"orderList"`   //需要补差的注单
+}
+
+// AdjustOrderDTO 单个注单的补差金额
+type AdjustOrderDTO struct {
+	OrderNo  int64       `json:"orderNo"`  //订单号
+	UserId   int64       `json:"userId"`   //用户id
+	Currency string      `json:"currency"` //币种
+	Amount   money.Money `json:"amount"`   //补差金额 正数补发 负数扣回
+}
+
+// AdjustResultDTO 单个注单的补差结果
+type AdjustResultDTO struct {
+	OrderNo  string `json:"orderNo"`  //订单号
+	SerialNo string `json:"serialNo"` //补差流水号
+	Status   string `json:"status"`   //状态:成功 Success,失败 Failed
+}
@@ -0,0 +1,11 @@
+package dto
+
+// PrincipalDTO 经过token校验的玩家身份 由filter.PlayerAuthFilter放入请求上下文
+type PrincipalDTO struct {
+	UserId     int64  //用户Id
+	UserName   string //用户名
+	UserType   string //用户类型
+	Currency   string //币种 token中未携带时为空
+	GameRoomId int64  //token限定的游戏房间 为0时不限定
+	SessionId  string //会话Id
+}
@@ -0,0 +1,17 @@
+package dto
+
+// RefundDTO 取消局退款请求
+type RefundDTO struct {
+	GameRoomId  int64   `json:"gameRoomId"`  //游戏房间id
+	GameRoundId int64   `json:"gameRoundId"` //游戏局id
+	GameRoundNo string  `json:"gameRoundNo"` //局号
+	Reason      string  `json:"reason"`      //退款原因
+	OrderNoList []int64 `json:"orderNoList"` //需要退款的订单号集合
+}
+
+// RefundResultDTO 单个注单的退款结果
+type RefundResultDTO struct {
+	OrderNo  string `json:"orderNo"`  //订单号
+	SerialNo string `json:"serialNo"` //退款流水号
+	Status   string `json:"status"`   //状态:成功 Success,失败 Failed
+}
@@ -0,0 +1,51 @@
+package types
+
+import "time"
+
+type (
+	/*
+		DynamicOddsSeedDTO 动态赔率服务端种子
+		上一局Bet_Start时预先生成下一局的种子并公布种子的sha256 Game_End或者Cancel_Round时公开种子
+		玩家和监管可以据此复算每个玩法的随机结果 种子保存在game db redis只做缓存
+	*/
+	DynamicOddsSeedDTO struct {
+		GameRoomId  int64     `json:"gameRoomId"`           //游戏房间id
+		GameRoundId int64     `json:"gameRoundId"`          //游戏局id
+		SeedHash    string    `json:"seedHash"`             //种子的sha256 hex Bet_Start时公布
+		ServerSeed  string    `json:"serverSeed,omitempty"` //服务端种子 hex Game_End之前不能对外公开
+		Revealed    bool      `json:"revealed"`             //种子是否已经公开
+		CommitTime  time.Time `json:"commitTime"`           //种子生成时间
+		RevealTime  time.Time `json:"revealTime"`           //种子公开时间
+	}
+
+	/*
+		DynamicOddsAuditDTO 动态赔率审计记录
+		每局每个动态赔率玩法一条 记录参与随机的权重以及随机值 权重均为万分比
+	*/
+	DynamicOddsAuditDTO struct {
+		TraceId       string                 `json:"traceId"`       //跟踪id
+		GameId        int64                  `json:"gameId"`        //游戏id
+		GameRoomId    int64                  `json:"gameRoomId"`    //游戏房间id
+		GameRoundId   int64                  `json:"gameRoundId"`   //游戏局id
+		WagerId       int64                  `json:"wagerId"`       //玩法id
+		SeedHash      string                 `json:"seedHash"`      //本局种子的sha256
+		ClientSeed    string                 `json:"clientSeed"`    //参与随机的外部种子 本局Bet_Start事件的请求id 种子承诺之后才产生
+		OriginalOdds  float32                `json:"originalOdds"`  //玩法默认赔率
+		TriggerWeight int                    `json:"triggerWeight"` //触发动态赔率的权重 随机值小于该值时触发
+		TriggerRoll   int                    `json:"triggerRoll"`   //触发随机值 [0,10000)
+		Triggered     bool                   `json:"triggered"`     //是否触发动态赔率
+		Weights       []DynamicOddsWeightDTO `json:"weights"`       //各个动态赔率的权重区间
+		OddsRange     int                    `json:"oddsRange"`     //抽取赔率的随机范围 未触发时为0
+		OddsRoll      int                    `json:"oddsRoll"`      //抽取赔率的随机值 [0,oddsRange)
+		Odds          float32                `json:"odds"`          //最终赔率 没有命中任何区间时为默认赔率
+		CreateTime    time.Time              `json:"createTime"`    //记录时间
+	}
+
+	// DynamicOddsWeightDTO 单个动态赔率的权重区间 [MinWeight,MaxWeight)
+	DynamicOddsWeightDTO struct {
+		OddsId    int64   `json:"oddsId"`    //动态赔率id
+		Odds      float32 `json:"odds"`      //赔率
+		MinWeight int     `json:"minWeight"` //区间起点 包含
+		MaxWeight int     `json:"maxWeight"` //区间终点 不包含
+	}
+)
@@ -1,6 +1,9 @@
 package types
 
-import "sl.framework.com/trace"
+import (
+	"context"
+	"sl.framework.com/trace"
+)
 
 type EventDTO struct {
 	GameRoomId      int64 // 房间ID
@@ -16,10 +19,16 @@ type EventDTO struct {
 type EventBase struct {
 	Dto            *EventDTO
 	RoundDTO       *GameRoundDTO
-	TraceId        string // traceId 跟踪流程
-	RequestId      string //请求id
-	RetHandleEvent *int   // 消息处理返回值
-	MsgHeader      string //具体类对应的消息 用于打印日志
+	TraceId        string          // traceId 跟踪流程
+	RequestId      string          //请求id
+	RetHandleEvent *int            // 消息处理返回值
+	MsgHeader      string          //具体类对应的消息 用于打印日志
+	Ctx            context.Context //日志上下文 携带traceId roomId roundId
+}
+
+// Log 带有事件traceId roomId roundId字段的日志对象
+func (e *EventBase) Log() *trace.Entry {
+	return trace.FromContext(e.Ctx)
 }
 
 /**
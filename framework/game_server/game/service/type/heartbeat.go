@@ -1,8 +0,0 @@
-package types
-
-import "time"
-
-type HeartbeatStatus struct {
-	ServerId   string
-	UpdateTime time.Time
-}
@@ -7,6 +7,11 @@ type GameDrawMessage struct {
 	GameRoundNo        string             `json:"gameRoundNo"`
 	GameRoundResultDTO GameRoundResultDTO `json:"gameRoundResultDTOs"`
 	OrderList          []int64            `json:"orderList"`
+	ShardNo            int                `json:"shardNo"` //开奖分片序号
+
+	Resettle          bool                `json:"resettle"`                    //是否为重新结算
+	ResettleId        string              `json:"resettleId,omitempty"`        //重新结算批次id
+	PreviousResultDTO *GameRoundResultDTO `json:"previousResultDTO,omitempty"` //修正前的局结果
 }
 type MQMESSAGE_TOPICS string
 
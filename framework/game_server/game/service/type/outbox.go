@@ -0,0 +1,49 @@
+package types
+
+import "time"
+
+// 发件箱消息表名
+const tableNameOutboxMessage = "outbox_message"
+
+// TableName 结构对应的表名
+func (m *OutboxMessage) TableName() string {
+	return tableNameOutboxMessage
+}
+
+// OutboxKind 发件箱消息类型 决定后台任务使用哪个接口发送
+type OutboxKind string
+
+const (
+	OutboxKindSettle      OutboxKind = "settle"      //注单派彩
+	OutboxKindReceipt     OutboxKind = "receipt"     //结算小票
+	OutboxKindGameMessage OutboxKind = "gameMessage" //局消息
+)
+
+// OutboxStatus 发件箱消息状态
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending" //等待发送或者等待重试
+	OutboxStatusSuccess OutboxStatus = "success" //发送成功
+	OutboxStatusFailed  OutboxStatus = "failed"  //超过最大重试次数 需要人工处理
+)
+
+type (
+	/*
+		OutboxMessage 发件箱消息
+		外发请求先落库再发送 发送失败由后台任务按退避间隔重试 服务重启后继续重试
+	*/
+	OutboxMessage struct {
+		Id            int64     `json:"id" orm:"pk;column(id)"`                                     //数据库字段:id 雪花id
+		TraceId       string    `json:"traceId" orm:"size(64);column(trace_id)"`                    //数据库字段:trace_id 跟踪id
+		Kind          string    `json:"kind" orm:"size(32);column(kind)"`                           //数据库字段:kind 消息类型
+		BizKey        string    `json:"bizKey" orm:"size(128);unique;column(biz_key)"`              //数据库字段:biz_key 业务唯一键 相同的键只发送一次
+		Payload       string    `json:"payload" orm:"type(text);column(payload)"`                   //数据库字段:payload 请求内容 json格式
+		Status        string    `json:"status" orm:"size(16);column(status)"`                       //数据库字段:status 状态 pending success failed
+		RetryCount    int       `json:"retryCount" orm:"default(0);column(retry_count)"`            //数据库字段:retry_count 已重试次数
+		LastCode      int       `json:"lastCode" orm:"default(0);column(last_code)"`                //数据库字段:last_code 最后一次发送的返回码
+		NextRetryTime time.Time `json:"nextRetryTime" orm:"type(datetime);column(next_retry_time)"` //数据库字段:next_retry_time 下次发送时间
+		CreateTime    time.Time `json:"createTime" orm:"type(datetime);column(create_time)"`        //数据库字段:create_time 创建时间
+		UpdateTime    time.Time `json:"updateTime" orm:"type(datetime);column(update_time)"`        //数据库字段:update_time 更新时间
+	}
+)
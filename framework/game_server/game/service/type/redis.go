@@ -1,5 +1,7 @@
 package types
 
+import "sl.framework.com/game_server/currency/money"
+
 /*Redis相关的Models*/
 
 type RoomCardStatOp string
@@ -13,8 +15,8 @@ type (
 	// LimitInfo 限红信息结构 个人限红和房间限红均可使用该结构
 	LimitInfo struct {
 		Currency  string
-		MinAmount float64
-		MaxAmount float64
+		MinAmount money.Money
+		MaxAmount money.Money
 	}
 
 	// OddInfo 房间赔率信息
@@ -28,11 +30,6 @@ type (
 		Currency string
 	}
 
-	// PlayerInRoom 玩家进入房间 离开房间的时候使用
-	PlayerInRoom struct {
-		PlayerInfoSet []PlayerInfo
-	}
-
 	// RoomCardStat 统计当前房间当前靴下牌的数量
 	RoomCardStat struct {
 		GameRoomId  int64  //房间号
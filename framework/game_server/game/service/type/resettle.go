@@ -0,0 +1,37 @@
+package types
+
+import (
+	"sl.framework.com/game_server/currency/money"
+	"time"
+)
+
+type (
+	/*
+		ResettleAuditDTO 重新结算审计记录
+		记录修正前后的局结果以及每个注单的派彩差额 每个结算分片产生一条记录
+	*/
+	ResettleAuditDTO struct {
+		TraceId        string              `json:"traceId"`        //跟踪id
+		ResettleId     string              `json:"resettleId"`     //重新结算批次id
+		GameRoomId     int64               `json:"gameRoomId"`     //游戏房间id
+		GameRoundId    int64               `json:"gameRoundId"`    //游戏局id
+		GameRoundNo    string              `json:"gameRoundNo"`    //局号
+		PreviousResult *GameRoundResultDTO `json:"previousResult"` //修正前的局结果
+		CurrentResult  *GameRoundResultDTO `json:"currentResult"`  //修正后的局结果
+		Orders         []*ResettleOrderDTO `json:"orders"`         //注单差额
+		CreateTime     time.Time           `json:"createTime"`     //记录时间
+	}
+
+	// ResettleOrderDTO 单个注单重新结算前后的差额
+	ResettleOrderDTO struct {
+		OrderNo               int64       `json:"orderNo"`               //订单号
+		UserId                int64       `json:"userId"`                //用户id
+		Currency              string      `json:"currency"`              //币种
+		PreviousWinAmount     money.Money `json:"previousWinAmount"`     //修正前派彩金额
+		WinAmount             money.Money `json:"winAmount"`             //修正后派彩金额
+		PreviousWinLostStatus string      `json:"previousWinLostStatus"` //修正前输赢状态
+		WinLostStatus         string      `json:"winLostStatus"`         //修正后输赢状态
+		AdjustAmount          money.Money `json:"adjustAmount"`          //补差金额 正数补发 负数扣回
+		AdjustStatus          string      `json:"adjustStatus"`          //补差状态:无需补差 None,成功 Success,失败 Failed,等待重试 Retry
+	}
+)
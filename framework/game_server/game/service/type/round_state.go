@@ -0,0 +1,29 @@
+package types
+
+import "time"
+
+// RoundState 房间当前局的状态
+type RoundState string
+
+const (
+	RoundStateIdle       RoundState = "Idle"       //房间还没有收到过局事件
+	RoundStateBetting    RoundState = "Betting"    //Bet_Start后 投注中
+	RoundStateBetStopped RoundState = "BetStopped" //Bet_Stop后 停止投注
+	RoundStateDealing    RoundState = "Dealing"    //Game_Data后 发牌中 可以多次收到Game_Data
+	RoundStateDrawn      RoundState = "Drawn"      //Game_Draw后 已开奖
+	RoundStateEnded      RoundState = "Ended"      //Game_End后 本局结束
+	RoundStateCancelled  RoundState = "Cancelled"  //Cancel_Round后 本局取消
+)
+
+// RoundStateDTO 房间局状态 每个房间一条 保存在redis中
+type RoundStateDTO struct {
+	GameRoomId  int64      `json:"gameRoomId"`        //游戏房间id
+	GameRoundNo string     `json:"gameRoundNo"`       //当前局号
+	State       RoundState `json:"state"`             //当前局状态
+	Command     string     `json:"command"`           //最近一次接受的事件
+	RequestId   string     `json:"requestId"`         //最近一次接受事件的请求id
+	LastRoundNo string     `json:"lastRoundNo"`       //上一局局号 上一局迟到的事件直接拒绝
+	Missed      []string   `json:"missed,omitempty"`  //最近一次迁移时补偿跳过的事件
+	Pending     bool       `json:"pending,omitempty"` //最近一次接受的事件还没有处理成功 同一局同一事件可以重新进入
+	UpdateTime  time.Time  `json:"updateTime"`        //最近一次迁移时间
+}
@@ -0,0 +1,14 @@
+package types
+
+type (
+	/*
+		SettleLedgerEntry 结算台账记录
+		每个注单派彩到平台中心成功后记录一条 开奖分片消息重复投递时据此跳过已经派彩的注单
+	*/
+	SettleLedgerEntry struct {
+		OrderNo    int64  `json:"orderNo"`    //注单号
+		ShardNo    int    `json:"shardNo"`    //注单所在的开奖分片序号
+		TraceId    string `json:"traceId"`    //派彩时的跟踪id
+		SettleTime int64  `json:"settleTime"` //派彩成功时间 毫秒时间戳
+	}
+)
@@ -0,0 +1,92 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/lifecycle"
+	"sync"
+	"time"
+)
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+)
+
+// getDefault 服务使用的健康检查注册表 第一次使用时按配置创建 readiness同时受生命周期管理控制
+func getDefault() *Registry {
+	defaultOnce.Do(func() {
+		cfg := conf.GetHealth()
+		defaultRegistry = NewRegistry(lifecycle.Ready, time.Duration(cfg.CacheTtl)*time.Millisecond,
+			time.Duration(cfg.Timeout)*time.Millisecond)
+	})
+
+	return defaultRegistry
+}
+
+// RegisterReadiness 注册readiness检查项
+func RegisterReadiness(check Check) {
+	getDefault().RegisterReadiness(check)
+}
+
+// RegisterLiveness 注册liveness检查项
+func RegisterLiveness(check Check) {
+	getDefault().RegisterLiveness(check)
+}
+
+// Readiness 检查是否可以接收流量
+func Readiness() Report {
+	return getDefault().Readiness()
+}
+
+// Liveness 检查进程是否存活
+func Liveness() Report {
+	return getDefault().Liveness()
+}
+
+// Detail readiness和liveness的详细结果 供运维查询
+func Detail() map[string]Report {
+	return map[string]Report{"readiness": Readiness(), "liveness": Liveness()}
+}
+
+/**
+ * ProbeHttp
+ * 生成http地址的检查函数 能收到响应即视为可达 5xx视为不可用
+ *
+ * @param url func() string - 获取检查地址 每次检查时调用 配置变化后使用新的地址
+ * @return func(ctx context.Context) error - 检查函数
+ */
+
+func ProbeHttp(url func() string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url(), nil)
+		if err != nil {
+			return err
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = rsp.Body.Close()
+		if rsp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("http status %v", rsp.StatusCode)
+		}
+		return nil
+	}
+}
+
+/**
+ * ProbeAlive
+ * 生成定时任务的liveness检查函数
+ *
+ * @param alive func(now time.Time) error - 定时任务的存活检查 如base.CLoopTask.Alive
+ * @return func(ctx context.Context) error - 检查函数
+ */
+
+func ProbeAlive(alive func(now time.Time) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return alive(time.Now())
+	}
+}
@@ -0,0 +1,192 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+/*
+	健康检查
+	readiness检查依赖 关键依赖失败时未就绪 负载均衡不再转发流量 非关键依赖失败只在详情中展示
+	liveness检查进程内部状态 如定时任务卡死 失败时k8s重启pod 不能检查外部依赖 避免依赖故障时所有pod被重启
+	检查结果按cacheTtl缓存 同一个检查同时只有一个请求在执行 其他请求等待结果
+*/
+
+// 健康状态 与spring boot actuator保持一致
+const (
+	StatusUp           = "UP"
+	StatusDown         = "DOWN"
+	StatusOutOfService = "OUT_OF_SERVICE" //服务启动完成之前或者开始关闭之后
+)
+
+type (
+	// Check 健康检查项
+	Check struct {
+		Name     string                          //名称
+		Critical bool                            //readiness中为关键依赖 失败时未就绪 liveness中全部为关键检查
+		Timeout  time.Duration                   //超时时间 0使用默认超时时间
+		Probe    func(ctx context.Context) error //检查函数 返回nil表示健康
+	}
+
+	// Component 一个检查项的结果
+	Component struct {
+		Status    string `json:"status"`
+		Critical  bool   `json:"critical"`
+		Error     string `json:"error,omitempty"`
+		Elapse    int64  `json:"elapse"`    //检查耗时 单位ms
+		CheckedAt int64  `json:"checkedAt"` //检查时间 unix ms
+	}
+
+	// Report 健康检查结果
+	Report struct {
+		Status     string               `json:"status"`
+		Components map[string]Component `json:"components,omitempty"`
+	}
+
+	// entry 检查项和缓存的结果
+	entry struct {
+		check     Check
+		mutex     sync.Mutex
+		result    Component
+		checkedAt time.Time
+	}
+)
+
+// Registry 健康检查注册表
+type Registry struct {
+	mutex     sync.RWMutex
+	readiness []*entry
+	liveness  []*entry
+	ready     func() bool //服务是否完成启动并且没有开始关闭
+	cacheTtl  time.Duration
+	timeout   time.Duration
+}
+
+/**
+ * NewRegistry
+ * 创建健康检查注册表
+ *
+ * @param ready func() bool - 服务是否可以接收流量 为nil时视为可以
+ * @param cacheTtl time.Duration - 检查结果的缓存时间
+ * @param timeout time.Duration - 检查项默认超时时间
+ * @return *Registry - 健康检查注册表
+ */
+
+func NewRegistry(ready func() bool, cacheTtl, timeout time.Duration) *Registry {
+	if ready == nil {
+		ready = func() bool { return true }
+	}
+	return &Registry{ready: ready, cacheTtl: cacheTtl, timeout: timeout}
+}
+
+// RegisterReadiness 注册readiness检查项
+func (r *Registry) RegisterReadiness(check Check) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.readiness = append(r.readiness, &entry{check: check})
+}
+
+// RegisterLiveness 注册liveness检查项 全部视为关键检查
+func (r *Registry) RegisterLiveness(check Check) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	check.Critical = true
+	r.liveness = append(r.liveness, &entry{check: check})
+}
+
+/**
+ * Readiness
+ * 检查是否可以接收流量 启动完成之前和开始关闭之后为OUT_OF_SERVICE 关键依赖失败时为DOWN
+ *
+ * @return Report - 检查结果
+ */
+
+func (r *Registry) Readiness() Report {
+	r.mutex.RLock()
+	entries := r.readiness
+	r.mutex.RUnlock()
+
+	report := r.run(entries)
+	if !r.ready() {
+		report.Status = StatusOutOfService
+	}
+	return report
+}
+
+// Liveness 检查进程是否存活 有检查项失败时为DOWN
+func (r *Registry) Liveness() Report {
+	r.mutex.RLock()
+	entries := r.liveness
+	r.mutex.RUnlock()
+
+	return r.run(entries)
+}
+
+// run 并发执行检查项 汇总结果
+func (r *Registry) run(entries []*entry) Report {
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(entries))}
+	results := make([]Component, len(entries))
+	wg := sync.WaitGroup{}
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			results[i] = r.probe(e, time.Now())
+		}(i, e)
+	}
+	wg.Wait()
+
+	for i, e := range entries {
+		report.Components[e.check.Name] = results[i]
+		if results[i].Critical && results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// probe 执行一个检查项 缓存没有过期时直接返回缓存的结果
+func (r *Registry) probe(e *entry, now time.Time) Component {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.checkedAt.IsZero() && now.Sub(e.checkedAt) < r.cacheTtl {
+		return e.result
+	}
+
+	timeout := e.check.Timeout
+	if timeout <= 0 {
+		timeout = r.timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	//检查函数不响应ctx时也按超时处理
+	errChan := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				errChan <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		errChan <- e.check.Probe(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout after %v", timeout)
+	}
+
+	e.result = Component{Status: StatusUp, Critical: e.check.Critical, Elapse: time.Since(now).Milliseconds(), CheckedAt: now.UnixMilli()}
+	if err != nil {
+		e.result.Status = StatusDown
+		e.result.Error = err.Error()
+	}
+	e.checkedAt = now
+	return e.result
+}
@@ -0,0 +1,111 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	ready := false
+	r := NewRegistry(func() bool { return ready }, 0, time.Second)
+	var redisErr, nacosErr error
+	r.RegisterReadiness(Check{Name: "redis", Critical: true, Probe: func(context.Context) error { return redisErr }})
+	r.RegisterReadiness(Check{Name: "nacos", Probe: func(context.Context) error { return nacosErr }})
+
+	//启动完成之前不接收流量
+	if report := r.Readiness(); report.Status != StatusOutOfService {
+		t.Fatalf("Readiness() before ready = %v, want %v", report.Status, StatusOutOfService)
+	}
+
+	ready = true
+	if report := r.Readiness(); report.Status != StatusUp || len(report.Components) != 2 {
+		t.Fatalf("Readiness() = %+v, want UP with 2 components", report)
+	}
+
+	//非关键依赖失败只在详情中展示
+	nacosErr = errors.New("connection refused")
+	report := r.Readiness()
+	if report.Status != StatusUp || report.Components["nacos"].Status != StatusDown ||
+		report.Components["nacos"].Error != "connection refused" {
+		t.Fatalf("Readiness() with nacos down = %+v", report)
+	}
+
+	//关键依赖失败时未就绪
+	redisErr = errors.New("redis down")
+	if report = r.Readiness(); report.Status != StatusDown || !report.Components["redis"].Critical {
+		t.Fatalf("Readiness() with redis down = %+v", report)
+	}
+}
+
+func TestProbeCacheAndTimeout(t *testing.T) {
+	r := NewRegistry(nil, time.Hour, 20*time.Millisecond)
+	var calls int32
+	r.RegisterReadiness(Check{Name: "db", Critical: true, Probe: func(context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}})
+	block := make(chan struct{})
+	defer close(block)
+	r.RegisterReadiness(Check{Name: "stuck", Probe: func(context.Context) error {
+		<-block //不响应ctx的检查也按超时处理
+		return nil
+	}})
+
+	start := time.Now()
+	report := r.Readiness()
+	if elapse := time.Since(start); elapse > 500*time.Millisecond {
+		t.Fatalf("Readiness() elapse = %v, want timeout respected", elapse)
+	}
+	if report.Status != StatusUp || !strings.Contains(report.Components["stuck"].Error, "timeout") {
+		t.Fatalf("Readiness() = %+v, want stuck timeout", report)
+	}
+
+	//缓存没有过期时不再执行检查
+	for i := 0; i < 5; i++ {
+		r.Readiness()
+	}
+	if calls != 1 {
+		t.Fatalf("probe calls = %v, want 1", calls)
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	r := NewRegistry(func() bool { return false }, 0, time.Second)
+	var stuck error
+	r.RegisterLiveness(Check{Name: "heartbeat", Probe: ProbeAlive(func(time.Time) error { return stuck })})
+
+	//liveness不受readiness影响
+	if report := r.Liveness(); report.Status != StatusUp {
+		t.Fatalf("Liveness() = %v, want UP", report.Status)
+	}
+	stuck = errors.New("loop task not run")
+	if report := r.Liveness(); report.Status != StatusDown || !report.Components["heartbeat"].Critical {
+		t.Fatalf("Liveness() = %+v, want DOWN", report)
+	}
+}
+
+func TestProbeHttp(t *testing.T) {
+	code := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	probe := ProbeHttp(func() string { return server.URL })
+	if err := probe(context.Background()); err != nil {
+		t.Fatalf("probe() with 404 error = %v, want nil", err)
+	}
+	code = http.StatusBadGateway
+	if err := probe(context.Background()); err == nil {
+		t.Fatal("probe() with 502 error = nil")
+	}
+	if err := ProbeHttp(func() string { return "http://127.0.0.1:1" })(context.Background()); err == nil {
+		t.Fatal("probe() unreachable error = nil")
+	}
+}
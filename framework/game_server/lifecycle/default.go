@@ -0,0 +1,42 @@
+package lifecycle
+
+import (
+	"sl.framework.com/game_server/conf"
+	"sync"
+	"time"
+)
+
+var (
+	defaultOnce    sync.Once
+	defaultManager *Manager
+)
+
+// getDefault 服务使用的生命周期管理 第一次使用时按配置创建
+func getDefault() *Manager {
+	defaultOnce.Do(func() {
+		cfg := conf.GetShutdown()
+		defaultManager = NewManager(time.Duration(cfg.ReadinessDelay)*time.Second, time.Duration(cfg.Timeout)*time.Second)
+	})
+
+	return defaultManager
+}
+
+// Register 注册关闭钩子
+func Register(hook Hook) {
+	getDefault().Register(hook)
+}
+
+// SetReady 启动完成后调用 readiness置为就绪
+func SetReady() {
+	getDefault().SetReady()
+}
+
+// Ready 是否可以接收流量 启动完成之前和开始关闭之后返回false
+func Ready() bool {
+	return getDefault().Ready()
+}
+
+// Shutdown readiness置为未就绪后按顺序执行所有关闭钩子
+func Shutdown() []Result {
+	return getDefault().Shutdown()
+}
@@ -0,0 +1,150 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sl.framework.com/trace"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	服务生命周期管理
+	各子系统启动后注册关闭钩子 关闭时:
+		1.readiness置为未就绪 等待负载均衡摘除流量
+		2.按Order从小到大依次执行关闭钩子 Order相同时按注册顺序执行
+		3.每个钩子有自己的超时时间 超时或者失败只记录日志 继续执行后面的钩子
+*/
+
+// 关闭顺序 越小越先执行
+const (
+	OrderHeartbeat = 100 //退出集群 其他节点不再把房间分配给本节点
+	OrderHTTP      = 200 //停止接收下注请求 等待处理中的请求完成
+	OrderMQ        = 300 //停止拉取开奖和提交注单消息 等待处理中的消息完成
+	OrderLoopTask  = 400 //停止定时任务
+	OrderExecutor  = 500 //等待协程池中的入库 小票 局消息任务完成
+	OrderOutbox    = 600 //停止发件箱后台任务
+	OrderLease     = 800 //释放节点id租约后停止心跳 需要在断开redis之前
+	OrderRedis     = 900 //断开redis 最后执行
+)
+
+// Hook 关闭钩子
+type Hook struct {
+	Name    string                          //名称 用于日志
+	Order   int                             //关闭顺序
+	Timeout time.Duration                   //超时时间 0使用默认超时时间
+	Stop    func(ctx context.Context) error //关闭函数 需要在ctx超时后尽快返回
+}
+
+// Result 关闭钩子的执行结果
+type Result struct {
+	Name   string
+	Elapse time.Duration
+	Err    error
+}
+
+// Manager 生命周期管理
+type Manager struct {
+	mutex          sync.Mutex
+	hooks          []Hook
+	ready          atomic.Bool
+	readinessDelay time.Duration //readiness置为未就绪后等待的时间
+	timeout        time.Duration //钩子默认超时时间
+}
+
+/**
+ * NewManager
+ * 创建生命周期管理
+ *
+ * @param readinessDelay time.Duration - readiness置为未就绪后等待负载均衡摘除流量的时间
+ * @param timeout time.Duration - 钩子默认超时时间
+ * @return *Manager - 生命周期管理
+ */
+
+func NewManager(readinessDelay, timeout time.Duration) *Manager {
+	return &Manager{readinessDelay: readinessDelay, timeout: timeout}
+}
+
+// Register 注册关闭钩子
+func (m *Manager) Register(hook Hook) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.hooks = append(m.hooks, hook)
+}
+
+// SetReady 启动完成后调用 readiness置为就绪
+func (m *Manager) SetReady() {
+	m.ready.Store(true)
+}
+
+// Ready 是否可以接收流量
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+/**
+ * Shutdown
+ * readiness置为未就绪后按顺序执行所有关闭钩子 只执行一次
+ *
+ * @return []Result - 每个钩子的执行结果 按执行顺序
+ */
+
+func (m *Manager) Shutdown() []Result {
+	start := time.Now()
+	m.ready.Store(false)
+	trace.Notice("lifecycle Shutdown readiness set to not ready, wait %v for traffic draining", m.readinessDelay)
+	time.Sleep(m.readinessDelay)
+
+	m.mutex.Lock()
+	hooks := m.hooks
+	m.hooks = nil
+	m.mutex.Unlock()
+	sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].Order < hooks[j].Order })
+
+	results := make([]Result, 0, len(hooks))
+	for _, hook := range hooks {
+		results = append(results, m.runHook(hook))
+	}
+	trace.Notice("lifecycle Shutdown done, hooks=%v, elapse=%v", len(results), time.Since(start))
+	return results
+}
+
+// runHook 在超时时间内执行一个关闭钩子 钩子panic时视为失败
+func (m *Manager) runHook(hook Hook) Result {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = m.timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	trace.Info("lifecycle stop hook=%v, order=%v, timeout=%v start", hook.Name, hook.Order, timeout)
+	start := time.Now()
+	errChan := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errChan <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errChan <- hook.Stop(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Name: hook.Name, Elapse: time.Since(start), Err: err}
+	if err != nil {
+		trace.Error("lifecycle stop hook=%v failed, elapse=%v, error=%v", hook.Name, result.Elapse, err.Error())
+	} else {
+		trace.Info("lifecycle stop hook=%v done, elapse=%v", hook.Name, result.Elapse)
+	}
+	return result
+}
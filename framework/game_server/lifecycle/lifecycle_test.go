@@ -0,0 +1,82 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownOrder(t *testing.T) {
+	m := NewManager(0, 50*time.Millisecond)
+	m.SetReady()
+
+	var stopped []string
+	hook := func(name string, order int, err error) Hook {
+		return Hook{Name: name, Order: order, Stop: func(context.Context) error {
+			//关闭钩子执行时readiness已经是未就绪
+			if m.Ready() {
+				t.Errorf("hook %v run while ready", name)
+			}
+			stopped = append(stopped, name)
+			return err
+		}}
+	}
+	m.Register(hook("redis", OrderRedis, nil))
+	m.Register(hook("mq", OrderMQ, errors.New("mq failed")))
+	m.Register(hook("http", OrderHTTP, nil))
+	m.Register(hook("cache", OrderLoopTask, nil))
+	m.Register(hook("presence", OrderLoopTask, nil))
+
+	results := m.Shutdown()
+	want := []string{"http", "mq", "cache", "presence", "redis"}
+	if len(stopped) != len(want) {
+		t.Fatalf("stopped = %v, want %v", stopped, want)
+	}
+	for i := range want {
+		if stopped[i] != want[i] || results[i].Name != want[i] {
+			t.Fatalf("stopped = %v, results[%v] = %v, want %v", stopped, i, results[i].Name, want)
+		}
+	}
+	if results[1].Err == nil {
+		t.Fatalf("mq result error = nil, want error")
+	}
+	//只执行一次
+	if again := m.Shutdown(); len(again) != 0 {
+		t.Fatalf("second Shutdown() results = %v, want empty", again)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	m := NewManager(0, time.Second)
+
+	var ran bool
+	block := make(chan struct{})
+	defer close(block)
+	m.Register(Hook{Name: "stuck", Order: OrderMQ, Timeout: 20 * time.Millisecond, Stop: func(context.Context) error {
+		<-block //忽略ctx的钩子不会阻塞后面的钩子
+		return nil
+	}})
+	m.Register(Hook{Name: "panic", Order: OrderExecutor, Stop: func(context.Context) error {
+		panic("stop panic")
+	}})
+	m.Register(Hook{Name: "redis", Order: OrderRedis, Stop: func(context.Context) error {
+		ran = true
+		return nil
+	}})
+
+	start := time.Now()
+	results := m.Shutdown()
+	if elapse := time.Since(start); elapse > 500*time.Millisecond {
+		t.Fatalf("Shutdown() elapse = %v, want deadline respected", elapse)
+	}
+	if !errors.Is(results[0].Err, context.DeadlineExceeded) {
+		t.Fatalf("stuck result error = %v, want DeadlineExceeded", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Fatalf("panic result error = nil, want error")
+	}
+	if !ran || results[2].Err != nil {
+		t.Fatalf("redis hook ran = %v, error = %v", ran, results[2].Err)
+	}
+}
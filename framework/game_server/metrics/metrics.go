@@ -0,0 +1,274 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	metrics 游戏服prometheus监控指标
+	所有指标注册到prometheus默认Registry 由健康检查端口的/actuator/prometheus导出
+	label取值必须是有限集合 不能包含traceId 房间id 局id等业务id 否则会导致时序数量无限增长
+*/
+
+const (
+	namespace = "game_server"
+
+	maxWatcherNames = 256     //Watcher名称label的最大数量 超出后统一记为otherLabel
+	otherLabel      = "other" //超出数量限制或者无法识别的label
+)
+
+// SettleDuplicate 重复结算的粒度
+type SettleDuplicate string
+
+const (
+	SettleDuplicateOrder SettleDuplicate = "order" //分片中已经派彩的注单
+	SettleDuplicateShard SettleDuplicate = "shard" //分片中全部注单都已经派彩
+	SettleDuplicateBusy  SettleDuplicate = "busy"  //分片正在被其他消费者结算
+)
+
+// LockResult redis锁获取结果
+type LockResult string
+
+const (
+	LockAcquired  LockResult = "acquired"  //加锁成功
+	LockContended LockResult = "contended" //锁已被占用
+	LockError     LockResult = "error"     //redis错误
+)
+
+var (
+	betDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "bet_duration_seconds",
+		Help:      "ServiceBet latency by result code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"code"})
+
+	gameEventTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "game_event_total",
+		Help:      "Game events dispatched by command and result code.",
+	}, []string{"command", "code"})
+
+	mqConsumeTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_consume_total",
+		Help:      "RocketMQ messages consumed by topic and result code.",
+	}, []string{"topic", "code"})
+
+	mqConsumeLag = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mq_consume_lag_seconds",
+		Help:      "Delay between a RocketMQ message being produced and consumed.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"topic"})
+
+	mqProduceTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_produce_total",
+		Help:      "RocketMQ messages produced by topic and result.",
+	}, []string{"topic", "result"})
+
+	mqProduceQueueLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mq_produce_queue_length",
+		Help:      "Messages waiting in the producer queue by topic.",
+	}, []string{"topic"})
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Platform RPC latency by endpoint and result code, retries included.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "code"})
+
+	outboxDispatchTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_dispatch_total",
+		Help:      "Outbox delivery attempts by message kind and result code.",
+	}, []string{"kind", "code"})
+
+	redisLockTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_lock_total",
+		Help:      "Redis lock attempts by lock name and result.",
+	}, []string{"lock", "result"})
+
+	settleDuplicateTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "settle_duplicate_total",
+		Help:      "Draw shard redeliveries skipped by the settlement ledger, by kind.",
+	}, []string{"kind"})
+
+	executorTaskTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "executor_task_total",
+		Help:      "Executor tasks by pool and result (done, panic, rejected, caller_runs).",
+	}, []string{"pool", "result"})
+
+	executorQueueLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "executor_queue_length",
+		Help:      "Tasks waiting in the executor queue by pool.",
+	}, []string{"pool"})
+
+	executorActiveWorkers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "executor_active_workers",
+		Help:      "Tasks currently running by pool, including caller-runs tasks.",
+	}, []string{"pool"})
+
+	watcherDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "watcher_duration_seconds",
+		Help:      "tool.Watcher stage timings by stage name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"name"})
+
+	watcherNames     = make(map[string]struct{}) //已经出现过的Watcher名称
+	watcherNamesLock sync.Mutex
+)
+
+func init() {
+	prometheus.MustRegister(betDuration, gameEventTotal, mqConsumeTotal, mqConsumeLag, mqProduceTotal,
+		mqProduceQueueLength, rpcDuration, outboxDispatchTotal, redisLockTotal, settleDuplicateTotal, executorTaskTotal, executorQueueLength,
+		executorActiveWorkers, watcherDuration)
+}
+
+// Handler prometheus指标导出的http handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveBet 记录一次下注耗时
+func ObserveBet(code int, elapse time.Duration) {
+	betDuration.WithLabelValues(strconv.Itoa(code)).Observe(elapse.Seconds())
+}
+
+// IncGameEvent 记录一次游戏事件分派
+func IncGameEvent(command string, code int) {
+	gameEventTotal.WithLabelValues(command, strconv.Itoa(code)).Inc()
+}
+
+// IncMQConsume 记录一次消息消费
+func IncMQConsume(topic string, code int) {
+	mqConsumeTotal.WithLabelValues(topic, strconv.Itoa(code)).Inc()
+}
+
+// ObserveMQConsumeLag 记录消息从生产到消费的延迟
+func ObserveMQConsumeLag(topic string, lag time.Duration) {
+	if lag < 0 {
+		lag = 0 //生产者和消费者时钟不一致
+	}
+	mqConsumeLag.WithLabelValues(topic).Observe(lag.Seconds())
+}
+
+// IncMQProduce 记录一次消息发送 err为nil表示发送成功
+func IncMQProduce(topic string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failed"
+	}
+	mqProduceTotal.WithLabelValues(topic, result).Inc()
+}
+
+// SetMQProduceQueueLength 记录生产者队列中等待发送的消息数量
+func SetMQProduceQueueLength(topic string, length int) {
+	mqProduceQueueLength.WithLabelValues(topic).Set(float64(length))
+}
+
+/**
+ * ObserveRPC
+ * 记录一次中台rpc请求耗时
+ *
+ * @param msg string - rpc日志信息 取第一个单词作为endpoint 例如"BalanceRequest traceId=..."取BalanceRequest
+ * @param code int - 请求返回码
+ * @param elapse time.Duration - 耗时 包含重试
+ */
+
+func ObserveRPC(msg string, code int, elapse time.Duration) {
+	rpcDuration.WithLabelValues(Name(msg), strconv.Itoa(code)).Observe(elapse.Seconds())
+}
+
+// IncOutboxDispatch 记录一次发件箱消息发送
+func IncOutboxDispatch(kind string, code int) {
+	outboxDispatchTotal.WithLabelValues(kind, strconv.Itoa(code)).Inc()
+}
+
+// IncRedisLock 记录一次redis加锁结果
+func IncRedisLock(lock string, result LockResult) {
+	if len(lock) == 0 {
+		lock = otherLabel
+	}
+	redisLockTotal.WithLabelValues(lock, string(result)).Inc()
+}
+
+// AddSettleDuplicate 记录结算台账跳过的重复投递 order按注单数累加 shard和busy按分片数累加
+func AddSettleDuplicate(kind SettleDuplicate, n int) {
+	if n <= 0 {
+		return
+	}
+	settleDuplicateTotal.WithLabelValues(string(kind)).Add(float64(n))
+}
+
+// IncExecutorTask 记录一个协程池任务的执行结果
+func IncExecutorTask(pool, result string) {
+	executorTaskTotal.WithLabelValues(pool, result).Inc()
+}
+
+// SetExecutorQueueLength 记录协程池队列中等待执行的任务数量
+func SetExecutorQueueLength(pool string, length int) {
+	executorQueueLength.WithLabelValues(pool).Set(float64(length))
+}
+
+// AddExecutorActive 协程池正在执行的任务数量加减delta
+func AddExecutorActive(pool string, delta int) {
+	executorActiveWorkers.WithLabelValues(pool).Add(float64(delta))
+}
+
+/**
+ * ObserveWatcher
+ * 记录tool.Watcher的阶段耗时
+ * Watcher名称中可能带有traceId等业务信息 截取后仍超过maxWatcherNames个名称时统一记为other
+ *
+ * @param name string - Watcher名称
+ * @param elapse time.Duration - 耗时
+ */
+
+func ObserveWatcher(name string, elapse time.Duration) {
+	label := Name(name)
+	watcherNamesLock.Lock()
+	if _, ok := watcherNames[label]; !ok {
+		if len(watcherNames) >= maxWatcherNames {
+			label = otherLabel
+		} else {
+			watcherNames[label] = struct{}{}
+		}
+	}
+	watcherNamesLock.Unlock()
+	watcherDuration.WithLabelValues(label).Observe(elapse.Seconds())
+}
+
+/**
+ * Name
+ * 将日志信息转为label 截取到第一个空白或者分隔符之前
+ * 例如"ServiceBet traceId=1"转为"ServiceBet" "getNextRoundInfo handle traceId=1"转为"getNextRoundInfo"
+ *
+ * @param msg string - 日志信息
+ * @return string - label
+ */
+
+func Name(msg string) string {
+	if i := strings.IndexAny(msg, " \t\r\n,=:"); i >= 0 {
+		msg = msg[:i]
+	}
+	if len(msg) == 0 {
+		return otherLabel
+	}
+	return msg
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{msg: "BalanceRequest traceId=1, userId=2", want: "BalanceRequest"},
+		{msg: "getNextRoundInfo handle traceId=1", want: "getNextRoundInfo"},
+		{msg: "游戏事件处理", want: "游戏事件处理"},
+		{msg: "traceId=1", want: "traceId"},
+		{msg: "", want: otherLabel},
+	}
+	for _, tt := range tests {
+		if got := Name(tt.msg); got != tt.want {
+			t.Errorf("Name(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestObserveWatcherLimitsLabels(t *testing.T) {
+	for i := 0; i < maxWatcherNames+10; i++ {
+		ObserveWatcher("stage"+strconv.Itoa(i)+" traceId=1", time.Millisecond)
+	}
+	if got := testutil.CollectAndCount(watcherDuration); got > maxWatcherNames+1 {
+		t.Errorf("watcher label count = %v, want <= %v", got, maxWatcherNames+1)
+	}
+}
+
+func TestIncRedisLock(t *testing.T) {
+	IncRedisLock("Game:GameEventLock", LockContended)
+	IncRedisLock("Game:GameEventLock", LockContended)
+	if got := testutil.ToFloat64(redisLockTotal.WithLabelValues("Game:GameEventLock", string(LockContended))); got != 2 {
+		t.Errorf("redis_lock_total = %v, want 2", got)
+	}
+}
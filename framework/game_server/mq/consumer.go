@@ -7,7 +7,6 @@ import (
 	"github.com/apache/rocketmq-client-go/v2/primitive"
 	"sl.framework.com/game_server/error_code"
 	"sl.framework.com/game_server/game/dao/redisdb"
-	"sl.framework.com/game_server/game/service"
 	"sl.framework.com/game_server/redis/redis_tool"
 	"sl.framework.com/trace"
 	"time"
@@ -32,9 +31,10 @@ const (
 
 type Consumer struct {
 	consumer rocketmq.PushConsumer //消费者接口
+	sub      Subscription          //订阅信息 包含处理函数
 	topic    string                //监听的topic
 	mode     ConsumerMode          //集合模式 集群模式或者广播模式
-	handler  fnOnMessage           //处理函数
+	orderly  bool                  //是否顺序消费
 	group    string                //组名
 	retries  int                   //失败重试次数
 	running  bool                  //是否正在运行
@@ -44,15 +44,14 @@ type Consumer struct {
  * newConsumer
  * 创建一个新的消费者并启动
  *
- * @param topic - 消费者订阅的主题
+ * @param addrs - name server地址
+ * @param sub - 订阅信息 顺序消费时同一队列的消息串行处理 失败时暂停该队列稍后重试
  * @param mode - 消费者的模式 集群或者广播
- * @param interfaces - 消费者处理消息的回调函数
- * @param group - 消费者组
  * @return *Consumer - 返回新创建的消费者
  * @return bool - 创建消费者是否成功
  */
 
-func newConsumer(addrs []string, topic Topic, group string, mode ConsumerMode, handler fnOnMessage, retries int) (*Consumer, bool) {
+func newConsumer(addrs []string, sub Subscription, mode ConsumerMode) (*Consumer, bool) {
 	consumerMode := consumer.Clustering
 	if mode == broadcast {
 		consumerMode = consumer.BroadCasting
@@ -60,21 +59,24 @@ func newConsumer(addrs []string, topic Topic, group string, mode ConsumerMode, h
 
 	//创建新的消费者包装对象
 	c := &Consumer{
-		topic:   string(topic),
+		sub:     sub,
+		topic:   sub.Topic,
 		mode:    cluster,
-		handler: handler,
-		group:   group,
-		retries: retries,
+		orderly: sub.Orderly,
+		group:   sub.Group,
+		retries: sub.Retries,
 	}
+	retries := c.retries
 
 	//创建消费者
 	var err error
 	if c.consumer, err = rocketmq.NewPushConsumer(
 		consumer.WithNameServer(addrs),
 		consumer.WithConsumeFromWhere(consumer.ConsumeFromFirstOffset),
-		consumer.WithGroupName(group),            // 消费组名称
+		consumer.WithGroupName(c.group),          // 消费组名称
 		consumer.WithConsumerModel(consumerMode), //注册模式
 		consumer.WithRetry(retries),
+		consumer.WithConsumerOrder(c.orderly),
 	); nil != err {
 		trace.Error("newConsumer create failed addr=%+v, group name=%v, topic=%v, mode=%v, retries=%v, error=%v",
 			addrs, c.group, c.topic, mode, retries, err.Error())
@@ -133,10 +135,21 @@ func (c *Consumer) subscribe() {
 					continue
 				}
 			*/
-			retSum += c.handler(traceId, msg.Body)
+			code := consumeMessage(&c.sub, &Message{
+				Topic:      msg.Topic,
+				Tag:        msg.GetTags(),
+				Properties: map[string]string{string(propertyTraceId): traceId},
+				Body:       msg.Body,
+				BornTime:   time.UnixMilli(msg.BornTimestamp),
+			})
+			retSum += code
 		}
 		if errcode.ErrorOk != retSum {
 			ret = consumer.ConsumeRetryLater
+			//顺序消费不支持RetryLater 需要挂起当前队列后重试 否则后续消息会越过失败消息
+			if c.orderly {
+				ret = consumer.SuspendCurrentQueueAMoment
+			}
 		}
 		return ret, nil
 	}
@@ -144,7 +157,7 @@ func (c *Consumer) subscribe() {
 	//只接收tag形式的mq消息
 	messageSelector := consumer.MessageSelector{
 		Type:       consumer.TAG,
-		Expression: service.BuildMQTags(),
+		Expression: c.sub.Tags,
 	}
 	if err := c.consumer.Subscribe(c.topic, messageSelector, cb); nil != err {
 		trace.Error("subscribe failed topic=%v, group=%v, mode=%v failed, error=%v",
@@ -0,0 +1,127 @@
+package mq
+
+import (
+	"context"
+	"sl.framework.com/trace"
+	"sync"
+	"time"
+)
+
+/*
+	停止消费
+	关闭时先暂停拉取消息 然后拒绝新到达的消息 等待正在处理的消息处理完毕再关闭传输层
+	被拒绝的消息返回GameErrorServerStopping 由rocketmq重新投递给其他节点或者重启后再处理
+*/
+
+// suspender 支持暂停拉取消息的传输层
+type suspender interface {
+	Suspend()
+}
+
+// checker 支持健康检查的传输层
+type checker interface {
+	Check() error
+}
+
+// consumeGate 统计正在处理的消息数量 停止消费后拒绝新的消息
+type consumeGate struct {
+	mutex    sync.Mutex
+	draining bool          //已经停止消费
+	inflight int           //正在处理的消息数量
+	idle     chan struct{} //停止消费后正在处理的消息全部完成时关闭
+}
+
+var gate = &consumeGate{}
+
+// enter 开始处理一条消息 停止消费后返回false
+func (g *consumeGate) enter() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.draining {
+		return false
+	}
+	g.inflight++
+	return true
+}
+
+// leave 一条消息处理完成
+func (g *consumeGate) leave() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.inflight--
+	if g.draining && g.inflight == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+}
+
+/**
+ * drain
+ * 停止消费并等待正在处理的消息处理完毕
+ *
+ * @param ctx context.Context - 等待超时控制
+ * @return int - 超时时还没有处理完的消息数量
+ * @return error - 超时返回ctx.Err()
+ */
+
+func (g *consumeGate) drain(ctx context.Context) (int, error) {
+	g.mutex.Lock()
+	g.draining = true
+	if g.inflight == 0 {
+		g.mutex.Unlock()
+		return 0, nil
+	}
+	if g.idle == nil {
+		g.idle = make(chan struct{})
+	}
+	idle := g.idle
+	g.mutex.Unlock()
+
+	select {
+	case <-idle:
+		return 0, nil
+	case <-ctx.Done():
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		return g.inflight, ctx.Err()
+	}
+}
+
+// reset 重新开始消费 设置新的传输层时调用
+func (g *consumeGate) reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.draining = false
+}
+
+/**
+ * DrainMQManager
+ * 优雅关闭消息队列
+ * 1.暂停消费者拉取消息
+ * 2.拒绝已经拉取但是还没有开始处理的消息
+ * 3.等待正在处理的开奖 提交注单等消息处理完毕 不会在派彩和更新注单之间中断
+ * 4.关闭消费者和生产者 消费进度在关闭时提交
+ *
+ * @param ctx context.Context - 等待超时控制 超时后仍然关闭传输层
+ * @return error - 超时返回ctx.Err()
+ */
+
+func DrainMQManager(ctx context.Context) error {
+	if s, ok := currentTransport().(suspender); ok {
+		s.Suspend()
+	}
+
+	start := time.Now()
+	inflight, err := gate.drain(ctx)
+	if err != nil {
+		trace.Error("DrainMQManager wait inflight messages timeout, inflight=%v, elapse=%v, error=%v", inflight, time.Since(start), err.Error())
+	} else {
+		trace.Info("DrainMQManager inflight messages done, elapse=%v", time.Since(start))
+	}
+
+	StopMQManager()
+	return err
+}
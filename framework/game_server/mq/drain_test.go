@@ -0,0 +1,91 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sl.framework.com/game_server/error_code"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDrainMQManager(t *testing.T) {
+	defer gate.reset()
+	tr := NewMemoryTransport(16)
+	SetTransport(tr)
+	if err := Check(); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	var (
+		mutex   sync.Mutex
+		handled []string
+	)
+	started, release := make(chan struct{}), make(chan struct{})
+	err := tr.Subscribe(Subscription{
+		Topic: "game-draw-0",
+		Group: "g",
+		Handler: func(traceId string, body []byte) int {
+			if string(body) == "a" {
+				close(started)
+				<-release
+			}
+			mutex.Lock()
+			handled = append(handled, string(body))
+			mutex.Unlock()
+			return errcode.ErrorOk
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"a", "b"} {
+		if err = tr.Publish(newTestMessage("game-draw-0", "", body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+
+	done := make(chan error, 1)
+	go func() { done <- DrainMQManager(context.Background()) }()
+	select {
+	case err = <-done:
+		t.Fatalf("DrainMQManager() returned before inflight message done, error = %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	//正在处理的消息完成 停止消费后到达的消息被拒绝
+	close(release)
+	if err = <-done; err != nil {
+		t.Fatalf("DrainMQManager() error = %v", err)
+	}
+	if len(handled) != 1 || handled[0] != "a" {
+		t.Fatalf("handled = %v, want [a]", handled)
+	}
+	if err = Check(); err == nil {
+		t.Fatalf("Check() after drain error = nil")
+	}
+}
+
+func TestConsumeGateTimeout(t *testing.T) {
+	g := &consumeGate{}
+	if !g.enter() {
+		t.Fatal("enter() = false before drain")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	inflight, err := g.drain(ctx)
+	if inflight != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("drain() = %v, %v, want 1, DeadlineExceeded", inflight, err)
+	}
+	if g.enter() {
+		t.Fatal("enter() = true after drain")
+	}
+	g.leave()
+
+	g.reset()
+	if !g.enter() {
+		t.Fatal("enter() = false after reset")
+	}
+}
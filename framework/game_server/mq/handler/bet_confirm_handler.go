@@ -2,8 +2,8 @@ package handler
 
 import (
 	"encoding/json"
-	"sl.framework.com/async"
 	errcode "sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/executor"
 	"sl.framework.com/game_server/game/dao/redisdb"
 	"sl.framework.com/game_server/game/service"
 	"sl.framework.com/game_server/game/service/bet"
@@ -13,6 +13,7 @@ import (
 	"sl.framework.com/tool"
 	"sl.framework.com/trace"
 	"strconv"
+	"sync"
 )
 
 /**
@@ -39,15 +40,16 @@ func OnBetConfirmHandler(traceId string, msgBody []byte) int {
 	}
 	pWatcher := tool.NewWatcher("注单提交处理")
 	ret := processBetConfirm(traceId, betConfirmMessagePayload)
-	trace.Info("[提交注单消息处理函数] traceId=%v 处理完成, GameRoomId=%v, GameRoundId=%v, ret=%v",
-		traceId, betConfirmMessagePayload.GameRoomId, betConfirmMessagePayload.GameRoundId, ret)
+	trace.FromContext(roundLogContext(traceId, betConfirmMessagePayload.GameRoomId, betConfirmMessagePayload.GameRoundId)).
+		Info("[提交注单消息处理函数] 处理完成", "users", len(betConfirmMessagePayload.UserInfo), "ret", ret)
 	pWatcher.Stop()
-	return errcode.ErrorOk
+	return ret
 }
 
 /**
  * processBetConfirm
- * 处理注单提交函数
+ * 处理注单提交函数 每个用户提交到betConfirm协程池并等待全部完成
+ * 有用户提交失败时返回错误由mq重新投递 重投时已经扣款成功的用户按注单提交状态跳过 只重试失败的用户
  *
  * @param traceId string- 跟踪id
  * @param payload *rocket_mq.BetConfirmMessagePayload -提交的注单用户信息
@@ -57,22 +59,71 @@ func OnBetConfirmHandler(traceId string, msgBody []byte) int {
 func processBetConfirm(traceId string, payload *rocket_mq.BetConfirmMessagePayload) int {
 	trace.Info("[处理提交注单] traceId=%v payload=%+v", traceId, payload)
 
-	gameRoomId := payload.GameRoomId
-	gameRoundId := payload.GameRoundId
+	var (
+		wg      sync.WaitGroup
+		mutex   sync.Mutex
+		ret     = errcode.ErrorOk
+		failed  = make([]string, 0)
+		roomId  = strconv.FormatInt(payload.GameRoomId, 10)
+		roundId = strconv.FormatInt(payload.GameRoundId, 10)
+	)
+	fail := func(userId string, code int) {
+		mutex.Lock()
+		failed = append(failed, userId)
+		ret = code
+		mutex.Unlock()
+	}
 	for _, userInfo := range payload.UserInfo {
 		trace.Debug("[处理提交注单] traceId=%v userInfo=%+v", traceId, userInfo)
-		async.AsyncRunCoroutine(func() {
-			//分布式锁防止重复确认，无需主动释放，让它自然过期
-			redisLockInfo := rediskey.GetBetConfirmedLockRedisInfo(strconv.FormatInt(gameRoomId, 10),
-				strconv.FormatInt(gameRoundId, 10), userInfo.UserId)
-			if !redisdb.TryLock(redisLockInfo) {
-				trace.Notice("[处理提交注单] traceId=%v, redis lock failed, lock info=%+v", traceId, redisLockInfo)
-				return
+		wg.Add(1)
+		fn := func() {
+			defer wg.Done()
+			if code := confirmUser(traceId, roomId, roundId, userInfo); code != errcode.ErrorOk {
+				fail(userInfo.UserId, code)
 			}
-			bet.ServiceBetConfirm(traceId, strconv.FormatInt(gameRoomId, 10),
-				strconv.FormatInt(gameRoundId, 10), userInfo.UserId, userInfo.Currency)
-		})
+		}
+		//任务被拒绝时该用户没有提交 等待重投
+		if err := executor.Submit(executor.PoolBetConfirm, fn); err != nil {
+			wg.Done()
+			trace.Notice("[处理提交注单] traceId=%v, userId=%v rejected, error=%v", traceId, userInfo.UserId, err.Error())
+			fail(userInfo.UserId, errcode.ErrorUnknown)
+		}
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		trace.FromContext(roundLogContext(traceId, payload.GameRoomId, payload.GameRoundId)).
+			Error("[处理提交注单] 提交失败等待重试", "users", failed, "ret", ret)
 	}
+	return ret
+}
 
-	return errcode.ErrorOk
+/**
+ * confirmUser
+ * 提交单个用户的注单
+ * 分布式锁防止同一用户并发提交 处理完成后释放 重新投递时可以立即重试
+ *
+ * @param traceId string - 跟踪id
+ * @param gameRoomId string - 房间Id
+ * @param gameRoundId string - 局Id
+ * @param userInfo types.UserCurrencyInfo - 用户信息
+ * @return int - 返回码
+ */
+
+func confirmUser(traceId, gameRoomId, gameRoundId string, userInfo types.UserCurrencyInfo) int {
+	redisLockInfo := rediskey.GetBetConfirmedLockRedisInfo(gameRoomId, gameRoundId, userInfo.UserId)
+	if !redisdb.TryLock(redisLockInfo) {
+		//其他请求正在提交该用户的注单 等待重投时按注单提交状态处理
+		trace.Notice("[处理提交注单] traceId=%v, redis lock failed, lock info=%+v", traceId, redisLockInfo)
+		return errcode.RedisErrorLock
+	}
+	defer redisdb.Unlock(redisLockInfo)
+
+	code := bet.ServiceBetConfirm(traceId, gameRoomId, gameRoundId, userInfo.UserId, userInfo.Currency)
+	if code == errcode.HttpErrorPlatformReply {
+		//余额不足 超过限额等业务错误 注单已经记为被拒绝 重新投递也不会成功
+		trace.Notice("[处理提交注单] traceId=%v, userId=%v, 中台拒绝扣款", traceId, userInfo.UserId)
+		return errcode.ErrorOk
+	}
+	return code
 }
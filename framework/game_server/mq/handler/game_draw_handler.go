@@ -4,14 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
-	"sl.framework.com/async"
-	"sl.framework.com/game_server/currency/impl"
 	errcode "sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/executor"
+	"sl.framework.com/game_server/game/dao/redisdb"
 	"sl.framework.com/game_server/game/service"
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/game/service/type/const_type"
 	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/metrics"
 	"sl.framework.com/game_server/redis/cache"
+	"sl.framework.com/game_server/redis/rediskey"
 	rpcreq "sl.framework.com/game_server/rpc_client"
 	"sl.framework.com/tool"
 	"sl.framework.com/trace"
@@ -45,10 +47,17 @@ func OnGameDrawHandler(traceId string, msgBody []byte) int {
 		return errcode.ErrorOk
 	}
 
-	trace.Info("%v, time elapse=%v, msg header=%+v", msgHeader, time.Since(timeStart), msgDrawGameDataDTO)
-	ret := processDrawGame(traceId, msgDrawGameDataDTO)
-	trace.Info("%v process game draw done, tme elapse=%v, gameRoundId=%v, gameRoundNo=%v, ret=%v",
-		msgHeader, time.Since(timeStart), msgDrawGameDataDTO.GameRoundId, msgDrawGameDataDTO.GameRoundNo, ret)
+	log := trace.FromContext(roundLogContext(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId))
+	log.Info("MQ消息 开始结算", "roundNo", msgDrawGameDataDTO.GameRoundNo, "shardNo", msgDrawGameDataDTO.ShardNo,
+		"resettle", msgDrawGameDataDTO.Resettle, "orders", len(msgDrawGameDataDTO.OrderList))
+	trace.Debug("%v, time elapse=%v, msg header=%+v", msgHeader, time.Since(timeStart), msgDrawGameDataDTO)
+	var ret int
+	if msgDrawGameDataDTO.Resettle {
+		ret = processResettleGame(traceId, msgDrawGameDataDTO)
+	} else {
+		ret = processDrawGame(traceId, msgDrawGameDataDTO)
+	}
+	log.Info("MQ消息 结算完成", "roundNo", msgDrawGameDataDTO.GameRoundNo, "elapse", time.Since(timeStart), "ret", ret)
 
 	return ret
 }
@@ -73,6 +82,32 @@ func processDrawGame(traceId string, msgDrawGameDataDTO *types.GameDrawDataDTO)
 		return errcode.ErrorOk
 	}
 
+	//同一分片重复投递时只允许一个消费者处理 其他的稍后重试 重试时已派彩注单由台账跳过
+	shardLock := rediskey.GetSettleShardLockRedisInfo(msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId, msgDrawGameDataDTO.ShardNo)
+	if !redisdb.TryLock(shardLock) {
+		trace.FromContext(roundLogContext(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId)).
+			Notice("分片正在结算 稍后重试", "shardNo", msgDrawGameDataDTO.ShardNo)
+		metrics.AddSettleDuplicate(metrics.SettleDuplicateBusy, 1)
+		return errcode.GameErrorSettleShardBusy
+	}
+	defer redisdb.Unlock(shardLock)
+
+	//结算台账 跳过已经派彩的注单 分片部分失败后重投时只结算剩余注单
+	ledger, ok := cache.GetSettleLedger(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId)
+	if !ok {
+		return errcode.RedisErrorGet
+	}
+	orderList, settledList := pendingSettleOrders(msgDrawGameDataDTO.OrderList, ledger)
+	if len(settledList) > 0 {
+		trace.Notice("%v, shardNo=%v 重复投递 跳过已派彩注单 settled=%v pending=%v",
+			msgHeader, msgDrawGameDataDTO.ShardNo, settledList, orderList)
+		metrics.AddSettleDuplicate(metrics.SettleDuplicateOrder, len(settledList))
+	}
+	if len(orderList) == 0 {
+		metrics.AddSettleDuplicate(metrics.SettleDuplicateShard, 1)
+		return errcode.ErrorOk
+	}
+
 	pDog := tool.NewWatcher("批量结算")
 	// 获取结算对象
 	drawer := service.GetDrawer(traceId, types.GameId(msgDrawGameDataDTO.GameId))
@@ -82,7 +117,7 @@ func processDrawGame(traceId string, msgDrawGameDataDTO *types.GameDrawDataDTO)
 	}
 	defer service.PutDrawer(types.GameId(msgDrawGameDataDTO.GameId), drawer)
 	//获取结算注单
-	SettleDTOList = drawer.SettleOrder(traceId, &msgDrawGameDataDTO.GameRoundResultDTO, msgDrawGameDataDTO, &msgDrawGameDataDTO.OrderList)
+	SettleDTOList = drawer.SettleOrder(traceId, &msgDrawGameDataDTO.GameRoundResultDTO, msgDrawGameDataDTO, &orderList)
 	trace.Info("MQ消息 未派彩注单派彩  获取结算注单:%+v OrderList:%+v", msgHeader, SettleDTOList)
 	pDog.Stop()
 
@@ -95,7 +130,8 @@ func processDrawGame(traceId string, msgDrawGameDataDTO *types.GameDrawDataDTO)
 	}
 
 	if len(SettleDTOMap) == 0 {
-		trace.Error("processDrawGame traceId:%v settle DTO list empty", traceId)
+		trace.FromContext(roundLogContext(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId)).
+			Error("processDrawGame settle DTO list empty", "shardNo", msgDrawGameDataDTO.ShardNo)
 		return errcode.ErrorSettleEmpty
 	}
 	pDog.Stop()
@@ -118,8 +154,8 @@ func processDrawGame(traceId string, msgDrawGameDataDTO *types.GameDrawDataDTO)
 				order.PostTime = time.Now()             //记录派彩完成时间
 				order.SettleStatus = "Success"          //走到这里说明结算成功了
 				order.UpdateTime = time.Now()
-				order.WinAmount = impl.NewCurrency(order.Currency, settleDTO.WinAmount).CurrencyValue()
-				order.AvailableBetAmount = impl.NewCurrency(order.Currency, settleDTO.AvailableBetAmount).CurrencyValue()
+				order.WinAmount = settleDTO.WinAmount.Truncate(order.Currency)
+				order.AvailableBetAmount = settleDTO.AvailableBetAmount.Truncate(order.Currency)
 				BetOrdersList = append(BetOrdersList, order)
 			}
 
@@ -130,7 +166,7 @@ func processDrawGame(traceId string, msgDrawGameDataDTO *types.GameDrawDataDTO)
 	pDog.Stop()
 
 	//更新用户注单缓存
-	cache.SetOrders(traceId, strconv.FormatInt(msgDrawGameDataDTO.GameRoomId, 10), strconv.FormatInt(msgDrawGameDataDTO.GameRoundId, 10), orderAllList)
+	cache.UpdateOrders(traceId, strconv.FormatInt(msgDrawGameDataDTO.GameRoomId, 10), strconv.FormatInt(msgDrawGameDataDTO.GameRoundId, 10), orderAllList)
 	//更新注单入库
 	pDog.Start("更新注单入库")
 	dbGet := service.NewGameDBSaver(traceId, types.GameId(msgDrawGameDataDTO.GameId))
@@ -145,12 +181,25 @@ func processDrawGame(traceId string, msgDrawGameDataDTO *types.GameDrawDataDTO)
 	//发送注单,更新平台中心注单状态
 	//先获取orderlist
 	trace.Info("MQ消息  发送注单,更新平台中心注单状态:%+v", msgHeader)
-	rpcreq.Settle(traceId, strconv.FormatInt(msgDrawGameDataDTO.GameRoomId, 10), strconv.FormatInt(msgDrawGameDataDTO.GameRoundId, 10), SettleDTOList)
+	//派彩请求先写入发件箱 平台中心暂时不可用时由发件箱后台任务重试
+	if code := rpcreq.OutboxSettle(traceId, settleBizKey(msgDrawGameDataDTO), strconv.FormatInt(msgDrawGameDataDTO.GameRoomId, 10),
+		strconv.FormatInt(msgDrawGameDataDTO.GameRoundId, 10), SettleDTOList); code != errcode.ErrorOk {
+		//写入发件箱失败并且直接派彩也失败 不记台账 返回错误由mq重新投递 重投时重新结算本分片未派彩的注单
+		trace.Error("MQ消息 未派彩注单派彩 %v, 派彩失败 code=%v", msgHeader, code)
+		return code
+	}
+	//派彩请求已经写入发件箱或者派彩成功后立即记台账 台账写入失败只记录日志 不能再返回错误导致重复派彩
+	if !cache.PutSettleLedger(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId,
+		buildSettleLedger(traceId, msgDrawGameDataDTO.ShardNo, SettleDTOList, time.Now())) {
+		trace.Error("MQ消息 未派彩注单派彩 %v, 记录结算台账失败", msgHeader)
+	}
 
 	//通知客户端开小票 需要等上一步执行完
-	async.AsyncRunCoroutine(func() {
+	if err := executor.Submit(executor.PoolReceipt, func() {
 		sendReceiptToUser(traceId, msgDrawGameDataDTO, BetOrdersList)
-	})
+	}); err != nil {
+		trace.Error("MQ消息 未派彩注单派彩 %v, 发送小票任务被拒绝 error=%v", msgHeader, err.Error())
+	}
 	//进行完成结算之后的逻辑处理
 	drawer.AfterCompletion(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId, SettleDTOList)
 	trace.Info("MQ消息 未派彩注单派彩 完成:%+v", msgHeader)
@@ -217,7 +266,12 @@ func sendReceiptToUser(tracdId string, gameDrawDataDTO *types.GameDrawDataDTO, b
 			UserIdSet = append(UserIdSet, v.UserId)
 			UserIdMap[v.UserId] = v
 		} else {
-			UserIdMap[v.UserId].WinAmount += v.WinAmount
+			sum, err := UserIdMap[v.UserId].WinAmount.Add(v.WinAmount)
+			if err != nil {
+				trace.Error("sendReceiptToUser traceId:%v, userId:%v sum win amount failed, error=%v", tracdId, v.UserId, err.Error())
+				continue
+			}
+			UserIdMap[v.UserId].WinAmount = sum
 		}
 	}
 
@@ -241,10 +295,14 @@ func sendReceiptToUser(tracdId string, gameDrawDataDTO *types.GameDrawDataDTO, b
 			Time:       strconv.FormatInt(tool.Current(), 10),
 			Body:       gameDrawResultVO,
 		}
-		async.AsyncRunCoroutine(func() {
+		bizKey := receiptBizKey(gameDrawDataDTO, UserIdSet[i])
+		//小票任务嵌套提交到同一个协程池 队列满时在当前协程执行 不会互相等待
+		if err := executor.Submit(executor.PoolReceipt, func() {
 			trace.Info("sendReceiptToUser traceId:%v,gameRoomId:%v,gameRoundId:%v,betOrderList:%v SendReceipts", tracdId, gameDrawDataDTO.GameRoomId, gameDrawDataDTO.GameRoundId, betOrdersList)
-			rpcreq.SendReceipts(tracdId, gameDrawDataDTO.GameRoundNo, gameDrawDataDTO.GameRoundId, gameDrawDataDTO.GameRoomId, message)
-		})
+			rpcreq.OutboxSendReceipts(tracdId, bizKey, gameDrawDataDTO.GameRoundNo, gameDrawDataDTO.GameRoundId, gameDrawDataDTO.GameRoomId, message)
+		}); err != nil {
+			trace.Error("sendReceiptToUser traceId:%v,gameRoomId:%v,gameRoundId:%v,userId:%v receipt rejected, error=%v", tracdId, gameDrawDataDTO.GameRoomId, gameDrawDataDTO.GameRoundId, UserIdSet[i], err.Error())
+		}
 
 	}
 	pWatch.Stop()
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	errcode "sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/executor"
+	"sl.framework.com/game_server/game/service"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/redis/cache"
+	rpcreq "sl.framework.com/game_server/rpc_client"
+	"sl.framework.com/tool"
+	"sl.framework.com/trace"
+	"strconv"
+	"time"
+)
+
+const (
+	adjustStatusNone    = "None"    //派彩金额未变化 无需补差
+	adjustStatusSuccess = "Success" //补差成功
+	adjustStatusFailed  = "Failed"  //差额无法计算 需要人工处理
+	adjustStatusRetry   = "Retry"   //钱包没有返回成功 保留原派彩等待消息重新投递后再补差
+)
+
+// processResettleGame 使用修正后的局结果对已派彩注单重新结算 按新旧派彩差额补差并记录审计
+func processResettleGame(traceId string, msgDrawGameDataDTO *types.GameDrawDataDTO) int {
+	msgHeader := fmt.Sprintf("processResettleGame traceId=%v, resettleId=%v, gameRoomId=%v, gameRoundId=%v, gameRoundNo=%v",
+		traceId, msgDrawGameDataDTO.ResettleId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId, msgDrawGameDataDTO.GameRoundNo)
+	log := trace.FromContext(roundLogContext(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId)).
+		With("resettleId", msgDrawGameDataDTO.ResettleId)
+	trace.Info("MQ消息 重新结算 %v", msgHeader)
+	if len(msgDrawGameDataDTO.OrderList) == 0 {
+		trace.Notice("%v, no order", msgHeader)
+		return errcode.ErrorOk
+	}
+	strRoomId := strconv.FormatInt(msgDrawGameDataDTO.GameRoomId, 10)
+	strRoundId := strconv.FormatInt(msgDrawGameDataDTO.GameRoundId, 10)
+
+	//使用修正后的局结果结算
+	pDog := tool.NewWatcher("重新结算")
+	drawer := service.GetDrawer(traceId, types.GameId(msgDrawGameDataDTO.GameId))
+	if drawer == nil {
+		trace.Error("%v, no game draw.handler, invalid gameId=%v", msgHeader, msgDrawGameDataDTO.GameId)
+		return errcode.ErrorOk //游戏类型错误 返回mq broker成功 不再重发该结算消息
+	}
+	defer service.PutDrawer(types.GameId(msgDrawGameDataDTO.GameId), drawer)
+	settleDTOList := drawer.SettleOrder(traceId, &msgDrawGameDataDTO.GameRoundResultDTO, msgDrawGameDataDTO, &msgDrawGameDataDTO.OrderList)
+	if len(settleDTOList) == 0 {
+		trace.Error("%v, settle DTO list empty", msgHeader)
+		return errcode.ErrorSettleEmpty
+	}
+	settleDTOMap := make(map[int64]*types.SettleDTO, len(settleDTOList))
+	for _, settleDTO := range settleDTOList {
+		settleDTOMap[settleDTO.OrderNo] = settleDTO
+	}
+	pDog.Stop()
+
+	//与修正前的派彩结果对比 计算差额
+	pDog.Start("重新结算计算差额")
+	orderAllList := cache.GetOrders(traceId, strRoomId, strRoundId)
+	var (
+		resettleOrders []*dto.BetDTO
+		diffList       []*types.ResettleOrderDTO
+	)
+	for _, order := range orderAllList {
+		settleDTO := settleDTOMap[order.OrderNo]
+		if settleDTO == nil {
+			continue
+		}
+		diffList = append(diffList, buildResettleDiff(order, settleDTO))
+		resettleOrders = append(resettleOrders, order)
+	}
+	if len(resettleOrders) == 0 {
+		trace.Error("%v, 缓存中没有需要重新结算的注单", msgHeader)
+		return errcode.RedisErrorGet
+	}
+	pDog.Stop()
+
+	//钱包补差
+	pDog.Start("重新结算补差")
+	if code := adjustOrders(traceId, msgHeader, msgDrawGameDataDTO, diffList); code != errcode.ErrorOk {
+		//钱包调用失败 注单没有任何修改 重新投递时按相同的resettleId补差 钱包保证幂等
+		pDog.Stop()
+		return code
+	}
+	pDog.Stop()
+
+	//更新注单 钱包没有补差成功的注单保留原派彩 等待重新投递
+	var (
+		updateOrders []*dto.BetDTO
+		retry        bool
+	)
+	for i, order := range resettleOrders {
+		if diffList[i].AdjustStatus == adjustStatusRetry {
+			retry = true
+			log.Error("注单补差没有成功 保留原派彩等待重新投递", "diff", fmt.Sprintf("%+v", diffList[i]))
+			continue
+		}
+		applyResettle(order, settleDTOMap[order.OrderNo], diffList[i])
+		if diffList[i].AdjustStatus == adjustStatusFailed {
+			log.Error("注单差额无法计算 需要人工处理", "diff", fmt.Sprintf("%+v", diffList[i]))
+		}
+		updateOrders = append(updateOrders, order)
+	}
+
+	//审计记录 包含修正前后的局结果和每个注单的差额
+	audit := &types.ResettleAuditDTO{
+		TraceId:        traceId,
+		ResettleId:     msgDrawGameDataDTO.ResettleId,
+		GameRoomId:     msgDrawGameDataDTO.GameRoomId,
+		GameRoundId:    msgDrawGameDataDTO.GameRoundId,
+		GameRoundNo:    msgDrawGameDataDTO.GameRoundNo,
+		PreviousResult: msgDrawGameDataDTO.PreviousResultDTO,
+		CurrentResult:  &msgDrawGameDataDTO.GameRoundResultDTO,
+		Orders:         diffList,
+		CreateTime:     time.Now(),
+	}
+	if data, err := json.Marshal(audit); err == nil {
+		trace.Notice("%v, 重新结算审计 audit=%v", msgHeader, string(data))
+	}
+	cache.PutResettleAudit(traceId, audit)
+
+	//更新小票 注单缓存以及数据库
+	pDog.Start("重新结算更新注单")
+	PutReceipts(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId, updateOrders)
+	cache.UpdateOrders(traceId, strRoomId, strRoundId, orderAllList)
+	dbGet := service.NewGameDBSaver(traceId, types.GameId(msgDrawGameDataDTO.GameId))
+	if dbGet == nil {
+		trace.Error("%v, NewGameDBSaver new game order saver interfaces failed", msgHeader)
+		return errcode.ErrorUnknown
+	}
+	dbGet.UpdateOrders(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId, &updateOrders)
+	pDog.Stop()
+	if retry {
+		//已补差的注单派彩已经更新 重新投递时差额为零不会重复补差
+		return errcode.ErrorResettleAdjust
+	}
+
+	//更新平台中心注单结算结果并通知客户端新的小票
+	if code := rpcreq.OutboxSettle(traceId, settleBizKey(msgDrawGameDataDTO), strRoomId, strRoundId, settleDTOList); code != errcode.ErrorOk {
+		//补差已经完成 重新投递时差额为零 只会重新写入发件箱
+		log.Error("重新结算写入发件箱失败", "code", code)
+		return code
+	}
+	if err := executor.Submit(executor.PoolReceipt, func() {
+		sendReceiptToUser(traceId, msgDrawGameDataDTO, resettleOrders)
+	}); err != nil {
+		trace.Error("%v, 发送小票任务被拒绝 error=%v", msgHeader, err.Error())
+	}
+	drawer.AfterCompletion(traceId, msgDrawGameDataDTO.GameRoomId, msgDrawGameDataDTO.GameRoundId, settleDTOList)
+	log.Info("MQ消息 重新结算完成", "orders", len(updateOrders))
+	return errcode.ErrorOk
+}
+
+/**
+ * buildResettleDiff
+ * 计算注单重新结算前后的派彩差额
+ *
+ * @param order *dto.BetDTO - 修正前的注单
+ * @param settleDTO *types.SettleDTO - 修正后的结算结果
+ * @return *types.ResettleOrderDTO - 差额 派彩金额未变化时补差状态为None
+ */
+
+func buildResettleDiff(order *dto.BetDTO, settleDTO *types.SettleDTO) *types.ResettleOrderDTO {
+	winAmount := settleDTO.WinAmount.Truncate(order.Currency)
+	diff := &types.ResettleOrderDTO{
+		OrderNo:               order.OrderNo,
+		UserId:                order.UserId,
+		Currency:              order.Currency,
+		PreviousWinAmount:     order.WinAmount,
+		WinAmount:             winAmount,
+		PreviousWinLostStatus: order.WinLostStatus,
+		WinLostStatus:         settleDTO.WinLostStatus,
+	}
+	adjustAmount, err := winAmount.Sub(order.WinAmount)
+	if err != nil {
+		//差额溢出无法补差 标记为失败人工处理
+		trace.Error("buildResettleDiff orderNo=%v, calculate adjust amount failed, error=%v", order.OrderNo, err.Error())
+		diff.AdjustStatus = adjustStatusFailed
+		return diff
+	}
+	diff.AdjustAmount = adjustAmount
+	if diff.AdjustAmount.IsZero() {
+		diff.AdjustStatus = adjustStatusNone
+	}
+	return diff
+}
+
+// applyResettle 按重新结算结果更新注单 差额无法计算的注单保留原派彩并标记派奖失败
+func applyResettle(order *dto.BetDTO, settleDTO *types.SettleDTO, diff *types.ResettleOrderDTO) {
+	strResult, _ := json.Marshal(settleDTO.GameRoundResult.Payload.Result)
+	order.PostTime = time.Now()
+	order.UpdateTime = time.Now()
+	if diff.AdjustStatus == adjustStatusFailed {
+		order.PostStatus = string(const_type.PostStatusFailed)
+		return
+	}
+	order.WinLostStatus = settleDTO.WinLostStatus
+	order.DrawOdds = settleDTO.DrawOdds
+	order.GameResult = string(strResult)
+	order.WinAmount = diff.WinAmount
+	order.AvailableBetAmount = settleDTO.AvailableBetAmount.Truncate(order.Currency)
+	order.AvailableStatus = string(const_type.AvailableStatusResettle)
+	order.PostStatus = string(const_type.PostStatusResettle)
+	order.SettleTime = settleDTO.SettleTime
+}
+
+/**
+ * adjustOrders
+ * 对派彩金额有变化的注单调用钱包补差 并回写每个注单的补差状态
+ * 钱包没有返回成功的注单补差状态为Retry
+ *
+ * @param traceId string - 跟踪id
+ * @param msgHeader string - 日志头
+ * @param msgDrawGameDataDTO *types.GameDrawDataDTO - 重新结算消息
+ * @param diffList []*types.ResettleOrderDTO - 注单差额
+ * @return int - 钱包调用失败时返回错误码 消息需要重新投递
+ */
+
+func adjustOrders(traceId, msgHeader string, msgDrawGameDataDTO *types.GameDrawDataDTO, diffList []*types.ResettleOrderDTO) int {
+	adjustDTO := &dto.AdjustDTO{
+		ResettleId:  msgDrawGameDataDTO.ResettleId,
+		GameRoomId:  msgDrawGameDataDTO.GameRoomId,
+		GameRoundId: msgDrawGameDataDTO.GameRoundId,
+		GameRoundNo: msgDrawGameDataDTO.GameRoundNo,
+		Reason:      string(types.GameEventCommandGameResettle),
+	}
+	for _, diff := range diffList {
+		if diff.AdjustStatus == adjustStatusNone || diff.AdjustStatus == adjustStatusFailed {
+			continue //无需补差或者差额无法计算
+		}
+		diff.AdjustStatus = adjustStatusRetry //钱包返回成功后再设置为成功
+		adjustDTO.OrderList = append(adjustDTO.OrderList, &dto.AdjustOrderDTO{
+			OrderNo:  diff.OrderNo,
+			UserId:   diff.UserId,
+			Currency: diff.Currency,
+			Amount:   diff.AdjustAmount,
+		})
+	}
+	if len(adjustDTO.OrderList) == 0 {
+		trace.Info("%v, 派彩金额没有变化 无需补差", msgHeader)
+		return errcode.ErrorOk
+	}
+
+	resultList, retCode := rpcreq.Adjust(traceId, adjustDTO)
+	if retCode != errcode.ErrorOk {
+		trace.Error("%v, 调用钱包补差失败 错误码:%v", msgHeader, retCode)
+		return retCode
+	}
+	succeed := make(map[string]struct{}, len(resultList))
+	for _, result := range resultList {
+		if result.Status == string(const_type.TransactionStatusSuccess) {
+			succeed[result.OrderNo] = struct{}{}
+		}
+	}
+	for _, diff := range diffList {
+		if _, ok := succeed[strconv.FormatInt(diff.OrderNo, 10)]; ok {
+			diff.AdjustStatus = adjustStatusSuccess
+		}
+	}
+	return errcode.ErrorOk
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"sl.framework.com/game_server/currency/money"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"testing"
+)
+
+func TestBuildResettleDiff(t *testing.T) {
+	order := &dto.BetDTO{
+		OrderNo:       1,
+		UserId:        1001,
+		Currency:      "USD",
+		WinAmount:     money.FromFloat(19.5),
+		WinLostStatus: "Win",
+	}
+
+	//修正后由赢变输 扣回已派彩金额
+	diff := buildResettleDiff(order, &types.SettleDTO{OrderNo: 1, WinAmount: money.FromFloat(0), WinLostStatus: "Lose"})
+	if diff.AdjustAmount.Cmp(money.FromFloat(-19.5)) != 0 || diff.AdjustStatus != "" {
+		t.Errorf("buildResettleDiff(Lose) = %+v, want adjust -19.5", diff)
+	}
+	if diff.PreviousWinLostStatus != "Win" || diff.WinLostStatus != "Lose" {
+		t.Errorf("buildResettleDiff(Lose) status = %v->%v", diff.PreviousWinLostStatus, diff.WinLostStatus)
+	}
+
+	//派彩金额按币种截取后没有变化 无需补差
+	diff = buildResettleDiff(order, &types.SettleDTO{OrderNo: 1, WinAmount: money.FromFloat(19.509), WinLostStatus: "Win"})
+	if !diff.AdjustAmount.IsZero() || diff.AdjustStatus != adjustStatusNone {
+		t.Errorf("buildResettleDiff(unchanged) = %+v, want no adjust", diff)
+	}
+}
+
+func TestApplyResettle(t *testing.T) {
+	settleDTO := &types.SettleDTO{OrderNo: 1, WinAmount: money.FromFloat(0), WinLostStatus: "Lose"}
+	order := &dto.BetDTO{OrderNo: 1, Currency: "USD", WinAmount: money.FromFloat(19.5), WinLostStatus: "Win"}
+	applyResettle(order, settleDTO, buildResettleDiff(order, settleDTO))
+	if !order.WinAmount.IsZero() || order.WinLostStatus != "Lose" || order.PostStatus != "Resettle" {
+		t.Errorf("applyResettle = %+v, want resettled to Lose", order)
+	}
+
+	//差额无法计算 保留原派彩 派奖状态为失败
+	order = &dto.BetDTO{OrderNo: 1, Currency: "USD", WinAmount: money.FromFloat(19.5), WinLostStatus: "Win"}
+	applyResettle(order, settleDTO, &types.ResettleOrderDTO{OrderNo: 1, WinAmount: money.FromFloat(0), AdjustStatus: adjustStatusFailed})
+	if order.WinAmount.Cmp(money.FromFloat(19.5)) != 0 || order.WinLostStatus != "Win" || order.PostStatus != "Failed" {
+		t.Errorf("applyResettle(failed) = %+v, want previous win amount kept", order)
+	}
+}
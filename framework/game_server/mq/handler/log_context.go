@@ -0,0 +1,12 @@
+package handler
+
+import (
+	"context"
+	"sl.framework.com/trace"
+)
+
+// roundLogContext MQ消息的日志上下文 之后通过trace.FromContext打印的日志自动带上traceId roomId roundId
+func roundLogContext(traceId string, gameRoomId, gameRoundId int64) context.Context {
+	ctx := trace.WithTraceId(context.Background(), traceId)
+	return trace.WithRoundId(trace.WithRoomId(ctx, gameRoomId), gameRoundId)
+}
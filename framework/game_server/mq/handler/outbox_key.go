@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"fmt"
+	types "sl.framework.com/game_server/game/service/type"
+)
+
+/**
+ * settleBizKey
+ * 分片派彩请求的发件箱业务键 同一分片重复投递时只派彩一次
+ * 重新结算按批次id区分 每个批次都会重新派彩
+ *
+ * @param dto *types.GameDrawDataDTO - 开奖分片消息
+ * @return string - 发件箱业务键
+ */
+
+func settleBizKey(dto *types.GameDrawDataDTO) string {
+	if dto.Resettle {
+		return fmt.Sprintf("resettle-%v-%v-%v-%v", dto.GameRoomId, dto.GameRoundId, dto.ResettleId, dto.ShardNo)
+	}
+	return fmt.Sprintf("settle-%v-%v-%v", dto.GameRoomId, dto.GameRoundId, dto.ShardNo)
+}
+
+/**
+ * receiptBizKey
+ * 分片结算小票的发件箱业务键 同一玩家同一分片的小票只发送一次
+ *
+ * @param dto *types.GameDrawDataDTO - 开奖分片消息
+ * @param userId int64 - 玩家id
+ * @return string - 发件箱业务键
+ */
+
+func receiptBizKey(dto *types.GameDrawDataDTO, userId int64) string {
+	return fmt.Sprintf("%v-receipt-%v", settleBizKey(dto), userId)
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	types "sl.framework.com/game_server/game/service/type"
+	"testing"
+)
+
+func TestOutboxBizKey(t *testing.T) {
+	dto := &types.GameDrawDataDTO{GameRoomId: 1, GameRoundId: 2, ShardNo: 3}
+	if key := settleBizKey(dto); key != "settle-1-2-3" {
+		t.Errorf("settleBizKey() = %v", key)
+	}
+	if key := receiptBizKey(dto, 9); key != "settle-1-2-3-receipt-9" {
+		t.Errorf("receiptBizKey() = %v", key)
+	}
+
+	//每个重新结算批次独立派彩
+	dto.Resettle, dto.ResettleId = true, "r1"
+	if key := settleBizKey(dto); key != "resettle-1-2-r1-3" {
+		t.Errorf("settleBizKey() resettle = %v", key)
+	}
+	dto.ResettleId = "r2"
+	if key := settleBizKey(dto); key != "resettle-1-2-r2-3" {
+		t.Errorf("settleBizKey() next resettle = %v", key)
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	types "sl.framework.com/game_server/game/service/type"
+	"time"
+)
+
+/**
+ * pendingSettleOrders
+ * 从分片注单中去掉结算台账中已经派彩的注单 保持原有顺序
+ *
+ * @param orderList []int64 - 分片注单号列表
+ * @param ledger map[int64]*types.SettleLedgerEntry - 结算台账
+ * @return []int64 - 需要结算的注单号
+ * @return []int64 - 已经派彩跳过的注单号
+ */
+
+func pendingSettleOrders(orderList []int64, ledger map[int64]*types.SettleLedgerEntry) (pending, settled []int64) {
+	pending = make([]int64, 0, len(orderList))
+	for _, orderNo := range orderList {
+		if _, ok := ledger[orderNo]; ok {
+			settled = append(settled, orderNo)
+			continue
+		}
+		pending = append(pending, orderNo)
+	}
+
+	return
+}
+
+/**
+ * buildSettleLedger
+ * 根据派彩成功的结算结果生成台账记录
+ *
+ * @param traceId string - 跟踪id
+ * @param shardNo int - 分片序号
+ * @param settleList []*types.SettleDTO - 已经派彩的结算结果
+ * @param settleTime time.Time - 派彩成功时间
+ * @return []*types.SettleLedgerEntry - 台账记录
+ */
+
+func buildSettleLedger(traceId string, shardNo int, settleList []*types.SettleDTO, settleTime time.Time) []*types.SettleLedgerEntry {
+	entries := make([]*types.SettleLedgerEntry, 0, len(settleList))
+	for _, settle := range settleList {
+		entries = append(entries, &types.SettleLedgerEntry{
+			OrderNo:    settle.OrderNo,
+			ShardNo:    shardNo,
+			TraceId:    traceId,
+			SettleTime: settleTime.UnixMilli(),
+		})
+	}
+
+	return entries
+}
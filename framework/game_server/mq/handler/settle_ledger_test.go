@@ -0,0 +1,37 @@
+package handler
+
+import (
+	types "sl.framework.com/game_server/game/service/type"
+	"testing"
+	"time"
+)
+
+func TestPendingSettleOrders(t *testing.T) {
+	ledger := map[int64]*types.SettleLedgerEntry{2: {OrderNo: 2}, 4: {OrderNo: 4}}
+
+	pending, settled := pendingSettleOrders([]int64{1, 2, 3, 4}, ledger)
+	if len(pending) != 2 || pending[0] != 1 || pending[1] != 3 {
+		t.Errorf("pending = %v, want [1 3]", pending)
+	}
+	if len(settled) != 2 || settled[0] != 2 || settled[1] != 4 {
+		t.Errorf("settled = %v, want [2 4]", settled)
+	}
+
+	pending, settled = pendingSettleOrders([]int64{2, 4}, ledger)
+	if len(pending) != 0 || len(settled) != 2 {
+		t.Errorf("fully settled shard pending=%v settled=%v", pending, settled)
+	}
+}
+
+func TestBuildSettleLedger(t *testing.T) {
+	now := time.UnixMilli(1700000000123)
+	entries := buildSettleLedger("trace", 3, []*types.SettleDTO{{OrderNo: 7}, {OrderNo: 8}}, now)
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %v", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.OrderNo != int64(7+i) || entry.ShardNo != 3 || entry.TraceId != "trace" || entry.SettleTime != 1700000000123 {
+			t.Errorf("entries[%v] = %+v", i, entry)
+		}
+	}
+}
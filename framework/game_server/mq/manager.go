@@ -1,14 +1,14 @@
 package mq
 
 import (
-	"github.com/apache/rocketmq-client-go/v2/rlog"
 	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/game/service"
 	"sl.framework.com/game_server/mq/handler"
+	"sync"
+	"time"
 
 	//"sl.framework.com/game_server/game/service/listenner"
 	"sl.framework.com/trace"
-	"strings"
-	"sync"
 )
 
 // Topic racket messageq 主题类型
@@ -20,22 +20,10 @@ const (
 	TopicBetConfirm       Topic = "bet-confirm"        //游戏提交注单
 	TopicJoinMessageRoom  Topic = "join-message-room"  //玩家进入房间
 	TopicLeaveMessageRoom Topic = "leave-message-room" //玩家离开你房间
-
-	TopicGameDrawOut0 Topic = "game-draw-0" //游戏开奖主题0
-	TopicGameDrawOut1 Topic = "game-draw-1" //游戏开奖主题1
-	TopicGameDrawOut2 Topic = "game-draw-2" //游戏开奖主题2
-	TopicGameDrawOut3 Topic = "game-draw-3" //游戏开奖主题3
-	TopicGameDrawOut4 Topic = "game-draw-4" //游戏开奖主题4
-	TopicGameDrawOut5 Topic = "game-draw-5" //游戏开奖主题5
-	TopicGameDrawOut6 Topic = "game-draw-6" //游戏开奖主题6
-	TopicGameDrawOut7 Topic = "game-draw-7" //游戏开奖主题7
-	TopicGameDrawOut8 Topic = "game-draw-8" //游戏开奖主题8
-	TopicGameDrawOut9 Topic = "game-draw-9" //游戏开奖主题9
-
-	TopicBetConfirmOut0 Topic = "bet-confirm-0" //注单提交主题0
-	TopicBetConfirmOut1 Topic = "bet-confirm-1" //注单提交主题1
 )
 
+// 开奖和提交注单的分片主题由conf.Rocket中的主题列表配置 见GameDrawTopic和BetConfirmTopic
+
 // Property racket mq属性变量
 type Property string
 
@@ -45,276 +33,270 @@ const (
 )
 
 var (
-	consumerInitOnce sync.Once
-	consumerManager  *ConsumerManager // 管理者对象
+	transportInitOnce sync.Once
+	transportMutex    sync.RWMutex
+	transport         Transport // 当前使用的传输层
+
+	subscribedMutex  sync.Mutex
+	subscribedTopics = make(map[string]bool) //已经订阅的分片主题 配置变化时只订阅新增的主题
 )
 
-// ConsumerManager racket消费者的管理对象
-type ConsumerManager struct {
-	consumers []*Consumer //消费者列表
+// producerRefresher 需要预先为发送主题创建生产者的传输层实现 配置变化时创建新增主题的生产者
+type producerRefresher interface {
+	refreshProducers(rocket conf.Rocket) bool
 }
 
 /**
- * stopConsumerManager
- * 关闭所有消费者
+ * newTransport
+ * 根据配置创建传输层
  *
- * @param
- * @return
+ * @param name string - 传输层名称 rocketmq或者memory
+ * @return Transport - 传输层
+ * @return bool - 是否创建成功
  */
 
-func stopConsumerManager() {
-	for _, c := range consumerManager.consumers {
-		c.shutdown()
+func newTransport(name string) (Transport, bool) {
+	switch name {
+	case TransportRocketMQ:
+		return newRocketTransport()
+	case TransportMemory:
+		return NewMemoryTransport(conf.GetRocketMQQueueMaxLen()), true
+	default:
+		trace.Error("newTransport unknown transport=%v", name)
+		return nil, false
 	}
 }
 
 /**
- * initConsumerManger
- * Racket管理者初始化 供外部调用
+ * SetTransport
+ * 设置当前使用的传输层 返回之前的传输层 供测试替换传输层使用
  *
- * @param
- * @return
+ * @param t Transport - 新的传输层
+ * @return Transport - 之前的传输层
  */
 
-func initConsumerManger() (ok bool) {
-	trace.Info("initConsumerManger...")
-	consumerInitOnce.Do(func() {
-		ok = consumerManagerInitOnce()
-	})
+func SetTransport(t Transport) Transport {
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
 
-	return
+	old := transport
+	transport = t
+	if t != nil {
+		gate.reset()
+	}
+	return old
+}
+
+// currentTransport 获取当前使用的传输层
+func currentTransport() Transport {
+	transportMutex.RLock()
+	defer transportMutex.RUnlock()
+
+	return transport
 }
 
 /**
- * consumerManagerInitOnce
- * mq消息管理者初始化
+ * subscribeConfiguredTopics
+ * 订阅配置中的开奖和提交注单分片主题 已经订阅的主题跳过
+ * 消费者的处理函数由主题所在的配置列表决定 分片数量完全由配置控制
  *
- * @param
- * @return
+ * @param rocket conf.Rocket - 消息队列配置
+ * @return bool - 是否全部订阅成功
  */
 
-func consumerManagerInitOnce() (ok bool) {
-	nameServer := conf.GetRocketMQNameServer()
-	//gameServerEventGroup := conf.GetGameServerEventGroup()
-	//gameDrawGroup := conf.GetGameDrawGroup()
-	joinMessageRoom := conf.GetJoinMessageRoomGroup()
-	leaveMessageRoom := conf.GetLeaveMessageRoomGroup()
-	retries := conf.GetRocketMQRetries()
-	trace.Info("consumerManagerInitOnce nameServer=%v", nameServer)
-	//workaround:域名需要增加http://头否则解析失败 与java保持一致 java用法没有这个http://
-	if len(nameServer) > 0 && !strings.HasPrefix(nameServer[0], "http") {
-		nameServer[0] = "http://" + nameServer[0]
-	}
-	if len(nameServer) <= 0 || retries == 0 {
-		trace.Error("rocketMQInit failed, nameServer=%v,  joinMessageRoom=%v, "+
-			"leaveMessageRoom=%v, retries=%v", nameServer, joinMessageRoom,
-			leaveMessageRoom, retries)
-		return false
-	}
-
-	//设置日志打印级别 打印>=warn
-	//日志级别:trace<debug<info<warning<error<fatal<panic
-	rlog.SetLogLevel("warn")
-
-	for _, topicDTO := range conf.ServerConf.Rocketmq.GameDrawTopicsIn {
-		ok = RegistMQConsumer(Topic(topicDTO.TopicName), topicDTO.TopicGroup)
-		if !ok {
-			return false
+func subscribeConfiguredTopics(rocket conf.Rocket) (ok bool) {
+	ok = true
+	for _, topicDTO := range rocket.GameDrawTopicsIn {
+		if ok = subscribeTopicOnce(topicDTO, handler.OnGameDrawHandler); !ok {
+			return
 		}
 	}
 
-	for _, topicDTO := range conf.ServerConf.Rocketmq.BetConfirmTopicsIn {
-		ok = RegistMQConsumer(Topic(topicDTO.TopicName), topicDTO.TopicGroup)
-		if !ok {
-			return false
+	for _, topicDTO := range rocket.BetConfirmTopicsIn {
+		if ok = subscribeTopicOnce(topicDTO, handler.OnBetConfirmHandler); !ok {
+			return
 		}
 	}
-	return ok
+
+	return
 }
 
-// 根据topic创建不同给的消费者
-func RegistMQConsumer(topic Topic, topGroup string) (ok bool) {
-	nameServer := conf.GetRocketMQNameServer()
-	if len(nameServer) > 0 && !strings.HasPrefix(nameServer[0], "http://") {
-		nameServer[0] = "http://" + nameServer[0]
-	}
-	trace.Info("RegistMQConsumer nameServer=%v, topic=%v,topGroup=%v", nameServer, topic, topGroup)
-	// 创建消费者管理对象
-	var c *Consumer
-	consumerManager = &ConsumerManager{
-		consumers: make([]*Consumer, 0, 4),
-	}
+// subscribeTopicOnce 订阅还没有订阅过的分片主题
+func subscribeTopicOnce(topicDTO conf.TopicConfigItem, onMessage fnOnMessage) bool {
+	subscribedMutex.Lock()
+	defer subscribedMutex.Unlock()
 
-	switch topic {
-	case TopicGameDrawOut0, TopicGameDrawOut1, TopicGameDrawOut2,
-		TopicGameDrawOut3, TopicGameDrawOut4, TopicGameDrawOut5,
-		TopicGameDrawOut6, TopicGameDrawOut7, TopicGameDrawOut8,
-		TopicGameDrawOut9:
-		// game-draw 结算事件消费者
-		if c, ok = newConsumer(nameServer, topic, topGroup, cluster,
-			handler.OnGameDrawHandler, conf.GetRocketMQRetries()); !ok {
-			return ok
-		}
-	case TopicBetConfirmOut0, TopicBetConfirmOut1:
-		if c, ok = newConsumer(nameServer, topic, topGroup, cluster,
-			handler.OnBetConfirmHandler, conf.GetRocketMQRetries()); !ok {
-			return ok
-		}
-	default:
-		trace.Info("RegistMQConsumer nameServer=%v, topic=%v,topGroup=%v default ", nameServer, topic, topGroup)
+	if subscribedTopics[topicDTO.TopicName] {
+		return true
 	}
+	if !RegistMQConsumer(Topic(topicDTO.TopicName), topicDTO.TopicGroup, onMessage) {
+		return false
+	}
+	subscribedTopics[topicDTO.TopicName] = true
 
-	consumerManager.consumers = append(consumerManager.consumers, c)
-	return ok
-}
-
-var (
-	producerInitOnce sync.Once
-	producerManager  *ProducerManager // 生产者管理对象
-)
-
-// ProducerManager rocket生产者的管理对象
-type ProducerManager struct {
-	producers map[Topic]*Producer //生产者映射 map[string]*Producer
+	return true
 }
 
 /**
- * stopProducerManager
- * 关闭所有生产者
+ * onRocketMQChange
+ * 消息队列配置变化 订阅新增的消费主题并为新增的发送主题创建生产者
+ * 传输层 name server变化以及移除的消费主题需要重启才能生效
  *
- * @param
+ * @param old *conf.Configuration - 变化前的配置
+ * @param new *conf.Configuration - 变化后的配置
  * @return
  */
 
-func stopProducerManager() {
-	for _, c := range producerManager.producers {
-		c.shutdown()
+func onRocketMQChange(old, new *conf.Configuration) {
+	t := currentTransport()
+	if t == nil {
+		return
+	}
+	if old.Rocketmq.Transport != new.Rocketmq.Transport || old.Rocketmq.NameServer != new.Rocketmq.NameServer {
+		trace.Notice("onRocketMQChange transport or nameServer changed, restart to take effect, transport=%v, nameServer=%v",
+			new.Rocketmq.Transport, new.Rocketmq.NameServer)
 	}
-}
-
-/**
- * initConsumerManger
- * Racket管理者初始化 供外部调用
- *
- * @param
- * @return
- */
-
-func initProducerManger() (ok bool) {
-	trace.Info("initProducerManger...")
-	producerInitOnce.Do(func() {
-		ok = producerManagerInitOnce()
-	})
 
-	return
+	if refresher, ok := t.(producerRefresher); ok && !refresher.refreshProducers(new.Rocketmq) {
+		trace.Error("onRocketMQChange create producers for new topics failed")
+	}
+	if !subscribeConfiguredTopics(new.Rocketmq) {
+		trace.Error("onRocketMQChange subscribe new topics failed")
+	}
 }
 
 /**
- * producerManagerInitOnce
- * mq生产者管理者初始化
+ * RegistMQConsumer
+ * 订阅分片主题 顺序消费 同一队列内的消息按发送顺序消费
  *
- * @param
- * @return
+ * @param topic Topic - 分片主题
+ * @param topGroup string - 消费者组
+ * @param onMessage fnOnMessage - 消息处理函数
+ * @return bool - 是否订阅成功
  */
 
-func producerManagerInitOnce() (ok bool) {
-	nameServer := conf.GetRocketMQNameServer()
-	retries := conf.GetRocketMQRetries()
-	trace.Info("producerManagerInitOnce nameServer=%v", nameServer)
-	//workaround:域名需要增加http://头否则解析失败 与java保持一致 java用法没有这个http://
-	if len(nameServer) > 0 && !strings.HasPrefix(nameServer[0], "http://") {
-		nameServer[0] = "http://" + nameServer[0]
-	}
-	if len(nameServer) <= 0 || retries == 0 {
-		trace.Error("rocketMQInit failed, nameServer=%v, retries=%v", nameServer, retries)
-		return
-	}
-
-	//设置日志打印级别 打印>=warn
-	//日志级别:trace<debug<info<warning<error<fatal<panic
-	rlog.SetLogLevel("warn")
-
-	// 创建生产者管理对象
-	var p *Producer
-	producerManager = &ProducerManager{
-		producers: make(map[Topic]*Producer),
+func RegistMQConsumer(topic Topic, topGroup string, onMessage fnOnMessage) bool {
+	t := currentTransport()
+	if t == nil {
+		trace.Error("RegistMQConsumer no transport, topic=%v, topGroup=%v", topic, topGroup)
+		return false
 	}
 
-	// 创建生产者
-	//gameDrawGroup := conf.GetGameDrawGroup()
-	for _, item := range conf.ServerConf.Rocketmq.GameDrawTopicsOut {
-		if p, ok = newProducer(nameServer, TopicGameDraw, item.TopicName, conf.GetRocketMQRetries()); !ok {
-			trace.Error("producerManagerInitOnce failed, nameServer=%v, retries=%v topicname:%v", nameServer, retries, item.TopicName)
-			return
-		}
-		topicName := Topic(item.TopicName)
-		producerManager.producers[topicName] = p
+	sub := Subscription{
+		Topic:   string(topic),
+		Group:   topGroup,
+		Tags:    service.BuildMQTags(),
+		Orderly: true,
+		Retries: conf.GetRocketMQRetries(),
+		Handler: onMessage,
 	}
-	for _, item := range conf.ServerConf.Rocketmq.BetConfirmTopicsOut {
-		if p, ok = newProducer(nameServer, TopicBetConfirm, item.TopicName, conf.GetRocketMQRetries()); !ok {
-			trace.Error("producerManagerInitOnce failed, nameServer=%v, retries=%v topicname:%v", nameServer, retries, item.TopicName)
-			return
-		}
-		topicName := Topic(item.TopicName)
-		producerManager.producers[topicName] = p
+	if err := t.Subscribe(sub); err != nil {
+		trace.Error("RegistMQConsumer subscribe failed, topic=%v, topGroup=%v, error=%v", topic, topGroup, err.Error())
+		return false
 	}
-	//listenner.SetMessageSender(SendMessage)
+	trace.Info("RegistMQConsumer topic=%v, topGroup=%v, tags=%v", topic, topGroup, sub.Tags)
 
 	return true
 }
 
 /**
  * SendMessage
- * 通过生产者发送消息
+ * 通过传输层发送消息
  *
  * @param tag - messageq tag
  * @param traceId - 用于日志跟踪
  * @param topic - 发送消息的主题
+ * @param shardingKey - 顺序消息分区键 相同分区键的消息进入主题内同一个队列 为空则随机选择队列
  * @param message - 要发送的消息
  * @return
  */
 
-func SendMessage(topic, tag, traceId, timestamp string, message string) {
-	t := Topic(topic)
-	producer, ok := producerManager.producers[t]
-	if !ok {
-		trace.Error("SendMessage no producer for topic=%v, message=%v", topic, message)
+func SendMessage(topic, tag, traceId, timestamp, shardingKey string, message string) {
+	t := currentTransport()
+	if t == nil {
+		trace.Error("SendMessage no transport, topic=%v, message=%v", topic, message)
 		return
 	}
 
-	producer.sendMsg(t, tag, traceId, timestamp, message)
+	msg := &Message{
+		Topic:       topic,
+		Tag:         tag,
+		ShardingKey: shardingKey,
+		Properties: map[string]string{
+			string(propertyTraceId):   traceId,
+			string(propertyTimestamp): timestamp,
+		},
+		Body:     []byte(message),
+		BornTime: time.Now(),
+	}
+	if err := t.Publish(msg); err != nil {
+		trace.Error("SendMessage failed, topic=%v, traceId=%v, error=%v, message=%v", topic, traceId, err.Error(), message)
+	}
 }
 
 /**
- * InitRocketManager
- * 初始化rocket mq包括rocket mq的消费者和生产者
+ * InitMQManager
+ * 按配置初始化消息队列传输层 并订阅开奖和提交注单分片主题
  *
  * @param
  * @return bool - 是否初始化成功
  */
 
-func InitRocketManager() bool {
-	okConsumer := initConsumerManger()
-	okProducer := initProducerManger()
+func InitMQManager() (ok bool) {
+	transportInitOnce.Do(func() {
+		name := conf.GetMQTransport()
+		trace.Info("InitMQManager transport=%v", name)
+
+		var t Transport
+		if t, ok = newTransport(name); !ok {
+			return
+		}
+		SetTransport(t)
+
+		//关闭启动成功的消费者或者生产者
+		if ok = subscribeConfiguredTopics(conf.GetRocketMQ()); !ok {
+			StopMQManager()
+			return
+		}
+		conf.OnChange(conf.SectionRocketmq, onRocketMQChange)
+	})
 
-	//关闭启动成功的消费者或者生产者
-	if !(okConsumer && okProducer) {
-		stopConsumerManager()
-		stopProducerManager()
+	return
+}
+
+/**
+ * Check
+ * 检测消息队列是否正常 供健康检查使用
+ *
+ * @return error - 传输层已经关闭或者消费者没有运行
+ */
+
+func Check() error {
+	t := currentTransport()
+	if t == nil {
+		return errTransportClosed
+	}
+	if c, ok := t.(checker); ok {
+		return c.Check()
 	}
 
-	return okConsumer && okProducer
+	return nil
 }
 
 /**
- * StopRocketManager
- * 关闭rocket mq包括rocket mq的消费者和生产者
+ * StopMQManager
+ * 关闭消息队列传输层包括消费者和生产者
  *
  * @param
  * @return
  */
 
-func StopRocketManager() {
-	stopConsumerManager()
-	stopProducerManager()
+func StopMQManager() {
+	if t := SetTransport(nil); t != nil {
+		t.Shutdown()
+	}
+
+	subscribedMutex.Lock()
+	subscribedTopics = make(map[string]bool)
+	subscribedMutex.Unlock()
 }
@@ -0,0 +1,202 @@
+package mq
+
+import (
+	"sl.framework.com/async"
+	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/trace"
+	"sync"
+	"time"
+)
+
+// memoryRetryInterval 进程内传输层处理失败后重新投递的间隔
+const memoryRetryInterval = 100 * time.Millisecond
+
+type (
+	/*
+		memoryTransport 进程内传输层 基于channel实现
+		每个订阅一个队列和一个消费协程 同一订阅内的消息严格按发送顺序处理
+		处理失败按Retries重新投递 超过重试次数后丢弃 对应rocketmq的死信队列
+	*/
+
+	memoryTransport struct {
+		mutex         sync.RWMutex
+		queueLen      int                            //每个订阅的队列长度
+		retryInterval time.Duration                  //重新投递间隔
+		subscribers   map[string][]*memorySubscriber //订阅映射 map[topic][]*memorySubscriber
+		closed        bool                           //是否已经关闭
+		wg            sync.WaitGroup                 //等待消费协程退出
+	}
+
+	// memorySubscriber 进程内订阅者
+	memorySubscriber struct {
+		sub   Subscription
+		queue chan *Message
+	}
+)
+
+/**
+ * NewMemoryTransport
+ * 创建进程内传输层
+ *
+ * @param queueLen int - 每个订阅的队列长度 队列满时发送失败
+ * @return Transport - 进程内传输层
+ */
+
+func NewMemoryTransport(queueLen int) Transport {
+	if queueLen <= 0 {
+		queueLen = 500
+	}
+
+	return &memoryTransport{
+		queueLen:      queueLen,
+		retryInterval: memoryRetryInterval,
+		subscribers:   make(map[string][]*memorySubscriber),
+	}
+}
+
+/**
+ * Publish
+ * 将消息投递到该主题下tag匹配的所有订阅
+ *
+ * @param msg *Message - 要发送的消息
+ * @return error - 传输层已关闭或者订阅队列已满
+ */
+
+func (t *memoryTransport) Publish(msg *Message) error {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	if t.closed {
+		return errTransportClosed
+	}
+
+	var err error
+	subscribers := t.subscribers[msg.Topic]
+	if len(subscribers) == 0 {
+		trace.Notice("memoryTransport Publish no subscriber, topic=%v, tag=%v", msg.Topic, msg.Tag)
+	}
+	for _, s := range subscribers {
+		if !matchTags(s.sub.Tags, msg.Tag) {
+			continue
+		}
+		select {
+		case s.queue <- msg:
+		default:
+			trace.Error("memoryTransport Publish queue is full, topic=%v, group=%v", s.sub.Topic, s.sub.Group)
+			err = errQueueFull
+		}
+	}
+
+	return err
+}
+
+/**
+ * Subscribe
+ * 订阅主题并启动消费协程
+ *
+ * @param sub Subscription - 订阅信息
+ * @return error - 传输层已关闭
+ */
+
+func (t *memoryTransport) Subscribe(sub Subscription) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		return errTransportClosed
+	}
+
+	s := &memorySubscriber{sub: sub, queue: make(chan *Message, t.queueLen)}
+	t.subscribers[sub.Topic] = append(t.subscribers[sub.Topic], s)
+
+	t.wg.Add(1)
+	async.AsyncRunCoroutine(func() {
+		defer t.wg.Done()
+		for msg := range s.queue {
+			t.deliver(s, msg)
+		}
+	})
+
+	return nil
+}
+
+/**
+ * deliver
+ * 投递一条消息 处理失败时按订阅的重试次数重新投递
+ *
+ * @param s *memorySubscriber - 订阅者
+ * @param msg *Message - 消息
+ * @return
+ */
+
+func (t *memoryTransport) deliver(s *memorySubscriber, msg *Message) {
+	for attempt := 0; ; attempt++ {
+		code := safeConsume(s, msg)
+		if isConsumeOk(code) {
+			return
+		}
+		//进程内消息不能转给其他节点 停止消费后不再重试
+		if code == errcode.GameErrorServerStopping {
+			trace.Notice("memoryTransport deliver dropped on stopping, topic=%v, group=%v, traceId=%v",
+				s.sub.Topic, s.sub.Group, msg.Property(propertyTraceId))
+			return
+		}
+		if attempt >= s.sub.Retries {
+			trace.Error("memoryTransport deliver give up, topic=%v, group=%v, traceId=%v, attempts=%v, code=%v",
+				s.sub.Topic, s.sub.Group, msg.Property(propertyTraceId), attempt+1, code)
+			return
+		}
+		trace.Notice("memoryTransport deliver retry later, topic=%v, group=%v, traceId=%v, attempt=%v, code=%v",
+			s.sub.Topic, s.sub.Group, msg.Property(propertyTraceId), attempt+1, code)
+		time.Sleep(t.retryInterval)
+	}
+}
+
+// safeConsume 处理函数panic时视为处理失败 避免消费协程退出后订阅队列阻塞
+func safeConsume(s *memorySubscriber, msg *Message) (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			trace.Error("memoryTransport consume panic, topic=%v, group=%v, traceId=%v, panic=%v",
+				s.sub.Topic, s.sub.Group, msg.Property(propertyTraceId), r)
+			code = errcode.ErrorUnknown
+		}
+	}()
+
+	return consumeMessage(&s.sub, msg)
+}
+
+// Check 传输层关闭后返回错误
+func (t *memoryTransport) Check() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		return errTransportClosed
+	}
+	return nil
+}
+
+/**
+ * Shutdown
+ * 关闭传输层 已入队的消息处理完毕后返回
+ *
+ * @param
+ * @return
+ */
+
+func (t *memoryTransport) Shutdown() {
+	t.mutex.Lock()
+	if t.closed {
+		t.mutex.Unlock()
+		return
+	}
+	t.closed = true
+	for _, subscribers := range t.subscribers {
+		for _, s := range subscribers {
+			close(s.queue)
+		}
+	}
+	t.mutex.Unlock()
+
+	t.wg.Wait()
+}
@@ -0,0 +1,154 @@
+package mq
+
+import (
+	"sl.framework.com/game_server/error_code"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestMessage(topic, tag, body string) *Message {
+	return &Message{
+		Topic:      topic,
+		Tag:        tag,
+		Properties: map[string]string{string(propertyTraceId): "trace-" + body},
+		Body:       []byte(body),
+		BornTime:   time.Now(),
+	}
+}
+
+func TestMemoryTransportOrderAndTags(t *testing.T) {
+	tr := NewMemoryTransport(16)
+
+	var (
+		mutex    sync.Mutex
+		received []string
+		traceIds []string
+	)
+	err := tr.Subscribe(Subscription{
+		Topic: "game-draw-0",
+		Group: "g",
+		Tags:  "1||2",
+		Handler: func(traceId string, body []byte) int {
+			mutex.Lock()
+			received = append(received, string(body))
+			traceIds = append(traceIds, traceId)
+			mutex.Unlock()
+			return errcode.ErrorOk
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []*Message{
+		newTestMessage("game-draw-0", "1", "a"),
+		newTestMessage("game-draw-0", "3", "skipped"), //tag不匹配
+		newTestMessage("game-draw-1", "1", "other"),   //其他主题
+		newTestMessage("game-draw-0", "2", "b"),
+		newTestMessage("game-draw-0", "1", "c"),
+	} {
+		if err = tr.Publish(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//关闭时等待已入队消息处理完毕
+	tr.Shutdown()
+
+	if got := len(received); got != 3 || received[0] != "a" || received[1] != "b" || received[2] != "c" {
+		t.Fatalf("received = %v, want [a b c]", received)
+	}
+	if traceIds[0] != "trace-a" {
+		t.Fatalf("traceId = %v, want trace-a", traceIds[0])
+	}
+	if err = tr.Publish(newTestMessage("game-draw-0", "1", "late")); err != errTransportClosed {
+		t.Fatalf("Publish after Shutdown error = %v", err)
+	}
+	if err = tr.Subscribe(Subscription{Topic: "game-draw-0"}); err != errTransportClosed {
+		t.Fatalf("Subscribe after Shutdown error = %v", err)
+	}
+}
+
+func TestMemoryTransportRetry(t *testing.T) {
+	tr := NewMemoryTransport(16).(*memoryTransport)
+	tr.retryInterval = time.Millisecond
+
+	attempts := map[string]int{}
+	handler := func(traceId string, body []byte) int {
+		attempts[string(body)]++
+		switch string(body) {
+		case "panic":
+			panic("handler panic")
+		case "flaky":
+			if attempts["flaky"] < 2 {
+				return errcode.ErrorUnknown
+			}
+		case "broken":
+			return errcode.ErrorUnknown
+		}
+		return errcode.ErrorOk
+	}
+	if err := tr.Subscribe(Subscription{Topic: "bet-confirm-0", Retries: 2, Handler: handler}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{"flaky", "broken", "panic", "ok"} {
+		if err := tr.Publish(newTestMessage("bet-confirm-0", "1", body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr.Shutdown()
+
+	//失败后重新投递 最多投递1+Retries次 panic视为处理失败
+	want := map[string]int{"flaky": 2, "broken": 3, "panic": 3, "ok": 1}
+	for body, n := range want {
+		if attempts[body] != n {
+			t.Errorf("attempts[%v] = %v, want %v", body, attempts[body], n)
+		}
+	}
+}
+
+func TestMemoryTransportQueueFull(t *testing.T) {
+	tr := NewMemoryTransport(1)
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	err := tr.Subscribe(Subscription{Topic: "game-draw-0", Handler: func(string, []byte) int {
+		started <- struct{}{}
+		<-release
+		return errcode.ErrorOk
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = tr.Publish(newTestMessage("game-draw-0", "1", "a"))
+	<-started //第一条消息正在处理
+	if err = tr.Publish(newTestMessage("game-draw-0", "1", "b")); err != nil {
+		t.Fatalf("Publish queued error = %v", err)
+	}
+	if err = tr.Publish(newTestMessage("game-draw-0", "1", "c")); err != errQueueFull {
+		t.Fatalf("Publish on full queue error = %v, want errQueueFull", err)
+	}
+	close(release)
+	tr.Shutdown()
+}
+
+func TestMatchTags(t *testing.T) {
+	tests := []struct {
+		expression, tag string
+		want            bool
+	}{
+		{"", "1", true},
+		{"*", "1", true},
+		{"1", "1", true},
+		{"1||2", "2", true},
+		{"1 || 2", "2", true},
+		{"1||2", "12", false},
+		{"1", "", false},
+	}
+	for _, tt := range tests {
+		if got := matchTags(tt.expression, tt.tag); got != tt.want {
+			t.Errorf("matchTags(%q, %q) = %v, want %v", tt.expression, tt.tag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package mq
+
+import (
+	"hash/fnv"
+	"sl.framework.com/game_server/conf"
+	"strconv"
+)
+
+/**
+ * GameDrawTopic
+ * 按房间选择开奖分片主题 同一房间固定落在同一个分片主题
+ * 分片数由conf.Rocket.GameDrawTopicsOut配置
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @return string - 分片主题名
+ * @return bool - 是否配置了开奖分片主题
+ */
+
+func GameDrawTopic(gameRoomId int64) (string, bool) {
+	return partitionTopic(conf.GetGameDrawTopicsOut(), strconv.FormatInt(gameRoomId, 10))
+}
+
+/**
+ * BetConfirmTopic
+ * 按房间选择提交注单分片主题 同一房间固定落在同一个分片主题
+ * 分片数由conf.Rocket.BetConfirmTopicsOut配置
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @return string - 分片主题名
+ * @return bool - 是否配置了提交注单分片主题
+ */
+
+func BetConfirmTopic(gameRoomId int64) (string, bool) {
+	return partitionTopic(conf.GetBetConfirmTopicsOut(), strconv.FormatInt(gameRoomId, 10))
+}
+
+/**
+ * RoundShardingKey
+ * 局维度的顺序消息分区键 同一局的分片消息进入主题内同一个队列 保证按发送顺序消费
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局号Id
+ * @return string - 分区键
+ */
+
+func RoundShardingKey(gameRoomId, gameRoundId int64) string {
+	return strconv.FormatInt(gameRoomId, 10) + "-" + strconv.FormatInt(gameRoundId, 10)
+}
+
+// partitionTopic 根据key的稳定hash从主题列表中选择一个主题
+func partitionTopic(topics []string, key string) (string, bool) {
+	if len(topics) == 0 {
+		return "", false
+	}
+
+	return topics[partitionIndex(key, len(topics))], true
+}
+
+// partitionIndex 使用fnv-1a计算key所在分片 与进程和重启无关
+func partitionIndex(key string, partitions int) int {
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(key))
+
+	return int(hasher.Sum32() % uint32(partitions))
+}
@@ -0,0 +1,79 @@
+package mq
+
+import (
+	"sl.framework.com/game_server/conf"
+	"strconv"
+	"testing"
+)
+
+func withRocketConf(t *testing.T, rocket conf.Rocket) {
+	old := conf.SetCurrent(&conf.Configuration{Rocketmq: rocket})
+	t.Cleanup(func() { conf.SetCurrent(old) })
+}
+
+func drawTopics(n int) []conf.TopicConfigItem {
+	items := make([]conf.TopicConfigItem, 0, n)
+	for i := 0; i < n; i++ {
+		items = append(items, conf.TopicConfigItem{TopicName: "game-draw-" + strconv.Itoa(i)})
+	}
+	return items
+}
+
+func TestGameDrawTopicStablePerRoom(t *testing.T) {
+	withRocketConf(t, conf.Rocket{GameDrawTopicsOut: drawTopics(10)})
+
+	for roomId := int64(1); roomId <= 100; roomId++ {
+		first, ok := GameDrawTopic(roomId)
+		if !ok {
+			t.Fatalf("room %v: no topic", roomId)
+		}
+		for i := 0; i < 5; i++ {
+			if topic, _ := GameDrawTopic(roomId); topic != first {
+				t.Fatalf("room %v: topic changed %v -> %v", roomId, first, topic)
+			}
+		}
+	}
+}
+
+func TestGameDrawTopicUsesAllPartitions(t *testing.T) {
+	withRocketConf(t, conf.Rocket{GameDrawTopicsOut: drawTopics(4)})
+
+	used := make(map[string]int)
+	for roomId := int64(1); roomId <= 1000; roomId++ {
+		topic, _ := GameDrawTopic(roomId)
+		used[topic]++
+	}
+	if len(used) != 4 {
+		t.Fatalf("expected 4 partitions used, got %v", used)
+	}
+}
+
+func TestPartitionTopicFollowsConfig(t *testing.T) {
+	withRocketConf(t, conf.Rocket{
+		GameDrawTopicsOut:   drawTopics(1),
+		BetConfirmTopicsOut: []conf.TopicConfigItem{{TopicName: ""}, {TopicName: "bet-confirm-0"}},
+	})
+
+	if topic, ok := GameDrawTopic(42); !ok || topic != "game-draw-0" {
+		t.Fatalf("GameDrawTopic = %v, %v", topic, ok)
+	}
+	// 空主题名被忽略
+	if topic, ok := BetConfirmTopic(42); !ok || topic != "bet-confirm-0" {
+		t.Fatalf("BetConfirmTopic = %v, %v", topic, ok)
+	}
+
+	withRocketConf(t, conf.Rocket{})
+	if _, ok := GameDrawTopic(42); ok {
+		t.Fatal("expected no topic without configured partitions")
+	}
+}
+
+func TestPartitionIndexIsFnv(t *testing.T) {
+	// 固定值 保证分片映射在版本之间不变
+	if got := partitionIndex("1001", 10); got != 7 {
+		t.Fatalf("partitionIndex(1001, 10) = %v", got)
+	}
+	if RoundShardingKey(1001, 77) != "1001-77" {
+		t.Fatalf("RoundShardingKey = %v", RoundShardingKey(1001, 77))
+	}
+}
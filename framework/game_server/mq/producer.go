@@ -7,26 +7,18 @@ import (
 	"github.com/apache/rocketmq-client-go/v2/producer"
 	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/metrics"
 	"sl.framework.com/trace"
 )
 
 type (
-	// RocketMessage 发送消息结构体
-	RocketMessage struct {
-		body      string
-		topic     string
-		tag       string //不同服务器根据tag过滤是否是自己关心的消息
-		traceId   string //用于日志跟踪
-		timestamp string //时间戳
-	}
-
 	// Producer 生产者对象封装
 	Producer struct {
-		producer     rocketmq.Producer   // rocketmq 生产者接口
-		topic        string              // 该生产者对应的主题
-		group        string              // 生产者组
-		messageQueue chan *RocketMessage // 消息队列
-		running      bool                //是否一起启动
+		producer     rocketmq.Producer // rocketmq 生产者接口
+		topic        string            // 该生产者对应的主题
+		group        string            // 生产者组
+		messageQueue chan *Message     // 消息队列
+		running      bool              //是否一起启动
 	}
 )
 
@@ -46,7 +38,7 @@ func newProducer(addrs []string, topic Topic, group string, retries int) (*Produ
 	p := &Producer{
 		topic:        string(topic),
 		group:        group,
-		messageQueue: make(chan *RocketMessage, conf.GetRocketMQQueueMaxLen()),
+		messageQueue: make(chan *Message, conf.GetRocketMQQueueMaxLen()),
 	}
 
 	//创建生产者
@@ -55,6 +47,7 @@ func newProducer(addrs []string, topic Topic, group string, retries int) (*Produ
 		producer.WithNameServer(addrs),
 		producer.WithRetry(retries),
 		producer.WithGroupName(group),
+		producer.WithQueueSelector(producer.NewHashQueueSelector()), //按分区键选择队列 保证同一局消息有序
 	); nil != err {
 		trace.Error("NewRocketProducer create producers failed,  addr=%v, groupName=%v, retries=%v, error=%v",
 			addrs, group, retries, err.Error())
@@ -93,20 +86,28 @@ func (p *Producer) start() {
 
 		trace.Info("Producer start success, group name=%v, topic=%v", p.group, p.topic)
 		for m := range p.messageQueue {
+			metrics.SetMQProduceQueueLength(p.topic, len(p.messageQueue))
 			msg := &primitive.Message{
-				Topic: m.topic,
-				Body:  []byte(m.body),
+				Topic: m.Topic,
+				Body:  m.Body,
+			}
+			msg.WithTag(m.Tag)
+			for key, value := range m.Properties {
+				msg.WithProperty(key, value)
+			}
+			if len(m.ShardingKey) > 0 {
+				msg.WithShardingKey(m.ShardingKey)
 			}
-			msg.WithTag(m.tag)
-			msg.WithProperty(string(propertyTraceId), m.traceId)
-			msg.WithProperty(string(propertyTimestamp), m.timestamp)
-			if result, err := p.producer.SendSync(context.Background(), msg); nil != err {
+			traceId, timestamp := m.Property(propertyTraceId), m.Property(propertyTimestamp)
+			result, err := p.producer.SendSync(context.Background(), msg)
+			metrics.IncMQProduce(m.Topic, err)
+			if nil != err {
 				trace.Error("Producer start send message failed, group name=%v, topic=%v, tag=%v, traceId=%v, "+
-					"timestamp=%v, status=%v, msg id=%v, error=%v", p.group, p.topic, m.tag, m.traceId,
-					m.timestamp, result.Status, result.MsgID, err.Error())
+					"timestamp=%v, error=%v", p.group, p.topic, m.Tag, traceId, timestamp, err.Error())
+				continue
 			}
-			trace.Info("Producer send message done, group name=%v, topic=%v, tag=%v, traceId=%v, timestamp=%v, message=%v",
-				p.group, p.topic, m.tag, m.traceId, m.timestamp, m.body)
+			trace.Info("Producer send message done, group name=%v, topic=%v, tag=%v, traceId=%v, timestamp=%v, status=%v, msg id=%v, message=%v",
+				p.group, p.topic, m.Tag, traceId, timestamp, result.Status, result.MsgID, string(m.Body))
 		}
 	}
 	async.AsyncRunCoroutine(fn)
@@ -138,25 +139,20 @@ func (p *Producer) shutdown() {
 
 /**
  * sendMsg
- * 发送消息rocketmq
+ * 将消息放入发送队列 由生产者协程按入队顺序发送到rocketmq
  *
- * @param topic string - 消息主题
- * @param tag - messageq tag
- * @param traceId - 用于日志跟踪
- * @param msg string - 要发送的消息
- * @return
+ * @param msg *Message - 要发送的消息
+ * @return error - 队列已满返回错误
  */
 
-func (p *Producer) sendMsg(topic Topic, tag, traceId, timestamp, msg string) {
-	if len(p.messageQueue) >= conf.GetRocketMQQueueMaxLen() {
-		trace.Error("sendMsg the queue is full, skip topic=%v, msg=%v", topic, msg)
-		return
+func (p *Producer) sendMsg(msg *Message) error {
+	select {
+	case p.messageQueue <- msg:
+	default:
+		trace.Error("sendMsg the queue is full, skip topic=%v, msg=%v", msg.Topic, string(msg.Body))
+		return errQueueFull
 	}
+	metrics.SetMQProduceQueueLength(p.topic, len(p.messageQueue))
 
-	p.messageQueue <- &RocketMessage{
-		topic:   string(topic),
-		body:    msg,
-		tag:     tag,
-		traceId: traceId,
-	}
+	return nil
 }
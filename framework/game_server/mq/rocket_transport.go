@@ -0,0 +1,188 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"github.com/apache/rocketmq-client-go/v2/rlog"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/trace"
+	"strings"
+	"sync"
+)
+
+/*
+	rocketTransport rocketmq传输层
+	生产者在创建时按配置的分片主题预先创建 消费者在订阅时创建
+*/
+
+type rocketTransport struct {
+	mutex      sync.Mutex
+	nameServer []string            //name server地址
+	producers  map[Topic]*Producer //生产者映射 map[topic]*Producer
+	consumers  []*Consumer         //消费者列表
+}
+
+/**
+ * newRocketTransport
+ * 创建rocketmq传输层 并为开奖和提交注单分片主题创建生产者
+ *
+ * @param
+ * @return *rocketTransport - rocketmq传输层
+ * @return bool - 是否创建成功
+ */
+
+func newRocketTransport() (*rocketTransport, bool) {
+	nameServer := conf.GetRocketMQNameServer()
+	retries := conf.GetRocketMQRetries()
+	trace.Info("newRocketTransport nameServer=%v", nameServer)
+	//workaround:域名需要增加http://头否则解析失败 与java保持一致 java用法没有这个http://
+	if len(nameServer) > 0 && !strings.HasPrefix(nameServer[0], "http") {
+		nameServer[0] = "http://" + nameServer[0]
+	}
+	if len(nameServer) <= 0 || retries == 0 {
+		trace.Error("newRocketTransport failed, nameServer=%v, retries=%v", nameServer, retries)
+		return nil, false
+	}
+
+	//设置日志打印级别 打印>=warn
+	//日志级别:trace<debug<info<warning<error<fatal<panic
+	rlog.SetLogLevel("warn")
+
+	t := &rocketTransport{
+		nameServer: nameServer,
+		producers:  make(map[Topic]*Producer),
+		consumers:  make([]*Consumer, 0, 4),
+	}
+
+	// 创建生产者
+	rocket := conf.GetRocketMQ()
+	if !t.createProducers(TopicGameDraw, rocket.GameDrawTopicsOut, retries) ||
+		!t.createProducers(TopicBetConfirm, rocket.BetConfirmTopicsOut, retries) {
+		t.Shutdown()
+		return nil, false
+	}
+
+	return t, true
+}
+
+// createProducers 为分片主题列表创建生产者 已经有生产者的主题跳过
+func (t *rocketTransport) createProducers(topic Topic, items []conf.TopicConfigItem, retries int) bool {
+	for _, item := range items {
+		if _, exist := t.producers[Topic(item.TopicName)]; exist {
+			continue
+		}
+		p, ok := newProducer(t.nameServer, topic, item.TopicName, retries)
+		if !ok {
+			trace.Error("rocketTransport createProducers failed, nameServer=%v, retries=%v topicname:%v", t.nameServer, retries, item.TopicName)
+			return false
+		}
+		t.producers[Topic(item.TopicName)] = p
+	}
+
+	return true
+}
+
+/**
+ * Publish
+ * 将消息放入对应主题生产者的发送队列
+ *
+ * @param msg *Message - 要发送的消息
+ * @return error - 没有该主题的生产者或者队列已满
+ */
+
+func (t *rocketTransport) Publish(msg *Message) error {
+	t.mutex.Lock()
+	producer, ok := t.producers[Topic(msg.Topic)]
+	t.mutex.Unlock()
+	if !ok {
+		return errNoProducer
+	}
+
+	return producer.sendMsg(msg)
+}
+
+/**
+ * refreshProducers
+ * 配置变化后为新增的发送主题创建生产者 移除的主题保留生产者直到关闭
+ *
+ * @param rocket conf.Rocket - 变化后的消息队列配置
+ * @return bool - 是否全部创建成功
+ */
+
+func (t *rocketTransport) refreshProducers(rocket conf.Rocket) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	retries := conf.GetRocketMQRetries()
+	return t.createProducers(TopicGameDraw, rocket.GameDrawTopicsOut, retries) &&
+		t.createProducers(TopicBetConfirm, rocket.BetConfirmTopicsOut, retries)
+}
+
+/**
+ * Subscribe
+ * 创建消费者并订阅主题
+ *
+ * @param sub Subscription - 订阅信息
+ * @return error - 创建消费者失败
+ */
+
+func (t *rocketTransport) Subscribe(sub Subscription) error {
+	c, ok := newConsumer(t.nameServer, sub, cluster)
+	if !ok {
+		return errSubscribeFailed
+	}
+
+	t.mutex.Lock()
+	t.consumers = append(t.consumers, c)
+	t.mutex.Unlock()
+	return nil
+}
+
+// Check 检测是否有消费者并且全部在运行
+func (t *rocketTransport) Check() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.consumers) == 0 {
+		return errors.New("no consumer subscribed")
+	}
+	for _, c := range t.consumers {
+		if c.consumer == nil || !c.running {
+			return fmt.Errorf("consumer topic=%v, group=%v is not running", c.topic, c.group)
+		}
+	}
+
+	return nil
+}
+
+// Suspend 暂停所有消费者拉取消息 已经拉取的消息由consumeMessage拒绝
+func (t *rocketTransport) Suspend() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, c := range t.consumers {
+		if c.consumer != nil {
+			c.consumer.Suspend()
+		}
+	}
+}
+
+/**
+ * Shutdown
+ * 关闭所有消费者和生产者 先停止消费再停止发送
+ *
+ * @param
+ * @return
+ */
+
+func (t *rocketTransport) Shutdown() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, c := range t.consumers {
+		c.shutdown()
+	}
+	for _, p := range t.producers {
+		p.shutdown()
+	}
+}
@@ -0,0 +1,134 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/metrics"
+	"sl.framework.com/trace"
+	"strings"
+	"time"
+)
+
+// 传输层名称 对应conf.Rocket.Transport
+const (
+	TransportRocketMQ = "rocketmq" //rocketmq传输层 生产环境使用
+	TransportMemory   = "memory"   //进程内传输层 单元测试和本地开发使用
+)
+
+var (
+	errTransportClosed = errors.New("transport closed")
+	errNoProducer      = errors.New("no producer for topic")
+	errQueueFull       = errors.New("send queue is full")
+	errSubscribeFailed = errors.New("subscribe failed")
+)
+
+type (
+	// Message 传输层消息
+	Message struct {
+		Topic       string            //主题
+		Tag         string            //不同服务器根据tag过滤是否是自己关心的消息
+		ShardingKey string            //顺序消息分区键 为空则不保证顺序
+		Properties  map[string]string //消息属性 traceId timestamp等
+		Body        []byte            //消息体
+		BornTime    time.Time         //消息产生时间 用于统计消费延迟
+	}
+
+	// Subscription 订阅信息
+	Subscription struct {
+		Topic   string      //订阅的主题
+		Group   string      //消费者组
+		Tags    string      //tag过滤表达式 多个tag用||分隔 *或者空表示全部
+		Orderly bool        //是否顺序消费
+		Retries int         //处理失败重试次数
+		Handler fnOnMessage //消息处理函数
+	}
+
+	/*
+		Transport 消息队列传输层
+		发送和订阅都与具体的消息队列无关 由conf.Rocket.Transport选择实现
+		处理函数返回非ErrorOk时由传输层按Retries重新投递
+	*/
+
+	Transport interface {
+		// Publish 发送消息 发送是异步的 返回值只表示是否成功入队
+		Publish(msg *Message) error
+		// Subscribe 订阅主题并开始消费
+		Subscribe(sub Subscription) error
+		// Shutdown 关闭所有生产者和消费者
+		Shutdown()
+	}
+)
+
+/**
+ * Property
+ * 获取消息属性
+ *
+ * @param key Property - 属性名
+ * @return string - 属性值 不存在返回空串
+ */
+
+func (m *Message) Property(key Property) string {
+	if m.Properties == nil {
+		return ""
+	}
+
+	return m.Properties[string(key)]
+}
+
+/**
+ * consumeMessage
+ * 调用订阅的处理函数并记录消费指标 各传输层实现共用 停止消费后直接返回GameErrorServerStopping
+ *
+ * @param sub *Subscription - 订阅信息
+ * @param msg *Message - 收到的消息
+ * @return int - 处理函数返回码
+ */
+
+func consumeMessage(sub *Subscription, msg *Message) int {
+	if !gate.enter() {
+		trace.Notice("consumeMessage server is stopping, topic=%v, traceId=%v, retry later", sub.Topic, msg.Property(propertyTraceId))
+		return errcode.GameErrorServerStopping
+	}
+	defer gate.leave()
+
+	if !msg.BornTime.IsZero() {
+		metrics.ObserveMQConsumeLag(sub.Topic, time.Since(msg.BornTime))
+	}
+	code := sub.Handler(msg.Property(propertyTraceId), msg.Body)
+	metrics.IncMQConsume(sub.Topic, code)
+	if !isConsumeOk(code) {
+		ctx := trace.WithTraceId(context.Background(), msg.Property(propertyTraceId))
+		trace.FromContext(ctx).Warning("consumeMessage handle failed, retry later", "topic", sub.Topic, "tag", msg.Tag, "code", code)
+	}
+
+	return code
+}
+
+/**
+ * matchTags
+ * 判断消息tag是否满足订阅的tag过滤表达式 与rocketmq的TAG表达式一致
+ *
+ * @param expression string - 过滤表达式 多个tag用||分隔
+ * @param tag string - 消息tag
+ * @return bool - 是否满足
+ */
+
+func matchTags(expression, tag string) bool {
+	expression = strings.TrimSpace(expression)
+	if len(expression) == 0 || expression == "*" {
+		return true
+	}
+	for _, t := range strings.Split(expression, "||") {
+		if strings.TrimSpace(t) == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isConsumeOk 处理函数是否处理成功
+func isConsumeOk(code int) bool {
+	return code == errcode.ErrorOk
+}
@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	types "sl.framework.com/game_server/game/service/type"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+	memoryStore 进程内发件箱存储
+	语义与dbStore一致 返回的消息都是副本 修改后需要调用Update写回
+*/
+
+type memoryStore struct {
+	mutex    sync.Mutex
+	messages map[int64]*types.OutboxMessage //map[id]*OutboxMessage
+	bizKeys  map[string]int64               //map[bizKey]id
+}
+
+// NewMemoryStore 创建进程内发件箱存储
+func NewMemoryStore() Store {
+	return &memoryStore{
+		messages: make(map[int64]*types.OutboxMessage),
+		bizKeys:  make(map[string]int64),
+	}
+}
+
+func (s *memoryStore) Insert(message *types.OutboxMessage) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.bizKeys[message.BizKey]; ok {
+		return false, nil
+	}
+	stored := *message
+	s.messages[message.Id] = &stored
+	s.bizKeys[message.BizKey] = message.Id
+	return true, nil
+}
+
+func (s *memoryStore) Due(now time.Time, limit int) ([]*types.OutboxMessage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	due := make([]*types.OutboxMessage, 0)
+	for _, message := range s.messages {
+		if message.Status == string(types.OutboxStatusPending) && !message.NextRetryTime.After(now) {
+			copied := *message
+			due = append(due, &copied)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].NextRetryTime.Equal(due[j].NextRetryTime) {
+			return due[i].Id < due[j].Id
+		}
+		return due[i].NextRetryTime.Before(due[j].NextRetryTime)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (s *memoryStore) Claim(id int64, now, until time.Time) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	message, ok := s.messages[id]
+	if !ok || message.Status != string(types.OutboxStatusPending) || message.NextRetryTime.After(now) {
+		return false, nil
+	}
+	message.NextRetryTime = until
+	message.UpdateTime = now
+	return true, nil
+}
+
+func (s *memoryStore) Update(message *types.OutboxMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if stored, ok := s.messages[message.Id]; ok {
+		stored.Status = message.Status
+		stored.RetryCount = message.RetryCount
+		stored.LastCode = message.LastCode
+		stored.NextRetryTime = message.NextRetryTime
+		stored.UpdateTime = message.UpdateTime
+	}
+	return nil
+}
+
+func (s *memoryStore) Stats() (map[string]int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]int64)
+	for _, message := range s.messages {
+		counts[message.Status]++
+	}
+	return counts, nil
+}
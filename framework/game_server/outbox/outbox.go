@@ -0,0 +1,244 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"sl.framework.com/game_server/conf"
+	snowflaker "sl.framework.com/game_server/conf/snow_flake_id"
+	"sl.framework.com/game_server/error_code"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/metrics"
+	"sl.framework.com/trace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+	outbox 外发请求发件箱
+	派彩 小票 局消息等请求先写入发件箱再发送 发送失败由后台任务按退避间隔重试 服务重启后继续重试
+	同一业务键只写入一次 消息重复投递时不会重复发送
+*/
+
+// claimLease 发送消息时的租约时长 租约内其他实例和后台任务不会重复发送 发送进程异常退出后租约到期重新发送
+const claimLease = 2 * time.Minute
+
+// Dispatcher 发件箱消息的发送函数 返回errcode.ErrorOk表示发送成功
+type Dispatcher func(traceId string, payload []byte) int
+
+var (
+	dispatcherMutex sync.RWMutex
+	dispatchers     = make(map[types.OutboxKind]Dispatcher) //map[kind]Dispatcher
+
+	storeMutex sync.Mutex
+	store      Store // 当前使用的存储 第一次使用时根据配置创建
+)
+
+/**
+ * RegisterDispatcher
+ * 注册消息类型对应的发送函数 同一类型重复注册时覆盖
+ *
+ * @param kind types.OutboxKind - 消息类型
+ * @param fn Dispatcher - 发送函数
+ */
+
+func RegisterDispatcher(kind types.OutboxKind, fn Dispatcher) {
+	dispatcherMutex.Lock()
+	defer dispatcherMutex.Unlock()
+	dispatchers[kind] = fn
+}
+
+/**
+ * SetStore
+ * 设置当前使用的存储 返回之前的存储 供测试替换存储使用
+ *
+ * @param s Store - 新的存储
+ * @return Store - 之前的存储
+ */
+
+func SetStore(s Store) Store {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	old := store
+	store = s
+	return old
+}
+
+// currentStore 获取当前使用的存储 未设置时根据配置创建
+func currentStore() Store {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	if store == nil {
+		switch name := conf.GetOutbox().Store; name {
+		case StoreMemory:
+			store = NewMemoryStore()
+		case StoreDB:
+			store = NewDBStore()
+		default:
+			trace.Error("outbox currentStore unknown store=%v, use %v", name, StoreDB)
+			store = NewDBStore()
+		}
+	}
+	return store
+}
+
+/**
+ * Send
+ * 写入发件箱并立即发送一次 发送失败由后台任务重试
+ * 写入发件箱失败时退化为直接发送一次 返回发送结果
+ *
+ * @param traceId string - 跟踪id
+ * @param kind types.OutboxKind - 消息类型
+ * @param bizKey string - 业务唯一键 相同的键只发送一次 为空时每次调用都是新消息
+ * @param payload interface{} - 请求内容 序列化成json后保存
+ * @return int - 写入发件箱成功或者相同业务键已经存在时返回errcode.ErrorOk 调用方不需要再重试
+ */
+
+func Send(traceId string, kind types.OutboxKind, bizKey string, payload interface{}) int {
+	msgHeader := fmt.Sprintf("outbox Send traceId=%v, kind=%v, bizKey=%v", traceId, kind, bizKey)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		trace.Error("%v, json marshal failed, error=%v", msgHeader, err.Error())
+		return errcode.JsonErrorMarshal
+	}
+
+	now := time.Now()
+	id, err := snowflaker.GetSnowFlakeInstance().GetUniqueId()
+	if err != nil {
+		trace.Error("%v, generate message id failed, error=%v", msgHeader, err.Error())
+		return errcode.GameErrorServerStopping
+	}
+	if len(bizKey) == 0 {
+		bizKey = strconv.FormatInt(id, 10)
+	}
+	message := &types.OutboxMessage{
+		Id:            id,
+		TraceId:       traceId,
+		Kind:          string(kind),
+		BizKey:        bizKey,
+		Payload:       string(data),
+		Status:        string(types.OutboxStatusPending),
+		NextRetryTime: now.Add(claimLease), //写入即由本实例持有租约 后台任务不会同时发送
+		CreateTime:    now,
+		UpdateTime:    now,
+	}
+	inserted, err := currentStore().Insert(message)
+	if err != nil {
+		trace.Error("%v, 写入发件箱失败 直接发送一次, error=%v", msgHeader, err.Error())
+		return dispatch(message)
+	}
+	if !inserted {
+		trace.Notice("%v, 相同业务键的消息已经在发件箱中 不再发送", msgHeader)
+		return errcode.ErrorOk
+	}
+
+	deliver(message, conf.GetOutbox())
+	return errcode.ErrorOk
+}
+
+/**
+ * Stats
+ * 按状态统计发件箱消息数量
+ *
+ * @return map[string]int64 - key为状态 pending success failed
+ * @return error - 存储错误
+ */
+
+func Stats() (map[string]int64, error) {
+	return currentStore().Stats()
+}
+
+/**
+ * deliver
+ * 发送已经抢占的消息并把结果写回存储
+ *
+ * @param message *types.OutboxMessage - 发件箱消息
+ * @param cfg conf.Outbox - 发件箱配置
+ */
+
+func deliver(message *types.OutboxMessage, cfg conf.Outbox) {
+	code := dispatch(message)
+	applyResult(message, code, time.Now(), cfg)
+	if err := currentStore().Update(message); err != nil {
+		//写回失败时租约到期后会重新发送 业务方需要能处理重复请求
+		trace.Error("outbox deliver traceId=%v, id=%v, bizKey=%v update failed, error=%v",
+			message.TraceId, message.Id, message.BizKey, err.Error())
+	}
+}
+
+// dispatch 调用消息类型对应的发送函数 发送函数panic时按失败处理
+func dispatch(message *types.OutboxMessage) (code int) {
+	msgHeader := fmt.Sprintf("outbox dispatch traceId=%v, id=%v, kind=%v, bizKey=%v, retryCount=%v",
+		message.TraceId, message.Id, message.Kind, message.BizKey, message.RetryCount)
+	defer func() {
+		if r := recover(); r != nil {
+			trace.Error("%v, dispatcher panic=%v", msgHeader, r)
+			code = errcode.ErrorUnknown
+		}
+		metrics.IncOutboxDispatch(message.Kind, code)
+	}()
+
+	dispatcherMutex.RLock()
+	fn, ok := dispatchers[types.OutboxKind(message.Kind)]
+	dispatcherMutex.RUnlock()
+	if !ok {
+		trace.Error("%v, no dispatcher registered", msgHeader)
+		return errcode.ErrorUnknown
+	}
+
+	if code = fn(message.TraceId, []byte(message.Payload)); code != errcode.ErrorOk {
+		trace.Error("%v, send failed, code=%v", msgHeader, code)
+	}
+	return code
+}
+
+/**
+ * applyResult
+ * 根据发送结果更新消息状态 失败时计算下次重试时间 超过最大重试次数标记为failed
+ *
+ * @param message *types.OutboxMessage - 发件箱消息
+ * @param code int - 发送结果
+ * @param now time.Time - 当前时间
+ * @param cfg conf.Outbox - 发件箱配置
+ */
+
+func applyResult(message *types.OutboxMessage, code int, now time.Time, cfg conf.Outbox) {
+	message.LastCode = code
+	message.UpdateTime = now
+	if code == errcode.ErrorOk {
+		message.Status = string(types.OutboxStatusSuccess)
+		return
+	}
+
+	message.RetryCount++
+	if message.RetryCount >= cfg.MaxRetries {
+		message.Status = string(types.OutboxStatusFailed)
+		trace.Error("outbox applyResult traceId=%v, id=%v, kind=%v, bizKey=%v 超过最大重试次数 需要人工处理, code=%v",
+			message.TraceId, message.Id, message.Kind, message.BizKey, code)
+		return
+	}
+	message.NextRetryTime = now.Add(backoff(message.RetryCount, cfg))
+}
+
+/**
+ * backoff
+ * 计算第retryCount次失败后的重试间隔 从RetryInterval开始按2倍递增 不超过RetryMax
+ *
+ * @param retryCount int - 已失败次数 从1开始
+ * @param cfg conf.Outbox - 发件箱配置
+ * @return time.Duration - 重试间隔
+ */
+
+func backoff(retryCount int, cfg conf.Outbox) time.Duration {
+	interval := time.Duration(cfg.RetryInterval) * time.Millisecond
+	max := time.Duration(cfg.RetryMax) * time.Second
+	for i := 1; i < retryCount && interval < max; i++ {
+		interval *= 2
+	}
+	if interval > max {
+		interval = max
+	}
+	return interval
+}
@@ -0,0 +1,158 @@
+package outbox
+
+import (
+	"errors"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/error_code"
+	types "sl.framework.com/game_server/game/service/type"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testKind 测试用消息类型
+const testKind types.OutboxKind = "test"
+
+// failStore 写入总是失败的存储
+type failStore struct{ Store }
+
+func (failStore) Insert(*types.OutboxMessage) (bool, error) {
+	return false, errors.New("db down")
+}
+
+func setupOutbox(t *testing.T, cfg conf.Outbox, fn Dispatcher) {
+	oldConf := conf.SetCurrent(&conf.Configuration{Outbox: cfg})
+	oldStore := SetStore(NewMemoryStore())
+	RegisterDispatcher(testKind, fn)
+	t.Cleanup(func() {
+		conf.SetCurrent(oldConf)
+		SetStore(oldStore)
+	})
+}
+
+func TestSendDeduplicatesByBizKey(t *testing.T) {
+	var calls int32
+	setupOutbox(t, conf.Outbox{}, func(traceId string, payload []byte) int {
+		if string(payload) != `{"orderNo":1}` {
+			t.Errorf("payload = %s", payload)
+		}
+		atomic.AddInt32(&calls, 1)
+		return errcode.ErrorOk
+	})
+
+	for i := 0; i < 2; i++ {
+		if code := Send("t", testKind, "settle-1", map[string]int{"orderNo": 1}); code != errcode.ErrorOk {
+			t.Fatalf("Send() = %v", code)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("dispatcher calls = %v, want 1", calls)
+	}
+	if stats, _ := Stats(); stats[string(types.OutboxStatusSuccess)] != 1 {
+		t.Errorf("Stats() = %v", stats)
+	}
+}
+
+func TestRetryUntilFailed(t *testing.T) {
+	var calls int32
+	setupOutbox(t, conf.Outbox{MaxRetries: 3, RetryInterval: 10}, func(string, []byte) int {
+		atomic.AddInt32(&calls, 1)
+		return errcode.HttpErrorDataFailed
+	})
+
+	//写入后立即发送一次 失败后等待重试
+	if code := Send("t", testKind, "", 1); code != errcode.ErrorOk {
+		t.Fatalf("Send() = %v", code)
+	}
+	if sent := runOnce(time.Now()); sent != 0 {
+		t.Errorf("runOnce() before backoff sent %v", sent)
+	}
+	for i := 0; i < 3; i++ {
+		runOnce(time.Now().Add(time.Hour))
+	}
+	if calls != 3 {
+		t.Errorf("dispatcher calls = %v, want 3", calls)
+	}
+	stats, _ := Stats()
+	if stats[string(types.OutboxStatusFailed)] != 1 || stats[string(types.OutboxStatusPending)] != 0 {
+		t.Errorf("Stats() = %v, want 1 failed", stats)
+	}
+}
+
+func TestWorkerRetriesPending(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	delivered := make(chan struct{}, 1)
+	setupOutbox(t, conf.Outbox{PollInterval: 5, RetryInterval: 5}, func(string, []byte) int {
+		if fail.Load() {
+			return errcode.HttpErrorDataFailed
+		}
+		delivered <- struct{}{}
+		return errcode.ErrorOk
+	})
+
+	Send("t", testKind, "", 1)
+	fail.Store(false)
+	Start()
+	defer Stop()
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not retry pending message")
+	}
+}
+
+func TestSendWithoutStore(t *testing.T) {
+	setupOutbox(t, conf.Outbox{}, func(string, []byte) int { return errcode.HttpErrorDataFailed })
+	SetStore(failStore{})
+
+	//写入失败时直接发送一次 返回发送结果
+	if code := Send("t", testKind, "", 1); code != errcode.HttpErrorDataFailed {
+		t.Errorf("Send() = %v, want %v", code, errcode.HttpErrorDataFailed)
+	}
+}
+
+func TestDispatchPanic(t *testing.T) {
+	setupOutbox(t, conf.Outbox{}, func(string, []byte) int { panic("boom") })
+
+	if code := dispatch(&types.OutboxMessage{Kind: string(testKind)}); code != errcode.ErrorUnknown {
+		t.Errorf("dispatch() = %v, want %v", code, errcode.ErrorUnknown)
+	}
+	if code := dispatch(&types.OutboxMessage{Kind: "unknown"}); code != errcode.ErrorUnknown {
+		t.Errorf("dispatch() unknown kind = %v", code)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := conf.Outbox{RetryInterval: 1000, RetryMax: 5}
+	expects := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expect := range expects {
+		if got := backoff(i+1, cfg); got != expect {
+			t.Errorf("backoff(%v) = %v, want %v", i+1, got, expect)
+		}
+	}
+	if got := backoff(1000, cfg); got != 5*time.Second {
+		t.Errorf("backoff(1000) = %v", got)
+	}
+}
+
+func TestMemoryStoreClaim(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	message := &types.OutboxMessage{Id: 1, BizKey: "k", Status: string(types.OutboxStatusPending), NextRetryTime: now}
+	if ok, _ := s.Insert(message); !ok {
+		t.Fatal("Insert() failed")
+	}
+	if ok, _ := s.Insert(&types.OutboxMessage{Id: 2, BizKey: "k"}); ok {
+		t.Error("Insert() duplicate bizKey should be ignored")
+	}
+	if ok, _ := s.Claim(1, now, now.Add(time.Minute)); !ok {
+		t.Error("first Claim() failed")
+	}
+	if ok, _ := s.Claim(1, now, now.Add(time.Minute)); ok {
+		t.Error("second Claim() should fail while leased")
+	}
+	if due, _ := s.Due(now.Add(2*time.Minute), 10); len(due) != 1 {
+		t.Errorf("Due() after lease = %v", due)
+	}
+}
@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"sl.framework.com/game_server/game/dao/gamedb"
+	types "sl.framework.com/game_server/game/service/type"
+	"time"
+)
+
+const (
+	StoreDB     = "db"     //使用game db的outbox_message表 生产环境使用
+	StoreMemory = "memory" //进程内存储 重启后丢失 仅用于单元测试和本地开发
+)
+
+/*
+	Store 发件箱存储
+	Insert 写入消息 业务键已经存在时返回false
+	Due 查询到期需要发送的消息
+	Claim 抢占消息 多个实例同时扫描时保证只有一个实例发送
+	Update 更新发送结果
+	Stats 按状态统计消息数量
+*/
+
+type Store interface {
+	Insert(message *types.OutboxMessage) (bool, error)
+	Due(now time.Time, limit int) ([]*types.OutboxMessage, error)
+	Claim(id int64, now, until time.Time) (bool, error)
+	Update(message *types.OutboxMessage) error
+	Stats() (map[string]int64, error)
+}
+
+// dbStore 基于game db的发件箱存储
+type dbStore struct{}
+
+// NewDBStore 创建基于game db的发件箱存储
+func NewDBStore() Store {
+	return dbStore{}
+}
+
+func (dbStore) Insert(message *types.OutboxMessage) (bool, error) {
+	return gamedb.InsertOutboxMessage(message)
+}
+
+func (dbStore) Due(now time.Time, limit int) ([]*types.OutboxMessage, error) {
+	return gamedb.QueryDueOutboxMessages(now, limit)
+}
+
+func (dbStore) Claim(id int64, now, until time.Time) (bool, error) {
+	return gamedb.ClaimOutboxMessage(id, now, until)
+}
+
+func (dbStore) Update(message *types.OutboxMessage) error {
+	return gamedb.UpdateOutboxMessage(message)
+}
+
+func (dbStore) Stats() (map[string]int64, error) {
+	return gamedb.CountOutboxMessages()
+}
@@ -0,0 +1,98 @@
+package outbox
+
+import (
+	"sl.framework.com/async"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/trace"
+	"sync"
+	"time"
+)
+
+var (
+	workerMutex sync.Mutex
+	workerStop  chan struct{} // 关闭后后台任务退出
+	workerDone  chan struct{} // 后台任务退出后关闭
+)
+
+/**
+ * Start
+ * 启动发件箱后台任务 按PollInterval扫描到期的消息并重新发送 重复调用只启动一次
+ */
+
+func Start() {
+	workerMutex.Lock()
+	defer workerMutex.Unlock()
+	if workerStop != nil {
+		return
+	}
+
+	cfg := conf.GetOutbox()
+	stop, done := make(chan struct{}), make(chan struct{})
+	workerStop, workerDone = stop, done
+	async.AsyncRunCoroutine(func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Duration(cfg.PollInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				runOnce(now)
+			}
+		}
+	})
+	trace.Info("outbox Start store=%v, pollInterval=%vms, maxRetries=%v", cfg.Store, cfg.PollInterval, cfg.MaxRetries)
+}
+
+/**
+ * Stop
+ * 停止发件箱后台任务 等待正在发送的消息处理完毕 未发送的消息在下次启动后继续发送
+ */
+
+func Stop() {
+	workerMutex.Lock()
+	stop, done := workerStop, workerDone
+	workerStop, workerDone = nil, nil
+	workerMutex.Unlock()
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+	trace.Info("outbox Stop done")
+}
+
+/**
+ * runOnce
+ * 扫描一次到期的消息 抢占成功的消息依次发送
+ *
+ * @param now time.Time - 当前时间
+ * @return int - 本次发送的消息数
+ */
+
+func runOnce(now time.Time) int {
+	cfg := conf.GetOutbox()
+	s := currentStore()
+	messages, err := s.Due(now, cfg.BatchSize)
+	if err != nil {
+		trace.Error("outbox runOnce query due messages failed, error=%v", err.Error())
+		return 0
+	}
+
+	sent := 0
+	for _, message := range messages {
+		claimed, err := s.Claim(message.Id, now, now.Add(claimLease))
+		if err != nil {
+			trace.Error("outbox runOnce claim id=%v failed, error=%v", message.Id, err.Error())
+			continue
+		}
+		if !claimed { //已经被其他实例抢占
+			continue
+		}
+		deliver(message, cfg)
+		sent++
+	}
+	return sent
+}
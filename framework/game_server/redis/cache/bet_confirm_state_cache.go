@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+	"strconv"
+)
+
+/**
+ * GetBetConfirmStates
+ * 获取局的注单提交状态
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return map[int64]*types.BetConfirmState - 注单提交状态 key为注单号
+ * @return bool - 是否读取成功 读取失败时调用方不能认为注单未提交
+ */
+
+func GetBetConfirmStates(traceId string, gameRoomId, gameRoundId int64) (map[int64]*types.BetConfirmState, bool) {
+	msgHeader := fmt.Sprintf("GetBetConfirmStates traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+	redisInfo := rediskey.GetBetConfirmStateRedisInfo(gameRoomId, gameRoundId)
+	values, err := redisdb.HGetAll(redisInfo.Key)
+	if err != nil {
+		trace.Error("%v, key=%v, HGetAll failed, err=%v", msgHeader, redisInfo.Key, err.Error())
+		return nil, false
+	}
+
+	states := make(map[int64]*types.BetConfirmState, len(values))
+	for field, value := range values {
+		state := new(types.BetConfirmState)
+		if err = json.Unmarshal([]byte(value), state); err != nil {
+			//记录损坏时按提交中处理 先对账再决定是否重新提交 避免重复扣款
+			trace.Error("%v, json unmarshal failed, field=%v, value=%v, err=%v", msgHeader, field, value, err.Error())
+			state.OrderNo, _ = strconv.ParseInt(field, 10, 64)
+			state.Status = string(const_type.ConfirmStatusPosting)
+		}
+		states[state.OrderNo] = state
+	}
+
+	return states, true
+}
+
+/**
+ * PutBetConfirmStates
+ * 记录注单提交状态
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @param states []*types.BetConfirmState - 注单提交状态
+ * @return bool - 是否记录成功
+ */
+
+func PutBetConfirmStates(traceId string, gameRoomId, gameRoundId int64, states []*types.BetConfirmState) bool {
+	if len(states) == 0 {
+		return true
+	}
+	msgHeader := fmt.Sprintf("PutBetConfirmStates traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+
+	values := make(map[string]string, len(states))
+	for _, state := range states {
+		buf, err := json.Marshal(state)
+		if err != nil {
+			trace.Error("%v, json marshal failed, state=%+v, err=%v", msgHeader, state, err.Error())
+			return false
+		}
+		values[strconv.FormatInt(state.OrderNo, 10)] = string(buf)
+	}
+
+	redisInfo := rediskey.GetBetConfirmStateRedisInfo(gameRoomId, gameRoundId)
+	if _, err := redisdb.HSetBatch(redisInfo.Key, values, redisInfo.Expire); err != nil {
+		trace.Error("%v, key=%v, HSetBatch failed, err=%v", msgHeader, redisInfo.Key, err.Error())
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/game/service/type/const_type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"testing"
+)
+
+func TestBetConfirmStates(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	states, ok := GetBetConfirmStates("test", 1, 2)
+	if !ok || len(states) != 0 {
+		t.Fatalf("GetBetConfirmStates() on empty round = %v, %v", states, ok)
+	}
+
+	if !PutBetConfirmStates("test", 1, 2, []*types.BetConfirmState{
+		{OrderNo: 11, UserId: 7, Status: string(const_type.ConfirmStatusPosting), Attempts: 1},
+		{OrderNo: 12, UserId: 7, Status: string(const_type.ConfirmStatusFailed), Attempts: 1},
+	}) {
+		t.Fatal("PutBetConfirmStates() failed")
+	}
+	//状态覆盖更新
+	if !PutBetConfirmStates("test", 1, 2, []*types.BetConfirmState{
+		{OrderNo: 11, UserId: 7, Status: string(const_type.ConfirmStatusConfirmed), Attempts: 1},
+	}) {
+		t.Fatal("PutBetConfirmStates() update failed")
+	}
+	//损坏的记录按提交中处理
+	redisInfo := rediskey.GetBetConfirmStateRedisInfo(1, 2)
+	if _, err := redisdb.HSet(redisInfo.Key, "13", "broken", redisInfo.Expire); err != nil {
+		t.Fatal(err)
+	}
+
+	states, ok = GetBetConfirmStates("test", 1, 2)
+	if !ok || len(states) != 3 {
+		t.Fatalf("GetBetConfirmStates() = %v, %v, want 3 states", states, ok)
+	}
+	expects := map[int64]const_type.ConfirmStatus{
+		11: const_type.ConfirmStatusConfirmed,
+		12: const_type.ConfirmStatusFailed,
+		13: const_type.ConfirmStatusPosting,
+	}
+	for orderNo, expect := range expects {
+		if state := states[orderNo]; state == nil || state.Status != string(expect) {
+			t.Errorf("states[%v] = %+v, want %v", orderNo, state, expect)
+		}
+	}
+}
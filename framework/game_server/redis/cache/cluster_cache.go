@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+	"sort"
+	"time"
+)
+
+/**
+ * HeartbeatClusterNode
+ * 上报节点心跳 更新节点信息和最后心跳时间 不加锁 各节点只写自己的成员
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param node *types.ClusterNode - 节点信息
+ * @param now time.Time - 心跳时间
+ * @return bool - 是否上报成功
+ */
+
+func HeartbeatClusterNode(traceId string, node *types.ClusterNode, now time.Time) bool {
+	msgHeader := fmt.Sprintf("HeartbeatClusterNode traceId=%v, serverId=%v", traceId, node.ServerId)
+	buf, err := json.Marshal(node)
+	if err != nil {
+		trace.Error("%v, json marshal failed, node=%+v, err=%v", msgHeader, node, err.Error())
+		return false
+	}
+
+	//先写节点信息再写成员 其他节点看到成员时节点信息已经存在
+	nodesInfo := rediskey.GetClusterNodesRedisInfo()
+	if _, err = redisdb.HSet(nodesInfo.Key, node.ServerId, string(buf), nodesInfo.Expire); err != nil {
+		trace.Error("%v, key=%v, HSet failed, err=%v", msgHeader, nodesInfo.Key, err.Error())
+		return false
+	}
+	membersInfo := rediskey.GetClusterMembersRedisInfo()
+	if err = redisdb.ZAdd(membersInfo.Key, node.ServerId, float64(now.UnixMilli()), membersInfo.Expire); err != nil {
+		trace.Error("%v, key=%v, ZAdd failed, err=%v", msgHeader, membersInfo.Key, err.Error())
+		return false
+	}
+
+	return true
+}
+
+/**
+ * GetClusterNodes
+ * 获取在线的集群节点 同时清理心跳超时的节点
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param now time.Time - 当前时间
+ * @param expired time.Duration - 心跳超时时间 最后心跳早于now-expired的节点视为下线
+ * @return []*types.ClusterNode - 在线节点 按serverId排序
+ * @return bool - 是否读取成功
+ */
+
+func GetClusterNodes(traceId string, now time.Time, expired time.Duration) ([]*types.ClusterNode, bool) {
+	msgHeader := fmt.Sprintf("GetClusterNodes traceId=%v", traceId)
+	membersInfo := rediskey.GetClusterMembersRedisInfo()
+	nodesInfo := rediskey.GetClusterNodesRedisInfo()
+
+	members, err := redisdb.ZRangeWithScores(membersInfo.Key)
+	if err != nil {
+		trace.Error("%v, key=%v, ZRangeWithScores failed, err=%v", msgHeader, membersInfo.Key, err.Error())
+		return nil, false
+	}
+	values, err := redisdb.HGetAll(nodesInfo.Key)
+	if err != nil {
+		trace.Error("%v, key=%v, HGetAll failed, err=%v", msgHeader, nodesInfo.Key, err.Error())
+		return nil, false
+	}
+
+	deadline := now.Add(-expired).UnixMilli()
+	nodes := make([]*types.ClusterNode, 0, len(members))
+	expiredIds := make([]string, 0)
+	for _, member := range members {
+		serverId, _ := member.Member.(string)
+		if int64(member.Score) < deadline {
+			expiredIds = append(expiredIds, serverId)
+			continue
+		}
+		node := &types.ClusterNode{ServerId: serverId}
+		if value, ok := values[serverId]; ok {
+			if err = json.Unmarshal([]byte(value), node); err != nil {
+				//节点信息损坏时只保留serverId 不影响成员统计
+				trace.Error("%v, json unmarshal failed, serverId=%v, value=%v, err=%v", msgHeader, serverId, value, err.Error())
+				node = &types.ClusterNode{ServerId: serverId}
+			}
+		}
+		node.LastSeen = int64(member.Score)
+		nodes = append(nodes, node)
+	}
+	//按serverId排序 保证各节点看到的成员顺序一致
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ServerId < nodes[j].ServerId })
+
+	//按分数删除 期间重新心跳的节点分数已经更新 不会被误删
+	if len(expiredIds) > 0 {
+		if _, err = redisdb.ZRemRangeByScore(membersInfo.Key, 0, float64(deadline-1)); err != nil {
+			trace.Error("%v, key=%v, ZRemRangeByScore failed, err=%v", msgHeader, membersInfo.Key, err.Error())
+		}
+		for _, serverId := range expiredIds {
+			_, _ = redisdb.HDel(nodesInfo.Key, serverId)
+		}
+		trace.Notice("%v, remove expired cluster nodes=%v", msgHeader, expiredIds)
+	}
+
+	return nodes, true
+}
+
+/**
+ * RemoveClusterNode
+ * 节点主动退出集群
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param serverId string - 服务id
+ * @return bool - 是否删除成功
+ */
+
+func RemoveClusterNode(traceId, serverId string) bool {
+	msgHeader := fmt.Sprintf("RemoveClusterNode traceId=%v, serverId=%v", traceId, serverId)
+	membersInfo := rediskey.GetClusterMembersRedisInfo()
+	if _, err := redisdb.ZRem(membersInfo.Key, serverId); err != nil {
+		trace.Error("%v, key=%v, ZRem failed, err=%v", msgHeader, membersInfo.Key, err.Error())
+		return false
+	}
+	nodesInfo := rediskey.GetClusterNodesRedisInfo()
+	if _, err := redisdb.HDel(nodesInfo.Key, serverId); err != nil {
+		trace.Error("%v, key=%v, HDel failed, err=%v", msgHeader, nodesInfo.Key, err.Error())
+		return false
+	}
+
+	return true
+}
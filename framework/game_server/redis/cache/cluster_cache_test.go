@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"testing"
+	"time"
+)
+
+func TestClusterNodes(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	now := time.UnixMilli(1_700_000_000_000)
+	expired := 6 * time.Second
+	if !HeartbeatClusterNode("test", &types.ClusterNode{ServerId: "2", Ip: "10.0.0.2", Rooms: []int64{7}}, now) {
+		t.Fatal("HeartbeatClusterNode() failed")
+	}
+	if !HeartbeatClusterNode("test", &types.ClusterNode{ServerId: "1", Ip: "10.0.0.1"}, now.Add(-time.Second)) {
+		t.Fatal("HeartbeatClusterNode() failed")
+	}
+	//心跳超时的节点
+	if !HeartbeatClusterNode("test", &types.ClusterNode{ServerId: "3"}, now.Add(-expired-time.Millisecond)) {
+		t.Fatal("HeartbeatClusterNode() failed")
+	}
+
+	nodes, ok := GetClusterNodes("test", now, expired)
+	if !ok || len(nodes) != 2 {
+		t.Fatalf("GetClusterNodes() = %v, %v, want 2 nodes", nodes, ok)
+	}
+	if nodes[0].ServerId != "1" || nodes[0].LastSeen != now.Add(-time.Second).UnixMilli() || nodes[0].Ip != "10.0.0.1" {
+		t.Errorf("nodes[0] = %+v", nodes[0])
+	}
+	if nodes[1].ServerId != "2" || len(nodes[1].Rooms) != 1 || nodes[1].Rooms[0] != 7 {
+		t.Errorf("nodes[1] = %+v", nodes[1])
+	}
+
+	//超时节点已经从有序集合和hash表中删除
+	if value, _ := redisdb.HGet(rediskey.GetClusterNodesRedisInfo().Key, "3"); value != "" {
+		t.Errorf("expired node info not removed, value=%v", value)
+	}
+	members, _ := redisdb.ZRangeWithScores(rediskey.GetClusterMembersRedisInfo().Key)
+	if len(members) != 2 {
+		t.Errorf("members = %v, want 2", members)
+	}
+
+	//节点主动退出
+	if !RemoveClusterNode("test", "2") {
+		t.Fatal("RemoveClusterNode() failed")
+	}
+	nodes, ok = GetClusterNodes("test", now, expired)
+	if !ok || len(nodes) != 1 || nodes[0].ServerId != "1" {
+		t.Fatalf("GetClusterNodes() after remove = %v, %v", nodes, ok)
+	}
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+	"sort"
+	"strconv"
+)
+
+/*
+	动态赔率种子和审计记录缓存
+	数据以game db为准 由fairodds写入数据库后再写缓存 缓存没有时从数据库加载
+*/
+
+/**
+ * SetOddsSeed
+ * 缓存局的动态赔率种子
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param seed *types.DynamicOddsSeedDTO - 已经保存到数据库的种子
+ * @return bool - 是否执行成功
+ */
+
+func SetOddsSeed(traceId string, seed *types.DynamicOddsSeedDTO) bool {
+	msgHeader := fmt.Sprintf("SetOddsSeed traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, seed.GameRoomId, seed.GameRoundId)
+	data, err := json.Marshal(seed)
+	if err != nil {
+		trace.Error("%v, json marshal failed, error=%v", msgHeader, err.Error())
+		return false
+	}
+
+	redisInfo := rediskey.GetDynamicOddsSeedRedisInfo(seed.GameRoomId, seed.GameRoundId)
+	if _, err = redisdb.Set(redisInfo.Key, string(data), redisInfo.Expire); err != nil {
+		trace.Error("%v, key=%v redis Set failed, error=%v", msgHeader, redisInfo.Key, err.Error())
+		return false
+	}
+	return true
+}
+
+/**
+ * GetOddsSeed
+ * 获取缓存的局动态赔率种子
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return *types.DynamicOddsSeedDTO - 种子 没有缓存时为nil
+ * @return bool - 是否存在
+ */
+
+func GetOddsSeed(traceId string, gameRoomId, gameRoundId int64) (*types.DynamicOddsSeedDTO, bool) {
+	msgHeader := fmt.Sprintf("GetOddsSeed traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+	redisInfo := rediskey.GetDynamicOddsSeedRedisInfo(gameRoomId, gameRoundId)
+	val, err := redisdb.Get(redisInfo.Key)
+	if err != nil {
+		trace.Error("%v, key=%v redis Get failed, error=%v", msgHeader, redisInfo.Key, err.Error())
+		return nil, false
+	}
+	if len(val) <= 0 {
+		trace.Notice("%v, key=%v no data", msgHeader, redisInfo.Key)
+		return nil, false
+	}
+
+	seed := new(types.DynamicOddsSeedDTO)
+	if err = json.Unmarshal([]byte(val), seed); err != nil {
+		trace.Error("%v, json unmarshal failed, key=%v, val=%v, err=%v", msgHeader, redisInfo.Key, val, err.Error())
+		return nil, false
+	}
+	return seed, true
+}
+
+/**
+ * PutDynamicOddsAudit
+ * 缓存玩法的动态赔率审计记录
+ *
+ * @param audit *types.DynamicOddsAuditDTO - 审计记录
+ * @return bool - 是否保存成功
+ */
+
+func PutDynamicOddsAudit(audit *types.DynamicOddsAuditDTO) bool {
+	msgHeader := fmt.Sprintf("PutDynamicOddsAudit traceId=%v, gameRoomId=%v, gameRoundId=%v, wagerId=%v",
+		audit.TraceId, audit.GameRoomId, audit.GameRoundId, audit.WagerId)
+	data, err := json.Marshal(audit)
+	if err != nil {
+		trace.Error("%v, json marshal failed, error=%v", msgHeader, err.Error())
+		return false
+	}
+
+	redisInfo := rediskey.GetDynamicOddsAuditRedisInfo(audit.GameRoomId, audit.GameRoundId)
+	if _, err = redisdb.HSet(redisInfo.Key, strconv.FormatInt(audit.WagerId, 10), string(data), redisInfo.Expire); err != nil {
+		trace.Error("%v, key=%v redis HSet failed, error=%v", msgHeader, redisInfo.Key, err.Error())
+		return false
+	}
+	return true
+}
+
+/**
+ * GetDynamicOddsAudits
+ * 获取缓存的局全部动态赔率审计记录
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return []*types.DynamicOddsAuditDTO - 审计记录 按玩法id升序排列
+ * @return bool - 是否执行成功
+ */
+
+func GetDynamicOddsAudits(traceId string, gameRoomId, gameRoundId int64) ([]*types.DynamicOddsAuditDTO, bool) {
+	msgHeader := fmt.Sprintf("GetDynamicOddsAudits traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+	redisInfo := rediskey.GetDynamicOddsAuditRedisInfo(gameRoomId, gameRoundId)
+	val, err := redisdb.HGetAll(redisInfo.Key)
+	if err != nil {
+		trace.Error("%v, key=%v redis HGetAll failed, error=%v", msgHeader, redisInfo.Key, err.Error())
+		return nil, false
+	}
+
+	auditList := make([]*types.DynamicOddsAuditDTO, 0, len(val))
+	for field, member := range val {
+		audit := new(types.DynamicOddsAuditDTO)
+		if err = json.Unmarshal([]byte(member), audit); err != nil {
+			trace.Error("%v, json unmarshal failed, key=%v, field=%v, val=%v, err=%v", msgHeader, redisInfo.Key, field, member, err.Error())
+			return nil, false
+		}
+		auditList = append(auditList, audit)
+	}
+	sort.Slice(auditList, func(i, j int) bool { return auditList[i].WagerId < auditList[j].WagerId })
+
+	return auditList, true
+}
@@ -3,22 +3,124 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/redis/go-redis/v9"
 	"sl.framework.com/game_server/game/dao/redisdb"
 	"sl.framework.com/game_server/game/service/type/dto"
 	"sl.framework.com/game_server/redis/rediskey"
 	"sl.framework.com/trace"
+	"sort"
 	"strconv"
 )
 
+/*
+	注单簿
+	每个玩家每局一个hash表 field为注单号 value为注单json 另有一个局内已下注玩家集合用于按局遍历
+	追加、移除、更新都通过lua脚本在redis中原子完成 避免多节点并发读改写整块json导致注单丢失
+	lua脚本中只做字符串的搬运 不解析json 避免cjson把int64注单号转为double丢失精度
+*/
+
+var (
+	// appendOrderScript 追加注单
+	// KEYS[1] 玩家注单hash表 KEYS[2] 局内玩家集合
+	// ARGV[1] 过期毫秒数 ARGV[2] 用户Id ARGV[3..] 注单号与注单json交替排列
+	appendOrderScript = redis.NewScript(`
+for i = 3, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+redis.call('SADD', KEYS[2], ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+return redis.call('HLEN', KEYS[1])
+`)
+
+	// removeOrderScript 移除注单 返回被移除注单的json 不存在的注单号忽略
+	// KEYS[1] 玩家注单hash表
+	// ARGV[1..] 注单号
+	removeOrderScript = redis.NewScript(`
+local removed = {}
+for i = 1, #ARGV do
+	local val = redis.call('HGET', KEYS[1], ARGV[i])
+	if val then
+		redis.call('HDEL', KEYS[1], ARGV[i])
+		table.insert(removed, val)
+	end
+end
+return removed
+`)
+
+	// updateOrderScript 更新注单 只更新仍然存在的注单 避免把已经取消的注单重新写回
+	// KEYS[1] 玩家注单hash表
+	// ARGV[1] 过期毫秒数 ARGV[2..] 注单号与注单json交替排列
+	updateOrderScript = redis.NewScript(`
+local updated = 0
+for i = 2, #ARGV, 2 do
+	if redis.call('HEXISTS', KEYS[1], ARGV[i]) == 1 then
+		redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+		updated = updated + 1
+	end
+end
+if updated > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return updated
+`)
+)
+
+/**
+ * marshalOrderArgs
+ * 将注单序列化为lua脚本参数 注单号与注单json交替排列
+ *
+ * @param msgHeader string - 日志头
+ * @param orderList []*dto.BetDTO - 注单列表
+ * @return []interface{} - 脚本参数
+ * @return bool - 序列化是否成功
+ */
+
+func marshalOrderArgs(msgHeader string, orderList []*dto.BetDTO) ([]interface{}, bool) {
+	args := make([]interface{}, 0, len(orderList)*2)
+	for _, order := range orderList {
+		data, err := json.Marshal(order)
+		if err != nil {
+			trace.Error("%v, json marshal failed, orderNo=%v, error=%v", msgHeader, order.OrderNo, err.Error())
+			return nil, false
+		}
+		args = append(args, strconv.FormatInt(order.OrderNo, 10), string(data))
+	}
+	return args, true
+}
+
+/**
+ * unmarshalOrders
+ * 解析注单json 并按注单号排序 注单号由雪花算法生成 排序后即为下单顺序
+ *
+ * @param msgHeader string - 日志头
+ * @param values []string - 注单json列表
+ * @return []*dto.BetDTO - 注单列表
+ */
+
+func unmarshalOrders(msgHeader string, values []string) []*dto.BetDTO {
+	orderList := make([]*dto.BetDTO, 0, len(values))
+	for _, val := range values {
+		order := new(dto.BetDTO)
+		if err := json.Unmarshal([]byte(val), order); nil != err {
+			trace.Error("%v, json unmarshal failed, val=%v, err=%v", msgHeader, val, err.Error())
+			continue
+		}
+		orderList = append(orderList, order)
+	}
+	sort.Slice(orderList, func(i, j int) bool { return orderList[i].OrderNo < orderList[j].OrderNo })
+	return orderList
+}
+
 /**
  * GetUserOrder
- * 从缓存hash中获取该用户的订单数据
+ * 从注单簿中获取该用户的订单数据
  *
  * @param traceId string - traceId用于日志跟踪
- * @param gameRoomId int64 - 房间Id
- * @param gameRoundId int64 - 局Id
- * @param userId int64 - 用户ID
- * @return *[]types.BetOrderV2 - 用户的投注订单信息
+ * @param gameRoomId string - 房间Id
+ * @param gameRoundId string - 局Id
+ * @param userId string - 用户ID
+ * @return []*dto.BetDTO - 用户的投注订单信息 没有数据或者读数据出错返回nil
  */
 
 func GetUserOrder(traceId, gameRoomId, gameRoundId, userId string) []*dto.BetDTO {
@@ -26,152 +128,213 @@ func GetUserOrder(traceId, gameRoomId, gameRoundId, userId string) []*dto.BetDTO
 		traceId, gameRoomId, gameRoundId, userId)
 
 	var (
-		val string
+		val map[string]string
 		err error
 	)
-	redisInfo := rediskey.GetBetHRedisInfo(gameRoomId, gameRoundId, userId)
-	if val, err = redisdb.HGet(redisInfo.HTable, redisInfo.Filed); nil != err {
-		trace.Error("%v, redis HGet failed, table=%v, field=%v, error=%v", msgHeader, redisInfo.HTable,
-			redisInfo.Filed, err.Error())
+	redisInfo := rediskey.GetBetOrderRedisInfo(gameRoomId, gameRoundId, userId)
+	if val, err = redisdb.HGetAll(redisInfo.Key); nil != err {
+		trace.Error("%v, redis HGetAll failed, key=%v, error=%v", msgHeader, redisInfo.Key, err.Error())
 		return nil
 	}
 	if len(val) <= 0 {
-		trace.Notice("%v, redis HGet no data, table=%v, field=%v,val=%v",
-			msgHeader, redisInfo.HTable, redisInfo.Filed, val)
+		trace.Notice("%v, redis HGetAll no data, key=%v", msgHeader, redisInfo.Key)
 		return nil
 	}
 
-	orderList := make([]*dto.BetDTO, 0)
-	if err = json.Unmarshal([]byte(val), &orderList); nil != err {
-		trace.Error("%v, json unmarshal failed, table=%v, field=%v, val=%v, err=%v", msgHeader, redisInfo.HTable,
-			redisInfo.Filed, val, err.Error())
-		return nil
+	values := make([]string, 0, len(val))
+	for _, v := range val {
+		values = append(values, v)
 	}
-	trace.Info("%v, key=%v ,field=%v,order list=%+v", msgHeader, redisInfo.HTable, redisInfo.Filed, orderList)
+	orderList := unmarshalOrders(msgHeader, values)
+	trace.Info("%v, key=%v, order list=%+v", msgHeader, redisInfo.Key, orderList)
 
 	return orderList
 }
 
 /**
  * GetOrders
- * 从缓存hash中获取该局订单数据
+ * 从注单簿中获取该局全量订单数据
  *
  * @param traceId string - traceId用于日志跟踪
- * @param gameRoomId int64 - 房间Id
- * @param gameRoundId int64 - 局Id
- * @return *[]types.BetOrderV2 - 用户的投注订单信息
+ * @param gameRoomId string - 房间Id
+ * @param gameRoundId string - 局Id
+ * @return []*dto.BetDTO - 该局的投注订单信息 没有数据或者读数据出错返回nil
  */
 
 func GetOrders(traceId, gameRoomId, gameRoundId string) []*dto.BetDTO {
-	msgHeader := fmt.Sprintf("获取局全量注单 GetUserOrder traceId=%v, gameRoomId=%v, gameRoundId=%v",
+	msgHeader := fmt.Sprintf("获取局全量注单 GetOrders traceId=%v, gameRoomId=%v, gameRoundId=%v",
 		traceId, gameRoomId, gameRoundId)
 
 	var (
-		val       map[string]string
+		userIds   []string
 		err       error
 		orderList []*dto.BetDTO
 	)
-	redisInfo := rediskey.GetBetHRedisInfo(gameRoomId, gameRoundId, "")
-	if val, err = redisdb.HGetAll(redisInfo.HTable); nil != err {
-		trace.Error("%v, redis GetOrders failed, table=%v, field=%v, error=%v", msgHeader, redisInfo.HTable,
-			redisInfo.Filed, err.Error())
+	userRedisInfo := rediskey.GetBetOrderUserRedisInfo(gameRoomId, gameRoundId)
+	if userIds, err = redisdb.SMembers(userRedisInfo.Key); nil != err {
+		trace.Error("%v, redis SMembers failed, key=%v, error=%v", msgHeader, userRedisInfo.Key, err.Error())
 		return nil
 	}
-	if len(val) <= 0 {
-		trace.Notice("%v, redis GetOrders no data, table=%v, field=%v", msgHeader, redisInfo.HTable, redisInfo.Filed)
+	if len(userIds) <= 0 {
+		trace.Notice("%v, redis SMembers no data, key=%v", msgHeader, userRedisInfo.Key)
 		return nil
 	}
 
-	for _, s2 := range val {
-		orderArray := make([]dto.BetDTO, 0)
-		if err = json.Unmarshal([]byte(s2), &orderArray); nil != err {
-			trace.Error("%v, json unmarshal failed, table=%v, field=%v, val=%v, err=%v", msgHeader, redisInfo.HTable,
-				redisInfo.Filed, val, err.Error())
-			return nil
-		}
-		for _, v2 := range orderArray {
-			orderList = append(orderList, &v2)
-		}
-
+	for _, userId := range userIds {
+		orderList = append(orderList, GetUserOrder(traceId, gameRoomId, gameRoundId, userId)...)
 	}
-
 	if len(orderList) <= 0 {
-		trace.Error("%v, GetOrders empty, table=%v, field=%v, val=%v, err=%v", msgHeader, redisInfo.HTable,
-			redisInfo.Filed, val, err.Error())
+		trace.Error("%v, GetOrders empty, userIds=%v", msgHeader, userIds)
 		return nil
 	}
-	trace.Info("%v, order list=%v", msgHeader, orderList)
+	trace.Info("%v, order size=%v", msgHeader, len(orderList))
 
 	return orderList
 }
 
 /**
- * SetUserOrder
- * 设置hash中的玩家订单信息
+ * AppendUserOrders
+ * 原子追加玩家注单 并记录玩家到局内玩家集合
  *
  * @param traceId string - traceId用于日志跟踪
- * @param gameRoomId int64 - 房间Id
- * @param gameRoundId int64 - 局Id
- * @param userId int64 - 用户ID
- * @param *[]types.BetOrderV2 - 用户的投注订单信息
- * @return
+ * @param gameRoomId string - 房间Id
+ * @param gameRoundId string - 局Id
+ * @param userId string - 用户ID
+ * @param orderList []*dto.BetDTO - 需要追加的注单
+ * @return bool - 是否追加成功
  */
 
-func SetUserOrder(traceId, gameRoomId, gameRoundId, userId string, orderList []*dto.BetDTO) {
-	msgHeader := fmt.Sprintf("更新用户注单信息 SetUserOrder traceId=%v, gameRoomId=%v, gameRoundId=%v, userId=%v",
+func AppendUserOrders(traceId, gameRoomId, gameRoundId, userId string, orderList []*dto.BetDTO) bool {
+	msgHeader := fmt.Sprintf("追加用户注单 AppendUserOrders traceId=%v, gameRoomId=%v, gameRoundId=%v, userId=%v",
 		traceId, gameRoomId, gameRoundId, userId)
+	if len(orderList) == 0 {
+		return true
+	}
 
-	var (
-		val  int64
-		err  error
-		data []byte
-	)
-	if data, err = json.Marshal(orderList); err != nil {
-		trace.Error("%v, json marshal failed, error=%v", msgHeader, err.Error())
-		return
+	orderArgs, ok := marshalOrderArgs(msgHeader, orderList)
+	if !ok {
+		return false
 	}
-	for _, betDTO := range orderList {
-		trace.Info("%v orderInfo=%+v", msgHeader, *betDTO)
+	redisInfo := rediskey.GetBetOrderRedisInfo(gameRoomId, gameRoundId, userId)
+	userRedisInfo := rediskey.GetBetOrderUserRedisInfo(gameRoomId, gameRoundId)
+	args := append([]interface{}{redisInfo.Expire.Milliseconds(), userId}, orderArgs...)
+	val, err := redisdb.EvalScript(appendOrderScript, []string{redisInfo.Key, userRedisInfo.Key}, args...)
+	if nil != err {
+		trace.Error("%v, append order script failed, key=%v, error=%v", msgHeader, redisInfo.Key, err.Error())
+		return false
 	}
-	redisInfo := rediskey.GetBetHRedisInfo(gameRoomId, gameRoundId, userId)
-	if val, err = redisdb.HSet(redisInfo.HTable, redisInfo.Filed, string(data), redisInfo.Expire); nil != err {
-		trace.Error("%v, redis HGet failed, table=%v, field=%v, error=%v", msgHeader, redisInfo.HTable,
-			redisInfo.Filed, err.Error())
-		return
+
+	trace.Info("%v, key=%v, order size=%v, append size=%v", msgHeader, redisInfo.Key, val, len(orderList))
+	return true
+}
+
+/**
+ * RemoveUserOrders
+ * 原子移除玩家注单 不存在的注单号忽略
+ *
+ * @param traceId string - traceId用于日志跟踪
+ * @param gameRoomId string - 房间Id
+ * @param gameRoundId string - 局Id
+ * @param userId string - 用户ID
+ * @param orderNoList []string - 需要移除的注单号
+ * @return []*dto.BetDTO - 实际被移除的注单 出错返回nil
+ */
+
+func RemoveUserOrders(traceId, gameRoomId, gameRoundId, userId string, orderNoList []string) []*dto.BetDTO {
+	msgHeader := fmt.Sprintf("移除用户注单 RemoveUserOrders traceId=%v, gameRoomId=%v, gameRoundId=%v, userId=%v",
+		traceId, gameRoomId, gameRoundId, userId)
+	if len(orderNoList) == 0 {
+		return make([]*dto.BetDTO, 0)
+	}
+
+	args := make([]interface{}, 0, len(orderNoList))
+	for _, orderNo := range orderNoList {
+		args = append(args, orderNo)
+	}
+	redisInfo := rediskey.GetBetOrderRedisInfo(gameRoomId, gameRoundId, userId)
+	val, err := redisdb.EvalScript(removeOrderScript, []string{redisInfo.Key}, args...)
+	if nil != err {
+		trace.Error("%v, remove order script failed, key=%v, error=%v", msgHeader, redisInfo.Key, err.Error())
+		return nil
+	}
+
+	values := make([]string, 0)
+	if removed, ok := val.([]interface{}); ok {
+		for _, item := range removed {
+			if str, isStr := item.(string); isStr {
+				values = append(values, str)
+			}
+		}
 	}
+	orderRemoved := unmarshalOrders(msgHeader, values)
+	trace.Info("%v, key=%v, orderNoList=%v, removed size=%v", msgHeader, redisInfo.Key, orderNoList, len(orderRemoved))
 
-	trace.Info("%v, val=%v, order list=%v", msgHeader, val, orderList)
+	return orderRemoved
 }
 
 /**
- * SetOrders
- * 设置所有当局注单信息
+ * UpdateUserOrders
+ * 原子更新玩家注单 只更新注单簿中仍然存在的注单
  *
  * @param traceId string - traceId用于日志跟踪
- * @param gameRoomId int64 - 房间Id
- * @param gameRoundId int64 - 局Id
- * @param *[]types.BetOrderV2 - 用户的投注订单信息
+ * @param gameRoomId string - 房间Id
+ * @param gameRoundId string - 局Id
+ * @param userId string - 用户ID
+ * @param orderList []*dto.BetDTO - 更新后的注单
+ * @return int - 实际更新的注单数量
+ */
+
+func UpdateUserOrders(traceId, gameRoomId, gameRoundId, userId string, orderList []*dto.BetDTO) int {
+	msgHeader := fmt.Sprintf("更新用户注单信息 UpdateUserOrders traceId=%v, gameRoomId=%v, gameRoundId=%v, userId=%v",
+		traceId, gameRoomId, gameRoundId, userId)
+	if len(orderList) == 0 {
+		return 0
+	}
+
+	orderArgs, ok := marshalOrderArgs(msgHeader, orderList)
+	if !ok {
+		return 0
+	}
+	redisInfo := rediskey.GetBetOrderRedisInfo(gameRoomId, gameRoundId, userId)
+	args := append([]interface{}{redisInfo.Expire.Milliseconds()}, orderArgs...)
+	val, err := redisdb.EvalScript(updateOrderScript, []string{redisInfo.Key}, args...)
+	if nil != err {
+		trace.Error("%v, update order script failed, key=%v, error=%v", msgHeader, redisInfo.Key, err.Error())
+		return 0
+	}
+
+	updated, _ := val.(int64)
+	if int(updated) != len(orderList) {
+		trace.Notice("%v, some orders not exist, key=%v, updated=%v, order size=%v",
+			msgHeader, redisInfo.Key, updated, len(orderList))
+	}
+	trace.Info("%v, key=%v, updated=%v", msgHeader, redisInfo.Key, updated)
+	return int(updated)
+}
+
+/**
+ * UpdateOrders
+ * 按玩家分组后原子更新当局注单信息
+ *
+ * @param traceId string - traceId用于日志跟踪
+ * @param gameRoomId string - 房间Id
+ * @param gameRoundId string - 局Id
+ * @param orderList []*dto.BetDTO - 更新后的注单
  * @return
  */
 
-func SetOrders(traceId, gameRoomId, gameRoundId string, orderList []*dto.BetDTO) {
-	msgHeader := fmt.Sprintf("更新全量用户注单信息 SetUserOrder traceId=%v, gameRoomId=%v, gameRoundId=%v order size=%v",
+func UpdateOrders(traceId, gameRoomId, gameRoundId string, orderList []*dto.BetDTO) {
+	msgHeader := fmt.Sprintf("更新全量用户注单信息 UpdateOrders traceId=%v, gameRoomId=%v, gameRoundId=%v order size=%v",
 		traceId, gameRoomId, gameRoundId, len(orderList))
 
-	var orderBetMap = make(map[string][]*dto.BetDTO, 0)
-
+	orderBetMap := make(map[string][]*dto.BetDTO)
 	for _, betDTO := range orderList {
 		usrId := strconv.FormatInt(betDTO.UserId, 10)
-		if len(orderBetMap[usrId]) == 0 {
-			orderBetMap[usrId] = []*dto.BetDTO{}
-			orderBetMap[usrId] = append(orderBetMap[usrId], betDTO)
-		} else {
-			orderBetMap[usrId] = append(orderBetMap[usrId], betDTO)
-		}
+		orderBetMap[usrId] = append(orderBetMap[usrId], betDTO)
 	}
 
-	for s, dtos := range orderBetMap {
-		SetUserOrder(traceId, gameRoomId, gameRoundId, s, dtos)
+	for usrId, dtos := range orderBetMap {
+		UpdateUserOrders(traceId, gameRoomId, gameRoundId, usrId, dtos)
 	}
 	trace.Info("%v 更新成功", msgHeader)
 }
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"sl.framework.com/game_server/currency/money"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/game/service/type/dto"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func initOrderCacheRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+}
+
+func newTestOrder(userId, orderNo int64) *dto.BetDTO {
+	return &dto.BetDTO{
+		Id:          orderNo,
+		OrderNo:     orderNo,
+		UserId:      userId,
+		GameRoomId:  1,
+		GameRoundId: 2,
+		BetAmount:   money.FromFloat(10),
+		BetStatus:   "Unpaid",
+	}
+}
+
+func TestAppendUserOrdersConcurrent(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	const (
+		nodes        = 8
+		betsPerNode  = 25
+		userId       = int64(1001)
+		orderNoStart = int64(1880000000000000000) //超过2^53 验证注单号不会丢失精度
+	)
+	wg := new(sync.WaitGroup)
+	for node := 0; node < nodes; node++ {
+		wg.Add(1)
+		go func(node int) {
+			defer wg.Done()
+			for i := 0; i < betsPerNode; i++ {
+				orderNo := orderNoStart + int64(node*betsPerNode+i)
+				if !AppendUserOrders("test", "1", "2", strconv.FormatInt(userId, 10),
+					[]*dto.BetDTO{newTestOrder(userId, orderNo)}) {
+					t.Errorf("AppendUserOrders() orderNo=%v failed", orderNo)
+				}
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	orderList := GetUserOrder("test", "1", "2", strconv.FormatInt(userId, 10))
+	if len(orderList) != nodes*betsPerNode {
+		t.Fatalf("GetUserOrder() size = %v, want %v", len(orderList), nodes*betsPerNode)
+	}
+	for i, order := range orderList {
+		if want := orderNoStart + int64(i); order.OrderNo != want {
+			t.Errorf("GetUserOrder()[%v].OrderNo = %v, want %v", i, order.OrderNo, want)
+		}
+	}
+}
+
+func TestRemoveUserOrdersRaceWithAppend(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	const total = 100
+	userId := "1002"
+	for i := 0; i < total; i++ {
+		AppendUserOrders("test", "1", "2", userId, []*dto.BetDTO{newTestOrder(1002, int64(i))})
+	}
+
+	wg := new(sync.WaitGroup)
+	removedCount := make([]int, total)
+	//偶数注单被两个协程同时取消 同时有新注单不断追加
+	for worker := 0; worker < 2; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < total; i += 2 {
+				removed := RemoveUserOrders("test", "1", "2", userId, []string{strconv.Itoa(i)})
+				for _, order := range removed {
+					removedCount[order.OrderNo]++
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := total; i < total*2; i++ {
+			AppendUserOrders("test", "1", "2", userId, []*dto.BetDTO{newTestOrder(1002, int64(i))})
+		}
+	}()
+	wg.Wait()
+
+	for i := 0; i < total; i += 2 {
+		if removedCount[i] != 1 {
+			t.Errorf("RemoveUserOrders() orderNo=%v removed %v times, want 1", i, removedCount[i])
+		}
+	}
+	orderList := GetUserOrder("test", "1", "2", userId)
+	if len(orderList) != total*2-total/2 {
+		t.Fatalf("GetUserOrder() size = %v, want %v", len(orderList), total*2-total/2)
+	}
+}
+
+func TestUpdateUserOrdersSkipRemoved(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	userId := "1003"
+	AppendUserOrders("test", "1", "2", userId, []*dto.BetDTO{newTestOrder(1003, 1), newTestOrder(1003, 2)})
+	RemoveUserOrders("test", "1", "2", userId, []string{"2"})
+
+	confirmed := []*dto.BetDTO{newTestOrder(1003, 1), newTestOrder(1003, 2)}
+	for _, order := range confirmed {
+		order.BetStatus = "Paid"
+	}
+	if updated := UpdateUserOrders("test", "1", "2", userId, confirmed); updated != 1 {
+		t.Errorf("UpdateUserOrders() = %v, want 1", updated)
+	}
+
+	orderList := GetOrders("test", "1", "2")
+	if len(orderList) != 1 || orderList[0].OrderNo != 1 || orderList[0].BetStatus != "Paid" {
+		t.Errorf("GetOrders() = %+v, want only order 1 with status Paid", orderList)
+	}
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+)
+
+/**
+ * PutResettleAudit
+ * 保存重新结算审计记录 同一局多次重新结算或者多个分片的记录按顺序追加
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param audit *types.ResettleAuditDTO - 审计记录
+ * @return bool - 是否保存成功
+ */
+
+func PutResettleAudit(traceId string, audit *types.ResettleAuditDTO) bool {
+	redisInfo := rediskey.GetGameResettleAuditRedisInfo(audit.GameRoomId, audit.GameRoundId)
+	if !redisdb.SetList[*types.ResettleAuditDTO](redisInfo.Key, redisInfo.Expire, audit) {
+		trace.Error("PutResettleAudit traceId=%v, key=%v, resettleId=%v save failed", traceId, redisInfo.Key, audit.ResettleId)
+		return false
+	}
+	return true
+}
+
+/**
+ * GetResettleAudits
+ * 获取局的全部重新结算审计记录
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return []*types.ResettleAuditDTO - 审计记录 按写入顺序排列
+ */
+
+func GetResettleAudits(traceId string, gameRoomId, gameRoundId int64) []*types.ResettleAuditDTO {
+	msgHeader := fmt.Sprintf("GetResettleAudits traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+	redisInfo := rediskey.GetGameResettleAuditRedisInfo(gameRoomId, gameRoundId)
+	members, ok := redisdb.LAllMember(redisInfo.Key)
+	if !ok {
+		trace.Notice("%v, key=%v no data", msgHeader, redisInfo.Key)
+		return nil
+	}
+
+	auditList := make([]*types.ResettleAuditDTO, 0, len(members))
+	for _, member := range members {
+		audit := new(types.ResettleAuditDTO)
+		if err := json.Unmarshal([]byte(member), audit); err != nil {
+			trace.Error("%v, json unmarshal failed, key=%v, val=%v, err=%v", msgHeader, redisInfo.Key, member, err.Error())
+			continue
+		}
+		auditList = append(auditList, audit)
+	}
+	return auditList
+}
@@ -0,0 +1,318 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+	"strconv"
+	"time"
+)
+
+/*
+	房间在线玩家
+	每个房间一个有序集合记录玩家最后活动时间 一个hash表记录玩家币种
+	进入 离开 刷新活动时间都通过lua脚本原子修改两个key 避免多节点并发读改写整块json导致玩家丢失
+	离开房间消息丢失时 玩家超过在线超时时间没有活动后由清理任务删除
+*/
+
+const (
+	presenceSweepBatch = 500 //清理脚本每次最多删除的玩家数量 避免单个脚本阻塞redis太久
+	presenceScanCount  = 200 //遍历房间玩家时每批读取的数量
+)
+
+var (
+	// joinPresenceScript 玩家进入房间 已经在房间内时只刷新活动时间和币种
+	// KEYS[1] 在线玩家有序集合 KEYS[2] 玩家币种hash表
+	// ARGV[1] 过期毫秒数 ARGV[2] 用户Id ARGV[3] 币种 ARGV[4] 当前毫秒时间
+	joinPresenceScript = redis.NewScript(`
+local added = redis.call('ZADD', KEYS[1], ARGV[4], ARGV[2])
+redis.call('HSET', KEYS[2], ARGV[2], ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+return added
+`)
+
+	// leavePresenceScript 玩家离开房间 返回是否删除了玩家
+	// KEYS[1] 在线玩家有序集合 KEYS[2] 玩家币种hash表
+	// ARGV[1] 用户Id
+	leavePresenceScript = redis.NewScript(`
+local removed = redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+return removed
+`)
+
+	// touchPresenceScript 刷新玩家活动时间 玩家不在房间内时不处理 返回是否刷新
+	// KEYS[1] 在线玩家有序集合 KEYS[2] 玩家币种hash表
+	// ARGV[1] 过期毫秒数 ARGV[2] 用户Id ARGV[3] 当前毫秒时间
+	touchPresenceScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+return 1
+`)
+
+	// sweepPresenceScript 删除活动时间早于超时时间点的玩家 返回删除数量
+	// KEYS[1] 在线玩家有序集合 KEYS[2] 玩家币种hash表
+	// ARGV[1] 超时时间点毫秒数 ARGV[2] 本次最多删除的数量
+	sweepPresenceScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[1], 'LIMIT', 0, ARGV[2])
+for i = 1, #expired do
+	redis.call('ZREM', KEYS[1], expired[i])
+	redis.call('HDEL', KEYS[2], expired[i])
+end
+return #expired
+`)
+)
+
+// presenceDeadline 活动时间早于该时间点的玩家视为已经离开房间
+func presenceDeadline(now time.Time) int64 {
+	return now.Add(-time.Duration(conf.GetPresenceExpired()) * time.Second).UnixMilli()
+}
+
+/**
+ * JoinRoomPresence
+ * 玩家进入房间 已经在房间内时刷新活动时间
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param roomId int64 - 房间Id
+ * @param userId int64 - 用户Id
+ * @param currency string - 币种
+ * @param now time.Time - 当前时间
+ * @return bool - 是否写入成功
+ */
+
+func JoinRoomPresence(traceId string, roomId, userId int64, currency string, now time.Time) bool {
+	msgHeader := fmt.Sprintf("JoinRoomPresence traceId=%v, roomId=%v, userId=%v", traceId, roomId, userId)
+	presenceInfo := rediskey.GetRoomPresenceRedisInfo(roomId)
+	playersInfo := rediskey.GetRoomPlayersRedisInfo(roomId)
+	if _, err := redisdb.EvalScript(joinPresenceScript, []string{presenceInfo.Key, playersInfo.Key},
+		presenceInfo.Expire.Milliseconds(), userId, currency, now.UnixMilli()); err != nil {
+		trace.Error("%v, key=%v, join script failed, err=%v", msgHeader, presenceInfo.Key, err.Error())
+		return false
+	}
+
+	//记录房间 供清理任务遍历
+	roomsInfo := rediskey.GetRoomPresenceRoomsRedisInfo()
+	if err := redisdb.SAdd(roomsInfo.Key, strconv.FormatInt(roomId, 10), roomsInfo.Expire); err != nil {
+		trace.Error("%v, key=%v, SAdd failed, err=%v", msgHeader, roomsInfo.Key, err.Error())
+	}
+
+	return true
+}
+
+/**
+ * LeaveRoomPresence
+ * 玩家离开房间
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param roomId int64 - 房间Id
+ * @param userId int64 - 用户Id
+ * @return bool - 玩家是否在房间内
+ * @return bool - 是否删除成功
+ */
+
+func LeaveRoomPresence(traceId string, roomId, userId int64) (bool, bool) {
+	msgHeader := fmt.Sprintf("LeaveRoomPresence traceId=%v, roomId=%v, userId=%v", traceId, roomId, userId)
+	presenceInfo := rediskey.GetRoomPresenceRedisInfo(roomId)
+	playersInfo := rediskey.GetRoomPlayersRedisInfo(roomId)
+	val, err := redisdb.EvalScript(leavePresenceScript, []string{presenceInfo.Key, playersInfo.Key}, userId)
+	if err != nil {
+		trace.Error("%v, key=%v, leave script failed, err=%v", msgHeader, presenceInfo.Key, err.Error())
+		return false, false
+	}
+
+	removed, _ := val.(int64)
+	return removed > 0, true
+}
+
+/**
+ * TouchRoomPresence
+ * 刷新玩家在房间内的活动时间 玩家不在房间内时不处理
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param roomId int64 - 房间Id
+ * @param userId int64 - 用户Id
+ * @param now time.Time - 当前时间
+ * @return bool - 是否刷新成功
+ */
+
+func TouchRoomPresence(traceId string, roomId, userId int64, now time.Time) bool {
+	msgHeader := fmt.Sprintf("TouchRoomPresence traceId=%v, roomId=%v, userId=%v", traceId, roomId, userId)
+	presenceInfo := rediskey.GetRoomPresenceRedisInfo(roomId)
+	playersInfo := rediskey.GetRoomPlayersRedisInfo(roomId)
+	val, err := redisdb.EvalScript(touchPresenceScript, []string{presenceInfo.Key, playersInfo.Key},
+		presenceInfo.Expire.Milliseconds(), userId, now.UnixMilli())
+	if err != nil {
+		trace.Error("%v, key=%v, touch script failed, err=%v", msgHeader, presenceInfo.Key, err.Error())
+		return false
+	}
+
+	touched, _ := val.(int64)
+	return touched > 0
+}
+
+/**
+ * IsRoomPlayerOnline
+ * 判断玩家是否在房间内 超过在线超时时间没有活动视为不在房间内
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param roomId int64 - 房间Id
+ * @param userId int64 - 用户Id
+ * @param now time.Time - 当前时间
+ * @return bool - 是否在房间内
+ * @return bool - 是否读取成功
+ */
+
+func IsRoomPlayerOnline(traceId string, roomId, userId int64, now time.Time) (bool, bool) {
+	presenceInfo := rediskey.GetRoomPresenceRedisInfo(roomId)
+	score, exist, err := redisdb.ZScore(presenceInfo.Key, strconv.FormatInt(userId, 10))
+	if err != nil {
+		trace.Error("IsRoomPlayerOnline traceId=%v, roomId=%v, userId=%v, key=%v, ZScore failed, err=%v",
+			traceId, roomId, userId, presenceInfo.Key, err.Error())
+		return false, false
+	}
+
+	return exist && int64(score) >= presenceDeadline(now), true
+}
+
+/**
+ * GetRoomOnlineCount
+ * 获取房间在线玩家数量 不包含超时还没有被清理的玩家
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param roomId int64 - 房间Id
+ * @param now time.Time - 当前时间
+ * @return int64 - 在线玩家数量
+ * @return bool - 是否读取成功
+ */
+
+func GetRoomOnlineCount(traceId string, roomId int64, now time.Time) (int64, bool) {
+	presenceInfo := rediskey.GetRoomPresenceRedisInfo(roomId)
+	count, err := redisdb.ZCount(presenceInfo.Key, strconv.FormatInt(presenceDeadline(now), 10), "+inf")
+	if err != nil {
+		trace.Error("GetRoomOnlineCount traceId=%v, roomId=%v, key=%v, ZCount failed, err=%v",
+			traceId, roomId, presenceInfo.Key, err.Error())
+		return 0, false
+	}
+
+	return count, true
+}
+
+/**
+ * SweepRoomPresence
+ * 清理房间内超时没有活动的玩家
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param roomId int64 - 房间Id
+ * @param now time.Time - 当前时间
+ * @return int64 - 清理的玩家数量
+ * @return bool - 是否清理成功
+ */
+
+func SweepRoomPresence(traceId string, roomId int64, now time.Time) (int64, bool) {
+	msgHeader := fmt.Sprintf("SweepRoomPresence traceId=%v, roomId=%v", traceId, roomId)
+	presenceInfo := rediskey.GetRoomPresenceRedisInfo(roomId)
+	playersInfo := rediskey.GetRoomPlayersRedisInfo(roomId)
+	deadline := presenceDeadline(now)
+
+	total := int64(0)
+	for {
+		val, err := redisdb.EvalScript(sweepPresenceScript, []string{presenceInfo.Key, playersInfo.Key},
+			deadline, presenceSweepBatch)
+		if err != nil {
+			trace.Error("%v, key=%v, sweep script failed, err=%v", msgHeader, presenceInfo.Key, err.Error())
+			return total, false
+		}
+		removed, _ := val.(int64)
+		total += removed
+		if removed < presenceSweepBatch {
+			break
+		}
+	}
+	if total > 0 {
+		trace.Info("%v, remove expired players=%v", msgHeader, total)
+	}
+
+	return total, true
+}
+
+/**
+ * GetPresenceRooms
+ * 获取有玩家进入过的房间 供清理任务遍历
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @return []int64 - 房间Id列表
+ * @return bool - 是否读取成功
+ */
+
+func GetPresenceRooms(traceId string) ([]int64, bool) {
+	roomsInfo := rediskey.GetRoomPresenceRoomsRedisInfo()
+	members, err := redisdb.SMembers(roomsInfo.Key)
+	if err != nil {
+		trace.Error("GetPresenceRooms traceId=%v, key=%v, SMembers failed, err=%v", traceId, roomsInfo.Key, err.Error())
+		return nil, false
+	}
+
+	rooms := make([]int64, 0, len(members))
+	for _, member := range members {
+		if roomId, err := strconv.ParseInt(member, 10, 64); err == nil {
+			rooms = append(rooms, roomId)
+		}
+	}
+	return rooms, true
+}
+
+/**
+ * ScanRoomPlayers
+ * 分批遍历房间内的在线玩家 先清理超时的玩家再使用hscan读取 房间人数多时不会一次读取整个房间
+ * hscan可能重复返回同一个玩家 处理函数需要幂等
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param roomId int64 - 房间Id
+ * @param now time.Time - 当前时间
+ * @param handle func([]types.PlayerInfo) - 每批玩家的处理函数
+ * @return bool - 是否遍历完成 读取失败时返回false 已经处理的批次不会回滚
+ */
+
+func ScanRoomPlayers(traceId string, roomId int64, now time.Time, handle func([]types.PlayerInfo)) bool {
+	msgHeader := fmt.Sprintf("ScanRoomPlayers traceId=%v, roomId=%v", traceId, roomId)
+	if _, ok := SweepRoomPresence(traceId, roomId, now); !ok {
+		trace.Notice("%v, sweep failed, expired players may be included", msgHeader)
+	}
+
+	playersInfo := rediskey.GetRoomPlayersRedisInfo(roomId)
+	cursor := uint64(0)
+	for {
+		values, next, err := redisdb.HScan(playersInfo.Key, cursor, presenceScanCount)
+		if err != nil {
+			trace.Error("%v, key=%v, HScan failed, err=%v", msgHeader, playersInfo.Key, err.Error())
+			return false
+		}
+
+		players := make([]types.PlayerInfo, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			userId, err := strconv.ParseInt(values[i], 10, 64)
+			if err != nil {
+				trace.Error("%v, invalid userId=%v", msgHeader, values[i])
+				continue
+			}
+			players = append(players, types.PlayerInfo{UserId: userId, Currency: values[i+1]})
+		}
+		if len(players) > 0 {
+			handle(players)
+		}
+
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+
+	return true
+}
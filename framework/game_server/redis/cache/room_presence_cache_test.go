@@ -0,0 +1,100 @@
+package cache
+
+import (
+	types "sl.framework.com/game_server/game/service/type"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRoomPresence(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	const roomId = int64(3001)
+	now := time.UnixMilli(1_700_000_000_000)
+	expired := 1800 * time.Second //conf未初始化时的默认在线超时时间
+
+	//并发进入房间不会丢失玩家
+	var wg sync.WaitGroup
+	for userId := int64(1); userId <= 20; userId++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !JoinRoomPresence("test", roomId, userId, "CNY", now) {
+				t.Errorf("JoinRoomPresence(%v) failed", userId)
+			}
+		}()
+	}
+	wg.Wait()
+	if count, ok := GetRoomOnlineCount("test", roomId, now); !ok || count != 20 {
+		t.Fatalf("GetRoomOnlineCount() = %v, %v, want 20", count, ok)
+	}
+
+	//离开房间
+	if removed, ok := LeaveRoomPresence("test", roomId, 20); !ok || !removed {
+		t.Fatalf("LeaveRoomPresence() = %v, %v", removed, ok)
+	}
+	if removed, ok := LeaveRoomPresence("test", roomId, 20); !ok || removed {
+		t.Fatalf("LeaveRoomPresence() again = %v, %v, want not removed", removed, ok)
+	}
+	if TouchRoomPresence("test", roomId, 20, now) {
+		t.Fatal("TouchRoomPresence() should not add a player who left")
+	}
+
+	//玩家1有活动 其他玩家超时
+	later := now.Add(expired / 2)
+	if !TouchRoomPresence("test", roomId, 1, later) {
+		t.Fatal("TouchRoomPresence() failed")
+	}
+	timeout := now.Add(expired + time.Second)
+	if online, ok := IsRoomPlayerOnline("test", roomId, 2, timeout); !ok || online {
+		t.Fatalf("IsRoomPlayerOnline() for expired player = %v, %v", online, ok)
+	}
+	if count, _ := GetRoomOnlineCount("test", roomId, timeout); count != 1 {
+		t.Fatalf("GetRoomOnlineCount() after timeout = %v, want 1", count)
+	}
+
+	//清理任务删除超时玩家 遍历时只剩玩家1
+	if rooms, ok := GetPresenceRooms("test"); !ok || len(rooms) != 1 || rooms[0] != roomId {
+		t.Fatalf("GetPresenceRooms() = %v, %v", rooms, ok)
+	}
+	if removed, ok := SweepRoomPresence("test", roomId, timeout); !ok || removed != 18 {
+		t.Fatalf("SweepRoomPresence() = %v, %v, want 18", removed, ok)
+	}
+	var players []types.PlayerInfo
+	if !ScanRoomPlayers("test", roomId, timeout, func(batch []types.PlayerInfo) { players = append(players, batch...) }) {
+		t.Fatal("ScanRoomPlayers() failed")
+	}
+	if len(players) != 1 || players[0].UserId != 1 || players[0].Currency != "CNY" {
+		t.Fatalf("ScanRoomPlayers() = %+v", players)
+	}
+}
+
+func TestScanRoomPlayersBatches(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	const roomId = int64(3002)
+	now := time.Now()
+	total := presenceScanCount*2 + 7
+	for userId := 1; userId <= total; userId++ {
+		JoinRoomPresence("test", roomId, int64(userId), "USD", now)
+	}
+
+	seen := make(map[int64]bool, total)
+	if !ScanRoomPlayers("test", roomId, now, func(batch []types.PlayerInfo) {
+		for _, player := range batch {
+			seen[player.UserId] = true
+		}
+	}) {
+		t.Fatal("ScanRoomPlayers() failed")
+	}
+	userIds := make([]int64, 0, len(seen))
+	for userId := range seen {
+		userIds = append(userIds, userId)
+	}
+	sort.Slice(userIds, func(i, j int) bool { return userIds[i] < userIds[j] })
+	if len(userIds) != total || userIds[0] != 1 || userIds[total-1] != int64(total) {
+		t.Fatalf("ScanRoomPlayers() saw %v players, want %v", len(userIds), total)
+	}
+}
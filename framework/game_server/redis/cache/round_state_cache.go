@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+	"sort"
+	"strconv"
+)
+
+const roundStateUpdateRetries = 5 //并发修改同一个房间状态时的重试次数
+
+// ErrRoundStateConflict 多次重试后仍然有其他节点在修改房间状态
+var ErrRoundStateConflict = errors.New("round state update conflict")
+
+// compareAndSetRoundStateScript 房间状态与读取时一致才写入 返回是否写入
+// KEYS[1] 状态hash ARGV[1] 房间id ARGV[2] 读取时的状态 不存在时为空字符串 ARGV[3] 新状态 ARGV[4] 过期秒数
+var compareAndSetRoundStateScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], ARGV[1])
+if (cur or '') ~= ARGV[2] then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return 1
+`)
+
+/**
+ * UpdateRoundState
+ * 读取房间局状态 由transition计算新状态后比较并写入 其他节点同时修改时重新读取重试
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param transition func(*types.RoundStateDTO) (*types.RoundStateDTO, error) - 状态迁移 房间没有状态时参数为nil
+ * @return *types.RoundStateDTO - 写入的新状态
+ * @return error - transition返回的错误或者redis错误
+ */
+
+func UpdateRoundState(traceId string, gameRoomId int64,
+	transition func(*types.RoundStateDTO) (*types.RoundStateDTO, error)) (*types.RoundStateDTO, error) {
+	msgHeader := fmt.Sprintf("UpdateRoundState traceId=%v, gameRoomId=%v", traceId, gameRoomId)
+	redisInfo := rediskey.GetRoundStateRedisInfo()
+	field := strconv.FormatInt(gameRoomId, 10)
+	for i := 0; i < roundStateUpdateRetries; i++ {
+		val, err := redisdb.HGet(redisInfo.Key, field)
+		if err != nil {
+			return nil, err
+		}
+		var cur *types.RoundStateDTO
+		if len(val) > 0 {
+			cur = new(types.RoundStateDTO)
+			if err = json.Unmarshal([]byte(val), cur); err != nil {
+				trace.Error("%v, json unmarshal failed, key=%v, val=%v, err=%v", msgHeader, redisInfo.Key, val, err.Error())
+				return nil, err
+			}
+		}
+
+		next, err := transition(cur)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(next)
+		if err != nil {
+			return nil, err
+		}
+		ret, err := redisdb.EvalScript(compareAndSetRoundStateScript, []string{redisInfo.Key}, field, val, string(data),
+			int64(redisInfo.Expire.Seconds()))
+		if err != nil {
+			return nil, err
+		}
+		if written, _ := ret.(int64); written == 1 {
+			return next, nil
+		}
+		trace.Notice("%v, state changed by others, retry=%v", msgHeader, i+1)
+	}
+
+	return nil, ErrRoundStateConflict
+}
+
+/**
+ * GetRoundStates
+ * 获取全部房间的局状态
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @return []*types.RoundStateDTO - 房间局状态 按房间id升序排列
+ * @return bool - 是否执行成功
+ */
+
+func GetRoundStates(traceId string) ([]*types.RoundStateDTO, bool) {
+	msgHeader := fmt.Sprintf("GetRoundStates traceId=%v", traceId)
+	redisInfo := rediskey.GetRoundStateRedisInfo()
+	val, err := redisdb.HGetAll(redisInfo.Key)
+	if err != nil {
+		trace.Error("%v, key=%v redis HGetAll failed, error=%v", msgHeader, redisInfo.Key, err.Error())
+		return nil, false
+	}
+
+	states := make([]*types.RoundStateDTO, 0, len(val))
+	for field, member := range val {
+		state := new(types.RoundStateDTO)
+		if err = json.Unmarshal([]byte(member), state); err != nil {
+			trace.Error("%v, json unmarshal failed, key=%v, field=%v, val=%v, err=%v", msgHeader, redisInfo.Key, field, member, err.Error())
+			continue
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].GameRoomId < states[j].GameRoomId })
+
+	return states, true
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUpdateRoundState(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	//并发修改同一个房间 每次修改都基于最新的状态
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int32
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				_, err := UpdateRoundState("test", 1, func(cur *types.RoundStateDTO) (*types.RoundStateDTO, error) {
+					next := &types.RoundStateDTO{GameRoomId: 1, State: types.RoundStateDealing}
+					if cur != nil {
+						next.Missed = append(cur.Missed, "x")
+					}
+					return next, nil
+				})
+				switch {
+				case err == nil:
+					succeeded.Add(1)
+				case !errors.Is(err, ErrRoundStateConflict):
+					t.Errorf("UpdateRoundState() error = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	//迁移返回错误时不写入
+	rejected := errors.New("rejected")
+	if _, err := UpdateRoundState("test", 2, func(cur *types.RoundStateDTO) (*types.RoundStateDTO, error) {
+		return nil, rejected
+	}); !errors.Is(err, rejected) {
+		t.Fatalf("UpdateRoundState() error = %v, want rejected", err)
+	}
+
+	states, ok := GetRoundStates("test")
+	if !ok || len(states) != 1 || states[0].GameRoomId != 1 {
+		t.Fatalf("GetRoundStates() = %+v, %v", states, ok)
+	}
+	//写入成功的修改一个都不会丢 第一次修改时没有状态 之后每次追加一个
+	if n := len(states[0].Missed) + 1; n != int(succeeded.Load()) {
+		t.Fatalf("updates in state = %v, successful returns = %v", n, succeeded.Load())
+	}
+}
+
+func TestUpdateRoundStateConflict(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	//每次读取之后都有其他节点抢先写入 重试用完后返回ErrRoundStateConflict
+	redisInfo := rediskey.GetRoundStateRedisInfo()
+	calls := 0
+	_, err := UpdateRoundState("test", 3, func(cur *types.RoundStateDTO) (*types.RoundStateDTO, error) {
+		calls++
+		other, _ := json.Marshal(&types.RoundStateDTO{GameRoomId: 3, GameRoundNo: strconv.Itoa(calls)})
+		if _, err := redisdb.HSet(redisInfo.Key, "3", string(other), redisInfo.Expire); err != nil {
+			t.Fatalf("HSet() error = %v", err)
+		}
+		return &types.RoundStateDTO{GameRoomId: 3, State: types.RoundStateBetting}, nil
+	})
+	if !errors.Is(err, ErrRoundStateConflict) {
+		t.Fatalf("UpdateRoundState() error = %v, want ErrRoundStateConflict", err)
+	}
+	if calls != roundStateUpdateRetries {
+		t.Errorf("transition calls = %v, want %v", calls, roundStateUpdateRetries)
+	}
+	states, _ := GetRoundStates("test")
+	if len(states) != 1 || states[0].State == types.RoundStateBetting {
+		t.Errorf("GetRoundStates() = %+v, conflicting update must not be written", states)
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+	"strconv"
+)
+
+/**
+ * GetSettleLedger
+ * 获取局的结算台账
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return map[int64]*types.SettleLedgerEntry - 已派彩注单 key为注单号
+ * @return bool - 是否读取成功 读取失败时调用方不能认为注单未派彩
+ */
+
+func GetSettleLedger(traceId string, gameRoomId, gameRoundId int64) (map[int64]*types.SettleLedgerEntry, bool) {
+	msgHeader := fmt.Sprintf("GetSettleLedger traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+	redisInfo := rediskey.GetSettleLedgerRedisInfo(gameRoomId, gameRoundId)
+	values, err := redisdb.HGetAll(redisInfo.Key)
+	if err != nil {
+		trace.Error("%v, key=%v, HGetAll failed, err=%v", msgHeader, redisInfo.Key, err.Error())
+		return nil, false
+	}
+
+	ledger := make(map[int64]*types.SettleLedgerEntry, len(values))
+	for field, value := range values {
+		entry := new(types.SettleLedgerEntry)
+		if err = json.Unmarshal([]byte(value), entry); err != nil {
+			//记录损坏时仍按已派彩处理 宁可漏派也不能重复派彩 由对账处理
+			trace.Error("%v, json unmarshal failed, field=%v, value=%v, err=%v", msgHeader, field, value, err.Error())
+			entry.OrderNo, _ = strconv.ParseInt(field, 10, 64)
+		}
+		ledger[entry.OrderNo] = entry
+	}
+
+	return ledger, true
+}
+
+/**
+ * PutSettleLedger
+ * 记录已派彩注单到结算台账
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @param entries []*types.SettleLedgerEntry - 台账记录
+ * @return bool - 是否记录成功
+ */
+
+func PutSettleLedger(traceId string, gameRoomId, gameRoundId int64, entries []*types.SettleLedgerEntry) bool {
+	if len(entries) == 0 {
+		return true
+	}
+	msgHeader := fmt.Sprintf("PutSettleLedger traceId=%v, gameRoomId=%v, gameRoundId=%v", traceId, gameRoomId, gameRoundId)
+
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			trace.Error("%v, json marshal failed, entry=%+v, err=%v", msgHeader, entry, err.Error())
+			return false
+		}
+		values[strconv.FormatInt(entry.OrderNo, 10)] = string(buf)
+	}
+
+	redisInfo := rediskey.GetSettleLedgerRedisInfo(gameRoomId, gameRoundId)
+	if _, err := redisdb.HSetBatch(redisInfo.Key, values, redisInfo.Expire); err != nil {
+		trace.Error("%v, key=%v, HSetBatch failed, err=%v", msgHeader, redisInfo.Key, err.Error())
+		return false
+	}
+
+	return true
+}
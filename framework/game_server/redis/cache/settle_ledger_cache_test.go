@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"sl.framework.com/game_server/game/dao/redisdb"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/redis/rediskey"
+	"testing"
+)
+
+func TestSettleLedger(t *testing.T) {
+	initOrderCacheRedis(t)
+
+	ledger, ok := GetSettleLedger("test", 1, 2)
+	if !ok || len(ledger) != 0 {
+		t.Fatalf("GetSettleLedger() on empty round = %v, %v", ledger, ok)
+	}
+
+	entries := []*types.SettleLedgerEntry{
+		{OrderNo: 1880000000000000001, ShardNo: 0, TraceId: "t1", SettleTime: 1},
+		{OrderNo: 1880000000000000002, ShardNo: 1, TraceId: "t2", SettleTime: 2},
+	}
+	if !PutSettleLedger("test", 1, 2, entries) {
+		t.Fatal("PutSettleLedger() failed")
+	}
+	//重复写入同一注单只覆盖记录
+	if !PutSettleLedger("test", 1, 2, entries[:1]) {
+		t.Fatal("PutSettleLedger() again failed")
+	}
+	//损坏的记录仍然视为已派彩
+	redisInfo := rediskey.GetSettleLedgerRedisInfo(1, 2)
+	if _, err := redisdb.HSet(redisInfo.Key, "1880000000000000003", "broken", redisInfo.Expire); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger, ok = GetSettleLedger("test", 1, 2)
+	if !ok || len(ledger) != 3 {
+		t.Fatalf("GetSettleLedger() = %v, %v, want 3 entries", ledger, ok)
+	}
+	if entry := ledger[1880000000000000002]; entry == nil || entry.ShardNo != 1 || entry.TraceId != "t2" {
+		t.Errorf("ledger entry = %+v", entry)
+	}
+	if _, ok = ledger[1880000000000000003]; !ok {
+		t.Error("broken ledger entry should be treated as settled")
+	}
+	if ledger, _ = GetSettleLedger("test", 1, 3); len(ledger) != 0 {
+		t.Errorf("ledger of other round = %v", ledger)
+	}
+}
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"sl.framework.com/game_server/redis/rediskey"
+	"sl.framework.com/trace"
+	"strconv"
+)
+
+/*
+	雪花算法节点id租约
+	每个节点id一个带过期时间的key 节点启动时用SetNX抢占一个空闲id 心跳时续期
+	节点下线或者心跳停止租约过期后 该id可以被其他节点重新使用
+*/
+
+var (
+	// renewWorkerLeaseScript 持有者一致时续期 返回是否续期成功
+	// KEYS[1] 租约key ARGV[1] 持有者 ARGV[2] 过期毫秒数
+	renewWorkerLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+	// releaseWorkerLeaseScript 持有者一致时删除租约 返回是否删除
+	// KEYS[1] 租约key ARGV[1] 持有者
+	releaseWorkerLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+)
+
+/**
+ * AcquireWorkerLease
+ * 从start开始依次尝试抢占[0, maxWorkerId]中的空闲节点id
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param owner string - 租约持有者 每次启动唯一
+ * @param start int64 - 开始尝试的节点id
+ * @param maxWorkerId int64 - 节点id最大值
+ * @return int64 - 抢占到的节点id 没有空闲id时返回-1
+ * @return int64 - 该节点id最后一次生成id的时间(ms) 没有记录时为0
+ * @return bool - 是否执行成功
+ */
+
+func AcquireWorkerLease(traceId, owner string, start, maxWorkerId int64) (int64, int64, bool) {
+	msgHeader := fmt.Sprintf("AcquireWorkerLease traceId=%v, owner=%v", traceId, owner)
+	total := maxWorkerId + 1
+	for i := int64(0); i < total; i++ {
+		workerId := (start%total + total + i) % total
+		leaseInfo := rediskey.GetWorkerIdLeaseRedisInfo(workerId)
+		acquired, err := redisdb.SetNX(leaseInfo.Key, owner, leaseInfo.Expire)
+		if err != nil {
+			trace.Error("%v, key=%v, SetNX failed, err=%v", msgHeader, leaseInfo.Key, err.Error())
+			return -1, 0, false
+		}
+		if !acquired {
+			continue
+		}
+
+		lastMs, ok := GetWorkerLastTime(traceId, workerId)
+		if !ok {
+			ReleaseWorkerLease(traceId, workerId, owner)
+			return -1, 0, false
+		}
+		trace.Info("%v, acquired workerId=%v, lastMs=%v", msgHeader, workerId, lastMs)
+		return workerId, lastMs, true
+	}
+
+	trace.Error("%v, no free worker id in [0, %v]", msgHeader, maxWorkerId)
+	return -1, 0, true
+}
+
+/**
+ * RenewWorkerLease
+ * 续期节点id租约 持有者不一致说明租约已经过期并被其他节点抢占
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param workerId int64 - 节点id
+ * @param owner string - 租约持有者
+ * @return bool - 是否仍然持有租约
+ * @return bool - 是否执行成功
+ */
+
+func RenewWorkerLease(traceId string, workerId int64, owner string) (bool, bool) {
+	leaseInfo := rediskey.GetWorkerIdLeaseRedisInfo(workerId)
+	ret, err := redisdb.EvalScript(renewWorkerLeaseScript, []string{leaseInfo.Key}, owner, leaseInfo.Expire.Milliseconds())
+	if err != nil {
+		trace.Error("RenewWorkerLease traceId=%v, key=%v, owner=%v, renew script failed, err=%v",
+			traceId, leaseInfo.Key, owner, err.Error())
+		return false, false
+	}
+	renewed, _ := ret.(int64)
+
+	return renewed == 1, true
+}
+
+/**
+ * ReleaseWorkerLease
+ * 释放节点id租约 只删除自己持有的租约
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param workerId int64 - 节点id
+ * @param owner string - 租约持有者
+ * @return bool - 是否执行成功
+ */
+
+func ReleaseWorkerLease(traceId string, workerId int64, owner string) bool {
+	leaseInfo := rediskey.GetWorkerIdLeaseRedisInfo(workerId)
+	if _, err := redisdb.EvalScript(releaseWorkerLeaseScript, []string{leaseInfo.Key}, owner); err != nil {
+		trace.Error("ReleaseWorkerLease traceId=%v, key=%v, owner=%v, release script failed, err=%v",
+			traceId, leaseInfo.Key, owner, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// SetWorkerLastTime 保存节点id最后一次生成id的时间(ms)
+func SetWorkerLastTime(traceId string, workerId, lastMs int64) bool {
+	lastTimeInfo := rediskey.GetWorkerIdLastTimeRedisInfo()
+	if _, err := redisdb.HSet(lastTimeInfo.Key, strconv.FormatInt(workerId, 10),
+		strconv.FormatInt(lastMs, 10), lastTimeInfo.Expire); err != nil {
+		trace.Error("SetWorkerLastTime traceId=%v, key=%v, workerId=%v, HSet failed, err=%v",
+			traceId, lastTimeInfo.Key, workerId, err.Error())
+		return false
+	}
+
+	return true
+}
+
+// GetWorkerLastTime 获取节点id最后一次生成id的时间(ms) 没有记录时返回0
+func GetWorkerLastTime(traceId string, workerId int64) (int64, bool) {
+	lastTimeInfo := rediskey.GetWorkerIdLastTimeRedisInfo()
+	val, err := redisdb.HGet(lastTimeInfo.Key, strconv.FormatInt(workerId, 10))
+	if err != nil {
+		trace.Error("GetWorkerLastTime traceId=%v, key=%v, workerId=%v, HGet failed, err=%v",
+			traceId, lastTimeInfo.Key, workerId, err.Error())
+		return 0, false
+	}
+	if val == "" {
+		return 0, true
+	}
+	lastMs, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		trace.Error("GetWorkerLastTime traceId=%v, workerId=%v, invalid value=%v", traceId, workerId, val)
+		return 0, true
+	}
+
+	return lastMs, true
+}
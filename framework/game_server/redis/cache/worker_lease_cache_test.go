@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"sl.framework.com/game_server/game/dao/redisdb"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerLeaseCollision(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisdb.RedisClientInitWith(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+
+	const maxWorkerId = int64(1023)
+
+	//全部节点id被并发抢占 不能有重复
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		owners = make(map[int64]string, maxWorkerId+1)
+	)
+	for i := int64(0); i <= maxWorkerId; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			owner := "node-" + strconv.FormatInt(i, 10)
+			workerId, _, ok := AcquireWorkerLease("test", owner, i*7, maxWorkerId)
+			if !ok || workerId < 0 {
+				t.Errorf("AcquireWorkerLease(%v) = %v, %v", owner, workerId, ok)
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if other, exist := owners[workerId]; exist {
+				t.Errorf("workerId=%v acquired by %v and %v", workerId, other, owner)
+			}
+			owners[workerId] = owner
+		}()
+	}
+	wg.Wait()
+	if len(owners) != int(maxWorkerId+1) {
+		t.Fatalf("acquired %v worker ids, want %v", len(owners), maxWorkerId+1)
+	}
+
+	//没有空闲id
+	if workerId, _, ok := AcquireWorkerLease("test", "node-full", 0, maxWorkerId); !ok || workerId != -1 {
+		t.Fatalf("AcquireWorkerLease() when full = %v, %v, want -1", workerId, ok)
+	}
+
+	//只有持有者可以续期和释放
+	if renewed, ok := RenewWorkerLease("test", 5, "node-other"); !ok || renewed {
+		t.Fatalf("RenewWorkerLease() by other owner = %v, %v, want not renewed", renewed, ok)
+	}
+	ReleaseWorkerLease("test", 5, "node-other")
+	if workerId, _, _ := AcquireWorkerLease("test", "node-full", 0, maxWorkerId); workerId != -1 {
+		t.Fatalf("lease released by other owner, workerId=%v", workerId)
+	}
+
+	//释放后的id被重新使用 并带上最后生成id的时间
+	if !SetWorkerLastTime("test", 5, 1_700_000_000_000) || !ReleaseWorkerLease("test", 5, owners[5]) {
+		t.Fatalf("release worker 5 failed")
+	}
+	workerId, lastMs, ok := AcquireWorkerLease("test", "node-new", 0, maxWorkerId)
+	if !ok || workerId != 5 || lastMs != 1_700_000_000_000 {
+		t.Fatalf("AcquireWorkerLease() after release = %v, %v, %v, want 5", workerId, lastMs, ok)
+	}
+	if renewed, ok := RenewWorkerLease("test", 5, owners[5]); !ok || renewed {
+		t.Fatalf("RenewWorkerLease() by old owner = %v, %v, want lease lost", renewed, ok)
+	}
+
+	//续期的租约不过期 没有续期的租约过期后被重新使用
+	server.FastForward(40 * time.Second)
+	if renewed, ok := RenewWorkerLease("test", 5, "node-new"); !ok || !renewed {
+		t.Fatalf("RenewWorkerLease() = %v, %v, want renewed", renewed, ok)
+	}
+	server.FastForward(30 * time.Second)
+	expiredIds := make(map[int64]bool, maxWorkerId)
+	for i := int64(0); i < maxWorkerId; i++ {
+		workerId, _, ok := AcquireWorkerLease("test", "node-after-expire", 0, maxWorkerId)
+		if !ok || workerId < 0 || workerId == 5 || expiredIds[workerId] {
+			t.Fatalf("AcquireWorkerLease() after expire = %v, %v", workerId, ok)
+		}
+		expiredIds[workerId] = true
+	}
+	if workerId, _, _ := AcquireWorkerLease("test", "node-full", 0, maxWorkerId); workerId != -1 {
+		t.Fatalf("renewed lease reused, workerId=%v", workerId)
+	}
+}
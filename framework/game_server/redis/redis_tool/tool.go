@@ -107,6 +107,9 @@ func BuildRedisLockInfo(expiration time.Duration, keys ...string) *types.RedisLo
 		RedisInfo: BuildRedisInfo(expiration, keys...),
 		Owner:     conf.GetServerId(),
 	}
+	if len(keys) >= 2 {
+		redisLockInfo.Name = keys[0] + ":" + keys[1]
+	}
 
 	return redisLockInfo
 }
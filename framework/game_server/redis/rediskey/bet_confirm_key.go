@@ -10,6 +10,7 @@ import (
 const (
 	betConfirmedPrefix     = "BetConfirmed"     //投注确认
 	betConfirmedLockPrefix = "BetConfirmedLock" //投注确认锁
+	betConfirmStatePrefix  = "BetConfirmState"  //注单提交状态
 )
 
 /**
@@ -69,3 +70,23 @@ func GetBetConfirmedGameDataLockRedisInfo(gameRoomId, gameRoundId string) *types
 		gameRoundId,
 	)
 }
+
+/**
+ * GetBetConfirmStateRedisInfo
+ * 注单提交状态redis信息 hash结构 field为注单号
+ * 保留一天 供提交消息重新投递和问题排查使用
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局号Id
+ * @return *RedisInfo - 注单提交状态redis信息
+ */
+
+func GetBetConfirmStateRedisInfo(gameRoomId, gameRoundId int64) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		time.Duration(24)*time.Hour,
+		betFileKeyPrefix,
+		betConfirmStatePrefix,
+		strconv.FormatInt(gameRoomId, 10),
+		strconv.FormatInt(gameRoundId, 10),
+	)
+}
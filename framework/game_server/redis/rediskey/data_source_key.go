@@ -0,0 +1,23 @@
+package rediskey
+
+import (
+	"sl.framework.com/game_server/redis/redis_tool"
+	"sl.framework.com/game_server/redis/types"
+	"time"
+)
+
+const (
+	dataSourcePrefix      = "DataSource"
+	dataSourceNoncePrefix = "Nonce"
+)
+
+// GetDataSourceNonceRedisInfo 数据源请求nonce redis信息 用于防重放 过期时间为重放窗口
+func GetDataSourceNonceRedisInfo(sourceId, nonce string, expire time.Duration) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		expire,
+		dataSourcePrefix,
+		dataSourceNoncePrefix,
+		sourceId,
+		nonce,
+	)
+}
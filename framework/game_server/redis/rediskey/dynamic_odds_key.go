@@ -0,0 +1,36 @@
+package rediskey
+
+import (
+	"sl.framework.com/game_server/redis/redis_tool"
+	"sl.framework.com/game_server/redis/types"
+	"strconv"
+	"time"
+)
+
+const (
+	dynamicOddsSeedPrefix  = "DynamicOddsSeed"
+	dynamicOddsAuditPrefix = "DynamicOddsAudit"
+	dynamicOddsAuditExpire = time.Duration(24) * time.Hour //种子和审计记录的缓存时间 数据以game db为准
+)
+
+// GetDynamicOddsSeedRedisInfo 动态赔率服务端种子redis信息
+func GetDynamicOddsSeedRedisInfo(gameRoomId, gameRoundId int64) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		dynamicOddsAuditExpire,
+		gameFileKeyPrefix,
+		dynamicOddsSeedPrefix,
+		strconv.FormatInt(gameRoomId, 10),
+		strconv.FormatInt(gameRoundId, 10),
+	)
+}
+
+// GetDynamicOddsAuditRedisInfo 动态赔率审计记录redis信息 hash field为玩法id
+func GetDynamicOddsAuditRedisInfo(gameRoomId, gameRoundId int64) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		dynamicOddsAuditExpire,
+		gameFileKeyPrefix,
+		dynamicOddsAuditPrefix,
+		strconv.FormatInt(gameRoomId, 10),
+		strconv.FormatInt(gameRoundId, 10),
+	)
+}
@@ -14,50 +14,55 @@ const (
 )
 
 const (
-	betPrefix     = "Bet"     //投注
-	betLockPrefix = "BetLock" //投注锁
+	betOrderPrefix     = "BetOrder"     //玩家注单簿
+	betOrderUserPrefix = "BetOrderUser" //局内已下注玩家集合
+	betLockPrefix      = "BetLock"      //投注锁
 )
 
 /**
- * GetBetHRedisInfo
- * 下注redis信息
+ * GetBetOrderRedisInfo
+ * 玩家注单簿redis信息 每个玩家一个hash表 hash表的field为注单号 value为注单json
+ * 房间和局使用hash tag包裹 保证同一局的注单簿和玩家集合在集群模式下落在同一个slot
  *
- * @param gameRoomId int64 - 房间Id 用于构建hash表名
- * @param gameRoundId int64 - 局号Id 用于构建hash表名
- * @param userId int64 - 用户Id 用于当做hash表的field字段
- * @return *RedisInfo - 下注redis信息
+ * @param gameRoomId string - 房间Id 用于构建hash表名
+ * @param gameRoundId string - 局号Id 用于构建hash表名
+ * @param userId string - 用户Id 用于构建hash表名
+ * @return *types.RedisInfo - 注单簿redis信息
  */
 
-func GetBetHRedisInfo(gameRoomId, gameRoundId, userId string) *types.HashRedisInfo {
-	return redistool.BuildHashRedisInfo(
+func GetBetOrderRedisInfo(gameRoomId, gameRoundId, userId string) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
 		redistool.GetRedisExpireDuration(),
-		userId,
 		betFileKeyPrefix,
-		betPrefix,
-		gameRoomId,
-		gameRoundId,
+		betOrderPrefix,
+		betRoundHashTag(gameRoomId, gameRoundId),
+		userId,
 	)
 }
 
 /**
- * GetBetInfo
- * 下注redis信息
+ * GetBetOrderUserRedisInfo
+ * 局内已下注玩家集合redis信息 用于按局遍历全量注单
  *
- * @param gameRoomId int64 - 房间Id 用于构建hash表名
- * @param gameRoundId int64 - 局号Id 用于构建hash表名
- * @return *RedisInfo - 下注redis信息
+ * @param gameRoomId string - 房间Id 用于构建集合名
+ * @param gameRoundId string - 局号Id 用于构建集合名
+ * @return *types.RedisInfo - 玩家集合redis信息
  */
 
-func GetBetInfo(gameRoomId, gameRoundId string) *types.HashRedisInfo {
-	return redistool.BuildHashRedisInfo(
+func GetBetOrderUserRedisInfo(gameRoomId, gameRoundId string) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
 		redistool.GetRedisExpireDuration(),
 		betFileKeyPrefix,
-		betPrefix,
-		gameRoomId,
-		gameRoundId,
+		betOrderUserPrefix,
+		betRoundHashTag(gameRoomId, gameRoundId),
 	)
 }
 
+// betRoundHashTag 构造局维度的hash tag
+func betRoundHashTag(gameRoomId, gameRoundId string) string {
+	return "{" + gameRoomId + "-" + gameRoundId + "}"
+}
+
 /**
  * GetBetLockRedisInfo
  * 下注redis锁信息
@@ -0,0 +1,23 @@
+package rediskey
+
+import (
+	"sl.framework.com/game_server/redis/redis_tool"
+	"sl.framework.com/game_server/redis/types"
+	"strconv"
+	"time"
+)
+
+const (
+	gameResettleAuditPrefix = "GameResettleAudit"
+)
+
+// GetGameResettleAuditRedisInfo 重新结算审计记录redis信息 审计记录保留7天
+func GetGameResettleAuditRedisInfo(gameRoomId, gameRoundId int64) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		time.Duration(7*24)*time.Hour,
+		gameFileKeyPrefix,
+		gameResettleAuditPrefix,
+		strconv.FormatInt(gameRoomId, 10),
+		strconv.FormatInt(gameRoundId, 10),
+	)
+}
@@ -2,6 +2,7 @@ package rediskey
 
 import (
 	"fmt"
+	"sl.framework.com/game_server/conf"
 	"sl.framework.com/game_server/redis/redis_tool"
 	"sl.framework.com/game_server/redis/types"
 	"strconv"
@@ -19,30 +20,58 @@ const (
 	roomInfoFileKeyPrefix = "RoomInfo"
 )
 
-// 玩家进入房间 离开房间
+/*
+玩家进入房间 离开房间
+
+	有序集合:	RoomPresence:{roomId}	member为userId score为最后活动时间(ms)
+	hash表:	RoomPlayers:{roomId}	field为userId value为币种
+	集合:		RoomPresenceRooms		有玩家进入过的房间 供清理任务遍历
+	房间id使用hash tag 保证集群模式下同一个房间的两个key在同一个slot 可以在lua脚本中一起修改
+*/
 const (
-	roomActionPrefix     = "JoinLeaveGameRoom"
-	roomActionLockPrefix = "RoomActionLock"
+	roomPresencePrefix          = "RoomPresence"
+	roomPlayersPrefix           = "RoomPlayers"
+	roomPresenceRoomsPrefix     = "RoomPresenceRooms"
+	roomPresenceSweepLockPrefix = "RoomPresenceSweepLock"
 )
 
-// GetRoomActionRedisInfo 房间用户key信息
-// 如:{serverRedisKeyPrefix}:JoinLeaveGameRoom:JoinLeaveGameRoom
-func GetRoomActionRedisInfo(roomId int64) *types.RedisInfo {
+// GetRoomPresenceRedisInfo 房间在线玩家有序集合key信息 过期时间为玩家在线超时时间 每次活动刷新
+// 如:{serverRedisKeyPrefix}:RoomInfo:RoomPresence:{3001}
+func GetRoomPresenceRedisInfo(roomId int64) *types.RedisInfo {
 	return redistool.BuildRedisInfo(
-		time.Duration(15)*time.Minute,
+		time.Duration(conf.GetPresenceExpired())*time.Second,
 		roomInfoFileKeyPrefix,
-		roomActionPrefix,
-		strconv.FormatInt(roomId, 10),
+		roomPresencePrefix,
+		"{"+strconv.FormatInt(roomId, 10)+"}",
 	)
 }
 
-// GetRoomActionLockRedisInfo 房间用户key信息锁 没有必要加锁
-func GetRoomActionLockRedisInfo(roomId int64) *types.RedisLockInfo {
+// GetRoomPlayersRedisInfo 房间在线玩家币种hash表key信息
+// 如:{serverRedisKeyPrefix}:RoomInfo:RoomPlayers:{3001}
+func GetRoomPlayersRedisInfo(roomId int64) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		time.Duration(conf.GetPresenceExpired())*time.Second,
+		roomInfoFileKeyPrefix,
+		roomPlayersPrefix,
+		"{"+strconv.FormatInt(roomId, 10)+"}",
+	)
+}
+
+// GetRoomPresenceRoomsRedisInfo 有玩家进入过的房间集合key信息
+func GetRoomPresenceRoomsRedisInfo() *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		time.Duration(24)*time.Hour,
+		roomInfoFileKeyPrefix,
+		roomPresenceRoomsPrefix,
+	)
+}
+
+// GetRoomPresenceSweepLockRedisInfo 清理超时玩家的锁 不主动释放 过期前其他节点跳过本轮清理
+func GetRoomPresenceSweepLockRedisInfo(expiration time.Duration) *types.RedisLockInfo {
 	return redistool.BuildRedisLockInfo(
-		types.RedisLockExpireDuration,
+		expiration,
 		roomInfoFileKeyPrefix,
-		roomActionLockPrefix,
-		strconv.FormatInt(roomId, 10),
+		roomPresenceSweepLockPrefix,
 	)
 }
 
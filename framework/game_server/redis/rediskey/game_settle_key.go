@@ -10,6 +10,9 @@ import (
 const (
 	gameResultPrefix     = "GameResult"
 	gameResultLockPrefix = "GameResultLock"
+
+	settleLedgerPrefix    = "SettleLedger"    //结算台账
+	settleShardLockPrefix = "SettleShardLock" //开奖分片结算锁
 )
 
 // GetGameResultRedisInfo 游戏结果redis信息
@@ -34,6 +37,48 @@ func GetGameResultLockRedisInfo(gameRoomId, gameRoundId int64) *types.RedisLockI
 	)
 }
 
+/**
+ * GetSettleLedgerRedisInfo
+ * 结算台账redis信息 每局一个hash表 field为注单号 value为台账记录json
+ * 台账需要覆盖mq消息的最长重投时间 保留1天
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @return *types.RedisInfo - redis信息
+ */
+
+func GetSettleLedgerRedisInfo(gameRoomId, gameRoundId int64) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		time.Duration(24)*time.Hour,
+		gameFileKeyPrefix,
+		settleLedgerPrefix,
+		strconv.FormatInt(gameRoomId, 10),
+		strconv.FormatInt(gameRoundId, 10),
+	)
+}
+
+/**
+ * GetSettleShardLockRedisInfo
+ * 开奖分片结算锁 同一分片重复投递时只允许一个消费者处理
+ * 过期时间需要覆盖一次分片结算的耗时 包括平台中心请求重试
+ *
+ * @param gameRoomId int64 - 房间Id
+ * @param gameRoundId int64 - 局Id
+ * @param shardNo int - 分片序号
+ * @return *types.RedisLockInfo - redis锁信息
+ */
+
+func GetSettleShardLockRedisInfo(gameRoomId, gameRoundId int64, shardNo int) *types.RedisLockInfo {
+	return redistool.BuildRedisLockInfo(
+		time.Duration(60)*time.Second,
+		gameFileKeyPrefix,
+		settleShardLockPrefix,
+		strconv.FormatInt(gameRoomId, 10),
+		strconv.FormatInt(gameRoundId, 10),
+		strconv.Itoa(shardNo),
+	)
+}
+
 //
 /**
  * GetGameRoundResultCacheKey
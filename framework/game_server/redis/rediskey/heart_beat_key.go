@@ -1,7 +1,6 @@
 package rediskey
 
 import (
-	"sl.framework.com/game_server/conf"
 	"sl.framework.com/game_server/redis/redis_tool"
 	"sl.framework.com/game_server/redis/types"
 	"time"
@@ -14,26 +13,32 @@ const (
 
 const (
 	/*
-		HeartbeatStatus保存服务心跳状态的key
+		ClusterMembers保存集群成员的有序集合 member为serverId score为最后心跳时间(ms)
+		ClusterNodes保存集群节点信息的hash表 field为serverId value为节点信息json
 	*/
-	heartbeatStatusPrefix     = "HeartbeatStatus"
-	heartbeatStatusLockPrefix = "HeartbeatStatusLock"
+	clusterMembersPrefix = "ClusterMembers"
+	clusterNodesPrefix   = "ClusterNodes"
+
+	// clusterKeyExpire 集群key的过期时间 每次心跳刷新 全部节点下线后自动清理
+	clusterKeyExpire = time.Hour
 )
 
-// GetHeartbeatStatusRedisInfo 服务器心跳状态的key信息
-// 如:G32FastBacGameServer:Heartbeat:HeartbeatStatus:65
-func GetHeartbeatStatusRedisInfo() *types.RedisInfo {
+// GetClusterMembersRedisInfo 集群成员有序集合的key信息
+// 如:G32FastBacGameServer:Heartbeat:ClusterMembers
+func GetClusterMembersRedisInfo() *types.RedisInfo {
 	return redistool.BuildRedisInfo(
-		time.Duration(conf.GetHeartbeatExpired())*time.Second,
+		clusterKeyExpire,
 		heartbeatFileKeyPrefix,
-		heartbeatStatusPrefix,
+		clusterMembersPrefix,
 	)
 }
 
-// GetHeartbeatStatusLockRedisInfo 服务器心跳状态的key信息锁
-func GetHeartbeatStatusLockRedisInfo() *types.RedisLockInfo {
-	return redistool.BuildRedisLockInfo(
-		time.Duration(conf.GetHeartbeatExpired())*time.Second,
-		heartbeatStatusLockPrefix,
-		gameNextGameRoundIdLockPrefix)
+// GetClusterNodesRedisInfo 集群节点信息hash表的key信息
+// 如:G32FastBacGameServer:Heartbeat:ClusterNodes
+func GetClusterNodesRedisInfo() *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		clusterKeyExpire,
+		heartbeatFileKeyPrefix,
+		clusterNodesPrefix,
+	)
 }
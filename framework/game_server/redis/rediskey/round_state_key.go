@@ -0,0 +1,20 @@
+package rediskey
+
+import (
+	"sl.framework.com/game_server/redis/redis_tool"
+	"sl.framework.com/game_server/redis/types"
+	"time"
+)
+
+const (
+	gameRoundStatePrefix = "RoundState"
+)
+
+// GetRoundStateRedisInfo 房间局状态redis信息 hash field为房间id 每次迁移时续期
+func GetRoundStateRedisInfo() *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		time.Duration(7*24)*time.Hour,
+		gameFileKeyPrefix,
+		gameRoundStatePrefix,
+	)
+}
@@ -0,0 +1,46 @@
+package rediskey
+
+import (
+	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/redis/redis_tool"
+	"sl.framework.com/game_server/redis/types"
+	"strconv"
+	"time"
+)
+
+const (
+	// workerIdFileKeyPrefix 避免key重复 每个文件中要使用一个key与其他文件区别
+	workerIdFileKeyPrefix = "WorkerId"
+)
+
+/*
+雪花算法节点id租约
+
+	字符串:	WorkerId:Lease:{workerId}	value为持有者 过期时间为租约超时时间 由心跳续期
+	hash表:	WorkerId:LastTime			field为workerId value为该节点id最后一次生成id的时间(ms) 用于检测重启前后的时钟回拨
+*/
+const (
+	workerIdLeasePrefix    = "Lease"
+	workerIdLastTimePrefix = "LastTime"
+)
+
+// GetWorkerIdLeaseRedisInfo 节点id租约key信息
+// 如:{serverRedisKeyPrefix}:WorkerId:Lease:12
+func GetWorkerIdLeaseRedisInfo(workerId int64) *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		time.Duration(conf.GetWorkerLeaseExpired())*time.Second,
+		workerIdFileKeyPrefix,
+		workerIdLeasePrefix,
+		strconv.FormatInt(workerId, 10),
+	)
+}
+
+// GetWorkerIdLastTimeRedisInfo 节点id最后生成id时间hash表key信息
+// 如:{serverRedisKeyPrefix}:WorkerId:LastTime
+func GetWorkerIdLastTimeRedisInfo() *types.RedisInfo {
+	return redistool.BuildRedisInfo(
+		time.Duration(7*24)*time.Hour,
+		workerIdFileKeyPrefix,
+		workerIdLastTimePrefix,
+	)
+}
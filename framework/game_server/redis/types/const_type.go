@@ -20,7 +20,8 @@ type (
 
 	RedisLockInfo struct {
 		*RedisInfo
-		Owner int64 //key持有者
+		Owner int64  //key持有者
+		Name  string //锁名称 由key的前两段固定前缀组成 不包含业务Id 用于监控指标
 	}
 )
 
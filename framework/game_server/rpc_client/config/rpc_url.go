@@ -14,6 +14,18 @@ DrawResultPost 推送游戏结果
 */
 const DrawResultPostURL = "%v/feign/game/drawResult/%v/%v"
 
+/*
+Refund 取消局退款
+调用接口:/feign/wallet/refund/{gameRoomId}/{gameRoundId}
+*/
+const RefundURL = "%v/feign/wallet/refund/%v/%v"
+
+/*
+Adjust 重新结算补差
+调用接口:/feign/wallet/adjust/{gameRoomId}/{gameRoundId}
+*/
+const AdjustURL = "%v/feign/wallet/adjust/%v/%v"
+
 /*
 *
 *创建现场员工信息
@@ -7,6 +7,7 @@ import (
 	"sl.framework.com/game_server/conf"
 	snowflaker "sl.framework.com/game_server/conf/snow_flake_id"
 	"sl.framework.com/game_server/error_code"
+	"sl.framework.com/game_server/metrics"
 	"sl.framework.com/tool"
 	"sl.framework.com/trace"
 	"strconv"
@@ -105,7 +106,7 @@ func httpSet(traceId, requestId string, request *httplib.BeegoHTTPRequest) *http
 	request.Header(string(httpHeaderTagContentType), contentTypeJson)
 	request.Header(string(httpHeaderTagTraceId), traceId)
 	request.Header(string(httpHeaderTagGameId), strconv.FormatInt(conf.GetGameId(), 10))
-	request.Header(string(httpHeaderToken), conf.ServerConf.AgentToken)
+	request.Header(string(httpHeaderToken), conf.GetAgentToken())
 	if len(requestId) > 0 {
 		request.Header(string(httpHeaderRequestId), requestId)
 	}
@@ -130,9 +131,9 @@ func httpPut(traceId, requestId string, url string) *httplib.BeegoHTTPRequest {
 
 // exceptionResponse 能力中心异常结果结构
 type exceptionResponse struct {
-	code string
-	data string
-	msg  string
+	Code json.RawMessage `json:"code"` //错误码 能力中心有字符串和数字两种格式
+	Data json.RawMessage `json:"data"`
+	Msg  string          `json:"msg"`
 }
 
 // 检测能力中心返回包是否有异常
@@ -150,12 +151,16 @@ func checkExceptionResponse(traceId string, resp []byte) int {
 			trace.Error("%v, unmarshal failed, error=%v", msgHeader, err.Error())
 			return errcode.JsonErrorUnMarshal
 		} else {
-			retCode := errcode.HttpErrorDataFailed
-			switch exceptionResp.code {
-			case "1406": //错误码1406:The draw result repeat 平台错误码是1406时不重试
+			code := strings.Trim(string(exceptionResp.Code), `"`)
+			retCode := errcode.HttpErrorPlatformReply
+			switch {
+			case code == "1406": //错误码1406:The draw result repeat 平台错误码是1406时不重试
 				retCode = errcode.ErrorOk
+			case len(code) == 0 || (len(code) == 3 && code[0] == '5'):
+				//没有错误码或者5xx为能力中心内部异常 可以重试
+				retCode = errcode.HttpErrorDataFailed
 			}
-			trace.Error("%v, code=%v, msg=%v strResp=%v", msgHeader, exceptionResp.code, exceptionResp.msg, strResp)
+			trace.Error("%v, code=%v, msg=%v strResp=%v", msgHeader, code, exceptionResp.Msg, strResp)
 			return retCode
 		}
 	}
@@ -164,11 +169,11 @@ func checkExceptionResponse(traceId string, resp []byte) int {
 
 type FuncOnTry func() int
 
-// http重试部分的封装
+// http重试部分的封装 能力中心返回业务错误或者id生成器已经停止时重试不会成功 直接返回
 func httpRunOnRetry(fn FuncOnTry) (ret int) {
 	retryTime, retryInterval := conf.GetHttpRetryInfo()
 	for i := 0; i < retryTime; i++ {
-		if ret = fn(); errcode.ErrorOk != ret {
+		if ret = fn(); errcode.ErrorOk != ret && errcode.HttpErrorPlatformReply != ret && errcode.GameErrorServerStopping != ret {
 			time.Sleep(time.Duration(retryInterval) * time.Millisecond)
 			continue
 		}
@@ -177,6 +182,15 @@ func httpRunOnRetry(fn FuncOnTry) (ret int) {
 	return
 }
 
+// newRequestId 生成请求id id生成器已经停止时返回错误
+func newRequestId() (string, error) {
+	id, err := snowflaker.GetSnowFlakeInstance().GetUniqueId()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
 /**
  * runHttpGet
  * 发起http get请求，其中对http request进行封装，错误重试，数据解析等
@@ -191,10 +205,15 @@ func httpRunOnRetry(fn FuncOnTry) (ret int) {
 func runHttpGet(traceId, msg, url string, receiver interface{}) int {
 	var err error
 	pDog := tool.NewWatcher(msg)
+	begin := time.Now()
 
 	fn := func() int {
 		var data []byte
-		requestId := strconv.FormatInt(snowflaker.GetSnowFlakeInstance().GetUniqueId(), 10)
+		requestId, idErr := newRequestId()
+		if idErr != nil {
+			trace.Error("runHttpGet %v, generate request id failed, error=%v", msg, idErr.Error())
+			return errcode.GameErrorServerStopping
+		}
 		req := httpGet(traceId, requestId, url)
 		if data, err = req.Bytes(); nil != err {
 			trace.Error("runHttpGet %v, http get error=%v", msg, err.Error())
@@ -220,6 +239,7 @@ func runHttpGet(traceId, msg, url string, receiver interface{}) int {
 	code := httpRunOnRetry(fn)
 
 	pDog.Stop()
+	metrics.ObserveRPC(msg, code, time.Since(begin))
 	return code
 }
 
@@ -240,6 +260,7 @@ func runHttpPut(traceId, msg, url string, sender interface{}) int {
 		err  error
 	)
 	pDog := tool.NewWatcher(msg)
+	begin := time.Now()
 
 	if sender != nil {
 		//序列化发送的数据
@@ -257,7 +278,11 @@ func runHttpPut(traceId, msg, url string, sender interface{}) int {
 	//发送数据
 	fn := func() int {
 		var respData []byte
-		requestId := strconv.FormatInt(snowflaker.GetSnowFlakeInstance().GetUniqueId(), 10)
+		requestId, idErr := newRequestId()
+		if idErr != nil {
+			trace.Error("runHttpPut %v, generate request id failed, error=%v", msg, idErr.Error())
+			return errcode.GameErrorServerStopping
+		}
 		req := httpPut(traceId, requestId, url)
 		if len(data) > 0 {
 			req.Body(data) //有数据则放入body中
@@ -281,6 +306,7 @@ func runHttpPut(traceId, msg, url string, sender interface{}) int {
 	code := httpRunOnRetry(fn)
 
 	pDog.Stop()
+	metrics.ObserveRPC(msg, code, time.Since(begin))
 	return code
 }
 
@@ -302,6 +328,7 @@ func runHttpPost(traceId, msg, url string, sender interface{}, receiver interfac
 		err  error
 	)
 	pDog := tool.NewWatcher(msg)
+	begin := time.Now()
 
 	if sender != nil {
 		//序列化发送的数据
@@ -319,7 +346,11 @@ func runHttpPost(traceId, msg, url string, sender interface{}, receiver interfac
 	//发送数据
 	fn := func() int {
 		var respData []byte
-		requestId := strconv.FormatInt(snowflaker.GetSnowFlakeInstance().GetUniqueId(), 10)
+		requestId, idErr := newRequestId()
+		if idErr != nil {
+			trace.Error("runHttpPost %v, generate request id failed, error=%v", msg, idErr.Error())
+			return errcode.GameErrorServerStopping
+		}
 		req := httpPost(traceId, requestId, url)
 		if len(data) > 0 {
 			req.Body(data) //有数据则放入body中
@@ -354,5 +385,6 @@ func runHttpPost(traceId, msg, url string, sender interface{}, receiver interfac
 	code := httpRunOnRetry(fn)
 
 	pDog.Stop()
+	metrics.ObserveRPC(msg, code, time.Since(begin))
 	return code
 }
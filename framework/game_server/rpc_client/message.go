@@ -1,10 +1,11 @@
 package rpcreq
 
 import (
-	"encoding/json"
 	"fmt"
-	"sl.framework.com/async"
 	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/executor"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/outbox"
 	"sl.framework.com/trace"
 	"time"
 )
@@ -24,7 +25,7 @@ type GameMessage[T any] struct {
 
 /**
  * AsyncGameMessageRequest
- * 发送游戏消息
+ * 发送游戏消息 在message协程池中写入发件箱后发送 发送失败由发件箱后台任务重试
  *
  * @param traceId string - traceId 用于日志跟踪
  * @param gameMessage GameMessage - 所发送的游戏消息
@@ -32,25 +33,25 @@ type GameMessage[T any] struct {
  */
 
 func AsyncGameMessageRequest[T any](traceId string, gameMessage GameMessage[T]) {
-	fn := func() { gameMessageRequest(traceId, gameMessage) }
-	async.AsyncRunCoroutine(fn)
+	fn := func() { outbox.Send(traceId, types.OutboxKindGameMessage, "", gameMessage) }
+	if err := executor.Submit(executor.PoolMessage, fn); err != nil {
+		trace.Error("AsyncGameMessageRequest traceId=%v, message rejected, error=%v", traceId, err.Error())
+	}
 }
 
 /**
  * gameMessageRequest
- * 发送游戏消息
+ * 发送游戏消息 发件箱中的游戏消息发送函数
  *
  * @param traceId string - traceId 用于日志跟踪
- * @param gameMessage GameMessage - 所发送的游戏消息
+ * @param message []byte - 序列化后的游戏消息
  * @return int - 请求返回码
  */
 
-func gameMessageRequest[T any](traceId string, gameMessage GameMessage[T]) {
+func gameMessageRequest(traceId string, message []byte) int {
 	url := fmt.Sprintf("%v/feign/message/game/send", conf.GetPlatformInfoUrl())
-	messageBuffer, _ := json.Marshal(gameMessage)
-	message := string(messageBuffer)
-	msg := fmt.Sprintf("gameMessageRequest traceId=%v, gameMessage=%+v, message=%v, url=%v", traceId, gameMessage, message, url)
-	_ = runHttpPost(traceId, msg, url, gameMessage, nil)
+	msg := fmt.Sprintf("gameMessageRequest traceId=%v, message=%v, url=%v", traceId, string(message), url)
+	return runHttpPost(traceId, msg, url, string(message), nil)
 }
 
 /*
@@ -0,0 +1,99 @@
+package rpcreq
+
+import (
+	"encoding/json"
+	"sl.framework.com/game_server/error_code"
+	types "sl.framework.com/game_server/game/service/type"
+	"sl.framework.com/game_server/outbox"
+	"sl.framework.com/trace"
+)
+
+/*
+	派彩 小票 局消息通过发件箱发送 发送失败由发件箱后台任务重试
+	发件箱中保存的是下面的请求内容 后台任务反序列化后调用对应的接口
+*/
+
+type (
+	// settlePayload 派彩请求内容
+	settlePayload struct {
+		GameRoomId      string             `json:"gameRoomId"`
+		GameRoundId     string             `json:"gameRoundId"`
+		OrderSettleList []*types.SettleDTO `json:"orderSettleList"`
+	}
+
+	// receiptPayload 结算小票请求内容
+	receiptPayload struct {
+		GameRoundNo string               `json:"gameRoundNo"`
+		GameRoundId int64                `json:"gameRoundId"`
+		GameRoomId  int64                `json:"gameRoomId"`
+		Message     types.UserMessageDTO `json:"message"`
+	}
+)
+
+func init() {
+	outbox.RegisterDispatcher(types.OutboxKindSettle, dispatchSettle)
+	outbox.RegisterDispatcher(types.OutboxKindReceipt, dispatchReceipt)
+	outbox.RegisterDispatcher(types.OutboxKindGameMessage, gameMessageRequest)
+}
+
+/**
+ * OutboxSettle
+ * 通过发件箱向中台发送订单开奖结果
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param bizKey string - 业务唯一键 同一分片重复结算时只派彩一次
+ * @param gameRoomId string - 房间id
+ * @param gameRoundId string - 局id
+ * @param orderSettleList []*types.SettleDTO - 注单结算结果
+ * @return int - 写入发件箱成功返回errcode.ErrorOk 写入失败时返回直接派彩的结果
+ */
+
+func OutboxSettle(traceId, bizKey, gameRoomId, gameRoundId string, orderSettleList []*types.SettleDTO) int {
+	return outbox.Send(traceId, types.OutboxKindSettle, bizKey, settlePayload{
+		GameRoomId:      gameRoomId,
+		GameRoundId:     gameRoundId,
+		OrderSettleList: orderSettleList,
+	})
+}
+
+/**
+ * OutboxSendReceipts
+ * 通过发件箱发送结算小票到ws集群
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param bizKey string - 业务唯一键 同一玩家同一分片的小票只发送一次
+ * @param gameRoundNo string - 局号
+ * @param gameRoundId int64 - 局Id
+ * @param gameRoomId int64 - 房间Id
+ * @param message types.UserMessageDTO - 小票消息
+ * @return int - 写入发件箱成功返回errcode.ErrorOk 写入失败时返回直接发送的结果
+ */
+
+func OutboxSendReceipts(traceId, bizKey, gameRoundNo string, gameRoundId, gameRoomId int64, message types.UserMessageDTO) int {
+	return outbox.Send(traceId, types.OutboxKindReceipt, bizKey, receiptPayload{
+		GameRoundNo: gameRoundNo,
+		GameRoundId: gameRoundId,
+		GameRoomId:  gameRoomId,
+		Message:     message,
+	})
+}
+
+// dispatchSettle 发件箱派彩发送函数
+func dispatchSettle(traceId string, payload []byte) int {
+	var settle settlePayload
+	if err := json.Unmarshal(payload, &settle); err != nil {
+		trace.Error("dispatchSettle traceId=%v, json unmarshal failed, error=%v, payload=%v", traceId, err.Error(), string(payload))
+		return errcode.JsonErrorUnMarshal
+	}
+	return Settle(traceId, settle.GameRoomId, settle.GameRoundId, settle.OrderSettleList)
+}
+
+// dispatchReceipt 发件箱小票发送函数
+func dispatchReceipt(traceId string, payload []byte) int {
+	var receipt receiptPayload
+	if err := json.Unmarshal(payload, &receipt); err != nil {
+		trace.Error("dispatchReceipt traceId=%v, json unmarshal failed, error=%v, payload=%v", traceId, err.Error(), string(payload))
+		return errcode.JsonErrorUnMarshal
+	}
+	return SendReceipts(traceId, receipt.GameRoundNo, receipt.GameRoundId, receipt.GameRoomId, receipt.Message)
+}
@@ -3,6 +3,7 @@ package rpcreq
 import (
 	"fmt"
 	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/currency/money"
 	types "sl.framework.com/game_server/game/service/type"
 	"sl.framework.com/game_server/rpc_client/config"
 	"sl.framework.com/trace"
@@ -39,11 +40,11 @@ func DrawResultPost(traceId string, res *types.GameRoundResultDTO) int {
 */
 
 type OrderSettled struct {
-	OrderNo            int64   //数据库字段:order_no 订单号
-	WinAmount          float64 //数据库字段:win_amount 如果赢金额,投注完成时计算好,派奖使用
-	AvailableBetAmount float64 //数据库字段:available_bet_amount 有效投注金额
-	DrawOdds           float64 //数据库字段:draw_odds 开奖时赔率
-	OrderWinLostStatus string  //数据库字段:win_lost_status 输赢状态：创建 Create,输 Lose，赢 Win,和 Tie"
+	OrderNo            int64       //数据库字段:order_no 订单号
+	WinAmount          money.Money //数据库字段:win_amount 如果赢金额,投注完成时计算好,派奖使用
+	AvailableBetAmount money.Money //数据库字段:available_bet_amount 有效投注金额
+	DrawOdds           float64     //数据库字段:draw_odds 开奖时赔率
+	OrderWinLostStatus string      //数据库字段:win_lost_status 输赢状态：创建 Create,输 Lose，赢 Win,和 Tie"
 }
 
 /**
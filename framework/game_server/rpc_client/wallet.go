@@ -3,7 +3,9 @@ package rpcreq
 import (
 	"fmt"
 	"sl.framework.com/game_server/conf"
+	"sl.framework.com/game_server/currency/money"
 	"sl.framework.com/game_server/game/service/type/dto"
+	"sl.framework.com/game_server/rpc_client/config"
 	"sl.framework.com/trace"
 )
 
@@ -13,11 +15,11 @@ import (
 */
 
 type BalanceResponse struct {
-	UserId           string  `json:"userId"`           //数据库字段:user_id 用户id
-	FinancialAccount string  `json:"financialAccount"` //数据库字段:financial_account 财务账号,全局唯一
-	Currency         string  `json:"currency"`         //数据库字段:currency 币种编码
-	Balance          float64 `json:"balance"`          //数据库字段:balance 余额
-	Status           string  `json:"status"`           //数据库字段:status 状态:启用 Enable，停用 Disable
+	UserId           string      `json:"userId"`           //数据库字段:user_id 用户id
+	FinancialAccount string      `json:"financialAccount"` //数据库字段:financial_account 财务账号,全局唯一
+	Currency         string      `json:"currency"`         //数据库字段:currency 币种编码
+	Balance          money.Money `json:"balance"`          //数据库字段:balance 余额
+	Status           string      `json:"status"`           //数据库字段:status 状态:启用 Enable，停用 Disable
 }
 
 /**
@@ -65,3 +67,45 @@ func GetTransactionList(traceId string, queryDto *dto.QueryTransactionDTO) ([]*d
 	}
 	return transactionDTO, ret
 }
+
+/**
+ * Refund
+ * 取消局时对已经扣款的注单退款 钱包按订单号幂等处理 重复调用不会重复退款
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param refund *dto.RefundDTO - 退款请求体
+ * @return []*dto.RefundResultDTO - 每个注单的退款结果
+ * @return int - 请求返回码
+ */
+
+func Refund(traceId string, refund *dto.RefundDTO) ([]*dto.RefundResultDTO, int) {
+	url := fmt.Sprintf(config.RefundURL, conf.GetPlatformInfoUrl(), refund.GameRoomId, refund.GameRoundId)
+	msg := fmt.Sprintf("Refund traceId=%v, gameRoomId=%v, gameRoundId=%v, orderNoList=%v, url=%v",
+		traceId, refund.GameRoomId, refund.GameRoundId, refund.OrderNoList, url)
+	trace.Info("取消局退款 msg=%v", msg)
+
+	resultList := make([]*dto.RefundResultDTO, 0)
+	ret := runHttpPost(traceId, msg, url, refund, &resultList)
+	return resultList, ret
+}
+
+/**
+ * Adjust
+ * 重新结算时按新旧派彩差额对玩家余额补差 钱包按批次id和订单号幂等处理
+ *
+ * @param traceId string - traceId 用于日志跟踪
+ * @param adjust *dto.AdjustDTO - 补差请求体
+ * @return []*dto.AdjustResultDTO - 每个注单的补差结果
+ * @return int - 请求返回码
+ */
+
+func Adjust(traceId string, adjust *dto.AdjustDTO) ([]*dto.AdjustResultDTO, int) {
+	url := fmt.Sprintf(config.AdjustURL, conf.GetPlatformInfoUrl(), adjust.GameRoomId, adjust.GameRoundId)
+	msg := fmt.Sprintf("Adjust traceId=%v, resettleId=%v, gameRoomId=%v, gameRoundId=%v, orderSize=%v, url=%v",
+		traceId, adjust.ResettleId, adjust.GameRoomId, adjust.GameRoundId, len(adjust.OrderList), url)
+	trace.Info("重新结算补差 msg=%v", msg)
+
+	resultList := make([]*dto.AdjustResultDTO, 0)
+	ret := runHttpPost(traceId, msg, url, adjust, &resultList)
+	return resultList, ret
+}
@@ -0,0 +1,122 @@
+package sign
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	数据源请求签名
+	数据源(荷官端)调用/game/event等接口时使用各自的密钥对请求签名 游戏服校验签名 时间戳以及nonce
+	签名内容为以下字段以\n连接:
+		HTTP方法(大写)
+		请求路径(不含query)
+		数据源Id
+		时间戳(毫秒)
+		nonce
+		body的sha256(十六进制小写)
+	签名算法为HMAC-SHA256 结果使用十六进制小写放入Signature头
+*/
+
+// http头
+const (
+	HeaderSourceId  = "Source-Id" //数据源Id 用于查找密钥
+	HeaderTimestamp = "timestamp" //毫秒时间戳 和原有的timestamp头保持一致
+	HeaderNonce     = "Nonce"     //随机串 在重放窗口内不能重复
+	HeaderSignature = "Signature" //签名
+)
+
+/**
+ * Sign
+ * 计算请求签名
+ *
+ * @param secret string - 数据源密钥
+ * @param method string - http方法
+ * @param path string - 请求路径 不包含query
+ * @param sourceId string - 数据源Id
+ * @param timestamp string - 毫秒时间戳
+ * @param nonce string - 随机串
+ * @param body []byte - 请求body
+ * @return string - 十六进制签名
+ */
+
+func Sign(secret, method, path, sourceId, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	content := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		sourceId,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(content))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名 使用常量时间比较
+func Verify(secret, method, path, sourceId, timestamp, nonce string, body []byte, signature string) bool {
+	expected := Sign(secret, method, path, sourceId, timestamp, nonce, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+/**
+ * CheckTimestamp
+ * 校验时间戳是否在允许的偏差范围内
+ *
+ * @param timestamp string - 毫秒时间戳
+ * @param now time.Time - 当前时间
+ * @param window time.Duration - 允许的偏差 前后均可
+ * @return bool - 时间戳是否有效
+ */
+
+func CheckTimestamp(timestamp string, now time.Time, window time.Duration) bool {
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	diff := now.Sub(time.UnixMilli(ms))
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= window
+}
+
+// NewNonce 生成随机nonce
+func NewNonce() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+/**
+ * SignRequest
+ * 为http请求设置签名相关的头 供数据源以及测试工具使用
+ * body需要和请求中实际发送的body一致
+ *
+ * @param req *http.Request - http请求
+ * @param sourceId string - 数据源Id
+ * @param secret string - 数据源密钥
+ * @param body []byte - 请求body
+ * @return
+ */
+
+func SignRequest(req *http.Request, sourceId, secret string, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	nonce := NewNonce()
+
+	req.Header.Set(HeaderSourceId, sourceId)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, Sign(secret, req.Method, req.URL.Path, sourceId, timestamp, nonce, body))
+}
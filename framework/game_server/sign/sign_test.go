@@ -0,0 +1,79 @@
+package sign
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"command":"Bet_Start"}`)
+	signature := Sign("secret", "post", "/game/event", "dealer-1", "1700000000000", "n1", body)
+	if signature != Sign("secret", "POST", "/game/event", "dealer-1", "1700000000000", "n1", body) {
+		t.Fatalf("Sign() must not depend on method case")
+	}
+	if !Verify("secret", "POST", "/game/event", "dealer-1", "1700000000000", "n1", body, signature) {
+		t.Errorf("Verify() = false, want true")
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		path      string
+		timestamp string
+		nonce     string
+		body      []byte
+	}{
+		{name: "secret", secret: "other", path: "/game/event", timestamp: "1700000000000", nonce: "n1", body: body},
+		{name: "path", secret: "secret", path: "/game/round", timestamp: "1700000000000", nonce: "n1", body: body},
+		{name: "timestamp", secret: "secret", path: "/game/event", timestamp: "1700000000001", nonce: "n1", body: body},
+		{name: "nonce", secret: "secret", path: "/game/event", timestamp: "1700000000000", nonce: "n2", body: body},
+		{name: "body", secret: "secret", path: "/game/event", timestamp: "1700000000000", nonce: "n1", body: []byte(`{}`)},
+	}
+	for _, tt := range tests {
+		if Verify(tt.secret, "POST", tt.path, "dealer-1", tt.timestamp, tt.nonce, tt.body, signature) {
+			t.Errorf("Verify() with modified %v = true, want false", tt.name)
+		}
+	}
+}
+
+func TestCheckTimestamp(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	tests := []struct {
+		timestamp string
+		want      bool
+	}{
+		{timestamp: "1700000000000", want: true},
+		{timestamp: "1699999700000", want: true},
+		{timestamp: "1700000300000", want: true},
+		{timestamp: "1699999699999", want: false},
+		{timestamp: "1700000300001", want: false},
+		{timestamp: "", want: false},
+		{timestamp: "abc", want: false},
+	}
+	for _, tt := range tests {
+		if got := CheckTimestamp(tt.timestamp, now, 5*time.Minute); got != tt.want {
+			t.Errorf("CheckTimestamp(%q) = %v, want %v", tt.timestamp, got, tt.want)
+		}
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	body := []byte(`{"command":"Bet_Stop"}`)
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/game/event?debug=1", bytes.NewReader(body))
+	SignRequest(req, "dealer-1", "secret", body)
+
+	h := req.Header
+	if h.Get(HeaderSourceId) != "dealer-1" || len(h.Get(HeaderNonce)) != 32 {
+		t.Fatalf("SignRequest() headers = %v", h)
+	}
+	if _, err := strconv.ParseInt(h.Get(HeaderTimestamp), 10, 64); err != nil {
+		t.Fatalf("SignRequest() timestamp = %v", h.Get(HeaderTimestamp))
+	}
+	if !Verify("secret", http.MethodPost, "/game/event", h.Get(HeaderSourceId), h.Get(HeaderTimestamp),
+		h.Get(HeaderNonce), body, h.Get(HeaderSignature)) {
+		t.Errorf("signed request does not verify")
+	}
+}
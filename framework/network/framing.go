@@ -0,0 +1,264 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	framing 二进制拆包
+	所有包头都以大端的 Cmd uint32 Size uint32 Seq uint32 开头 Size为包头加包体的总长度
+	Default_Packet_Header在此之后还有 Session uint16 Version uint16 两个保留字段
+	包头中的长度不可信 拆包前必须校验下限(包头长度)和上限(按命令字配置的最大包长)
+*/
+
+const (
+	frameSizeOffset    = 4  //包头中Size的偏移
+	frameSeqOffset     = 8  //包头中Seq的偏移
+	frameVersionOffset = 14 //Default_Packet_Header中Version的偏移 CRC校验码存放在这里
+	frameCrcHeaderSize = 16 //开启CRC校验要求的最小包头长度
+)
+
+var (
+	Conf_MaxPacketSize   uint32 = 1024 * 1024      /* 没有单独配置的命令字的最大包长 */
+	Conf_BodyReadTimeout        = time.Second * 30 /* 收到包头后读完包体的超时时间 */
+)
+
+var (
+	ErrFrameTooShort   = errors.New("frame size smaller than header")
+	ErrFrameTooLarge   = errors.New("frame size exceeds limit")
+	ErrFrameUnknownCmd = errors.New("frame command not registered")
+	ErrFrameChecksum   = errors.New("frame checksum mismatch")
+	ErrFrameSequence   = errors.New("frame sequence out of order")
+)
+
+// frameKinds 拒绝原因 顺序与rejectedFrames的下标一致
+var frameKinds = []error{ErrFrameTooShort, ErrFrameTooLarge, ErrFrameUnknownCmd, ErrFrameChecksum, ErrFrameSequence}
+var frameKindNames = []string{"too_short", "too_large", "unknown_cmd", "checksum", "sequence"}
+
+var (
+	rejectedFrames [5]atomic.Uint64
+	rejectHook     atomic.Pointer[FrameRejectHook]
+	maxPacketSizes sync.Map //cmd -> uint32
+)
+
+// FrameRejectHook 包被拒绝时的回调 kind与RejectedFrames的key一致 在读包的协程中同步调用 不能阻塞
+type FrameRejectHook func(kind string, err *FrameError)
+
+// FrameError 拆包错误 Err为ErrFrame*之一 可以用errors.Is判断
+type FrameError struct {
+	Err  error
+	Cmd  uint32
+	Size uint32
+	Seq  uint32
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("%v, cmd=0x%06x size=%d seq=%d", e.Err, e.Cmd, e.Size, e.Seq)
+}
+
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// SetMaxPacketSize 设置单个命令字的最大包长 size为0表示恢复使用Conf_MaxPacketSize
+func SetMaxPacketSize(cmd uint32, size uint32) {
+	if size == 0 {
+		maxPacketSizes.Delete(cmd)
+		return
+	}
+	maxPacketSizes.Store(cmd, size)
+}
+
+// MaxPacketSize 命令字的最大包长
+func MaxPacketSize(cmd uint32) uint32 {
+	if v, ok := maxPacketSizes.Load(cmd); ok {
+		return v.(uint32)
+	}
+	return Conf_MaxPacketSize
+}
+
+// RejectedFrames 各原因被拒绝的包数量 key为too_short too_large unknown_cmd checksum sequence
+func RejectedFrames() map[string]uint64 {
+	stats := make(map[string]uint64, len(frameKindNames))
+	for i, name := range frameKindNames {
+		stats[name] = rejectedFrames[i].Load()
+	}
+	return stats
+}
+
+// SetFrameRejectHook 设置包被拒绝时的回调 用于接入监控 fn为nil表示取消
+func SetFrameRejectHook(fn FrameRejectHook) {
+	if fn == nil {
+		rejectHook.Store(nil)
+		return
+	}
+	rejectHook.Store(&fn)
+}
+
+func reject(err *FrameError) *FrameError {
+	for i, kind := range frameKinds {
+		if kind == err.Err {
+			rejectedFrames[i].Add(1)
+			if hook := rejectHook.Load(); hook != nil {
+				(*hook)(frameKindNames[i], err)
+			}
+			break
+		}
+	}
+	return err
+}
+
+// Checksum 包体的校验码 取crc32(IEEE)的低16位 写入Default_Packet_Header.Version
+func Checksum(body []byte) uint16 {
+	return uint16(crc32.ChecksumIEEE(body))
+}
+
+/**
+ * SealPacket
+ * 发送方开启CRC校验时调用 按包体计算校验码并写入包头的Version字段
+ * buf必须是以Default_Packet_Header开头的完整数据包
+ *
+ * @param buf []byte - 完整数据包
+ */
+
+func SealPacket(buf []byte) {
+	if len(buf) < frameCrcHeaderSize {
+		return
+	}
+	binary.BigEndian.PutUint16(buf[frameVersionOffset:], Checksum(buf[frameCrcHeaderSize:]))
+}
+
+/**
+ * FrameDecoder
+ * 从字节流中拆出完整数据包 一条连接一个 不能并发使用
+ * Check返回包的总长度 返回(0, nil)表示命令字不认识但保持连接 此时按包头中的Size跳过包体继续读下一个包
+ * Check为nil时直接使用包头中的Size
+ */
+
+type FrameDecoder struct {
+	HeaderSize uint32
+	Check      fnCheckPacketCallback
+	CheckCRC   bool              //校验Version中的CRC 要求包头为Default_Packet_Header
+	CheckSeq   bool              //要求Seq逐包加1
+	OnHeader   func(size uint32) //包头校验通过后 读包体之前调用 用于设置包体的读超时
+
+	lastSeq uint32
+	hasSeq  bool
+}
+
+// NewFrameDecoder 创建拆包器
+func NewFrameDecoder(headerSize uint32, check fnCheckPacketCallback) *FrameDecoder {
+	return &FrameDecoder{HeaderSize: headerSize, Check: check}
+}
+
+/**
+ * ReadFrame
+ * 读取一个完整的数据包 包含包头
+ * 长度 CRC 序列号不合法时返回*FrameError 调用方应当关闭连接 io错误原样返回
+ *
+ * @param r io.Reader - 字节流
+ * @return []byte - 完整数据包
+ * @return error - 错误
+ */
+
+func (d *FrameDecoder) ReadFrame(r io.Reader) ([]byte, error) {
+	for {
+		head := make([]byte, d.HeaderSize)
+		if _, err := io.ReadFull(r, head); err != nil {
+			return nil, err
+		}
+		pktLen, err := d.check(head)
+		if err != nil {
+			return nil, err
+		}
+		ferr := &FrameError{Size: pktLen}
+		if d.HeaderSize >= 4 {
+			ferr.Cmd = binary.BigEndian.Uint32(head)
+		}
+		if d.HeaderSize >= frameSeqOffset+4 {
+			ferr.Seq = binary.BigEndian.Uint32(head[frameSeqOffset:])
+		}
+
+		if pktLen == 0 {
+			//命令字不认识 按包头中的Size跳过 Size本身也必须合法 否则无法继续拆包
+			ferr.Err = ErrFrameUnknownCmd
+			if d.HeaderSize < frameSizeOffset+4 {
+				return nil, reject(ferr)
+			}
+			ferr.Size = binary.BigEndian.Uint32(head[frameSizeOffset:])
+			if err = d.checkSize(ferr); err != nil {
+				return nil, err
+			}
+			reject(ferr)
+			if _, err = io.CopyN(io.Discard, r, int64(ferr.Size-d.HeaderSize)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err = d.checkSize(ferr); err != nil {
+			return nil, err
+		}
+		if d.OnHeader != nil {
+			d.OnHeader(pktLen)
+		}
+		frame := make([]byte, pktLen)
+		copy(frame, head)
+		if _, err = io.ReadFull(r, frame[d.HeaderSize:]); err != nil {
+			return nil, err
+		}
+		if err = d.verify(frame, ferr); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+}
+
+// check 没有设置Check时直接使用包头中的Size
+func (d *FrameDecoder) check(head []byte) (uint32, error) {
+	if d.Check != nil {
+		return d.Check(head)
+	}
+	if d.HeaderSize < frameSizeOffset+4 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint32(head[frameSizeOffset:]), nil
+}
+
+// checkSize 包长必须在[包头长度, 命令字最大包长]之间
+func (d *FrameDecoder) checkSize(ferr *FrameError) error {
+	if ferr.Size < d.HeaderSize {
+		ferr.Err = ErrFrameTooShort
+		return reject(ferr)
+	}
+	if ferr.Size > MaxPacketSize(ferr.Cmd) {
+		ferr.Err = ErrFrameTooLarge
+		return reject(ferr)
+	}
+	return nil
+}
+
+// verify 按配置校验CRC和序列号
+func (d *FrameDecoder) verify(frame []byte, ferr *FrameError) error {
+	if d.CheckCRC && d.HeaderSize >= frameCrcHeaderSize {
+		if binary.BigEndian.Uint16(frame[frameVersionOffset:]) != Checksum(frame[d.HeaderSize:]) {
+			ferr.Err = ErrFrameChecksum
+			return reject(ferr)
+		}
+	}
+	if d.CheckSeq && d.HeaderSize >= frameSeqOffset+4 {
+		if d.hasSeq && ferr.Seq != d.lastSeq+1 {
+			ferr.Err = ErrFrameSequence
+			return reject(ferr)
+		}
+		d.lastSeq, d.hasSeq = ferr.Seq, true
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+const testHeaderSize = 16
+
+// testFrame 构造以Default_Packet_Header开头的数据包 size为0时按实际长度填写
+func testFrame(cmd, size, seq uint32, body []byte) []byte {
+	buf := make([]byte, testHeaderSize, testHeaderSize+len(body))
+	if size == 0 {
+		size = uint32(testHeaderSize + len(body))
+	}
+	binary.BigEndian.PutUint32(buf, cmd)
+	binary.BigEndian.PutUint32(buf[4:], size)
+	binary.BigEndian.PutUint32(buf[8:], seq)
+	buf = append(buf, body...)
+	SealPacket(buf)
+	return buf
+}
+
+// testCheck 只认识0x01和0x02两个命令字
+func testCheck(buf []byte) (uint32, error) {
+	switch binary.BigEndian.Uint32(buf) {
+	case 0x01, 0x02:
+		return binary.BigEndian.Uint32(buf[4:]), nil
+	}
+	return 0, nil
+}
+
+func TestReadFrame(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(testFrame(0x01, 0, 1, []byte("hello")))
+	stream.Write(testFrame(0x09, 0, 2, []byte("skipped")))
+	stream.Write(testFrame(0x02, 0, 3, nil))
+
+	d := NewFrameDecoder(testHeaderSize, testCheck)
+	before := RejectedFrames()["unknown_cmd"]
+	frame, err := d.ReadFrame(&stream)
+	if err != nil || string(frame[testHeaderSize:]) != "hello" {
+		t.Fatalf("frame %q err %v", frame, err)
+	}
+	frame, err = d.ReadFrame(&stream)
+	if err != nil || binary.BigEndian.Uint32(frame) != 0x02 || len(frame) != testHeaderSize {
+		t.Fatalf("frame %q err %v, unknown command should be skipped", frame, err)
+	}
+	if n := RejectedFrames()["unknown_cmd"] - before; n != 1 {
+		t.Errorf("unknown_cmd rejected %d, want 1", n)
+	}
+	if _, err = d.ReadFrame(&stream); !errors.Is(err, io.EOF) {
+		t.Errorf("err %v, want EOF", err)
+	}
+}
+
+func TestReadFrameRejects(t *testing.T) {
+	SetMaxPacketSize(0x02, 64)
+	defer SetMaxPacketSize(0x02, 0)
+
+	cases := []struct {
+		name  string
+		data  []byte
+		check bool
+		want  error
+	}{
+		{"too short", testFrame(0x01, testHeaderSize-1, 1, nil), false, ErrFrameTooShort},
+		{"underflow", testFrame(0x01, 1, 1, nil), false, ErrFrameTooShort},
+		{"huge", testFrame(0x01, 0xffffffff, 1, nil), false, ErrFrameTooLarge},
+		{"per command limit", testFrame(0x02, 0, 1, make([]byte, 64)), false, ErrFrameTooLarge},
+		{"unknown with bad size", testFrame(0x09, 0xfffffff0, 1, nil), false, ErrFrameTooLarge},
+		{"checksum", func() []byte {
+			buf := testFrame(0x01, 0, 1, []byte("body"))
+			buf[len(buf)-1] ^= 0xff
+			return buf
+		}(), true, ErrFrameChecksum},
+	}
+	for _, c := range cases {
+		d := NewFrameDecoder(testHeaderSize, testCheck)
+		d.CheckCRC = c.check
+		_, err := d.ReadFrame(bytes.NewReader(c.data))
+		var ferr *FrameError
+		if !errors.Is(err, c.want) || !errors.As(err, &ferr) {
+			t.Errorf("%s: err %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestReadFrameSequence(t *testing.T) {
+	var stream bytes.Buffer
+	for _, seq := range []uint32{7, 8, 10} {
+		stream.Write(testFrame(0x01, 0, seq, nil))
+	}
+	d := NewFrameDecoder(testHeaderSize, testCheck)
+	d.CheckSeq = true
+	for i := 0; i < 2; i++ {
+		if _, err := d.ReadFrame(&stream); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.ReadFrame(&stream); !errors.Is(err, ErrFrameSequence) {
+		t.Errorf("err %v, want ErrFrameSequence", err)
+	}
+}
+
+func TestReadFrameBodyHook(t *testing.T) {
+	d := NewFrameDecoder(testHeaderSize, testCheck)
+	calls := 0
+	d.OnHeader = func(size uint32) { calls++ }
+	// 包头声明的包长合法但包体不完整 读包体前必须先调用OnHeader设置超时
+	data := testFrame(0x01, testHeaderSize+100, 1, []byte("short"))
+	if _, err := d.ReadFrame(bytes.NewReader(data)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err %v, want ErrUnexpectedEOF", err)
+	}
+	if calls != 1 {
+		t.Errorf("OnHeader called %d times, want 1", calls)
+	}
+}
+
+func TestFrameRejectHook(t *testing.T) {
+	var kinds []string
+	var cmds []uint32
+	SetFrameRejectHook(func(kind string, err *FrameError) {
+		kinds = append(kinds, kind)
+		cmds = append(cmds, err.Cmd)
+	})
+	defer SetFrameRejectHook(nil)
+
+	var stream bytes.Buffer
+	stream.Write(testFrame(0x09, 0, 1, nil))
+	stream.Write(testFrame(0x01, testHeaderSize-1, 2, nil))
+	d := NewFrameDecoder(testHeaderSize, testCheck)
+	if _, err := d.ReadFrame(&stream); !errors.Is(err, ErrFrameTooShort) {
+		t.Fatalf("err %v, want ErrFrameTooShort", err)
+	}
+	if len(kinds) != 2 || kinds[0] != "unknown_cmd" || kinds[1] != "too_short" || cmds[0] != 0x09 || cmds[1] != 0x01 {
+		t.Errorf("hook kinds %v cmds %v", kinds, cmds)
+	}
+}
+
+func FuzzReadFrame(f *testing.F) {
+	f.Add(testFrame(0x01, 0, 1, []byte("hello")), false, false)
+	f.Add(append(testFrame(0x09, 0, 1, []byte("x")), testFrame(0x02, 0, 2, nil)...), true, true)
+	f.Add(testFrame(0x01, 0xffffffff, 0, nil), false, false)
+	f.Add(testFrame(0x02, 3, 0, nil), false, true)
+
+	f.Fuzz(func(t *testing.T, data []byte, checkCRC, checkSeq bool) {
+		d := NewFrameDecoder(testHeaderSize, testCheck)
+		d.CheckCRC, d.CheckSeq = checkCRC, checkSeq
+		r := bytes.NewReader(data)
+		read := 0
+		for {
+			frame, err := d.ReadFrame(r)
+			if err != nil {
+				var ferr *FrameError
+				if !errors.As(err, &ferr) && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Fatalf("unexpected error type %T: %v", err, err)
+				}
+				return
+			}
+			if uint32(len(frame)) < testHeaderSize || uint32(len(frame)) > MaxPacketSize(binary.BigEndian.Uint32(frame)) {
+				t.Fatalf("frame length %d out of bounds", len(frame))
+			}
+			if uint32(len(frame)) != binary.BigEndian.Uint32(frame[4:]) {
+				t.Fatalf("frame length %d does not match header", len(frame))
+			}
+			read += len(frame)
+			if read > len(data) {
+				t.Fatalf("decoded %d bytes from %d bytes of input", read, len(data))
+			}
+		}
+	})
+}
@@ -12,10 +12,24 @@ type InboundConnection struct {
 	*tcpsocket
 }
 
+// InboundConfig 服务端连接配置 CRC和序列号校验需要对端按Default_Packet_Header封包
+type InboundConfig struct {
+	HeaderSize uint32
+	CheckCRC   bool // 校验包头Version中的CRC
+	CheckSeq   bool // 校验包头Seq逐包加1
+}
+
 func NewInboundConnection(conn *net.TCPConn, headerSize uint32) *InboundConnection {
-	return &InboundConnection{
-		tcpsocket: newTcpSocket(conn, headerSize),
+	return NewInboundConnectionWithConfig(conn, InboundConfig{HeaderSize: headerSize})
+}
+
+// NewInboundConnectionWithConfig 按配置创建服务端连接 校验失败的包计入RejectedFrames并断开连接
+func NewInboundConnectionWithConfig(conn *net.TCPConn, cfg InboundConfig) *InboundConnection {
+	c := &InboundConnection{
+		tcpsocket: newTcpSocket(conn, cfg.HeaderSize),
 	}
+	c.SetFraming(cfg.CheckCRC, cfg.CheckSeq)
+	return c
 }
 
 func (c *InboundConnection) Run() {
@@ -0,0 +1,52 @@
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// TestInboundRejectsBadChecksum 服务端开启CRC校验后 校验失败的包被拒绝计数并断开连接
+func TestInboundRejectsBadChecksum(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenTCP() error = %v", err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+	conn, err := listener.AcceptTCP()
+	if err != nil {
+		t.Fatalf("AcceptTCP() error = %v", err)
+	}
+
+	received := make(chan []byte, 1)
+	closed := make(chan struct{})
+	c := NewInboundConnectionWithConfig(conn, InboundConfig{HeaderSize: testHeaderSize, CheckCRC: true})
+	c.OnCheckPacket = testCheck
+	c.OnRecvPacket = func(buf []byte) { received <- buf }
+	c.OnClosed = func() { close(closed) }
+	before := RejectedFrames()["checksum"]
+	c.Run()
+
+	frame := testFrame(0x01, 0, 1, []byte("body"))
+	frame[len(frame)-1] ^= 0xff
+	if _, err = client.Write(frame); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	select {
+	case <-closed:
+	case buf := <-received:
+		t.Fatalf("received %q, want rejected", buf)
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed after bad checksum")
+	}
+	if n := RejectedFrames()["checksum"] - before; n != 1 {
+		t.Errorf("checksum rejected %d, want 1", n)
+	}
+}
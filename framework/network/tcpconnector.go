@@ -59,6 +59,8 @@ type TcpConnector struct {
 	IsLogin       bool
 	HeaderSize    uint32
 	Addr          string
+	CheckCRC      bool // 校验包头Version中的CRC
+	CheckSeq      bool // 校验包头Seq逐包加1
 
 	// private variable
 	msgInfoes       map[uint32]*PbMsgInfo
@@ -123,6 +125,7 @@ func (c *TcpConnector) Connect() error {
 	if c.sock != nil {
 		c.sock.SetAlive(true)
 		c.sock.SetSocketType(c.socketType)
+		c.sock.SetFraming(c.CheckCRC, c.CheckSeq)
 		c.sock.OnCheckPacket = c.cbCheckPacket
 		c.sock.OnRecvPacket = c.cbRecvPacket
 		c.sock.OnClosed = c.cbClosed
@@ -299,11 +302,9 @@ func (c *TcpConnector) cbCheckPacket(buf []byte) (uint32, error) {
 					header.Cmd, header.Size, header.Seq, p.connection.Endpoint())
 			}*/
 		valid := false
-		nLen := header.Size
 		if nil == err {
-			if _, ok := c.msgInfoes[header.Cmd]; ok {
-				valid = nLen >= base.StreamSizeof(header)
-			}
+			// 包长由FrameDecoder按包头长度和最大包长校验
+			_, valid = c.msgInfoes[header.Cmd]
 			// trace.Info("check packet, cmd=%0x, size=%v", header.Cmd, header.Size)
 		}
 		if valid {
@@ -313,6 +314,9 @@ func (c *TcpConnector) cbCheckPacket(buf []byte) (uint32, error) {
 				int(header.Cmd), c.Endpoint())
 			c.Close()
 		}
+		if err == nil {
+			err = reject(&FrameError{Err: ErrFrameUnknownCmd, Cmd: header.Cmd, Size: header.Size, Seq: header.Seq})
+		}
 		return 0, err
 	}
 }
@@ -2,7 +2,6 @@ package network
 
 import (
 	"errors"
-	"io"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -39,6 +38,7 @@ type tcpsocket struct {
 	quitRecvChan  chan bool
 	quitSendChan  chan bool
 	socketType    int32 //0-normal 1-read时没有超时
+	decoder       *FrameDecoder
 }
 
 func newTcpSocket(conn *net.TCPConn, headerSize uint32) *tcpsocket {
@@ -55,6 +55,12 @@ func newTcpSocket(conn *net.TCPConn, headerSize uint32) *tcpsocket {
 		quitSendChan: make(chan bool, 1),
 		socketType:   SocketType_noreadtimeout,
 	}
+	p.decoder = NewFrameDecoder(headerSize, func(buf []byte) (uint32, error) {
+		return p.OnCheckPacket(buf)
+	})
+	p.decoder.OnHeader = func(uint32) {
+		p.conn.SetReadDeadline(time.Now().Add(Conf_BodyReadTimeout))
+	}
 	p.bStart.Set(false)
 	return p
 }
@@ -108,26 +114,13 @@ func (t *tcpsocket) SetAlive(flag bool) {
 
 func (t *tcpsocket) readPacket() (packetRawData, error) {
 	t.conn.SetReadDeadline(time.Now().Add(Conf_ReadBlockTime))
-	msgHead := make([]byte, t.headerSize)
-	if _, err := io.ReadFull(t.conn, msgHead); err != nil {
-		return nil, err
-	}
-	pktLen, err := t.OnCheckPacket(msgHead)
-	if err != nil {
-		return nil, err
-	}
-	msgBuf := msgHead
+	return t.decoder.ReadFrame(t.conn)
+}
 
-	//attention: pktLen is uint32, when minus will be large number cause out of memory. exsample 0-16=4294967200
-	dataSize := int32(pktLen - t.headerSize)
-	if dataSize > 0 {
-		msgBody := make([]byte, dataSize)
-		if _, err := io.ReadFull(t.conn, msgBody); err != nil {
-			return nil, err
-		}
-		msgBuf = append(msgBuf, msgBody...)
-	}
-	return msgBuf, nil
+// SetFraming 开启CRC和序列号校验 必须在doWork之前调用
+func (t *tcpsocket) SetFraming(checkCRC, checkSeq bool) {
+	t.decoder.CheckCRC = checkCRC
+	t.decoder.CheckSeq = checkSeq
 }
 
 func (t *tcpsocket) exitThread() {
@@ -198,7 +191,12 @@ func (t *tcpsocket) doReadPacket() {
 		}
 		pkt, err := t.readPacket()
 		if err != nil {
-			trace.Info("doReadData failed, err=%v, addr=%v", err, t.conn.RemoteAddr())
+			var ferr *FrameError
+			if errors.As(err, &ferr) {
+				trace.Error("doReadData rejected frame, err=%v, addr=%v", err, t.conn.RemoteAddr())
+			} else {
+				trace.Info("doReadData failed, err=%v, addr=%v", err, t.conn.RemoteAddr())
+			}
 			t.Close()
 			break
 		}
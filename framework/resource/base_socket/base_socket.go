@@ -36,11 +36,16 @@ type Socket struct {
 
 // New 创建新的 Socket 并初始化 packetHandlers
 func New(conn *net.TCPConn, stableMode int8, headSize uint32) *Socket {
+	return NewWithConfig(conn, stableMode, network.InboundConfig{HeaderSize: headSize})
+}
+
+// NewWithConfig 按服务端连接配置创建 Socket 用于开启荷官端包的CRC和序列号校验
+func NewWithConfig(conn *net.TCPConn, stableMode int8, cfg network.InboundConfig) *Socket {
 	p := &Socket{
-		conn:       network.NewInboundConnection(conn, headSize),
+		conn:       network.NewInboundConnectionWithConfig(conn, cfg),
 		stableMode: stableMode,
 		createTime: time.Now().Unix(),
-		headSize:   headSize,
+		headSize:   cfg.HeaderSize,
 	}
 	p.hasValidPacket.Set(false)
 	p.conn.OnCheckPacket = p.CheckPacket
@@ -0,0 +1,240 @@
+package dealersim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sl.framework.com/async"
+	"sl.framework.com/resource/protocol"
+	"sl.framework.com/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	writeTimeout = 5 * time.Second
+	replyBuffer  = 1024
+)
+
+var (
+	ErrClosed       = errors.New("dealer client closed")
+	ErrReplyTimeout = errors.New("wait reply timeout")
+)
+
+// RunOptions 执行脚本的参数
+type RunOptions struct {
+	Speed        float64       //回放速度倍数 2表示两倍速 小于等于0表示忽略所有等待
+	ReplyTimeout time.Duration //等待回复的超时时间 0表示不等待回复
+	Keepalive    time.Duration //心跳间隔 0表示不发送心跳
+}
+
+// Client 模拟荷官客户端 一个Client对应一条tcp连接
+type Client struct {
+	conn     net.Conn
+	recorder *Recorder
+	seq      atomic.Uint32
+	writeMu  sync.Mutex
+	replies  chan Packet
+	closed   chan struct{}
+	once     sync.Once
+	err      error //读协程退出的原因 closed关闭后可读
+}
+
+// Dial 连接资源服务器
+func Dial(addr string, timeout time.Duration, recorder *Recorder) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, recorder), nil
+}
+
+/**
+ * NewClient
+ * 使用已经建立的连接创建客户端 并启动读协程
+ *
+ * @param conn net.Conn - tcp连接
+ * @param recorder *Recorder - 会话录制 nil表示不录制
+ * @return *Client - 客户端
+ */
+
+func NewClient(conn net.Conn, recorder *Recorder) *Client {
+	c := &Client{
+		conn:     conn,
+		recorder: recorder,
+		replies:  make(chan Packet, replyBuffer),
+		closed:   make(chan struct{}),
+	}
+	async.AsyncRunCoroutine(c.readLoop)
+	return c
+}
+
+func (c *Client) readLoop() {
+	defer close(c.replies)
+	for {
+		packet, err := ReadPacket(c.conn)
+		if err != nil {
+			c.err = err
+			c.Close()
+			return
+		}
+		c.recorder.Record(DirRecv, 0, packet)
+		select {
+		case c.replies <- packet:
+		default:
+			trace.Warn("dealersim addr %s, reply buffer full, drop cmd=[0x%06x]", c.conn.RemoteAddr(), packet.Cmd)
+		}
+	}
+}
+
+// Endpoint 本地地址
+func (c *Client) Endpoint() string {
+	return c.conn.LocalAddr().String()
+}
+
+// Replies 服务器发来的数据包 连接断开后关闭
+func (c *Client) Replies() <-chan Packet {
+	return c.replies
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.closed)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+/**
+ * Send
+ * 发送一个数据包 序列号自动递增
+ *
+ * @param cmd uint32 - 命令字
+ * @param body any - 包体 定长结构体或者原始[]byte
+ * @return uint32 - 本次使用的序列号
+ * @return error - 发送错误
+ */
+
+func (c *Client) Send(cmd uint32, body any) (uint32, error) {
+	seq := c.seq.Add(1)
+	data := Encode(cmd, seq, body)
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(data); err != nil {
+		return seq, err
+	}
+	c.recorder.Record(DirSend, 0, Packet{Cmd: cmd, Seq: seq, Body: data[protocol.VLPackHeader:]})
+	return seq, nil
+}
+
+/**
+ * Wait
+ * 等待指定命令字的回复 期间收到的其他数据包(心跳 广播等)直接丢弃
+ *
+ * @param ctx context.Context - 上下文
+ * @param cmd uint32 - 期望的命令字
+ * @param timeout time.Duration - 超时时间
+ * @return Packet - 回复
+ * @return error - 超时或者连接断开
+ */
+
+func (c *Client) Wait(ctx context.Context, cmd uint32, timeout time.Duration) (Packet, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case packet, ok := <-c.replies:
+			if !ok {
+				return Packet{}, fmt.Errorf("%w: %v", ErrClosed, c.err)
+			}
+			if packet.Cmd == cmd {
+				return packet, nil
+			}
+		case <-timer.C:
+			return Packet{}, fmt.Errorf("%w: cmd=0x%06x", ErrReplyTimeout, cmd)
+		case <-ctx.Done():
+			return Packet{}, ctx.Err()
+		}
+	}
+}
+
+/**
+ * Run
+ * 按脚本顺序发送数据包 每一步按Speed缩放等待时间
+ *
+ * @param ctx context.Context - 上下文 取消后立即返回
+ * @param script Script - 脚本
+ * @param opt RunOptions - 执行参数
+ * @return error - 第一个失败的步骤
+ */
+
+func (c *Client) Run(ctx context.Context, script Script, opt RunOptions) error {
+	if opt.Keepalive > 0 {
+		stop := c.keepalive(opt.Keepalive)
+		defer stop()
+	}
+	for i, step := range script.Steps {
+		if err := sleep(ctx, scale(step.Delay, opt.Speed)); err != nil {
+			return err
+		}
+		if _, err := c.Send(step.Cmd, step.Body); err != nil {
+			return fmt.Errorf("%s step %d cmd=0x%06x: %w", script.Game, i, step.Cmd, err)
+		}
+		if step.Reply == 0 || opt.ReplyTimeout <= 0 {
+			continue
+		}
+		if _, err := c.Wait(ctx, step.Reply, opt.ReplyTimeout); err != nil {
+			return fmt.Errorf("%s step %d cmd=0x%06x: %w", script.Game, i, step.Cmd, err)
+		}
+	}
+	return nil
+}
+
+// keepalive 定时发送心跳 返回停止函数
+func (c *Client) keepalive(interval time.Duration) func() {
+	stop := make(chan struct{})
+	async.AsyncRunCoroutine(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := c.Send(protocol.CmdDealerKeepalive, nil); err != nil {
+					return
+				}
+			case <-stop:
+				return
+			case <-c.closed:
+				return
+			}
+		}
+	})
+	return func() { close(stop) }
+}
+
+// scale 按速度倍数缩放等待时间
+func scale(d time.Duration, speed float64) time.Duration {
+	if speed <= 0 || d <= 0 {
+		return 0
+	}
+	return time.Duration(float64(d) / speed)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,285 @@
+package dealersim
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sl.framework.com/network"
+	basesk "sl.framework.com/resource/base_socket"
+	"sl.framework.com/resource/protocol"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer 使用base_socket.Socket搭建的资源服务器 对每个荷官命令回复对应的R命令
+type testServer struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	counts map[uint32]int
+	codes  map[string]bool //收到的局号
+}
+
+type testDealer struct {
+	sk     *basesk.Socket
+	server *testServer
+}
+
+var registerOnce sync.Once
+
+func newTestServer(t *testing.T) *testServer {
+	registerOnce.Do(func() {
+		for _, cmd := range []uint32{protocol.CmdDealerLogin, protocol.CmdNewCard, protocol.CmdStartGame, protocol.CmdNewShoe,
+			protocol.CmdChangeCard, protocol.CmdDealerCloseRound, protocol.CmdDealerCancelRound, protocol.CmdDealerKeepalive,
+			protocol.CmdDealerLogout} {
+			cmd := cmd
+			basesk.RegisterProtocolHandler(cmd, func(ds any, packet []byte) { ds.(*testDealer).handle(cmd, packet) })
+		}
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{ln: ln, counts: make(map[uint32]int), codes: make(map[string]bool)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			sk := basesk.New(conn.(*net.TCPConn), 0, protocol.VLPackHeader)
+			sk.SetLoginCommand(protocol.CmdDealerLogin)
+			sk.SetBusinessSocket(&testDealer{sk: sk, server: s})
+			sk.Run()
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (d *testDealer) handle(cmd uint32, packet []byte) {
+	d.server.mu.Lock()
+	d.server.counts[cmd]++
+	if cmd == protocol.CmdStartGame {
+		body := new(RoundBody)
+		if Decode(packet, body) == nil {
+			d.server.codes[Text(body.GmCode[:])] = true
+		}
+	}
+	d.server.mu.Unlock()
+
+	switch cmd {
+	case protocol.CmdDealerLogin:
+		d.sk.SetBConn(true)
+	case protocol.CmdDealerKeepalive, protocol.CmdDealerLogout:
+		return
+	}
+	_ = d.sk.SendPacket(Encode(cmd+0x010000, 0, nil))
+}
+
+func (s *testServer) count(cmd uint32) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[cmd]
+}
+
+func TestBaccaratShoe(t *testing.T) {
+	s := newTestServer(t)
+	opt := ShoeOptions{Vid: "B001", Dealer: "D0001", Rounds: 90, Decks: 1, CancelEvery: 7, ChangeCardEvery: 5, Seed: 1}
+	script := Baccarat(opt)
+
+	c, err := Dial(s.ln.Addr().String(), time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err = c.Run(context.Background(), script, RunOptions{ReplyTimeout: 2 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := s.count(protocol.CmdStartGame); n != 90 {
+		t.Errorf("start game %d, want 90", n)
+	}
+	if n := s.count(protocol.CmdDealerCancelRound); n != 12 {
+		t.Errorf("cancel round %d, want 12", n)
+	}
+	if n := s.count(protocol.CmdDealerCloseRound); n != 78 {
+		t.Errorf("close round %d, want 78", n)
+	}
+	if n := s.count(protocol.CmdNewShoe); n < 2 {
+		t.Errorf("new shoe %d, want at least 2 for 90 rounds of one deck", n)
+	}
+	if len(s.codes) != 90 {
+		t.Errorf("distinct game codes %d, want 90", len(s.codes))
+	}
+}
+
+func TestScriptDeterministic(t *testing.T) {
+	opt := ShoeOptions{Vid: "D001", Dealer: "D0001", Rounds: 30, Seed: 42, Date: time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC)}
+	for _, build := range []func(ShoeOptions) Script{Baccarat, DragonTiger, Roulette} {
+		a, b := build(opt), build(opt)
+		if len(a.Steps) != len(b.Steps) {
+			t.Fatalf("%s steps %d != %d", a.Game, len(a.Steps), len(b.Steps))
+		}
+		for i := range a.Steps {
+			if !bytes.Equal(Encode(a.Steps[i].Cmd, 0, a.Steps[i].Body), Encode(b.Steps[i].Cmd, 0, b.Steps[i].Body)) {
+				t.Fatalf("%s step %d differs", a.Game, i)
+			}
+		}
+		if a.Steps[0].Cmd != protocol.CmdDealerLogin || a.Steps[len(a.Steps)-1].Cmd != protocol.CmdDealerLogout {
+			t.Errorf("%s script must begin with login and end with logout", a.Game)
+		}
+	}
+}
+
+func TestBaccaratDrawRules(t *testing.T) {
+	cases := []struct {
+		banker, third int
+		draw          bool
+	}{
+		{5, -1, true}, {6, -1, false}, {2, 9, true}, {3, 8, false}, {3, 7, true},
+		{4, 1, false}, {4, 2, true}, {5, 3, false}, {5, 4, true}, {6, 6, true}, {6, 5, false}, {7, 6, false},
+	}
+	for _, c := range cases {
+		if got := bankerDraws(c.banker, c.third); got != c.draw {
+			t.Errorf("bankerDraws(%d, %d) = %v, want %v", c.banker, c.third, got, c.draw)
+		}
+	}
+
+	script := Baccarat(ShoeOptions{Vid: "B001", Rounds: 200, Seed: 7})
+	cards := 0
+	for _, step := range script.Steps {
+		switch step.Cmd {
+		case protocol.CmdStartGame:
+			cards = 0
+		case protocol.CmdNewCard:
+			cards++
+		case protocol.CmdDealerCloseRound:
+			if cards < 4 || cards > 6 {
+				t.Fatalf("round dealt %d cards", cards)
+			}
+		}
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	s := newTestServer(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var file bytes.Buffer
+	var fileMu sync.Mutex
+	proxy := NewProxy(s.ln.Addr().String(), NewRecorder(writerFunc(func(p []byte) (int, error) {
+		fileMu.Lock()
+		defer fileMu.Unlock()
+		return file.Write(p)
+	})))
+	go proxy.Serve(ln)
+
+	script := DragonTiger(ShoeOptions{Vid: "T001", Dealer: "D0002", Rounds: 5, BetTime: 20 * time.Millisecond})
+	c, err := Dial(ln.Addr().String(), time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Run(context.Background(), script, RunOptions{Speed: 1, ReplyTimeout: 2 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	var records []Record
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		fileMu.Lock()
+		records, err = ReadRecords(bytes.NewReader(file.Bytes()))
+		fileMu.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) >= 2*len(script.Steps)-1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	sessions := Sessions(records)
+	if len(sessions) != 1 {
+		t.Fatalf("sessions %d, want 1", len(sessions))
+	}
+	sends := 0
+	for _, r := range sessions[0] {
+		if r.Dir == DirSend {
+			sends++
+		}
+	}
+	if sends != len(script.Steps) {
+		t.Fatalf("recorded %d sends, want %d", sends, len(script.Steps))
+	}
+
+	before := s.count(protocol.CmdStartGame)
+	replay, err := Dial(s.ln.Addr().String(), time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+	begin := time.Now()
+	if err = Replay(context.Background(), replay, sessions[0], RunOptions{Speed: 10, ReplyTimeout: 2 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if elapse := time.Since(begin); elapse > time.Second {
+		t.Errorf("replay at 10x took %v", elapse)
+	}
+	if n := s.count(protocol.CmdStartGame) - before; n != 5 {
+		t.Errorf("replayed start game %d, want 5", n)
+	}
+}
+
+func TestRunLoad(t *testing.T) {
+	s := newTestServer(t)
+	result := RunLoad(context.Background(), LoadOptions{
+		Addr:        s.ln.Addr().String(),
+		Dealers:     8,
+		DialTimeout: time.Second,
+		Script: func(i int) Script {
+			return Roulette(ShoeOptions{Vid: "R00" + string(rune('0'+i)), Rounds: 10, ChangeCardEvery: 3, Seed: int64(i)})
+		},
+		Run: RunOptions{ReplyTimeout: 2 * time.Second},
+	})
+	if result.Failed != 0 || result.Succeed != 8 {
+		t.Fatalf("load result %+v", result)
+	}
+	if n := s.count(protocol.CmdChangeCard); n != 8*3 {
+		t.Errorf("change card %d, want 24", n)
+	}
+}
+
+func TestReadPacketRejectsSize(t *testing.T) {
+	cases := []struct {
+		size uint32
+		want error
+	}{
+		{0, network.ErrFrameTooShort},
+		{protocol.VLPackHeader - 1, network.ErrFrameTooShort},
+		{network.MaxPacketSize(protocol.CmdNewCard) + 1, network.ErrFrameTooLarge},
+	}
+	for _, c := range cases {
+		data := Encode(protocol.CmdNewCard, 1, nil)
+		data[4], data[5], data[6], data[7] = byte(c.size>>24), byte(c.size>>16), byte(c.size>>8), byte(c.size)
+		if _, err := ReadPacket(bytes.NewReader(data)); !errors.Is(err, c.want) {
+			t.Errorf("size %d err %v, want %v", c.size, err, c.want)
+		}
+	}
+	packet, err := ReadPacket(bytes.NewReader(Encode(protocol.CmdStartGame, 9, newRound("B001", "B0012601020001"))))
+	if err != nil || packet.Cmd != protocol.CmdStartGame || packet.Seq != 9 {
+		t.Fatalf("packet %+v err %v", packet, err)
+	}
+	body := new(RoundBody)
+	if err = Decode(packet.Body, body); err != nil || Text(body.GmCode[:]) != "B0012601020001" || Text(body.Vid[:]) != "B001" {
+		t.Fatalf("body %+v err %v", body, err)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
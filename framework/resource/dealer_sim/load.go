@@ -0,0 +1,69 @@
+package dealersim
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LoadOptions 压测参数
+type LoadOptions struct {
+	Addr        string             //资源服务器地址
+	Dealers     int                //并发荷官数
+	DialTimeout time.Duration      //连接超时
+	Script      func(i int) Script //第i个荷官的脚本 桌台和荷官号应当各不相同
+	Run         RunOptions
+}
+
+// LoadResult 压测结果
+type LoadResult struct {
+	Succeed int
+	Failed  int
+	Errors  []error //每个失败荷官的错误
+	Elapse  time.Duration
+}
+
+/**
+ * RunLoad
+ * 并发启动Dealers个模拟荷官 各自建立连接执行脚本 全部结束后返回
+ *
+ * @param ctx context.Context - 上下文 取消后所有荷官停止
+ * @param opt LoadOptions - 压测参数
+ * @return LoadResult - 结果汇总
+ */
+
+func RunLoad(ctx context.Context, opt LoadOptions) LoadResult {
+	begin := time.Now()
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		result LoadResult
+	)
+	for i := 0; i < opt.Dealers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := runDealer(ctx, opt, i)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, err)
+				return
+			}
+			result.Succeed++
+		}(i)
+	}
+	wg.Wait()
+	result.Elapse = time.Since(begin)
+	return result
+}
+
+func runDealer(ctx context.Context, opt LoadOptions, i int) error {
+	c, err := Dial(opt.Addr, opt.DialTimeout, nil)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Run(ctx, opt.Script(i), opt.Run)
+}
@@ -0,0 +1,144 @@
+package dealersim
+
+import (
+	"io"
+	"sl.framework.com/base"
+	"sl.framework.com/network"
+	basesk "sl.framework.com/resource/base_socket"
+	"sl.framework.com/resource/protocol"
+)
+
+/*
+	dealersim 荷官端协议模拟器
+	按base_socket的PacketHeader二进制协议(大端 Cmd/Size/Seq 共12字节 Size包含包头)收发数据包
+	包体布局由模拟器按protocol中的VL*定长字段定义 字符串字段右侧补0
+	录制的会话保存原始包体 回放时不依赖这里的包体布局
+*/
+
+// Packet 一个完整的数据包
+type Packet struct {
+	Cmd  uint32
+	Seq  uint32
+	Body []byte
+}
+
+// LoginBody 荷官登录 CmdDealerLogin
+type LoginBody struct {
+	Vid    [protocol.VLVID]byte
+	Dealer [protocol.VLDealer]byte
+}
+
+// RoundBody 开局 结算 取消局 CmdStartGame CmdDealerCloseRound CmdDealerCancelRound
+type RoundBody struct {
+	Vid    [protocol.VLVID]byte
+	GmCode [protocol.VLGame]byte
+}
+
+// CardBody 发牌 换牌 CmdNewCard CmdChangeCard 轮盘的Card为开出的号码
+type CardBody struct {
+	Vid    [protocol.VLVID]byte
+	GmCode [protocol.VLGame]byte
+	Pos    uint8
+	Card   uint8
+}
+
+// ShoeBody 换靴 CmdNewShoe
+type ShoeBody struct {
+	Vid  [protocol.VLVID]byte
+	Shoe [protocol.VLShoe]byte
+}
+
+/**
+ * Encode
+ * 编码数据包 包头Size为包头加包体的总长度
+ *
+ * @param cmd uint32 - 命令字
+ * @param seq uint32 - 序列号
+ * @param body any - 包体 定长结构体或者[]byte nil表示没有包体
+ * @return []byte - 完整数据包
+ */
+
+func Encode(cmd, seq uint32, body any) []byte {
+	var data []byte
+	switch v := body.(type) {
+	case nil:
+	case []byte:
+		data = v
+	default:
+		data = base.SerializeToBytes(v)
+	}
+	header := new(basesk.PacketHeader)
+	header.Init(cmd, uint32(protocol.VLPackHeader+len(data)), seq)
+	return append(base.SerializeToBytes(header), data...)
+}
+
+/**
+ * ReadPacket
+ * 从连接中读取一个完整的数据包 拆包使用network.FrameDecoder
+ * Size小于包头长度或者超过network.MaxPacketSize时返回*network.FrameError
+ *
+ * @param r io.Reader - 连接
+ * @return Packet - 数据包
+ * @return error - 读取错误
+ */
+
+func ReadPacket(r io.Reader) (Packet, error) {
+	frame, err := network.NewFrameDecoder(protocol.VLPackHeader, nil).ReadFrame(r)
+	if err != nil {
+		return Packet{}, err
+	}
+	header := new(basesk.PacketHeader)
+	if err = base.UnSerializeFromBytes(frame[:protocol.VLPackHeader], header); err != nil {
+		return Packet{}, err
+	}
+	return Packet{Cmd: header.Cmd, Seq: header.Seq, Body: frame[protocol.VLPackHeader:]}, nil
+}
+
+// Decode 将包体解码到定长结构体
+func Decode(body []byte, v any) error {
+	return base.UnSerializeFromBytes(body, v)
+}
+
+// fixed 将字符串转为定长字段 超出部分截断 不足部分补0
+func fixed(dst []byte, s string) {
+	copy(dst, s)
+}
+
+// Text 定长字段转回字符串 去掉右侧补的0
+func Text(b []byte) string {
+	n := len(b)
+	for n > 0 && b[n-1] == 0 {
+		n--
+	}
+	return string(b[:n])
+}
+
+func newLogin(vid, dealer string) *LoginBody {
+	body := new(LoginBody)
+	fixed(body.Vid[:], vid)
+	fixed(body.Dealer[:], dealer)
+	return body
+}
+
+func newRound(vid, gmCode string) *RoundBody {
+	body := new(RoundBody)
+	fixed(body.Vid[:], vid)
+	fixed(body.GmCode[:], gmCode)
+	return body
+}
+
+func newCard(vid, gmCode string, pos, card uint8) *CardBody {
+	body := new(CardBody)
+	fixed(body.Vid[:], vid)
+	fixed(body.GmCode[:], gmCode)
+	body.Pos = pos
+	body.Card = card
+	return body
+}
+
+func newShoe(vid, shoe string) *ShoeBody {
+	body := new(ShoeBody)
+	fixed(body.Vid[:], vid)
+	fixed(body.Shoe[:], shoe)
+	return body
+}
@@ -0,0 +1,87 @@
+package dealersim
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sl.framework.com/async"
+	"sl.framework.com/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ * Proxy
+ * 录制真实荷官会话的代理 荷官端连接代理 代理转发到资源服务器
+ * 双向按PacketHeader拆包后原样转发 并记录到Recorder 连接编号从1开始递增
+ */
+
+type Proxy struct {
+	upstream string
+	recorder *Recorder
+	conns    atomic.Uint32
+}
+
+// NewProxy 创建录制代理 upstream为资源服务器地址
+func NewProxy(upstream string, recorder *Recorder) *Proxy {
+	return &Proxy{upstream: upstream, recorder: recorder}
+}
+
+// Serve 接收荷官连接 直到ln被关闭
+func (p *Proxy) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		id := p.conns.Add(1)
+		async.AsyncRunCoroutine(func() { p.serve(id, conn) })
+	}
+}
+
+func (p *Proxy) serve(id uint32, dealer net.Conn) {
+	defer dealer.Close()
+	server, err := net.DialTimeout("tcp", p.upstream, 5*time.Second)
+	if err != nil {
+		trace.Error("dealersim proxy conn=%d dial %s failed, err=%v", id, p.upstream, err)
+		return
+	}
+	defer server.Close()
+	trace.Notice("dealersim proxy conn=%d %s <-> %s", id, dealer.RemoteAddr(), p.upstream)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	async.AsyncRunCoroutine(func() {
+		defer wg.Done()
+		p.pipe(id, DirSend, dealer, server)
+	})
+	async.AsyncRunCoroutine(func() {
+		defer wg.Done()
+		p.pipe(id, DirRecv, server, dealer)
+	})
+	wg.Wait()
+}
+
+// pipe 单向转发 任意一端出错时关闭两端 让另一个方向也退出
+func (p *Proxy) pipe(id uint32, dir string, src, dst net.Conn) {
+	defer src.Close()
+	defer dst.Close()
+	for {
+		packet, err := ReadPacket(src)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				trace.Warn("dealersim proxy conn=%d %s read failed, err=%v", id, dir, err)
+			}
+			return
+		}
+		p.recorder.Record(dir, id, packet)
+		if _, err = dst.Write(Encode(packet.Cmd, packet.Seq, packet.Body)); err != nil {
+			trace.Warn("dealersim proxy conn=%d %s write cmd=[0x%06x] failed, err=%v", id, dir, packet.Cmd, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package dealersim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sl.framework.com/trace"
+	"sync"
+	"time"
+)
+
+// 数据包方向
+const (
+	DirSend = "send" //荷官发往服务器
+	DirRecv = "recv" //服务器发往荷官
+)
+
+// Record 会话中的一个数据包 每行一个json
+type Record struct {
+	At   int64  `json:"at"`   //距离录制开始的毫秒数
+	Conn uint32 `json:"conn"` //连接编号 代理录制多个荷官时区分会话
+	Dir  string `json:"dir"`
+	Cmd  uint32 `json:"cmd"`
+	Seq  uint32 `json:"seq"`
+	Body []byte `json:"body"` //原始包体 base64
+}
+
+// Recorder 会话录制 并发安全 nil表示不录制
+type Recorder struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	start time.Time
+}
+
+// NewRecorder 创建录制 所有数据包以json行写入w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w), start: time.Now()}
+}
+
+// Record 录制一个数据包 写入失败只记日志 不影响会话
+func (r *Recorder) Record(dir string, conn uint32, packet Packet) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := Record{At: time.Since(r.start).Milliseconds(), Conn: conn, Dir: dir, Cmd: packet.Cmd, Seq: packet.Seq,
+		Body: packet.Body}
+	if err := r.enc.Encode(record); err != nil {
+		trace.Error("dealersim record cmd=[0x%06x] failed, err=%v", packet.Cmd, err)
+	}
+}
+
+// ReadRecords 读取录制文件
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	dec := json.NewDecoder(r)
+	for {
+		var record Record
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return records, nil
+			}
+			return records, fmt.Errorf("read record %d: %w", len(records), err)
+		}
+		records = append(records, record)
+	}
+}
+
+// Sessions 按连接编号拆分录制 保持每个连接首次出现的顺序
+func Sessions(records []Record) [][]Record {
+	index := make(map[uint32]int)
+	var sessions [][]Record
+	for _, record := range records {
+		i, ok := index[record.Conn]
+		if !ok {
+			i = len(sessions)
+			index[record.Conn] = i
+			sessions = append(sessions, nil)
+		}
+		sessions[i] = append(sessions[i], record)
+	}
+	return sessions
+}
+
+/**
+ * Replay
+ * 回放一个会话 只重发DirSend的数据包 按录制时的间隔除以Speed等待
+ * ReplyTimeout大于0时 每个录制到的DirRecv数据包都必须在超时时间内收到相同命令字的回复
+ * 序列号由Client重新生成 包体原样发送
+ *
+ * @param ctx context.Context - 上下文
+ * @param c *Client - 客户端
+ * @param session []Record - 一个连接的录制
+ * @param opt RunOptions - 回放参数 Keepalive无效 录制中已经包含心跳
+ * @return error - 发送失败 或者期望的回复没有收到
+ */
+
+func Replay(ctx context.Context, c *Client, session []Record, opt RunOptions) error {
+	var last int64
+	for i, record := range session {
+		switch record.Dir {
+		case DirSend:
+			if err := sleep(ctx, scale(time.Duration(record.At-last)*time.Millisecond, opt.Speed)); err != nil {
+				return err
+			}
+			last = record.At
+			if _, err := c.Send(record.Cmd, record.Body); err != nil {
+				return fmt.Errorf("replay record %d cmd=0x%06x: %w", i, record.Cmd, err)
+			}
+		case DirRecv:
+			if opt.ReplyTimeout <= 0 {
+				continue
+			}
+			if _, err := c.Wait(ctx, record.Cmd, opt.ReplyTimeout); err != nil {
+				return fmt.Errorf("replay record %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
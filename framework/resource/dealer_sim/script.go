@@ -0,0 +1,296 @@
+package dealersim
+
+import (
+	"fmt"
+	"math/rand"
+	"sl.framework.com/resource/protocol"
+	"time"
+)
+
+const (
+	GameBaccarat    = "baccarat"
+	GameDragonTiger = "dragonTiger"
+	GameRoulette    = "roulette"
+)
+
+// 百家乐牌位 闲1 庄1 闲2 庄2 闲3 庄3
+const (
+	PosPlayer1 uint8 = iota + 1
+	PosBanker1
+	PosPlayer2
+	PosBanker2
+	PosPlayer3
+	PosBanker3
+)
+
+// 龙虎牌位
+const (
+	PosDragon uint8 = iota + 1
+	PosTiger
+)
+
+// Step 脚本中的一步 发送前先等待Delay 发送后等待Reply命令字的回复 Reply为0表示不等待
+type Step struct {
+	Cmd   uint32
+	Body  any
+	Delay time.Duration
+	Reply uint32
+}
+
+// Script 一个荷官的完整脚本
+type Script struct {
+	Game  string
+	Steps []Step
+}
+
+// ShoeOptions 生成整靴脚本的参数
+type ShoeOptions struct {
+	Vid             string        //桌台 4位
+	Dealer          string        //荷官 最多8位
+	Rounds          int           //局数 牌不够时自动换靴
+	Decks           int           //牌副数 百家乐默认8副 龙虎默认8副
+	BetTime         time.Duration //开局到发第一张牌之间的下注时间
+	CardInterval    time.Duration //两张牌之间的间隔
+	CancelEvery     int           //每隔多少局取消一局 0表示不取消
+	ChangeCardEvery int           //每隔多少局换一次牌 0表示不换牌
+	Seed            int64         //洗牌随机种子 相同种子生成相同脚本
+	Date            time.Time     //局号和靴号中的日期 默认当前时间
+}
+
+/**
+ * Card
+ * 牌的编码 高4位花色0-3 低4位点数1-13
+ *
+ * @param suit uint8 - 花色
+ * @param rank uint8 - 点数
+ * @return uint8 - 编码后的牌
+ */
+
+func Card(suit, rank uint8) uint8 {
+	return suit<<4 | rank
+}
+
+// Rank 牌的点数
+func Rank(card uint8) uint8 {
+	return card & 0x0f
+}
+
+// baccaratPoint 百家乐点数 10 J Q K计0点
+func baccaratPoint(card uint8) int {
+	if r := int(Rank(card)); r < 10 {
+		return r
+	}
+	return 0
+}
+
+type builder struct {
+	opt    ShoeOptions
+	rnd    *rand.Rand
+	deck   []uint8
+	script Script
+	shoe   int
+	round  int
+}
+
+func newBuilder(game string, opt ShoeOptions) *builder {
+	if opt.Decks <= 0 {
+		opt.Decks = 8
+	}
+	if opt.Date.IsZero() {
+		opt.Date = time.Now()
+	}
+	b := &builder{opt: opt, rnd: rand.New(rand.NewSource(opt.Seed)), script: Script{Game: game}}
+	b.add(Step{Cmd: protocol.CmdDealerLogin, Body: newLogin(opt.Vid, opt.Dealer), Reply: protocol.CmdDealerLoginR})
+	return b
+}
+
+func (b *builder) add(step Step) {
+	b.script.Steps = append(b.script.Steps, step)
+}
+
+// newShoe 换靴并重新洗牌 靴号为桌台加日期时间加靴序号
+func (b *builder) newShoe(cards bool) {
+	b.shoe++
+	shoe := fmt.Sprintf("%s%s%02d", b.opt.Vid, b.opt.Date.Format("0601021504"), b.shoe%100)
+	b.add(Step{Cmd: protocol.CmdNewShoe, Body: newShoe(b.opt.Vid, shoe), Reply: protocol.CmdNewShoeR})
+	if !cards {
+		return
+	}
+	b.deck = b.deck[:0]
+	for i := 0; i < b.opt.Decks; i++ {
+		for suit := uint8(0); suit < 4; suit++ {
+			for rank := uint8(1); rank <= 13; rank++ {
+				b.deck = append(b.deck, Card(suit, rank))
+			}
+		}
+	}
+	b.rnd.Shuffle(len(b.deck), func(i, j int) { b.deck[i], b.deck[j] = b.deck[j], b.deck[i] })
+}
+
+func (b *builder) draw() uint8 {
+	card := b.deck[len(b.deck)-1]
+	b.deck = b.deck[:len(b.deck)-1]
+	return card
+}
+
+// start 开局 返回局号 局号为桌台加日期加4位局序号 共VLGame位
+func (b *builder) start() string {
+	b.round++
+	gmCode := fmt.Sprintf("%s%s%04d", b.opt.Vid, b.opt.Date.Format("060102"), b.round%10000)
+	b.add(Step{Cmd: protocol.CmdStartGame, Body: newRound(b.opt.Vid, gmCode), Reply: protocol.CmdStartGameR})
+	return gmCode
+}
+
+func (b *builder) card(gmCode string, pos, card uint8, delay time.Duration) {
+	b.add(Step{Cmd: protocol.CmdNewCard, Body: newCard(b.opt.Vid, gmCode, pos, card), Delay: delay,
+		Reply: protocol.CmdNewCardR})
+}
+
+// every 第n局是否命中每隔every局一次的事件
+func every(n, every int) bool {
+	return every > 0 && n%every == 0
+}
+
+// finish 结束一局 命中CancelEvery时取消 否则先按ChangeCardEvery换牌再结算
+func (b *builder) finish(gmCode string, change func() (uint8, uint8)) {
+	if every(b.round, b.opt.ChangeCardEvery) && change != nil {
+		pos, card := change()
+		b.add(Step{Cmd: protocol.CmdChangeCard, Body: newCard(b.opt.Vid, gmCode, pos, card), Delay: b.opt.CardInterval,
+			Reply: protocol.CmdChangeCardR})
+	}
+	b.add(Step{Cmd: protocol.CmdDealerCloseRound, Body: newRound(b.opt.Vid, gmCode), Delay: b.opt.CardInterval,
+		Reply: protocol.CmdDealerCloseRoundR})
+}
+
+// cancel 开局后在下注时间结束时取消本局
+func (b *builder) cancel(gmCode string) bool {
+	if !every(b.round, b.opt.CancelEvery) {
+		return false
+	}
+	b.add(Step{Cmd: protocol.CmdDealerCancelRound, Body: newRound(b.opt.Vid, gmCode), Delay: b.opt.BetTime,
+		Reply: protocol.CmdDealerCancelRoundR})
+	return true
+}
+
+func (b *builder) done() Script {
+	b.add(Step{Cmd: protocol.CmdDealerLogout})
+	return b.script
+}
+
+/**
+ * Baccarat
+ * 生成百家乐整靴脚本 按补牌规则发4到6张牌 剩余牌数不足一局时换靴
+ *
+ * @param opt ShoeOptions - 脚本参数
+ * @return Script - 脚本
+ */
+
+func Baccarat(opt ShoeOptions) Script {
+	b := newBuilder(GameBaccarat, opt)
+	b.newShoe(true)
+	for i := 0; i < opt.Rounds; i++ {
+		if len(b.deck) < 6 {
+			b.newShoe(true)
+		}
+		gmCode := b.start()
+		if b.cancel(gmCode) {
+			continue
+		}
+		cards := make([]uint8, 0, 6)
+		deal := func(pos uint8) uint8 {
+			card := b.draw()
+			delay := b.opt.CardInterval
+			if len(cards) == 0 {
+				delay = b.opt.BetTime
+			}
+			cards = append(cards, card)
+			b.card(gmCode, pos, card, delay)
+			return card
+		}
+		player := baccaratPoint(deal(PosPlayer1))
+		banker := baccaratPoint(deal(PosBanker1))
+		player = (player + baccaratPoint(deal(PosPlayer2))) % 10
+		banker = (banker + baccaratPoint(deal(PosBanker2))) % 10
+		if player < 8 && banker < 8 {
+			third := -1
+			if player <= 5 {
+				third = baccaratPoint(deal(PosPlayer3))
+			}
+			if bankerDraws(banker, third) {
+				deal(PosBanker3)
+			}
+		}
+		b.finish(gmCode, func() (uint8, uint8) { return PosPlayer1, b.draw() })
+	}
+	return b.done()
+}
+
+// bankerDraws 庄家是否补牌 third为闲家第三张牌点数 -1表示闲家没有补牌
+func bankerDraws(banker, third int) bool {
+	if third < 0 {
+		return banker <= 5
+	}
+	switch banker {
+	case 0, 1, 2:
+		return true
+	case 3:
+		return third != 8
+	case 4:
+		return third >= 2 && third <= 7
+	case 5:
+		return third >= 4 && third <= 7
+	case 6:
+		return third == 6 || third == 7
+	}
+	return false
+}
+
+/**
+ * DragonTiger
+ * 生成龙虎整靴脚本 每局龙虎各一张牌
+ *
+ * @param opt ShoeOptions - 脚本参数
+ * @return Script - 脚本
+ */
+
+func DragonTiger(opt ShoeOptions) Script {
+	b := newBuilder(GameDragonTiger, opt)
+	b.newShoe(true)
+	for i := 0; i < opt.Rounds; i++ {
+		if len(b.deck) < 2 {
+			b.newShoe(true)
+		}
+		gmCode := b.start()
+		if b.cancel(gmCode) {
+			continue
+		}
+		b.card(gmCode, PosDragon, b.draw(), b.opt.BetTime)
+		b.card(gmCode, PosTiger, b.draw(), b.opt.CardInterval)
+		b.finish(gmCode, func() (uint8, uint8) { return PosTiger, b.draw() })
+	}
+	return b.done()
+}
+
+/**
+ * Roulette
+ * 生成轮盘脚本 每局开出一个0-36的号码 只在开始时发送一次换靴
+ *
+ * @param opt ShoeOptions - 脚本参数
+ * @return Script - 脚本
+ */
+
+func Roulette(opt ShoeOptions) Script {
+	b := newBuilder(GameRoulette, opt)
+	b.newShoe(false)
+	number := func() uint8 { return uint8(b.rnd.Intn(37)) }
+	for i := 0; i < opt.Rounds; i++ {
+		gmCode := b.start()
+		if b.cancel(gmCode) {
+			continue
+		}
+		b.add(Step{Cmd: protocol.CmdNewCardRou, Body: newCard(b.opt.Vid, gmCode, 1, number()), Delay: b.opt.BetTime,
+			Reply: protocol.CmdNewCardR})
+		b.finish(gmCode, func() (uint8, uint8) { return 1, number() })
+	}
+	return b.done()
+}
@@ -1,6 +1,9 @@
 package controllers
 
-import "github.com/beego/beego/v2/server/web"
+import (
+	"github.com/beego/beego/v2/server/web"
+	"sl.framework.com/network"
+)
 
 type HealthController struct {
 	web.Controller
@@ -15,3 +18,9 @@ func (p *HealthController) PrometheusMetrics() {
 	p.Data["json"] = map[string]string{"status": "UP"}
 	_ = p.ServeJSON()
 }
+
+// RejectedFrames 拆包时各原因被拒绝的包数量 进程启动后累计
+func (p *HealthController) RejectedFrames() {
+	p.Data["json"] = network.RejectedFrames()
+	_ = p.ServeJSON()
+}
@@ -30,6 +30,7 @@ func HttpEntrance() {
 	web.Router("/healthcheck", c, "*:Healthcheck")
 	web.Router("/actuator/health/liveness", c, "get:PrometheusMetrics")
 	web.Router("/actuator/health/readiness", c, "get:PrometheusMetrics")
+	web.Router("/actuator/frames", c, "get:RejectedFrames")
 
 	_pprof, _ := strconv.ParseBool(conf.Section("beego", "enablePprof"))
 	if _pprof {
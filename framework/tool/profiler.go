@@ -6,6 +6,8 @@ import (
 	"time"
 )
 
+var watcherObserver func(name string, elapse time.Duration) //Watcher耗时上报函数 由SetWatcherObserver设置
+
 const (
 	profilerDefaultWarningDoor = time.Duration(100) * time.Millisecond //执行时间超过默认100ms则以告警形式打印信息
 )
@@ -53,6 +55,19 @@ func TimeoutWarning(fnName string, warningDoor time.Duration) func() {
 	}
 }
 
+/**
+ * SetWatcherObserver
+ * 设置Watcher耗时上报函数 每次Watcher.Stop都会调用 用于导出监控指标
+ * 需要在服务启动时设置 运行期间不能修改
+ *
+ * @param observer func(name string, elapse time.Duration) - 上报函数 name为Watcher的打印信息
+ * @return
+ */
+
+func SetWatcherObserver(observer func(name string, elapse time.Duration)) {
+	watcherObserver = observer
+}
+
 /**
  * NewWatcher
  * 创建一个代码测量对象
@@ -104,6 +119,9 @@ func (p *Watcher) Start(msg string) {
 
 func (p *Watcher) Stop() {
 	timeElapse := time.Since(p.start)
+	if watcherObserver != nil {
+		watcherObserver(p.msg, timeElapse)
+	}
 	if timeElapse >= p.warningDoor {
 		trace.SetLogFuncCallDepth(4)
 		trace.Notice("%v cost %v", p.msg, timeElapse)
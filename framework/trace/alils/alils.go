@@ -9,7 +9,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/pkg/errors"
 
-	"github.com/beego/beego/v2/core/logs"
+	"sl.framework.com/trace"
 )
 
 const (
@@ -42,13 +42,13 @@ type aliLSWriter struct {
 	groupMap map[string]*LogGroup
 	lock     *sync.Mutex
 	Config
-	formatter logs.LogFormatter
+	formatter trace.LogFormatter
 }
 
 // NewAliLS creates a new Logger
-func NewAliLS() logs.Logger {
+func NewAliLS() trace.Logger {
 	alils := new(aliLSWriter)
-	alils.Level = logs.LevelTrace
+	alils.Level = trace.LevelTrace
 	alils.formatter = alils
 	return alils
 }
@@ -108,7 +108,7 @@ func (c *aliLSWriter) Init(config string) error {
 	c.lock = &sync.Mutex{}
 
 	if len(c.Formatter) > 0 {
-		fmtr, ok := logs.GetFormatter(c.Formatter)
+		fmtr, ok := trace.GetFormatter(c.Formatter)
 		if !ok {
 			return errors.New(fmt.Sprintf("the formatter with name: %s not found", c.Formatter))
 		}
@@ -118,17 +118,20 @@ func (c *aliLSWriter) Init(config string) error {
 	return nil
 }
 
-func (c *aliLSWriter) Format(lm *logs.LogMsg) string {
-	return lm.OldStyleFormat()
+// Format 默认格式不包含日志字段 字段在WriteMsg中作为独立的key写入
+func (c *aliLSWriter) Format(lm *trace.LogMsg) string {
+	msg := *lm
+	msg.Fields = nil
+	return msg.OldStyleFormat()
 }
 
-func (c *aliLSWriter) SetFormatter(f logs.LogFormatter) {
+func (c *aliLSWriter) SetFormatter(f trace.LogFormatter) {
 	c.formatter = f
 }
 
 // WriteMsg writes a message in connection.
 // If connection is down, try to re-connect.
-func (c *aliLSWriter) WriteMsg(lm *logs.LogMsg) error {
+func (c *aliLSWriter) WriteMsg(lm *trace.LogMsg) error {
 	if lm.Level > c.Level {
 		return nil
 	}
@@ -162,10 +165,16 @@ func (c *aliLSWriter) WriteMsg(lm *logs.LogMsg) error {
 	}
 
 	l := &Log{
-		Time: proto.Uint32(uint32(lm.When.Unix())),
-		Contents: []*LogContent{
-			c1,
-		},
+		Time:     proto.Uint32(uint32(lm.When.Unix())),
+		Contents: make([]*LogContent, 0, len(lm.Fields)+1),
+	}
+	l.Contents = append(l.Contents, c1)
+	// 结构化字段作为独立的key写入 可以在日志服务中建立索引
+	for _, field := range lm.Fields {
+		l.Contents = append(l.Contents, &LogContent{
+			Key:   proto.String(field.Key),
+			Value: proto.String(fmt.Sprint(field.Value)),
+		})
 	}
 
 	c.lock.Lock()
@@ -202,5 +211,5 @@ func (c *aliLSWriter) flush(lg *LogGroup) {
 }
 
 func init() {
-	logs.Register(logs.AdapterAliLS, NewAliLS)
+	trace.Register(trace.AdapterAliLS, NewAliLS)
 }
@@ -0,0 +1,222 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	结构化日志
+	1.通过WithTraceId/WithRoomId/WithRoundId/WithUserId把公共字段放入context 沿调用链传递
+	2.FromContext(ctx)取出带字段的Entry 以key/value形式打印日志 不再手动拼接msgHeader
+	3.字段保存在LogMsg.Fields中 JSONLogFormatter以及es alils适配器会把字段输出为独立的索引字段
+	原有的trace.Info(format, ...)调用方式不变
+*/
+
+// 自动输出的公共字段名
+const (
+	FieldTraceId = "traceId"
+	FieldRoomId  = "roomId"
+	FieldRoundId = "roundId"
+	FieldUserId  = "userId"
+
+	fieldBadKey = "!BADKEY" //key/value不成对时 最后一个value使用的key
+)
+
+// Field 日志字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// fieldsContextKey context中保存日志字段的key
+type fieldsContextKey struct{}
+
+// Entry 携带字段的日志对象 通过FromContext或者With创建 创建后不可修改 可以在协程间共享
+type Entry struct {
+	logger *BeeLogger
+	fields []Field
+}
+
+/**
+ * WithFields
+ * 把日志字段放入context 同名字段覆盖原有的值
+ *
+ * @param ctx context.Context - 父context
+ * @param keyvals ...interface{} - 成对的key/value key为字符串
+ * @return context.Context - 携带字段的context
+ */
+
+func WithFields(ctx context.Context, keyvals ...interface{}) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	fields := appendFields(FieldsFromContext(ctx), keyvals...)
+	return context.WithValue(ctx, fieldsContextKey{}, fields)
+}
+
+// WithTraceId 把traceId放入context
+func WithTraceId(ctx context.Context, traceId string) context.Context {
+	return WithFields(ctx, FieldTraceId, traceId)
+}
+
+// WithRoomId 把房间Id放入context
+func WithRoomId(ctx context.Context, roomId interface{}) context.Context {
+	return WithFields(ctx, FieldRoomId, roomId)
+}
+
+// WithRoundId 把局Id放入context
+func WithRoundId(ctx context.Context, roundId interface{}) context.Context {
+	return WithFields(ctx, FieldRoundId, roundId)
+}
+
+// WithUserId 把用户Id放入context
+func WithUserId(ctx context.Context, userId interface{}) context.Context {
+	return WithFields(ctx, FieldUserId, userId)
+}
+
+// FieldsFromContext 获取context中的日志字段 返回值不能修改
+func FieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsContextKey{}).([]Field)
+	return fields
+}
+
+// TraceIdFromContext 获取context中的traceId 不存在时返回空字符串
+func TraceIdFromContext(ctx context.Context) string {
+	for _, field := range FieldsFromContext(ctx) {
+		if field.Key == FieldTraceId {
+			traceId, _ := field.Value.(string)
+			return traceId
+		}
+	}
+	return ""
+}
+
+/**
+ * FromContext
+ * 获取携带context中日志字段的Entry 使用默认的日志对象输出
+ *
+ * @param ctx context.Context - 携带日志字段的context 可以为nil
+ * @return *Entry - 日志对象
+ */
+
+func FromContext(ctx context.Context) *Entry {
+	return &Entry{logger: beeLogger, fields: FieldsFromContext(ctx)}
+}
+
+// With 返回追加了字段的新Entry 原Entry不变
+func (e *Entry) With(keyvals ...interface{}) *Entry {
+	return &Entry{logger: e.logger, fields: appendFields(e.fields, keyvals...)}
+}
+
+// Fields 获取Entry中的字段 返回值不能修改
+func (e *Entry) Fields() []Field {
+	return e.fields
+}
+
+// Error 打印ERROR级别日志 keyvals为本条日志额外的key/value字段
+func (e *Entry) Error(msg string, keyvals ...interface{}) {
+	e.write(LevelError, msg, keyvals)
+}
+
+// Warning 打印WARNING级别日志
+func (e *Entry) Warning(msg string, keyvals ...interface{}) {
+	e.write(LevelWarning, msg, keyvals)
+}
+
+// Notice 打印NOTICE级别日志
+func (e *Entry) Notice(msg string, keyvals ...interface{}) {
+	e.write(LevelNotice, msg, keyvals)
+}
+
+// Info 打印INFO级别日志
+func (e *Entry) Info(msg string, keyvals ...interface{}) {
+	e.write(LevelInformational, msg, keyvals)
+}
+
+// Debug 打印DEBUG级别日志
+func (e *Entry) Debug(msg string, keyvals ...interface{}) {
+	e.write(LevelDebug, msg, keyvals)
+}
+
+// write 构造LogMsg并输出 调用层级和BeeLogger.Info等函数一致 保证文件行号正确
+func (e *Entry) write(level int, msg string, keyvals []interface{}) {
+	if level > e.logger.level {
+		return
+	}
+	lm := &LogMsg{
+		Level:  level,
+		Msg:    msg,
+		When:   time.Now(),
+		Fields: appendFields(e.fields, keyvals...),
+	}
+	e.logger.writeMsg(lm)
+}
+
+/**
+ * appendFields
+ * 把key/value追加到字段列表中 同名字段覆盖原有的值 始终返回新的切片 不修改fields
+ *
+ * @param fields []Field - 原有字段
+ * @param keyvals ...interface{} - 成对的key/value
+ * @return []Field - 新的字段列表
+ */
+
+func appendFields(fields []Field, keyvals ...interface{}) []Field {
+	if len(keyvals) == 0 {
+		return fields
+	}
+	res := make([]Field, len(fields), len(fields)+(len(keyvals)+1)/2)
+	copy(res, fields)
+	for i := 0; i < len(keyvals); i += 2 {
+		var field Field
+		if i+1 < len(keyvals) {
+			field = Field{Key: fieldKey(keyvals[i]), Value: keyvals[i+1]}
+		} else {
+			field = Field{Key: fieldBadKey, Value: keyvals[i]}
+		}
+
+		replaced := false
+		for j := range res {
+			if res[j].Key == field.Key {
+				res[j].Value = field.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			res = append(res, field)
+		}
+	}
+	return res
+}
+
+// fieldKey 字段名转为字符串
+func fieldKey(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
+
+// formatFields 把字段格式化为文本格式 " key=value key2=value2" 值中带空格或者=时加引号
+func formatFields(fields []Field) string {
+	var sb strings.Builder
+	for _, field := range fields {
+		value := fmt.Sprint(field.Value)
+		if value == "" || strings.ContainsAny(value, " =\"\t\r\n") {
+			value = strconv.Quote(value)
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(field.Key)
+		sb.WriteByte('=')
+		sb.WriteString(value)
+	}
+	return sb.String()
+}
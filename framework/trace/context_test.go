@@ -0,0 +1,57 @@
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type captureLogger struct {
+	msgs []*LogMsg
+}
+
+func (c *captureLogger) Init(string) error         { return nil }
+func (c *captureLogger) WriteMsg(lm *LogMsg) error { c.msgs = append(c.msgs, lm); return nil }
+func (c *captureLogger) Destroy()                  {}
+func (c *captureLogger) Flush()                    {}
+func (c *captureLogger) SetFormatter(LogFormatter) {}
+
+func TestWithFields(t *testing.T) {
+	ctx := WithTraceId(context.Background(), "t1")
+	ctx = WithRoomId(ctx, int64(1001))
+	child := WithRoundId(WithTraceId(ctx, "t2"), "r1")
+
+	assert.Equal(t, []Field{{FieldTraceId, "t1"}, {FieldRoomId, int64(1001)}}, FieldsFromContext(ctx))
+	assert.Equal(t, []Field{{FieldTraceId, "t2"}, {FieldRoomId, int64(1001)}, {FieldRoundId, "r1"}},
+		FieldsFromContext(child))
+	assert.Equal(t, "t2", TraceIdFromContext(child))
+	assert.Equal(t, "", TraceIdFromContext(nil))
+}
+
+func TestEntry(t *testing.T) {
+	capture := &captureLogger{}
+	bl := NewLogger()
+	bl.outputs = []*nameLogger{{Logger: capture, name: "capture"}}
+	bl.init = true
+	bl.SetLevel(LevelInfo)
+
+	ctx := WithUserId(WithTraceId(context.Background(), "t1"), 7)
+	entry := &Entry{logger: bl, fields: FieldsFromContext(ctx)}
+	entry.With("orderNo", 42).Info("下注成功 100%", "amount", "1.5", "odd")
+	entry.Debug("not printed")
+
+	assert.Len(t, capture.msgs, 1)
+	lm := capture.msgs[0]
+	assert.Equal(t, LevelInfo, lm.Level)
+	assert.Equal(t, "context_test.go", lm.FilePath[len(lm.FilePath)-len("context_test.go"):])
+	assert.Equal(t, []Field{{FieldTraceId, "t1"}, {FieldUserId, 7}, {"orderNo", 42}, {"amount", "1.5"}, {fieldBadKey, "odd"}},
+		lm.Fields)
+	assert.Equal(t, "[   INFO]  下注成功 100% traceId=t1 userId=7 orderNo=42 amount=1.5 !BADKEY=odd", lm.OldStyleFormat())
+	assert.Len(t, entry.Fields(), 2, "With must not modify the parent entry")
+}
+
+func TestFormatFields(t *testing.T) {
+	assert.Equal(t, "", formatFields(nil))
+	assert.Equal(t, ` a=1 b="x y" c=""`, formatFields([]Field{{"a", 1}, {"b", "x y"}, {"c", ""}}))
+}
@@ -12,15 +12,16 @@ import (
 	"github.com/elastic/go-elasticsearch/v6"
 	"github.com/elastic/go-elasticsearch/v6/esapi"
 
-	"github.com/beego/beego/v2/core/logs"
+	"sl.framework.com/trace"
 )
 
 // NewES returns a LoggerInterface
-func NewES() logs.Logger {
+func NewES() trace.Logger {
 	cw := &esLogger{
-		Level:       logs.LevelDebug,
+		Level:       trace.LevelDebug,
 		indexNaming: indexNaming,
 	}
+	cw.formatter = cw
 	return cw
 }
 
@@ -34,26 +35,18 @@ type esLogger struct {
 	*elasticsearch.Client
 	DSN       string `json:"dsn"`
 	Level     int    `json:"level"`
-	formatter logs.LogFormatter
+	formatter trace.LogFormatter
 	Formatter string `json:"formatter"`
 
 	indexNaming IndexNaming
 }
 
-func (el *esLogger) Format(lm *logs.LogMsg) string {
-	msg := lm.OldStyleFormat()
-	idx := LogDocument{
-		Timestamp: lm.When.Format(time.RFC3339),
-		Msg:       msg,
-	}
-	body, err := json.Marshal(idx)
-	if err != nil {
-		return msg
-	}
-	return string(body)
+// Format 默认以JSON文档写入es 日志字段作为文档的独立字段便于检索
+func (el *esLogger) Format(lm *trace.LogMsg) string {
+	return documentFormatter.Format(lm)
 }
 
-func (el *esLogger) SetFormatter(f logs.LogFormatter) {
+func (el *esLogger) SetFormatter(f trace.LogFormatter) {
 	el.formatter = f
 }
 
@@ -79,7 +72,7 @@ func (el *esLogger) Init(config string) error {
 		el.Client = conn
 	}
 	if len(el.Formatter) > 0 {
-		fmtr, ok := logs.GetFormatter(el.Formatter)
+		fmtr, ok := trace.GetFormatter(el.Formatter)
 		if !ok {
 			return errors.New(fmt.Sprintf("the formatter with name: %s not found", el.Formatter))
 		}
@@ -89,7 +82,7 @@ func (el *esLogger) Init(config string) error {
 }
 
 // WriteMsg writes the msg and level into es
-func (el *esLogger) WriteMsg(lm *logs.LogMsg) error {
+func (el *esLogger) WriteMsg(lm *trace.LogMsg) error {
 	if lm.Level > el.Level {
 		return nil
 	}
@@ -113,11 +106,9 @@ func (el *esLogger) Destroy() {
 func (el *esLogger) Flush() {
 }
 
-type LogDocument struct {
-	Timestamp string `json:"timestamp"`
-	Msg       string `json:"msg"`
-}
+// documentFormatter es文档格式 timestamp msg level file line以及日志字段
+var documentFormatter = &trace.JSONLogFormatter{WhenFormat: time.RFC3339}
 
 func init() {
-	logs.Register(logs.AdapterEs, NewES)
+	trace.Register(trace.AdapterEs, NewES)
 }
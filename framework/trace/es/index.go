@@ -17,12 +17,12 @@ package es
 import (
 	"fmt"
 
-	"github.com/beego/beego/v2/core/logs"
+	"sl.framework.com/trace"
 )
 
 // IndexNaming generate the index name
 type IndexNaming interface {
-	IndexName(lm *logs.LogMsg) string
+	IndexName(lm *trace.LogMsg) string
 }
 
 var indexNaming IndexNaming = &defaultIndexNaming{}
@@ -34,6 +34,6 @@ func SetIndexNaming(i IndexNaming) {
 
 type defaultIndexNaming struct{}
 
-func (d *defaultIndexNaming) IndexName(lm *logs.LogMsg) string {
+func (d *defaultIndexNaming) IndexName(lm *trace.LogMsg) string {
 	return fmt.Sprintf("%04d.%02d.%02d", lm.When.Year(), lm.When.Month(), lm.When.Day())
 }
@@ -20,12 +20,12 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
-	"github.com/beego/beego/v2/core/logs"
+	"sl.framework.com/trace"
 )
 
 func TestDefaultIndexNaming_IndexName(t *testing.T) {
 	tm := time.Date(2020, 9, 12, 1, 34, 45, 234, time.UTC)
-	lm := &logs.LogMsg{
+	lm := &trace.LogMsg{
 		When: tm,
 	}
 
@@ -15,7 +15,6 @@
 package trace
 
 import (
-	"fmt"
 	"path"
 	"strconv"
 )
@@ -65,10 +64,9 @@ func GetFormatter(name string) (LogFormatter, bool) {
 // 'l' level number, 't' prefix of level type, 'T' full name of level type
 func (p *PatternLogFormatter) ToString(lm *LogMsg) string {
 	s := []rune(p.Pattern)
-	msg := fmt.Sprintf(lm.Msg, lm.Args...)
 	m := map[rune]string{
 		'w': lm.When.Format(p.getWhenFormatter()),
-		'm': msg,
+		'm': lm.message() + formatFields(lm.Fields),
 		'n': strconv.Itoa(lm.LineNumber),
 		'l': strconv.Itoa(lm.Level),
 		't': levelPrefix[lm.Level],
@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+)
+
+// FormatterJSON JSONLogFormatter注册的名称 适配器配置{"formatter":"json"}即可使用
+const FormatterJSON = "json"
+
+// JSON日志中的固定字段 LogMsg.Fields中同名字段会加上fieldConflictPrefix前缀
+const (
+	jsonKeyTimestamp = "timestamp"
+	jsonKeyLevel     = "level"
+	jsonKeyFile      = "file"
+	jsonKeyLine      = "line"
+	jsonKeyPrefix    = "prefix"
+	jsonKeyMsg       = "msg"
+
+	fieldConflictPrefix = "field."
+)
+
+var jsonReservedKeys = map[string]struct{}{
+	jsonKeyTimestamp: {},
+	jsonKeyLevel:     {},
+	jsonKeyFile:      {},
+	jsonKeyLine:      {},
+	jsonKeyPrefix:    {},
+	jsonKeyMsg:       {},
+}
+
+/*
+	JSONLogFormatter 把日志格式化为一行JSON
+	固定字段timestamp level file line msg 之后依次输出LogMsg.Fields中的字段
+	例如:{"timestamp":"2024-01-02T15:04:05.123+08:00","level":"info","file":"bet_service.go","line":120,
+	"msg":"下注成功","traceId":"abc","roomId":1001}
+*/
+
+type JSONLogFormatter struct {
+	WhenFormat string //时间格式 默认为RFC3339毫秒精度
+}
+
+func init() {
+	RegisterFormatter(FormatterJSON, &JSONLogFormatter{})
+}
+
+func (j *JSONLogFormatter) getWhenFormat() string {
+	if j.WhenFormat == "" {
+		return "2006-01-02T15:04:05.000Z07:00"
+	}
+	return j.WhenFormat
+}
+
+// Format 实现LogFormatter接口
+func (j *JSONLogFormatter) Format(lm *LogMsg) string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeJSONField(&buf, jsonKeyTimestamp, lm.When.Format(j.getWhenFormat()))
+	if lm.Level >= 0 && lm.Level < len(levelNames) {
+		buf.WriteByte(',')
+		writeJSONField(&buf, jsonKeyLevel, levelNames[lm.Level])
+	}
+	if len(lm.FilePath) > 0 {
+		filePath := lm.FilePath
+		if !lm.enableFullFilePath {
+			_, filePath = path.Split(filePath)
+		}
+		buf.WriteByte(',')
+		writeJSONField(&buf, jsonKeyFile, filePath)
+		buf.WriteByte(',')
+		writeJSONField(&buf, jsonKeyLine, lm.LineNumber)
+	}
+	if len(lm.Prefix) > 0 {
+		buf.WriteByte(',')
+		writeJSONField(&buf, jsonKeyPrefix, lm.Prefix)
+	}
+	buf.WriteByte(',')
+	writeJSONField(&buf, jsonKeyMsg, lm.message())
+
+	for _, field := range lm.Fields {
+		key := field.Key
+		if _, ok := jsonReservedKeys[key]; ok {
+			key = fieldConflictPrefix + key
+		}
+		buf.WriteByte(',')
+		writeJSONField(&buf, key, field.Value)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// writeJSONField 写入"key":value 无法序列化的值以fmt.Sprint的结果作为字符串写入
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	} else if d, ok := value.(time.Duration); ok {
+		value = d.String()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(v)
+}
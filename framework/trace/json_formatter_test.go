@@ -0,0 +1,49 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLogFormatter(t *testing.T) {
+	when := time.Date(2024, 1, 2, 15, 4, 5, 123e6, time.UTC)
+	f := &JSONLogFormatter{}
+
+	lm := &LogMsg{
+		Level:      LevelInfo,
+		Msg:        "hello %v",
+		Args:       []interface{}{"world"},
+		When:       when,
+		FilePath:   "/user/home/main.go",
+		LineNumber: 13,
+	}
+	assert.Equal(t, `{"timestamp":"2024-01-02T15:04:05.123Z","level":"info","file":"main.go","line":13,"msg":"hello world"}`,
+		f.Format(lm))
+
+	lm = &LogMsg{
+		Level: LevelError,
+		Msg:   "下注失败",
+		When:  when,
+		Fields: []Field{
+			{FieldTraceId, "t1"},
+			{FieldRoomId, int64(1001)},
+			{"error", errors.New("timeout")},
+			{"msg", "conflict"},
+			{"span", 1500 * time.Millisecond},
+		},
+	}
+	res := f.Format(lm)
+	assert.Equal(t, `{"timestamp":"2024-01-02T15:04:05.123Z","level":"error","msg":"下注失败","traceId":"t1",`+
+		`"roomId":1001,"error":"timeout","field.msg":"conflict","span":"1.5s"}`, res)
+
+	var doc map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(res), &doc))
+
+	fmtr, ok := GetFormatter(FormatterJSON)
+	assert.True(t, ok)
+	assert.IsType(t, &JSONLogFormatter{}, fmtr)
+}
@@ -330,6 +330,7 @@ func (bl *BeeLogger) writeMsg(lm *LogMsg) error {
 		logM.Msg = lm.Msg
 		logM.When = lm.When
 		logM.Args = lm.Args
+		logM.Fields = lm.Fields
 		logM.FilePath = lm.FilePath
 		logM.LineNumber = lm.LineNumber
 		logM.Prefix = lm.Prefix
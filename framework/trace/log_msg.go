@@ -27,6 +27,7 @@ type LogMsg struct {
 	FilePath            string
 	LineNumber          int
 	Args                []interface{}
+	Fields              []Field //结构化字段 由Entry输出时设置
 	Prefix              string
 	enableFullFilePath  bool
 	enableFuncCallDepth bool
@@ -34,13 +35,7 @@ type LogMsg struct {
 
 // OldStyleFormat you should never invoke this
 func (lm *LogMsg) OldStyleFormat() string {
-	msg := lm.Msg
-
-	if len(lm.Args) > 0 {
-		msg = fmt.Sprintf(lm.Msg, lm.Args...)
-	}
-
-	msg = lm.Prefix + " " + msg
+	msg := lm.Prefix + " " + lm.message() + formatFields(lm.Fields)
 
 	if lm.enableFuncCallDepth {
 		filePath := lm.FilePath
@@ -53,3 +48,11 @@ func (lm *LogMsg) OldStyleFormat() string {
 	msg = levelPrefix[lm.Level] + " " + msg
 	return msg
 }
+
+// message 返回格式化后的日志内容 不包含字段 Args为空时Msg原样返回
+func (lm *LogMsg) message() string {
+	if len(lm.Args) > 0 {
+		return fmt.Sprintf(lm.Msg, lm.Args...)
+	}
+	return lm.Msg
+}